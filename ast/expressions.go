@@ -263,6 +263,9 @@ func (e *GetAttr) Object() Expression { return e.object }
 
 func (e *GetAttr) Name() string { return e.attribute.value }
 
+// Attribute returns the identifier of the attribute being accessed.
+func (e *GetAttr) Attribute() *Ident { return e.attribute }
+
 func (e *GetAttr) String() string {
 	var out bytes.Buffer
 	out.WriteString(e.object.String())
@@ -533,6 +536,231 @@ func (s *Switch) String() string {
 	return out.String()
 }
 
+// MatchCase is one case within a match expression. A default case has no
+// pattern.
+type MatchCase struct {
+	token token.Token
+
+	// Default branch?
+	isDefault bool
+
+	// The pattern the value must match
+	pattern Pattern
+
+	// An optional condition that must also be true for the case to match
+	guard Expression
+
+	// The code to execute if there is a match
+	block *Block
+}
+
+// NewMatchCase creates a new MatchCase node.
+func NewMatchCase(token token.Token, pattern Pattern, guard Expression, block *Block) *MatchCase {
+	return &MatchCase{token: token, pattern: pattern, guard: guard, block: block}
+}
+
+// NewDefaultMatchCase creates the default case within a match expression.
+func NewDefaultMatchCase(token token.Token, block *Block) *MatchCase {
+	return &MatchCase{token: token, isDefault: true, block: block}
+}
+
+func (c *MatchCase) ExpressionNode() {}
+
+func (c *MatchCase) IsExpression() bool { return true }
+
+func (c *MatchCase) Token() token.Token { return c.token }
+
+func (c *MatchCase) Literal() string { return c.token.Literal }
+
+func (c *MatchCase) IsDefault() bool { return c.isDefault }
+
+func (c *MatchCase) Pattern() Pattern { return c.pattern }
+
+func (c *MatchCase) Guard() Expression { return c.guard }
+
+func (c *MatchCase) Block() *Block { return c.block }
+
+func (c *MatchCase) String() string {
+	var out bytes.Buffer
+	if c.isDefault {
+		out.WriteString("default")
+	} else {
+		out.WriteString("case ")
+		out.WriteString(c.pattern.String())
+		if c.guard != nil {
+			out.WriteString(" if ")
+			out.WriteString(c.guard.String())
+		}
+	}
+	out.WriteString(":\n")
+	if c.block != nil {
+		for i, exp := range c.block.statements {
+			if i > 0 {
+				out.WriteString("\n")
+			}
+			out.WriteString("\t" + exp.String())
+		}
+	}
+	out.WriteString("\n")
+	return out.String()
+}
+
+// Match is an expression node that compares a value against a series of
+// patterns, evaluating the block of the first case that matches.
+type Match struct {
+	// token containing "match"
+	token token.Token
+
+	// the expression to match
+	value Expression
+
+	// match cases
+	cases []*MatchCase
+}
+
+// NewMatch creates a new Match node.
+func NewMatch(token token.Token, value Expression, cases []*MatchCase) *Match {
+	return &Match{token: token, value: value, cases: cases}
+}
+
+func (m *Match) ExpressionNode() {}
+
+func (m *Match) IsExpression() bool { return true }
+
+func (m *Match) Token() token.Token { return m.token }
+
+func (m *Match) Literal() string { return m.token.Literal }
+
+func (m *Match) Value() Expression { return m.value }
+
+func (m *Match) Cases() []*MatchCase { return m.cases }
+
+func (m *Match) String() string {
+	var out bytes.Buffer
+	out.WriteString("\nmatch ")
+	out.WriteString(m.value.String())
+	out.WriteString(" {\n")
+	for _, c := range m.cases {
+		if c != nil {
+			out.WriteString(c.String())
+		}
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// SelectCase is one case within a select expression. Each case other than the
+// default case has a channel operation, which is either a send or a receive.
+type SelectCase struct {
+	token token.Token
+
+	// Default branch?
+	isDefault bool
+
+	// The channel operation, a *Send or a *Receive
+	comm Node
+
+	// Names declared to hold the received value and, optionally, whether the
+	// channel was open
+	names []*Ident
+
+	// The code to execute if this case is chosen
+	block *Block
+}
+
+// NewSelectCase creates a new SelectCase node.
+func NewSelectCase(token token.Token, comm Node, names []*Ident, block *Block) *SelectCase {
+	return &SelectCase{token: token, comm: comm, names: names, block: block}
+}
+
+// NewDefaultSelectCase creates the default case within a select expression.
+func NewDefaultSelectCase(token token.Token, block *Block) *SelectCase {
+	return &SelectCase{token: token, isDefault: true, block: block}
+}
+
+func (c *SelectCase) ExpressionNode() {}
+
+func (c *SelectCase) IsExpression() bool { return true }
+
+func (c *SelectCase) Token() token.Token { return c.token }
+
+func (c *SelectCase) Literal() string { return c.token.Literal }
+
+func (c *SelectCase) IsDefault() bool { return c.isDefault }
+
+func (c *SelectCase) Comm() Node { return c.comm }
+
+func (c *SelectCase) Names() []*Ident { return c.names }
+
+func (c *SelectCase) Block() *Block { return c.block }
+
+func (c *SelectCase) String() string {
+	var out bytes.Buffer
+	if c.isDefault {
+		out.WriteString("default")
+	} else {
+		out.WriteString("case ")
+		if len(c.names) > 0 {
+			for i, name := range c.names {
+				if i > 0 {
+					out.WriteString(", ")
+				}
+				out.WriteString(name.String())
+			}
+			out.WriteString(" := ")
+		}
+		out.WriteString(c.comm.String())
+	}
+	out.WriteString(":\n")
+	if c.block != nil {
+		for i, exp := range c.block.statements {
+			if i > 0 {
+				out.WriteString("\n")
+			}
+			out.WriteString("\t" + exp.String())
+		}
+	}
+	out.WriteString("\n")
+	return out.String()
+}
+
+// Select is an expression node that waits until one of several channel
+// operations can proceed, then evaluates the block of that case.
+type Select struct {
+	// token containing "select"
+	token token.Token
+
+	// select cases
+	cases []*SelectCase
+}
+
+// NewSelect creates a new Select node.
+func NewSelect(token token.Token, cases []*SelectCase) *Select {
+	return &Select{token: token, cases: cases}
+}
+
+func (s *Select) ExpressionNode() {}
+
+func (s *Select) IsExpression() bool { return true }
+
+func (s *Select) Token() token.Token { return s.token }
+
+func (s *Select) Literal() string { return s.token.Literal }
+
+func (s *Select) Cases() []*SelectCase { return s.cases }
+
+func (s *Select) String() string {
+	var out bytes.Buffer
+	out.WriteString("\nselect {\n")
+	for _, c := range s.cases {
+		if c != nil {
+			out.WriteString(c.String())
+		}
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
 // In is an expression node that checks whether a value is present in a container.
 type In struct {
 	token token.Token
@@ -107,12 +107,24 @@ type Func struct {
 
 	name *Ident
 
+	// receiver is the parameter the struct instance is bound to, for methods.
+	receiver *Ident
+
+	// receiverType names the struct type a method is defined on.
+	receiverType *Ident
+
 	// parameters is the list of parameters the function receives.
 	parameters []*Ident
 
 	// defaults holds any default values for arguments which aren't specified.
 	defaults map[string]Expression
 
+	// types holds the type annotations of parameters that have one.
+	types map[string]*TypeExpr
+
+	// result is the annotated result type, if any.
+	result *TypeExpr
+
 	// body contains the set of statements within the function.
 	body *Block
 }
@@ -128,6 +140,19 @@ func NewFunc(token token.Token, name *Ident, parameters []*Ident, defaults map[s
 	}
 }
 
+// NewMethod creates a new Func node for a method defined on a struct type.
+func NewMethod(token token.Token, receiver *Ident, receiverType *Ident, name *Ident, parameters []*Ident, defaults map[string]Expression, body *Block) *Func {
+	return &Func{
+		token:        token,
+		name:         name,
+		receiver:     receiver,
+		receiverType: receiverType,
+		parameters:   parameters,
+		defaults:     defaults,
+		body:         body,
+	}
+}
+
 func (f *Func) ExpressionNode() {}
 
 func (f *Func) IsExpression() bool { return f.name == nil }
@@ -138,6 +163,12 @@ func (f *Func) Literal() string { return f.token.Literal }
 
 func (f *Func) Name() *Ident { return f.name }
 
+func (f *Func) Receiver() *Ident { return f.receiver }
+
+func (f *Func) ReceiverType() *Ident { return f.receiverType }
+
+func (f *Func) IsMethod() bool { return f.receiver != nil }
+
 func (f *Func) Parameters() []*Ident { return f.parameters }
 
 func (f *Func) ParameterNames() []string {
@@ -150,21 +181,45 @@ func (f *Func) ParameterNames() []string {
 
 func (f *Func) Defaults() map[string]Expression { return f.defaults }
 
+// SetTypes sets the type annotations of the parameters and the result. This
+// is called by the parser when a function is annotated.
+func (f *Func) SetTypes(params map[string]*TypeExpr, result *TypeExpr) {
+	f.types = params
+	f.result = result
+}
+
+// ParameterType returns the type annotation of the named parameter, or nil.
+func (f *Func) ParameterType(name string) *TypeExpr { return f.types[name] }
+
+// ResultType returns the annotated result type, or nil.
+func (f *Func) ResultType() *TypeExpr { return f.result }
+
 func (f *Func) Body() *Block { return f.body }
 
 func (f *Func) String() string {
 	var out bytes.Buffer
 	params := make([]string, 0)
 	for _, p := range f.parameters {
-		params = append(params, p.value)
+		if t, ok := f.types[p.value]; ok {
+			params = append(params, p.value+": "+t.String())
+		} else {
+			params = append(params, p.value)
+		}
 	}
 	out.WriteString(f.Literal())
+	if f.receiver != nil {
+		out.WriteString(" (" + f.receiver.value + " " + f.receiverType.value + ")")
+	}
 	if f.name != nil {
 		out.WriteString(" " + f.name.value)
 	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
-	out.WriteString(") { ")
+	out.WriteString(")")
+	if f.result != nil {
+		out.WriteString(" -> " + f.result.String())
+	}
+	out.WriteString(" { ")
 	out.WriteString(f.body.String())
 	out.WriteString(" }")
 	return out.String()
@@ -0,0 +1,266 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/itrn0/risor/token"
+)
+
+// Pattern is the pattern in one case of a match expression. A value may or
+// may not match a pattern, and matching may bind parts of the value to names.
+type Pattern interface {
+	// Node is embedded here to indicate that all patterns are AST nodes.
+	Node
+
+	// PatternNode signals that this Node is a pattern.
+	PatternNode()
+}
+
+// WildcardPattern is the "_" pattern, which matches any value.
+type WildcardPattern struct {
+	token token.Token
+}
+
+// NewWildcardPattern creates a new WildcardPattern node.
+func NewWildcardPattern(tok token.Token) *WildcardPattern {
+	return &WildcardPattern{token: tok}
+}
+
+func (p *WildcardPattern) PatternNode() {}
+
+func (p *WildcardPattern) IsExpression() bool { return false }
+
+func (p *WildcardPattern) Token() token.Token { return p.token }
+
+func (p *WildcardPattern) Literal() string { return p.token.Literal }
+
+func (p *WildcardPattern) String() string { return "_" }
+
+// BindPattern is a name, which matches any value and binds it to the name.
+type BindPattern struct {
+	ident *Ident
+}
+
+// NewBindPattern creates a new BindPattern node.
+func NewBindPattern(ident *Ident) *BindPattern {
+	return &BindPattern{ident: ident}
+}
+
+func (p *BindPattern) PatternNode() {}
+
+func (p *BindPattern) IsExpression() bool { return false }
+
+func (p *BindPattern) Token() token.Token { return p.ident.Token() }
+
+func (p *BindPattern) Literal() string { return p.ident.Literal() }
+
+func (p *BindPattern) Ident() *Ident { return p.ident }
+
+func (p *BindPattern) String() string { return p.ident.String() }
+
+// LiteralPattern matches values equal to a literal, e.g. 1, -2.5, "pod",
+// true or nil.
+type LiteralPattern struct {
+	value Expression
+}
+
+// NewLiteralPattern creates a new LiteralPattern node.
+func NewLiteralPattern(value Expression) *LiteralPattern {
+	return &LiteralPattern{value: value}
+}
+
+func (p *LiteralPattern) PatternNode() {}
+
+func (p *LiteralPattern) IsExpression() bool { return false }
+
+func (p *LiteralPattern) Token() token.Token { return p.value.Token() }
+
+func (p *LiteralPattern) Literal() string { return p.value.Literal() }
+
+func (p *LiteralPattern) Value() Expression { return p.value }
+
+func (p *LiteralPattern) String() string { return p.value.String() }
+
+// RestPattern is "...name" within a list pattern, which matches the items
+// not matched by the other elements and binds them to the name as a list.
+// The name is optional.
+type RestPattern struct {
+	// token containing "..."
+	token token.Token
+
+	// the name to bind, which may be nil
+	ident *Ident
+}
+
+// NewRestPattern creates a new RestPattern node.
+func NewRestPattern(tok token.Token, ident *Ident) *RestPattern {
+	return &RestPattern{token: tok, ident: ident}
+}
+
+func (p *RestPattern) PatternNode() {}
+
+func (p *RestPattern) IsExpression() bool { return false }
+
+func (p *RestPattern) Token() token.Token { return p.token }
+
+func (p *RestPattern) Literal() string { return p.token.Literal }
+
+// Ident returns the name the rest of the list is bound to, or nil if it
+// isn't bound.
+func (p *RestPattern) Ident() *Ident { return p.ident }
+
+func (p *RestPattern) String() string {
+	if p.ident == nil {
+		return "..."
+	}
+	return "..." + p.ident.String()
+}
+
+// ListPattern matches lists whose items match its elements, e.g. [a, b] or
+// [first, ...rest]. Without a RestPattern, the list must have exactly as many
+// items as there are elements.
+type ListPattern struct {
+	// token containing "["
+	token token.Token
+
+	elements []Pattern
+}
+
+// NewListPattern creates a new ListPattern node.
+func NewListPattern(tok token.Token, elements []Pattern) *ListPattern {
+	return &ListPattern{token: tok, elements: elements}
+}
+
+func (p *ListPattern) PatternNode() {}
+
+func (p *ListPattern) IsExpression() bool { return false }
+
+func (p *ListPattern) Token() token.Token { return p.token }
+
+func (p *ListPattern) Literal() string { return p.token.Literal }
+
+func (p *ListPattern) Elements() []Pattern { return p.elements }
+
+func (p *ListPattern) String() string {
+	elements := make([]string, 0, len(p.elements))
+	for _, el := range p.elements {
+		elements = append(elements, el.String())
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// MapPattern matches maps that contain each of its keys, with values that
+// match the corresponding patterns, e.g. {"kind": "pod", "name": n}. Other
+// keys in the map are ignored.
+type MapPattern struct {
+	// token containing "{"
+	token token.Token
+
+	// keys are String or Ident nodes, in the order they were written
+	keys []Expression
+
+	values []Pattern
+}
+
+// NewMapPattern creates a new MapPattern node. The keys and values must have
+// the same length.
+func NewMapPattern(tok token.Token, keys []Expression, values []Pattern) *MapPattern {
+	return &MapPattern{token: tok, keys: keys, values: values}
+}
+
+func (p *MapPattern) PatternNode() {}
+
+func (p *MapPattern) IsExpression() bool { return false }
+
+func (p *MapPattern) Token() token.Token { return p.token }
+
+func (p *MapPattern) Literal() string { return p.token.Literal }
+
+func (p *MapPattern) Keys() []Expression { return p.keys }
+
+func (p *MapPattern) Values() []Pattern { return p.values }
+
+func (p *MapPattern) String() string {
+	var out bytes.Buffer
+	out.WriteString("{")
+	for i, key := range p.keys {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(key.String())
+		out.WriteString(": ")
+		out.WriteString(p.values[i].String())
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+// TypePattern matches values of the named type whose value also matches the
+// inner pattern, if there is one, e.g. int(x) or string().
+type TypePattern struct {
+	// the first token of the type name
+	token token.Token
+
+	// the type name, e.g. "int" or "sql.rows"
+	name string
+
+	// the pattern the value must also match, which may be nil
+	pattern Pattern
+}
+
+// NewTypePattern creates a new TypePattern node.
+func NewTypePattern(tok token.Token, name string, pattern Pattern) *TypePattern {
+	return &TypePattern{token: tok, name: name, pattern: pattern}
+}
+
+func (p *TypePattern) PatternNode() {}
+
+func (p *TypePattern) IsExpression() bool { return false }
+
+func (p *TypePattern) Token() token.Token { return p.token }
+
+func (p *TypePattern) Literal() string { return p.token.Literal }
+
+func (p *TypePattern) Name() string { return p.name }
+
+func (p *TypePattern) Pattern() Pattern { return p.pattern }
+
+func (p *TypePattern) String() string {
+	if p.pattern == nil {
+		return p.name + "()"
+	}
+	return p.name + "(" + p.pattern.String() + ")"
+}
+
+// OrPattern matches values that match any of its alternatives, e.g.
+// "a" | "b". The alternatives may not bind names.
+type OrPattern struct {
+	// the first token of the first alternative
+	token token.Token
+
+	alternatives []Pattern
+}
+
+// NewOrPattern creates a new OrPattern node.
+func NewOrPattern(tok token.Token, alternatives []Pattern) *OrPattern {
+	return &OrPattern{token: tok, alternatives: alternatives}
+}
+
+func (p *OrPattern) PatternNode() {}
+
+func (p *OrPattern) IsExpression() bool { return false }
+
+func (p *OrPattern) Token() token.Token { return p.token }
+
+func (p *OrPattern) Literal() string { return p.token.Literal }
+
+func (p *OrPattern) Alternatives() []Pattern { return p.alternatives }
+
+func (p *OrPattern) String() string {
+	alternatives := make([]string, 0, len(p.alternatives))
+	for _, alt := range p.alternatives {
+		alternatives = append(alternatives, alt.String())
+	}
+	return strings.Join(alternatives, " | ")
+}
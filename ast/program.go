@@ -2,6 +2,7 @@ package ast
 
 import (
 	"bytes"
+	"strings"
 
 	"github.com/itrn0/risor/token"
 )
@@ -11,12 +12,21 @@ import (
 type Program struct {
 	// The list of statements which comprise the program.
 	statements []Node
+
+	// The comments found in the source of the program.
+	comments []*Comment
 }
 
 func NewProgram(statements []Node) *Program {
 	return &Program{statements: statements}
 }
 
+// NewProgramWithComments creates a Program that also holds the comments found
+// in its source, in the order they appear.
+func NewProgramWithComments(statements []Node, comments []*Comment) *Program {
+	return &Program{statements: statements, comments: comments}
+}
+
 func (p *Program) Token() token.Token {
 	if len(p.statements) > 0 {
 		return p.statements[0].Token()
@@ -30,6 +40,8 @@ func (p *Program) Literal() string { return p.Token().Literal }
 
 func (p *Program) Statements() []Node { return p.statements }
 
+func (p *Program) Comments() []*Comment { return p.comments }
+
 func (p *Program) First() Node {
 	if len(p.statements) > 0 {
 		return p.statements[0]
@@ -48,3 +60,29 @@ func (p *Program) String() string {
 	}
 	return out.String()
 }
+
+// Comment is a node that holds a comment found in the source code. Comments
+// are not part of the statements of a program, but are kept so that tools
+// like formatters can reproduce them.
+type Comment struct {
+	token token.Token
+}
+
+// NewComment creates a new Comment node.
+func NewComment(token token.Token) *Comment {
+	return &Comment{token: token}
+}
+
+func (c *Comment) IsExpression() bool { return false }
+
+func (c *Comment) Token() token.Token { return c.token }
+
+func (c *Comment) Literal() string { return c.token.Literal }
+
+// Text returns the text of the comment, including the comment markers.
+func (c *Comment) Text() string { return c.token.Literal }
+
+// IsBlock returns true if this is a /* ... */ comment.
+func (c *Comment) IsBlock() bool { return strings.HasPrefix(c.token.Literal, "/*") }
+
+func (c *Comment) String() string { return c.token.Literal }
@@ -21,6 +21,9 @@ type Var struct {
 
 	// isWalrus is true if this is a ":=" statement.
 	isWalrus bool
+
+	// typ is the type annotation of the variable, if any.
+	typ *TypeExpr
 }
 
 // NewVar creates a new Var node.
@@ -43,17 +46,31 @@ func (s *Var) Literal() string { return s.token.Literal }
 
 func (s *Var) Value() (string, Expression) { return s.name.value, s.value }
 
+// Ident returns the identifier of the variable being assigned.
+func (s *Var) Ident() *Ident { return s.name }
+
 func (s *Var) IsWalrus() bool { return s.isWalrus }
 
+// SetType sets the type annotation of the variable. This is called by the
+// parser when the variable is annotated.
+func (s *Var) SetType(typ *TypeExpr) { s.typ = typ }
+
+// Type returns the type annotation of the variable, or nil.
+func (s *Var) Type() *TypeExpr { return s.typ }
+
 func (s *Var) String() string {
 	var out bytes.Buffer
+	name := s.name.Literal()
+	if s.typ != nil {
+		name += ": " + s.typ.String()
+	}
 	if s.isWalrus {
-		out.WriteString(s.name.Literal() + " := ")
+		out.WriteString(name + " := ")
 		out.WriteString(s.value.String())
 		return out.String()
 	}
 	out.WriteString(s.Literal() + " ")
-	out.WriteString(s.name.Literal())
+	out.WriteString(name)
 	out.WriteString(" = ")
 	if s.value != nil {
 		out.WriteString(s.value.String())
@@ -91,6 +108,9 @@ func (s *MultiVar) Value() ([]string, Expression) {
 	return names, s.value
 }
 
+// Idents returns the identifiers of the variables being assigned.
+func (s *MultiVar) Idents() []*Ident { return s.names }
+
 func (s *MultiVar) IsWalrus() bool { return s.isWalrus }
 
 func (s *MultiVar) String() string {
@@ -119,6 +139,9 @@ type Const struct {
 
 	// value of the constant
 	value Expression
+
+	// typ is the type annotation of the constant, if any.
+	typ *TypeExpr
 }
 
 // NewConst creates a new Const node.
@@ -136,10 +159,23 @@ func (c *Const) Literal() string { return c.token.Literal }
 
 func (c *Const) Value() (string, Expression) { return c.name.value, c.value }
 
+// Ident returns the identifier of the constant.
+func (c *Const) Ident() *Ident { return c.name }
+
+// SetType sets the type annotation of the constant. This is called by the
+// parser when the constant is annotated.
+func (c *Const) SetType(typ *TypeExpr) { c.typ = typ }
+
+// Type returns the type annotation of the constant, or nil.
+func (c *Const) Type() *TypeExpr { return c.typ }
+
 func (c *Const) String() string {
 	var out bytes.Buffer
 	out.WriteString(c.Literal() + " ")
 	out.WriteString(c.name.Literal())
+	if c.typ != nil {
+		out.WriteString(": " + c.typ.String())
+	}
 	out.WriteString(" = ")
 	if c.value != nil {
 		out.WriteString(c.value.String())
@@ -217,6 +253,40 @@ func (r *Return) String() string {
 	return out.String()
 }
 
+// Yield defines a yield statement, which suspends a generator function and
+// produces a value from it.
+type Yield struct {
+	// "yield"
+	token token.Token
+
+	// optional value
+	value Expression
+}
+
+// NewYield creates a new Yield node.
+func NewYield(token token.Token, value Expression) *Yield {
+	return &Yield{token: token, value: value}
+}
+
+func (y *Yield) StatementNode() {}
+
+func (y *Yield) IsExpression() bool { return false }
+
+func (y *Yield) Token() token.Token { return y.token }
+
+func (y *Yield) Literal() string { return y.token.Literal }
+
+func (y *Yield) Value() Expression { return y.value }
+
+func (y *Yield) String() string {
+	var out bytes.Buffer
+	out.WriteString(y.Literal())
+	if y.value != nil {
+		out.WriteString(" " + y.value.String())
+	}
+	return out.String()
+}
+
 // Block is a node that holds a sequence of statements. This is used to
 // represent the body of a function, loop, or a conditional.
 type Block struct {
@@ -384,6 +454,10 @@ func (a *Assign) Literal() string { return a.token.Literal }
 
 func (a *Assign) Name() string { return a.name.value }
 
+// Ident returns the identifier of the variable being assigned, which is nil
+// for index assignments.
+func (a *Assign) Ident() *Ident { return a.name }
+
 func (a *Assign) Index() *Index { return a.index }
 
 func (a *Assign) Operator() string { return a.operator }
@@ -471,6 +545,8 @@ func (i *FromImport) Parents() []*Ident { return i.parents }
 
 func (i *FromImport) Imports() []*Import { return i.imports }
 
+func (i *FromImport) IsGrouped() bool { return i.isGrouped }
+
 func (i *FromImport) String() string {
 	var out bytes.Buffer
 	out.WriteString(i.Literal() + " ")
@@ -561,6 +637,9 @@ func (e *SetAttr) Object() Expression { return e.object }
 
 func (e *SetAttr) Name() string { return e.attribute.value }
 
+// Attribute returns the identifier of the attribute being set.
+func (e *SetAttr) Attribute() *Ident { return e.attribute }
+
 func (e *SetAttr) Value() Expression { return e.value }
 
 func (e *SetAttr) String() string {
@@ -650,3 +729,133 @@ func (s *Send) Value() Expression { return s.value }
 func (s *Send) String() string {
 	return fmt.Sprintf("%s <- %s", s.channel.String(), s.value.String())
 }
+
+// Try is a statement node that runs a block of code and handles any error it
+// raises. At least one of the catch and finally blocks is present.
+type Try struct {
+	token token.Token
+
+	// body is the block whose errors are handled.
+	body *Block
+
+	// catchIdent is the optional name the caught error is assigned to.
+	catchIdent *Ident
+
+	// catchBlock runs if the body raises an error.
+	catchBlock *Block
+
+	// finallyBlock runs after the body and catch block, whether or not they
+	// raised an error.
+	finallyBlock *Block
+}
+
+// NewTry creates a new Try node.
+func NewTry(token token.Token, body *Block, catchIdent *Ident, catchBlock *Block, finallyBlock *Block) *Try {
+	return &Try{
+		token:        token,
+		body:         body,
+		catchIdent:   catchIdent,
+		catchBlock:   catchBlock,
+		finallyBlock: finallyBlock,
+	}
+}
+
+func (t *Try) StatementNode() {}
+
+func (t *Try) IsExpression() bool { return false }
+
+func (t *Try) Token() token.Token { return t.token }
+
+func (t *Try) Literal() string { return t.token.Literal }
+
+func (t *Try) Body() *Block { return t.body }
+
+func (t *Try) CatchIdent() *Ident { return t.catchIdent }
+
+func (t *Try) CatchBlock() *Block { return t.catchBlock }
+
+func (t *Try) FinallyBlock() *Block { return t.finallyBlock }
+
+func (t *Try) String() string {
+	var out bytes.Buffer
+	out.WriteString("try { ")
+	out.WriteString(t.body.String())
+	out.WriteString(" }")
+	if t.catchBlock != nil {
+		out.WriteString(" catch ")
+		if t.catchIdent != nil {
+			out.WriteString(t.catchIdent.Literal() + " ")
+		}
+		out.WriteString("{ ")
+		out.WriteString(t.catchBlock.String())
+		out.WriteString(" }")
+	}
+	if t.finallyBlock != nil {
+		out.WriteString(" finally { ")
+		out.WriteString(t.finallyBlock.String())
+		out.WriteString(" }")
+	}
+	return out.String()
+}
+
+// Struct is a statement node that declares a struct type with a fixed set of
+// fields, each of which may have a default value.
+type Struct struct {
+	token token.Token
+
+	// name is the name of the struct type.
+	name *Ident
+
+	// fields is the list of fields, in declaration order.
+	fields []*Ident
+
+	// defaults holds the default values of fields that have one.
+	defaults map[string]Expression
+}
+
+// NewStruct creates a new Struct node.
+func NewStruct(token token.Token, name *Ident, fields []*Ident, defaults map[string]Expression) *Struct {
+	return &Struct{
+		token:    token,
+		name:     name,
+		fields:   fields,
+		defaults: defaults,
+	}
+}
+
+func (s *Struct) StatementNode() {}
+
+func (s *Struct) IsExpression() bool { return false }
+
+func (s *Struct) Token() token.Token { return s.token }
+
+func (s *Struct) Literal() string { return s.token.Literal }
+
+func (s *Struct) Name() *Ident { return s.name }
+
+func (s *Struct) Fields() []*Ident { return s.fields }
+
+func (s *Struct) FieldNames() []string {
+	names := make([]string, 0, len(s.fields))
+	for _, f := range s.fields {
+		names = append(names, f.value)
+	}
+	return names
+}
+
+func (s *Struct) Defaults() map[string]Expression { return s.defaults }
+
+func (s *Struct) String() string {
+	fields := make([]string, 0, len(s.fields))
+	for _, f := range s.fields {
+		if expr, ok := s.defaults[f.value]; ok {
+			fields = append(fields, f.value+" = "+expr.String())
+		} else {
+			fields = append(fields, f.value)
+		}
+	}
+	if len(fields) == 0 {
+		return fmt.Sprintf("struct %s {}", s.name.value)
+	}
+	return fmt.Sprintf("struct %s { %s }", s.name.value, strings.Join(fields, ", "))
+}
@@ -0,0 +1,66 @@
+package ast
+
+import (
+	"strings"
+
+	"github.com/itrn0/risor/token"
+)
+
+// TypeExpr is a type annotation on a variable, constant, function parameter
+// or function result, e.g. "int", "list[string]" or "string | nil". Type
+// annotations are ignored by the compiler unless type checking is enabled.
+type TypeExpr struct {
+	// the first token of the annotation
+	token token.Token
+
+	// name is the type name, e.g. "int". Empty for unions.
+	name string
+
+	// params holds the type parameters of a generic type, e.g. the element
+	// type of "list[int]"
+	params []*TypeExpr
+
+	// alternatives holds the members of a union type
+	alternatives []*TypeExpr
+}
+
+// NewTypeExpr creates a new TypeExpr for a named type.
+func NewTypeExpr(tok token.Token, name string, params []*TypeExpr) *TypeExpr {
+	return &TypeExpr{token: tok, name: name, params: params}
+}
+
+// NewUnionTypeExpr creates a new TypeExpr for a union of types.
+func NewUnionTypeExpr(tok token.Token, alternatives []*TypeExpr) *TypeExpr {
+	return &TypeExpr{token: tok, alternatives: alternatives}
+}
+
+func (t *TypeExpr) Token() token.Token { return t.token }
+
+func (t *TypeExpr) Literal() string { return t.token.Literal }
+
+// Name returns the name of the type, or an empty string for a union.
+func (t *TypeExpr) Name() string { return t.name }
+
+func (t *TypeExpr) Params() []*TypeExpr { return t.params }
+
+func (t *TypeExpr) IsUnion() bool { return len(t.alternatives) > 0 }
+
+func (t *TypeExpr) Alternatives() []*TypeExpr { return t.alternatives }
+
+func (t *TypeExpr) String() string {
+	if t.IsUnion() {
+		alternatives := make([]string, 0, len(t.alternatives))
+		for _, alt := range t.alternatives {
+			alternatives = append(alternatives, alt.String())
+		}
+		return strings.Join(alternatives, " | ")
+	}
+	if len(t.params) == 0 {
+		return t.name
+	}
+	params := make([]string, 0, len(t.params))
+	for _, p := range t.params {
+		params = append(params, p.String())
+	}
+	return t.name + "[" + strings.Join(params, ", ") + "]"
+}
@@ -87,6 +87,9 @@ func Set(ctx context.Context, args ...object.Object) object.Object {
 			return res
 		}
 	}
+	if err := object.IteratorErr(iter); err != nil {
+		return err
+	}
 	return set
 }
 
@@ -121,6 +124,9 @@ func List(ctx context.Context, args ...object.Object) object.Object {
 		}
 		items = append(items, val)
 	}
+	if err := object.IteratorErr(iter); err != nil {
+		return err
+	}
 	return object.NewList(items)
 }
 
@@ -166,6 +172,9 @@ func Map(ctx context.Context, args ...object.Object) object.Object {
 			result.Set(k.Inspect(), v)
 		}
 	}
+	if err := object.IteratorErr(iter); err != nil {
+		return err
+	}
 	return result
 }
 
@@ -372,6 +381,9 @@ func Any(ctx context.Context, args ...object.Object) object.Object {
 				return object.True
 			}
 		}
+		if err := object.IteratorErr(iter); err != nil {
+			return err
+		}
 	case object.Iterator:
 		for {
 			val, ok := arg.Next(ctx)
@@ -382,6 +394,9 @@ func Any(ctx context.Context, args ...object.Object) object.Object {
 				return object.True
 			}
 		}
+		if err := object.IteratorErr(arg); err != nil {
+			return err
+		}
 	default:
 		return object.TypeErrorf("type error: any() argument must be a container (%s given)", args[0].Type())
 	}
@@ -428,6 +443,9 @@ func All(ctx context.Context, args ...object.Object) object.Object {
 				return object.False
 			}
 		}
+		if err := object.IteratorErr(iter); err != nil {
+			return err
+		}
 	case object.Iterator:
 		for {
 			val, ok := arg.Next(ctx)
@@ -438,6 +456,9 @@ func All(ctx context.Context, args ...object.Object) object.Object {
 				return object.False
 			}
 		}
+		if err := object.IteratorErr(arg); err != nil {
+			return err
+		}
 	default:
 		return object.TypeErrorf("type error: all() argument must be a container (%s given)", args[0].Type())
 	}
@@ -474,6 +495,17 @@ func Sorted(ctx context.Context, args ...object.Object) object.Object {
 		items = arg.Runes()
 	case *object.ByteSlice:
 		items = arg.Integers()
+	case object.Iterator:
+		for {
+			val, ok := arg.Next(ctx)
+			if !ok {
+				break
+			}
+			items = append(items, val)
+		}
+		if err := object.IteratorErr(arg); err != nil {
+			return err
+		}
 	default:
 		return object.TypeErrorf("type error: sorted() unsupported argument (%s given)", arg.Type())
 	}
@@ -597,6 +629,9 @@ func iterKeys(ctx context.Context, iter object.Iterator) object.Object {
 		entry, _ := iter.Entry()
 		keys = append(keys, entry.Key())
 	}
+	if err := object.IteratorErr(iter); err != nil {
+		return err
+	}
 	return object.NewList(keys)
 }
 
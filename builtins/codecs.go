@@ -2,7 +2,10 @@ package builtins
 
 import (
 	"bytes"
+	"compress/bzip2"
+	"compress/flate"
 	"compress/gzip"
+	"compress/zlib"
 	"context"
 	"encoding/base32"
 	"encoding/base64"
@@ -16,6 +19,7 @@ import (
 	"sync"
 
 	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/limits"
 	"github.com/itrn0/risor/object"
 	"gopkg.in/yaml.v3"
 )
@@ -40,6 +44,9 @@ func init() {
 	RegisterCodec("csv", &Codec{Encode: encodeCsv, Decode: decodeCsv})
 	RegisterCodec("urlquery", &Codec{Encode: encodeUrlQuery, Decode: decodeUrlQuery})
 	RegisterCodec("gzip", &Codec{Encode: encodeGzip, Decode: decodeGzip})
+	RegisterCodec("zlib", &Codec{Encode: encodeZlib, Decode: decodeZlib})
+	RegisterCodec("flate", &Codec{Encode: encodeFlate, Decode: decodeFlate})
+	RegisterCodec("bzip2", &Codec{Encode: encodeBzip2, Decode: decodeBzip2})
 }
 
 // RegisterCodec registers a new codec
@@ -97,6 +104,46 @@ func encodeGzip(ctx context.Context, obj object.Object) object.Object {
 	return object.NewByteSlice(buf.Bytes())
 }
 
+func encodeZlib(ctx context.Context, obj object.Object) object.Object {
+	data, err := object.AsBytes(obj)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	writer := zlib.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return object.NewError(err)
+	}
+	if err := writer.Close(); err != nil {
+		return object.NewError(err)
+	}
+	return object.NewByteSlice(buf.Bytes())
+}
+
+func encodeFlate(ctx context.Context, obj object.Object) object.Object {
+	data, err := object.AsBytes(obj)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	writer, flateErr := flate.NewWriter(&buf, flate.DefaultCompression)
+	if flateErr != nil {
+		return object.NewError(flateErr)
+	}
+	if _, err := writer.Write(data); err != nil {
+		return object.NewError(err)
+	}
+	if err := writer.Close(); err != nil {
+		return object.NewError(err)
+	}
+	return object.NewByteSlice(buf.Bytes())
+}
+
+// The standard library only implements bzip2 decompression
+func encodeBzip2(ctx context.Context, obj object.Object) object.Object {
+	return object.Errorf("value error: encode() does not support bzip2 compression")
+}
+
 func encodeBase64(ctx context.Context, obj object.Object) object.Object {
 	data, err := object.AsBytes(obj)
 	if err != nil {
@@ -250,6 +297,15 @@ func Decode(ctx context.Context, args ...object.Object) object.Object {
 	return codec.Decode(ctx, args[0])
 }
 
+// Reads decompressed data, up to the buffer size limit of the context. Small
+// inputs may decompress to very large outputs.
+func readDecompressed(ctx context.Context, r io.Reader) ([]byte, error) {
+	if lim, ok := limits.GetLimits(ctx); ok && lim.MaxBufferSize() > 0 {
+		return limits.ReadAll(r, lim.MaxBufferSize())
+	}
+	return io.ReadAll(r)
+}
+
 func decodeGzip(ctx context.Context, obj object.Object) object.Object {
 	data, errObj := object.AsBytes(obj)
 	if errObj != nil {
@@ -260,7 +316,47 @@ func decodeGzip(ctx context.Context, obj object.Object) object.Object {
 	if err != nil {
 		return object.NewError(err)
 	}
-	output, err := io.ReadAll(gzreader)
+	output, err := readDecompressed(ctx, gzreader)
+	if err != nil {
+		return object.NewError(err)
+	}
+	return object.NewByteSlice(output)
+}
+
+func decodeZlib(ctx context.Context, obj object.Object) object.Object {
+	data, errObj := object.AsBytes(obj)
+	if errObj != nil {
+		return errObj
+	}
+	zreader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return object.NewError(err)
+	}
+	output, err := readDecompressed(ctx, zreader)
+	if err != nil {
+		return object.NewError(err)
+	}
+	return object.NewByteSlice(output)
+}
+
+func decodeFlate(ctx context.Context, obj object.Object) object.Object {
+	data, errObj := object.AsBytes(obj)
+	if errObj != nil {
+		return errObj
+	}
+	output, err := readDecompressed(ctx, flate.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return object.NewError(err)
+	}
+	return object.NewByteSlice(output)
+}
+
+func decodeBzip2(ctx context.Context, obj object.Object) object.Object {
+	data, errObj := object.AsBytes(obj)
+	if errObj != nil {
+		return errObj
+	}
+	output, err := readDecompressed(ctx, bzip2.NewReader(bytes.NewReader(data)))
 	if err != nil {
 		return object.NewError(err)
 	}
@@ -2,8 +2,10 @@ package builtins
 
 import (
 	"context"
+	"strings"
 	"testing"
 
+	"github.com/itrn0/risor/limits"
 	"github.com/itrn0/risor/object"
 	"github.com/stretchr/testify/require"
 )
@@ -14,6 +16,8 @@ func TestBinaryCodecs(t *testing.T) {
 		"base32",
 		"hex",
 		"gzip",
+		"zlib",
+		"flate",
 	}
 	ctx := context.Background()
 	value := "Farfalle"
@@ -33,6 +37,43 @@ func TestBinaryCodecs(t *testing.T) {
 	}
 }
 
+func TestBzip2Codec(t *testing.T) {
+	ctx := context.Background()
+	// "hello\n" compressed with bzip2
+	compressed := []byte{
+		0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xc1, 0xc0,
+		0x80, 0xe2, 0x00, 0x00, 0x01, 0x41, 0x00, 0x00, 0x10, 0x02, 0x44, 0xa0,
+		0x00, 0x30, 0xcd, 0x00, 0xc3, 0x46, 0x29, 0x97, 0x17, 0x72, 0x45, 0x38,
+		0x50, 0x90, 0xc1, 0xc0, 0x80, 0xe2,
+	}
+	decoded := Decode(ctx, object.NewByteSlice(compressed), object.NewString("bzip2"))
+	require.Equal(t, object.NewByteSlice([]byte("hello\n")), decoded)
+
+	decoded = Decode(ctx, object.NewString("not bzip2"), object.NewString("bzip2"))
+	require.IsType(t, &object.Error{}, decoded)
+
+	encoded := Encode(ctx, object.NewString("hello"), object.NewString("bzip2"))
+	errObj, ok := encoded.(*object.Error)
+	require.True(t, ok)
+	require.Equal(t, "value error: encode() does not support bzip2 compression", errObj.Value().Error())
+}
+
+func TestDecompressionLimit(t *testing.T) {
+	ctx := limits.WithLimits(context.Background(), limits.New(limits.WithMaxBufferSize(100)))
+	value := object.NewString(strings.Repeat("a", 1000))
+	for _, codec := range []string{"gzip", "zlib", "flate"} {
+		t.Run(codec, func(t *testing.T) {
+			codecName := object.NewString(codec)
+			encoded := Encode(context.Background(), value, codecName)
+			require.IsType(t, &object.ByteSlice{}, encoded)
+			decoded := Decode(ctx, encoded, codecName)
+			errObj, ok := decoded.(*object.Error)
+			require.True(t, ok)
+			require.Equal(t, "limit error: data size exceeded limit of 100 bytes", errObj.Value().Error())
+		})
+	}
+}
+
 func TestUnknownCodec(t *testing.T) {
 	ctx := context.Background()
 	encoded := Encode(ctx, object.NewString("oops"), object.NewString("unknown"))
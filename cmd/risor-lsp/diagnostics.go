@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/itrn0/risor"
+	"github.com/itrn0/risor/ast"
+	"github.com/itrn0/risor/compiler"
+	"github.com/itrn0/risor/parser"
+	"github.com/itrn0/risor/token"
+	"github.com/jdbaldry/go-language-server-protocol/lsp/protocol"
+	"github.com/rs/zerolog/log"
+)
+
+const diagnosticSource = "risor"
+
+// Computes the diagnostics for the document and publishes them to the client.
+func (s *Server) queueDiagnostics(uri protocol.DocumentURI) {
+	doc, err := s.cache.get(uri)
+	if err != nil {
+		log.Error().Err(err).Str("call", "queueDiagnostics").Msg("failed to get document")
+		return
+	}
+	doc.diagnostics = diagnose(context.Background(), doc.item.Text)
+	if s.client == nil {
+		return
+	}
+	err = s.client.PublishDiagnostics(context.Background(), &protocol.PublishDiagnosticsParams{
+		URI:         uri,
+		Version:     doc.item.Version,
+		Diagnostics: doc.diagnostics,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("call", "queueDiagnostics").Msg("failed to publish diagnostics")
+	}
+}
+
+// Returns the diagnostics for the given source code. If the code doesn't
+// parse, only the parse error is reported. Otherwise the first compile error
+// is reported, along with unused names and calls with the wrong number of
+// arguments.
+func diagnose(ctx context.Context, text string) []protocol.Diagnostic {
+	src := newSource(text)
+	program, err := parser.Parse(ctx, text)
+	if err != nil {
+		var parseErr parser.ParserError
+		if errors.As(err, &parseErr) {
+			return []protocol.Diagnostic{
+				src.diagnostic(parseErr.StartPosition(), parseErr.EndPosition(), protocol.SeverityError, err.Error()),
+			}
+		}
+		return []protocol.Diagnostic{src.diagnostic(token.Position{}, token.Position{}, protocol.SeverityError, err.Error())}
+	}
+	// Diagnostics are never nil, so that publishing them clears old ones
+	diagnostics := []protocol.Diagnostic{}
+	diagnostics = append(diagnostics, compileDiagnostics(src, program)...)
+	res := resolve(program)
+	diagnostics = append(diagnostics, unusedDiagnostics(src, res)...)
+	diagnostics = append(diagnostics, callDiagnostics(src, res)...)
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i].Range.Start, diagnostics[j].Range.Start
+		return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+	})
+	return diagnostics
+}
+
+// Compiles the program with the default builtins and modules, returning a
+// diagnostic for the first compile error, if any, or otherwise for each of
+// the compiler's warnings.
+func compileDiagnostics(src *source, program *ast.Program) []protocol.Diagnostic {
+	c, err := compiler.New(risor.NewConfig().CompilerOpts()...)
+	if err == nil {
+		_, err = c.Compile(program)
+	}
+	if err != nil {
+		var compileErr *compiler.CompileError
+		if errors.As(err, &compileErr) {
+			return []protocol.Diagnostic{
+				src.diagnostic(compileErr.StartPosition(), compileErr.EndPosition(), protocol.SeverityError, err.Error()),
+			}
+		}
+		return []protocol.Diagnostic{src.diagnostic(token.Position{}, token.Position{}, protocol.SeverityError, err.Error())}
+	}
+	var diagnostics []protocol.Diagnostic
+	for _, warning := range c.Warnings() {
+		diagnostics = append(diagnostics, src.diagnostic(warning.StartPosition(), warning.EndPosition(),
+			protocol.SeverityWarning, warning.Error()))
+	}
+	return diagnostics
+}
+
+// Returns warnings for imports that are never used and for variables defined
+// in functions that are never read. Variables defined at the top level may be
+// read by the host application, so they aren't reported.
+func unusedDiagnostics(src *source, res *resolution) []protocol.Diagnostic {
+	var diagnostics []protocol.Diagnostic
+	for _, sym := range res.symbols {
+		if sym.used || sym.name == "_" {
+			continue
+		}
+		var msg string
+		switch {
+		case sym.kind == importSymbol:
+			msg = fmt.Sprintf("%q imported and not used", sym.name)
+		case sym.kind == variableSymbol && !sym.global:
+			msg = fmt.Sprintf("declared and not used: %s", sym.name)
+		default:
+			continue
+		}
+		d := src.diagnostic(sym.def.StartPosition, sym.def.EndPosition, protocol.SeverityWarning, msg)
+		d.Tags = []protocol.DiagnosticTag{protocol.Unnecessary}
+		diagnostics = append(diagnostics, d)
+	}
+	return diagnostics
+}
+
+// Returns errors for calls to functions defined in the program that pass the
+// wrong number of arguments. These calls would fail when run. Functions are
+// only checked if the name they're bound to is never reassigned.
+func callDiagnostics(src *source, res *resolution) []protocol.Diagnostic {
+	var diagnostics []protocol.Diagnostic
+	for _, c := range res.calls {
+		if c.symbol.assigned {
+			continue
+		}
+		fn := c.symbol.fn
+		argc := len(c.node.Arguments())
+		paramsCount := len(fn.Parameters())
+		required := paramsCount - len(fn.Defaults())
+		if argc <= paramsCount && argc >= required {
+			continue
+		}
+		// The message matches the error raised when the call is made
+		msg := "args error: function"
+		if name := fn.Name(); name != nil {
+			msg = fmt.Sprintf("%s %q", msg, name.Literal())
+		}
+		switch paramsCount {
+		case 0:
+			msg = fmt.Sprintf("%s takes 0 arguments (%d given)", msg, argc)
+		case 1:
+			msg = fmt.Sprintf("%s takes 1 argument (%d given)", msg, argc)
+		default:
+			msg = fmt.Sprintf("%s takes %d arguments (%d given)", msg, paramsCount, argc)
+		}
+		tok := c.node.Function().Token()
+		diagnostics = append(diagnostics, src.diagnostic(tok.StartPosition, tok.EndPosition, protocol.SeverityError, msg))
+	}
+	return diagnostics
+}
+
+func (s *source) diagnostic(start, end token.Position, severity protocol.DiagnosticSeverity, msg string) protocol.Diagnostic {
+	return protocol.Diagnostic{
+		Range:    s.span(start, end),
+		Severity: severity,
+		Source:   diagnosticSource,
+		Message:  msg,
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jdbaldry/go-language-server-protocol/lsp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func span(startLine, startChar, endLine, endChar uint32) protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{Line: startLine, Character: startChar},
+		End:   protocol.Position{Line: endLine, Character: endChar},
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	type expected struct {
+		rng      protocol.Range
+		severity protocol.DiagnosticSeverity
+		message  string
+	}
+	tests := []struct {
+		name     string
+		input    string
+		expected []expected
+	}{
+		{
+			"valid",
+			"x := 1\nprint(x + len([1]))",
+			nil,
+		},
+		{
+			"parse error",
+			"x := 1\nfunc f( {",
+			[]expected{{span(1, 8, 1, 9), protocol.SeverityError, "parse error: expected an identifier (got {)"}},
+		},
+		{
+			"undefined variable",
+			"x := 1\n  y = 2",
+			[]expected{{span(1, 2, 1, 3), protocol.SeverityError, `compile error: undefined variable "y" (line 2)`}},
+		},
+		{
+			"assignment to constant",
+			"const c = 1\nc = 2",
+			[]expected{{span(1, 0, 1, 1), protocol.SeverityError, `compile error: cannot assign to constant "c" (line 2)`}},
+		},
+		{
+			"unused names",
+			"import json\nimport strings as s\nx := 1\nfunc f() {\n  y := s.to_upper('a')\n  _ := 2\n  for i, v := range [1] { print(v) }\n}",
+			[]expected{
+				{span(0, 7, 0, 11), protocol.SeverityWarning, `"json" imported and not used`},
+				{span(4, 2, 4, 3), protocol.SeverityWarning, "declared and not used: y"},
+				{span(6, 6, 6, 7), protocol.SeverityWarning, "declared and not used: i"},
+			},
+		},
+		{
+			"used names",
+			"func f(a) {\n  x := 1\n  x += 1\n  y := 0\n  y++\n  z := 2\n  return func() { return z }\n}",
+			nil,
+		},
+		{
+			"argument count",
+			"func f(a, b=1) { return a }\nf()\nf(1, 2, 3)\nf(1)\ng := func() {}\ng(1)\n[1] | f(2)",
+			[]expected{
+				{span(1, 0, 1, 1), protocol.SeverityError, `args error: function "f" takes 2 arguments (0 given)`},
+				{span(2, 0, 2, 1), protocol.SeverityError, `args error: function "f" takes 2 arguments (3 given)`},
+				{span(5, 0, 5, 1), protocol.SeverityError, "args error: function takes 0 arguments (1 given)"},
+			},
+		},
+		{
+			"reassigned functions aren't checked",
+			"f := func(a) {}\nf = func() {}\nf()",
+			nil,
+		},
+		{
+			"shadowed functions aren't checked",
+			"func f(a) {}\nfunc g() {\n  f := func() {}\n  f()\n}",
+			nil,
+		},
+		{
+			"match",
+			"func f(v) {\n  return match v {\n  case [a, b]: a\n  }\n}",
+			[]expected{
+				{span(1, 9, 1, 14), protocol.SeverityWarning, "match is not exhaustive: add a default case or a case that matches all values"},
+				{span(2, 11, 2, 12), protocol.SeverityWarning, "declared and not used: b"},
+			},
+		},
+		{
+			"select",
+			"func f(c) {\n  select {\n  case v, ok := <-c: v\n  }\n}",
+			[]expected{
+				{span(2, 10, 2, 12), protocol.SeverityWarning, "declared and not used: ok"},
+			},
+		},
+		{
+			"utf-16 ranges",
+			"s := \"😀\"; func f() {\n  s := \"é😀\"; y := 1\n}",
+			[]expected{
+				{span(1, 2, 1, 3), protocol.SeverityWarning, "declared and not used: s"},
+				{span(1, 14, 1, 15), protocol.SeverityWarning, "declared and not used: y"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := diagnose(context.Background(), tt.input)
+			require.Len(t, diagnostics, len(tt.expected))
+			for i, d := range diagnostics {
+				require.Equal(t, tt.expected[i].rng, d.Range)
+				require.Equal(t, tt.expected[i].severity, d.Severity)
+				require.Equal(t, tt.expected[i].message, d.Message)
+				require.Equal(t, "risor", d.Source)
+			}
+		})
+	}
+}
+
+func TestDiagnosticsOnChange(t *testing.T) {
+	ctx := context.Background()
+	s := &Server{cache: newCache()}
+	uri := protocol.DocumentURI("file:///test.risor")
+	require.Nil(t, s.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: uri, Version: 1, Text: "x := y\n"},
+	}))
+	doc, err := s.cache.get(uri)
+	require.Nil(t, err)
+	require.Len(t, doc.diagnostics, 1)
+	require.Equal(t, span(0, 5, 0, 6), doc.diagnostics[0].Range)
+
+	require.Nil(t, s.DidChange(ctx, &protocol.DidChangeTextDocumentParams{
+		TextDocument: protocol.VersionedTextDocumentIdentifier{
+			Version:                2,
+			TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri},
+		},
+		ContentChanges: []protocol.TextDocumentContentChangeEvent{{Text: "y := 1\nx := y\n"}},
+	}))
+	doc, err = s.cache.get(uri)
+	require.Nil(t, err)
+	require.NotNil(t, doc.diagnostics)
+	require.Empty(t, doc.diagnostics)
+}
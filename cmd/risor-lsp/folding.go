@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/itrn0/risor/lexer"
+	"github.com/itrn0/risor/token"
+	"github.com/jdbaldry/go-language-server-protocol/lsp/protocol"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *Server) FoldingRange(ctx context.Context, params *protocol.FoldingRangeParams) ([]protocol.FoldingRange, error) {
+	doc, err := s.cache.get(params.TextDocument.URI)
+	if err != nil {
+		log.Error().Err(err).Str("call", "FoldingRange").Msg("failed to get document")
+		return nil, nil
+	}
+	return foldingRanges(doc.item.Text), nil
+}
+
+// Returns the folding ranges of the text: blocks, maps, lists and other code
+// in braces or brackets that spans multiple lines, and comments that span
+// multiple lines. The text is lexed rather than parsed, so that folding works
+// while the code is being edited. The closing bracket of a range is left
+// visible when the range is folded.
+func foldingRanges(text string) []protocol.FoldingRange {
+	l := lexer.New(text)
+	var ranges []protocol.FoldingRange
+	var open []token.Token
+	for {
+		tok, err := l.Next()
+		if err != nil || tok.Type == token.EOF {
+			break
+		}
+		switch tok.Type {
+		case token.LBRACE, token.LBRACKET:
+			open = append(open, tok)
+		case token.RBRACE, token.RBRACKET:
+			if len(open) == 0 {
+				continue
+			}
+			start := open[len(open)-1]
+			open = open[:len(open)-1]
+			if end := tok.StartPosition.Line - 1; end > start.StartPosition.Line {
+				ranges = append(ranges, protocol.FoldingRange{
+					StartLine: uint32(start.StartPosition.Line),
+					EndLine:   uint32(end),
+				})
+			}
+		}
+	}
+	ranges = append(ranges, commentRanges(l.Comments())...)
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].StartLine < ranges[j].StartLine
+	})
+	return ranges
+}
+
+// Returns ranges for block comments that span multiple lines and for runs of
+// line comments on consecutive lines.
+func commentRanges(comments []token.Token) []protocol.FoldingRange {
+	var ranges []protocol.FoldingRange
+	add := func(start, end int) {
+		if end > start {
+			ranges = append(ranges, protocol.FoldingRange{
+				StartLine: uint32(start),
+				EndLine:   uint32(end),
+				Kind:      string(protocol.Comment),
+			})
+		}
+	}
+	runStart, runEnd := -1, -1
+	for _, c := range comments {
+		if strings.HasPrefix(c.Literal, "/*") {
+			add(runStart, runEnd)
+			runStart, runEnd = -1, -1
+			add(c.StartPosition.Line, c.EndPosition.Line)
+			continue
+		}
+		line := c.StartPosition.Line
+		if runEnd >= 0 && line == runEnd+1 {
+			runEnd = line
+			continue
+		}
+		add(runStart, runEnd)
+		runStart, runEnd = line, line
+	}
+	add(runStart, runEnd)
+	return ranges
+}
@@ -2,10 +2,51 @@ package main
 
 import (
 	"context"
+	"strings"
+	"unicode/utf16"
 
+	"github.com/itrn0/risor/printer"
 	"github.com/jdbaldry/go-language-server-protocol/lsp/protocol"
+	"github.com/rs/zerolog/log"
 )
 
 func (s *Server) Formatting(ctx context.Context, params *protocol.DocumentFormattingParams) ([]protocol.TextEdit, error) {
-	return nil, nil
+	doc, err := s.cache.get(params.TextDocument.URI)
+	if err != nil {
+		log.Error().Err(err).Str("call", "Formatting").Msg("failed to get document")
+		return nil, nil
+	}
+	return formatDocument(ctx, doc.item.Text)
+}
+
+// Returns the edits that format the given text, which replace the whole
+// document if its formatting changes. Text that can't be parsed is left as
+// it is.
+func formatDocument(ctx context.Context, text string) ([]protocol.TextEdit, error) {
+	formatted, err := printer.Format(ctx, text)
+	if err != nil {
+		log.Error().Err(err).Str("call", "Formatting").Msg("failed to format document")
+		return nil, nil
+	}
+	if formatted == text {
+		return nil, nil
+	}
+	return []protocol.TextEdit{{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: 0, Character: 0},
+			End:   endPosition(text),
+		},
+		NewText: formatted,
+	}}, nil
+}
+
+// Returns the position of the end of the text. Characters are counted in
+// UTF-16 code units, as the protocol requires.
+func endPosition(text string) protocol.Position {
+	line := strings.Count(text, "\n")
+	last := text[strings.LastIndex(text, "\n")+1:]
+	return protocol.Position{
+		Line:      uint32(line),
+		Character: uint32(len(utf16.Encode([]rune(last)))),
+	}
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jdbaldry/go-language-server-protocol/lsp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatting(t *testing.T) {
+	ctx := context.Background()
+	s := &Server{cache: newCache()}
+	uri := protocol.DocumentURI("file:///test.risor")
+	require.Nil(t, s.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: uri, Version: 1, Text: "x := 1\n"},
+	}))
+	params := &protocol.DocumentFormattingParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	}
+	edits, err := s.Formatting(ctx, params)
+	require.Nil(t, err)
+	require.Empty(t, edits)
+
+	// Formatting uses the latest text of the document
+	require.Nil(t, s.DidChange(ctx, &protocol.DidChangeTextDocumentParams{
+		TextDocument: protocol.VersionedTextDocumentIdentifier{
+			Version:                2,
+			TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri},
+		},
+		ContentChanges: []protocol.TextDocumentContentChangeEvent{
+			{Text: "x:=1 // one\ny:=\"é😀\""},
+		},
+	}))
+	edits, err = s.Formatting(ctx, params)
+	require.Nil(t, err)
+	require.Equal(t, []protocol.TextEdit{{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: 0, Character: 0},
+			End:   protocol.Position{Line: 1, Character: 8},
+		},
+		NewText: "x := 1 // one\ny := \"é😀\"\n",
+	}}, edits)
+
+	// Documents that don't parse aren't formatted
+	edits, err = formatDocument(ctx, "x := ")
+	require.Nil(t, err)
+	require.Empty(t, edits)
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/itrn0/risor/lexer"
+	"github.com/itrn0/risor/token"
+	"github.com/jdbaldry/go-language-server-protocol/lsp/protocol"
+	"github.com/rs/zerolog/log"
+)
+
+// target is a symbol that's renamed or whose references are found, along
+// with the file that defines it.
+type target struct {
+	file   *file
+	symbol *symbol
+}
+
+// occurrence is an identifier that refers to a target.
+type occurrence struct {
+	file  *file
+	tok   token.Token
+	write bool
+	def   bool
+}
+
+// Returns the target at the given position in a document, along with the
+// token found there. Globals of modules that are referred to through imports
+// are found in the files that define them.
+func (w *workspace) targetAt(uri protocol.DocumentURI, pos protocol.Position) (target, token.Token, bool) {
+	f, err := w.file(uri)
+	if err != nil || f.res == nil {
+		return target{}, token.Token{}, false
+	}
+	char := f.src.offset(pos)
+	sym, tok, ok := f.res.symbolAt(char)
+	if !ok && char > 0 {
+		// The position may be just after the identifier
+		sym, tok, ok = f.res.symbolAt(char - 1)
+	}
+	if ok {
+		// A name imported with "from m import name" refers to the global
+		// defined by the module, unless it was given an alias
+		if sym.attr != "" && sym.name == sym.attr {
+			if t, ok := w.global(sym.module, sym.attr); ok {
+				return t, tok, true
+			}
+		}
+		return target{file: f, symbol: sym}, tok, true
+	}
+	attr, ok := f.res.attributeAt(char)
+	if !ok && char > 0 {
+		attr, ok = f.res.attributeAt(char - 1)
+	}
+	if ok {
+		if t, ok := w.global(attr.module, attr.name); ok {
+			return t, attr.tok, true
+		}
+	}
+	return target{}, token.Token{}, false
+}
+
+// Returns the global with the given name that's defined by a module.
+func (w *workspace) global(module, name string) (target, bool) {
+	f, ok := w.module(module)
+	if !ok || f.res == nil {
+		return target{}, false
+	}
+	sym, ok := f.res.global(name)
+	if !ok {
+		return target{}, false
+	}
+	return target{file: f, symbol: sym}, true
+}
+
+// Returns the identifiers that refer to the target. A global defined by a
+// module may be referred to by the given files, either as an attribute of the
+// imported module or as a name imported with "from m import name".
+func occurrences(t target, files []*file) []occurrence {
+	sym := t.symbol
+	result := []occurrence{{file: t.file, tok: sym.def, write: true, def: true}}
+	for _, ref := range sym.refs {
+		result = append(result, occurrence{file: t.file, tok: ref.tok, write: ref.write})
+	}
+	if !sym.global || t.file.module == "" {
+		return dedupe(result)
+	}
+	for _, f := range files {
+		if f.res == nil {
+			continue
+		}
+		for _, attr := range f.res.attrs {
+			if attr.module == t.file.module && attr.name == sym.name {
+				result = append(result, occurrence{file: f, tok: attr.tok, write: attr.write})
+			}
+		}
+		for _, imported := range f.res.symbols {
+			if imported.module != t.file.module || imported.attr != sym.name || imported.name != sym.name {
+				continue
+			}
+			result = append(result, occurrence{file: f, tok: imported.def})
+			for _, ref := range imported.refs {
+				result = append(result, occurrence{file: f, tok: ref.tok, write: ref.write})
+			}
+		}
+	}
+	return dedupe(result)
+}
+
+// Removes repeated occurrences, e.g. a name imported with "from m import
+// name" is both a reference to the module's global and a definition.
+func dedupe(occs []occurrence) []occurrence {
+	type key struct {
+		uri  protocol.DocumentURI
+		char int
+	}
+	seen := map[key]bool{}
+	var result []occurrence
+	for _, o := range occs {
+		k := key{o.file.uri, o.tok.StartPosition.Char}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		result = append(result, o)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].file.uri != result[j].file.uri {
+			return result[i].file.uri < result[j].file.uri
+		}
+		return result[i].tok.StartPosition.Char < result[j].tok.StartPosition.Char
+	})
+	return result
+}
+
+// Returns an error if the target can't be renamed.
+func checkRename(t target) error {
+	sym := t.symbol
+	if sym.kind != importSymbol {
+		return nil
+	}
+	if sym.attr != "" && sym.name == sym.attr {
+		return fmt.Errorf("cannot rename %q, which is imported from %q", sym.name, sym.module)
+	}
+	if sym.attr == "" && sym.name == sym.imported {
+		return fmt.Errorf("cannot rename imported module %q", sym.name)
+	}
+	return nil
+}
+
+// Returns an error if the name isn't a valid identifier.
+func checkIdentifier(name string) error {
+	l := lexer.New(name)
+	tok, err := l.Next()
+	if err == nil && tok.Type == token.IDENT && tok.Literal == name {
+		if next, err := l.Next(); err == nil && next.Type == token.EOF {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not a valid identifier", name)
+}
+
+func (s *Server) PrepareRename(ctx context.Context, params *protocol.PrepareRenameParams) (*protocol.Range, error) {
+	w := s.workspace(ctx, params.TextDocument.URI)
+	t, tok, ok := w.targetAt(params.TextDocument.URI, params.Position)
+	if !ok {
+		return nil, nil
+	}
+	if err := checkRename(t); err != nil {
+		return nil, err
+	}
+	f, err := w.file(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	r := f.src.tokenRange(tok)
+	return &r, nil
+}
+
+func (s *Server) Rename(ctx context.Context, params *protocol.RenameParams) (*protocol.WorkspaceEdit, error) {
+	if err := checkIdentifier(params.NewName); err != nil {
+		return nil, err
+	}
+	w := s.workspace(ctx, params.TextDocument.URI)
+	t, _, ok := w.targetAt(params.TextDocument.URI, params.Position)
+	if !ok {
+		return nil, errors.New("no name to rename at this position")
+	}
+	if err := checkRename(t); err != nil {
+		return nil, err
+	}
+	changes := map[string][]protocol.TextEdit{}
+	for _, o := range occurrences(t, w.all()) {
+		uri := string(o.file.uri)
+		changes[uri] = append(changes[uri], protocol.TextEdit{
+			Range:   o.file.src.tokenRange(o.tok),
+			NewText: params.NewName,
+		})
+	}
+	log.Info().Str("call", "Rename").Int("files", len(changes)).Msg("renamed")
+	return &protocol.WorkspaceEdit{Changes: changes}, nil
+}
+
+func (s *Server) References(ctx context.Context, params *protocol.ReferenceParams) ([]protocol.Location, error) {
+	w := s.workspace(ctx, params.TextDocument.URI)
+	t, _, ok := w.targetAt(params.TextDocument.URI, params.Position)
+	if !ok {
+		return nil, nil
+	}
+	var locations []protocol.Location
+	for _, o := range occurrences(t, w.all()) {
+		if o.def && !params.Context.IncludeDeclaration {
+			continue
+		}
+		locations = append(locations, protocol.Location{
+			URI:   o.file.uri,
+			Range: o.file.src.tokenRange(o.tok),
+		})
+	}
+	return locations, nil
+}
+
+func (s *Server) DocumentHighlight(ctx context.Context, params *protocol.DocumentHighlightParams) ([]protocol.DocumentHighlight, error) {
+	uri := params.TextDocument.URI
+	w := s.workspace(ctx, uri)
+	t, _, ok := w.targetAt(uri, params.Position)
+	if !ok {
+		return nil, nil
+	}
+	f, err := w.file(uri)
+	if err != nil {
+		return nil, err
+	}
+	// Only the document itself needs to be searched
+	var highlights []protocol.DocumentHighlight
+	for _, o := range occurrences(t, []*file{f}) {
+		if o.file.uri != uri {
+			continue
+		}
+		kind := protocol.Read
+		if o.write {
+			kind = protocol.Write
+		}
+		highlights = append(highlights, protocol.DocumentHighlight{
+			Range: f.src.tokenRange(o.tok),
+			Kind:  kind,
+		})
+	}
+	return highlights, nil
+}
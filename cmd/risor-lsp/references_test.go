@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jdbaldry/go-language-server-protocol/lsp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func openDocument(t *testing.T, s *Server, uri protocol.DocumentURI, text string) {
+	t.Helper()
+	require.Nil(t, s.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: uri, Version: 1, Text: text},
+	}))
+}
+
+func position(line, char uint32) protocol.TextDocumentPositionParams {
+	return protocol.TextDocumentPositionParams{Position: protocol.Position{Line: line, Character: char}}
+}
+
+func TestRenameLocal(t *testing.T) {
+	ctx := context.Background()
+	s := &Server{cache: newCache(), modulesDir: t.TempDir()}
+	uri := protocol.URIFromPath(filepath.Join(s.modulesDir, "main.risor"))
+	openDocument(t, s, uri, "x := 1\nfunc f(x) {\n  y := x\n  return func() { x = y; return x }\n}\nprint(x)")
+
+	// The parameter shadows the global, and is a free variable in the closure
+	params := &protocol.RenameParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Position:     protocol.Position{Line: 2, Character: 7},
+		NewName:      "value",
+	}
+	edit, err := s.Rename(ctx, params)
+	require.Nil(t, err)
+	require.Equal(t, map[string][]protocol.TextEdit{
+		string(uri): {
+			{Range: span(1, 7, 1, 8), NewText: "value"},
+			{Range: span(2, 7, 2, 8), NewText: "value"},
+			{Range: span(3, 18, 3, 19), NewText: "value"},
+			{Range: span(3, 32, 3, 33), NewText: "value"},
+		},
+	}, edit.Changes)
+
+	// The global is renamed where it isn't shadowed
+	params.Position = protocol.Position{Line: 5, Character: 6}
+	edit, err = s.Rename(ctx, params)
+	require.Nil(t, err)
+	require.Equal(t, map[string][]protocol.TextEdit{
+		string(uri): {
+			{Range: span(0, 0, 0, 1), NewText: "value"},
+			{Range: span(5, 6, 5, 7), NewText: "value"},
+		},
+	}, edit.Changes)
+
+	params.NewName = "for"
+	_, err = s.Rename(ctx, params)
+	require.EqualError(t, err, `"for" is not a valid identifier`)
+	params.NewName = "a b"
+	_, err = s.Rename(ctx, params)
+	require.EqualError(t, err, `"a b" is not a valid identifier`)
+}
+
+func TestRenameAcrossModules(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	lib := "func greet(name) {\n  return 'hi ' + name\n}\ncount := 0\n"
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "lib.risor"), []byte(lib), 0o644))
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "pkg"), 0o755))
+	other := "from lib import greet as hello\nhello('c')\n"
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "pkg", "other.risor"), []byte(other), 0o644))
+
+	s := &Server{cache: newCache(), modulesDir: dir}
+	libURI := protocol.URIFromPath(filepath.Join(dir, "lib.risor"))
+	mainURI := protocol.URIFromPath(filepath.Join(dir, "main.risor"))
+	otherURI := protocol.URIFromPath(filepath.Join(dir, "pkg", "other.risor"))
+	// The main script isn't saved, so it's only known from the open document
+	openDocument(t, s, mainURI, "import lib\nfrom lib import greet\nx := lib.greet('a')\nprint(x, greet('b'), lib.count)")
+
+	expected := map[string][]protocol.TextEdit{
+		string(libURI): {{Range: span(0, 5, 0, 10), NewText: "welcome"}},
+		string(mainURI): {
+			{Range: span(1, 16, 1, 21), NewText: "welcome"},
+			{Range: span(2, 9, 2, 14), NewText: "welcome"},
+			{Range: span(3, 9, 3, 14), NewText: "welcome"},
+		},
+		string(otherURI): {{Range: span(0, 16, 0, 21), NewText: "welcome"}},
+	}
+	// Renaming works the same from the definition and from any reference
+	for _, pos := range []struct {
+		uri protocol.DocumentURI
+		pos protocol.Position
+	}{
+		{libURI, protocol.Position{Line: 0, Character: 6}},
+		{mainURI, protocol.Position{Line: 2, Character: 10}},
+		{mainURI, protocol.Position{Line: 3, Character: 14}},
+		{otherURI, protocol.Position{Line: 0, Character: 18}},
+	} {
+		edit, err := s.Rename(ctx, &protocol.RenameParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: pos.uri},
+			Position:     pos.pos,
+			NewName:      "welcome",
+		})
+		require.Nil(t, err)
+		require.Equal(t, expected, edit.Changes)
+	}
+
+	locations, err := s.References(ctx, &protocol.ReferenceParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: mainURI},
+			Position:     protocol.Position{Line: 3, Character: 26},
+		},
+	})
+	require.Nil(t, err)
+	require.Equal(t, []protocol.Location{{URI: mainURI, Range: span(3, 25, 3, 30)}}, locations)
+
+	locations, err = s.References(ctx, &protocol.ReferenceParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: mainURI},
+			Position:     protocol.Position{Line: 3, Character: 26},
+		},
+		Context: protocol.ReferenceContext{IncludeDeclaration: true},
+	})
+	require.Nil(t, err)
+	require.Equal(t, []protocol.Location{
+		{URI: libURI, Range: span(3, 0, 3, 5)},
+		{URI: mainURI, Range: span(3, 25, 3, 30)},
+	}, locations)
+
+	// Imported modules can't be renamed
+	_, err = s.PrepareRename(ctx, &protocol.PrepareRenameParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: mainURI},
+			Position:     protocol.Position{Line: 2, Character: 6},
+		},
+	})
+	require.EqualError(t, err, `cannot rename imported module "lib"`)
+
+	rng, err := s.PrepareRename(ctx, &protocol.PrepareRenameParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: mainURI},
+			Position:     protocol.Position{Line: 2, Character: 12},
+		},
+	})
+	require.Nil(t, err)
+	require.Equal(t, span(2, 9, 2, 14), *rng)
+
+	// Builtins can't be renamed
+	rng, err = s.PrepareRename(ctx, &protocol.PrepareRenameParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: mainURI},
+			Position:     protocol.Position{Line: 3, Character: 1},
+		},
+	})
+	require.Nil(t, err)
+	require.Nil(t, rng)
+}
+
+func TestDocumentHighlight(t *testing.T) {
+	ctx := context.Background()
+	s := &Server{cache: newCache(), modulesDir: t.TempDir()}
+	uri := protocol.URIFromPath(filepath.Join(s.modulesDir, "main.risor"))
+	openDocument(t, s, uri, "func f() {\n  n := 0\n  n++\n  return n\n}")
+	params := &protocol.DocumentHighlightParams{TextDocumentPositionParams: position(3, 9)}
+	params.TextDocument.URI = uri
+	highlights, err := s.DocumentHighlight(ctx, params)
+	require.Nil(t, err)
+	require.Equal(t, []protocol.DocumentHighlight{
+		{Range: span(1, 2, 1, 3), Kind: protocol.Write},
+		{Range: span(2, 2, 2, 3), Kind: protocol.Write},
+		{Range: span(3, 9, 3, 10), Kind: protocol.Read},
+	}, highlights)
+
+	params.Position = protocol.Position{Line: 0, Character: 0}
+	highlights, err = s.DocumentHighlight(ctx, params)
+	require.Nil(t, err)
+	require.Empty(t, highlights)
+}
+
+func TestFoldingRanges(t *testing.T) {
+	text := `// one
+// two
+func f() {
+  x := {
+    "a": [
+      1,
+    ],
+  }
+  return [1, 2]
+}
+/* block
+   comment */
+if true { print(1) }
+y := [
+`
+	require.Equal(t, []protocol.FoldingRange{
+		{StartLine: 0, EndLine: 1, Kind: "comment"},
+		{StartLine: 2, EndLine: 8},
+		{StartLine: 3, EndLine: 6},
+		{StartLine: 4, EndLine: 5},
+		{StartLine: 10, EndLine: 11, Kind: "comment"},
+	}, foldingRanges(text))
+}
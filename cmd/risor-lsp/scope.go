@@ -0,0 +1,576 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/itrn0/risor/ast"
+	"github.com/itrn0/risor/compiler"
+	"github.com/itrn0/risor/token"
+)
+
+// symbolKind describes how a name was defined.
+type symbolKind int
+
+const (
+	variableSymbol symbolKind = iota
+	constantSymbol
+	parameterSymbol
+	functionSymbol
+	importSymbol
+	structSymbol
+)
+
+// symbol is a name defined in a document.
+type symbol struct {
+	name string
+	kind symbolKind
+
+	// The token of the identifier that defines the name
+	def token.Token
+
+	// The identifiers that refer to the name after its definition
+	refs []*reference
+
+	// True if the value of the name is read somewhere
+	used bool
+
+	// True if the name is assigned to after its definition
+	assigned bool
+
+	// True if the name is defined at the top level of the document, where it
+	// may be used by code that isn't visible here
+	global bool
+
+	// The function the name refers to, if it's known statically
+	fn *ast.Func
+
+	// For names imported with "from m import name", the module and the name
+	// of the attribute that was imported
+	module string
+	attr   string
+
+	// For imported names, the name of the module they may refer to
+	imported string
+}
+
+// reference is an identifier that refers to a symbol.
+type reference struct {
+	tok token.Token
+
+	// The scope of the symbol where it's referenced, as resolved by the
+	// compiler's symbol table
+	scope compiler.Scope
+
+	// True if the reference assigns to the symbol
+	write bool
+}
+
+// attribute is an identifier that refers to an attribute of a module, like
+// "b" in "a.b" where "a" is an imported module.
+type attribute struct {
+	module string
+	name   string
+	tok    token.Token
+	write  bool
+}
+
+// call is a call to a function that's referred to by name.
+type call struct {
+	node   *ast.Call
+	symbol *symbol
+}
+
+// resolution holds the symbols defined in a program and the references to
+// them.
+type resolution struct {
+	symbols []*symbol
+
+	// Attributes of imported modules that the program refers to
+	attrs []attribute
+
+	// Calls to functions defined in the program
+	calls []call
+
+	// References that don't refer to a name defined in the program, which are
+	// either builtins or undefined names
+	unresolved []token.Token
+}
+
+// resolver walks a program to resolve the names it refers to. Scopes are
+// tracked with the compiler's symbol tables, so that names resolve exactly as
+// they do when the program is compiled.
+type resolver struct {
+	table   *compiler.SymbolTable
+	symbols map[*compiler.Symbol]*symbol
+	refs    map[int]*reference
+	result  *resolution
+}
+
+// resolve returns the symbols defined in the program and their references.
+func resolve(program *ast.Program) *resolution {
+	r := &resolver{
+		table:   compiler.NewSymbolTable(),
+		symbols: map[*compiler.Symbol]*symbol{},
+		refs:    map[int]*reference{},
+		result:  &resolution{},
+	}
+	for _, stmt := range program.Statements() {
+		r.walk(stmt)
+	}
+	return r.result
+}
+
+// symbolAt returns the symbol that is defined or referenced at the given
+// offset in the source, along with the token found there.
+func (res *resolution) symbolAt(char int) (*symbol, token.Token, bool) {
+	for _, sym := range res.symbols {
+		if contains(sym.def, char) {
+			return sym, sym.def, true
+		}
+		for _, ref := range sym.refs {
+			if contains(ref.tok, char) {
+				return sym, ref.tok, true
+			}
+		}
+	}
+	return nil, token.Token{}, false
+}
+
+// attributeAt returns the module attribute referenced at the given offset.
+func (res *resolution) attributeAt(char int) (attribute, bool) {
+	for _, attr := range res.attrs {
+		if contains(attr.tok, char) {
+			return attr, true
+		}
+	}
+	return attribute{}, false
+}
+
+// global returns the symbol defined with the given name at the top level.
+func (res *resolution) global(name string) (*symbol, bool) {
+	for _, sym := range res.symbols {
+		if sym.global && sym.name == name {
+			return sym, true
+		}
+	}
+	return nil, false
+}
+
+func contains(tok token.Token, char int) bool {
+	return char >= tok.StartPosition.Char && char <= tok.EndPosition.Char
+}
+
+// Begins the scope of a function.
+func (r *resolver) function() {
+	r.table = r.table.NewChild()
+}
+
+// Begins the scope of a block within a function or the program.
+func (r *resolver) push() {
+	r.table = r.table.NewBlock()
+}
+
+func (r *resolver) pop() {
+	r.table = r.table.Parent()
+}
+
+// Defines a name in the current scope.
+func (r *resolver) define(ident *ast.Ident, kind symbolKind) *symbol {
+	tok := ident.Token()
+	insert := r.table.InsertConstant
+	if kind == variableSymbol || kind == parameterSymbol {
+		insert = r.table.InsertVariable
+	}
+	s, err := insert(tok.Literal)
+	if err != nil {
+		// The name is already defined in this scope, which is a compile
+		// error except for repeated imports. Treat it as an assignment.
+		s, _ = r.table.Get(tok.Literal)
+		if sym, ok := r.symbols[s]; ok {
+			r.reference(tok).write = true
+			sym.assigned = true
+			return sym
+		}
+	}
+	sym := &symbol{
+		name:   tok.Literal,
+		kind:   kind,
+		def:    tok,
+		global: r.table.IsGlobal(),
+	}
+	r.symbols[s] = sym
+	r.result.symbols = append(r.result.symbols, sym)
+	return sym
+}
+
+// Records a reference to a name, returning nil if the name isn't defined in
+// the program. "x++" is parsed as an identifier followed by a postfix
+// statement that share the same token, so a token is only recorded once.
+func (r *resolver) reference(tok token.Token) *reference {
+	if ref, ok := r.refs[tok.StartPosition.Char]; ok {
+		return ref
+	}
+	resolution, found := r.table.Resolve(tok.Literal)
+	if !found {
+		r.refs[tok.StartPosition.Char] = nil
+		r.result.unresolved = append(r.result.unresolved, tok)
+		return nil
+	}
+	sym, ok := r.symbols[resolution.Symbol()]
+	if !ok {
+		return nil
+	}
+	ref := &reference{tok: tok, scope: resolution.Scope()}
+	r.refs[tok.StartPosition.Char] = ref
+	sym.refs = append(sym.refs, ref)
+	return ref
+}
+
+// Returns the symbol that a token refers to.
+func (r *resolver) lookup(tok token.Token) *symbol {
+	resolution, found := r.table.Resolve(tok.Literal)
+	if !found {
+		return nil
+	}
+	return r.symbols[resolution.Symbol()]
+}
+
+// Records a read of a name.
+func (r *resolver) read(tok token.Token) *symbol {
+	if r.reference(tok) == nil {
+		return nil
+	}
+	sym := r.lookup(tok)
+	sym.used = true
+	return sym
+}
+
+// Records an assignment to a name.
+func (r *resolver) assign(tok token.Token) {
+	if ref := r.reference(tok); ref != nil {
+		ref.write = true
+		r.lookup(tok).assigned = true
+	}
+}
+
+// Records a reference to an attribute of an object, if the object is an
+// imported module.
+func (r *resolver) attribute(object ast.Expression, ident *ast.Ident, write bool) {
+	obj, ok := object.(*ast.Ident)
+	if !ok {
+		r.walk(object)
+		return
+	}
+	sym := r.read(obj.Token())
+	if sym == nil || sym.imported == "" {
+		return
+	}
+	r.result.attrs = append(r.result.attrs, attribute{
+		module: sym.imported,
+		name:   ident.Literal(),
+		tok:    ident.Token(),
+		write:  write,
+	})
+}
+
+func (r *resolver) walkAll(nodes []ast.Node) {
+	for _, node := range nodes {
+		r.walk(node)
+	}
+}
+
+func (r *resolver) walkExprs(exprs []ast.Expression) {
+	for _, expr := range exprs {
+		r.walk(expr)
+	}
+}
+
+// Walks a block in a new scope.
+func (r *resolver) block(node *ast.Block) {
+	if node == nil {
+		return
+	}
+	r.push()
+	r.walkAll(node.Statements())
+	r.pop()
+}
+
+func (r *resolver) walk(node ast.Node) {
+	switch node := node.(type) {
+	case nil:
+	case *ast.Ident:
+		r.read(node.Token())
+	case *ast.Var:
+		_, value := node.Value()
+		r.walk(value)
+		sym := r.define(node.Ident(), variableSymbol)
+		if fn, ok := value.(*ast.Func); ok {
+			sym.fn = fn
+		}
+	case *ast.MultiVar:
+		_, value := node.Value()
+		r.walk(value)
+		for _, ident := range node.Idents() {
+			if node.IsWalrus() {
+				r.define(ident, variableSymbol)
+			} else {
+				r.assign(ident.Token())
+			}
+		}
+	case *ast.Const:
+		_, value := node.Value()
+		r.walk(value)
+		sym := r.define(node.Ident(), constantSymbol)
+		if fn, ok := value.(*ast.Func); ok {
+			sym.fn = fn
+		}
+	case *ast.Assign:
+		r.walk(node.Value())
+		if node.Index() != nil {
+			r.walk(node.Index())
+			return
+		}
+		tok := node.Ident().Token()
+		if node.Operator() != "=" {
+			r.read(tok)
+		}
+		r.assign(tok)
+	case *ast.Postfix:
+		r.read(node.Token())
+		r.assign(node.Token())
+	case *ast.Func:
+		r.funcLiteral(node)
+	case *ast.Call:
+		r.call(node, true)
+	case *ast.ObjectCall:
+		// The function of the call is the name of a method
+		c, ok := node.Call().(*ast.Call)
+		if !ok {
+			r.walk(node.Object())
+			return
+		}
+		if ident, ok := c.Function().(*ast.Ident); ok {
+			r.attribute(node.Object(), ident, false)
+		} else {
+			r.walk(node.Object())
+		}
+		r.walkAll(c.Arguments())
+	case *ast.GetAttr:
+		r.attribute(node.Object(), node.Attribute(), false)
+	case *ast.SetAttr:
+		r.walk(node.Value())
+		r.attribute(node.Object(), node.Attribute(), true)
+	case *ast.Pipe:
+		// Each call in a pipe receives an extra argument, so the number of
+		// arguments isn't checked
+		for _, expr := range node.Expressions() {
+			if c, ok := expr.(*ast.Call); ok {
+				r.call(c, false)
+			} else {
+				r.walk(expr)
+			}
+		}
+	case *ast.Prefix:
+		r.walk(node.Right())
+	case *ast.Infix:
+		r.walk(node.Left())
+		r.walk(node.Right())
+	case *ast.In:
+		r.walk(node.Left())
+		r.walk(node.Right())
+	case *ast.Ternary:
+		r.walk(node.Condition())
+		r.walk(node.IfTrue())
+		r.walk(node.IfFalse())
+	case *ast.If:
+		r.walk(node.Condition())
+		r.block(node.Consequence())
+		r.block(node.Alternative())
+	case *ast.Index:
+		r.walk(node.Left())
+		r.walk(node.Index())
+	case *ast.Slice:
+		r.walk(node.Left())
+		r.walk(node.FromIndex())
+		r.walk(node.ToIndex())
+	case *ast.List:
+		r.walkExprs(node.Items())
+	case *ast.Set:
+		r.walkExprs(node.Items())
+	case *ast.Map:
+		for key, value := range node.Items() {
+			// Identifiers used as keys are strings
+			if _, ok := key.(*ast.Ident); !ok {
+				r.walk(key)
+			}
+			r.walk(value)
+		}
+	case *ast.String:
+		r.walkExprs(node.TemplateExpressions())
+	case *ast.Switch:
+		r.walk(node.Value())
+		for _, choice := range node.Choices() {
+			r.walkExprs(choice.Expressions())
+			r.block(choice.Block())
+		}
+	case *ast.Match:
+		r.walk(node.Value())
+		for _, matchCase := range node.Cases() {
+			// Names bound by a pattern are scoped to its case
+			r.push()
+			r.pattern(matchCase.Pattern())
+			r.walk(matchCase.Guard())
+			if block := matchCase.Block(); block != nil {
+				r.walkAll(block.Statements())
+			}
+			r.pop()
+		}
+	case *ast.Select:
+		for _, selectCase := range node.Cases() {
+			// Names declared by a case are scoped to the case
+			r.push()
+			r.walk(selectCase.Comm())
+			for _, ident := range selectCase.Names() {
+				r.define(ident, variableSymbol)
+			}
+			if block := selectCase.Block(); block != nil {
+				r.walkAll(block.Statements())
+			}
+			r.pop()
+		}
+	case *ast.For:
+		r.push()
+		r.walk(node.Init())
+		r.walk(node.Condition())
+		r.walk(node.Post())
+		r.block(node.Consequence())
+		r.pop()
+	case *ast.Range:
+		r.walk(node.Container())
+	case *ast.Receive:
+		r.walk(node.Channel())
+	case *ast.Send:
+		r.walk(node.Channel())
+		r.walk(node.Value())
+	case *ast.Go:
+		r.walk(node.Call())
+	case *ast.Defer:
+		r.walk(node.Call())
+	case *ast.Return:
+		r.walk(node.Value())
+	case *ast.Yield:
+		r.walk(node.Value())
+	case *ast.Control:
+		r.walk(node.Value())
+	case *ast.Block:
+		r.block(node)
+	case *ast.Try:
+		r.block(node.Body())
+		if node.CatchBlock() != nil {
+			r.push()
+			if ident := node.CatchIdent(); ident != nil {
+				// The caught error is treated like a parameter, which is
+				// allowed to go unused
+				r.define(ident, parameterSymbol)
+			}
+			r.walkAll(node.CatchBlock().Statements())
+			r.pop()
+		}
+		r.block(node.FinallyBlock())
+	case *ast.Struct:
+		for _, value := range node.Defaults() {
+			r.walk(value)
+		}
+		r.define(node.Name(), structSymbol)
+	case *ast.Import:
+		sym := r.importName(node)
+		sym.imported = node.Name().Literal()
+	case *ast.FromImport:
+		parents := make([]string, 0, len(node.Parents()))
+		for _, parent := range node.Parents() {
+			parents = append(parents, parent.Literal())
+		}
+		module := strings.Join(parents, "/")
+		for _, im := range node.Imports() {
+			sym := r.importName(im)
+			sym.module = module
+			sym.attr = im.Name().Literal()
+			// The name may be a module itself, or an attribute of the module
+			sym.imported = module + "/" + sym.attr
+			r.result.attrs = append(r.result.attrs, attribute{
+				module: module,
+				name:   sym.attr,
+				tok:    im.Name().Token(),
+			})
+		}
+	}
+}
+
+// Defines the names bound by a pattern in a match expression.
+func (r *resolver) pattern(node ast.Pattern) {
+	switch node := node.(type) {
+	case *ast.BindPattern:
+		r.define(node.Ident(), variableSymbol)
+	case *ast.RestPattern:
+		if ident := node.Ident(); ident != nil {
+			r.define(ident, variableSymbol)
+		}
+	case *ast.LiteralPattern:
+		r.walk(node.Value())
+	case *ast.TypePattern:
+		r.pattern(node.Pattern())
+	case *ast.ListPattern:
+		for _, el := range node.Elements() {
+			r.pattern(el)
+		}
+	case *ast.MapPattern:
+		for _, value := range node.Values() {
+			r.pattern(value)
+		}
+	case *ast.OrPattern:
+		for _, alt := range node.Alternatives() {
+			r.pattern(alt)
+		}
+	}
+}
+
+func (r *resolver) importName(node *ast.Import) *symbol {
+	ident := node.Name()
+	if node.Alias() != nil {
+		ident = node.Alias()
+	}
+	return r.define(ident, importSymbol)
+}
+
+func (r *resolver) call(node *ast.Call, check bool) {
+	var sym *symbol
+	if ident, ok := node.Function().(*ast.Ident); ok {
+		sym = r.read(ident.Token())
+	} else {
+		r.walk(node.Function())
+	}
+	r.walkAll(node.Arguments())
+	if check && sym != nil && sym.fn != nil {
+		r.result.calls = append(r.result.calls, call{node: node, symbol: sym})
+	}
+}
+
+func (r *resolver) funcLiteral(node *ast.Func) {
+	if node.IsMethod() {
+		r.read(node.ReceiverType().Token())
+	} else if name := node.Name(); name != nil {
+		// The name is defined before the body, so that recursive calls
+		// refer to the same symbol
+		sym := r.define(name, functionSymbol)
+		sym.fn = node
+	}
+	r.function()
+	if node.IsMethod() {
+		r.define(node.Receiver(), parameterSymbol)
+	}
+	for _, param := range node.Parameters() {
+		r.define(param, parameterSymbol)
+	}
+	r.block(node.Body())
+	r.pop()
+}
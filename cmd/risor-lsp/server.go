@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"path/filepath"
 
 	"github.com/itrn0/risor/parser"
 	"github.com/jdbaldry/go-language-server-protocol/lsp/protocol"
@@ -13,13 +14,36 @@ type Server struct {
 	version string
 	client  protocol.ClientCloser
 	cache   *cache
-}
 
-func (s *Server) queueDiagnostics(uri protocol.DocumentURI) {}
+	// The directory that Risor modules are imported from, which is searched
+	// for references to the globals of modules. If empty, modules are found
+	// relative to the document.
+	modulesDir string
+}
 
 func (s *Server) DidChange(ctx context.Context, params *protocol.DidChangeTextDocumentParams) error {
 	defer s.queueDiagnostics(params.TextDocument.URI)
-	return nil
+	old, err := s.cache.get(params.TextDocument.URI)
+	if err != nil {
+		return err
+	}
+	// Documents are synced in full, so the last change holds the whole text
+	if len(params.ContentChanges) == 0 {
+		return nil
+	}
+	item := old.item
+	item.Text = params.ContentChanges[len(params.ContentChanges)-1].Text
+	item.Version = params.TextDocument.Version
+	doc := &document{
+		item:                 item,
+		linesChangedSinceAST: map[int]bool{},
+	}
+	doc.ast, doc.err = parser.Parse(ctx, item.Text)
+	if doc.err != nil {
+		// Keep the last good AST for features that can use it
+		doc.ast = old.ast
+	}
+	return s.cache.put(doc)
 }
 
 func (s *Server) DidOpen(ctx context.Context, params *protocol.DidOpenTextDocumentParams) (err error) {
@@ -42,6 +66,7 @@ func (s *Server) DidOpen(ctx context.Context, params *protocol.DidOpenTextDocume
 
 func (s *Server) Initialize(ctx context.Context, params *protocol.ParamInitialize) (*protocol.InitializeResult, error) {
 	log.Info().Msg("Initialize")
+	s.modulesDir = modulesDir(params)
 	return &protocol.InitializeResult{
 		Capabilities: protocol.ServerCapabilities{
 			CompletionProvider: protocol.CompletionOptions{
@@ -51,6 +76,10 @@ func (s *Server) Initialize(ctx context.Context, params *protocol.ParamInitializ
 			DefinitionProvider:         true,
 			DocumentFormattingProvider: true,
 			DocumentSymbolProvider:     true,
+			DocumentHighlightProvider:  true,
+			ReferencesProvider:         true,
+			RenameProvider:             protocol.RenameOptions{PrepareProvider: true},
+			FoldingRangeProvider:       true,
 			ExecuteCommandProvider: protocol.ExecuteCommandOptions{
 				Commands: []string{},
 			},
@@ -71,3 +100,22 @@ func (s *Server) Initialize(ctx context.Context, params *protocol.ParamInitializ
 		},
 	}, nil
 }
+
+// Returns the directory that modules are imported from. Like the "modules"
+// flag of the risor command, it may be given as the "modules" initialization
+// option, relative to the root of the workspace. Otherwise it's the root.
+func modulesDir(params *protocol.ParamInitialize) string {
+	root := params.RootPath
+	if params.RootURI != "" {
+		root = params.RootURI.SpanURI().Filename()
+	}
+	if opts, ok := params.InitializationOptions.(map[string]interface{}); ok {
+		if dir, ok := opts["modules"].(string); ok && dir != "" {
+			if filepath.IsAbs(dir) || root == "" {
+				return dir
+			}
+			return filepath.Join(root, dir)
+		}
+	}
+	return root
+}
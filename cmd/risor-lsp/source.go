@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf16"
+
+	"github.com/itrn0/risor/token"
+	"github.com/jdbaldry/go-language-server-protocol/lsp/protocol"
+)
+
+// source converts between positions in Risor source code and protocol
+// positions.
+type source struct {
+	lines [][]rune
+}
+
+func newSource(text string) *source {
+	var lines [][]rune
+	for _, line := range strings.Split(text, "\n") {
+		lines = append(lines, []rune(line))
+	}
+	return &source{lines: lines}
+}
+
+// Returns the protocol position of the given line and column. Characters are
+// counted in UTF-16 code units, as the protocol requires.
+func (s *source) position(line, column int) protocol.Position {
+	if line < 0 {
+		return protocol.Position{}
+	}
+	if line >= len(s.lines) {
+		line = len(s.lines) - 1
+		column = len(s.lines[line])
+	}
+	runes := s.lines[line]
+	if column > len(runes) {
+		column = len(runes)
+	}
+	if column < 0 {
+		column = 0
+	}
+	return protocol.Position{
+		Line:      uint32(line),
+		Character: uint32(len(utf16.Encode(runes[:column]))),
+	}
+}
+
+// Returns the offset in runes of the given protocol position, which is the
+// offset used by token positions.
+func (s *source) offset(pos protocol.Position) int {
+	line := int(pos.Line)
+	if line >= len(s.lines) {
+		line = len(s.lines) - 1
+	}
+	offset := 0
+	for _, runes := range s.lines[:line] {
+		offset += len(runes) + 1
+	}
+	units := 0
+	for _, r := range s.lines[line] {
+		if units >= int(pos.Character) {
+			break
+		}
+		units += len(utf16.Encode([]rune{r}))
+		offset++
+	}
+	return offset
+}
+
+// Returns the range of the given token.
+func (s *source) tokenRange(tok token.Token) protocol.Range {
+	return s.span(tok.StartPosition, tok.EndPosition)
+}
+
+// Returns the range that spans the given token positions. The end position
+// is that of the last character in the range.
+func (s *source) span(start, end token.Position) protocol.Range {
+	r := protocol.Range{
+		Start: s.position(start.Line, start.Column),
+		End:   s.position(end.Line, end.Column+1),
+	}
+	if r.End.Line < r.Start.Line || (r.End.Line == r.Start.Line && r.End.Character <= r.Start.Character) {
+		// Highlight at least one character
+		r.End = s.position(start.Line, start.Column+1)
+	}
+	return r
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/itrn0/risor/parser"
+	"github.com/jdbaldry/go-language-server-protocol/lsp/protocol"
+	"github.com/rs/zerolog/log"
+)
+
+// The file extensions the local importer tries when locating a module
+var moduleExtensions = []string{".risor", ".rsr"}
+
+// file is a parsed Risor document along with the names it resolves.
+type file struct {
+	uri protocol.DocumentURI
+
+	// The name the file is imported by, which is its path relative to the
+	// modules directory without the extension. Empty if the file is outside
+	// of the directory.
+	module string
+
+	src *source
+
+	// The names in the file, which is nil if the file doesn't parse
+	res *resolution
+}
+
+// workspace gives access to the documents that may refer to each other
+// through imports. These are the open documents and the Risor files in the
+// modules directory, which is where the local importer finds modules.
+type workspace struct {
+	ctx   context.Context
+	cache *cache
+	dir   string
+	files map[protocol.DocumentURI]*file
+}
+
+func (s *Server) workspace(ctx context.Context, uri protocol.DocumentURI) *workspace {
+	dir := s.modulesDir
+	if dir == "" {
+		// Modules are imported relative to the script by default
+		dir = filepath.Dir(uri.SpanURI().Filename())
+	}
+	return &workspace{
+		ctx:   ctx,
+		cache: s.cache,
+		dir:   dir,
+		files: map[protocol.DocumentURI]*file{},
+	}
+}
+
+// Returns the file with the given URI. Open documents are read from the
+// cache, so that unsaved changes are seen. Otherwise the file is read from
+// disk.
+func (w *workspace) file(uri protocol.DocumentURI) (*file, error) {
+	if f, ok := w.files[uri]; ok {
+		return f, nil
+	}
+	var text string
+	if doc, err := w.cache.get(uri); err == nil {
+		text = doc.item.Text
+	} else {
+		data, err := os.ReadFile(uri.SpanURI().Filename())
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+	f := &file{
+		uri:    uri,
+		module: w.moduleName(uri.SpanURI().Filename()),
+		src:    newSource(text),
+	}
+	if program, err := parser.Parse(w.ctx, text); err == nil {
+		f.res = resolve(program)
+	}
+	w.files[uri] = f
+	return f, nil
+}
+
+// Returns the name a file is imported by, or an empty string if it's outside
+// of the modules directory.
+func (w *workspace) moduleName(path string) string {
+	rel, err := filepath.Rel(w.dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))
+}
+
+// Returns the file that the local importer loads for the given module name.
+func (w *workspace) module(name string) (*file, bool) {
+	for _, ext := range moduleExtensions {
+		path := filepath.Join(w.dir, filepath.FromSlash(name)+ext)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		f, err := w.file(protocol.URIFromPath(path))
+		if err != nil {
+			return nil, false
+		}
+		return f, true
+	}
+	return nil, false
+}
+
+// Returns every file in the workspace: the Risor files in the modules
+// directory and its subdirectories, and the open documents.
+func (w *workspace) all() []*file {
+	var uris []protocol.DocumentURI
+	err := filepath.WalkDir(w.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != w.dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		for _, ext := range moduleExtensions {
+			if filepath.Ext(path) == ext {
+				uris = append(uris, protocol.URIFromPath(path))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error().Err(err).Str("dir", w.dir).Msg("failed to list modules")
+	}
+	w.cache.mu.RLock()
+	for uri := range w.cache.docs {
+		uris = append(uris, uri)
+	}
+	w.cache.mu.RUnlock()
+
+	var files []*file
+	seen := map[protocol.DocumentURI]bool{}
+	for _, uri := range uris {
+		if seen[uri] {
+			continue
+		}
+		seen[uri] = true
+		f, err := w.file(uri)
+		if err != nil {
+			log.Error().Err(err).Str("uri", string(uri)).Msg("failed to read file")
+			continue
+		}
+		files = append(files, f)
+	}
+	return files
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/itrn0/risor"
+	"github.com/itrn0/risor/compiler"
+	"github.com/itrn0/risor/parser"
+	"github.com/itrn0/risor/token"
+	"github.com/spf13/cobra"
+)
+
+const checkExample = `  risor check ./path/to/script.risor
+
+  risor check ./path/to/dir
+
+  risor check -c "func f(a: int) { a }; f(\"x\")"`
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check Risor code for type errors",
+	Long: `Check Risor code for type errors without running it.
+
+Types are inferred from literals, the signatures of builtins and optional type
+annotations on variables, function parameters and results:
+
+  func add(a: int, b: int) -> int { a + b }
+
+Operations that are sure to fail and values that don't match annotated types
+are reported along with their positions, as are parse and compile errors.
+Compiler warnings, such as for a match expression that may not match any of its
+cases, are also reported but aren't counted as errors. Directories are searched recursively for .risor files. The command exits with
+a non-zero status if any errors are found.`,
+	Example: checkExample,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		processGlobalFlags()
+		opts := getRisorOptions()
+
+		// Check code given with --code or --stdin
+		if len(args) == 0 {
+			code, err := getRisorCode(cmd, args)
+			if err != nil {
+				fatal(err)
+			}
+			if count := checkSource(ctx, "", code, opts, os.Stdout); count > 0 {
+				os.Exit(1)
+			}
+			return
+		}
+
+		paths, err := risorFiles(args)
+		if err != nil {
+			fatal(err)
+		}
+		var count int
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				fatal(err)
+			}
+			count += checkSource(ctx, path, string(data), opts, os.Stdout)
+		}
+		if count > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// Checks the source code of a file, writing any errors to out prefixed with
+// their positions. Compile errors are only reported if there are no type
+// errors, and compile warnings only if there are no errors. Returns the number
+// of errors found.
+func checkSource(ctx context.Context, path, source string, opts []risor.Option, out io.Writer) int {
+	if path != "" {
+		opts = append(opts, risor.WithFilename(path))
+	}
+	cfg := risor.NewConfig(opts...)
+	report := func(pos token.Position, err error) {
+		if path != "" {
+			fmt.Fprintf(out, "%s:", path)
+		}
+		fmt.Fprintf(out, "%d:%d: %s\n", pos.LineNumber(), pos.ColumnNumber(), err)
+	}
+	program, err := parser.Parse(ctx, source, cfg.ParserOpts()...)
+	if err != nil {
+		var parseErr parser.ParserError
+		if errors.As(err, &parseErr) {
+			report(parseErr.StartPosition(), err)
+		} else {
+			report(token.Position{}, err)
+		}
+		return 1
+	}
+	typeErrs := compiler.TypeCheck(program, cfg.CompilerOpts()...)
+	for _, err := range typeErrs {
+		report(err.StartPosition(), err)
+	}
+	if len(typeErrs) > 0 {
+		return len(typeErrs)
+	}
+	c, err := compiler.New(cfg.CompilerOpts()...)
+	if err != nil {
+		report(token.Position{}, err)
+		return 1
+	}
+	if _, err := c.Compile(program); err != nil {
+		var compileErr *compiler.CompileError
+		if errors.As(err, &compileErr) {
+			report(compileErr.StartPosition(), err)
+		} else {
+			report(token.Position{}, err)
+		}
+		return 1
+	}
+	for _, warning := range c.Warnings() {
+		report(warning.StartPosition(), fmt.Errorf("warning: %w", warning))
+	}
+	return 0
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		source   string
+		expected string
+	}{
+		{
+			"valid",
+			"ok.risor",
+			"func add(a: int, b: int) -> int { a + b }\nprint(add(1, 2))\n",
+			"",
+		},
+		{
+			"type errors",
+			"bad.risor",
+			"func add(a: int, b: int) -> int { a + b }\nx := add(1, \"2\")\ny := len(\"abc\") + \"d\"\n",
+			"bad.risor:2:13: type error: cannot use string as int in argument b to add()\n" +
+				"bad.risor:3:17: type error: unsupported operation for int: + on type string\n",
+		},
+		{
+			"compile error",
+			"",
+			"x := 1\nprint(y)",
+			"2:7: compile error: undefined variable \"y\" (line 2)\n",
+		},
+		{
+			"parse error",
+			"",
+			"func f(a: ) {}",
+			"1:11: parse error: expected a type (got ))\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			count := checkSource(context.Background(), tt.path, tt.source, getRisorOptions(), &out)
+			require.Equal(t, tt.expected, out.String())
+			require.Equal(t, tt.expected == "", count == 0)
+		})
+	}
+}
+
+func TestCheckSourceWarnings(t *testing.T) {
+	var out bytes.Buffer
+	count := checkSource(context.Background(), "w.risor", "x := 1\nmatch x { case 1: 2 }\n", getRisorOptions(), &out)
+	require.Equal(t, 0, count)
+	require.Equal(t, "w.risor:2:1: warning: match is not exhaustive: add a default case or a case that matches all values\n", out.String())
+}
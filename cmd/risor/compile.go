@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/itrn0/risor"
+	"github.com/itrn0/risor/compiler"
+	"github.com/itrn0/risor/parser"
+	"github.com/spf13/cobra"
+)
+
+const compileExample = `  risor compile ./path/to/script.risor
+
+  risor compile ./path/to/script.risor -o script.rsrc
+
+  risor compile -c "print(1 + 2)" -o script.rsrc`
+
+var compileCmd = &cobra.Command{
+	Use:     "compile",
+	Short:   "Compile Risor code to bytecode",
+	Long:    "Compile Risor code to a binary bytecode file that may be run with `risor <file>`.",
+	Example: compileExample,
+	Args:    cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		processGlobalFlags()
+		opts := getRisorOptions()
+		code, err := getRisorCode(cmd, args)
+		if err != nil {
+			fatal(err)
+		}
+		if len(args) > 0 {
+			opts = append(opts, risor.WithFilename(args[0]))
+		}
+
+		// Determine the output path, defaulting to the input path with an
+		// .rsrc extension
+		outputPath, _ := cmd.Flags().GetString("output")
+		if outputPath == "" {
+			if len(args) == 0 {
+				fatal("an output path must be given with -o when compiling from --code or --stdin")
+			}
+			outputPath = strings.TrimSuffix(args[0], filepath.Ext(args[0])) + ".rsrc"
+		}
+
+		// Parse then compile the input code
+		cfg := risor.NewConfig(opts...)
+		ast, err := parser.Parse(ctx, code, cfg.ParserOpts()...)
+		if err != nil {
+			fatal(err)
+		}
+		compiledCode, err := compiler.Compile(ast, cfg.CompilerOpts()...)
+		if err != nil {
+			fatal(err)
+		}
+
+		// Encode the bytecode and write it to the output file
+		data, err := compiler.MarshalCodeBinary(compiledCode)
+		if err != nil {
+			fatal(err)
+		}
+		if err := os.WriteFile(outputPath, data, 0o644); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(compileCmd)
+	compileCmd.Flags().StringP("output", "o", "", "Output file path")
+}
@@ -0,0 +1,614 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/itrn0/risor"
+	"github.com/itrn0/risor/compiler"
+	"github.com/itrn0/risor/errz"
+	"github.com/itrn0/risor/object"
+	ros "github.com/itrn0/risor/os"
+	"github.com/itrn0/risor/vm"
+)
+
+// The Debug Adapter Protocol (DAP) is used by editors such as VS Code to
+// drive debuggers. Messages are JSON objects preceded by a Content-Length
+// header. See https://microsoft.github.io/debug-adapter-protocol/
+
+// The only thread reported to the client. Goroutines started by the script
+// run in VM clones which are not debugged.
+const dapThreadID = 1
+
+type dapRequest struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+type dapResponse struct {
+	Seq        int    `json:"seq"`
+	Type       string `json:"type"`
+	RequestSeq int    `json:"request_seq"`
+	Success    bool   `json:"success"`
+	Command    string `json:"command"`
+	Message    string `json:"message,omitempty"`
+	Body       any    `json:"body,omitempty"`
+}
+
+type dapEvent struct {
+	Seq   int    `json:"seq"`
+	Type  string `json:"type"`
+	Event string `json:"event"`
+	Body  any    `json:"body,omitempty"`
+}
+
+type dapSource struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+type dapLaunchArguments struct {
+	Program     string `json:"program"`
+	StopOnEntry bool   `json:"stopOnEntry"`
+}
+
+type dapSetBreakpointsArguments struct {
+	Source      dapSource `json:"source"`
+	Breakpoints []struct {
+		Line int `json:"line"`
+	} `json:"breakpoints"`
+}
+
+type dapFrameArguments struct {
+	FrameID int `json:"frameId"`
+}
+
+type dapVariablesArguments struct {
+	VariablesReference int `json:"variablesReference"`
+}
+
+type dapEvaluateArguments struct {
+	Expression string `json:"expression"`
+	FrameID    int    `json:"frameId"`
+}
+
+type dapVariable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	Type               string `json:"type,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+// Reads one DAP message from the reader.
+func readDAPMessage(r *bufio.Reader) ([]byte, error) {
+	contentLength := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		name, value, found := strings.Cut(line, ":")
+		if found && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			contentLength, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length header: %q", line)
+			}
+		}
+	}
+	if contentLength < 0 {
+		return nil, errors.New("missing Content-Length header")
+	}
+	data := make([]byte, contentLength)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// A debug session for one program, driven by a DAP client.
+type dapSession struct {
+	in       *bufio.Reader
+	out      io.Writer
+	opts     []risor.Option
+	debugger *vm.Debugger
+
+	// Guards writes to out and the message sequence number
+	outMu sync.Mutex
+	seq   int
+
+	// Set by the launch request
+	program     string
+	stopOnEntry bool
+
+	// Cancels the running program
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// The program waits on resume while stopped
+	resume chan vm.DebugAction
+
+	// State of the current stop, which is discarded when execution resumes
+	mu      sync.Mutex
+	stop    *vm.Stop
+	varRefs [][]vm.Variable
+}
+
+func newDAPSession(ctx context.Context, in io.Reader, out io.Writer, opts []risor.Option) *dapSession {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &dapSession{
+		in:     bufio.NewReader(in),
+		out:    out,
+		opts:   opts,
+		cancel: cancel,
+		resume: make(chan vm.DebugAction),
+	}
+	// What the program writes to stdout is shown in the client's debug console
+	s.ctx = ros.WithOS(ctx, &dapOS{
+		OS:     ros.GetDefaultOS(ctx),
+		stdout: &dapOutputFile{session: s, category: "stdout"},
+	})
+	s.debugger = vm.NewDebugger(s.handleStop)
+	return s
+}
+
+// Serve handles requests until the client disconnects.
+func (s *dapSession) Serve() error {
+	defer s.cancel()
+	for {
+		data, err := readDAPMessage(s.in)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		var req dapRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return fmt.Errorf("invalid debug adapter message: %w", err)
+		}
+		if req.Type != "request" {
+			continue
+		}
+		if done := s.handleRequest(&req); done {
+			return nil
+		}
+	}
+}
+
+func (s *dapSession) send(msg any) {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	s.seq++
+	switch msg := msg.(type) {
+	case *dapResponse:
+		msg.Seq = s.seq
+	case *dapEvent:
+		msg.Seq = s.seq
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+func (s *dapSession) respond(req *dapRequest, body any) {
+	s.send(&dapResponse{
+		Type:       "response",
+		RequestSeq: req.Seq,
+		Success:    true,
+		Command:    req.Command,
+		Body:       body,
+	})
+}
+
+func (s *dapSession) respondError(req *dapRequest, err error) {
+	s.send(&dapResponse{
+		Type:       "response",
+		RequestSeq: req.Seq,
+		Success:    false,
+		Command:    req.Command,
+		Message:    err.Error(),
+	})
+}
+
+func (s *dapSession) event(name string, body any) {
+	s.send(&dapEvent{Type: "event", Event: name, Body: body})
+}
+
+// Output sends text to the client to display in its debug console.
+func (s *dapSession) Output(category, text string) {
+	s.event("output", map[string]any{"category": category, "output": text})
+}
+
+// Handles a request, returning true if the session is over.
+func (s *dapSession) handleRequest(req *dapRequest) bool {
+	switch req.Command {
+	case "initialize":
+		s.respond(req, map[string]any{
+			"supportsConfigurationDoneRequest": true,
+			"supportsEvaluateForHovers":        true,
+		})
+		s.event("initialized", nil)
+	case "launch":
+		var args dapLaunchArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			s.respondError(req, err)
+			return false
+		}
+		if args.Program == "" {
+			s.respondError(req, errors.New("a program to debug must be given"))
+			return false
+		}
+		program, err := filepath.Abs(args.Program)
+		if err != nil {
+			s.respondError(req, err)
+			return false
+		}
+		s.program = program
+		s.stopOnEntry = args.StopOnEntry
+		s.respond(req, nil)
+	case "setBreakpoints":
+		var args dapSetBreakpointsArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			s.respondError(req, err)
+			return false
+		}
+		path, err := filepath.Abs(args.Source.Path)
+		if err != nil {
+			s.respondError(req, err)
+			return false
+		}
+		lines := make([]int, 0, len(args.Breakpoints))
+		breakpoints := make([]map[string]any, 0, len(args.Breakpoints))
+		for _, bp := range args.Breakpoints {
+			lines = append(lines, bp.Line)
+			breakpoints = append(breakpoints, map[string]any{"verified": true, "line": bp.Line})
+		}
+		s.debugger.SetBreakpoints(path, lines)
+		s.respond(req, map[string]any{"breakpoints": breakpoints})
+	case "setExceptionBreakpoints":
+		s.respond(req, map[string]any{})
+	case "configurationDone":
+		if s.program == "" {
+			s.respondError(req, errors.New("no program was launched"))
+			return false
+		}
+		s.respond(req, nil)
+		go s.run()
+	case "threads":
+		s.respond(req, map[string]any{
+			"threads": []map[string]any{{"id": dapThreadID, "name": "main"}},
+		})
+	case "stackTrace":
+		s.stackTrace(req)
+	case "scopes":
+		s.scopes(req)
+	case "variables":
+		s.variables(req)
+	case "evaluate":
+		s.evaluate(req)
+	case "continue":
+		s.respond(req, map[string]any{"allThreadsContinued": true})
+		s.resumeWith(vm.DebugContinue)
+	case "next":
+		s.respond(req, nil)
+		s.resumeWith(vm.DebugStepOver)
+	case "stepIn":
+		s.respond(req, nil)
+		s.resumeWith(vm.DebugStepIn)
+	case "stepOut":
+		s.respond(req, nil)
+		s.resumeWith(vm.DebugStepOut)
+	case "pause":
+		s.debugger.Pause()
+		s.respond(req, nil)
+	case "disconnect", "terminate":
+		s.cancel()
+		s.respond(req, nil)
+		return req.Command == "disconnect"
+	default:
+		s.respondError(req, fmt.Errorf("unsupported request: %s", req.Command))
+	}
+	return false
+}
+
+// Runs the program until it finishes or the session ends.
+func (s *dapSession) run() {
+	exitCode := 0
+	defer func() {
+		s.event("exited", map[string]any{"exitCode": exitCode})
+		s.event("terminated", nil)
+	}()
+	source, err := os.ReadFile(s.program)
+	if err != nil {
+		s.Output("stderr", err.Error()+"\n")
+		exitCode = 1
+		return
+	}
+	s.debugger.SetStopOnEntry(s.stopOnEntry)
+	opts := append([]risor.Option{}, s.opts...)
+	opts = append(opts, risor.WithFilename(s.program), risor.WithDebugger(s.debugger))
+	if compiler.IsBinaryCode(source) {
+		var main *compiler.Code
+		main, err = compiler.UnmarshalCodeBinary(source)
+		if err == nil {
+			_, err = risor.EvalCode(s.ctx, main, opts...)
+		}
+	} else {
+		_, err = risor.Eval(s.ctx, string(source), opts...)
+	}
+	if err != nil && s.ctx.Err() == nil {
+		msg := err.Error()
+		if friendlyErr, ok := err.(errz.FriendlyError); ok {
+			msg = friendlyErr.FriendlyErrorMessage()
+		}
+		s.Output("stderr", msg+"\n")
+		exitCode = 1
+	}
+}
+
+// Called by the debugger on the program's goroutine when execution stops. The
+// program remains stopped until the client asks it to resume.
+func (s *dapSession) handleStop(ctx context.Context, stop *vm.Stop) vm.DebugAction {
+	s.mu.Lock()
+	s.stop = stop
+	s.varRefs = nil
+	s.mu.Unlock()
+	s.event("stopped", map[string]any{
+		"reason":            string(stop.Reason),
+		"threadId":          dapThreadID,
+		"allThreadsStopped": true,
+	})
+	select {
+	case action := <-s.resume:
+		return action
+	case <-ctx.Done():
+		return vm.DebugContinue
+	}
+}
+
+// Resumes the program if it is stopped.
+func (s *dapSession) resumeWith(action vm.DebugAction) {
+	s.mu.Lock()
+	stopped := s.stop != nil
+	s.stop = nil
+	s.varRefs = nil
+	s.mu.Unlock()
+	if stopped {
+		select {
+		case s.resume <- action:
+		case <-s.ctx.Done():
+		}
+	}
+}
+
+// Returns the current stop, or an error if the program is running.
+func (s *dapSession) currentStop() (*vm.Stop, error) {
+	if s.stop == nil {
+		return nil, errors.New("the program is not stopped")
+	}
+	return s.stop, nil
+}
+
+// Frame IDs are the index of the frame in the stop, which is ordered from the
+// outermost frame. The client expects the innermost frame first.
+func (s *dapSession) stackTrace(req *dapRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stop, err := s.currentStop()
+	if err != nil {
+		s.respondError(req, err)
+		return
+	}
+	frames := make([]map[string]any, 0, len(stop.Frames))
+	for i := len(stop.Frames) - 1; i >= 0; i-- {
+		frame := stop.Frames[i]
+		result := map[string]any{
+			"id":     i,
+			"name":   frame.Function,
+			"line":   frame.Location.Line,
+			"column": frame.Location.Column,
+		}
+		if frame.Location.File != "" {
+			result["source"] = dapSource{
+				Name: filepath.Base(frame.Location.File),
+				Path: frame.Location.File,
+			}
+		}
+		frames = append(frames, result)
+	}
+	s.respond(req, map[string]any{"stackFrames": frames, "totalFrames": len(frames)})
+}
+
+func (s *dapSession) scopes(req *dapRequest) {
+	var args dapFrameArguments
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		s.respondError(req, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stop, err := s.currentStop()
+	if err != nil {
+		s.respondError(req, err)
+		return
+	}
+	if args.FrameID < 0 || args.FrameID >= len(stop.Frames) {
+		s.respondError(req, fmt.Errorf("invalid frame: %d", args.FrameID))
+		return
+	}
+	frame := stop.Frames[args.FrameID]
+	scopes := []map[string]any{}
+	addScope := func(name string, variables []vm.Variable, expensive bool) {
+		scopes = append(scopes, map[string]any{
+			"name":               name,
+			"variablesReference": s.addVarRef(variables),
+			"expensive":          expensive,
+		})
+	}
+	addScope("Locals", frame.Locals, false)
+	if len(frame.Free) > 0 {
+		addScope("Closure", frame.Free, false)
+	}
+	addScope("Globals", stop.Globals, true)
+	s.respond(req, map[string]any{"scopes": scopes})
+}
+
+func (s *dapSession) variables(req *dapRequest) {
+	var args dapVariablesArguments
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		s.respondError(req, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.currentStop(); err != nil {
+		s.respondError(req, err)
+		return
+	}
+	index := args.VariablesReference - 1
+	if index < 0 || index >= len(s.varRefs) {
+		s.respondError(req, fmt.Errorf("invalid variables reference: %d", args.VariablesReference))
+		return
+	}
+	variables := make([]dapVariable, 0, len(s.varRefs[index]))
+	for _, v := range s.varRefs[index] {
+		variables = append(variables, s.dapVariable(v))
+	}
+	s.respond(req, map[string]any{"variables": variables})
+}
+
+// Evaluates an expression in the context of a frame. Only variable names are
+// supported, which is enough for hovers and watches of variables.
+func (s *dapSession) evaluate(req *dapRequest) {
+	var args dapEvaluateArguments
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		s.respondError(req, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stop, err := s.currentStop()
+	if err != nil {
+		s.respondError(req, err)
+		return
+	}
+	var candidates []vm.Variable
+	if args.FrameID >= 0 && args.FrameID < len(stop.Frames) {
+		frame := stop.Frames[args.FrameID]
+		candidates = append(candidates, frame.Locals...)
+		candidates = append(candidates, frame.Free...)
+	}
+	candidates = append(candidates, stop.Globals...)
+	name := strings.TrimSpace(args.Expression)
+	for _, candidate := range candidates {
+		if candidate.Name == name {
+			v := s.dapVariable(candidate)
+			s.respond(req, map[string]any{
+				"result":             v.Value,
+				"type":               v.Type,
+				"variablesReference": v.VariablesReference,
+			})
+			return
+		}
+	}
+	s.respondError(req, fmt.Errorf("undefined variable %q", name))
+}
+
+// Registers a list of variables the client may request, returning its
+// reference. References begin at 1, since 0 means there is nothing to expand.
+func (s *dapSession) addVarRef(variables []vm.Variable) int {
+	s.varRefs = append(s.varRefs, variables)
+	return len(s.varRefs)
+}
+
+// Converts a variable to its DAP representation. Containers are given a
+// reference so that the client may expand them.
+func (s *dapSession) dapVariable(v vm.Variable) dapVariable {
+	result := dapVariable{
+		Name:  v.Name,
+		Value: v.Value.Inspect(),
+		Type:  string(v.Value.Type()),
+	}
+	if children := childVariables(v.Value); len(children) > 0 {
+		result.VariablesReference = s.addVarRef(children)
+	}
+	return result
+}
+
+// Returns the items of a container as variables.
+func childVariables(obj object.Object) []vm.Variable {
+	var children []vm.Variable
+	switch obj := obj.(type) {
+	case *object.List:
+		for i, item := range obj.Value() {
+			children = append(children, vm.Variable{Name: fmt.Sprintf("[%d]", i), Value: item})
+		}
+	case *object.Map:
+		items := obj.Value()
+		keys := make([]string, 0, len(items))
+		for key := range items {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			children = append(children, vm.Variable{Name: key, Value: items[key]})
+		}
+	case *object.Struct:
+		for _, field := range obj.StructType().Fields() {
+			value, _ := obj.GetAttr(field)
+			children = append(children, vm.Variable{Name: field, Value: value})
+		}
+	}
+	return children
+}
+
+// An OS that forwards the program's stdout to the client.
+type dapOS struct {
+	ros.OS
+	stdout ros.File
+}
+
+func (o *dapOS) Stdout() ros.File {
+	return o.stdout
+}
+
+// A write-only file that sends what is written to it as output events.
+type dapOutputFile struct {
+	session  *dapSession
+	category string
+}
+
+func (f *dapOutputFile) Close() error {
+	return nil
+}
+
+func (f *dapOutputFile) Read(p []byte) (int, error) {
+	return 0, io.EOF
+}
+
+func (f *dapOutputFile) Write(p []byte) (int, error) {
+	f.session.Output(f.category, string(p))
+	return len(p), nil
+}
+
+func (f *dapOutputFile) Stat() (ros.FileInfo, error) {
+	return ros.NewFileInfo(ros.GenericFileInfoOpts{Name: f.category}), nil
+}
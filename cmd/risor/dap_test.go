@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itrn0/risor"
+	"github.com/stretchr/testify/require"
+)
+
+// A DAP client that talks to a session over in-memory pipes.
+type testDAPClient struct {
+	t   *testing.T
+	seq int
+	in  io.Writer
+	out *bufio.Reader
+}
+
+func newTestDAPClient(t *testing.T) *testDAPClient {
+	clientIn, sessionOut := io.Pipe()
+	sessionIn, clientOut := io.Pipe()
+	session := newDAPSession(context.Background(), sessionIn, sessionOut, []risor.Option{
+		risor.WithGlobal("extra", 1),
+	})
+	go func() {
+		require.Nil(t, session.Serve())
+		sessionOut.Close()
+	}()
+	return &testDAPClient{t: t, in: clientOut, out: bufio.NewReader(clientIn)}
+}
+
+func (c *testDAPClient) send(command string, arguments any) {
+	c.seq++
+	data, err := json.Marshal(map[string]any{
+		"seq":       c.seq,
+		"type":      "request",
+		"command":   command,
+		"arguments": arguments,
+	})
+	require.Nil(c.t, err)
+	_, err = fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	require.Nil(c.t, err)
+}
+
+// Reads messages until one matches, returning the output seen on the way.
+func (c *testDAPClient) readUntil(kind, name string) (map[string]any, string) {
+	var output string
+	for {
+		data, err := readDAPMessage(c.out)
+		require.Nil(c.t, err)
+		var msg map[string]any
+		require.Nil(c.t, json.Unmarshal(data, &msg))
+		if msg["event"] == "output" {
+			output += msg["body"].(map[string]any)["output"].(string)
+		}
+		if msg["type"] == kind && (msg["command"] == name || msg["event"] == name) {
+			return msg, output
+		}
+	}
+}
+
+func (c *testDAPClient) request(command string, arguments any) map[string]any {
+	c.send(command, arguments)
+	msg, _ := c.readUntil("response", command)
+	require.Equal(c.t, true, msg["success"], msg["message"])
+	body, _ := msg["body"].(map[string]any)
+	return body
+}
+
+func TestDAPSession(t *testing.T) {
+	program := filepath.Join(t.TempDir(), "main.risor")
+	require.Nil(t, os.WriteFile(program, []byte(`x := 1
+func f(a) {
+	b := [a, {"k": a}]
+	return b
+}
+y := f(x)
+print("y is", y)
+`), 0o644))
+
+	client := newTestDAPClient(t)
+	client.request("initialize", map[string]any{"adapterID": "risor"})
+	client.readUntil("event", "initialized")
+	client.request("launch", map[string]any{"program": program})
+	body := client.request("setBreakpoints", map[string]any{
+		"source":      map[string]any{"path": program},
+		"breakpoints": []map[string]any{{"line": 3}},
+	})
+	require.Len(t, body["breakpoints"], 1)
+	client.request("configurationDone", nil)
+
+	stopped, _ := client.readUntil("event", "stopped")
+	require.Equal(t, "breakpoint", stopped["body"].(map[string]any)["reason"])
+
+	// The innermost frame is first
+	body = client.request("stackTrace", map[string]any{"threadId": 1})
+	frames := body["stackFrames"].([]any)
+	require.Len(t, frames, 2)
+	top := frames[0].(map[string]any)
+	require.Equal(t, "f", top["name"])
+	require.Equal(t, float64(3), top["line"])
+	require.Equal(t, program, top["source"].(map[string]any)["path"])
+
+	// Locals of f hold only its parameter at this point
+	body = client.request("scopes", map[string]any{"frameId": top["id"]})
+	scopes := body["scopes"].([]any)
+	require.Equal(t, "Locals", scopes[0].(map[string]any)["name"])
+	body = client.request("variables", map[string]any{
+		"variablesReference": scopes[0].(map[string]any)["variablesReference"],
+	})
+	require.Equal(t, []any{map[string]any{
+		"name": "a", "value": "1", "type": "int", "variablesReference": float64(0),
+	}}, body["variables"])
+
+	// Globals exclude those supplied by the host
+	globals := scopes[len(scopes)-1].(map[string]any)
+	require.Equal(t, "Globals", globals["name"])
+	body = client.request("variables", map[string]any{
+		"variablesReference": globals["variablesReference"],
+	})
+	var names []string
+	for _, v := range body["variables"].([]any) {
+		names = append(names, v.(map[string]any)["name"].(string))
+	}
+	require.Equal(t, []string{"x", "f"}, names)
+
+	// Step over the assignment to b, then expand it
+	client.request("next", map[string]any{"threadId": 1})
+	stopped, _ = client.readUntil("event", "stopped")
+	require.Equal(t, "step", stopped["body"].(map[string]any)["reason"])
+	body = client.request("evaluate", map[string]any{"expression": "b", "frameId": top["id"]})
+	require.Equal(t, `[1, {"k": 1}]`, body["result"])
+	body = client.request("variables", map[string]any{
+		"variablesReference": body["variablesReference"],
+	})
+	require.Len(t, body["variables"], 2)
+
+	// Output is delivered before the program exits
+	client.request("continue", map[string]any{"threadId": 1})
+	_, output := client.readUntil("event", "terminated")
+	require.Equal(t, "y is [1, {\"k\": 1}]\n", output)
+	client.request("disconnect", nil)
+}
+
+func TestDAPSessionRequiresStop(t *testing.T) {
+	client := newTestDAPClient(t)
+	client.send("stackTrace", map[string]any{"threadId": 1})
+	msg, _ := client.readUntil("response", "stackTrace")
+	require.Equal(t, false, msg["success"])
+	require.Equal(t, "the program is not stopped", msg["message"])
+	client.request("disconnect", nil)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var debugCmd = &cobra.Command{
+	Use:   "debug",
+	Short: "Run a debug adapter for Risor scripts",
+	Long: `Run a debug adapter that speaks the Debug Adapter Protocol (DAP) over stdio.
+
+Editors such as VS Code start this command and then launch a script by sending
+a "launch" request with the path of the script as the "program" argument.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		processGlobalFlags()
+
+		// Stdout carries the protocol. Script output is forwarded to the client
+		// by the session, and anything else written to stdout goes to stderr so
+		// that it can't corrupt the protocol stream.
+		protocolOut := os.Stdout
+		os.Stdout = os.Stderr
+
+		session := newDAPSession(context.Background(), os.Stdin, protocolOut, getRisorOptions())
+		if err := session.Serve(); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(debugCmd)
+}
@@ -17,7 +17,9 @@ const disExample = `  risor dis -c "a := 1 + 2"
 
   risor dis ./path/to/script.risor
 
-  risor dis ./path/to/script.risor --func myfunc`
+  risor dis ./path/to/script.risor --func myfunc
+
+  risor dis -c "a := 60 * 60 * 24" --optimize 2`
 
 var disCmd = &cobra.Command{
 	Use:     "dis",
@@ -43,39 +45,66 @@ var disCmd = &cobra.Command{
 		if err != nil {
 			fatal(err)
 		}
-		targetCode := compiledCode
-
-		// If a function name was provided, disassemble its code only
-		if funcName := viper.GetString("func"); funcName != "" {
-			var fn *compiler.Function
-			for i := 0; i < compiledCode.ConstantsCount(); i++ {
-				obj, ok := compiledCode.Constant(i).(*compiler.Function)
-				if !ok {
-					continue
-				}
-				if obj.Name() == funcName {
-					fn = obj
-					break
-				}
+		funcName := viper.GetString("func")
+		level := viper.GetInt("optimize")
+		if level == compiler.OptimizeNone {
+			if err := disassemble(compiledCode, funcName); err != nil {
+				fatal(err)
 			}
-			if fn == nil {
-				fatal(fmt.Sprintf("function %q not found", funcName))
-			}
-			targetCode = fn.Code()
+			return
 		}
 
-		// Disassemble and print the instructions
-		instructions, err := dis.Disassemble(targetCode)
+		// Show the code before and after optimization
+		compilerOpts := append(cfg.CompilerOpts(), compiler.WithOptimizationLevel(level))
+		optimizedCode, err := compiler.Compile(ast, compilerOpts...)
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			fatal(err)
+		}
+		fmt.Println("Before optimization:")
+		if err := disassemble(compiledCode, funcName); err != nil {
+			fatal(err)
+		}
+		fmt.Println()
+		fmt.Printf("After optimization (level %d):\n", level)
+		if err := disassemble(optimizedCode, funcName); err != nil {
+			fatal(err)
 		}
-		dis.Print(instructions, os.Stdout)
 	},
 }
 
+// Prints the instructions of the given code, or of the function with the
+// given name if one is provided.
+func disassemble(code *compiler.Code, funcName string) error {
+	targetCode := code
+	if funcName != "" {
+		var fn *compiler.Function
+		for i := 0; i < code.ConstantsCount(); i++ {
+			obj, ok := code.Constant(i).(*compiler.Function)
+			if !ok {
+				continue
+			}
+			if obj.Name() == funcName {
+				fn = obj
+				break
+			}
+		}
+		if fn == nil {
+			return fmt.Errorf("function %q not found", funcName)
+		}
+		targetCode = fn.Code()
+	}
+	instructions, err := dis.Disassemble(targetCode)
+	if err != nil {
+		return err
+	}
+	dis.Print(instructions, os.Stdout)
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(disCmd)
 	disCmd.Flags().String("func", "", "Function name")
 	viper.BindPFlag("func", disCmd.Flags().Lookup("func"))
+	disCmd.Flags().IntP("optimize", "O", 0, "Show the code before and after optimizing at this level (1-2)")
+	viper.BindPFlag("optimize", disCmd.Flags().Lookup("optimize"))
 }
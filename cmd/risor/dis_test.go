@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
 )
 
@@ -34,3 +35,40 @@ func TestDisassembly(t *testing.T) {
 `
 	require.Equal(t, strings.TrimPrefix(expected, "\n"), capturedOutput)
 }
+
+func TestDisassemblyOptimized(t *testing.T) {
+	viper.Set("optimize", 1)
+	defer viper.Set("optimize", 0)
+
+	// Capture stdout
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	defer func() { os.Stdout = old }()
+
+	disCmd.Run(disCmd, []string{"fixtures/ex1.risor"})
+
+	w.Close()
+
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	capturedOutput := buf.String()
+	expected := `
+Before optimization:
++--------+------------+----------+------+
+| OFFSET |   OPCODE   | OPERANDS | INFO |
++--------+------------+----------+------+
+|      0 | LOAD_CONST |        0 | 3    |
+|      2 | LOAD_CONST |        1 | 4    |
+|      4 | BINARY_OP  |        1 | +    |
++--------+------------+----------+------+
+
+After optimization (level 1):
++--------+------------+----------+------+
+| OFFSET |   OPCODE   | OPERANDS | INFO |
++--------+------------+----------+------+
+|      0 | LOAD_CONST |        0 | 7    |
++--------+------------+----------+------+
+`
+	require.Equal(t, strings.TrimPrefix(expected, "\n"), capturedOutput)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/itrn0/risor/parser"
+	"github.com/itrn0/risor/printer"
+	"github.com/spf13/cobra"
+)
+
+const fmtExample = `  risor fmt ./path/to/script.risor
+
+  risor fmt -w ./path/to/script.risor ./path/to/dir
+
+  risor fmt --check ./path/to/dir
+
+  risor fmt -c "x:=1+2"`
+
+var fmtCmd = &cobra.Command{
+	Use:   "fmt",
+	Short: "Format Risor code",
+	Long: `Format Risor code in canonical style, preserving comments.
+
+Formatted code is printed to stdout, unless -w is given to write it back to
+the input files. Directories are searched recursively for .risor files. With
+--check, the names of files that aren't formatted are printed and the command
+exits with a non-zero status if there are any.`,
+	Example: fmtExample,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		write, _ := cmd.Flags().GetBool("write")
+		check, _ := cmd.Flags().GetBool("check")
+		if write && check {
+			fatal("-w and --check may not be used together")
+		}
+
+		// Format code given with --code or --stdin
+		if len(args) == 0 {
+			if write {
+				fatal("-w requires at least one file")
+			}
+			code, err := getRisorCode(cmd, args)
+			if err != nil {
+				fatal(err)
+			}
+			formatted, err := printer.Format(ctx, code)
+			if err != nil {
+				fatal(err)
+			}
+			if check {
+				if formatted != code {
+					os.Exit(1)
+				}
+				return
+			}
+			fmt.Print(formatted)
+			return
+		}
+
+		paths, err := risorFiles(args)
+		if err != nil {
+			fatal(err)
+		}
+		var unformatted bool
+		for _, path := range paths {
+			changed, err := formatFile(ctx, path, write, check, os.Stdout)
+			if err != nil {
+				fatal(err)
+			}
+			unformatted = unformatted || changed
+		}
+		if check && unformatted {
+			os.Exit(1)
+		}
+	},
+}
+
+// Returns the files to format given the command line arguments, which may be
+// files or directories that are searched recursively for .risor files.
+func risorFiles(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && filepath.Ext(path) == ".risor" {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+// Formats a file. With write, the file is updated if its formatting changed.
+// With check, the path is written to out if its formatting would change.
+// Otherwise the formatted code is written to out. Returns true if the
+// formatting of the file changed.
+func formatFile(ctx context.Context, path string, write, check bool, out io.Writer) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	source := string(data)
+	formatted, err := printer.Format(ctx, source, parser.WithFile(path))
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	changed := formatted != source
+	switch {
+	case check:
+		if changed {
+			fmt.Fprintln(out, path)
+		}
+	case write:
+		if changed {
+			info, err := os.Stat(path)
+			if err != nil {
+				return false, err
+			}
+			if err := os.WriteFile(path, []byte(formatted), info.Mode().Perm()); err != nil {
+				return false, err
+			}
+		}
+	default:
+		if _, err := io.WriteString(out, formatted); err != nil {
+			return false, err
+		}
+	}
+	return changed, nil
+}
+
+func init() {
+	rootCmd.AddCommand(fmtCmd)
+	fmtCmd.Flags().BoolP("write", "w", false, "Write the formatted code back to the input files")
+	fmtCmd.Flags().Bool("check", false, "List files that aren't formatted and fail if there are any")
+}
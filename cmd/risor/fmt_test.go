@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatFile(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "script.risor")
+	require.Nil(t, os.WriteFile(path, []byte("x:=1 // one\nprint( x )"), 0o644))
+	expected := "x := 1 // one\nprint(x)\n"
+
+	var out bytes.Buffer
+	changed, err := formatFile(ctx, path, false, false, &out)
+	require.Nil(t, err)
+	require.True(t, changed)
+	require.Equal(t, expected, out.String())
+
+	out.Reset()
+	changed, err = formatFile(ctx, path, false, true, &out)
+	require.Nil(t, err)
+	require.True(t, changed)
+	require.Equal(t, path+"\n", out.String())
+
+	out.Reset()
+	changed, err = formatFile(ctx, path, true, false, &out)
+	require.Nil(t, err)
+	require.True(t, changed)
+	require.Empty(t, out.String())
+	data, err := os.ReadFile(path)
+	require.Nil(t, err)
+	require.Equal(t, expected, string(data))
+
+	changed, err = formatFile(ctx, path, false, true, &out)
+	require.Nil(t, err)
+	require.False(t, changed)
+	require.Empty(t, out.String())
+
+	require.Nil(t, os.WriteFile(path, []byte("x := "), 0o644))
+	_, err = formatFile(ctx, path, false, false, &out)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "script.risor")
+}
+
+func TestRisorFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o755))
+	for _, name := range []string{"a.risor", "b.txt", "sub/c.risor"} {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+	paths, err := risorFiles([]string{dir, filepath.Join(dir, "b.txt")})
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "a.risor"),
+		filepath.Join(dir, "sub", "c.risor"),
+		filepath.Join(dir, "b.txt"),
+	}, paths)
+
+	_, err = risorFiles([]string{filepath.Join(dir, "missing")})
+	require.NotNil(t, err)
+}
@@ -11,19 +11,26 @@ import (
 	"github.com/itrn0/risor/modules/carbon"
 	"github.com/itrn0/risor/modules/cli"
 	"github.com/itrn0/risor/modules/color"
+	"github.com/itrn0/risor/modules/dotenv"
 	"github.com/itrn0/risor/modules/gha"
 	"github.com/itrn0/risor/modules/image"
+	"github.com/itrn0/risor/modules/ini"
 	"github.com/itrn0/risor/modules/isatty"
+	"github.com/itrn0/risor/modules/iter"
 	"github.com/itrn0/risor/modules/jmespath"
 	k8s "github.com/itrn0/risor/modules/kubernetes"
+	"github.com/itrn0/risor/modules/log"
 	"github.com/itrn0/risor/modules/net"
 	"github.com/itrn0/risor/modules/pgx"
 	"github.com/itrn0/risor/modules/semver"
 	"github.com/itrn0/risor/modules/sql"
+	"github.com/itrn0/risor/modules/sync"
 	"github.com/itrn0/risor/modules/tablewriter"
 	"github.com/itrn0/risor/modules/template"
+	"github.com/itrn0/risor/modules/toml"
 	"github.com/itrn0/risor/modules/uuid"
 	"github.com/itrn0/risor/modules/vault"
+	"github.com/itrn0/risor/modules/xml"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -43,15 +50,21 @@ func getGlobals() risor.Option {
 		"carbon":      carbon.Module(),
 		"cli":         cli.Module(),
 		"color":       color.Module(),
+		"dotenv":      dotenv.Module(),
 		"gha":         gha.Module(),
 		"image":       image.Module(),
+		"ini":         ini.Module(),
 		"isatty":      isatty.Module(),
+		"log":         log.Module(),
 		"net":         net.Module(),
 		"pgx":         pgx.Module(),
 		"sql":         sql.Module(),
+		"sync":        sync.Module(),
 		"tablewriter": tablewriter.Module(),
 		"template":    template.Module(),
+		"toml":        toml.Module(),
 		"uuid":        uuid.Module(),
+		"xml":         xml.Module(),
 	}
 
 	//************************************************************************//
@@ -90,11 +103,22 @@ func getRisorOptions() []risor.Option {
 	opts := []risor.Option{
 		risor.WithConcurrency(),
 		risor.WithListenersAllowed(),
+		risor.WithRuntimeErrors(),
 		getGlobals(),
 	}
+	if !viper.GetBool("no-default-globals") {
+		// The iter module replaces the iter builtin, which it's compatible
+		// with since calling the module also returns an iterator
+		opts = append(opts, risor.WithGlobalOverride("iter", iter.Module()))
+	}
 	if modulesDir := viper.GetString("modules"); modulesDir != "" {
 		opts = append(opts, risor.WithLocalImporter(modulesDir))
 	}
+	logger, err := newLogger(viper.GetString("log-format"), os.Stderr)
+	if err != nil {
+		fatal(err)
+	}
+	opts = append(opts, risor.WithLogger(logger))
 	return opts
 }
 
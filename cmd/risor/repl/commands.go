@@ -0,0 +1,126 @@
+package repl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/itrn0/risor/compiler"
+	"github.com/itrn0/risor/dis"
+	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/parser"
+)
+
+// A meta-command, which is entered as a line beginning with a colon
+type command struct {
+	name  string
+	usage string
+	help  string
+	run   func(ctx context.Context, s *session, arg string) error
+}
+
+var commands []*command
+
+func init() {
+	// Assigned here because the help command refers to the list
+	commands = []*command{
+		{"dis", ":dis <name or code>", "Disassemble a function or some code", disCommand},
+		{"help", ":help", "Show the available commands", helpCommand},
+		{"load", ":load <file>", "Run a file in the session", loadCommand},
+		{"type", ":type <expression>", "Show the type of an expression", typeCommand},
+	}
+}
+
+// Returns true if the input is a meta-command rather than code.
+func isCommand(input string) bool {
+	return strings.HasPrefix(strings.TrimSpace(input), ":")
+}
+
+// Runs the meta-command in the given input and prints any error.
+func (s *session) runCommand(ctx context.Context, input string) error {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(input), ":"), " ")
+	arg = strings.TrimSpace(arg)
+	for _, cmd := range commands {
+		if cmd.name == name {
+			if err := cmd.run(ctx, s, arg); err != nil {
+				s.printError(err)
+				return err
+			}
+			return nil
+		}
+	}
+	err := fmt.Errorf("unknown command %q (try :help)", ":"+name)
+	s.printError(err)
+	return err
+}
+
+func helpCommand(ctx context.Context, s *session, arg string) error {
+	for _, cmd := range commands {
+		fmt.Fprintf(s.out, "%-22s %s\n", cmd.usage, cmd.help)
+	}
+	return nil
+}
+
+// Disassembles the function bound to the given name, or else compiles the
+// given code without running it and disassembles the result.
+func disCommand(ctx context.Context, s *session, arg string) error {
+	if arg == "" {
+		return errors.New("usage: :dis <name or code>")
+	}
+	if obj, err := s.resolve(arg); err == nil {
+		if fn, ok := obj.(*object.Function); ok {
+			return disassemble(s, fn.Code())
+		}
+	}
+	ast, err := parser.Parse(ctx, arg)
+	if err != nil {
+		return err
+	}
+	code, err := compiler.Compile(ast, compiler.WithGlobalNames(s.globalNames()))
+	if err != nil {
+		return err
+	}
+	return disassemble(s, code)
+}
+
+func disassemble(s *session, code *compiler.Code) error {
+	instructions, err := dis.Disassemble(code)
+	if err != nil {
+		return err
+	}
+	dis.Print(instructions, s.out)
+	return nil
+}
+
+// Evaluates the given expression and prints the type of the result.
+func typeCommand(ctx context.Context, s *session, arg string) error {
+	if arg == "" {
+		return errors.New("usage: :type <expression>")
+	}
+	result, err := s.run(ctx, arg)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(s.out, result.Type())
+	return nil
+}
+
+// Runs the given file in the session, so that what it defines is available
+// afterwards.
+func loadCommand(ctx context.Context, s *session, arg string) error {
+	if arg == "" {
+		return errors.New("usage: :load <file>")
+	}
+	data, err := os.ReadFile(arg)
+	if err != nil {
+		return err
+	}
+	result, err := s.run(ctx, string(data), parser.WithFile(arg))
+	if err != nil {
+		return err
+	}
+	s.printResult(result)
+	return nil
+}
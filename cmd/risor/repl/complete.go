@@ -0,0 +1,66 @@
+package repl
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/token"
+)
+
+func isWordChar(r rune) bool {
+	return r == '_' || r == '.' ||
+		(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+// Returns completions for the word that ends at the given position in the
+// input, along with the offset where the part being completed begins. A word
+// is a name, optionally preceded by a dotted path to an object, in which case
+// the attributes of the object are completed.
+func (s *session) complete(input []rune, pos int) (int, []string) {
+	start := pos
+	for start > 0 && isWordChar(input[start-1]) {
+		start--
+	}
+	word := string(input[start:pos])
+	var names []string
+	prefix := word
+	if dot := strings.LastIndex(word, "."); dot >= 0 {
+		obj, err := s.resolve(word[:dot])
+		if err != nil {
+			return pos, nil
+		}
+		lister, ok := obj.(object.AttrLister)
+		if !ok {
+			return pos, nil
+		}
+		names = lister.AttrNames()
+		prefix = word[dot+1:]
+	} else {
+		names = append(s.globalNames(), token.Keywords()...)
+	}
+	var candidates []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) && !seen[name] {
+			seen[name] = true
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+	return pos - len([]rune(prefix)), candidates
+}
+
+// Returns the longest prefix shared by all the given strings.
+func commonPrefix(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	prefix := values[0]
+	for _, value := range values[1:] {
+		for !strings.HasPrefix(value, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,194 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	prompt             = ">>> "
+	continuationPrompt = "... "
+
+	moveUp    = "\033[%dA"
+	moveDown  = "\033[%dB"
+	moveRight = "\033[%dC"
+	clearDown = "\r\033[J"
+)
+
+// Edits the input of the REPL, which may span several lines. Each line is
+// drawn after a prompt, and the input is redrawn in place after each change.
+type editor struct {
+	out  io.Writer
+	text []rune
+	pos  int
+
+	// The row of the cursor, relative to the first line of the input, when
+	// the input was last drawn
+	row int
+}
+
+func (e *editor) String() string {
+	return string(e.text)
+}
+
+// Replaces the input and moves the cursor to its end.
+func (e *editor) set(text string) {
+	e.text = []rune(text)
+	e.pos = len(e.text)
+}
+
+func (e *editor) insert(runes ...rune) {
+	text := make([]rune, 0, len(e.text)+len(runes))
+	text = append(text, e.text[:e.pos]...)
+	text = append(text, runes...)
+	e.text = append(text, e.text[e.pos:]...)
+	e.pos += len(runes)
+}
+
+// Replaces the text between the given offset and the cursor.
+func (e *editor) replace(start int, value string) {
+	rest := append([]rune(value), e.text[e.pos:]...)
+	e.text = append(e.text[:start], rest...)
+	e.pos = start + len([]rune(value))
+}
+
+func (e *editor) backspace() {
+	if e.pos == 0 {
+		return
+	}
+	e.text = append(e.text[:e.pos-1], e.text[e.pos:]...)
+	e.pos--
+}
+
+func (e *editor) delete() {
+	if e.pos == len(e.text) {
+		return
+	}
+	e.text = append(e.text[:e.pos], e.text[e.pos+1:]...)
+}
+
+func (e *editor) left() {
+	if e.pos > 0 {
+		e.pos--
+	}
+}
+
+func (e *editor) right() {
+	if e.pos < len(e.text) {
+		e.pos++
+	}
+}
+
+// Moves the cursor to the beginning of the current line.
+func (e *editor) home() {
+	for e.pos > 0 && e.text[e.pos-1] != '\n' {
+		e.pos--
+	}
+}
+
+// Moves the cursor to the end of the current line.
+func (e *editor) end() {
+	for e.pos < len(e.text) && e.text[e.pos] != '\n' {
+		e.pos++
+	}
+}
+
+// Returns the row and column of the cursor within the input.
+func (e *editor) cursor() (int, int) {
+	row, col := 0, 0
+	for _, r := range e.text[:e.pos] {
+		if r == '\n' {
+			row++
+			col = 0
+		} else {
+			col++
+		}
+	}
+	return row, col
+}
+
+func (e *editor) lineCount() int {
+	return strings.Count(string(e.text), "\n") + 1
+}
+
+// Moves the cursor to the same column of the previous line, returning false
+// if the cursor is on the first line.
+func (e *editor) up() bool {
+	_, col := e.cursor()
+	e.home()
+	if e.pos == 0 {
+		return false
+	}
+	e.pos--
+	e.home()
+	e.moveToColumn(col)
+	return true
+}
+
+// Moves the cursor to the same column of the next line, returning false if
+// the cursor is on the last line.
+func (e *editor) down() bool {
+	_, col := e.cursor()
+	e.end()
+	if e.pos == len(e.text) {
+		return false
+	}
+	e.pos++
+	e.moveToColumn(col)
+	return true
+}
+
+func (e *editor) moveToColumn(col int) {
+	for ; col > 0 && e.pos < len(e.text) && e.text[e.pos] != '\n'; col-- {
+		e.pos++
+	}
+}
+
+// Returns true if the input spans several lines and the last one is blank,
+// which is how incomplete input is submitted anyway.
+func (e *editor) endsWithBlankLine() bool {
+	text := string(e.text)
+	i := strings.LastIndex(text, "\n")
+	return i >= 0 && strings.TrimSpace(text[i+1:]) == ""
+}
+
+// Draws the input, replacing what was drawn before.
+func (e *editor) render() {
+	var b strings.Builder
+	if e.row > 0 {
+		fmt.Fprintf(&b, moveUp, e.row)
+	}
+	b.WriteString(clearDown)
+	for i, line := range strings.Split(string(e.text), "\n") {
+		if i == 0 {
+			b.WriteString(prompt)
+		} else {
+			b.WriteString("\r\n" + continuationPrompt)
+		}
+		b.WriteString(line)
+	}
+	row, col := e.cursor()
+	if last := e.lineCount() - 1; last > row {
+		fmt.Fprintf(&b, moveUp, last-row)
+	}
+	fmt.Fprintf(&b, "\r"+moveRight, len(prompt)+col)
+	e.row = row
+	io.WriteString(e.out, b.String())
+}
+
+// Moves the cursor below the input, so that output may follow it.
+func (e *editor) finish() {
+	if below := e.lineCount() - 1 - e.row; below > 0 {
+		fmt.Fprintf(e.out, moveDown, below)
+	}
+	io.WriteString(e.out, "\r\n")
+	e.row = 0
+}
+
+// Clears the input after it was submitted.
+func (e *editor) reset() {
+	e.text = nil
+	e.pos = 0
+	e.row = 0
+}
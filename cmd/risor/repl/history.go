@@ -0,0 +1,118 @@
+package repl
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The most entries kept in the history file
+const maxHistory = 1000
+
+// Inputs entered in earlier sessions, persisted in a file. Each line of the
+// file holds one quoted entry, so that entries may span several lines.
+type history struct {
+	path    string
+	entries []string
+	index   int
+}
+
+// Returns the path of the history file in the user's config directory, e.g.
+// ~/.config/risor/history on Linux.
+func defaultHistoryPath() string {
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "risor", "history")
+	}
+	if dir, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(dir, ".risor_history")
+	}
+	return ""
+}
+
+// Loads the history from the given file. A missing file is an empty history.
+func loadHistory(path string) *history {
+	h := &history{path: path}
+	if path == "" {
+		return h
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return h
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		// Older history files hold one unquoted entry per line
+		if entry, err := strconv.Unquote(line); err == nil {
+			line = entry
+		}
+		h.entries = append(h.entries, line)
+	}
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+		h.rewrite()
+	}
+	h.index = len(h.entries)
+	return h
+}
+
+// Adds an entry to the history and appends it to the history file.
+func (h *history) add(entry string) {
+	h.index = len(h.entries)
+	if strings.TrimSpace(entry) == "" {
+		return
+	}
+	if n := len(h.entries); n > 0 && h.entries[n-1] == entry {
+		return
+	}
+	h.entries = append(h.entries, entry)
+	h.index = len(h.entries)
+	if h.path == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o755); err != nil {
+		return
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.WriteString(strconv.Quote(entry) + "\n")
+}
+
+// Replaces the contents of the history file with the entries in memory.
+func (h *history) rewrite() {
+	var b strings.Builder
+	for _, entry := range h.entries {
+		b.WriteString(strconv.Quote(entry) + "\n")
+	}
+	os.WriteFile(h.path, []byte(b.String()), 0o600)
+}
+
+// Returns the entry before the current one, or false if there isn't one.
+func (h *history) previous() (string, bool) {
+	if h.index == 0 {
+		return "", false
+	}
+	h.index--
+	return h.entries[h.index], true
+}
+
+// Returns the entry after the current one. Moving past the newest entry
+// returns an empty string and false.
+func (h *history) next() (string, bool) {
+	if h.index >= len(h.entries)-1 {
+		h.index = len(h.entries)
+		return "", false
+	}
+	h.index++
+	return h.entries[h.index], true
+}
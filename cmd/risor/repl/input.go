@@ -0,0 +1,48 @@
+package repl
+
+import (
+	"context"
+	"strings"
+
+	"github.com/itrn0/risor/lexer"
+	"github.com/itrn0/risor/parser"
+	"github.com/itrn0/risor/token"
+)
+
+// Returns true if the input is the beginning of some code that continues on
+// further lines, such as a block whose closing brace hasn't been entered yet.
+// Input that is simply invalid is not incomplete.
+func isIncomplete(ctx context.Context, input string) bool {
+	_, err := parser.Parse(ctx, input)
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	if strings.Contains(msg, "unterminated string literal") {
+		// Only raw strings may span lines
+		return strings.Count(input, "`")%2 == 1
+	}
+	if strings.Contains(msg, "unterminated") || strings.Contains(msg, "end of file") {
+		return true
+	}
+	return hasUnclosedBrackets(input)
+}
+
+// Returns true if more brackets are opened than closed in the input.
+func hasUnclosedBrackets(input string) bool {
+	l := lexer.New(input)
+	depth := 0
+	for {
+		tok, err := l.Next()
+		if err != nil || tok.Type == token.EOF {
+			break
+		}
+		switch tok.Type {
+		case token.LPAREN, token.LBRACKET, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACKET, token.RBRACE:
+			depth--
+		}
+	}
+	return depth > 0
+}
@@ -5,228 +5,120 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path"
 	"strings"
 
 	"atomicgo.dev/keyboard"
 	"atomicgo.dev/keyboard/keys"
 	"github.com/fatih/color"
 	"github.com/itrn0/risor"
-	"github.com/itrn0/risor/compiler"
-	"github.com/itrn0/risor/object"
-	"github.com/itrn0/risor/parser"
-	"github.com/itrn0/risor/vm"
-)
-
-const (
-	clearLine   = "\033[2K\r"
-	moveBack    = "\033[%dD"
-	moveForward = "\033[%dC"
 )
 
+// Run starts a REPL that reads input from the keyboard until Ctrl-D is pressed.
+// History is saved in the user's config directory. Input continues on the
+// next line while it is incomplete, e.g. within an unterminated block, and
+// Tab completes names. Lines beginning with a colon are meta-commands; enter
+// :help to list them.
 func Run(ctx context.Context, options []risor.Option) error {
 	color.New(color.Bold).Println("Risor")
 	fmt.Println("")
-	fmt.Printf(">>> ")
-
-	var column int
-	var historyIndex int
-	var history []string
-	var accumulate string
 
-	// Read execution history just like Python's REPL.
-	var historyPath string
-	homeDir, err := os.UserHomeDir()
-	if err == nil {
-		historyPath = path.Join(homeDir, ".risor_history")
-		historyData, err := os.ReadFile(historyPath)
-		if err == nil {
-			history = strings.Split(string(historyData), "\n")
-			historyIndex = len(history) - 1
+	cfg := risor.NewConfig(options...)
+	s := newSession(cfg, os.Stdout)
+	h := loadHistory(defaultHistoryPath())
+	e := &editor{out: os.Stdout}
+	e.render()
+
+	// The input being entered before moving through the history
+	var draft string
+
+	submit := func() {
+		input := e.String()
+		e.finish()
+		h.add(strings.TrimRight(input, "\n"))
+		if isCommand(input) {
+			s.runCommand(ctx, input)
+		} else if strings.TrimSpace(input) != "" {
+			s.eval(ctx, input)
 		}
+		e.reset()
 	}
 
-	appendToHistory := func(line string) {
-		if historyPath != "" {
-			f, err := os.OpenFile(historyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
-			if err != nil {
-				return
-			}
-			defer f.Close()
-			f.WriteString(line + "\n")
+	complete := func() {
+		start, candidates := s.complete(e.text, e.pos)
+		if len(candidates) == 0 {
+			return
 		}
+		if prefix := commonPrefix(candidates); len(candidates) == 1 ||
+			len([]rune(prefix)) > e.pos-start {
+			e.replace(start, prefix)
+			return
+		}
+		// Show the choices below the input, then draw the input again
+		e.finish()
+		fmt.Fprint(os.Stdout, strings.Join(candidates, "  ")+"\r\n")
 	}
 
-	getLineText := func() string {
-		return clearLine + ">>> " + accumulate
-	}
-
-	r := risor.NewConfig()
-	for _, opt := range options {
-		opt(r)
-	}
-
-	evalFunc := getEvaluator(r)
-
-	// This could certainly use a refactor! But it works for now.
 	return keyboard.Listen(func(key keys.Key) (stop bool, err error) {
 		switch key.Code {
 		case keys.Enter:
-			fmt.Printf("\n")
-			evalFunc(ctx, accumulate)
-			appendToHistory(accumulate)
-			history = append(history, accumulate)
-			historyIndex = len(history)
-			accumulate = ""
-			fmt.Print(getLineText())
-			column = 0
-		case keys.RuneKey, keys.Space, keys.Tab:
-			if column < len(accumulate) {
-				rest := accumulate[column:]
-				restLen := len(rest)
-				accumulate = accumulate[:column] + string(key.Runes) + rest
-				fmt.Print(getLineText() + fmt.Sprintf(moveBack, restLen))
+			input := e.String()
+			if !isCommand(input) && isIncomplete(ctx, input) && !e.endsWithBlankLine() {
+				e.pos = len(e.text)
+				e.insert('\n')
 			} else {
-				accumulate += string(key.Runes)
-				fmt.Print(getLineText())
+				submit()
 			}
-			column += len(key.Runes)
+		case keys.RuneKey, keys.Space:
+			e.insert(key.Runes...)
+		case keys.Tab:
+			complete()
 		case keys.Backspace:
-			if len(accumulate) > 0 {
-				if column < len(accumulate) {
-					rest := accumulate[column:]
-					restLen := len(rest)
-					if column > 0 {
-						accumulate = accumulate[:column-1] + rest
-					}
-					fmt.Print(getLineText() + fmt.Sprintf(moveBack, restLen))
-				} else {
-					accumulate = accumulate[:len(accumulate)-1]
-					fmt.Print(getLineText())
-				}
-				if column > 0 {
-					column--
-				}
-			}
+			e.backspace()
 		case keys.Delete:
-			if len(accumulate) > 0 {
-				if column < len(accumulate) {
-					rest := accumulate[column+1:]
-					restLen := len(rest)
-					if restLen > 0 {
-						accumulate = accumulate[:column] + rest
-						fmt.Print(getLineText() + fmt.Sprintf(moveBack, restLen))
-					} else {
-						accumulate = accumulate[:column]
-						fmt.Print(getLineText())
-					}
-				}
-			}
+			e.delete()
 		case keys.Up:
-			if historyIndex > 0 {
-				historyIndex--
+			if e.up() {
+				break
 			}
-			if historyIndex < len(history) {
-				accumulate = history[historyIndex]
-				column = len(accumulate)
-				fmt.Print(getLineText())
+			if h.index == len(h.entries) {
+				draft = e.String()
+			}
+			if entry, ok := h.previous(); ok {
+				e.set(entry)
 			}
 		case keys.Down:
-			if historyIndex < len(history)-1 {
-				historyIndex++
+			if e.down() {
+				break
 			}
-			if historyIndex < len(history) {
-				accumulate = history[historyIndex]
-				column = len(accumulate)
-				fmt.Print(getLineText())
+			if entry, ok := h.next(); ok {
+				e.set(entry)
 			} else {
-				column = 0
-				accumulate = ""
-				fmt.Print(getLineText())
+				e.set(draft)
 			}
 		case keys.Left:
-			if column > 0 {
-				fmt.Printf(moveBack, 1)
-				column--
-			}
+			e.left()
 		case keys.Right:
-			if column < len(accumulate) {
-				fmt.Printf(moveForward, 1)
-				column++
-			}
+			e.right()
 		case keys.CtrlA:
-			fmt.Print(getLineText() + strings.Repeat("\b", len(accumulate)))
-			column = 0
+			e.home()
 		case keys.CtrlE:
-			fmt.Printf(moveForward, len(accumulate)-column)
-			column = len(accumulate)
-		case keys.CtrlC, keys.CtrlD:
-			fmt.Println()
-			return true, nil
-		}
-		return false, nil
-	})
-}
-
-func getEvaluator(cfg *risor.Config) func(ctx context.Context, source string) (object.Object, error) {
-	var c *compiler.Compiler
-	var v *vm.VirtualMachine
-
-	return func(ctx context.Context, source string) (object.Object, error) {
-		if c == nil {
-			var err error
-			c, err = compiler.New(cfg.CompilerOpts()...)
-			if err != nil {
-				return nil, err
+			e.end()
+		case keys.CtrlC:
+			// Discard the input, or exit if there is none
+			if len(e.text) == 0 {
+				fmt.Println()
+				return true, nil
 			}
-		}
-
-		ast, err := parser.Parse(ctx, source)
-		if err != nil {
-			color.Red(err.Error())
-			return nil, err
-		}
-
-		code, err := c.Compile(ast)
-		if err != nil {
-			color.Red(err.Error())
-			return nil, err
-		}
-
-		if v == nil {
-			v = vm.New(code, cfg.VMOpts()...)
-		}
-		if err := v.Run(ctx); err != nil {
-			// Update the IP to be after the last instruction, so that next
-			// time around we start in the right location.
-			v.SetIP(code.InstructionCount())
-			color.Red(err.Error())
-			return nil, err
-		}
-
-		result, ok := v.TOS()
-		if !ok || result == nil {
-			return object.Nil, nil
-		}
-
-		switch result := result.(type) {
-		case *object.Error:
-			errStr := result.Value().Error()
-			if result.IsRaised() {
-				color.Red(errStr)
-			} else {
-				color.Magenta(errStr)
+			e.finish()
+			e.reset()
+		case keys.CtrlD:
+			if len(e.text) == 0 {
+				fmt.Println()
+				return true, nil
 			}
-		case *object.Int, *object.Float, *object.Bool:
-			color.Yellow(result.Inspect())
-		case *object.String:
-			color.Green(result.Inspect())
-		case *object.Builtin, *object.Module:
-			color.New(color.Bold).Println(result.Inspect())
-		case *object.NilType:
-		default:
-			fmt.Println(result.Inspect())
+			e.delete()
 		}
-		return result, nil
-	}
+		e.render()
+		return false, nil
+	})
 }
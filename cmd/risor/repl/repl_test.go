@@ -0,0 +1,244 @@
+package repl
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/itrn0/risor"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	color.NoColor = true
+}
+
+func newTestSession() (*session, *bytes.Buffer) {
+	var out bytes.Buffer
+	return newSession(risor.NewConfig(), &out), &out
+}
+
+func TestIsIncomplete(t *testing.T) {
+	ctx := context.Background()
+	incomplete := []string{
+		"func f() {",
+		"if x {\n  print(x)",
+		"[1, 2,",
+		"foo(",
+		"x := `abc",
+		"struct Point {",
+		"switch x {",
+		"func(a,",
+	}
+	for _, input := range incomplete {
+		require.True(t, isIncomplete(ctx, input), input)
+	}
+	complete := []string{
+		"x := 1",
+		"func f() {\n  return 1\n}",
+		`"abc`,
+		"x := )",
+		"1 +",
+		"",
+	}
+	for _, input := range complete {
+		require.False(t, isIncomplete(ctx, input), input)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "risor", "history")
+	h := loadHistory(path)
+	require.Empty(t, h.entries)
+	h.add("x := 1")
+	h.add("x := 1")
+	h.add("   ")
+	h.add("func f() {\n  return 1\n}")
+
+	h = loadHistory(path)
+	require.Equal(t, []string{"x := 1", "func f() {\n  return 1\n}"}, h.entries)
+	entry, ok := h.previous()
+	require.True(t, ok)
+	require.Equal(t, "func f() {\n  return 1\n}", entry)
+	entry, ok = h.previous()
+	require.True(t, ok)
+	require.Equal(t, "x := 1", entry)
+	_, ok = h.previous()
+	require.False(t, ok)
+	entry, ok = h.next()
+	require.True(t, ok)
+	require.Equal(t, "func f() {\n  return 1\n}", entry)
+	_, ok = h.next()
+	require.False(t, ok)
+}
+
+func TestHistoryLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	var data []byte
+	for i := 0; i < maxHistory+10; i++ {
+		data = append(data, []byte("line\n")...)
+	}
+	// Unquoted entries from older history files are read as they are
+	require.Nil(t, os.WriteFile(path, data, 0o600))
+	h := loadHistory(path)
+	require.Len(t, h.entries, maxHistory)
+	require.Equal(t, "line", h.entries[0])
+	h = loadHistory(path)
+	require.Len(t, h.entries, maxHistory)
+}
+
+func TestComplete(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestSession()
+
+	complete := func(input string) (string, []string) {
+		text := []rune(input)
+		start, candidates := s.complete(text, len(text))
+		return string(text[start:]), candidates
+	}
+
+	// Globals and keywords
+	prefix, candidates := complete("x := stri")
+	require.Equal(t, "stri", prefix)
+	require.Contains(t, candidates, "string")
+	require.Contains(t, candidates, "strings")
+	_, candidates = complete("fun")
+	require.Equal(t, []string{"func"}, candidates)
+
+	// Module attributes
+	prefix, candidates = complete("strings.to_")
+	require.Equal(t, "to_", prefix)
+	require.Equal(t, []string{"to_lower", "to_upper"}, candidates)
+
+	// Methods of values held in variables, once they are defined
+	_, err := s.run(ctx, `name := "risor"; items := [1, 2]`)
+	require.Nil(t, err)
+	_, candidates = complete("name.has_")
+	require.Equal(t, []string{"has_prefix", "has_suffix"}, candidates)
+	_, candidates = complete("print(items.app")
+	require.Equal(t, []string{"append"}, candidates)
+	_, candidates = complete("nam")
+	require.Equal(t, []string{"name"}, candidates)
+
+	// Nothing to complete
+	_, candidates = complete("missing.x")
+	require.Empty(t, candidates)
+	_, candidates = complete("name.zzz")
+	require.Empty(t, candidates)
+}
+
+func TestCommonPrefix(t *testing.T) {
+	require.Equal(t, "to_", commonPrefix([]string{"to_lower", "to_upper"}))
+	require.Equal(t, "abc", commonPrefix([]string{"abc"}))
+	require.Equal(t, "", commonPrefix([]string{"a", "b"}))
+	require.Equal(t, "", commonPrefix(nil))
+}
+
+func TestSessionEval(t *testing.T) {
+	ctx := context.Background()
+	s, out := newTestSession()
+	_, err := s.eval(ctx, "x := 40")
+	require.Nil(t, err)
+	result, err := s.eval(ctx, "x + 2")
+	require.Nil(t, err)
+	require.Equal(t, "42", result.Inspect())
+	require.Equal(t, "42\n", out.String())
+
+	// The session continues after an error
+	out.Reset()
+	_, err = s.eval(ctx, "undefined_thing")
+	require.NotNil(t, err)
+	require.Contains(t, out.String(), "undefined variable")
+	result, err = s.eval(ctx, "x")
+	require.Nil(t, err)
+	require.Equal(t, "40", result.Inspect())
+}
+
+func TestTypeCommand(t *testing.T) {
+	ctx := context.Background()
+	s, out := newTestSession()
+	require.Nil(t, s.runCommand(ctx, ":type [1, 2]"))
+	require.Equal(t, "list\n", out.String())
+
+	out.Reset()
+	require.NotNil(t, s.runCommand(ctx, ":type"))
+	require.Equal(t, "usage: :type <expression>\n", out.String())
+}
+
+func TestDisCommand(t *testing.T) {
+	ctx := context.Background()
+	s, out := newTestSession()
+	require.Nil(t, s.runCommand(ctx, ":dis 1 + 2"))
+	require.Contains(t, out.String(), "BINARY_OP")
+
+	_, err := s.run(ctx, "func add(a, b) { return a + b }")
+	require.Nil(t, err)
+	out.Reset()
+	require.Nil(t, s.runCommand(ctx, ":dis add"))
+	require.Contains(t, out.String(), "LOAD_FAST")
+	require.Contains(t, out.String(), "RETURN_VALUE")
+}
+
+func TestLoadCommand(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "lib.risor")
+	require.Nil(t, os.WriteFile(path, []byte("func double(x) { return x * 2 }\n"), 0o600))
+
+	s, out := newTestSession()
+	require.Nil(t, s.runCommand(ctx, ":load "+path))
+	result, err := s.run(ctx, "double(21)")
+	require.Nil(t, err)
+	require.Equal(t, "42", result.Inspect())
+
+	out.Reset()
+	require.NotNil(t, s.runCommand(ctx, ":load "+filepath.Join(t.TempDir(), "missing.risor")))
+	require.Contains(t, out.String(), "no such file")
+}
+
+func TestUnknownCommand(t *testing.T) {
+	s, out := newTestSession()
+	require.NotNil(t, s.runCommand(context.Background(), ":nope"))
+	require.Equal(t, "unknown command \":nope\" (try :help)\n", out.String())
+
+	out.Reset()
+	require.Nil(t, s.runCommand(context.Background(), ":help"))
+	for _, cmd := range commands {
+		require.Contains(t, out.String(), cmd.usage)
+	}
+}
+
+func TestEditor(t *testing.T) {
+	var out bytes.Buffer
+	e := &editor{out: &out}
+	e.insert([]rune("if x {")...)
+	e.insert('\n')
+	e.insert([]rune("  y")...)
+	require.Equal(t, "if x {\n  y", e.String())
+	require.Equal(t, 2, e.lineCount())
+
+	e.render()
+	require.Equal(t, "\r\033[J>>> if x {\r\n...   y\r\033[7C", out.String())
+	require.Equal(t, 1, e.row)
+
+	// Moving up and down keeps the column
+	require.True(t, e.up())
+	row, col := e.cursor()
+	require.Equal(t, 0, row)
+	require.Equal(t, 3, col)
+	require.False(t, e.up())
+	require.True(t, e.down())
+	require.False(t, e.down())
+
+	e.home()
+	e.backspace()
+	require.Equal(t, "if x {  y", e.String())
+	e.end()
+	e.replace(e.pos-1, "yes")
+	require.Equal(t, "if x {  yes", e.String())
+	require.False(t, e.endsWithBlankLine())
+	e.set("if x {\n  ")
+	require.True(t, e.endsWithBlankLine())
+}
@@ -0,0 +1,147 @@
+package repl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/itrn0/risor"
+	"github.com/itrn0/risor/compiler"
+	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/parser"
+	"github.com/itrn0/risor/vm"
+)
+
+// A REPL session. Code entered in the session is compiled incrementally into
+// one code object, so that variables persist from one input to the next.
+type session struct {
+	cfg      *risor.Config
+	compiler *compiler.Compiler
+	machine  *vm.VirtualMachine
+	out      io.Writer
+}
+
+func newSession(cfg *risor.Config, out io.Writer) *session {
+	return &session{cfg: cfg, out: out}
+}
+
+// Compiles and runs the given source, returning the resulting value.
+func (s *session) run(ctx context.Context, source string, opts ...parser.Option) (object.Object, error) {
+	if s.compiler == nil {
+		c, err := compiler.New(s.cfg.CompilerOpts()...)
+		if err != nil {
+			return nil, err
+		}
+		s.compiler = c
+	}
+	ast, err := parser.Parse(ctx, source, opts...)
+	if err != nil {
+		return nil, err
+	}
+	code, err := s.compiler.Compile(ast)
+	if err != nil {
+		return nil, err
+	}
+	if s.machine == nil {
+		s.machine = vm.New(code, s.cfg.VMOpts()...)
+	}
+	if err := s.machine.Run(ctx); err != nil {
+		// Update the IP to be after the last instruction, so that next
+		// time around we start in the right location.
+		s.machine.SetIP(code.InstructionCount())
+		return nil, err
+	}
+	result, ok := s.machine.TOS()
+	if !ok || result == nil {
+		return object.Nil, nil
+	}
+	return result, nil
+}
+
+// Runs the given source and prints the result or error.
+func (s *session) eval(ctx context.Context, source string, opts ...parser.Option) (object.Object, error) {
+	result, err := s.run(ctx, source, opts...)
+	if err != nil {
+		s.printError(err)
+		return nil, err
+	}
+	s.printResult(result)
+	return result, nil
+}
+
+func (s *session) printError(err error) {
+	color.New(color.FgRed).Fprintln(s.out, err.Error())
+}
+
+func (s *session) printResult(result object.Object) {
+	switch result := result.(type) {
+	case *object.Error:
+		errStr := result.Value().Error()
+		if result.IsRaised() {
+			color.New(color.FgRed).Fprintln(s.out, errStr)
+		} else {
+			color.New(color.FgMagenta).Fprintln(s.out, errStr)
+		}
+	case *object.Int, *object.Float, *object.Bool:
+		color.New(color.FgYellow).Fprintln(s.out, result.Inspect())
+	case *object.String:
+		color.New(color.FgGreen).Fprintln(s.out, result.Inspect())
+	case *object.Builtin, *object.Module:
+		color.New(color.Bold).Fprintln(s.out, result.Inspect())
+	case *object.NilType:
+	default:
+		fmt.Fprintln(s.out, result.Inspect())
+	}
+}
+
+// Returns the names of the globals available in the session, including the
+// variables defined by code entered so far.
+func (s *session) globalNames() []string {
+	if s.machine != nil {
+		return s.machine.GlobalNames()
+	}
+	return s.cfg.GlobalNames()
+}
+
+// Returns the current value of the global with the given name.
+func (s *session) lookup(name string) (object.Object, bool) {
+	if s.machine != nil {
+		value, err := s.machine.Get(name)
+		if err != nil || value == nil {
+			return nil, false
+		}
+		return value, true
+	}
+	value, ok := s.cfg.Globals()[name]
+	if !ok {
+		return nil, false
+	}
+	if obj, ok := value.(object.Object); ok {
+		return obj, true
+	}
+	obj := object.FromGoType(value)
+	if _, isErr := obj.(*object.Error); isErr {
+		return nil, false
+	}
+	return obj, true
+}
+
+// Resolves a dotted path of names, like "strings.to_upper", to a value. Only
+// globals and their attributes are followed, so nothing is evaluated.
+func (s *session) resolve(path string) (object.Object, error) {
+	parts := strings.Split(path, ".")
+	obj, ok := s.lookup(parts[0])
+	if !ok {
+		return nil, fmt.Errorf("undefined variable %q", parts[0])
+	}
+	for _, name := range parts[1:] {
+		attr, ok := obj.GetAttr(name)
+		if !ok {
+			return nil, fmt.Errorf("attribute %q not found on %s object", name, obj.Type())
+		}
+		obj = attr
+	}
+	return obj, nil
+}
@@ -12,8 +12,11 @@ import (
 	"github.com/fatih/color"
 	"github.com/itrn0/risor"
 	"github.com/itrn0/risor/cmd/risor/repl"
+	"github.com/itrn0/risor/compiler"
 	"github.com/itrn0/risor/errz"
+	"github.com/itrn0/risor/object"
 	ros "github.com/itrn0/risor/os"
+	"github.com/itrn0/risor/vm"
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -38,6 +41,7 @@ func init() {
 	rootCmd.PersistentFlags().StringArrayP("mount", "m", []string{}, "Mount a filesystem")
 	rootCmd.PersistentFlags().Bool("no-default-globals", false, "Disable the default globals")
 	rootCmd.PersistentFlags().String("modules", ".", "Path to library modules")
+	rootCmd.PersistentFlags().String("log-format", "text", "Format of records written by the log module (text or json)")
 	rootCmd.PersistentFlags().BoolP("help", "h", false, "Help for Risor")
 
 	viper.BindPFlag("code", rootCmd.PersistentFlags().Lookup("code"))
@@ -48,22 +52,30 @@ func init() {
 	viper.BindPFlag("mount", rootCmd.PersistentFlags().Lookup("mount"))
 	viper.BindPFlag("no-default-globals", rootCmd.PersistentFlags().Lookup("no-default-globals"))
 	viper.BindPFlag("modules", rootCmd.PersistentFlags().Lookup("modules"))
+	viper.BindPFlag("log-format", rootCmd.PersistentFlags().Lookup("log-format"))
 	viper.BindPFlag("help", rootCmd.PersistentFlags().Lookup("help"))
 
 	// Root command flags
 	rootCmd.Flags().Bool("timing", false, "Show timing information")
 	rootCmd.Flags().StringP("output", "o", "", "Set the output format")
 	rootCmd.Flags().Bool("no-repl", false, "Disable the REPL")
+	rootCmd.Flags().String("profile", "", "Write a profile of the script's Risor functions in pprof format")
 	rootCmd.RegisterFlagCompletionFunc("output",
 		cobra.FixedCompletions(
 			outputFormatsCompletion,
 			cobra.ShellCompDirectiveNoFileComp,
 		))
+	rootCmd.RegisterFlagCompletionFunc("log-format",
+		cobra.FixedCompletions(
+			logFormatsCompletion,
+			cobra.ShellCompDirectiveNoFileComp,
+		))
 	rootCmd.Flags().SetInterspersed(false)
 
 	viper.BindPFlag("timing", rootCmd.Flags().Lookup("timing"))
 	viper.BindPFlag("output", rootCmd.Flags().Lookup("output"))
 	viper.BindPFlag("no-repl", rootCmd.Flags().Lookup("no-repl"))
+	viper.BindPFlag("profile", rootCmd.Flags().Lookup("profile"))
 
 	viper.AutomaticEnv()
 }
@@ -180,9 +192,38 @@ var rootCmd = &cobra.Command{
 			fatal(err)
 		}
 
-		// Execute the code
+		// Errors are reported relative to the script file, if there is one
+		if len(args) > 0 {
+			opts = append(opts, risor.WithFilename(args[0]))
+		}
+
+		// Optionally profile the script, for viewing with "go tool pprof"
+		var profiler *vm.Profiler
+		profilePath := viper.GetString("profile")
+		if profilePath != "" {
+			profiler = vm.NewProfiler(vm.DefaultProfileInterval)
+			opts = append(opts, risor.WithProfiler(profiler))
+		}
+
+		// Execute the code. Files produced by `risor compile` contain bytecode
+		// which is run directly, skipping the parse and compile steps.
 		start := time.Now()
-		result, err := risor.Eval(ctx, code, opts...)
+		var result object.Object
+		if compiler.IsBinaryCode([]byte(code)) {
+			var main *compiler.Code
+			main, err = compiler.UnmarshalCodeBinary([]byte(code))
+			if err != nil {
+				fatal(err)
+			}
+			result, err = risor.EvalCode(ctx, main, opts...)
+		} else {
+			result, err = risor.Eval(ctx, code, opts...)
+		}
+		if profiler != nil {
+			if err := writeProfile(profilePath, profiler); err != nil {
+				fatal(err)
+			}
+		}
 		if err != nil {
 			errMsg := err.Error()
 			if friendlyErr, ok := err.(errz.FriendlyError); ok {
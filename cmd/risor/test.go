@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/itrn0/risor"
+	"github.com/itrn0/risor/compiler"
+	rtesting "github.com/itrn0/risor/modules/testing"
+	"github.com/itrn0/risor/parser"
+	"github.com/itrn0/risor/vm"
+	"github.com/spf13/cobra"
+)
+
+const testExample = `  risor test
+
+  risor test ./path/to/dir/...
+
+  risor test -v --run add ./math_test.risor
+
+  risor test --parallel 8 --junit report.xml ./...
+
+  risor test --cover --coverprofile cover.out --lcov lcov.info ./...`
+
+var testCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Run Risor tests",
+	Long: `Run the tests in Risor test files.
+
+Test files are named *_test.risor. A directory argument runs the test files in
+that directory, while a path ending in /... also runs those in its
+subdirectories. With no arguments, tests are found in the current directory and
+its subdirectories.
+
+Tests are global functions whose names begin with test_. Each is passed a test
+object as its first argument, which is used to make assertions:
+
+  func test_add(t) {
+      t.assert_equal(1 + 2, 3)
+  }
+
+Any further parameters are supplied by fixtures, which are global functions
+named fixture_<parameter>. Each test runs in its own VM, so tests may run in
+parallel without sharing state. The command exits with a non-zero status if
+any test fails.
+
+With --cover, the lines of code run by the tests are recorded and the
+percentage covered is reported for each file other than the test files. Use
+--coverprofile and --lcov to write coverage profiles for other tools.`,
+	Example: testExample,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		processGlobalFlags()
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		parallel, _ := cmd.Flags().GetInt("parallel")
+		format, _ := cmd.Flags().GetString("format")
+		junitPath, _ := cmd.Flags().GetString("junit")
+		runPattern, _ := cmd.Flags().GetString("run")
+		cover, _ := cmd.Flags().GetBool("cover")
+		coverProfilePath, _ := cmd.Flags().GetString("coverprofile")
+		lcovPath, _ := cmd.Flags().GetString("lcov")
+
+		runner := &rtesting.Runner{Parallel: parallel}
+		if runPattern != "" {
+			filter, err := regexp.Compile(runPattern)
+			if err != nil {
+				fatal(fmt.Errorf("invalid --run pattern: %w", err))
+			}
+			runner.Filter = filter
+		}
+		if len(args) == 0 {
+			args = []string{"./..."}
+		}
+		paths, err := testFiles(args)
+		if err != nil {
+			fatal(err)
+		}
+		opts := getRisorOptions()
+		var coverage *vm.Coverage
+		if cover || coverProfilePath != "" || lcovPath != "" {
+			coverage = vm.NewCoverage()
+			opts = append(opts, risor.WithCoverage(coverage))
+		}
+		results, err := runTests(ctx, runner, paths, opts)
+		if err != nil {
+			fatal(err)
+		}
+		if err := writeTestReport(os.Stdout, format, results, verbose); err != nil {
+			fatal(err)
+		}
+		if coverage != nil {
+			lines := coverageLines(coverage)
+			if format == "" || format == "text" {
+				writeCoverageSummary(os.Stdout, lines)
+			}
+			if coverProfilePath != "" {
+				if err := writeFile(coverProfilePath, func(w io.Writer) error {
+					return vm.WriteCoverProfile(w, lines)
+				}); err != nil {
+					fatal(err)
+				}
+			}
+			if lcovPath != "" {
+				if err := writeFile(lcovPath, func(w io.Writer) error {
+					return vm.WriteLCOV(w, lines)
+				}); err != nil {
+					fatal(err)
+				}
+			}
+		}
+		if junitPath != "" {
+			if err := writeFile(junitPath, func(w io.Writer) error {
+				return rtesting.WriteJUnit(w, results)
+			}); err != nil {
+				fatal(err)
+			}
+		}
+		summary := rtesting.Summarize(results)
+		if summary.Failed > 0 || summary.Errored > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// Returns the test files given the command line arguments. Arguments may be
+// files, directories, or directories followed by /... to search them
+// recursively.
+func testFiles(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		recursive := false
+		if arg == "..." || strings.HasSuffix(arg, "/...") {
+			recursive = true
+			arg = strings.TrimSuffix(strings.TrimSuffix(arg, "..."), "/")
+			if arg == "" {
+				arg = "."
+			}
+		}
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != arg && !recursive {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasSuffix(path, "_test.risor") {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+// Parses and compiles the test files, then runs their tests.
+func runTests(ctx context.Context, runner *rtesting.Runner, paths []string, opts []risor.Option) ([]*rtesting.Result, error) {
+	cfg := risor.NewConfig(opts...)
+	var files []*rtesting.File
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		ast, err := parser.Parse(ctx, string(data), parser.WithFile(path))
+		if err != nil {
+			return nil, err
+		}
+		code, err := compiler.Compile(ast, cfg.CompilerOpts()...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		files = append(files, &rtesting.File{Path: path, Code: code})
+	}
+	runner.VMOpts = cfg.VMOpts()
+	return runner.Run(ctx, files), nil
+}
+
+func writeTestReport(w io.Writer, format string, results []*rtesting.Result, verbose bool) error {
+	switch strings.ToLower(format) {
+	case "", "text":
+		return rtesting.WriteText(w, results, verbose)
+	case "tap":
+		return rtesting.WriteTAP(w, results)
+	case "junit":
+		return rtesting.WriteJUnit(w, results)
+	default:
+		return fmt.Errorf("unknown test output format: %s", format)
+	}
+}
+
+// Returns the coverage of the lines in files other than test files.
+func coverageLines(coverage *vm.Coverage) []vm.CoverageLine {
+	var lines []vm.CoverageLine
+	for _, line := range coverage.Lines() {
+		if line.File != "" && !strings.HasSuffix(line.File, "_test.risor") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// Writes the percentage of lines covered in each file and overall.
+func writeCoverageSummary(w io.Writer, lines []vm.CoverageLine) {
+	if len(lines) == 0 {
+		fmt.Fprintln(w, "coverage: no code outside test files was run")
+		return
+	}
+	for i := 0; i < len(lines); {
+		j := i
+		for j < len(lines) && lines[j].File == lines[i].File {
+			j++
+		}
+		fmt.Fprintf(w, "coverage: %.1f%% of lines in %s\n", vm.CoveragePercent(lines[i:j]), lines[i].File)
+		i = j
+	}
+	fmt.Fprintf(w, "coverage: %.1f%% of lines\n", vm.CoveragePercent(lines))
+}
+
+// Creates the file at the given path and calls write to write its contents.
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(testCmd)
+	testCmd.Flags().BoolP("verbose", "v", false, "List all tests and their output, including tests that pass")
+	testCmd.Flags().IntP("parallel", "p", 1, "Maximum number of tests to run at once")
+	testCmd.Flags().String("format", "text", "Output format: text, tap or junit")
+	testCmd.Flags().String("junit", "", "Also write a JUnit XML report to this file")
+	testCmd.Flags().Bool("cover", false, "Report the percentage of lines run by the tests, excluding test files")
+	testCmd.Flags().String("coverprofile", "", "Write a coverage profile in Go's coverprofile format to this file")
+	testCmd.Flags().String("lcov", "", "Write a coverage report in LCOV format to this file")
+	testCmd.Flags().String("run", "", "Run only the tests whose names match this regular expression")
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itrn0/risor"
+	rtesting "github.com/itrn0/risor/modules/testing"
+	"github.com/itrn0/risor/vm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, source string) string {
+		path := filepath.Join(dir, name)
+		require.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.Nil(t, os.WriteFile(path, []byte(source), 0o644))
+		return path
+	}
+	a := write("a_test.risor", "func test_a(t) { t.assert_equal(strings.to_upper(\"a\"), \"A\") }")
+	b := write("sub/b_test.risor", "func test_b(t) { t.fail(\"nope\") }\nfunc helper() {}")
+	write("sub/c.risor", "func test_c(t) {}")
+
+	paths, err := testFiles([]string{dir})
+	require.Nil(t, err)
+	require.Equal(t, []string{a}, paths)
+
+	paths, err = testFiles([]string{dir + "/..."})
+	require.Nil(t, err)
+	require.Equal(t, []string{a, b}, paths)
+
+	results, err := runTests(context.Background(), &rtesting.Runner{}, paths, getRisorOptions())
+	require.Nil(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, rtesting.Passed, results[0].Status)
+	require.Equal(t, rtesting.Failed, results[1].Status)
+	require.Equal(t, "nope", results[1].Message)
+
+	write("bad_test.risor", "func test_x(t) { y }")
+	_, err = runTests(context.Background(), &rtesting.Runner{}, []string{filepath.Join(dir, "bad_test.risor")}, getRisorOptions())
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "bad_test.risor")
+}
+
+func TestTestCoverage(t *testing.T) {
+	dir := t.TempDir()
+	lib := "func sign(n) {\n\tif n < 0 {\n\t\treturn -1\n\t}\n\treturn 1\n}\n"
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "lib.risor"), []byte(lib), 0o644))
+	test := filepath.Join(dir, "lib_test.risor")
+	require.Nil(t, os.WriteFile(test, []byte("import lib\nfunc test_sign(t) { t.assert_equal(lib.sign(1), 1) }"), 0o644))
+
+	coverage := vm.NewCoverage()
+	opts := append(getRisorOptions(), risor.WithLocalImporter(dir), risor.WithCoverage(coverage))
+	results, err := runTests(context.Background(), &rtesting.Runner{}, []string{test}, opts)
+	require.Nil(t, err)
+	require.Equal(t, rtesting.Passed, results[0].Status)
+
+	lines := coverageLines(coverage)
+	var covered []int
+	for _, line := range lines {
+		require.Equal(t, filepath.Join(dir, "lib.risor"), line.File)
+		if line.Count > 0 {
+			covered = append(covered, line.Line)
+		}
+	}
+	require.Equal(t, []int{1, 2, 5}, covered)
+	require.Len(t, lines, 4)
+
+	var out bytes.Buffer
+	writeCoverageSummary(&out, lines)
+	require.Contains(t, out.String(), "coverage: 75.0% of lines\n")
+}
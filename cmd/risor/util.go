@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"os/signal"
 	"runtime/pprof"
@@ -17,6 +19,7 @@ import (
 	"github.com/itrn0/risor/object"
 	ros "github.com/itrn0/risor/os"
 	"github.com/itrn0/risor/os/s3fs"
+	"github.com/itrn0/risor/vm"
 	"github.com/mattn/go-isatty"
 	"github.com/spf13/viper"
 )
@@ -70,6 +73,21 @@ func getScriptArgs(positionalArgs []string) ([]string, []string) {
 
 var outputFormatsCompletion = []string{"json", "text"}
 
+var logFormatsCompletion = []string{"json", "text"}
+
+// Returns a logger for the log module that writes records to w in the given
+// format.
+func newLogger(format string, w io.Writer) (*slog.Logger, error) {
+	switch strings.ToLower(format) {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, nil)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, nil)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format: %s", format)
+	}
+}
+
 func getOutput(result object.Object, format string) (string, error) {
 	switch strings.ToLower(format) {
 	case "":
@@ -168,6 +186,19 @@ func handleSigForProfiler() {
 	}()
 }
 
+// Writes the work recorded by a profiler to a file in pprof format.
+func writeProfile(path string, profiler *vm.Profiler) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := profiler.WriteProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 // Reads global flags from Viper and adjusts the environment accordingly.
 func processGlobalFlags() {
 	if viper.GetBool("no-color") {
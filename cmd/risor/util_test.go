@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"os"
 	"strings"
 	"testing"
@@ -64,3 +65,20 @@ func TestGetScriptArguments(t *testing.T) {
 		})
 	}
 }
+
+func TestNewLogger(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := newLogger("json", &out)
+	require.Nil(t, err)
+	logger.Info("started", "port", 80)
+	require.Contains(t, out.String(), `"level":"INFO","msg":"started","port":80}`)
+
+	out.Reset()
+	logger, err = newLogger("text", &out)
+	require.Nil(t, err)
+	logger.Warn("slow", "ms", 250)
+	require.Contains(t, out.String(), "level=WARN msg=slow ms=250")
+
+	_, err = newLogger("xml", &out)
+	require.EqualError(t, err, "unknown log format: xml")
+}
@@ -30,9 +30,13 @@ type Code struct {
 	names        []string
 	source       string
 	functionID   string
+	locations    []locationEntry
+	handlers     []ExceptionHandler
+	isGenerator  bool
 
 	// Used during compilation only
 	loops      []*loop
+	tries      []*tryBlock
 	pipeActive bool
 }
 
@@ -53,6 +57,12 @@ func (c *Code) IsNamed() bool {
 	return c.isNamed
 }
 
+// IsGenerator returns true if this is the code of a generator function, which
+// is a function that contains a yield statement.
+func (c *Code) IsGenerator() bool {
+	return c.isGenerator
+}
+
 func (c *Code) FunctionID() string {
 	return c.functionID
 }
@@ -119,6 +129,14 @@ func (c *Code) Global(index int) *Symbol {
 	return c.symbols.Root().Symbol(uint16(index))
 }
 
+func (c *Code) FreeCount() int {
+	return int(c.symbols.FreeCount())
+}
+
+func (c *Code) Free(index int) *Symbol {
+	return c.symbols.Free(uint16(index)).Symbol()
+}
+
 func (c *Code) GlobalNames() []string {
 	root := c.symbols.Root()
 	count := root.Count()
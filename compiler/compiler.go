@@ -40,6 +40,18 @@ type Compiler struct {
 
 	// Increments with each function compiled
 	funcIndex int
+
+	// Source location of the node currently being compiled
+	location SourceLocation
+
+	// Determines which optimizations are applied to compiled code
+	optimizationLevel int
+
+	// Whether types are checked before compiling
+	typeCheck bool
+
+	// Warnings found during the most recent compilation
+	warnings []*CompileError
 }
 
 // Option is a configuration function for a Compiler.
@@ -60,6 +72,27 @@ func WithCode(code *Code) Option {
 	}
 }
 
+// WithOptimizationLevel configures the compiler to optimize the bytecode it
+// produces. Level 0 (OptimizeNone) disables optimization and is the default.
+// Level 1 (OptimizeBasic) folds arithmetic and string concatenation on
+// literals and removes constants that are pushed and immediately popped.
+// Level 2 (OptimizeFull) also threads jumps and removes unreachable code.
+func WithOptimizationLevel(level int) Option {
+	return func(c *Compiler) {
+		c.optimizationLevel = level
+	}
+}
+
+// WithTypeCheck configures the compiler to check the types in the program
+// before compiling it, as described by TypeCheck. The first type error found
+// is returned as the compile error. Without this option, type annotations
+// are ignored.
+func WithTypeCheck() Option {
+	return func(c *Compiler) {
+		c.typeCheck = true
+	}
+}
+
 // Compile the given AST node and return the compiled code object. This is a
 // shorthand for compiler.New(options).Compile(node).
 func Compile(node ast.Node, options ...Option) (*Code, error) {
@@ -77,6 +110,9 @@ func New(options ...Option) (*Compiler, error) {
 	for _, opt := range options {
 		opt(c)
 	}
+	if c.optimizationLevel < OptimizeNone || c.optimizationLevel > OptimizeFull {
+		return nil, fmt.Errorf("compile error: invalid optimization level: %d", c.optimizationLevel)
+	}
 	// Create a default, empty code object to compile into if the caller didn't
 	// supply one. If the caller did supply one, it may be a situation like the
 	// REPL where compilation is done incrementally, as new code is entered.
@@ -107,14 +143,34 @@ func (c *Compiler) Code() *Code {
 	return c.main
 }
 
+// Warnings returns the warnings found by the most recent call to Compile.
+// Warnings describe code that compiles but is likely to be a mistake, such
+// as a match expression that may not match any of its cases.
+func (c *Compiler) Warnings() []*CompileError {
+	return c.warnings
+}
+
 // Compile the given AST node and return the compiled code object.
 func (c *Compiler) Compile(node ast.Node) (*Code, error) {
 	c.failure = nil
+	c.warnings = nil
+	if c.typeCheck {
+		if errs := typeCheck(node, c.globalNames); len(errs) > 0 {
+			return nil, errs[0]
+		}
+	}
 	if c.main.source == "" {
 		c.main.source = node.String()
 	} else {
 		c.main.source = fmt.Sprintf("%s\n%s", c.main.source, node.String())
 	}
+	// Remember what existed before this call, so that only the newly
+	// compiled code is optimized
+	start := len(c.main.instructions)
+	existing := map[*Code]bool{}
+	for _, code := range c.main.Flatten() {
+		existing[code] = true
+	}
 	if err := c.compile(node); err != nil {
 		return nil, err
 	}
@@ -123,11 +179,31 @@ func (c *Compiler) Compile(node ast.Node) (*Code, error) {
 	if c.failure != nil {
 		return nil, c.failure
 	}
+	if c.optimizationLevel > OptimizeNone {
+		for _, code := range c.main.Flatten() {
+			offset := 0
+			if code == c.main {
+				offset = start
+			} else if existing[code] {
+				continue
+			}
+			if err := optimize(code, offset, c.optimizationLevel); err != nil {
+				return nil, err
+			}
+		}
+	}
 	return c.main, nil
 }
 
 // compile the given AST node and all its children.
 func (c *Compiler) compile(node ast.Node) error {
+	// Attribute emitted instructions to the position of this node. Synthetic
+	// nodes have an empty token and inherit the position of their parent.
+	if tok := node.Token(); tok.Type != "" {
+		parentLocation := c.location
+		c.location = NewSourceLocation(tok.StartPosition)
+		defer func() { c.location = parentLocation }()
+	}
 	switch node := node.(type) {
 	case *ast.Nil:
 		if err := c.compileNil(); err != nil {
@@ -189,6 +265,10 @@ func (c *Compiler) compile(node ast.Node) error {
 		if err := c.compileReturn(node); err != nil {
 			return err
 		}
+	case *ast.Yield:
+		if err := c.compileYield(node); err != nil {
+			return err
+		}
 	case *ast.Call:
 		if err := c.compileCall(node); err != nil {
 			return err
@@ -197,6 +277,10 @@ func (c *Compiler) compile(node ast.Node) error {
 		if err := c.compileFunc(node); err != nil {
 			return err
 		}
+	case *ast.Struct:
+		if err := c.compileStruct(node); err != nil {
+			return err
+		}
 	case *ast.List:
 		if err := c.compileList(node); err != nil {
 			return err
@@ -265,6 +349,14 @@ func (c *Compiler) compile(node ast.Node) error {
 		if err := c.compileSwitch(node); err != nil {
 			return err
 		}
+	case *ast.Match:
+		if err := c.compileMatch(node); err != nil {
+			return err
+		}
+	case *ast.Select:
+		if err := c.compileSelect(node); err != nil {
+			return err
+		}
 	case *ast.MultiVar:
 		if err := c.compileMultiVar(node); err != nil {
 			return err
@@ -281,6 +373,10 @@ func (c *Compiler) compile(node ast.Node) error {
 		if err := c.compileDeferStmt(node); err != nil {
 			return err
 		}
+	case *ast.Try:
+		if err := c.compileTry(node); err != nil {
+			return err
+		}
 	case *ast.Send:
 		if err := c.compileSend(node); err != nil {
 			return err
@@ -425,23 +521,14 @@ func (c *Compiler) compileVar(node *ast.Var) error {
 	if err := c.compile(expr); err != nil {
 		return err
 	}
-	sym, err := c.current.symbols.InsertVariable(name)
-	if err != nil {
-		return err
-	}
-	if c.current.parent == nil {
-		c.emit(op.StoreGlobal, sym.Index())
-	} else {
-		c.emit(op.StoreFast, sym.Index())
-	}
-	return nil
+	return c.declareName(name)
 }
 
 func (c *Compiler) compileIdent(node *ast.Ident) error {
 	name := node.Literal()
 	resolution, found := c.current.symbols.Resolve(name)
 	if !found {
-		return fmt.Errorf("compile error: undefined variable %q (line %d)",
+		return newCompileError(node.Token(), "compile error: undefined variable %q (line %d)",
 			name, node.Token().StartPosition.LineNumber())
 	}
 	switch resolution.scope {
@@ -486,7 +573,8 @@ func (c *Compiler) compileMultiVar(node *ast.MultiVar) error {
 		name := names[i]
 		resolution, found := c.current.symbols.Resolve(name)
 		if !found {
-			return fmt.Errorf("compile error: undefined variable %q (line %d)",
+			tok := node.Idents()[i].Token()
+			return newCompileError(tok, "compile error: undefined variable %q (line %d)",
 				name, node.Token().StartPosition.LineNumber())
 		}
 		symbolIndex := resolution.symbol.Index()
@@ -803,7 +891,7 @@ func (c *Compiler) compilePostfix(node *ast.Postfix) error {
 	name := node.Literal()
 	resolution, found := c.current.symbols.Resolve(name)
 	if !found {
-		return fmt.Errorf("compile error: undefined variable %q (line %d)",
+		return newCompileError(node.Token(), "compile error: undefined variable %q (line %d)",
 			name, node.Token().StartPosition.LineNumber())
 	}
 	symbolIndex := resolution.symbol.Index()
@@ -1007,6 +1095,125 @@ func (c *Compiler) compileReceive(node *ast.Receive) error {
 	return nil
 }
 
+func (c *Compiler) compileSelect(node *ast.Select) error {
+	// Push the operands of each channel operation, followed by a flag that
+	// indicates whether it's a send. A send has a channel and a value, while
+	// a receive has only a channel.
+	var commCases []*ast.SelectCase
+	var defaultCase *ast.SelectCase
+	for _, selectCase := range node.Cases() {
+		if selectCase.IsDefault() {
+			defaultCase = selectCase
+			continue
+		}
+		switch comm := selectCase.Comm().(type) {
+		case *ast.Send:
+			if err := c.compile(comm.Channel()); err != nil {
+				return err
+			}
+			if err := c.compile(comm.Value()); err != nil {
+				return err
+			}
+			c.emit(op.True)
+		case *ast.Receive:
+			if err := c.compile(comm.Channel()); err != nil {
+				return err
+			}
+			c.emit(op.False)
+		default:
+			return fmt.Errorf("compile error: invalid select case: %s", comm)
+		}
+		commCases = append(commCases, selectCase)
+	}
+	if len(commCases) > math.MaxUint16 {
+		return fmt.Errorf("compile error: too many cases in select statement")
+	}
+	var hasDefault uint16
+	if defaultCase != nil {
+		hasDefault = 1
+	}
+
+	// The Select opcode pushes the received value, whether the channel was
+	// open, and the index of the chosen case, which is -1 for the default
+	c.emit(op.Select, uint16(len(commCases)), hasDefault)
+
+	var endBlockPosits []int
+	for i, selectCase := range commCases {
+		c.emit(op.Copy, 0)
+		c.emit(op.LoadConst, c.constant(int64(i)))
+		c.emit(op.CompareOp, uint16(op.Equal))
+		nextCasePos := c.emit(op.PopJumpForwardIfFalse, Placeholder)
+		c.emit(op.PopTop)
+		// Names declared by the case are scoped to the case
+		code := c.current
+		code.symbols = code.symbols.NewBlock()
+		err := c.compileSelectCase(selectCase)
+		code.symbols = code.symbols.parent
+		if err != nil {
+			return err
+		}
+		endBlockPosits = append(endBlockPosits, c.emit(op.JumpForward, Placeholder))
+		if err := c.patchJumps([]int{nextCasePos}); err != nil {
+			return err
+		}
+	}
+
+	// The default case. Exactly one case is chosen, so this is reached only
+	// when the default case is.
+	c.emit(op.PopTop)
+	c.emit(op.PopTop)
+	c.emit(op.PopTop)
+	if defaultCase != nil && defaultCase.Block() != nil {
+		if err := c.compile(defaultCase.Block()); err != nil {
+			return err
+		}
+	} else {
+		c.emit(op.Nil)
+	}
+	return c.patchJumps(endBlockPosits)
+}
+
+// Compiles one case of a select statement. The received value and whether the
+// channel was open are on the top of the stack.
+func (c *Compiler) compileSelectCase(node *ast.SelectCase) error {
+	names := node.Names()
+	if len(names) == 2 {
+		if err := c.declareName(names[1].Literal()); err != nil {
+			return err
+		}
+	} else {
+		c.emit(op.PopTop)
+	}
+	if len(names) > 0 {
+		if err := c.declareName(names[0].Literal()); err != nil {
+			return err
+		}
+	} else {
+		c.emit(op.PopTop)
+	}
+	if node.Block() == nil {
+		// Empty case block
+		c.emit(op.Nil)
+		return nil
+	}
+	return c.compile(node.Block())
+}
+
+// Declares a variable in the current scope and stores the value on the top
+// of the stack in it.
+func (c *Compiler) declareName(name string) error {
+	sym, err := c.current.symbols.InsertVariable(name)
+	if err != nil {
+		return err
+	}
+	if c.current.parent == nil {
+		c.emit(op.StoreGlobal, sym.Index())
+	} else {
+		c.emit(op.StoreFast, sym.Index())
+	}
+	return nil
+}
+
 func (c *Compiler) compileSet(node *ast.Set) error {
 	items := node.Items()
 	count := len(items)
@@ -1034,6 +1241,15 @@ func (c *Compiler) compileFunc(node *ast.Func) error {
 		functionName = ident.Literal()
 	}
 
+	// Methods receive the struct instance as an implicit first parameter.
+	// They are named after the struct type so they are identifiable in stack
+	// traces, e.g. "Point.dist".
+	paramIdents := node.Parameters()
+	if node.IsMethod() {
+		functionName = node.ReceiverType().Literal() + "." + functionName
+		paramIdents = append([]*ast.Ident{node.Receiver()}, paramIdents...)
+	}
+
 	// This new code object will store the compiled code for this function.
 	c.funcIndex++
 	functionID := fmt.Sprintf("%d", c.funcIndex)
@@ -1045,9 +1261,10 @@ func (c *Compiler) compileFunc(node *ast.Func) error {
 
 	// Make it quick to look up the index of a parameter
 	paramsIdx := map[string]int{}
-	params := node.ParameterNames()
-	for i, name := range params {
-		paramsIdx[name] = i
+	params := make([]string, 0, len(paramIdents))
+	for i, ident := range paramIdents {
+		params = append(params, ident.Literal())
+		paramsIdx[ident.Literal()] = i
 	}
 
 	// Build an array of default values for parameters, supporting only
@@ -1095,7 +1312,7 @@ func (c *Compiler) compileFunc(node *ast.Func) error {
 	}
 
 	// Add the parameter names to the symbol table
-	for _, arg := range node.Parameters() {
+	for _, arg := range paramIdents {
 		if _, err := code.symbols.InsertVariable(arg.Literal()); err != nil {
 			return err
 		}
@@ -1140,6 +1357,17 @@ func (c *Compiler) compileFunc(node *ast.Func) error {
 		c.emit(op.LoadConst, c.constant(fn))
 	}
 
+	// Methods are attached to their struct type rather than stored as a
+	// variable. As with named functions, the function is left on the stack.
+	if node.IsMethod() {
+		c.emit(op.Copy, 0)
+		if err := c.compile(node.ReceiverType()); err != nil {
+			return err
+		}
+		c.emit(op.StoreAttr, c.current.addName(node.Name().Literal()))
+		return nil
+	}
+
 	// If the function was named, we store it as a named variable in the current
 	// code. Otherwise, we just leave it on the stack.
 	if code.isNamed {
@@ -1159,6 +1387,51 @@ func (c *Compiler) compileFunc(node *ast.Func) error {
 	return nil
 }
 
+func (c *Compiler) compileStruct(node *ast.Struct) error {
+	name := node.Name().Literal()
+	fields := node.Fields()
+	if len(fields) > 255 {
+		return fmt.Errorf("compile error: struct %q exceeded field limit of 255", name)
+	}
+
+	// Push the struct name followed by a name and default value for each
+	// field. Like function parameter defaults, only the basic types of int,
+	// string, bool, float, and nil are supported. Fields without a default
+	// are nil.
+	c.emit(op.LoadConst, c.constant(name))
+	defaults := node.Defaults()
+	for _, field := range fields {
+		c.emit(op.LoadConst, c.constant(field.Literal()))
+		expr, ok := defaults[field.Literal()]
+		if !ok {
+			c.emit(op.Nil)
+			continue
+		}
+		switch expr.(type) {
+		case *ast.Int, *ast.String, *ast.Bool, *ast.Float, *ast.Nil:
+			if err := c.compile(expr); err != nil {
+				return err
+			}
+		default:
+			line := field.Token().StartPosition.Line + 1
+			return fmt.Errorf("compile error: unsupported default value (got %s, line %d)", expr, line)
+		}
+	}
+	c.emit(op.BuildStruct, uint16(len(fields)))
+
+	// The struct type is stored as a constant, the same as a named function
+	sym, err := c.current.symbols.InsertConstant(name)
+	if err != nil {
+		return err
+	}
+	if c.current.parent == nil {
+		c.emit(op.StoreGlobal, sym.Index())
+	} else {
+		c.emit(op.StoreFast, sym.Index())
+	}
+	return nil
+}
+
 func (c *Compiler) compileControl(node *ast.Control) error {
 	literal := node.Literal()
 	loop := c.currentLoop()
@@ -1168,6 +1441,10 @@ func (c *Compiler) compileControl(node *ast.Control) error {
 		}
 		return fmt.Errorf("compile error: invalid continue statement outside of a loop")
 	}
+	// Leave any try statements inside the loop before jumping
+	if err := c.exitTries(len(c.current.loops)); err != nil {
+		return err
+	}
 	if literal == "break" {
 		position := c.emit(op.JumpForward, Placeholder)
 		loop.breakPos = append(loop.breakPos, position)
@@ -1190,10 +1467,35 @@ func (c *Compiler) compileReturn(node *ast.Return) error {
 			return err
 		}
 	}
+	// Leave all try statements in the function, keeping the return value
+	// on the stack
+	if err := c.exitTries(0); err != nil {
+		return err
+	}
 	c.emit(op.ReturnValue)
 	return nil
 }
 
+func (c *Compiler) compileYield(node *ast.Yield) error {
+	if c.current.IsRoot() {
+		return fmt.Errorf("compile error: yield statement outside of a function (line %d)",
+			node.Token().StartPosition.LineNumber())
+	}
+	// A function containing a yield statement is a generator. Calling it
+	// creates a generator object instead of running the function body.
+	c.current.isGenerator = true
+	value := node.Value()
+	if value == nil {
+		c.emit(op.Nil)
+	} else {
+		if err := c.compile(value); err != nil {
+			return err
+		}
+	}
+	c.emit(op.Yield)
+	return nil
+}
+
 func (c *Compiler) compileSetItem(node *ast.Assign) error {
 	// StoreSubscr / STORE_SUBSCR
 	// Implements TOS1[TOS] = TOS2.
@@ -1224,11 +1526,11 @@ func (c *Compiler) compileAssign(node *ast.Assign) error {
 	name := node.Name()
 	resolution, found := c.current.symbols.Resolve(name)
 	if !found {
-		return fmt.Errorf("compile error: undefined variable %q (line %d)", name, lineNum)
+		return newCompileError(node.Ident().Token(), "compile error: undefined variable %q (line %d)", name, lineNum)
 	}
 	sym := resolution.symbol
 	if sym.IsConstant() {
-		return fmt.Errorf("compile error: cannot assign to constant %q (line %d)", name, lineNum)
+		return newCompileError(node.Ident().Token(), "compile error: cannot assign to constant %q (line %d)", name, lineNum)
 	}
 	symbolIndex := sym.Index()
 	if node.Operator() == "=" {
@@ -1722,6 +2024,130 @@ func (c *Compiler) compileOr(node *ast.Infix) error {
 	return nil
 }
 
+func (c *Compiler) compileTry(node *ast.Try) error {
+	code := c.current
+	if len(code.handlers) >= math.MaxUint16 {
+		return fmt.Errorf("compile error: number of try statements exceeded limits")
+	}
+	index := len(code.handlers)
+	code.handlers = append(code.handlers, ExceptionHandler{Catch: -1, Finally: -1})
+	finallyBlock := node.FinallyBlock()
+
+	// Errors raised from here until the handler is popped are routed to the
+	// catch block, or else the finally block
+	code.tries = append(code.tries, &tryBlock{
+		finally:   finallyBlock,
+		loopDepth: len(code.loops),
+	})
+	c.emit(op.PushExcept, uint16(index))
+	start := c.currentPosition()
+	if err := c.compile(node.Body()); err != nil {
+		return err
+	}
+	c.emit(op.PopTop)
+	end := c.emit(op.PopExcept)
+
+	// On success, skip the catch block and go on to the finally block, if
+	// any. The finally block expects to find the pending error on the stack,
+	// which is nil in this case.
+	var exitPositions []int
+	if finallyBlock != nil {
+		c.emit(op.Nil)
+	}
+	catchBlock := node.CatchBlock()
+	if catchBlock != nil {
+		exitPositions = append(exitPositions, c.emit(op.JumpForward, Placeholder))
+	}
+
+	// The catch block begins with the error on the stack. Its handler stays
+	// active while it runs, so that errors raised here reach the finally block.
+	catch := -1
+	if catchBlock != nil {
+		catch = c.currentPosition()
+		code.symbols = code.symbols.NewBlock()
+		if ident := node.CatchIdent(); ident != nil {
+			sym, err := code.symbols.InsertVariable(ident.Literal())
+			if err != nil {
+				return err
+			}
+			if code.symbols.IsGlobal() {
+				c.emit(op.StoreGlobal, sym.Index())
+			} else {
+				c.emit(op.StoreFast, sym.Index())
+			}
+		} else {
+			c.emit(op.PopTop)
+		}
+		if err := c.compile(catchBlock); err != nil {
+			return err
+		}
+		code.symbols = code.symbols.parent
+		c.emit(op.PopTop)
+		c.emit(op.PopExcept)
+		if finallyBlock != nil {
+			c.emit(op.Nil)
+		}
+	}
+	code.tries = code.tries[:len(code.tries)-1]
+
+	// The finally block re-raises the pending error, if any, once it's done
+	finally := -1
+	if finallyBlock != nil {
+		finally = c.currentPosition()
+		for _, pos := range exitPositions {
+			delta, err := c.calculateDelta(pos)
+			if err != nil {
+				return err
+			}
+			c.changeOperand(pos, delta)
+		}
+		exitPositions = nil
+		if err := c.compile(finallyBlock); err != nil {
+			return err
+		}
+		c.emit(op.PopTop)
+		c.emit(op.EndFinally)
+	}
+	for _, pos := range exitPositions {
+		delta, err := c.calculateDelta(pos)
+		if err != nil {
+			return err
+		}
+		c.changeOperand(pos, delta)
+	}
+	code.handlers[index] = ExceptionHandler{
+		Start:   start,
+		End:     end,
+		Catch:   catch,
+		Finally: finally,
+	}
+	return nil
+}
+
+// exitTries emits the instructions needed to transfer control out of the try
+// statements being compiled that are enclosed by at least the given number of
+// loops. Innermost try statements are exited first: each one's handler is
+// popped and its finally block, if any, is run.
+func (c *Compiler) exitTries(loopDepth int) error {
+	code := c.current
+	tries := code.tries
+	defer func() { code.tries = tries }()
+	for i := len(tries) - 1; i >= 0 && tries[i].loopDepth >= loopDepth; i-- {
+		c.emit(op.PopExcept)
+		if tries[i].finally == nil {
+			continue
+		}
+		// A return or break within the finally block must only exit the
+		// try statements that enclose this one
+		code.tries = tries[:i]
+		if err := c.compile(tries[i].finally); err != nil {
+			return err
+		}
+		c.emit(op.PopTop)
+	}
+	return nil
+}
+
 func (c *Compiler) compileGoStmt(node *ast.Go) error {
 	expr := node.Call()
 	switch expr := expr.(type) {
@@ -1816,6 +2242,7 @@ func (c *Compiler) emit(opcode op.Code, operands ...uint16) int {
 	code := c.current
 	pos := len(code.instructions)
 	code.instructions = append(code.instructions, inst...)
+	code.addLocation(pos, c.location)
 	return pos
 }
 
@@ -2,6 +2,8 @@ package compiler
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/itrn0/risor/ast"
@@ -89,6 +91,21 @@ func TestCompileErrors(t *testing.T) {
 			input:  "\n defer func() {}()",
 			errMsg: "compile error: defer statement outside of a function (line 2)",
 		},
+		{
+			name:   "name bound twice in pattern",
+			input:  "x := [1, 2]\nmatch x { case [a, a]: a; default: 0 }",
+			errMsg: "compile error: \"a\" is bound more than once in pattern (line 2)",
+		},
+		{
+			name:   "name bound in alternative pattern",
+			input:  "x := 1\nmatch x { case 1 | n: n; default: 0 }",
+			errMsg: "compile error: alternative patterns can't bind names (line 2)",
+		},
+		{
+			name:   "yield outside function",
+			input:  "x := 1\nyield x",
+			errMsg: "compile error: yield statement outside of a function (line 2)",
+		},
 	}
 	for _, tt := range testCase {
 		t.Run(tt.name, func(t *testing.T) {
@@ -103,6 +120,66 @@ func TestCompileErrors(t *testing.T) {
 	}
 }
 
+func TestMatchWarnings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"x := 1\nmatch x { case 1: 2; default: 3 }", nil},
+		{"x := 1\nmatch x { case 1: 2; case _: 3 }", nil},
+		{"x := 1\nmatch x { case 1: 2 }", []string{
+			"2:1: match is not exhaustive: add a default case or a case that matches all values",
+		}},
+		{"x := 1\nmatch x { case n if n > 1: 2; case int(n): 3 }", []string{
+			"2:1: match is not exhaustive: add a default case or a case that matches all values",
+		}},
+		{"x := 1\nmatch x {\ncase n: n\ncase 2: 3\ndefault: 4\n}", []string{
+			"4:1: unreachable case: a previous case matches all values",
+			"5:1: unreachable default case: a previous case matches all values",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program, err := parser.Parse(context.Background(), tt.input)
+			require.Nil(t, err)
+			c, err := New()
+			require.Nil(t, err)
+			_, err = c.Compile(program)
+			require.Nil(t, err)
+			var warnings []string
+			for _, w := range c.Warnings() {
+				pos := w.StartPosition()
+				warnings = append(warnings, fmt.Sprintf("%d:%d: %s", pos.LineNumber(), pos.ColumnNumber(), w))
+			}
+			require.Equal(t, tt.expected, warnings)
+		})
+	}
+}
+
+func TestGeneratorCode(t *testing.T) {
+	c, err := New()
+	require.Nil(t, err)
+	ast, err := parser.Parse(context.Background(), `
+	func gen() {
+		yield 1
+		func inner() { return 2 }
+	}
+	func plain() { return 3 }
+	`)
+	require.Nil(t, err)
+	main, err := c.Compile(ast)
+	require.Nil(t, err)
+	gen, ok := main.Constant(0).(*Function)
+	require.True(t, ok)
+	require.True(t, gen.Code().IsGenerator())
+	inner, ok := gen.Code().Constant(1).(*Function)
+	require.True(t, ok)
+	require.False(t, inner.Code().IsGenerator())
+	plain, ok := main.Constant(1).(*Function)
+	require.True(t, ok)
+	require.False(t, plain.Code().IsGenerator())
+}
+
 func TestCompilerLoopError(t *testing.T) {
 	input := `
 for _, v := range [1, 2, 3] {
@@ -119,3 +196,74 @@ for _, v := range [1, 2, 3] {
 	require.NotNil(t, err)
 	require.Equal(t, "compile error: undefined variable \"undefined_var\" (line 4)", err.Error())
 }
+
+func TestCompileErrorPosition(t *testing.T) {
+	tests := []struct {
+		input string
+		start token.Position
+	}{
+		{"x := 1\ny + x", token.Position{Char: 7, Line: 1, Column: 0}},
+		{"const a = 1\n  a = 2", token.Position{Char: 14, Line: 1, Column: 2}},
+		{"x := 1\nx, yy = [1, 2]", token.Position{Char: 10, Line: 1, Column: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program, err := parser.Parse(context.Background(), tt.input)
+			require.Nil(t, err)
+			_, err = Compile(program)
+			require.NotNil(t, err)
+			var compileErr *CompileError
+			require.True(t, errors.As(err, &compileErr))
+			require.Equal(t, tt.start.Char, compileErr.StartPosition().Char)
+			require.Equal(t, tt.start.Line, compileErr.StartPosition().Line)
+			require.Equal(t, tt.start.Column, compileErr.StartPosition().Column)
+		})
+	}
+}
+
+func TestSourceLocations(t *testing.T) {
+	program, err := parser.Parse(context.Background(), "x := 1\ny := x + 2", parser.WithFile("a.risor"))
+	require.Nil(t, err)
+	code, err := Compile(program)
+	require.Nil(t, err)
+
+	// The first instruction loads the constant 1
+	loc, ok := code.LocationAt(0)
+	require.True(t, ok)
+	require.Equal(t, SourceLocation{File: "a.risor", Line: 1, Column: 6}, loc)
+	require.Equal(t, "a.risor:1:6", loc.String())
+
+	// The instruction before the trailing Nil stores y on the second line
+	loc, ok = code.LocationAt(code.InstructionCount() - 3)
+	require.True(t, ok)
+	require.Equal(t, 2, loc.Line)
+}
+
+func TestTryHandlerTable(t *testing.T) {
+	program, err := parser.Parse(context.Background(), `
+	try { x := 1 } catch err { err } finally { y := 2 }
+	`)
+	require.Nil(t, err)
+	code, err := Compile(program)
+	require.Nil(t, err)
+	require.Equal(t, 1, code.ExceptionHandlerCount())
+
+	handler := code.ExceptionHandler(0)
+	require.Equal(t, op.PushExcept, code.Instruction(0))
+	require.Equal(t, 2, handler.Start)
+	require.Equal(t, op.PopExcept, code.Instruction(handler.End))
+	require.Greater(t, handler.Catch, handler.End)
+	require.Greater(t, handler.Finally, handler.Catch)
+	require.Equal(t, op.StoreGlobal, code.Instruction(handler.Catch))
+}
+
+func TestTryCatchVariableScope(t *testing.T) {
+	program, err := parser.Parse(context.Background(), `
+	try { x := 1 } catch err { err }
+	err
+	`)
+	require.Nil(t, err)
+	_, err = Compile(program)
+	require.NotNil(t, err)
+	require.Equal(t, "compile error: undefined variable \"err\" (line 3)", err.Error())
+}
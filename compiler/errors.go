@@ -0,0 +1,44 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/itrn0/risor/token"
+)
+
+// CompileError is an error in a program that's detected while compiling it,
+// such as a reference to an undefined variable. It records the token where
+// the error was found, so that tools can report its position in the source.
+type CompileError struct {
+	msg   string
+	token token.Token
+}
+
+func newCompileError(tok token.Token, format string, args ...any) *CompileError {
+	return &CompileError{msg: fmt.Sprintf(format, args...), token: tok}
+}
+
+func (e *CompileError) Error() string {
+	return e.msg
+}
+
+// Token returns the token where the error was found.
+func (e *CompileError) Token() token.Token {
+	return e.token
+}
+
+// StartPosition returns the position where the offending token begins.
+func (e *CompileError) StartPosition() token.Position {
+	return e.token.StartPosition
+}
+
+// EndPosition returns the position of the last character of the offending
+// token.
+func (e *CompileError) EndPosition() token.Position {
+	return e.token.EndPosition
+}
+
+// Records a warning at the given token.
+func (c *Compiler) warnf(tok token.Token, format string, args ...any) {
+	c.warnings = append(c.warnings, newCompileError(tok, format, args...))
+}
@@ -0,0 +1,47 @@
+package compiler
+
+import "github.com/itrn0/risor/ast"
+
+// ExceptionHandler is an entry in the exception handler table of a Code
+// object. Each entry corresponds to one try statement and is activated by a
+// PushExcept instruction that refers to the entry by its index. Offsets are
+// instruction offsets within the Code.
+type ExceptionHandler struct {
+	// Start is the offset of the first instruction of the try block.
+	Start int
+
+	// End is the offset just past the last instruction of the try block.
+	End int
+
+	// Catch is the offset of the catch block, or -1 if there is none. The
+	// caught error is on the top of the stack when the block begins.
+	Catch int
+
+	// Finally is the offset of the finally block, or -1 if there is none.
+	// When the block begins, the top of the stack holds the error that is
+	// pending, or nil if there isn't one.
+	Finally int
+}
+
+// Tracks a try statement that is being compiled. Statements that transfer
+// control out of the try statement, like return, use this to run its finally
+// block on the way out.
+type tryBlock struct {
+	// The finally block of the try statement, if any
+	finally *ast.Block
+
+	// The number of loops enclosing the try statement
+	loopDepth int
+}
+
+// ExceptionHandlerCount returns the number of entries in the exception
+// handler table.
+func (c *Code) ExceptionHandlerCount() int {
+	return len(c.handlers)
+}
+
+// ExceptionHandler returns the entry at the given index in the exception
+// handler table.
+func (c *Code) ExceptionHandler(index int) ExceptionHandler {
+	return c.handlers[index]
+}
@@ -0,0 +1,70 @@
+package compiler
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/itrn0/risor/token"
+)
+
+// SourceLocation identifies a position in Risor source code. Line and Column
+// are 1-indexed. A zero Line indicates the location is unknown.
+type SourceLocation struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// IsValid returns true if the location refers to a line in the source.
+func (l SourceLocation) IsValid() bool {
+	return l.Line > 0
+}
+
+func (l SourceLocation) String() string {
+	if !l.IsValid() {
+		return "<unknown>"
+	}
+	if l.File == "" {
+		return fmt.Sprintf("%d:%d", l.Line, l.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Column)
+}
+
+// NewSourceLocation returns the SourceLocation for the given token position.
+func NewSourceLocation(pos token.Position) SourceLocation {
+	return SourceLocation{
+		File:   pos.File,
+		Line:   pos.LineNumber(),
+		Column: pos.ColumnNumber(),
+	}
+}
+
+// Associates the instructions starting at the given offset with a location.
+// The location applies until the offset of the next entry in the table.
+type locationEntry struct {
+	offset   int
+	location SourceLocation
+}
+
+// Records the location of the instruction at the given offset, unless it is
+// the same as that of the preceding instruction.
+func (c *Code) addLocation(offset int, location SourceLocation) {
+	if n := len(c.locations); n > 0 && c.locations[n-1].location == location {
+		return
+	}
+	c.locations = append(c.locations, locationEntry{offset: offset, location: location})
+}
+
+// LocationAt returns the source location of the instruction that contains the
+// given offset. False is returned if no location is known.
+func (c *Code) LocationAt(offset int) (SourceLocation, bool) {
+	// Find the first entry beyond the offset; the one before it applies
+	idx := sort.Search(len(c.locations), func(i int) bool {
+		return c.locations[i].offset > offset
+	})
+	if idx == 0 {
+		return SourceLocation{}, false
+	}
+	location := c.locations[idx-1].location
+	return location, location.IsValid()
+}
@@ -0,0 +1,383 @@
+package compiler
+
+import (
+	"github.com/itrn0/risor/ast"
+	"github.com/itrn0/risor/op"
+)
+
+func (c *Compiler) compileMatch(node *ast.Match) error {
+	// The value being matched stays on the top of the stack until a case
+	// block has produced the result
+	if err := c.compile(node.Value()); err != nil {
+		return err
+	}
+	c.checkMatch(node)
+
+	var defaultCase *ast.MatchCase
+	var endBlockPosits []int
+	for _, matchCase := range node.Cases() {
+		if matchCase.IsDefault() {
+			defaultCase = matchCase
+			continue
+		}
+		// Names bound by the pattern are scoped to the case
+		code := c.current
+		code.symbols = code.symbols.NewBlock()
+		var failPosits []int
+		err := c.compileMatchCase(matchCase, &failPosits)
+		code.symbols = code.symbols.parent
+		if err != nil {
+			return err
+		}
+		endBlockPosits = append(endBlockPosits, c.emit(op.JumpForward, Placeholder))
+		// A failed match continues with the next case
+		if err := c.patchJumps(failPosits); err != nil {
+			return err
+		}
+	}
+
+	// The default case is tried last, wherever it appears
+	if defaultCase != nil && defaultCase.Block() != nil {
+		if err := c.compile(defaultCase.Block()); err != nil {
+			return err
+		}
+	} else {
+		c.emit(op.Nil)
+	}
+
+	if err := c.patchJumps(endBlockPosits); err != nil {
+		return err
+	}
+
+	c.emit(op.Swap, 1)
+
+	// Remove the matched value from the stack
+	c.emit(op.PopTop)
+	return nil
+}
+
+// Compiles the pattern, guard and block of one case. The positions of jumps
+// to take when the case doesn't match are added to failPosits.
+func (c *Compiler) compileMatchCase(node *ast.MatchCase, failPosits *[]int) error {
+	if err := c.compilePattern(node.Pattern(), nil, failPosits); err != nil {
+		return err
+	}
+	if guard := node.Guard(); guard != nil {
+		if err := c.compile(guard); err != nil {
+			return err
+		}
+		*failPosits = append(*failPosits, c.emit(op.PopJumpForwardIfFalse, Placeholder))
+	}
+	if node.Block() == nil {
+		// Empty case block
+		c.emit(op.Nil)
+		return nil
+	}
+	return c.compile(node.Block())
+}
+
+// Pushes the part of the matched value at the given path, which is a list of
+// the indexes and keys used to reach it. The matched value must be on the top
+// of the stack.
+func (c *Compiler) loadMatchPath(path []any) {
+	c.emit(op.Copy, 0)
+	for _, key := range path {
+		c.emit(op.LoadConst, c.constant(key))
+		c.emit(op.BinarySubscr)
+	}
+}
+
+// Returns a copy of the path with the given index or key added.
+func withMatchKey(path []any, key any) []any {
+	result := make([]any, len(path), len(path)+1)
+	copy(result, path)
+	return append(result, key)
+}
+
+// Emits a jump that's taken if the value on the top of the stack is false.
+func (c *Compiler) emitMatchTest(failPosits *[]int) {
+	*failPosits = append(*failPosits, c.emit(op.PopJumpForwardIfFalse, Placeholder))
+}
+
+// Checks that the part of the matched value at the given path has the type.
+func (c *Compiler) emitMatchType(path []any, name string, failPosits *[]int) {
+	c.loadMatchPath(path)
+	c.emit(op.LoadConst, c.constant(name))
+	c.emit(op.MatchType)
+	c.emitMatchTest(failPosits)
+}
+
+// Compiles a pattern that's applied to the part of the matched value at the
+// given path. The positions of jumps to take if it doesn't match are added to
+// failPosits.
+func (c *Compiler) compilePattern(pattern ast.Pattern, path []any, failPosits *[]int) error {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		return nil
+	case *ast.BindPattern:
+		c.loadMatchPath(path)
+		return c.bindPatternName(pattern.Ident())
+	case *ast.LiteralPattern:
+		c.loadMatchPath(path)
+		if err := c.compile(pattern.Value()); err != nil {
+			return err
+		}
+		c.emit(op.CompareOp, uint16(op.Equal))
+		c.emitMatchTest(failPosits)
+		return nil
+	case *ast.TypePattern:
+		c.emitMatchType(path, pattern.Name(), failPosits)
+		if pattern.Pattern() == nil {
+			return nil
+		}
+		return c.compilePattern(pattern.Pattern(), path, failPosits)
+	case *ast.ListPattern:
+		return c.compileListPattern(pattern, path, failPosits)
+	case *ast.MapPattern:
+		return c.compileMapPattern(pattern, path, failPosits)
+	case *ast.OrPattern:
+		return c.compileOrPattern(pattern, path, failPosits)
+	default:
+		return newCompileError(pattern.Token(), "compile error: invalid pattern: %s", pattern)
+	}
+}
+
+func (c *Compiler) compileListPattern(pattern *ast.ListPattern, path []any, failPosits *[]int) error {
+	elements := pattern.Elements()
+	rest := -1
+	for i, el := range elements {
+		if _, ok := el.(*ast.RestPattern); ok {
+			rest = i
+		}
+	}
+	c.emitMatchType(path, "list", failPosits)
+
+	// Check the length of the list
+	c.loadMatchPath(path)
+	c.emit(op.Length)
+	if rest == -1 {
+		c.emit(op.LoadConst, c.constant(int64(len(elements))))
+		c.emit(op.CompareOp, uint16(op.Equal))
+	} else {
+		c.emit(op.LoadConst, c.constant(int64(len(elements)-1)))
+		c.emit(op.CompareOp, uint16(op.GreaterThanOrEqual))
+	}
+	c.emitMatchTest(failPosits)
+
+	// Elements after a rest element are indexed from the end of the list
+	for i, el := range elements {
+		var index int64
+		switch {
+		case i == rest:
+			if ident := el.(*ast.RestPattern).Ident(); ident != nil {
+				if err := c.compileListRest(path, int64(rest), int64(len(elements)-1)); err != nil {
+					return err
+				}
+				if err := c.bindPatternName(ident); err != nil {
+					return err
+				}
+			}
+			continue
+		case rest != -1 && i > rest:
+			index = int64(i - len(elements))
+		default:
+			index = int64(i)
+		}
+		if err := c.compilePattern(el, withMatchKey(path, index), failPosits); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pushes the items of the list at the given path that are matched by a rest
+// element, given the index of the rest element and the number of other
+// elements. The list is known to have at least that many items.
+func (c *Compiler) compileListRest(path []any, start, count int64) error {
+	// Slicing requires the start index to be in range, so an empty list is
+	// built instead when there are no remaining items
+	c.loadMatchPath(path)
+	c.emit(op.Length)
+	c.emit(op.LoadConst, c.constant(count))
+	c.emit(op.CompareOp, uint16(op.Equal))
+	sliceJumpPos := c.emit(op.PopJumpForwardIfFalse, Placeholder)
+	c.emit(op.BuildList, 0)
+	endJumpPos := c.emit(op.JumpForward, Placeholder)
+	if err := c.patchJumps([]int{sliceJumpPos}); err != nil {
+		return err
+	}
+	c.loadMatchPath(path)
+	c.emit(op.Copy, 0)
+	c.emit(op.Length)
+	c.emit(op.LoadConst, c.constant(count-start))
+	c.emit(op.BinaryOp, uint16(op.Subtract))
+	c.emit(op.LoadConst, c.constant(start))
+	c.emit(op.Slice)
+	return c.patchJumps([]int{endJumpPos})
+}
+
+// Changes the jumps at the given positions to jump to the next instruction.
+func (c *Compiler) patchJumps(posits []int) error {
+	for _, pos := range posits {
+		delta, err := c.calculateDelta(pos)
+		if err != nil {
+			return err
+		}
+		c.changeOperand(pos, delta)
+	}
+	return nil
+}
+
+func (c *Compiler) compileMapPattern(pattern *ast.MapPattern, path []any, failPosits *[]int) error {
+	c.emitMatchType(path, "map", failPosits)
+	values := pattern.Values()
+	for i, key := range pattern.Keys() {
+		var name string
+		switch key := key.(type) {
+		case *ast.String:
+			name = key.Literal()
+		case *ast.Ident:
+			name = key.Literal()
+		default:
+			return newCompileError(key.Token(), "compile error: invalid map pattern key: %s", key)
+		}
+		// Check that the key is present before its value is matched
+		c.loadMatchPath(path)
+		c.emit(op.LoadConst, c.constant(name))
+		c.emit(op.ContainsOp, 0)
+		c.emitMatchTest(failPosits)
+		if err := c.compilePattern(values[i], withMatchKey(path, name), failPosits); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) compileOrPattern(pattern *ast.OrPattern, path []any, failPosits *[]int) error {
+	alternatives := pattern.Alternatives()
+	for _, alt := range alternatives {
+		if node, ok := findPatternBinding(alt); ok {
+			return newCompileError(node.Token(), "compile error: alternative patterns can't bind names (line %d)",
+				node.Token().StartPosition.LineNumber())
+		}
+	}
+	var matchedPosits []int
+	for i, alt := range alternatives {
+		if i == len(alternatives)-1 {
+			// If the last alternative fails, the whole pattern fails
+			if err := c.compilePattern(alt, path, failPosits); err != nil {
+				return err
+			}
+			break
+		}
+		var altFailPosits []int
+		if err := c.compilePattern(alt, path, &altFailPosits); err != nil {
+			return err
+		}
+		matchedPosits = append(matchedPosits, c.emit(op.JumpForward, Placeholder))
+		if err := c.patchJumps(altFailPosits); err != nil {
+			return err
+		}
+	}
+	if err := c.patchJumps(matchedPosits); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Stores the value on the top of the stack in a new variable, scoped to the
+// current match case.
+func (c *Compiler) bindPatternName(ident *ast.Ident) error {
+	name := ident.Literal()
+	if _, found := c.current.symbols.Get(name); found {
+		return newCompileError(ident.Token(), "compile error: %q is bound more than once in pattern (line %d)",
+			name, ident.Token().StartPosition.LineNumber())
+	}
+	sym, err := c.current.symbols.InsertVariable(name)
+	if err != nil {
+		return err
+	}
+	if c.current.parent == nil {
+		c.emit(op.StoreGlobal, sym.Index())
+	} else {
+		c.emit(op.StoreFast, sym.Index())
+	}
+	return nil
+}
+
+// Returns the first name bound by the pattern, if any.
+func findPatternBinding(pattern ast.Pattern) (ast.Node, bool) {
+	switch pattern := pattern.(type) {
+	case *ast.BindPattern:
+		return pattern, true
+	case *ast.RestPattern:
+		if pattern.Ident() != nil {
+			return pattern, true
+		}
+	case *ast.TypePattern:
+		if pattern.Pattern() != nil {
+			return findPatternBinding(pattern.Pattern())
+		}
+	case *ast.ListPattern:
+		for _, el := range pattern.Elements() {
+			if node, ok := findPatternBinding(el); ok {
+				return node, true
+			}
+		}
+	case *ast.MapPattern:
+		for _, value := range pattern.Values() {
+			if node, ok := findPatternBinding(value); ok {
+				return node, true
+			}
+		}
+	case *ast.OrPattern:
+		for _, alt := range pattern.Alternatives() {
+			if node, ok := findPatternBinding(alt); ok {
+				return node, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Returns true if the pattern matches any value.
+func isIrrefutablePattern(pattern ast.Pattern) bool {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern, *ast.BindPattern:
+		return true
+	case *ast.OrPattern:
+		for _, alt := range pattern.Alternatives() {
+			if isIrrefutablePattern(alt) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Adds warnings for cases of the match expression that can never be reached,
+// and for a match expression that may not match any case. A match expression
+// that matches no case evaluates to nil.
+func (c *Compiler) checkMatch(node *ast.Match) {
+	var defaultCase *ast.MatchCase
+	exhaustive := false
+	for _, matchCase := range node.Cases() {
+		if matchCase.IsDefault() {
+			defaultCase = matchCase
+			continue
+		}
+		if exhaustive {
+			c.warnf(matchCase.Token(), "unreachable case: a previous case matches all values")
+			continue
+		}
+		if matchCase.Guard() == nil && isIrrefutablePattern(matchCase.Pattern()) {
+			exhaustive = true
+		}
+	}
+	switch {
+	case exhaustive && defaultCase != nil:
+		c.warnf(defaultCase.Token(), "unreachable default case: a previous case matches all values")
+	case !exhaustive && defaultCase == nil:
+		c.warnf(node.Token(), "match is not exhaustive: add a default case or a case that matches all values")
+	}
+}
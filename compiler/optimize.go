@@ -0,0 +1,684 @@
+package compiler
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/itrn0/risor/op"
+)
+
+// Optimization levels accepted by WithOptimizationLevel.
+const (
+	// OptimizeNone leaves the compiled bytecode unchanged.
+	OptimizeNone = 0
+
+	// OptimizeBasic folds operations on constants and removes instructions
+	// whose results are unused.
+	OptimizeBasic = 1
+
+	// OptimizeFull also threads jumps and removes unreachable code.
+	OptimizeFull = 2
+)
+
+// Maximum number of times the passes are repeated on one code object. Each
+// round typically enables more simplifications in the next, e.g. folding
+// 1 + 2 + 3 takes two rounds.
+const maxOptimizationRounds = 16
+
+// A decoded instruction. Jumps refer to their destination directly, so that
+// instructions may be removed without breaking them.
+type optInstruction struct {
+	opcode   op.Code
+	operands []uint16
+	location SourceLocation
+	target   *optInstruction
+	removed  bool
+
+	// Where references to a removed instruction are redirected
+	forward *optInstruction
+}
+
+type optHandler struct {
+	index                      int
+	start, end, catch, finally *optInstruction
+}
+
+// Optimizes a region of a code object, which begins at the given offset and
+// runs to the end of its instructions.
+type optimizer struct {
+	code         *Code
+	level        int
+	start        int
+	instructions []*optInstruction
+	handlers     []*optHandler
+
+	// Marks the end of the instructions. Jumps that leave the code land here.
+	end *optInstruction
+}
+
+// Optimizes the instructions of the given code object from the given offset
+// onwards. Earlier instructions are left as they are, which allows code that
+// is compiled incrementally to be optimized as it grows.
+func optimize(code *Code, start, level int) error {
+	if level <= OptimizeNone || start >= len(code.instructions) {
+		return nil
+	}
+	o := &optimizer{code: code, level: level, start: start}
+	if !o.decode() {
+		// The code doesn't have a shape the optimizer understands, so it is
+		// left unoptimized.
+		return nil
+	}
+	for round := 0; round < maxOptimizationRounds; round++ {
+		changed := o.foldConstants()
+		changed = o.removeUnusedValues() || changed
+		if level >= OptimizeFull {
+			changed = o.threadJumps() || changed
+			changed = o.removeUnreachable() || changed
+			changed = o.removeJumpsToNext() || changed
+		}
+		if !changed {
+			break
+		}
+	}
+	if err := o.encode(); err != nil {
+		return err
+	}
+	if start == 0 {
+		code.compactConstants()
+	}
+	return nil
+}
+
+func isJump(opcode op.Code) bool {
+	switch opcode {
+	case op.JumpForward, op.JumpBackward, op.PopJumpForwardIfTrue,
+		op.PopJumpForwardIfFalse, op.ForIter:
+		return true
+	}
+	return false
+}
+
+func (o *optimizer) decode() bool {
+	code := o.code
+	byOffset := map[int]*optInstruction{}
+	offsets := map[*optInstruction]int{}
+	for pos := o.start; pos < len(code.instructions); {
+		opcode := code.instructions[pos]
+		count := op.GetInfo(opcode).OperandCount
+		if pos+count >= len(code.instructions) {
+			return false
+		}
+		inst := &optInstruction{
+			opcode:   opcode,
+			operands: make([]uint16, count),
+			location: code.locationEntryAt(pos),
+		}
+		for i := 0; i < count; i++ {
+			inst.operands[i] = uint16(code.instructions[pos+1+i])
+		}
+		byOffset[pos] = inst
+		offsets[inst] = pos
+		o.instructions = append(o.instructions, inst)
+		pos += 1 + count
+	}
+	o.end = &optInstruction{opcode: op.Invalid}
+	byOffset[len(code.instructions)] = o.end
+
+	lookup := func(offset int) (*optInstruction, bool) {
+		inst, ok := byOffset[offset]
+		return inst, ok
+	}
+	for _, inst := range o.instructions {
+		if !isJump(inst.opcode) {
+			continue
+		}
+		pos := offsets[inst]
+		var dest int
+		if inst.opcode == op.JumpBackward {
+			dest = pos - int(inst.operands[0])
+		} else {
+			dest = pos + int(inst.operands[0])
+		}
+		target, ok := lookup(dest)
+		if !ok {
+			return false
+		}
+		inst.target = target
+	}
+	for i, handler := range code.handlers {
+		if handler.Start < o.start {
+			continue
+		}
+		h := &optHandler{index: i}
+		for _, ref := range []struct {
+			offset int
+			dest   **optInstruction
+		}{
+			{handler.Start, &h.start},
+			{handler.End, &h.end},
+			{handler.Catch, &h.catch},
+			{handler.Finally, &h.finally},
+		} {
+			if ref.offset < 0 {
+				continue
+			}
+			inst, ok := lookup(ref.offset)
+			if !ok {
+				return false
+			}
+			*ref.dest = inst
+		}
+		o.handlers = append(o.handlers, h)
+	}
+	return true
+}
+
+// Returns the set of instructions that control may arrive at other than by
+// falling through from the preceding instruction.
+func (o *optimizer) targets() map[*optInstruction]bool {
+	targets := map[*optInstruction]bool{}
+	for _, inst := range o.instructions {
+		if inst.target != nil {
+			targets[inst.target] = true
+		}
+	}
+	for _, h := range o.handlers {
+		for _, inst := range []*optInstruction{h.start, h.end, h.catch, h.finally} {
+			if inst != nil {
+				targets[inst] = true
+			}
+		}
+	}
+	return targets
+}
+
+// Drops removed instructions and redirects references to them to the next
+// instruction that remains.
+func (o *optimizer) compact() {
+	next := o.end
+	for i := len(o.instructions) - 1; i >= 0; i-- {
+		inst := o.instructions[i]
+		if inst.removed {
+			inst.forward = next
+		} else {
+			next = inst
+		}
+	}
+	resolve := func(inst *optInstruction) *optInstruction {
+		for inst != nil && inst.removed {
+			inst = inst.forward
+		}
+		return inst
+	}
+	kept := o.instructions[:0]
+	for _, inst := range o.instructions {
+		if inst.removed {
+			continue
+		}
+		inst.target = resolve(inst.target)
+		kept = append(kept, inst)
+	}
+	for i := len(kept); i < len(o.instructions); i++ {
+		o.instructions[i] = nil
+	}
+	o.instructions = kept
+	for _, h := range o.handlers {
+		h.start = resolve(h.start)
+		h.end = resolve(h.end)
+		h.catch = resolve(h.catch)
+		h.finally = resolve(h.finally)
+	}
+}
+
+// Replaces operations on constants with their results.
+func (o *optimizer) foldConstants() bool {
+	targets := o.targets()
+	changed := false
+	insts := o.instructions
+	for i := 0; i < len(insts); i++ {
+		inst := insts[i]
+		if inst.removed {
+			continue
+		}
+		// Folding a sequence is only safe when nothing jumps into its middle
+		fits := func(n int) bool {
+			if i+n > len(insts) {
+				return false
+			}
+			for j := i + 1; j < i+n; j++ {
+				if insts[j].removed || targets[insts[j]] {
+					return false
+				}
+			}
+			return true
+		}
+		switch inst.opcode {
+		case op.LoadConst:
+			if fits(3) && insts[i+1].opcode == op.LoadConst && insts[i+2].opcode == op.BinaryOp {
+				left := o.code.constants[inst.operands[0]]
+				right := o.code.constants[insts[i+1].operands[0]]
+				result, ok := foldBinaryOp(op.BinaryOpType(insts[i+2].operands[0]), left, right)
+				if ok {
+					index, ok := o.code.addConstant(result)
+					if ok {
+						inst.operands[0] = index
+						insts[i+1].removed = true
+						insts[i+2].removed = true
+						changed = true
+					}
+				}
+			} else if fits(2) && insts[i+1].opcode == op.UnaryNegative {
+				var result any
+				switch value := o.code.constants[inst.operands[0]].(type) {
+				case int64:
+					result = -value
+				case float64:
+					result = -value
+				}
+				if result != nil {
+					if index, ok := o.code.addConstant(result); ok {
+						inst.operands[0] = index
+						insts[i+1].removed = true
+						changed = true
+					}
+				}
+			}
+		case op.True, op.False, op.Nil:
+			if !fits(2) {
+				continue
+			}
+			truthy := inst.opcode == op.True
+			next := insts[i+1]
+			switch next.opcode {
+			case op.UnaryNot:
+				if truthy {
+					inst.opcode = op.False
+				} else {
+					inst.opcode = op.True
+				}
+				next.removed = true
+				changed = true
+			case op.PopJumpForwardIfTrue, op.PopJumpForwardIfFalse:
+				if truthy == (next.opcode == op.PopJumpForwardIfTrue) {
+					// The jump is always taken
+					next.opcode = op.JumpForward
+				} else {
+					next.removed = true
+				}
+				inst.removed = true
+				changed = true
+			}
+		}
+	}
+	if changed {
+		o.compact()
+	}
+	return changed
+}
+
+// Removes values that are pushed onto the stack only to be popped right away,
+// as well as instructions that do nothing.
+func (o *optimizer) removeUnusedValues() bool {
+	targets := o.targets()
+	changed := false
+	insts := o.instructions
+	for i, inst := range insts {
+		if inst.removed {
+			continue
+		}
+		if inst.opcode == op.Nop {
+			inst.removed = true
+			changed = true
+			continue
+		}
+		if i+1 >= len(insts) || insts[i+1].opcode != op.PopTop || targets[insts[i+1]] {
+			continue
+		}
+		switch inst.opcode {
+		case op.LoadConst, op.Nil, op.True, op.False, op.LoadFast, op.LoadFree:
+		case op.Copy:
+			if inst.operands[0] != 0 {
+				continue
+			}
+		default:
+			continue
+		}
+		inst.removed = true
+		insts[i+1].removed = true
+		changed = true
+	}
+	if changed {
+		o.compact()
+	}
+	return changed
+}
+
+// Redirects jumps that land on unconditional jumps to the final destination.
+func (o *optimizer) threadJumps() bool {
+	index := make(map[*optInstruction]int, len(o.instructions)+1)
+	for i, inst := range o.instructions {
+		index[inst] = i
+	}
+	index[o.end] = len(o.instructions)
+	changed := false
+	for i, inst := range o.instructions {
+		if inst.target == nil {
+			continue
+		}
+		dest := inst.target
+		for hops := 0; hops < maxOptimizationRounds; hops++ {
+			if dest.opcode != op.JumpForward && dest.opcode != op.JumpBackward {
+				break
+			}
+			if dest == inst || dest.target == dest {
+				break
+			}
+			dest = dest.target
+		}
+		if dest == inst.target {
+			continue
+		}
+		// Only the unconditional jumps may change direction
+		if index[dest] <= i && inst.opcode != op.JumpForward && inst.opcode != op.JumpBackward {
+			continue
+		}
+		inst.target = dest
+		changed = true
+	}
+	return changed
+}
+
+// Removes instructions that can't be reached from the start of the code or
+// from an exception handler.
+func (o *optimizer) removeUnreachable() bool {
+	index := make(map[*optInstruction]int, len(o.instructions)+1)
+	for i, inst := range o.instructions {
+		index[inst] = i
+	}
+	index[o.end] = len(o.instructions)
+	reached := make([]bool, len(o.instructions)+1)
+	var work []int
+	visit := func(inst *optInstruction) {
+		if inst == nil {
+			return
+		}
+		if i := index[inst]; !reached[i] {
+			reached[i] = true
+			work = append(work, i)
+		}
+	}
+	if len(o.instructions) > 0 {
+		visit(o.instructions[0])
+	}
+	for _, h := range o.handlers {
+		visit(h.catch)
+		visit(h.finally)
+	}
+	for len(work) > 0 {
+		i := work[len(work)-1]
+		work = work[:len(work)-1]
+		if i == len(o.instructions) {
+			continue
+		}
+		inst := o.instructions[i]
+		visit(inst.target)
+		switch inst.opcode {
+		case op.ReturnValue, op.JumpForward, op.JumpBackward, op.Halt:
+		default:
+			if i+1 < len(o.instructions) {
+				visit(o.instructions[i+1])
+			} else {
+				visit(o.end)
+			}
+		}
+	}
+	changed := false
+	for i, inst := range o.instructions {
+		if !reached[i] {
+			inst.removed = true
+			changed = true
+		}
+	}
+	if changed {
+		o.compact()
+	}
+	return changed
+}
+
+// Removes jumps to the instruction that follows them, which would be reached
+// anyway. Conditional jumps still need to pop the condition.
+func (o *optimizer) removeJumpsToNext() bool {
+	changed := false
+	for i, inst := range o.instructions {
+		next := o.end
+		if i+1 < len(o.instructions) {
+			next = o.instructions[i+1]
+		}
+		if inst.target != next {
+			continue
+		}
+		switch inst.opcode {
+		case op.JumpForward:
+			inst.removed = true
+		case op.PopJumpForwardIfTrue, op.PopJumpForwardIfFalse:
+			inst.opcode = op.PopTop
+			inst.operands = nil
+			inst.target = nil
+		default:
+			continue
+		}
+		changed = true
+	}
+	if changed {
+		o.compact()
+	}
+	return changed
+}
+
+// Writes the optimized instructions back to the code object, along with the
+// offsets they are referred to by.
+func (o *optimizer) encode() error {
+	offsets := make(map[*optInstruction]int, len(o.instructions)+1)
+	pos := o.start
+	for _, inst := range o.instructions {
+		offsets[inst] = pos
+		pos += 1 + len(inst.operands)
+	}
+	offsets[o.end] = pos
+
+	instructions := make([]op.Code, o.start, pos)
+	copy(instructions, o.code.instructions[:o.start])
+	var locations []locationEntry
+	for _, entry := range o.code.locations {
+		if entry.offset < o.start {
+			locations = append(locations, entry)
+		}
+	}
+	region := &Code{locations: locations}
+	for _, inst := range o.instructions {
+		at := len(instructions)
+		if inst.target != nil {
+			delta := offsets[inst.target] - at
+			switch inst.opcode {
+			case op.JumpForward, op.JumpBackward:
+				if delta < 0 {
+					inst.opcode = op.JumpBackward
+					delta = -delta
+				} else {
+					inst.opcode = op.JumpForward
+				}
+			}
+			if delta < 0 || delta > math.MaxUint16 {
+				return fmt.Errorf("compile error: jump destination is too far away")
+			}
+			inst.operands[0] = uint16(delta)
+		}
+		instructions = append(instructions, makeInstruction(inst.opcode, inst.operands...)...)
+		region.addLocation(at, inst.location)
+	}
+	o.code.instructions = instructions
+	o.code.locations = region.locations
+	for _, h := range o.handlers {
+		handler := &o.code.handlers[h.index]
+		handler.Start = offsets[h.start]
+		handler.End = offsets[h.end]
+		if h.catch != nil {
+			handler.Catch = offsets[h.catch]
+		}
+		if h.finally != nil {
+			handler.Finally = offsets[h.finally]
+		}
+	}
+	return nil
+}
+
+// Returns the location recorded for the instruction at the given offset, even
+// if it is not valid.
+func (c *Code) locationEntryAt(offset int) SourceLocation {
+	var location SourceLocation
+	for _, entry := range c.locations {
+		if entry.offset > offset {
+			break
+		}
+		location = entry.location
+	}
+	return location
+}
+
+// Appends a constant produced by the optimizer. False is returned if the
+// constants table is full.
+func (c *Code) addConstant(value any) (uint16, bool) {
+	if len(c.constants) >= math.MaxUint16 {
+		return 0, false
+	}
+	c.constants = append(c.constants, value)
+	return uint16(len(c.constants) - 1), true
+}
+
+// Removes constants that no instruction refers to.
+func (c *Code) compactConstants() {
+	used := make([]bool, len(c.constants))
+	iter := NewInstructionIter(c)
+	var refs []int
+	for pos := 0; ; {
+		instr, ok := iter.Next()
+		if !ok {
+			break
+		}
+		switch instr[0] {
+		case op.LoadConst, op.LoadClosure:
+			used[instr[1]] = true
+			refs = append(refs, pos+1)
+		}
+		pos += len(instr)
+	}
+	remap := make([]uint16, len(c.constants))
+	var constants []any
+	for i, value := range c.constants {
+		if used[i] {
+			remap[i] = uint16(len(constants))
+			constants = append(constants, value)
+		}
+	}
+	if len(constants) == len(c.constants) {
+		return
+	}
+	for _, ref := range refs {
+		c.instructions[ref] = op.Code(remap[c.instructions[ref]])
+	}
+	c.constants = constants
+}
+
+// Computes the result of a binary operation on two constants the same way the
+// VM would. False is returned if the operation can't be folded, for example
+// because it would fail at runtime.
+func foldBinaryOp(opType op.BinaryOpType, left, right any) (any, bool) {
+	switch l := left.(type) {
+	case int64:
+		switch r := right.(type) {
+		case int64:
+			return foldIntOp(opType, l, r)
+		case float64:
+			if opType == op.Power {
+				return int64(math.Pow(float64(l), r)), true
+			}
+			return foldFloatOp(opType, float64(l), r)
+		}
+	case float64:
+		switch r := right.(type) {
+		case int64:
+			return foldFloatOp(opType, l, float64(r))
+		case float64:
+			return foldFloatOp(opType, l, r)
+		}
+	case string:
+		if r, ok := right.(string); ok && opType == op.Add {
+			return l + r, true
+		}
+	}
+	return nil, false
+}
+
+func foldIntOp(opType op.BinaryOpType, l, r int64) (any, bool) {
+	switch opType {
+	case op.Add:
+		return l + r, true
+	case op.Subtract:
+		return l - r, true
+	case op.Multiply:
+		return l * r, true
+	case op.Divide:
+		if r == 0 {
+			return nil, false
+		}
+		return l / r, true
+	case op.Modulo:
+		if r == 0 {
+			return nil, false
+		}
+		return l % r, true
+	case op.Xor:
+		return l ^ r, true
+	case op.Power:
+		return int64(math.Pow(float64(l), float64(r))), true
+	case op.LShift:
+		if r < 0 {
+			return nil, false
+		}
+		return l << r, true
+	case op.RShift:
+		if r < 0 {
+			return nil, false
+		}
+		return l >> r, true
+	case op.BitwiseAnd:
+		return l & r, true
+	case op.BitwiseOr:
+		return l | r, true
+	}
+	return nil, false
+}
+
+func foldFloatOp(opType op.BinaryOpType, l, r float64) (any, bool) {
+	var result float64
+	switch opType {
+	case op.Add:
+		result = l + r
+	case op.Subtract:
+		result = l - r
+	case op.Multiply:
+		result = l * r
+	case op.Divide:
+		result = l / r
+	case op.Power:
+		result = math.Pow(l, r)
+	default:
+		return nil, false
+	}
+	// Infinities and NaN can't be represented in serialized code
+	if math.IsInf(result, 0) || math.IsNaN(result) {
+		return nil, false
+	}
+	return result, true
+}
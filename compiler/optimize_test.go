@@ -0,0 +1,203 @@
+package compiler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/itrn0/risor/op"
+	"github.com/itrn0/risor/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func compileOptimized(t *testing.T, input string, level int, options ...Option) *Code {
+	t.Helper()
+	ast, err := parser.Parse(context.Background(), input)
+	require.Nil(t, err)
+	options = append(options, WithOptimizationLevel(level))
+	code, err := Compile(ast, options...)
+	require.Nil(t, err)
+	return code
+}
+
+func opcodes(code *Code) []op.Code {
+	var result []op.Code
+	for _, instr := range NewInstructionIter(code).All() {
+		result = append(result, instr[0])
+	}
+	return result
+}
+
+func TestOptimizeConstantFolding(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`60 * 60 * 24`, int64(86400)},
+		{`"a" + "b" + "c"`, "abc"},
+		{`1 + 2.5`, 3.5},
+		{`1.5 * 2`, 3.0},
+		{`2 ** 0.5`, int64(1)},
+		{`-3 - 2`, int64(-5)},
+		{`7 % 4 << 2`, int64(12)},
+		{`1 + 2 * 3`, int64(7)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			code := compileOptimized(t, tt.input, OptimizeBasic)
+			require.Equal(t, []op.Code{op.LoadConst}, opcodes(code))
+			require.Equal(t, 1, code.ConstantsCount())
+			require.Equal(t, tt.expected, code.Constant(0))
+		})
+	}
+}
+
+func TestOptimizeNoFolding(t *testing.T) {
+	// These would fail at runtime or can't be stored in serialized code
+	tests := []string{
+		`1 / 0`,
+		`1 % 0`,
+		`1.0 / 0`,
+		`"a" + 1`,
+		`"a" * 2`,
+	}
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			code := compileOptimized(t, input, OptimizeFull)
+			require.Equal(t, []op.Code{op.LoadConst, op.LoadConst, op.BinaryOp}, opcodes(code))
+		})
+	}
+}
+
+func TestOptimizeUnusedValues(t *testing.T) {
+	code := compileOptimized(t, "1\n\"x\"\nnil\n2", OptimizeBasic)
+	require.Equal(t, []op.Code{op.LoadConst}, opcodes(code))
+	require.Equal(t, int64(2), code.Constant(0))
+}
+
+func TestOptimizeConstantConditions(t *testing.T) {
+	code := compileOptimized(t, `x := 1; if false { x = 2 }; x`, OptimizeFull)
+	require.Equal(t, []op.Code{
+		op.LoadConst,
+		op.StoreGlobal,
+		op.LoadGlobal,
+	}, opcodes(code))
+
+	code = compileOptimized(t, `if true { 1 } else { 2 }`, OptimizeFull)
+	require.Equal(t, []op.Code{op.LoadConst}, opcodes(code))
+	require.Equal(t, int64(1), code.Constant(0))
+}
+
+func TestOptimizeUnreachableCode(t *testing.T) {
+	code := compileOptimized(t, `func f() { return 1; print(2) }`, OptimizeFull,
+		WithGlobalNames([]string{"print"}))
+	fn, ok := code.Constant(0).(*Function)
+	require.True(t, ok)
+	require.Equal(t, []op.Code{op.LoadConst, op.ReturnValue}, opcodes(fn.Code()))
+}
+
+func TestOptimizeJumpThreading(t *testing.T) {
+	input := `
+x := 0
+for i := 0; i < 3; i++ {
+	if i == 1 { x = 1 } else { x = 2 }
+}
+x`
+	code := compileOptimized(t, input, OptimizeFull)
+	// No jump should land on an unconditional jump
+	var offsets []int
+	instructions := NewInstructionIter(code).All()
+	pos := 0
+	for _, instr := range instructions {
+		offsets = append(offsets, pos)
+		pos += len(instr)
+	}
+	for i, instr := range instructions {
+		var dest int
+		switch instr[0] {
+		case op.JumpForward, op.PopJumpForwardIfTrue, op.PopJumpForwardIfFalse:
+			dest = offsets[i] + int(instr[1])
+		case op.JumpBackward:
+			dest = offsets[i] - int(instr[1])
+		default:
+			continue
+		}
+		if dest < code.InstructionCount() {
+			target := code.Instruction(dest)
+			require.NotEqual(t, op.JumpForward, target)
+			require.NotEqual(t, op.JumpBackward, target)
+		}
+	}
+}
+
+func TestOptimizeLocations(t *testing.T) {
+	code := compileOptimized(t, "x := 60 * 60\ny := x + 1", OptimizeBasic)
+	require.Equal(t, []op.Code{
+		op.LoadConst,
+		op.StoreGlobal,
+		op.LoadGlobal,
+		op.LoadConst,
+		op.BinaryOp,
+		op.StoreGlobal,
+		op.Nil,
+	}, opcodes(code))
+	loc, ok := code.LocationAt(0)
+	require.True(t, ok)
+	require.Equal(t, 1, loc.Line)
+	loc, ok = code.LocationAt(4)
+	require.True(t, ok)
+	require.Equal(t, 2, loc.Line)
+}
+
+func TestOptimizeExceptionHandlers(t *testing.T) {
+	code := compileOptimized(t, `try { 1 + 2 } catch e { 3 * 4 }`, OptimizeFull)
+	require.Equal(t, 1, code.ExceptionHandlerCount())
+	handler := code.ExceptionHandler(0)
+	require.Equal(t, op.PushExcept, code.Instruction(0))
+	require.Equal(t, op.PopExcept, code.Instruction(handler.End))
+	require.Equal(t, op.StoreGlobal, code.Instruction(handler.Catch))
+}
+
+func TestOptimizeIncremental(t *testing.T) {
+	// Code that was compiled earlier is left as it is
+	c, err := New(WithOptimizationLevel(OptimizeBasic))
+	require.Nil(t, err)
+	ast, err := parser.Parse(context.Background(), "x := 1 + 2")
+	require.Nil(t, err)
+	_, err = c.Compile(ast)
+	require.Nil(t, err)
+	count := c.Code().InstructionCount()
+	first := opcodes(c.Code())
+
+	ast, err = parser.Parse(context.Background(), "y := x + 2 * 3")
+	require.Nil(t, err)
+	code, err := c.Compile(ast)
+	require.Nil(t, err)
+	require.Equal(t, first, opcodes(code)[:len(first)])
+	require.Equal(t, []op.Code{
+		op.LoadConst,
+		op.StoreGlobal,
+		op.Nil,
+		op.LoadGlobal,
+		op.LoadConst,
+		op.BinaryOp,
+		op.StoreGlobal,
+		op.Nil,
+	}, opcodes(code))
+	require.Greater(t, code.InstructionCount(), count)
+}
+
+func TestOptimizeNone(t *testing.T) {
+	input := `x := 60 * 60; if false { x = 1 }`
+	ast, err := parser.Parse(context.Background(), input)
+	require.Nil(t, err)
+	plain, err := Compile(ast)
+	require.Nil(t, err)
+	code := compileOptimized(t, input, OptimizeNone)
+	require.Equal(t, opcodes(plain), opcodes(code))
+}
+
+func TestInvalidOptimizationLevel(t *testing.T) {
+	_, err := New(WithOptimizationLevel(3))
+	require.NotNil(t, err)
+	require.Equal(t, "compile error: invalid optimization level: 3", err.Error())
+}
@@ -88,6 +88,22 @@ type symbolTableDef struct {
 	Children      []*symbolTableDef     `json:"children,omitempty"`
 }
 
+// Used to marshal an entry in a Code location table.
+type locationDef struct {
+	Offset int    `json:"offset"`
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// Used to marshal an entry in a Code exception handler table.
+type handlerDef struct {
+	Start   int `json:"start"`
+	End     int `json:"end"`
+	Catch   int `json:"catch"`
+	Finally int `json:"finally"`
+}
+
 // Flat form of a Code object used in marshaling.
 type codeDef struct {
 	ID            string            `json:"id,omitempty"`
@@ -99,6 +115,12 @@ type codeDef struct {
 	Constants     []json.RawMessage `json:"constants,omitempty"`
 	Names         []string          `json:"names,omitempty"`
 	Source        string            `json:"source,omitempty"`
+	Locations     []*locationDef    `json:"locations,omitempty"`
+	Handlers      []*handlerDef     `json:"handlers,omitempty"`
+	IsGenerator   bool              `json:"is_generator,omitempty"`
+
+	// Decoded constants, set when reading a format other than JSON
+	constants []any
 }
 
 // A representation of a Code object that can be marshalled more easily.
@@ -125,9 +147,12 @@ func codeFromState(state *state) (*Code, error) {
 		if !found && c.ParentID != "" {
 			return nil, fmt.Errorf("parent code not found: %s", c.ParentID)
 		}
-		constants, err := unmarshalConstants(c.Constants)
-		if err != nil {
-			return nil, err
+		constants := c.constants
+		if constants == nil {
+			constants, err = unmarshalConstants(c.Constants)
+			if err != nil {
+				return nil, err
+			}
 		}
 		code := &Code{
 			id:           c.ID,
@@ -140,6 +165,9 @@ func codeFromState(state *state) (*Code, error) {
 			constants:    constants,
 			names:        copyStrings(c.Names),
 			source:       c.Source,
+			locations:    locationsFromDefinition(c.Locations),
+			handlers:     handlersFromDefinition(c.Handlers),
+			isGenerator:  c.IsGenerator,
 		}
 		codesByID[code.id] = code
 		codes = append(codes, code)
@@ -344,6 +372,9 @@ func stateFromCode(code *Code) (*state, error) {
 			Name:          code.name,
 			Names:         copyStrings(code.names),
 			Source:        code.source,
+			Locations:     definitionFromLocations(code.locations),
+			Handlers:      definitionFromHandlers(code.handlers),
+			IsGenerator:   code.isGenerator,
 		}
 		if code.parent != nil {
 			cdef.ParentID = code.parent.id
@@ -402,6 +433,72 @@ func definitionFromFunction(function *Function) (*functionDef, error) {
 	}, nil
 }
 
+func definitionFromLocations(locations []locationEntry) []*locationDef {
+	if locations == nil {
+		return nil
+	}
+	defs := make([]*locationDef, 0, len(locations))
+	for _, entry := range locations {
+		defs = append(defs, &locationDef{
+			Offset: entry.offset,
+			File:   entry.location.File,
+			Line:   entry.location.Line,
+			Column: entry.location.Column,
+		})
+	}
+	return defs
+}
+
+func locationsFromDefinition(defs []*locationDef) []locationEntry {
+	if defs == nil {
+		return nil
+	}
+	locations := make([]locationEntry, 0, len(defs))
+	for _, def := range defs {
+		locations = append(locations, locationEntry{
+			offset: def.Offset,
+			location: SourceLocation{
+				File:   def.File,
+				Line:   def.Line,
+				Column: def.Column,
+			},
+		})
+	}
+	return locations
+}
+
+func definitionFromHandlers(handlers []ExceptionHandler) []*handlerDef {
+	if handlers == nil {
+		return nil
+	}
+	defs := make([]*handlerDef, 0, len(handlers))
+	for _, handler := range handlers {
+		defs = append(defs, &handlerDef{
+			Start:   handler.Start,
+			End:     handler.End,
+			Catch:   handler.Catch,
+			Finally: handler.Finally,
+		})
+	}
+	return defs
+}
+
+func handlersFromDefinition(defs []*handlerDef) []ExceptionHandler {
+	if defs == nil {
+		return nil
+	}
+	handlers := make([]ExceptionHandler, 0, len(defs))
+	for _, def := range defs {
+		handlers = append(handlers, ExceptionHandler{
+			Start:   def.Start,
+			End:     def.End,
+			Catch:   def.Catch,
+			Finally: def.Finally,
+		})
+	}
+	return handlers
+}
+
 func definitionFromResolution(resolution *Resolution) *resolutionDef {
 	return &resolutionDef{
 		Symbol:    definitionFromSymbol(resolution.symbol),
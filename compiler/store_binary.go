@@ -0,0 +1,515 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"sort"
+
+	"github.com/itrn0/risor/op"
+)
+
+// BinaryFormatVersion is the version of the binary bytecode format written by
+// MarshalCodeBinary. Data written with a newer version is rejected.
+//
+// Version history:
+//
+//	1  initial format
+//	2  adds exception handler tables
+//	3  adds the generator flag of each code object
+const BinaryFormatVersion = 3
+
+// The binary format consists of a fixed size header followed by the payload:
+//
+//	magic    [4]byte  "RSRC"
+//	version  uint16   format version
+//	flags    uint16   reserved, always zero
+//	length   uint32   payload length in bytes
+//	checksum uint32   CRC-32 (Castagnoli) of the payload
+//
+// All fixed size integers are big-endian. Within the payload, integers are
+// varint encoded and strings are length-prefixed.
+const (
+	binaryMagic      = "RSRC"
+	binaryHeaderSize = 16
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Tags identifying the type of an encoded constant.
+const (
+	tagNil byte = iota
+	tagBool
+	tagInt
+	tagFloat
+	tagString
+	tagFunction
+)
+
+// IsBinaryCode returns true if the data begins with the header written by
+// MarshalCodeBinary.
+func IsBinaryCode(data []byte) bool {
+	return len(data) >= len(binaryMagic) && string(data[:len(binaryMagic)]) == binaryMagic
+}
+
+// MarshalCodeBinary converts a Code object into the compact binary format.
+func MarshalCodeBinary(code *Code) ([]byte, error) {
+	w := &binaryWriter{}
+	if err := w.symbolTable(definitionFromSymbolTable(code.symbols)); err != nil {
+		return nil, err
+	}
+	allCode := code.Flatten()
+	w.uvarint(uint64(len(allCode)))
+	for _, c := range allCode {
+		if err := w.code(c); err != nil {
+			return nil, err
+		}
+	}
+	payload := w.buf.Bytes()
+	if uint64(len(payload)) > math.MaxUint32 {
+		return nil, errors.New("bytecode error: code is too large to encode")
+	}
+	data := make([]byte, binaryHeaderSize, binaryHeaderSize+len(payload))
+	copy(data, binaryMagic)
+	binary.BigEndian.PutUint16(data[4:], BinaryFormatVersion)
+	binary.BigEndian.PutUint16(data[6:], 0)
+	binary.BigEndian.PutUint32(data[8:], uint32(len(payload)))
+	binary.BigEndian.PutUint32(data[12:], crc32.Checksum(payload, crcTable))
+	return append(data, payload...), nil
+}
+
+// UnmarshalCodeBinary converts data written by MarshalCodeBinary into a Code.
+func UnmarshalCodeBinary(data []byte) (*Code, error) {
+	if len(data) < binaryHeaderSize || !IsBinaryCode(data) {
+		return nil, errors.New("bytecode error: missing binary header")
+	}
+	version := binary.BigEndian.Uint16(data[4:])
+	if version > BinaryFormatVersion {
+		return nil, fmt.Errorf("bytecode error: unsupported format version %d", version)
+	}
+	length := binary.BigEndian.Uint32(data[8:])
+	payload := data[binaryHeaderSize:]
+	if uint64(len(payload)) != uint64(length) {
+		return nil, fmt.Errorf("bytecode error: expected %d bytes of payload (got %d)",
+			length, len(payload))
+	}
+	if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(data[12:]) {
+		return nil, errors.New("bytecode error: checksum mismatch")
+	}
+	r := &binaryReader{data: payload, version: version}
+	table := r.symbolTable()
+	count := r.count()
+	codes := make([]*codeDef, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		codes = append(codes, r.code())
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.pos != len(r.data) {
+		return nil, errors.New("bytecode error: unexpected trailing data")
+	}
+	if len(codes) == 0 {
+		return nil, errors.New("bytecode error: no code found")
+	}
+	return codeFromState(&state{Code: codes, SymbolTable: table})
+}
+
+type binaryWriter struct {
+	buf bytes.Buffer
+	tmp [binary.MaxVarintLen64]byte
+}
+
+func (w *binaryWriter) uvarint(v uint64) {
+	n := binary.PutUvarint(w.tmp[:], v)
+	w.buf.Write(w.tmp[:n])
+}
+
+func (w *binaryWriter) varint(v int64) {
+	n := binary.PutVarint(w.tmp[:], v)
+	w.buf.Write(w.tmp[:n])
+}
+
+func (w *binaryWriter) bool(v bool) {
+	if v {
+		w.buf.WriteByte(1)
+	} else {
+		w.buf.WriteByte(0)
+	}
+}
+
+func (w *binaryWriter) string(s string) {
+	w.uvarint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+func (w *binaryWriter) strings(s []string) {
+	w.uvarint(uint64(len(s)))
+	for _, v := range s {
+		w.string(v)
+	}
+}
+
+func (w *binaryWriter) constant(c any) error {
+	switch c := c.(type) {
+	case nil:
+		w.buf.WriteByte(tagNil)
+	case bool:
+		w.buf.WriteByte(tagBool)
+		w.bool(c)
+	case int:
+		w.buf.WriteByte(tagInt)
+		w.varint(int64(c))
+	case int64:
+		w.buf.WriteByte(tagInt)
+		w.varint(c)
+	case float32:
+		w.buf.WriteByte(tagFloat)
+		w.buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(float64(c))))
+	case float64:
+		w.buf.WriteByte(tagFloat)
+		w.buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(c)))
+	case string:
+		w.buf.WriteByte(tagString)
+		w.string(c)
+	case *Function:
+		w.buf.WriteByte(tagFunction)
+		w.string(c.id)
+		w.string(c.name)
+		w.strings(c.parameters)
+		return w.constants(c.defaults)
+	default:
+		return fmt.Errorf("unknown constant type: %T", c)
+	}
+	return nil
+}
+
+func (w *binaryWriter) constants(constants []any) error {
+	w.uvarint(uint64(len(constants)))
+	for _, c := range constants {
+		if err := w.constant(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *binaryWriter) symbol(s *symbolDef) error {
+	w.string(s.Name)
+	w.uvarint(uint64(s.Index))
+	w.bool(s.IsConstant)
+	return w.constant(s.Value)
+}
+
+func (w *binaryWriter) symbolTable(t *symbolTableDef) error {
+	w.string(t.ID)
+	w.bool(t.IsBlock)
+	w.uvarint(uint64(len(t.Symbols)))
+	for _, s := range t.Symbols {
+		if err := w.symbol(s); err != nil {
+			return err
+		}
+	}
+	// Write named symbols in a deterministic order
+	names := make([]string, 0, len(t.SymbolsByName))
+	for name := range t.SymbolsByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w.uvarint(uint64(len(names)))
+	for _, name := range names {
+		w.string(name)
+		if err := w.symbol(t.SymbolsByName[name]); err != nil {
+			return err
+		}
+	}
+	w.uvarint(uint64(len(t.Free)))
+	for _, res := range t.Free {
+		if err := w.symbol(res.Symbol); err != nil {
+			return err
+		}
+		w.string(string(res.Scope))
+		w.varint(int64(res.Depth))
+		w.varint(int64(res.FreeIndex))
+	}
+	w.uvarint(uint64(len(t.Children)))
+	for _, child := range t.Children {
+		if err := w.symbolTable(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *binaryWriter) code(c *Code) error {
+	w.string(c.id)
+	w.string(c.name)
+	if c.parent != nil {
+		w.string(c.parent.id)
+	} else {
+		w.string("")
+	}
+	w.string(c.symbols.ID())
+	w.string(c.functionID)
+	w.uvarint(uint64(len(c.instructions)))
+	for _, instr := range c.instructions {
+		w.uvarint(uint64(uint16(instr)))
+	}
+	if err := w.constants(c.constants); err != nil {
+		return err
+	}
+	w.strings(c.names)
+	w.string(c.source)
+	w.uvarint(uint64(len(c.locations)))
+	for _, entry := range c.locations {
+		w.uvarint(uint64(entry.offset))
+		w.string(entry.location.File)
+		w.uvarint(uint64(entry.location.Line))
+		w.uvarint(uint64(entry.location.Column))
+	}
+	w.uvarint(uint64(len(c.handlers)))
+	for _, handler := range c.handlers {
+		w.uvarint(uint64(handler.Start))
+		w.uvarint(uint64(handler.End))
+		w.varint(int64(handler.Catch))
+		w.varint(int64(handler.Finally))
+	}
+	w.bool(c.isGenerator)
+	return nil
+}
+
+// Reads values from a binary payload. The first error encountered is saved
+// and subsequent reads return zero values, so callers only need to check the
+// error once they are done reading.
+type binaryReader struct {
+	data    []byte
+	pos     int
+	err     error
+	version uint16
+}
+
+func (r *binaryReader) fail(msg string) {
+	if r.err == nil {
+		r.err = fmt.Errorf("bytecode error: %s at offset %d", msg, r.pos)
+	}
+}
+
+func (r *binaryReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		r.fail("invalid integer")
+		return 0
+	}
+	r.pos += n
+	return v
+}
+
+func (r *binaryReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.data[r.pos:])
+	if n <= 0 {
+		r.fail("invalid integer")
+		return 0
+	}
+	r.pos += n
+	return v
+}
+
+// Reads a length which must not exceed the remaining data, since every
+// counted item occupies at least one byte. This guards against allocating
+// huge slices when decoding corrupt input.
+func (r *binaryReader) count() int {
+	n := r.uvarint()
+	if n > uint64(len(r.data)-r.pos) {
+		r.fail("invalid length")
+		return 0
+	}
+	return int(n)
+}
+
+func (r *binaryReader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	if r.pos >= len(r.data) {
+		r.fail("unexpected end of data")
+		return 0
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *binaryReader) bool() bool {
+	return r.byte() != 0
+}
+
+func (r *binaryReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > len(r.data)-r.pos {
+		r.fail("unexpected end of data")
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *binaryReader) string() string {
+	return string(r.bytes(r.count()))
+}
+
+func (r *binaryReader) strings() []string {
+	count := r.count()
+	if count == 0 {
+		return nil
+	}
+	s := make([]string, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		s = append(s, r.string())
+	}
+	return s
+}
+
+func (r *binaryReader) constant() any {
+	switch tag := r.byte(); tag {
+	case tagNil:
+		return nil
+	case tagBool:
+		return r.bool()
+	case tagInt:
+		return r.varint()
+	case tagFloat:
+		b := r.bytes(8)
+		if b == nil {
+			return nil
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b))
+	case tagString:
+		return r.string()
+	case tagFunction:
+		// The compiler always allocates these slices, even when empty
+		opts := FunctionOpts{
+			ID:         r.string(),
+			Name:       r.string(),
+			Parameters: r.strings(),
+			Defaults:   r.constants(),
+		}
+		if opts.Parameters == nil {
+			opts.Parameters = []string{}
+		}
+		if opts.Defaults == nil {
+			opts.Defaults = []any{}
+		}
+		return NewFunction(opts)
+	default:
+		r.fail(fmt.Sprintf("unknown constant tag %d", tag))
+		return nil
+	}
+}
+
+func (r *binaryReader) constants() []any {
+	count := r.count()
+	if count == 0 {
+		return nil
+	}
+	constants := make([]any, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		constants = append(constants, r.constant())
+	}
+	return constants
+}
+
+func (r *binaryReader) symbol() *symbolDef {
+	return &symbolDef{
+		Name:       r.string(),
+		Index:      uint16(r.uvarint()),
+		IsConstant: r.bool(),
+		Value:      r.constant(),
+	}
+}
+
+func (r *binaryReader) symbolTable() *symbolTableDef {
+	t := &symbolTableDef{
+		ID:            r.string(),
+		IsBlock:       r.bool(),
+		SymbolsByName: map[string]*symbolDef{},
+	}
+	count := r.count()
+	for i := 0; i < count && r.err == nil; i++ {
+		t.Symbols = append(t.Symbols, r.symbol())
+	}
+	count = r.count()
+	for i := 0; i < count && r.err == nil; i++ {
+		name := r.string()
+		t.SymbolsByName[name] = r.symbol()
+	}
+	count = r.count()
+	for i := 0; i < count && r.err == nil; i++ {
+		t.Free = append(t.Free, &resolutionDef{
+			Symbol:    r.symbol(),
+			Scope:     Scope(r.string()),
+			Depth:     int(r.varint()),
+			FreeIndex: int(r.varint()),
+		})
+	}
+	count = r.count()
+	for i := 0; i < count && r.err == nil; i++ {
+		t.Children = append(t.Children, r.symbolTable())
+	}
+	return t
+}
+
+func (r *binaryReader) code() *codeDef {
+	c := &codeDef{
+		ID:            r.string(),
+		Name:          r.string(),
+		ParentID:      r.string(),
+		SymbolTableID: r.string(),
+		FunctionID:    r.string(),
+	}
+	count := r.count()
+	c.Instructions = make([]op.Code, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		c.Instructions = append(c.Instructions, op.Code(r.uvarint()))
+	}
+	c.constants = r.constants()
+	c.Names = r.strings()
+	c.Source = r.string()
+	count = r.count()
+	for i := 0; i < count && r.err == nil; i++ {
+		c.Locations = append(c.Locations, &locationDef{
+			Offset: int(r.uvarint()),
+			File:   r.string(),
+			Line:   int(r.uvarint()),
+			Column: int(r.uvarint()),
+		})
+	}
+	if r.version < 2 {
+		return c
+	}
+	count = r.count()
+	for i := 0; i < count && r.err == nil; i++ {
+		c.Handlers = append(c.Handlers, &handlerDef{
+			Start:   int(r.uvarint()),
+			End:     int(r.uvarint()),
+			Catch:   int(r.varint()),
+			Finally: int(r.varint()),
+		})
+	}
+	if r.version < 3 {
+		return c
+	}
+	c.IsGenerator = r.bool()
+	return c
+}
@@ -0,0 +1,157 @@
+package compiler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalCodeBinary(t *testing.T) {
+	sources := []string{
+		`
+		x := 1.0
+		y := 2.0
+		x + y
+		`,
+		`
+		func test(a, b=2, c="three", d=4.5, e=false) {
+			if a > b {
+				return a
+			} else {
+				return b
+			}
+		}
+		test(1) + test(2, 3)
+		`,
+		`
+		start := 10
+		func counter(a) {
+			current := a
+			return func() {
+				current++
+				return current
+			}
+		}
+		c := counter(start)
+		c()
+		`,
+		`
+		func risky(x) {
+			try {
+				if x > 1 { print("too big") }
+				return x
+			} catch err {
+				return err
+			} finally {
+				x = 0
+			}
+		}
+		try { risky(2) } finally { print("done") }
+		`,
+		`
+		struct Point { x, y = 0 }
+		func (p Point) sum(scale=1) { return (p.x + p.y) * scale }
+		Point(1, 2).sum()
+		`,
+		`
+		func count(n) {
+			for i := range n { yield i }
+		}
+		list(count(3))
+		`,
+	}
+	for _, source := range sources {
+		codeA, err := compileSource(source)
+		require.Nil(t, err)
+		data, err := MarshalCodeBinary(codeA)
+		require.Nil(t, err)
+		require.True(t, IsBinaryCode(data))
+		codeB, err := UnmarshalCodeBinary(data)
+		require.Nil(t, err)
+		// Loop and try statement state should not factor in
+		for _, c := range codeA.Flatten() {
+			c.loops = nil
+			c.tries = nil
+		}
+		require.Equal(t, codeA, codeB)
+	}
+}
+
+func TestMarshalCodeBinaryIsSmaller(t *testing.T) {
+	code, err := compileSource(`
+	func fib(n) { return n < 2 ? n : fib(n - 1) + fib(n - 2) }
+	fib(10)
+	`)
+	require.Nil(t, err)
+	jsonData, err := MarshalCode(code)
+	require.Nil(t, err)
+	binaryData, err := MarshalCodeBinary(code)
+	require.Nil(t, err)
+	require.Less(t, len(binaryData), len(jsonData)/2)
+}
+
+func TestUnmarshalCodeBinaryErrors(t *testing.T) {
+	code, err := compileSource(`x := [1, 2, 3]; x[0]`)
+	require.Nil(t, err)
+	data, err := MarshalCodeBinary(code)
+	require.Nil(t, err)
+
+	_, err = UnmarshalCodeBinary([]byte(`{"code": []}`))
+	require.Equal(t, "bytecode error: missing binary header", err.Error())
+
+	newer := append([]byte{}, data...)
+	binary.BigEndian.PutUint16(newer[4:], BinaryFormatVersion+1)
+	_, err = UnmarshalCodeBinary(newer)
+	require.Equal(t, fmt.Sprintf("bytecode error: unsupported format version %d",
+		BinaryFormatVersion+1), err.Error())
+
+	corrupt := append([]byte{}, data...)
+	corrupt[len(corrupt)-1] ^= 0xff
+	_, err = UnmarshalCodeBinary(corrupt)
+	require.Equal(t, "bytecode error: checksum mismatch", err.Error())
+
+	_, err = UnmarshalCodeBinary(data[:len(data)-1])
+	require.Error(t, err)
+}
+
+// Rewrites binary data in an older format version, given the number of bytes
+// of fields added since that version at the end of the payload.
+func downgradeBinaryCode(data []byte, version uint16, trim int) []byte {
+	payload := data[binaryHeaderSize : len(data)-trim]
+	result := append([]byte{}, data[:binaryHeaderSize]...)
+	binary.BigEndian.PutUint16(result[4:], version)
+	binary.BigEndian.PutUint32(result[8:], uint32(len(payload)))
+	binary.BigEndian.PutUint32(result[12:], crc32.Checksum(payload, crcTable))
+	return append(result, payload...)
+}
+
+func TestUnmarshalCodeBinaryVersion1(t *testing.T) {
+	code, err := compileSource(`x := 1; x + 2`)
+	require.Nil(t, err)
+	data, err := MarshalCodeBinary(code)
+	require.Nil(t, err)
+
+	// Version 1 data is identical except that it lacks the exception handler
+	// table and the generator flag, which are the last (empty) fields of the
+	// only code object
+	decoded, err := UnmarshalCodeBinary(downgradeBinaryCode(data, 1, 2))
+	require.Nil(t, err)
+	require.Equal(t, code.instructions, decoded.instructions)
+	require.Equal(t, 0, decoded.ExceptionHandlerCount())
+}
+
+func TestUnmarshalCodeBinaryVersion2(t *testing.T) {
+	code, err := compileSource(`x := 1; x + 2`)
+	require.Nil(t, err)
+	data, err := MarshalCodeBinary(code)
+	require.Nil(t, err)
+
+	// Version 2 data lacks only the generator flag
+	decoded, err := UnmarshalCodeBinary(downgradeBinaryCode(data, 2, 1))
+	require.Nil(t, err)
+	require.Equal(t, code.instructions, decoded.instructions)
+	require.False(t, decoded.IsGenerator())
+}
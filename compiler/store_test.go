@@ -180,3 +180,22 @@ func TestCompiledInstructions(t *testing.T) {
 		{op.BinaryOp, op.Code(op.Add)},
 	}, instrs)
 }
+
+func TestMarshalCodeLocations(t *testing.T) {
+	codeA, err := compileSource(`
+	x := 1
+	func f() {
+		return x + 2
+	}
+	`)
+	require.Nil(t, err)
+	data, err := MarshalCode(codeA)
+	require.Nil(t, err)
+	codeB, err := UnmarshalCode(data)
+	require.Nil(t, err)
+	fn, ok := codeB.Constant(1).(*Function)
+	require.True(t, ok)
+	loc, ok := fn.Code().LocationAt(0)
+	require.True(t, ok)
+	require.Equal(t, SourceLocation{Line: 4, Column: 10}, loc)
+}
@@ -0,0 +1,716 @@
+package compiler
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/itrn0/risor/ast"
+	"github.com/itrn0/risor/op"
+	"github.com/itrn0/risor/token"
+)
+
+// TypeCheck checks the types in a program without compiling it, returning
+// the errors found in source order. Types are inferred from literals, the
+// signatures of the default builtins and type annotations. Operations that
+// are sure to fail are reported, as are values that don't match the type of
+// the variable, parameter or result they're assigned to. Of the options, only
+// the global names are used.
+func TypeCheck(node ast.Node, options ...Option) []*CompileError {
+	c := &Compiler{}
+	for _, opt := range options {
+		opt(c)
+	}
+	return typeCheck(node, c.globalNames)
+}
+
+func typeCheck(node ast.Node, globalNames []string) []*CompileError {
+	// The first pass finds the struct types and the variables that are
+	// reassigned, whose types can't be inferred from their initial values
+	first := newChecker(globalNames, nil)
+	first.expr(node)
+	c := newChecker(globalNames, first)
+	c.expr(node)
+	sort.SliceStable(c.errors, func(i, j int) bool {
+		return c.errors[i].StartPosition().Char < c.errors[j].StartPosition().Char
+	})
+	return c.errors
+}
+
+// checker infers the types of expressions and records type errors.
+type checker struct {
+	scope *typeScope
+
+	// struct types defined anywhere in the program
+	structs map[string]bool
+
+	// names of variables that are assigned to after being declared
+	reassigned map[string]bool
+
+	// the function whose body is being checked, if any
+	fn *signature
+
+	errors []*CompileError
+}
+
+// typeScope holds the variables defined in a block.
+type typeScope struct {
+	parent *typeScope
+	vars   map[string]*typedVar
+}
+
+type typedVar struct {
+	// typ is the type of the variable's value
+	typ *typ
+
+	// declared is the annotated type of the variable, if any
+	declared *typ
+}
+
+func newChecker(globalNames []string, first *checker) *checker {
+	c := &checker{
+		scope:      &typeScope{vars: map[string]*typedVar{}},
+		structs:    map[string]bool{},
+		reassigned: map[string]bool{},
+	}
+	if first != nil {
+		c.structs = first.structs
+		c.reassigned = first.reassigned
+	}
+	for _, name := range globalNames {
+		if sig, ok := builtinSignatures[name]; ok {
+			c.scope.vars[name] = &typedVar{typ: &typ{name: "builtin", sig: sig}}
+		}
+	}
+	return c
+}
+
+func (c *checker) errorf(tok token.Token, format string, args ...any) {
+	c.errors = append(c.errors, newCompileError(tok, format, args...))
+}
+
+func (c *checker) push() {
+	c.scope = &typeScope{parent: c.scope, vars: map[string]*typedVar{}}
+}
+
+func (c *checker) pop() {
+	c.scope = c.scope.parent
+}
+
+func (c *checker) lookup(name string) (*typedVar, bool) {
+	for scope := c.scope; scope != nil; scope = scope.parent {
+		if v, ok := scope.vars[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Defines a variable with the given initial value type and annotated type.
+// The type of an unannotated variable that's reassigned isn't known.
+func (c *checker) define(name string, value, declared *typ) {
+	v := &typedVar{typ: value, declared: declared}
+	switch {
+	case declared != nil:
+		v.typ = declared
+	case c.reassigned[name]:
+		v.typ = anyType
+	}
+	c.scope.vars[name] = v
+}
+
+// Reports an error if a value of the given type can't be used where the
+// target type is expected.
+func (c *checker) checkAssignable(node ast.Node, value, target *typ, context string) {
+	if target == nil || value.assignableTo(target) {
+		return
+	}
+	c.errorf(startToken(node), "type error: cannot use %s as %s in %s", value, target, context)
+}
+
+// Returns the type for a type annotation, or nil if there is no annotation.
+func (c *checker) annotation(expr *ast.TypeExpr) *typ {
+	if expr == nil {
+		return nil
+	}
+	if expr.IsUnion() {
+		alternatives := make([]*typ, 0, len(expr.Alternatives()))
+		for _, alt := range expr.Alternatives() {
+			alternatives = append(alternatives, c.annotation(alt))
+		}
+		return newUnionType(alternatives...)
+	}
+	name := expr.Name()
+	if c.structs[name] {
+		return &typ{name: name, isStruct: true}
+	}
+	if !typeNames[name] {
+		c.errorf(expr.Token(), "type error: unknown type %q", name)
+		return anyType
+	}
+	if name == "any" {
+		return anyType
+	}
+	params := expr.Params()
+	if len(params) == 0 {
+		return &typ{name: name}
+	}
+	if !containerTypes[name] {
+		c.errorf(expr.Token(), "type error: type %s does not take type parameters", name)
+		return &typ{name: name}
+	}
+	// Map keys are always strings, so a map may be annotated as map[string, V]
+	// or simply as map[V]
+	if name == "map" && len(params) == 2 && params[0].String() == "string" {
+		params = params[1:]
+	}
+	if len(params) != 1 {
+		c.errorf(expr.Token(), "type error: wrong number of type parameters for %s", name)
+		return &typ{name: name}
+	}
+	return &typ{name: name, elem: c.annotation(params[0])}
+}
+
+func (c *checker) block(node *ast.Block) *typ {
+	if node == nil {
+		return nilType
+	}
+	c.push()
+	defer c.pop()
+	return c.statements(node.Statements())
+}
+
+// Checks a list of statements, returning the type of the last one.
+func (c *checker) statements(nodes []ast.Node) *typ {
+	result := nilType
+	for _, node := range nodes {
+		result = c.expr(node)
+	}
+	return result
+}
+
+func (c *checker) exprs(exprs []ast.Expression) []*typ {
+	types := make([]*typ, 0, len(exprs))
+	for _, expr := range exprs {
+		types = append(types, c.expr(expr))
+	}
+	return types
+}
+
+// Returns the common element type of a list or set literal.
+func elementType(types []*typ) *typ {
+	if len(types) == 0 {
+		return nil
+	}
+	for _, t := range types[1:] {
+		if !t.equals(types[0]) {
+			return nil
+		}
+	}
+	if types[0].isAny() || types[0].isUnion() {
+		return nil
+	}
+	return types[0]
+}
+
+// Checks a node and returns its type. Statements have the nil type.
+func (c *checker) expr(node ast.Node) *typ {
+	switch node := node.(type) {
+	case nil:
+		return anyType
+	case *ast.Program:
+		return c.statements(node.Statements())
+	case *ast.Ident:
+		if v, ok := c.lookup(node.Literal()); ok {
+			return v.typ
+		}
+		return anyType
+	case *ast.Int:
+		return intType
+	case *ast.Float:
+		return floatType
+	case *ast.Bool:
+		return boolType
+	case *ast.Nil:
+		return nilType
+	case *ast.String:
+		c.exprs(node.TemplateExpressions())
+		return stringType
+	case *ast.List:
+		return &typ{name: "list", elem: elementType(c.exprs(node.Items()))}
+	case *ast.Set:
+		return &typ{name: "set", elem: elementType(c.exprs(node.Items()))}
+	case *ast.Map:
+		var values []*typ
+		for key, value := range node.Items() {
+			// Identifiers used as keys are strings
+			if _, ok := key.(*ast.Ident); !ok {
+				c.expr(key)
+			}
+			values = append(values, c.expr(value))
+		}
+		return &typ{name: "map", elem: elementType(values)}
+	case *ast.Func:
+		return c.function(node)
+	case *ast.Var:
+		name, value := node.Value()
+		valueType := c.expr(value)
+		declared := c.annotation(node.Type())
+		c.checkAssignable(value, valueType, declared, "assignment to "+name)
+		c.define(name, namedFunction(valueType, name), declared)
+		return nilType
+	case *ast.Const:
+		name, value := node.Value()
+		valueType := c.expr(value)
+		declared := c.annotation(node.Type())
+		c.checkAssignable(value, valueType, declared, "assignment to "+name)
+		// Constants can't be reassigned, so the type of the value is kept
+		c.scope.vars[name] = &typedVar{typ: namedFunction(valueType, name), declared: declared}
+		return nilType
+	case *ast.MultiVar:
+		names, value := node.Value()
+		c.expr(value)
+		for _, name := range names {
+			if node.IsWalrus() {
+				c.define(name, anyType, nil)
+			} else {
+				c.assign(node, name, anyType)
+			}
+		}
+		return nilType
+	case *ast.Assign:
+		valueType := c.expr(node.Value())
+		if node.Index() != nil {
+			c.expr(node.Index())
+			return nilType
+		}
+		name := node.Name()
+		if operator := strings.TrimSuffix(node.Operator(), "="); operator != "" {
+			current := c.expr(node.Ident())
+			if opType, ok := binaryOps[operator]; ok {
+				valueType = c.binaryOp(node, opType, current, valueType)
+			}
+		}
+		c.assign(node.Value(), name, valueType)
+		return nilType
+	case *ast.Postfix:
+		if v, ok := c.lookup(node.Literal()); ok {
+			c.binaryOp(node, op.Add, v.typ, intType)
+		}
+		return nilType
+	case *ast.Infix:
+		left := c.expr(node.Left())
+		right := c.expr(node.Right())
+		switch operator := node.Operator(); operator {
+		case "==", "!=", "<", "<=", ">", ">=":
+			return boolType
+		case "&&", "||":
+			if left.equals(boolType) && right.equals(boolType) {
+				return boolType
+			}
+			// The result is one of the operands
+			return newUnionType(left, right)
+		default:
+			if opType, ok := binaryOps[operator]; ok {
+				return c.binaryOp(node, opType, left, right)
+			}
+			return anyType
+		}
+	case *ast.Prefix:
+		right := c.expr(node.Right())
+		switch node.Operator() {
+		case "!":
+			return boolType
+		case "-":
+			if right.equals(intType) || right.equals(floatType) {
+				return right
+			}
+		}
+		return anyType
+	case *ast.In:
+		c.expr(node.Left())
+		c.expr(node.Right())
+		return boolType
+	case *ast.Ternary:
+		c.expr(node.Condition())
+		return newUnionType(c.expr(node.IfTrue()), c.expr(node.IfFalse()))
+	case *ast.If:
+		c.expr(node.Condition())
+		c.block(node.Consequence())
+		c.block(node.Alternative())
+		return anyType
+	case *ast.Call:
+		return c.call(node)
+	case *ast.ObjectCall:
+		c.expr(node.Object())
+		// The function of the call is the name of a method
+		if call, ok := node.Call().(*ast.Call); ok {
+			for _, arg := range call.Arguments() {
+				c.expr(arg)
+			}
+		}
+		return anyType
+	case *ast.GetAttr:
+		c.expr(node.Object())
+		return anyType
+	case *ast.SetAttr:
+		c.expr(node.Object())
+		c.expr(node.Value())
+		return nilType
+	case *ast.Pipe:
+		// Each call in a pipe receives an extra argument, so calls aren't
+		// checked against their signatures
+		for _, expr := range node.Expressions() {
+			if call, ok := expr.(*ast.Call); ok {
+				c.expr(call.Function())
+				for _, arg := range call.Arguments() {
+					c.expr(arg)
+				}
+			} else {
+				c.expr(expr)
+			}
+		}
+		return anyType
+	case *ast.Index:
+		left := c.expr(node.Left())
+		c.expr(node.Index())
+		switch {
+		case left.equals(stringType):
+			return stringType
+		case (left.name == "list" || left.name == "map") && left.elem != nil:
+			return left.elem
+		}
+		return anyType
+	case *ast.Slice:
+		left := c.expr(node.Left())
+		c.expr(node.FromIndex())
+		c.expr(node.ToIndex())
+		switch left.name {
+		case "list", "string", "byte_slice":
+			return left
+		}
+		return anyType
+	case *ast.Switch:
+		c.expr(node.Value())
+		for _, choice := range node.Choices() {
+			c.exprs(choice.Expressions())
+			c.block(choice.Block())
+		}
+		return anyType
+	case *ast.Match:
+		value := c.expr(node.Value())
+		for _, matchCase := range node.Cases() {
+			c.push()
+			if pattern := matchCase.Pattern(); pattern != nil {
+				c.pattern(pattern, value)
+			}
+			c.expr(matchCase.Guard())
+			c.block(matchCase.Block())
+			c.pop()
+		}
+		return anyType
+	case *ast.Select:
+		for _, selectCase := range node.Cases() {
+			c.push()
+			c.expr(selectCase.Comm())
+			names := selectCase.Names()
+			if len(names) > 0 {
+				c.define(names[0].Literal(), anyType, nil)
+			}
+			if len(names) > 1 {
+				c.define(names[1].Literal(), boolType, nil)
+			}
+			c.block(selectCase.Block())
+			c.pop()
+		}
+		return anyType
+	case *ast.For:
+		c.push()
+		c.expr(node.Init())
+		c.expr(node.Condition())
+		c.expr(node.Post())
+		c.block(node.Consequence())
+		c.pop()
+		return nilType
+	case *ast.Range:
+		c.expr(node.Container())
+		return anyType
+	case *ast.Receive:
+		c.expr(node.Channel())
+		return anyType
+	case *ast.Send:
+		c.expr(node.Channel())
+		c.expr(node.Value())
+		return nilType
+	case *ast.Go:
+		c.expr(node.Call())
+		return nilType
+	case *ast.Defer:
+		c.expr(node.Call())
+		return nilType
+	case *ast.Return:
+		c.checkReturn(node, node.Value())
+		return nilType
+	case *ast.Yield:
+		c.expr(node.Value())
+		return nilType
+	case *ast.Control:
+		c.expr(node.Value())
+		return nilType
+	case *ast.Block:
+		return c.block(node)
+	case *ast.Try:
+		c.block(node.Body())
+		if node.CatchBlock() != nil {
+			c.push()
+			if ident := node.CatchIdent(); ident != nil {
+				c.define(ident.Literal(), errorType, nil)
+			}
+			c.statements(node.CatchBlock().Statements())
+			c.pop()
+		}
+		c.block(node.FinallyBlock())
+		return anyType
+	case *ast.Struct:
+		for _, value := range node.Defaults() {
+			c.expr(value)
+		}
+		name := node.Name().Literal()
+		c.structs[name] = true
+		sig := &signature{name: name, result: &typ{name: name, isStruct: true}}
+		c.scope.vars[name] = &typedVar{typ: &typ{name: "struct_type", sig: sig}}
+		return nilType
+	case *ast.Import:
+		name := node.Name()
+		if node.Alias() != nil {
+			name = node.Alias()
+		}
+		c.define(name.Literal(), anyType, nil)
+		return nilType
+	case *ast.FromImport:
+		for _, im := range node.Imports() {
+			c.expr(im)
+		}
+		return nilType
+	}
+	return anyType
+}
+
+// Maps operators to the binary operations they run
+var binaryOps = map[string]op.BinaryOpType{
+	"+":  op.Add,
+	"-":  op.Subtract,
+	"*":  op.Multiply,
+	"/":  op.Divide,
+	"%":  op.Modulo,
+	"**": op.Power,
+	"<<": op.LShift,
+	">>": op.RShift,
+}
+
+// Defines the names bound by a pattern that's matched against a value of the
+// given type.
+func (c *checker) pattern(node ast.Pattern, value *typ) {
+	switch node := node.(type) {
+	case *ast.BindPattern:
+		if value == nil {
+			value = anyType
+		}
+		c.define(node.Ident().Literal(), value, nil)
+	case *ast.LiteralPattern:
+		c.expr(node.Value())
+	case *ast.TypePattern:
+		matched := anyType
+		if typeNames[node.Name()] || c.structs[node.Name()] {
+			matched = &typ{name: node.Name(), isStruct: c.structs[node.Name()]}
+			if !value.isAny() && value.assignableTo(matched) {
+				matched = value
+			}
+		}
+		if node.Pattern() != nil {
+			c.pattern(node.Pattern(), matched)
+		}
+	case *ast.ListPattern:
+		var elem *typ
+		if value != nil && value.name == "list" {
+			elem = value.elem
+		}
+		for _, el := range node.Elements() {
+			if rest, ok := el.(*ast.RestPattern); ok {
+				if ident := rest.Ident(); ident != nil {
+					c.define(ident.Literal(), &typ{name: "list", elem: elem}, nil)
+				}
+				continue
+			}
+			c.pattern(el, elem)
+		}
+	case *ast.MapPattern:
+		var elem *typ
+		if value != nil && value.name == "map" {
+			elem = value.elem
+		}
+		for _, v := range node.Values() {
+			c.pattern(v, elem)
+		}
+	case *ast.OrPattern:
+		for _, alt := range node.Alternatives() {
+			c.pattern(alt, value)
+		}
+	}
+}
+
+func (c *checker) binaryOp(node ast.Node, opType op.BinaryOpType, left, right *typ) *typ {
+	result, err := binaryOpType(opType, left, right)
+	if err != nil {
+		c.errorf(node.Token(), "%s", err)
+		return anyType
+	}
+	return result
+}
+
+// Records an assignment to a variable, checking the value against the type
+// the variable was declared with.
+func (c *checker) assign(node ast.Node, name string, value *typ) {
+	c.reassigned[name] = true
+	v, ok := c.lookup(name)
+	if !ok {
+		return
+	}
+	c.checkAssignable(node, value, v.declared, "assignment to "+name)
+}
+
+// Gives an anonymous function the name of the variable it's assigned to, for
+// use in error messages.
+func namedFunction(t *typ, name string) *typ {
+	if t.sig == nil || t.sig.name != "" {
+		return t
+	}
+	sig := *t.sig
+	sig.name = name
+	return &typ{name: t.name, sig: &sig}
+}
+
+// Checks a function literal and returns its type.
+func (c *checker) function(node *ast.Func) *typ {
+	sig := &signature{result: c.annotation(node.ResultType())}
+	if name := node.Name(); name != nil {
+		sig.name = name.Literal()
+	}
+	for _, param := range node.Parameters() {
+		name := param.Literal()
+		paramType := c.annotation(node.ParameterType(name))
+		if value, ok := node.Defaults()[name]; ok {
+			c.checkAssignable(value, c.expr(value), paramType, "default value of "+name)
+		}
+		if paramType == nil {
+			paramType = anyType
+		}
+		sig.params = append(sig.params, name)
+		sig.types = append(sig.types, paramType)
+	}
+	fnType := newFunctionType(sig)
+	// Named functions may call themselves. Methods are attached to their
+	// struct type rather than being stored as a variable.
+	if !node.IsMethod() && sig.name != "" {
+		c.define(sig.name, fnType, nil)
+	}
+	parent := c.fn
+	c.fn = sig
+	c.push()
+	if node.IsMethod() {
+		receiverType := node.ReceiverType().Literal()
+		c.define(node.Receiver().Literal(), &typ{name: receiverType, isStruct: true}, nil)
+	}
+	for i, name := range sig.params {
+		c.define(name, sig.types[i], sig.types[i])
+	}
+	statements := node.Body().Statements()
+	explicit := false
+	for _, stmt := range statements {
+		if _, ok := stmt.(*ast.Return); ok {
+			explicit = true
+		}
+	}
+	for i, stmt := range statements {
+		t := c.expr(stmt)
+		// Without a return statement, the function returns the value of its
+		// last expression
+		if expr, ok := stmt.(ast.Expression); ok && !explicit && i == len(statements)-1 {
+			c.checkAssignable(expr, t, sig.result, "return from "+sig.displayName())
+		}
+	}
+	c.pop()
+	c.fn = parent
+	return fnType
+}
+
+func (c *checker) checkReturn(node *ast.Return, value ast.Expression) {
+	if value == nil {
+		if c.fn != nil && c.fn.result != nil && !nilType.assignableTo(c.fn.result) {
+			c.errorf(node.Token(), "type error: cannot use nil as %s in return from %s", c.fn.result, c.fn.displayName())
+		}
+		return
+	}
+	t := c.expr(value)
+	if c.fn != nil {
+		c.checkAssignable(value, t, c.fn.result, "return from "+c.fn.displayName())
+	}
+}
+
+// Checks a call against the signature of the function, if it's known, and
+// returns the type of the result.
+func (c *checker) call(node *ast.Call) *typ {
+	fnType := c.expr(node.Function())
+	args := node.Arguments()
+	types := make([]*typ, 0, len(args))
+	for _, arg := range args {
+		types = append(types, c.expr(arg))
+	}
+	sig := fnType.sig
+	if sig == nil {
+		return anyType
+	}
+	for i, t := range types {
+		if i >= len(sig.types) {
+			break
+		}
+		context := "argument " + sig.params[i] + " to " + sig.displayName()
+		c.checkAssignable(args[i], t, sig.types[i], context)
+	}
+	if sig.result == nil {
+		return anyType
+	}
+	return sig.result
+}
+
+func (s *signature) displayName() string {
+	if s.name == "" {
+		return "function"
+	}
+	return s.name + "()"
+}
+
+// Returns the first token of an expression, which is where errors about the
+// expression are reported.
+func startToken(node ast.Node) token.Token {
+	switch node := node.(type) {
+	case *ast.Infix:
+		return startToken(node.Left())
+	case *ast.Index:
+		return startToken(node.Left())
+	case *ast.Slice:
+		return startToken(node.Left())
+	case *ast.GetAttr:
+		return startToken(node.Object())
+	case *ast.ObjectCall:
+		return startToken(node.Object())
+	case *ast.Call:
+		return startToken(node.Function())
+	case *ast.Ternary:
+		return startToken(node.Condition())
+	case *ast.Pipe:
+		if exprs := node.Expressions(); len(exprs) > 0 {
+			return startToken(exprs[0])
+		}
+	}
+	return node.Token()
+}
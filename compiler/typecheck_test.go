@@ -0,0 +1,110 @@
+package compiler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/itrn0/risor/parser"
+	"github.com/stretchr/testify/require"
+)
+
+var testBuiltins = []string{"len", "chr", "ord", "sprintf", "print", "int"}
+
+func typeErrors(t *testing.T, input string) []string {
+	t.Helper()
+	program, err := parser.Parse(context.Background(), input)
+	require.Nil(t, err)
+	var messages []string
+	for _, err := range TypeCheck(program, WithGlobalNames(testBuiltins)) {
+		messages = append(messages, err.Error())
+	}
+	return messages
+}
+
+func TestTypeCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`1 + "a"`, []string{"type error: unsupported operation for int: + on type string"}},
+		{`x := "a"; x - 1`, []string{"type error: unsupported operation for string: - on type int"}},
+		{`x := 1.5; x % 2`, []string{"type error: unsupported operation for float: %"}},
+		{`[1] + [2, 3]`, nil},
+		{`{a: 1} + 2`, []string{"type error: unsupported operation for map: +"}},
+		{`x := 1; x = "a"; x + 1`, nil},
+		{`x := len("abc") + 1.5`, nil},
+		{`x := len("abc") + "s"`, []string{"type error: unsupported operation for int: + on type string"}},
+		{`chr("a")`, []string{"type error: cannot use string as int in argument 1 to chr()"}},
+		{`s := sprintf("%d", 1) + 2`, []string{"type error: unsupported operation for string: + on type int"}},
+		{`var x: int = "a"`, []string{"type error: cannot use string as int in assignment to x"}},
+		{`x: float := 1`, nil},
+		{`const x: string | nil = nil`, nil},
+		{`x: int := 1; x = 2.5`, []string{"type error: cannot use float as int in assignment to x"}},
+		{`x: int := 1; x += "a"`, []string{"type error: unsupported operation for int: + on type string"}},
+		{`var l: list[int] = [1, "a"]`, nil},
+		{`var l: list[int] = ["a", "b"]`, []string{"type error: cannot use list[string] as list[int] in assignment to l"}},
+		{`var m: map[string, int] = {a: "b"}`, []string{"type error: cannot use map[string, string] as map[string, int] in assignment to m"}},
+		{`var m: map[int] = {a: "b"}`, []string{"type error: cannot use map[string, string] as map[string, int] in assignment to m"}},
+		{`var x: integer = 1`, []string{`type error: unknown type "integer"`}},
+		{`var x: int[string] = 1`, []string{"type error: type int does not take type parameters"}},
+		{`match 1 { case int(n): n + "a"; default: 0 }`, []string{"type error: unsupported operation for int: + on type string"}},
+		{`l := ["a"]; match l { case [s, ...rest]: s - 1; default: 0 }`, []string{"type error: unsupported operation for string: - on type int"}},
+		{`match 1 { case n if n > 0: n + "a"; default: 0 }`, []string{"type error: unsupported operation for int: + on type string"}},
+		{`func g(n: int) { yield n + "a" }`, []string{"type error: unsupported operation for int: + on type string"}},
+		{`c := chan(1); select { case v, ok := <-c: ok - 1; default: 0 }`, []string{"type error: unsupported operation for bool: -"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			require.Equal(t, tt.expected, typeErrors(t, tt.input))
+		})
+	}
+}
+
+func TestTypeCheckFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`func add(a: int, b: int) -> int { a + b }; add(1, 2)`, nil},
+		{`func add(a: int, b: int) -> int { a + b }; add(1, "2")`,
+			[]string{"type error: cannot use string as int in argument b to add()"}},
+		{`func add(a: int, b: int) -> int { a + b }; add(1, 2) + "x"`,
+			[]string{"type error: unsupported operation for int: + on type string"}},
+		{`func f(a: string) { a + 1 }`, []string{"type error: unsupported operation for string: + on type int"}},
+		{`func f() -> int { return "a" }`, []string{"type error: cannot use string as int in return from f()"}},
+		{`func f() -> int { "a" }`, []string{"type error: cannot use string as int in return from f()"}},
+		{`func f(x) -> int { if x { return 1 }; return nil }`, []string{"type error: cannot use nil as int in return from f()"}},
+		{`func f() -> int | nil { return }`, nil},
+		{`func f(a: int = "x") {}`, []string{"type error: cannot use string as int in default value of a"}},
+		{`f := func(s: string) { s }; f(1)`, []string{"type error: cannot use int as string in argument s to f()"}},
+		{`func f(n: int) -> int { n <= 1 ? 1 : n * f(n - 1) }; f(3.5)`,
+			[]string{"type error: cannot use float as int in argument n to f()"}},
+		{`func f(a: int) { a = "b" }`, []string{"type error: cannot use string as int in assignment to a"}},
+		{`struct Point { x }; func f(p: Point) {}; f(Point(1)); f(1)`,
+			[]string{"type error: cannot use int as Point in argument p to f()"}},
+		{`struct Point { x }; p := Point(1); p + 1`, []string{"type error: unsupported operation for Point: +"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			require.Equal(t, tt.expected, typeErrors(t, tt.input))
+		})
+	}
+}
+
+func TestTypeCheckOption(t *testing.T) {
+	program, err := parser.Parse(context.Background(), "x := 1\nfunc f(a: int) -> int { a }\ny := f(\"s\")")
+	require.Nil(t, err)
+
+	// Annotations are ignored unless types are checked
+	_, err = Compile(program)
+	require.Nil(t, err)
+
+	_, err = Compile(program, WithTypeCheck())
+	require.NotNil(t, err)
+	require.Equal(t, "type error: cannot use string as int in argument a to f()", err.Error())
+	var compileErr *CompileError
+	require.True(t, errors.As(err, &compileErr))
+	require.Equal(t, 2, compileErr.StartPosition().Line)
+	require.Equal(t, 7, compileErr.StartPosition().Column)
+}
@@ -0,0 +1,298 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/itrn0/risor/op"
+)
+
+// typ is the static type of an expression, as inferred by the type checker.
+// The "any" type is used whenever a type isn't known.
+type typ struct {
+	// name is the name of the type, e.g. "int", or the name of a struct type.
+	// It's empty for unions.
+	name string
+
+	// elem is the element type of a list or set, or the value type of a map.
+	// Nil when the element type isn't known.
+	elem *typ
+
+	// union holds the alternatives of a union type
+	union []*typ
+
+	// isStruct is true for instances of struct types defined by the program
+	isStruct bool
+
+	// sig is the signature of a function, if known
+	sig *signature
+}
+
+// signature describes the parameters and result of a function.
+type signature struct {
+	name string
+
+	// params holds the parameter names and types. Unannotated parameters
+	// have the "any" type.
+	params []string
+	types  []*typ
+
+	result *typ
+}
+
+var (
+	anyType       = &typ{name: "any"}
+	boolType      = &typ{name: "bool"}
+	byteType      = &typ{name: "byte"}
+	byteSliceType = &typ{name: "byte_slice"}
+	errorType     = &typ{name: "error"}
+	floatType     = &typ{name: "float"}
+	intType       = &typ{name: "int"}
+	listType      = &typ{name: "list"}
+	mapType       = &typ{name: "map"}
+	nilType       = &typ{name: "nil"}
+	setType       = &typ{name: "set"}
+	stringType    = &typ{name: "string"}
+)
+
+// typeNames holds the names of the types that may be used in annotations,
+// other than struct types. These are the names reported by the type builtin.
+var typeNames = map[string]bool{
+	"any":           true,
+	"bool":          true,
+	"buffer":        true,
+	"builtin":       true,
+	"byte":          true,
+	"byte_slice":    true,
+	"channel":       true,
+	"color":         true,
+	"complex":       true,
+	"complex_slice": true,
+	"dir_entry":     true,
+	"error":         true,
+	"file":          true,
+	"file_info":     true,
+	"file_mode":     true,
+	"float":         true,
+	"float_slice":   true,
+	"function":      true,
+	"go_type":       true,
+	"int":           true,
+	"iter_entry":    true,
+	"list":          true,
+	"map":           true,
+	"module":        true,
+	"nil":           true,
+	"proxy":         true,
+	"result":        true,
+	"set":           true,
+	"string":        true,
+	"struct_type":   true,
+	"thread":        true,
+	"time":          true,
+}
+
+// Types with an element type, e.g. list[int]
+var containerTypes = map[string]bool{"list": true, "set": true, "map": true}
+
+// Types whose values don't support any binary operations
+var inertTypes = map[string]bool{
+	"bool":     true,
+	"builtin":  true,
+	"error":    true,
+	"function": true,
+	"map":      true,
+	"module":   true,
+	"nil":      true,
+	"set":      true,
+	"time":     true,
+}
+
+func newFunctionType(sig *signature) *typ { return &typ{name: "function", sig: sig} }
+
+// Returns a union of the given types, or the single type if they're all the
+// same. Any type in the union makes the union "any".
+func newUnionType(types ...*typ) *typ {
+	var union []*typ
+	for _, t := range types {
+		if t.isAny() {
+			return anyType
+		}
+		alternatives := []*typ{t}
+		if t.isUnion() {
+			alternatives = t.union
+		}
+		for _, alt := range alternatives {
+			if !containsType(union, alt) {
+				union = append(union, alt)
+			}
+		}
+	}
+	if len(union) == 1 {
+		return union[0]
+	}
+	return &typ{union: union}
+}
+
+func containsType(types []*typ, t *typ) bool {
+	for _, other := range types {
+		if other.equals(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *typ) isAny() bool { return t == nil || t.name == "any" }
+
+func (t *typ) isUnion() bool { return len(t.union) > 0 }
+
+func (t *typ) equals(other *typ) bool {
+	if t.isUnion() || other.isUnion() {
+		return t.String() == other.String()
+	}
+	if t.name != other.name {
+		return false
+	}
+	if t.elem == nil || other.elem == nil {
+		return t.elem == other.elem
+	}
+	return t.elem.equals(other.elem)
+}
+
+func (t *typ) String() string {
+	if t.isUnion() {
+		names := make([]string, 0, len(t.union))
+		for _, alt := range t.union {
+			names = append(names, alt.String())
+		}
+		return strings.Join(names, " | ")
+	}
+	if t.elem != nil {
+		// Map keys are always strings, so only the element type is stored
+		if t.name == "map" {
+			return fmt.Sprintf("map[string, %s]", t.elem)
+		}
+		return fmt.Sprintf("%s[%s]", t.name, t.elem)
+	}
+	return t.name
+}
+
+// Returns true if a value of type t may be used where the target type is
+// expected. An int may be used as a float, and builtins may be used as
+// functions.
+func (t *typ) assignableTo(target *typ) bool {
+	if t.isAny() || target.isAny() {
+		return true
+	}
+	if t.isUnion() {
+		for _, alt := range t.union {
+			if !alt.assignableTo(target) {
+				return false
+			}
+		}
+		return true
+	}
+	if target.isUnion() {
+		for _, alt := range target.union {
+			if t.assignableTo(alt) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case t.name == target.name:
+		return t.elem == nil || target.elem == nil || t.elem.assignableTo(target.elem)
+	case t.name == "int" && target.name == "float":
+		return true
+	case t.name == "builtin" && target.name == "function":
+		return true
+	}
+	return false
+}
+
+// Returns the type of a binary operation on values of the given types. If
+// the operation is sure to fail, the error matches the one raised when the
+// operation runs.
+func binaryOpType(opType op.BinaryOpType, left, right *typ) (*typ, error) {
+	if left.isAny() || right.isAny() || left.isUnion() || right.isUnion() {
+		return anyType, nil
+	}
+	unsupported := func() error {
+		return fmt.Errorf("type error: unsupported operation for %s: %v on type %s", left.name, opType, right.name)
+	}
+	numeric := right.name == "int" || right.name == "float" || right.name == "byte"
+	switch {
+	case left.name == "int":
+		switch {
+		case right.name == "int" || right.name == "byte":
+			return intType, nil
+		case right.name != "float":
+			return nil, unsupported()
+		}
+		switch opType {
+		case op.Add, op.Subtract, op.Multiply, op.Divide:
+			return floatType, nil
+		case op.Power:
+			return intType, nil
+		}
+		return nil, unsupported()
+	case left.name == "float":
+		if !numeric {
+			return nil, unsupported()
+		}
+		switch opType {
+		case op.Add, op.Subtract, op.Multiply, op.Divide, op.Power:
+			return floatType, nil
+		}
+		return nil, fmt.Errorf("type error: unsupported operation for float: %v", opType)
+	case left.name == "string" || left.name == "list":
+		if right.name != left.name || opType != op.Add {
+			return nil, unsupported()
+		}
+		if left.name == "list" && !left.equals(right) {
+			return listType, nil
+		}
+		return left, nil
+	case inertTypes[left.name] || left.isStruct:
+		return nil, fmt.Errorf("type error: unsupported operation for %s: %v", left.name, opType)
+	}
+	return anyType, nil
+}
+
+// The signatures of the default builtins. The types of their parameters are
+// only given where any other type causes an error.
+var builtinSignatures = map[string]*signature{
+	"all":         builtinSignature("all", boolType, anyType),
+	"any":         builtinSignature("any", boolType, anyType),
+	"assert":      builtinSignature("assert", nilType, anyType),
+	"bool":        builtinSignature("bool", boolType),
+	"byte_slice":  builtinSignature("byte_slice", byteSliceType),
+	"byte":        builtinSignature("byte", byteType),
+	"chr":         builtinSignature("chr", stringType, intType),
+	"error":       builtinSignature("error", errorType, anyType),
+	"float":       builtinSignature("float", floatType),
+	"int":         builtinSignature("int", intType),
+	"is_hashable": builtinSignature("is_hashable", boolType, anyType),
+	"keys":        builtinSignature("keys", listType, anyType),
+	"len":         builtinSignature("len", intType, anyType),
+	"list":        builtinSignature("list", listType),
+	"map":         builtinSignature("map", mapType),
+	"ord":         builtinSignature("ord", intType, stringType),
+	"set":         builtinSignature("set", setType),
+	"sorted":      builtinSignature("sorted", listType, anyType),
+	"sprintf":     builtinSignature("sprintf", stringType, stringType),
+	"string":      builtinSignature("string", stringType),
+	"type":        builtinSignature("type", stringType, anyType),
+}
+
+// Returns the signature of a builtin with the given parameter types. Any
+// further arguments are accepted without being checked.
+func builtinSignature(name string, result *typ, params ...*typ) *signature {
+	names := make([]string, len(params))
+	for i := range params {
+		names[i] = fmt.Sprint(i + 1)
+	}
+	return &signature{name: name, params: names, types: params, result: result}
+}
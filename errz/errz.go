@@ -108,3 +108,32 @@ func AreTypeErrorsFatal() bool {
 func SetTypeErrorsAreFatal(fatal bool) {
 	typeErrorsAreFatal = fatal
 }
+
+// PermissionError is used to indicate that a policy denied a call to a
+// privileged function. All PermissionErrors are considered fatal errors, so
+// that denials can't be caught and ignored by the script.
+type PermissionError struct {
+	// Name is the name of the function that was called, e.g. "os.read_file".
+	Name string
+	Err  error
+}
+
+func (p *PermissionError) Error() string {
+	return fmt.Sprintf("permission error: %s: %s", p.Name, p.Err)
+}
+
+func (p *PermissionError) Unwrap() error {
+	return p.Err
+}
+
+func (p *PermissionError) IsFatal() bool {
+	return true
+}
+
+func NewPermissionError(name string, err error) *PermissionError {
+	return &PermissionError{Name: name, Err: err}
+}
+
+func PermissionErrorf(name string, format string, args ...any) *PermissionError {
+	return NewPermissionError(name, fmt.Errorf(format, args...))
+}
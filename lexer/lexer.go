@@ -52,6 +52,9 @@ type Lexer struct {
 
 	// Name of the file be read
 	file string
+
+	// Comments read so far, which are not returned as tokens
+	comments []token.Token
 }
 
 // Option is a configuration function for a Lexer.
@@ -89,6 +92,13 @@ func (l *Lexer) SetFilename(file string) {
 	l.file = file
 }
 
+// Comments returns the comments read so far, in the order they appear in the
+// input. Each is a token of type COMMENT whose literal is the full text of the
+// comment, including the comment markers.
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
 // Position returns the current read position of the Lexer as a Position object.
 func (l *Lexer) Position() token.Position {
 	return token.Position{
@@ -117,6 +127,7 @@ func (l *Lexer) Next() (token.Token, error) {
 	// multi-line comments
 	if l.ch == rune('/') && l.peekChar() == rune('*') {
 		l.skipMultiLineComment()
+		return l.Next()
 	}
 
 	if l.prevToken.Type == token.EOF {
@@ -158,7 +169,14 @@ func (l *Lexer) Next() (token.Token, error) {
 	case rune(','):
 		tok = l.newToken(token.COMMA, string(l.ch))
 	case rune('.'):
-		tok = l.newToken(token.PERIOD, string(l.ch))
+		if l.peekChar() == rune('.') && l.nextPosition+1 < len(l.characters) &&
+			l.characters[l.nextPosition+1] == rune('.') {
+			l.readChar()
+			l.readChar()
+			tok = l.newToken(token.ELLIPSIS, "...")
+		} else {
+			tok = l.newToken(token.PERIOD, string(l.ch))
+		}
 	case rune('+'):
 		if l.peekChar() == rune('+') {
 			ch := l.ch
@@ -186,6 +204,10 @@ func (l *Lexer) Next() (token.Token, error) {
 			ch := l.ch
 			l.readChar()
 			tok = l.newToken(token.MINUS_EQUALS, string(ch)+string(l.ch))
+		} else if l.peekChar() == rune('>') {
+			ch := l.ch
+			l.readChar()
+			tok = l.newToken(token.ARROW, string(ch)+string(l.ch))
 		} else {
 			tok = l.newToken(token.MINUS, string(l.ch))
 		}
@@ -402,9 +424,11 @@ func (l *Lexer) skipTabsAndSpaces() {
 
 // Skip a comment until the end of the line
 func (l *Lexer) skipComment() {
-	for l.ch != '\n' && l.ch != rune(0) {
+	for l.peekChar() != '\n' && l.peekChar() != rune(0) {
 		l.readChar()
 	}
+	l.recordComment()
+	l.readChar()
 	l.skipTabsAndSpaces()
 }
 
@@ -422,11 +446,26 @@ func (l *Lexer) skipMultiLineComment() {
 			// Our current position is "*", so skip forward to consume the "/"
 			l.readChar()
 		}
+		if found {
+			l.recordComment()
+		}
 		l.readChar()
 	}
 	l.skipTabsAndSpaces()
 }
 
+// Records the comment that begins at the start of the current token and ends
+// at the current character.
+func (l *Lexer) recordComment() {
+	start := l.tokenStartPosition.Char
+	end := l.position + 1
+	if end > len(l.characters) {
+		end = len(l.characters)
+	}
+	text := strings.TrimRight(string(l.characters[start:end]), " \t\r")
+	l.comments = append(l.comments, l.newToken(token.COMMENT, text))
+}
+
 // Read a decimal, hex, or octal number
 func (l *Lexer) readNumber(onlyDecimal bool) (NumberType, string, error) {
 	str := string(l.ch)
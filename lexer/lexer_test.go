@@ -566,6 +566,76 @@ a = 3/4;`
 	}
 }
 
+func TestArrow(t *testing.T) {
+	input := `func f(a: int) -> int { a - -1 }`
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.FUNC, "func"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.COLON, ":"},
+		{token.IDENT, "int"},
+		{token.RPAREN, ")"},
+		{token.ARROW, "->"},
+		{token.IDENT, "int"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "a"},
+		{token.MINUS, "-"},
+		{token.MINUS, "-"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for i, tt := range tests {
+		tok, err := l.Next()
+		require.Nil(t, err)
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong, expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - Literal wrong, expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestEllipsis(t *testing.T) {
+	input := `[a, ...rest] x.y ..`
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.LBRACKET, "["},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "rest"},
+		{token.RBRACKET, "]"},
+		{token.IDENT, "x"},
+		{token.PERIOD, "."},
+		{token.IDENT, "y"},
+		{token.PERIOD, "."},
+		{token.PERIOD, "."},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for i, tt := range tests {
+		tok, err := l.Next()
+		require.Nil(t, err)
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong, expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - Literal wrong, expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
 func TestLineNumbers(t *testing.T) {
 	l := New("ab + cd\n foo+=111")
 	tests := []struct {
@@ -787,3 +857,31 @@ func TestInvalids(t *testing.T) {
 		})
 	}
 }
+
+func TestComments(t *testing.T) {
+	input := "#!/usr/bin/env risor\nx := 1 // one  \n/* two\n lines */ y := 2\n# three"
+	l := New(input)
+	var types []token.Type
+	for {
+		tok, err := l.Next()
+		require.Nil(t, err)
+		types = append(types, tok.Type)
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+	require.NotContains(t, types, token.Type(token.COMMENT))
+	comments := l.Comments()
+	require.Len(t, comments, 4)
+	require.Equal(t, "#!/usr/bin/env risor", comments[0].Literal)
+	require.Equal(t, "// one", comments[1].Literal)
+	require.Equal(t, 1, comments[1].StartPosition.Line)
+	require.Equal(t, 7, comments[1].StartPosition.Column)
+	require.Equal(t, "/* two\n lines */", comments[2].Literal)
+	require.Equal(t, 2, comments[2].StartPosition.Line)
+	require.Equal(t, 3, comments[2].EndPosition.Line)
+	require.Equal(t, "# three", comments[3].Literal)
+	for _, c := range comments {
+		require.Equal(t, token.Type(token.COMMENT), c.Type)
+	}
+}
@@ -37,6 +37,20 @@ type Limits interface {
 	ReadAll(reader io.Reader) ([]byte, error)
 }
 
+// VMLimits may be implemented by Limits that also restrict the work done by
+// the VM. The VM keeps its own default for each limit that isn't set.
+type VMLimits interface {
+	// MaxMemoryUsage returns the maximum number of bytes that may be held on
+	// the VM stack during an evaluation, and whether it's set. NoLimit means
+	// unlimited.
+	MaxMemoryUsage() (int64, bool)
+
+	// MaxInstructions returns the maximum number of instructions that may be
+	// executed during an evaluation, and whether it's set. NoLimit means
+	// unlimited.
+	MaxInstructions() (int64, bool)
+}
+
 type contextKey string
 
 const limitsKey = contextKey("risor:limits")
@@ -2,17 +2,25 @@ package limits
 
 import (
 	"io"
+	"math"
 	"net/http"
 	"sync"
 	"time"
 )
 
+// Marks a VM limit that wasn't configured, so the VM default applies
+const unset = math.MinInt64
+
+var _ VMLimits = (*StandardLimits)(nil)
+
 type StandardLimits struct {
 	// Configuration
 	ioTimeout           time.Duration
 	maxBufferSize       int64
 	maxHttpRequestCount int64
 	maxCost             int64
+	maxMemoryUsage      int64
+	maxInstructions     int64
 	// Metrics
 	httpRequestsCount int64
 	cost              int64
@@ -28,6 +36,16 @@ func (l *StandardLimits) MaxBufferSize() int64 {
 	return l.maxBufferSize
 }
 
+// MaxMemoryUsage returns the limit set with WithMaxMemoryUsage, if any.
+func (l *StandardLimits) MaxMemoryUsage() (int64, bool) {
+	return l.maxMemoryUsage, l.maxMemoryUsage != unset
+}
+
+// MaxInstructions returns the limit set with WithMaxInstructions, if any.
+func (l *StandardLimits) MaxInstructions() (int64, bool) {
+	return l.maxInstructions, l.maxInstructions != unset
+}
+
 func (l *StandardLimits) TrackHTTPRequest(req *http.Request) error {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
@@ -104,12 +122,30 @@ func WithMaxCost(cost int64) Option {
 	}
 }
 
+// WithMaxMemoryUsage sets the maximum number of bytes that may be held on the
+// VM stack during an evaluation. If it isn't set, the VM default applies.
+func WithMaxMemoryUsage(size int64) Option {
+	return func(l *StandardLimits) {
+		l.maxMemoryUsage = size
+	}
+}
+
+// WithMaxInstructions sets the maximum number of instructions that may be
+// executed during an evaluation. If it isn't set, the VM default applies.
+func WithMaxInstructions(count int64) Option {
+	return func(l *StandardLimits) {
+		l.maxInstructions = count
+	}
+}
+
 // New creates a new Limits instance with the given options.
 func New(opts ...Option) Limits {
 	l := &StandardLimits{
 		maxBufferSize:       NoLimit,
 		maxHttpRequestCount: NoLimit,
 		maxCost:             NoLimit,
+		maxMemoryUsage:      unset,
+		maxInstructions:     unset,
 	}
 	for _, opt := range opts {
 		opt(l)
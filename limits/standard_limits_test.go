@@ -36,3 +36,19 @@ func TestLimits(t *testing.T) {
 	require.Error(t, err)
 	require.Equal(t, "limit error: reached maximum number of http requests (1)", err.Error())
 }
+
+func TestVMLimits(t *testing.T) {
+	l := New().(VMLimits)
+	_, ok := l.MaxMemoryUsage()
+	require.False(t, ok)
+	_, ok = l.MaxInstructions()
+	require.False(t, ok)
+
+	l = New(WithMaxMemoryUsage(1024), WithMaxInstructions(NoLimit)).(VMLimits)
+	size, ok := l.MaxMemoryUsage()
+	require.True(t, ok)
+	require.Equal(t, int64(1024), size)
+	count, ok := l.MaxInstructions()
+	require.True(t, ok)
+	require.Equal(t, int64(NoLimit), count)
+}
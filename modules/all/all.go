@@ -2,17 +2,23 @@ package all
 
 import (
 	"github.com/itrn0/risor/builtins"
+	modArchive "github.com/itrn0/risor/modules/archive"
 	modBase64 "github.com/itrn0/risor/modules/base64"
 	modBytes "github.com/itrn0/risor/modules/bytes"
 	modColor "github.com/itrn0/risor/modules/color"
+	modCrypto "github.com/itrn0/risor/modules/crypto"
+	modDotenv "github.com/itrn0/risor/modules/dotenv"
 	modErrors "github.com/itrn0/risor/modules/errors"
 	modExec "github.com/itrn0/risor/modules/exec"
 	modFilepath "github.com/itrn0/risor/modules/filepath"
 	modFmt "github.com/itrn0/risor/modules/fmt"
 	modGha "github.com/itrn0/risor/modules/gha"
 	modHTTP "github.com/itrn0/risor/modules/http"
+	modINI "github.com/itrn0/risor/modules/ini"
 	modIsTTY "github.com/itrn0/risor/modules/isatty"
+	modIter "github.com/itrn0/risor/modules/iter"
 	modJSON "github.com/itrn0/risor/modules/json"
+	modLog "github.com/itrn0/risor/modules/log"
 	modMath "github.com/itrn0/risor/modules/math"
 	modNet "github.com/itrn0/risor/modules/net"
 	modOs "github.com/itrn0/risor/modules/os"
@@ -20,25 +26,33 @@ import (
 	modRegexp "github.com/itrn0/risor/modules/regexp"
 	modStrconv "github.com/itrn0/risor/modules/strconv"
 	modStrings "github.com/itrn0/risor/modules/strings"
+	modSync "github.com/itrn0/risor/modules/sync"
 	modTablewriter "github.com/itrn0/risor/modules/tablewriter"
 	modTime "github.com/itrn0/risor/modules/time"
+	modTOML "github.com/itrn0/risor/modules/toml"
+	modXML "github.com/itrn0/risor/modules/xml"
 	modYAML "github.com/itrn0/risor/modules/yaml"
 	"github.com/itrn0/risor/object"
 )
 
 func Builtins() map[string]object.Object {
 	result := map[string]object.Object{
+		"archive":     modArchive.Module(),
 		"base64":      modBase64.Module(),
 		"bytes":       modBytes.Module(),
 		"color":       modColor.Module(),
+		"crypto":      modCrypto.Module(),
+		"dotenv":      modDotenv.Module(),
 		"errors":      modErrors.Module(),
 		"exec":        modExec.Module(),
 		"filepath":    modFilepath.Module(),
 		"fmt":         modFmt.Module(),
 		"gha":         modGha.Module(),
 		"http":        modHTTP.Module(),
+		"ini":         modINI.Module(),
 		"isatty":      modIsTTY.Module(),
 		"json":        modJSON.Module(),
+		"log":         modLog.Module(),
 		"math":        modMath.Module(),
 		"net":         modNet.Module(),
 		"os":          modOs.Module(),
@@ -46,8 +60,11 @@ func Builtins() map[string]object.Object {
 		"regexp":      modRegexp.Module(),
 		"strconv":     modStrconv.Module(),
 		"strings":     modStrings.Module(),
+		"sync":        modSync.Module(),
 		"tablewriter": modTablewriter.Module(),
 		"time":        modTime.Module(),
+		"toml":        modTOML.Module(),
+		"xml":         modXML.Module(),
 		"yaml":        modYAML.Module(),
 	}
 	for k, v := range modHTTP.Builtins() {
@@ -62,5 +79,8 @@ func Builtins() map[string]object.Object {
 	for k, v := range modOs.Builtins() {
 		result[k] = v
 	}
+	// The iter module replaces the iter builtin, which it's compatible with
+	// since calling the module also returns an iterator
+	result["iter"] = modIter.Module()
 	return result
 }
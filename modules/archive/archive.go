@@ -0,0 +1,371 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/limits"
+	"github.com/itrn0/risor/object"
+	ros "github.com/itrn0/risor/os"
+)
+
+// Returns the options map passed as the argument at the given index, after
+// checking that it contains only the allowed keys.
+func getOptions(funcName string, args []object.Object, index int, allowed ...string) (*object.Map, *object.Error) {
+	if len(args) <= index {
+		return object.NewMap(map[string]object.Object{}), nil
+	}
+	opts, ok := args[index].(*object.Map)
+	if !ok {
+		return nil, object.TypeErrorf("type error: %s() expected a map of options (%s given)", funcName, args[index].Type())
+	}
+	for _, key := range opts.SortedKeys() {
+		found := false
+		for _, name := range allowed {
+			if key == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, object.Errorf("value error: %s() got an unexpected option %q", funcName, key)
+		}
+	}
+	return opts, nil
+}
+
+// Returns the format given in the options, if any.
+func getFormat(funcName string, opts *object.Map) (string, *object.Error) {
+	formatObj := opts.GetWithDefault("format", nil)
+	if formatObj == nil {
+		return "", nil
+	}
+	name, err := object.AsString(formatObj)
+	if err != nil {
+		return "", err
+	}
+	format, ok := parseFormat(name)
+	if !ok {
+		return "", object.Errorf("value error: %s() unsupported format %q (expected %q, %q or %q)",
+			funcName, name, formatTar, formatTarGz, formatZip)
+	}
+	return format, nil
+}
+
+// Reads all data from the reader, up to the buffer size limit of the context.
+// Archives may be small while containing very large files, so every read is
+// limited rather than only the archive itself.
+func readAll(ctx context.Context, r io.Reader) ([]byte, error) {
+	if lim, ok := limits.GetLimits(ctx); ok && lim.MaxBufferSize() > 0 {
+		return limits.ReadAll(r, lim.MaxBufferSize())
+	}
+	return io.ReadAll(r)
+}
+
+func readFile(ctx context.Context, osObj ros.OS, name string) ([]byte, error) {
+	f, err := osObj.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readAll(ctx, f)
+}
+
+// Reads an archive from the filesystem, returning its contents and format.
+func readArchive(ctx context.Context, funcName string, filename string, opts *object.Map) ([]byte, string, *object.Error) {
+	format, err := getFormat(funcName, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	data, ioErr := readFile(ctx, ros.GetDefaultOS(ctx), filename)
+	if ioErr != nil {
+		return nil, "", object.NewError(ioErr)
+	}
+	if format == "" {
+		format = formatFromData(data)
+	}
+	return data, format, nil
+}
+
+// Returns the path within the destination directory at which an entry is
+// extracted. Entries with absolute paths or paths containing ".." elements
+// that lead outside of the directory are rejected.
+func entryPath(dest, name string) (string, bool) {
+	name = strings.TrimSuffix(name, "/")
+	if name == "" || strings.Contains(name, `\`) || !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", false
+	}
+	return filepath.Join(dest, filepath.FromSlash(name)), true
+}
+
+// linkChecker finds existing symlinks below the destination directory of an
+// extraction. Writing through them could place files outside of it.
+type linkChecker struct {
+	os      ros.OS
+	dest    string
+	checked map[string]bool
+}
+
+// Returns the first symlink on the path from the destination directory to
+// the target, or an empty string if there is none. The destination directory
+// itself may be a symlink.
+func (c *linkChecker) find(target string) string {
+	rel, err := filepath.Rel(c.dest, target)
+	if err != nil || rel == "." {
+		return ""
+	}
+	current := c.dest
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		if c.checked[current] {
+			continue
+		}
+		info, err := ros.Lstat(c.os, current)
+		if err != nil {
+			// Nothing exists at this path yet, so nothing exists below it
+			return ""
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return current
+		}
+		c.checked[current] = true
+	}
+	return ""
+}
+
+type creator struct {
+	ctx      context.Context
+	funcName string
+	os       ros.OS
+	dir      string
+	w        writer
+}
+
+// Adds the file or directory with the given name, relative to the base
+// directory. Directories are added recursively.
+func (c *creator) add(name string, info fs.FileInfo) error {
+	fullPath := filepath.Join(c.dir, filepath.FromSlash(name))
+	if !info.IsDir() {
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("%s() can't add %s: not a regular file or directory", c.funcName, name)
+		}
+		data, err := readFile(c.ctx, c.os, fullPath)
+		if err != nil {
+			return err
+		}
+		return c.w.add(name, info, data)
+	}
+	if name != "." {
+		if err := c.w.add(name+"/", info, nil); err != nil {
+			return err
+		}
+	}
+	entries, err := c.os.ReadDir(fullPath)
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	for _, e := range entries {
+		childName := path.Join(name, e.Name())
+		childInfo, err := c.os.Stat(filepath.Join(fullPath, e.Name()))
+		if err != nil {
+			return err
+		}
+		// Following links to directories could recurse forever
+		if e.Type()&fs.ModeSymlink != 0 && childInfo.IsDir() {
+			return fmt.Errorf("%s() can't add %s: symlinks to directories are not supported", c.funcName, childName)
+		}
+		if err := c.add(childName, childInfo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Create(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("archive.create", 2, 3, args); err != nil {
+		return err
+	}
+	filename, err := object.AsString(args[0])
+	if err != nil {
+		return err
+	}
+	paths, err := object.AsStringSlice(args[1])
+	if err != nil {
+		return err
+	}
+	opts, err := getOptions("archive.create", args, 2, "dir", "format")
+	if err != nil {
+		return err
+	}
+	format, err := getFormat("archive.create", opts)
+	if err != nil {
+		return err
+	}
+	if format == "" {
+		var ok bool
+		if format, ok = formatFromPath(filename); !ok {
+			return object.Errorf("value error: archive.create() can't determine the format of %q (use the format option)", filename)
+		}
+	}
+	var dir string
+	if dirObj := opts.GetWithDefault("dir", nil); dirObj != nil {
+		if dir, err = object.AsString(dirObj); err != nil {
+			return err
+		}
+	}
+	var buf bytes.Buffer
+	c := &creator{
+		ctx:      ctx,
+		funcName: "archive.create",
+		os:       ros.GetDefaultOS(ctx),
+		dir:      dir,
+		w:        newWriter(format, &buf),
+	}
+	for _, p := range paths {
+		// Entry names are the given paths, so they must stay within the base
+		// directory to be extracted safely
+		name := filepath.ToSlash(filepath.Clean(p))
+		if !filepath.IsLocal(p) {
+			return object.Errorf("value error: archive.create() path %q must be relative and within the base directory", p)
+		}
+		info, statErr := c.os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+		if statErr != nil {
+			return object.NewError(statErr)
+		}
+		if addErr := c.add(name, info); addErr != nil {
+			return object.NewError(addErr)
+		}
+	}
+	if closeErr := c.w.close(); closeErr != nil {
+		return object.NewError(closeErr)
+	}
+	if lim, ok := limits.GetLimits(ctx); ok && lim.MaxBufferSize() > 0 && int64(buf.Len()) > lim.MaxBufferSize() {
+		return object.NewError(limits.NewLimitsError(
+			"limit error: archive size exceeded limit of %d bytes (got %d)", lim.MaxBufferSize(), buf.Len()))
+	}
+	if ioErr := c.os.WriteFile(filename, buf.Bytes(), 0o644); ioErr != nil {
+		return object.NewError(ioErr)
+	}
+	return object.Nil
+}
+
+func List(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("archive.list", 1, 2, args); err != nil {
+		return err
+	}
+	filename, err := object.AsString(args[0])
+	if err != nil {
+		return err
+	}
+	opts, err := getOptions("archive.list", args, 1, "format")
+	if err != nil {
+		return err
+	}
+	data, format, err := readArchive(ctx, "archive.list", filename, opts)
+	if err != nil {
+		return err
+	}
+	var items []object.Object
+	walkErr := walk(format, data, func(e *entry, r io.Reader) error {
+		items = append(items, object.NewMap(map[string]object.Object{
+			"name":     object.NewString(e.name),
+			"type":     object.NewString(e.kind),
+			"size":     object.NewInt(e.size),
+			"mode":     object.NewFileMode(e.mode),
+			"mod_time": object.NewTime(e.modTime),
+		}))
+		return nil
+	})
+	if walkErr != nil {
+		return object.NewError(walkErr)
+	}
+	return object.NewList(items)
+}
+
+func Extract(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("archive.extract", 2, 3, args); err != nil {
+		return err
+	}
+	filename, err := object.AsString(args[0])
+	if err != nil {
+		return err
+	}
+	dest, err := object.AsString(args[1])
+	if err != nil {
+		return err
+	}
+	opts, err := getOptions("archive.extract", args, 2, "format")
+	if err != nil {
+		return err
+	}
+	data, format, err := readArchive(ctx, "archive.extract", filename, opts)
+	if err != nil {
+		return err
+	}
+	// Check every entry before writing anything, so that an unsafe archive
+	// doesn't leave a partial extraction behind
+	osObj := ros.GetDefaultOS(ctx)
+	checker := &linkChecker{os: osObj, dest: dest, checked: map[string]bool{}}
+	walkErr := walk(format, data, func(e *entry, r io.Reader) error {
+		target, ok := entryPath(dest, e.name)
+		if !ok {
+			return fmt.Errorf("archive.extract() entry %q is outside of the destination directory", e.name)
+		}
+		if e.kind != "file" && e.kind != "dir" {
+			return fmt.Errorf("archive.extract() entry %q is a %s, which is not supported", e.name, e.kind)
+		}
+		if link := checker.find(target); link != "" {
+			return fmt.Errorf("archive.extract() entry %q would be written through the symlink %q", e.name, link)
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return object.Errorf("value error: %s", walkErr)
+	}
+	if ioErr := osObj.MkdirAll(dest, 0o755); ioErr != nil {
+		return object.NewError(ioErr)
+	}
+	var names []object.Object
+	walkErr = walk(format, data, func(e *entry, r io.Reader) error {
+		target, _ := entryPath(dest, e.name)
+		names = append(names, object.NewString(e.name))
+		if e.kind == "dir" {
+			return osObj.MkdirAll(target, 0o755)
+		}
+		if err := osObj.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		content, err := readAll(ctx, r)
+		if err != nil {
+			return err
+		}
+		perm := e.mode
+		if perm == 0 {
+			perm = 0o644
+		}
+		return osObj.WriteFile(target, content, perm)
+	})
+	if walkErr != nil {
+		return object.NewError(walkErr)
+	}
+	return object.NewList(names)
+}
+
+func Module() *object.Module {
+	return object.NewBuiltinsModule("archive", map[string]object.Object{
+		"create":  object.NewBuiltin("archive.create", Create),
+		"extract": object.NewBuiltin("archive.extract", Extract),
+		"list":    object.NewBuiltin("archive.list", List),
+	})
+}
@@ -0,0 +1,244 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itrn0/risor/limits"
+	"github.com/itrn0/risor/object"
+	ros "github.com/itrn0/risor/os"
+	"github.com/itrn0/risor/os/localfs"
+	"github.com/stretchr/testify/require"
+)
+
+// Returns a context with a virtual OS whose root is a temporary directory,
+// along with the path of that directory on the host.
+func testContext(t *testing.T) (context.Context, string) {
+	t.Helper()
+	root := t.TempDir()
+	ctx := context.Background()
+	fs, err := localfs.New(ctx, localfs.WithBase(root))
+	require.NoError(t, err)
+	vos := ros.NewVirtualOS(ctx, ros.WithMounts(map[string]*ros.Mount{
+		"/": {Source: fs, Target: "/", Type: "local"},
+	}))
+	return ros.WithOS(ctx, vos), root
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func stringList(items ...string) *object.List {
+	list := make([]object.Object, 0, len(items))
+	for _, item := range items {
+		list = append(list, object.NewString(item))
+	}
+	return object.NewList(list)
+}
+
+func TestCreateListExtract(t *testing.T) {
+	for _, filename := range []string{"out.tar", "out.tar.gz", "out.tgz", "out.zip"} {
+		t.Run(filename, func(t *testing.T) {
+			ctx, root := testContext(t)
+			writeFiles(t, root, map[string]string{
+				"build/bin/app":    "binary",
+				"build/README.md":  "# app",
+				"build/docs/a.txt": "a",
+			})
+			result := Create(ctx, object.NewString(filename), stringList("bin", "README.md", "docs"),
+				object.NewMap(map[string]object.Object{"dir": object.NewString("build")}))
+			require.Equal(t, object.Nil, result)
+
+			listed := List(ctx, object.NewString(filename))
+			require.IsType(t, &object.List{}, listed)
+			var names, kinds []string
+			for _, item := range listed.(*object.List).Value() {
+				m := item.(*object.Map)
+				names = append(names, m.Get("name").(*object.String).Value())
+				kinds = append(kinds, m.Get("type").(*object.String).Value())
+			}
+			require.Equal(t, []string{"bin/", "bin/app", "README.md", "docs/", "docs/a.txt"}, names)
+			require.Equal(t, []string{"dir", "file", "file", "dir", "file"}, kinds)
+
+			extracted := Extract(ctx, object.NewString(filename), object.NewString("dest"))
+			require.Equal(t, stringList("bin/", "bin/app", "README.md", "docs/", "docs/a.txt"), extracted)
+			for name, want := range map[string]string{"bin/app": "binary", "README.md": "# app", "docs/a.txt": "a"} {
+				data, err := os.ReadFile(filepath.Join(root, "dest", filepath.FromSlash(name)))
+				require.NoError(t, err)
+				require.Equal(t, want, string(data))
+			}
+		})
+	}
+}
+
+func TestCreateErrors(t *testing.T) {
+	ctx, root := testContext(t)
+	writeFiles(t, root, map[string]string{"a.txt": "a"})
+	tests := []struct {
+		args     []object.Object
+		expected string
+	}{
+		{
+			[]object.Object{object.NewString("out.rar"), stringList("a.txt")},
+			`value error: archive.create() can't determine the format of "out.rar" (use the format option)`,
+		},
+		{
+			[]object.Object{object.NewString("out"), stringList("a.txt"),
+				object.NewMap(map[string]object.Object{"format": object.NewString("rar")})},
+			`value error: archive.create() unsupported format "rar" (expected "tar", "tar.gz" or "zip")`,
+		},
+		{
+			[]object.Object{object.NewString("out.zip"), stringList("a.txt"),
+				object.NewMap(map[string]object.Object{"level": object.NewInt(9)})},
+			`value error: archive.create() got an unexpected option "level"`,
+		},
+		{
+			[]object.Object{object.NewString("out.zip"), stringList("../a.txt")},
+			`value error: archive.create() path "../a.txt" must be relative and within the base directory`,
+		},
+		{
+			[]object.Object{object.NewString("out.zip"), stringList("/a.txt")},
+			`value error: archive.create() path "/a.txt" must be relative and within the base directory`,
+		},
+	}
+	for _, tt := range tests {
+		result := Create(ctx, tt.args...)
+		errObj, ok := result.(*object.Error)
+		require.True(t, ok, "expected an error, got %v", result)
+		require.Equal(t, tt.expected, errObj.Value().Error())
+	}
+}
+
+func TestCreateWithFormat(t *testing.T) {
+	ctx, root := testContext(t)
+	writeFiles(t, root, map[string]string{"a.txt": "a"})
+	result := Create(ctx, object.NewString("out.bin"), stringList("a.txt"),
+		object.NewMap(map[string]object.Object{"format": object.NewString("zip")}))
+	require.Equal(t, object.Nil, result)
+	data, err := os.ReadFile(filepath.Join(root, "out.bin"))
+	require.NoError(t, err)
+	require.Equal(t, formatZip, formatFromData(data))
+}
+
+func writeTar(t *testing.T, path string, headers ...*tar.Header) {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range headers {
+		require.NoError(t, tw.WriteHeader(hdr))
+		if hdr.Size > 0 {
+			_, err := tw.Write(bytes.Repeat([]byte("x"), int(hdr.Size)))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+}
+
+func TestExtractRejectsUnsafeEntries(t *testing.T) {
+	tests := []struct {
+		header   *tar.Header
+		expected string
+	}{
+		{
+			&tar.Header{Name: "../evil.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1},
+			`value error: archive.extract() entry "../evil.txt" is outside of the destination directory`,
+		},
+		{
+			&tar.Header{Name: "a/../../evil.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1},
+			`value error: archive.extract() entry "a/../../evil.txt" is outside of the destination directory`,
+		},
+		{
+			&tar.Header{Name: "/etc/evil.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1},
+			`value error: archive.extract() entry "/etc/evil.txt" is outside of the destination directory`,
+		},
+		{
+			&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd", Mode: 0o777},
+			`value error: archive.extract() entry "link" is a symlink, which is not supported`,
+		},
+		{
+			&tar.Header{Name: "hard", Typeflag: tar.TypeLink, Linkname: "/etc/passwd", Mode: 0o644},
+			`value error: archive.extract() entry "hard" is a link, which is not supported`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header.Name, func(t *testing.T) {
+			ctx, root := testContext(t)
+			// The safe entry must not be written either
+			writeTar(t, filepath.Join(root, "bad.tar"),
+				&tar.Header{Name: "ok.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: 2},
+				tt.header)
+			result := Extract(ctx, object.NewString("bad.tar"), object.NewString("dest"))
+			errObj, ok := result.(*object.Error)
+			require.True(t, ok, "expected an error, got %v", result)
+			require.Equal(t, tt.expected, errObj.Value().Error())
+			_, err := os.Stat(filepath.Join(root, "dest"))
+			require.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestExtractRejectsExistingSymlinks(t *testing.T) {
+	ctx, root := testContext(t)
+	outside := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "dest"), 0o755))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "dest", "link")))
+	writeTar(t, filepath.Join(root, "bad.tar"),
+		&tar.Header{Name: "link/evil.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1})
+
+	result := Extract(ctx, object.NewString("bad.tar"), object.NewString("dest"))
+	errObj, ok := result.(*object.Error)
+	require.True(t, ok, "expected an error, got %v", result)
+	require.Equal(t, `value error: archive.extract() entry "link/evil.txt" would be written through the symlink "dest/link"`,
+		errObj.Value().Error())
+	_, err := os.Stat(filepath.Join(outside, "evil.txt"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestBufferLimit(t *testing.T) {
+	ctx, root := testContext(t)
+	writeFiles(t, root, map[string]string{"big.txt": string(bytes.Repeat([]byte("a"), 100000))})
+	require.Equal(t, object.Nil, Create(ctx, object.NewString("big.zip"), stringList("big.txt")))
+
+	// The archive is small, but the file it contains is not
+	limited := limits.WithLimits(ctx, limits.New(limits.WithMaxBufferSize(1000)))
+	result := Extract(limited, object.NewString("big.zip"), object.NewString("dest"))
+	errObj, ok := result.(*object.Error)
+	require.True(t, ok, "expected an error, got %v", result)
+	require.Equal(t, "limit error: data size exceeded limit of 1000 bytes", errObj.Value().Error())
+
+	result = Create(limited, object.NewString("again.zip"), stringList("big.txt"))
+	errObj, ok = result.(*object.Error)
+	require.True(t, ok, "expected an error, got %v", result)
+	require.Equal(t, "limit error: data size exceeded limit of 1000 bytes", errObj.Value().Error())
+}
+
+func TestListIncludesLinks(t *testing.T) {
+	ctx, root := testContext(t)
+	writeTar(t, filepath.Join(root, "links.tar"),
+		&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "target", Mode: 0o777})
+	listed := List(ctx, object.NewString("links.tar"), object.NewMap(map[string]object.Object{
+		"format": object.NewString("tar"),
+	}))
+	require.IsType(t, &object.List{}, listed)
+	items := listed.(*object.List).Value()
+	require.Len(t, items, 1)
+	require.Equal(t, object.NewString("symlink"), items[0].(*object.Map).Get("type"))
+}
+
+func TestListInvalidArchive(t *testing.T) {
+	ctx, root := testContext(t)
+	writeFiles(t, root, map[string]string{"bad.zip": "PK\x03\x04garbage"})
+	result := List(ctx, object.NewString("bad.zip"))
+	require.IsType(t, &object.Error{}, result)
+}
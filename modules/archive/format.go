@@ -0,0 +1,222 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+const (
+	formatTar   = "tar"
+	formatTarGz = "tar.gz"
+	formatZip   = "zip"
+)
+
+// Returns the canonical name of a format given as an option.
+func parseFormat(name string) (string, bool) {
+	switch strings.ToLower(name) {
+	case "tar":
+		return formatTar, true
+	case "tar.gz", "tgz":
+		return formatTarGz, true
+	case "zip":
+		return formatZip, true
+	}
+	return "", false
+}
+
+// Determines the format of an archive to be created from its file extension.
+func formatFromPath(path string) (string, bool) {
+	path = strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(path, ".tar.gz"), strings.HasSuffix(path, ".tgz"):
+		return formatTarGz, true
+	case strings.HasSuffix(path, ".tar"):
+		return formatTar, true
+	case strings.HasSuffix(path, ".zip"):
+		return formatZip, true
+	}
+	return "", false
+}
+
+// Determines the format of an existing archive from its contents.
+func formatFromData(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")), bytes.HasPrefix(data, []byte("PK\x05\x06")):
+		return formatZip
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		return formatTarGz
+	}
+	return formatTar
+}
+
+// An entry read from an archive
+type entry struct {
+	name    string
+	kind    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func modeKind(mode fs.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "dir"
+	case mode.IsRegular():
+		return "file"
+	case mode&fs.ModeSymlink != 0:
+		return "symlink"
+	}
+	return "other"
+}
+
+// Calls fn for each entry of the archive, with a reader for its contents.
+func walk(format string, data []byte, fn func(e *entry, r io.Reader) error) error {
+	if format == formatZip {
+		return walkZip(data, fn)
+	}
+	var r io.Reader = bytes.NewReader(data)
+	if format == formatTarGz {
+		gzreader, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gzreader.Close()
+		r = gzreader
+	}
+	return walkTar(r, fn)
+}
+
+func walkTar(r io.Reader, fn func(e *entry, r io.Reader) error) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// Global headers carry metadata for the whole archive, such as the
+		// commit ID written by git archive
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		info := hdr.FileInfo()
+		e := &entry{
+			name:    hdr.Name,
+			kind:    modeKind(info.Mode()),
+			size:    hdr.Size,
+			mode:    info.Mode().Perm(),
+			modTime: hdr.ModTime,
+		}
+		if hdr.Typeflag == tar.TypeLink {
+			e.kind = "link"
+		}
+		if err := fn(e, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func walkZip(data []byte, fn func(e *entry, r io.Reader) error) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		e := &entry{
+			name:    f.Name,
+			kind:    modeKind(f.Mode()),
+			size:    int64(f.UncompressedSize64),
+			mode:    f.Mode().Perm(),
+			modTime: f.Modified,
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = fn(e, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writer adds files and directories to an archive
+type writer interface {
+	add(name string, info fs.FileInfo, data []byte) error
+	close() error
+}
+
+func newWriter(format string, w io.Writer) writer {
+	switch format {
+	case formatZip:
+		return &zipWriter{zw: zip.NewWriter(w)}
+	case formatTarGz:
+		gzwriter := gzip.NewWriter(w)
+		return &tarWriter{tw: tar.NewWriter(gzwriter), gz: gzwriter}
+	}
+	return &tarWriter{tw: tar.NewWriter(w)}
+}
+
+type tarWriter struct {
+	tw *tar.Writer
+	gz *gzip.Writer
+}
+
+func (w *tarWriter) add(name string, info fs.FileInfo, data []byte) error {
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := w.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = w.tw.Write(data)
+	return err
+}
+
+func (w *tarWriter) close() error {
+	if err := w.tw.Close(); err != nil {
+		return err
+	}
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	return nil
+}
+
+type zipWriter struct {
+	zw *zip.Writer
+}
+
+func (w *zipWriter) add(name string, info fs.FileInfo, data []byte) error {
+	hdr, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if !info.IsDir() {
+		hdr.Method = zip.Deflate
+	}
+	fw, err := w.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(data)
+	return err
+}
+
+func (w *zipWriter) close() error {
+	return w.zw.Close()
+}
@@ -0,0 +1,328 @@
+package crypto
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"hash"
+
+	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/limits"
+	"github.com/itrn0/risor/object"
+)
+
+// The smallest RSA key size accepted by generate_key
+const minRSABits = 2048
+
+func asKey(funcName string, obj object.Object) (*Key, *object.Error) {
+	key, ok := obj.(*Key)
+	if !ok {
+		return nil, object.TypeErrorf("type error: %s() expected a key (%s given)", funcName, obj.Type())
+	}
+	return key, nil
+}
+
+// Returns the AEAD for AES-GCM with the given key, which must be 16, 24 or
+// 32 bytes long to select AES-128, AES-192 or AES-256.
+func newGCM(key []byte) (cipher.AEAD, *object.Error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, object.Errorf("value error: aes key must be 16, 24, or 32 bytes (%d given)", len(key))
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, object.NewError(err)
+	}
+	return gcm, nil
+}
+
+func HMAC(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("crypto.hmac", 2, 3, args); err != nil {
+		return err
+	}
+	key, err := object.AsBytes(args[0])
+	if err != nil {
+		return err
+	}
+	data, err := object.AsBytes(args[1])
+	if err != nil {
+		return err
+	}
+	alg := "sha256"
+	if len(args) == 3 {
+		if alg, err = object.AsString(args[2]); err != nil {
+			return err
+		}
+	}
+	var h func() hash.Hash
+	switch alg {
+	case "sha256":
+		h = sha256.New
+	case "sha512":
+		h = sha512.New
+	case "sha1":
+		h = sha1.New
+	case "md5":
+		h = md5.New
+	default:
+		return object.Errorf("value error: crypto.hmac() algorithm must be one of sha256, sha512, sha1, md5")
+	}
+	mac := hmac.New(h, key)
+	mac.Write(data)
+	return object.NewByteSlice(mac.Sum(nil))
+}
+
+func Equal(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("crypto.equal", 2, args); err != nil {
+		return err
+	}
+	a, err := object.AsBytes(args[0])
+	if err != nil {
+		return err
+	}
+	b, err := object.AsBytes(args[1])
+	if err != nil {
+		return err
+	}
+	return object.NewBool(subtle.ConstantTimeCompare(a, b) == 1)
+}
+
+func RandomBytes(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("crypto.random_bytes", 1, args); err != nil {
+		return err
+	}
+	n, err := object.AsInt(args[0])
+	if err != nil {
+		return err
+	}
+	if n < 0 {
+		return object.Errorf("value error: crypto.random_bytes() size must be >= 0 (%d given)", n)
+	}
+	if lim, ok := limits.GetLimits(ctx); ok && lim.MaxBufferSize() > 0 && n > lim.MaxBufferSize() {
+		return object.NewError(limits.NewLimitsError(
+			"limit error: random bytes exceeded limit of %d bytes (got %d)", lim.MaxBufferSize(), n))
+	}
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return object.NewError(err)
+	}
+	return object.NewByteSlice(buf)
+}
+
+func AESGCMSeal(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("crypto.aes_gcm_seal", 2, 3, args); err != nil {
+		return err
+	}
+	key, err := object.AsBytes(args[0])
+	if err != nil {
+		return err
+	}
+	plaintext, err := object.AsBytes(args[1])
+	if err != nil {
+		return err
+	}
+	var additional []byte
+	if len(args) == 3 {
+		if additional, err = object.AsBytes(args[2]); err != nil {
+			return err
+		}
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	// A random nonce is generated and prepended to the ciphertext
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return object.NewError(err)
+	}
+	return object.NewByteSlice(gcm.Seal(nonce, nonce, plaintext, additional))
+}
+
+func AESGCMOpen(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("crypto.aes_gcm_open", 2, 3, args); err != nil {
+		return err
+	}
+	key, err := object.AsBytes(args[0])
+	if err != nil {
+		return err
+	}
+	sealed, err := object.AsBytes(args[1])
+	if err != nil {
+		return err
+	}
+	var additional []byte
+	if len(args) == 3 {
+		if additional, err = object.AsBytes(args[2]); err != nil {
+			return err
+		}
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	if len(sealed) < gcm.NonceSize()+gcm.Overhead() {
+		return object.Errorf("value error: crypto.aes_gcm_open() ciphertext is too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, openErr := gcm.Open(nil, nonce, ciphertext, additional)
+	if openErr != nil {
+		return object.Errorf("value error: crypto.aes_gcm_open() failed to decrypt or authenticate the ciphertext")
+	}
+	return object.NewByteSlice(plaintext)
+}
+
+func GenerateKey(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("crypto.generate_key", 1, 2, args); err != nil {
+		return err
+	}
+	alg, err := object.AsString(args[0])
+	if err != nil {
+		return err
+	}
+	switch alg {
+	case "ed25519":
+		if len(args) == 2 {
+			return object.Errorf("value error: crypto.generate_key() takes no options for ed25519 keys")
+		}
+		_, private, genErr := ed25519.GenerateKey(rand.Reader)
+		if genErr != nil {
+			return object.NewError(genErr)
+		}
+		return &Key{private: private, public: private.Public()}
+	case "ecdsa":
+		curveName := "p256"
+		if len(args) == 2 {
+			if curveName, err = object.AsString(args[1]); err != nil {
+				return err
+			}
+		}
+		var curve elliptic.Curve
+		switch curveName {
+		case "p256":
+			curve = elliptic.P256()
+		case "p384":
+			curve = elliptic.P384()
+		case "p521":
+			curve = elliptic.P521()
+		default:
+			return object.Errorf("value error: crypto.generate_key() curve must be one of p256, p384, p521")
+		}
+		private, genErr := ecdsa.GenerateKey(curve, rand.Reader)
+		if genErr != nil {
+			return object.NewError(genErr)
+		}
+		return &Key{private: private, public: private.Public()}
+	case "rsa":
+		bits := int64(minRSABits)
+		if len(args) == 2 {
+			if bits, err = object.AsInt(args[1]); err != nil {
+				return err
+			}
+		}
+		if bits < minRSABits || bits > 8192 {
+			return object.Errorf("value error: crypto.generate_key() rsa key size must be between %d and 8192 bits (%d given)", minRSABits, bits)
+		}
+		private, genErr := rsa.GenerateKey(rand.Reader, int(bits))
+		if genErr != nil {
+			return object.NewError(genErr)
+		}
+		return &Key{private: private, public: private.Public()}
+	default:
+		return object.Errorf("value error: crypto.generate_key() algorithm must be one of ed25519, ecdsa, rsa")
+	}
+}
+
+func Sign(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("crypto.sign", 2, args); err != nil {
+		return err
+	}
+	key, err := asKey("crypto.sign", args[0])
+	if err != nil {
+		return err
+	}
+	data, err := object.AsBytes(args[1])
+	if err != nil {
+		return err
+	}
+	signature, signErr := key.Sign(data)
+	if signErr != nil {
+		return object.NewError(signErr)
+	}
+	return object.NewByteSlice(signature)
+}
+
+func Verify(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("crypto.verify", 3, args); err != nil {
+		return err
+	}
+	key, err := asKey("crypto.verify", args[0])
+	if err != nil {
+		return err
+	}
+	data, err := object.AsBytes(args[1])
+	if err != nil {
+		return err
+	}
+	signature, err := object.AsBytes(args[2])
+	if err != nil {
+		return err
+	}
+	return object.NewBool(key.Verify(data, signature))
+}
+
+func ExportPEM(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("crypto.export_pem", 1, args); err != nil {
+		return err
+	}
+	key, err := asKey("crypto.export_pem", args[0])
+	if err != nil {
+		return err
+	}
+	data, pemErr := key.PEM()
+	if pemErr != nil {
+		return object.NewError(pemErr)
+	}
+	return object.NewString(string(data))
+}
+
+func ImportPEM(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("crypto.import_pem", 1, args); err != nil {
+		return err
+	}
+	data, err := object.AsBytes(args[0])
+	if err != nil {
+		return err
+	}
+	key, pemErr := parsePEM(data)
+	if pemErr != nil {
+		return object.NewError(pemErr)
+	}
+	return key
+}
+
+func Module() *object.Module {
+	return object.NewBuiltinsModule("crypto", map[string]object.Object{
+		"aes_gcm_open": object.NewBuiltin("crypto.aes_gcm_open", AESGCMOpen),
+		"aes_gcm_seal": object.NewBuiltin("crypto.aes_gcm_seal", AESGCMSeal),
+		"equal":        object.NewBuiltin("crypto.equal", Equal),
+		"export_pem":   object.NewBuiltin("crypto.export_pem", ExportPEM),
+		"generate_key": object.NewBuiltin("crypto.generate_key", GenerateKey),
+		"hmac":         object.NewBuiltin("crypto.hmac", HMAC),
+		"import_pem":   object.NewBuiltin("crypto.import_pem", ImportPEM),
+		"random_bytes": object.NewBuiltin("crypto.random_bytes", RandomBytes),
+		"sign":         object.NewBuiltin("crypto.sign", Sign),
+		"verify":       object.NewBuiltin("crypto.verify", Verify),
+	})
+}
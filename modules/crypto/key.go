@@ -0,0 +1,298 @@
+package crypto
+
+import (
+	"context"
+	gocrypto "crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/itrn0/risor/errz"
+	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/op"
+)
+
+const (
+	PRIVATE_KEY object.Type = "crypto.private_key"
+	PUBLIC_KEY  object.Type = "crypto.public_key"
+)
+
+// Key is an Ed25519, ECDSA or RSA key. A private key is also able to provide
+// its public key, so a Key holding a private key can both sign and verify.
+type Key struct {
+	private gocrypto.Signer
+	public  gocrypto.PublicKey
+}
+
+func (k *Key) Type() object.Type {
+	if k.private != nil {
+		return PRIVATE_KEY
+	}
+	return PUBLIC_KEY
+}
+
+func (k *Key) Inspect() string {
+	return fmt.Sprintf("%s(%s)", k.Type(), k.Algorithm())
+}
+
+func (k *Key) String() string {
+	return k.Inspect()
+}
+
+// Algorithm returns the name of the key algorithm, e.g. "ecdsa-p256".
+func (k *Key) Algorithm() string {
+	switch key := k.public.(type) {
+	case ed25519.PublicKey:
+		return "ed25519"
+	case *ecdsa.PublicKey:
+		switch key.Curve.Params().BitSize {
+		case 384:
+			return "ecdsa-p384"
+		case 521:
+			return "ecdsa-p521"
+		default:
+			return "ecdsa-p256"
+		}
+	case *rsa.PublicKey:
+		return fmt.Sprintf("rsa-%d", key.N.BitLen())
+	default:
+		return "unknown"
+	}
+}
+
+// IsPrivate returns true if this is a private key.
+func (k *Key) IsPrivate() bool {
+	return k.private != nil
+}
+
+// PublicKey returns the public half of this key.
+func (k *Key) PublicKey() *Key {
+	if k.private == nil {
+		return k
+	}
+	return &Key{public: k.public}
+}
+
+func (k *Key) Interface() interface{} {
+	if k.private != nil {
+		return k.private
+	}
+	return k.public
+}
+
+func (k *Key) IsTruthy() bool {
+	return true
+}
+
+func (k *Key) Cost() int {
+	return 8
+}
+
+func (k *Key) MarshalJSON() ([]byte, error) {
+	return nil, errz.TypeErrorf("type error: unable to marshal %s", k.Type())
+}
+
+func (k *Key) RunOperation(opType op.BinaryOpType, right object.Object) object.Object {
+	return object.TypeErrorf("type error: unsupported operation for %s: %v", k.Type(), opType)
+}
+
+func (k *Key) Equals(other object.Object) object.Object {
+	otherKey, ok := other.(*Key)
+	if !ok || k.IsPrivate() != otherKey.IsPrivate() {
+		return object.False
+	}
+	// All of the standard library key types implement Equal
+	if k.private != nil {
+		key, ok := k.private.(interface {
+			Equal(gocrypto.PrivateKey) bool
+		})
+		return object.NewBool(ok && key.Equal(otherKey.private))
+	}
+	key, ok := k.public.(interface{ Equal(gocrypto.PublicKey) bool })
+	return object.NewBool(ok && key.Equal(otherKey.public))
+}
+
+func (k *Key) SetAttr(name string, value object.Object) error {
+	return object.TypeErrorf("type error: %s object has no attribute %q", k.Type(), name)
+}
+
+func (k *Key) GetAttr(name string) (object.Object, bool) {
+	switch name {
+	case "algorithm":
+		return object.NewString(k.Algorithm()), true
+	case "is_private":
+		return object.NewBool(k.IsPrivate()), true
+	case "public_key":
+		return object.NewBuiltin("crypto.key.public_key",
+			func(ctx context.Context, args ...object.Object) object.Object {
+				if len(args) != 0 {
+					return object.NewArgsError("crypto.key.public_key", 0, len(args))
+				}
+				return k.PublicKey()
+			}), true
+	case "sign":
+		return object.NewBuiltin("crypto.key.sign",
+			func(ctx context.Context, args ...object.Object) object.Object {
+				return Sign(ctx, append([]object.Object{k}, args...)...)
+			}), true
+	case "verify":
+		return object.NewBuiltin("crypto.key.verify",
+			func(ctx context.Context, args ...object.Object) object.Object {
+				return Verify(ctx, append([]object.Object{k}, args...)...)
+			}), true
+	case "to_pem":
+		return object.NewBuiltin("crypto.key.to_pem",
+			func(ctx context.Context, args ...object.Object) object.Object {
+				return ExportPEM(ctx, append([]object.Object{k}, args...)...)
+			}), true
+	}
+	return nil, false
+}
+
+// Returns the hash used when signing with the key. Ed25519 signs the message
+// itself, while ECDSA and RSA sign a digest of it.
+func (k *Key) hash() gocrypto.Hash {
+	switch key := k.public.(type) {
+	case ed25519.PublicKey:
+		return 0
+	case *ecdsa.PublicKey:
+		switch key.Curve.Params().BitSize {
+		case 384:
+			return gocrypto.SHA384
+		case 521:
+			return gocrypto.SHA512
+		}
+	}
+	return gocrypto.SHA256
+}
+
+func digest(hash gocrypto.Hash, data []byte) []byte {
+	switch hash {
+	case gocrypto.SHA384:
+		sum := sha512.Sum384(data)
+		return sum[:]
+	case gocrypto.SHA512:
+		sum := sha512.Sum512(data)
+		return sum[:]
+	case gocrypto.SHA256:
+		sum := sha256.Sum256(data)
+		return sum[:]
+	default:
+		return data
+	}
+}
+
+// Sign returns a signature of the data. RSA keys sign using PKCS #1 v1.5 and
+// ECDSA keys produce an ASN.1 encoded signature.
+func (k *Key) Sign(data []byte) ([]byte, error) {
+	if k.private == nil {
+		return nil, errors.New("value error: a private key is required to sign")
+	}
+	hash := k.hash()
+	return k.private.Sign(rand.Reader, digest(hash, data), hash)
+}
+
+// Verify returns true if the signature of the data is valid for this key.
+func (k *Key) Verify(data, signature []byte) bool {
+	hash := k.hash()
+	switch key := k.public.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, data, signature)
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest(hash, data), signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, hash, digest(hash, data), signature) == nil
+	default:
+		return false
+	}
+}
+
+// PEM returns the key PEM encoded, with private keys in PKCS #8 form and
+// public keys in PKIX form.
+func (k *Key) PEM() ([]byte, error) {
+	if k.private != nil {
+		der, err := x509.MarshalPKCS8PrivateKey(k.private)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+	}
+	der, err := x509.MarshalPKIXPublicKey(k.public)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// NewPrivateKey wraps an Ed25519, ECDSA or RSA private key.
+func NewPrivateKey(key gocrypto.Signer) (*Key, error) {
+	switch key.(type) {
+	case ed25519.PrivateKey, *ecdsa.PrivateKey, *rsa.PrivateKey:
+		return &Key{private: key, public: key.Public()}, nil
+	default:
+		return nil, fmt.Errorf("type error: unsupported private key type %T", key)
+	}
+}
+
+// NewPublicKey wraps an Ed25519, ECDSA or RSA public key.
+func NewPublicKey(key gocrypto.PublicKey) (*Key, error) {
+	switch key.(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey, *rsa.PublicKey:
+		return &Key{public: key}, nil
+	default:
+		return nil, fmt.Errorf("type error: unsupported public key type %T", key)
+	}
+}
+
+// Parses the first PEM block in the data as a private or public key.
+func parsePEM(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("value error: no PEM data found")
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(gocrypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("type error: unsupported private key type %T", key)
+		}
+		return NewPrivateKey(signer)
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewPrivateKey(key)
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewPrivateKey(key)
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewPublicKey(key)
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewPublicKey(key)
+	default:
+		return nil, fmt.Errorf("value error: unsupported PEM block type %q", block.Type)
+	}
+}
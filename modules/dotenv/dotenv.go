@@ -0,0 +1,338 @@
+package dotenv
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/builtins"
+	"github.com/itrn0/risor/object"
+)
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || c == '.' || (c >= '0' && c <= '9')
+}
+
+// parser reads the variables of a dotenv file. References to variables, as
+// $NAME or ${NAME}, are replaced with the values of variables defined earlier
+// in the same file. The process environment is never consulted.
+type parser struct {
+	data string
+	pos  int
+	vars map[string]string
+}
+
+func (p *parser) peek() byte {
+	if p.pos < len(p.data) {
+		return p.data[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) line() int {
+	return strings.Count(p.data[:p.pos], "\n") + 1
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("dotenv: line %d: %s", p.line(), fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpaces() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.pos++
+	}
+}
+
+func (p *parser) skipLine() {
+	for p.pos < len(p.data) && p.data[p.pos] != '\n' {
+		p.pos++
+	}
+}
+
+func (p *parser) name() string {
+	start := p.pos
+	if !isNameStart(p.peek()) {
+		return ""
+	}
+	for isNameChar(p.peek()) {
+		p.pos++
+	}
+	return p.data[start:p.pos]
+}
+
+// Expands the variable reference starting at the current position, which is
+// just after a "$". A "$" that doesn't start a reference is kept.
+func (p *parser) expand(sb *strings.Builder) error {
+	if p.peek() == '{' {
+		p.pos++
+		name := p.name()
+		if name == "" || p.peek() != '}' {
+			return p.errorf("invalid variable reference")
+		}
+		p.pos++
+		sb.WriteString(p.vars[name])
+		return nil
+	}
+	if name := p.name(); name != "" {
+		sb.WriteString(p.vars[name])
+	} else {
+		sb.WriteByte('$')
+	}
+	return nil
+}
+
+// Reads an unquoted value, which ends at the end of the line or at a comment
+// preceded by whitespace.
+func (p *parser) unquoted() (string, error) {
+	var sb strings.Builder
+	for p.pos < len(p.data) && p.data[p.pos] != '\n' {
+		c := p.data[p.pos]
+		if c == '#' && (p.data[p.pos-1] == ' ' || p.data[p.pos-1] == '\t') {
+			p.skipLine()
+			break
+		}
+		p.pos++
+		if c == '$' {
+			if err := p.expand(&sb); err != nil {
+				return "", err
+			}
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// Reads a quoted value, which may span multiple lines. Single quoted values
+// are taken literally. Double quoted values may contain variable references
+// and the escape sequences \n, \r, \t, \", \$ and \\.
+func (p *parser) quoted() (string, error) {
+	quote := p.data[p.pos]
+	start := p.line()
+	p.pos++
+	var sb strings.Builder
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch {
+		case c == quote:
+			return sb.String(), nil
+		case quote == '\'':
+			sb.WriteByte(c)
+		case c == '\\' && p.pos < len(p.data):
+			escaped := p.data[p.pos]
+			p.pos++
+			switch escaped {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case '"', '$', '\\':
+				sb.WriteByte(escaped)
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(escaped)
+			}
+		case c == '$':
+			if err := p.expand(&sb); err != nil {
+				return "", err
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("dotenv: line %d: unterminated quoted value", start)
+}
+
+func (p *parser) parse() (*object.Map, error) {
+	result := object.NewMap(map[string]object.Object{})
+	for {
+		// Skip blank lines and comments
+		for p.pos < len(p.data) && strings.IndexByte(" \t\r\n", p.data[p.pos]) >= 0 {
+			p.pos++
+		}
+		if p.pos >= len(p.data) {
+			return result, nil
+		}
+		if p.peek() == '#' {
+			p.skipLine()
+			continue
+		}
+		if strings.HasPrefix(p.data[p.pos:], "export ") || strings.HasPrefix(p.data[p.pos:], "export\t") {
+			p.pos += len("export")
+			p.skipSpaces()
+		}
+		key := p.name()
+		if key == "" {
+			return nil, p.errorf("invalid variable name")
+		}
+		p.skipSpaces()
+		if p.peek() != '=' {
+			return nil, p.errorf("expected = after %s", key)
+		}
+		p.pos++
+		p.skipSpaces()
+		var value string
+		var err error
+		if c := p.peek(); c == '"' || c == '\'' {
+			if value, err = p.quoted(); err != nil {
+				return nil, err
+			}
+			p.skipSpaces()
+			switch p.peek() {
+			case '#':
+				p.skipLine()
+			case '\r', '\n', 0:
+			default:
+				return nil, p.errorf("unexpected characters after quoted value")
+			}
+		} else if value, err = p.unquoted(); err != nil {
+			return nil, err
+		}
+		p.vars[key] = value
+		result.Set(key, object.NewString(value))
+	}
+}
+
+// Decodes a dotenv file to a map of strings.
+func unmarshal(data []byte) (object.Object, error) {
+	p := &parser{data: string(data), vars: map[string]string{}}
+	result, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Returns the text for a scalar value.
+func scalarText(obj object.Object) (string, bool) {
+	switch obj := obj.(type) {
+	case *object.String:
+		return obj.Value(), true
+	case *object.Int, *object.Float, *object.Bool:
+		return obj.Inspect(), true
+	case *object.Time:
+		return obj.Value().Format(time.RFC3339), true
+	case *object.NilType:
+		return "", true
+	default:
+		return "", false
+	}
+}
+
+// Double quotes a value unless it consists only of characters that are read
+// back unchanged when unquoted.
+func formatValue(value string) string {
+	safe := true
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if !isNameChar(c) && strings.IndexByte("-/:@%+,=", c) < 0 {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return value
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+func isValidName(name string) bool {
+	if name == "" || !isNameStart(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if !isNameChar(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Encodes a map of scalar values as a dotenv file, sorted by name.
+func marshal(funcName string, obj object.Object) ([]byte, *object.Error) {
+	m, ok := obj.(*object.Map)
+	if !ok {
+		return nil, object.TypeErrorf("type error: %s() expected a map (%s given)", funcName, obj.Type())
+	}
+	var buf bytes.Buffer
+	for _, name := range m.SortedKeys() {
+		if !isValidName(name) {
+			return nil, object.Errorf("value error: %s() invalid variable name %q", funcName, name)
+		}
+		value := m.Get(name)
+		text, ok := scalarText(value)
+		if !ok {
+			return nil, object.TypeErrorf("type error: %s() can't encode %s values", funcName, value.Type())
+		}
+		fmt.Fprintf(&buf, "%s=%s\n", name, formatValue(text))
+	}
+	return buf.Bytes(), nil
+}
+
+func Unmarshal(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("dotenv.unmarshal", 1, args); err != nil {
+		return err
+	}
+	data, err := object.AsBytes(args[0])
+	if err != nil {
+		return err
+	}
+	result, decodeErr := unmarshal(data)
+	if decodeErr != nil {
+		return object.Errorf("value error: dotenv.unmarshal failed with: %s", decodeErr.Error())
+	}
+	return result
+}
+
+func Marshal(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("dotenv.marshal", 1, args); err != nil {
+		return err
+	}
+	data, err := marshal("dotenv.marshal", args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewString(string(data))
+}
+
+func encodeDotenv(ctx context.Context, obj object.Object) object.Object {
+	data, err := marshal("encode", obj)
+	if err != nil {
+		return err
+	}
+	return object.NewString(string(data))
+}
+
+func decodeDotenv(ctx context.Context, obj object.Object) object.Object {
+	data, err := object.AsBytes(obj)
+	if err != nil {
+		return err
+	}
+	result, decodeErr := unmarshal(data)
+	if decodeErr != nil {
+		return object.NewError(decodeErr)
+	}
+	return result
+}
+
+func init() {
+	builtins.RegisterCodec("dotenv", &builtins.Codec{Encode: encodeDotenv, Decode: decodeDotenv})
+}
+
+func Module() *object.Module {
+	return object.NewBuiltinsModule("dotenv", map[string]object.Object{
+		"marshal":   object.NewBuiltin("dotenv.marshal", Marshal),
+		"unmarshal": object.NewBuiltin("dotenv.unmarshal", Unmarshal),
+	})
+}
@@ -0,0 +1,92 @@
+package dotenv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/itrn0/risor/builtins"
+	"github.com/itrn0/risor/object"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshal(t *testing.T) {
+	ctx := context.Background()
+	result := Unmarshal(ctx, object.NewString(`# database settings
+DB_HOST=db.local
+export DB_PORT = 5432   # default port
+DB_URL=postgres://${DB_HOST}:$DB_PORT/app
+EMPTY=
+COLOR=#ff0000
+SINGLE='literal $DB_HOST\n'
+DOUBLE="line one\nline \"two\" costs \$5 on $DB_HOST"
+MULTI="first
+second"
+UNDEFINED=${MISSING}x
+`))
+	require.Equal(t, object.NewMap(map[string]object.Object{
+		"DB_HOST":   object.NewString("db.local"),
+		"DB_PORT":   object.NewString("5432"),
+		"DB_URL":    object.NewString("postgres://db.local:5432/app"),
+		"EMPTY":     object.NewString(""),
+		"COLOR":     object.NewString("#ff0000"),
+		"SINGLE":    object.NewString(`literal $DB_HOST\n`),
+		"DOUBLE":    object.NewString("line one\nline \"two\" costs $5 on db.local"),
+		"MULTI":     object.NewString("first\nsecond"),
+		"UNDEFINED": object.NewString("x"),
+	}), result)
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		input  string
+		errMsg string
+	}{
+		{"1A=b", "dotenv: line 1: invalid variable name"},
+		{"\nA b", "dotenv: line 2: expected = after A"},
+		{"A=\"open\nB=1", "dotenv: line 1: unterminated quoted value"},
+		{"A='x' y", "dotenv: line 1: unexpected characters after quoted value"},
+		{"A=${B", "dotenv: line 1: invalid variable reference"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result := Unmarshal(ctx, object.NewString(tt.input))
+			require.Equal(t, object.Errorf("value error: dotenv.unmarshal failed with: %s", tt.errMsg), result)
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	ctx := context.Background()
+	value := object.NewMap(map[string]object.Object{
+		"PORT":     object.NewInt(8080),
+		"DEBUG":    object.True,
+		"URL":      object.NewString("https://example.com/a?b=c"),
+		"GREETING": object.NewString("hello \"world\"\n$HOME"),
+		"EMPTY":    object.Nil,
+	})
+	result := Marshal(ctx, value)
+	require.Equal(t, object.NewString(`DEBUG=true
+EMPTY=
+GREETING="hello \"world\"\n\$HOME"
+PORT=8080
+URL="https://example.com/a?b=c"
+`), result)
+
+	decoded := Unmarshal(ctx, result).(*object.Map)
+	require.Equal(t, object.NewString("hello \"world\"\n$HOME"), decoded.Get("GREETING"))
+	require.Equal(t, object.NewString("https://example.com/a?b=c"), decoded.Get("URL"))
+
+	require.Equal(t, object.Errorf(`value error: dotenv.marshal() invalid variable name "MY-VAR"`),
+		Marshal(ctx, object.NewMap(map[string]object.Object{"MY-VAR": object.NewInt(1)})))
+	require.Equal(t, object.TypeErrorf("type error: dotenv.marshal() can't encode map values"),
+		Marshal(ctx, object.NewMap(map[string]object.Object{"A": object.NewMap(nil)})))
+}
+
+func TestCodec(t *testing.T) {
+	ctx := context.Background()
+	value := object.NewMap(map[string]object.Object{"A": object.NewString("1"), "B": object.NewString("two words")})
+	encoded := builtins.Encode(ctx, value, object.NewString("dotenv"))
+	require.Equal(t, object.NewString("A=1\nB=\"two words\"\n"), encoded)
+	require.Equal(t, value, builtins.Decode(ctx, encoded, object.NewString("dotenv")))
+}
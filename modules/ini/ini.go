@@ -0,0 +1,264 @@
+package ini
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/builtins"
+	"github.com/itrn0/risor/object"
+)
+
+// Removes a comment that follows an unquoted value. The comment character
+// must be preceded by whitespace so that values such as URLs with fragments
+// are kept intact.
+func stripComment(value string) string {
+	for i := 1; i < len(value); i++ {
+		if (value[i] == ';' || value[i] == '#') && (value[i-1] == ' ' || value[i-1] == '\t') {
+			return strings.TrimSpace(value[:i])
+		}
+	}
+	return value
+}
+
+// Parses a value, which may be quoted. Double quoted values may contain the
+// escape sequences \\, \", \n, \r and \t, while single quoted values are taken
+// literally.
+func parseValue(value string) (string, error) {
+	if len(value) == 0 || (value[0] != '"' && value[0] != '\'') {
+		return stripComment(value), nil
+	}
+	quote := value[0]
+	var sb strings.Builder
+	for i := 1; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == quote:
+			if rest := strings.TrimSpace(value[i+1:]); rest != "" && rest[0] != ';' && rest[0] != '#' {
+				return "", fmt.Errorf("unexpected %q after quoted value", rest)
+			}
+			return sb.String(), nil
+		case c == '\\' && quote == '"' && i+1 < len(value):
+			i++
+			switch value[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteByte(value[i])
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated quoted value")
+}
+
+// Decodes an INI document to a map. Keys that appear before the first section
+// are stored at the top level of the map, and each section is stored as a
+// nested map. All values are strings.
+func unmarshal(data []byte) (object.Object, error) {
+	result := object.NewMap(map[string]object.Object{})
+	current := result
+	for i, line := range strings.Split(string(data), "\n") {
+		lineNum := i + 1
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			end := strings.IndexByte(line, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("ini: line %d: unterminated section header", lineNum)
+			}
+			if rest := strings.TrimSpace(line[end+1:]); rest != "" && rest[0] != ';' && rest[0] != '#' {
+				return nil, fmt.Errorf("ini: line %d: unexpected %q after section header", lineNum, rest)
+			}
+			name := strings.TrimSpace(line[1:end])
+			if name == "" {
+				return nil, fmt.Errorf("ini: line %d: empty section name", lineNum)
+			}
+			// Sections with the same name are merged
+			switch existing := result.Get(name).(type) {
+			case *object.Map:
+				current = existing
+			case *object.NilType:
+				current = object.NewMap(map[string]object.Object{})
+				result.Set(name, current)
+			default:
+				return nil, fmt.Errorf("ini: line %d: section %q has the same name as a key", lineNum, name)
+			}
+			continue
+		}
+		sep := strings.IndexAny(line, "=:")
+		if sep < 0 {
+			return nil, fmt.Errorf("ini: line %d: expected a key and value separated by = or :", lineNum)
+		}
+		key := strings.TrimSpace(line[:sep])
+		if key == "" {
+			return nil, fmt.Errorf("ini: line %d: missing key", lineNum)
+		}
+		value, err := parseValue(strings.TrimSpace(line[sep+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("ini: line %d: %s", lineNum, err)
+		}
+		current.Set(key, object.NewString(value))
+	}
+	return result, nil
+}
+
+// Returns the text for a scalar value.
+func scalarText(obj object.Object) (string, bool) {
+	switch obj := obj.(type) {
+	case *object.String:
+		return obj.Value(), true
+	case *object.Int, *object.Float, *object.Bool:
+		return obj.Inspect(), true
+	case *object.Time:
+		return obj.Value().Format(time.RFC3339), true
+	case *object.NilType:
+		return "", true
+	default:
+		return "", false
+	}
+}
+
+// Quotes a value if it would otherwise not be read back unchanged.
+func formatValue(value string) string {
+	if strings.TrimSpace(value) == value && !strings.ContainsAny(value, ";#\"'\\\n\r\t") {
+		return value
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+func isValidKey(key string) bool {
+	return key != "" &&
+		strings.TrimSpace(key) == key &&
+		!strings.ContainsAny(key, "=:[]\n\r") &&
+		key[0] != ';' && key[0] != '#'
+}
+
+type encoder struct {
+	funcName string
+	buf      bytes.Buffer
+}
+
+// Writes the scalar entries of the map in key order.
+func (e *encoder) entries(m *object.Map, section string) *object.Error {
+	for _, key := range m.SortedKeys() {
+		value := m.Get(key)
+		if _, ok := value.(*object.Map); ok {
+			if section != "" {
+				return object.Errorf("value error: %s() section %q contains a nested map", e.funcName, section)
+			}
+			continue
+		}
+		if !isValidKey(key) {
+			return object.Errorf("value error: %s() invalid key %q", e.funcName, key)
+		}
+		text, ok := scalarText(value)
+		if !ok {
+			return object.TypeErrorf("type error: %s() can't encode %s values", e.funcName, value.Type())
+		}
+		if text == "" {
+			fmt.Fprintf(&e.buf, "%s =\n", key)
+		} else {
+			fmt.Fprintf(&e.buf, "%s = %s\n", key, formatValue(text))
+		}
+	}
+	return nil
+}
+
+// Encodes a map as an INI document. Nested maps are written as sections,
+// after the other values of the map.
+func marshal(funcName string, obj object.Object) ([]byte, *object.Error) {
+	m, ok := obj.(*object.Map)
+	if !ok {
+		return nil, object.TypeErrorf("type error: %s() expected a map (%s given)", funcName, obj.Type())
+	}
+	e := &encoder{funcName: funcName}
+	if err := e.entries(m, ""); err != nil {
+		return nil, err
+	}
+	for _, name := range m.SortedKeys() {
+		section, ok := m.Get(name).(*object.Map)
+		if !ok {
+			continue
+		}
+		if name == "" || strings.TrimSpace(name) != name || strings.ContainsAny(name, "]\n\r") {
+			return nil, object.Errorf("value error: %s() invalid section name %q", funcName, name)
+		}
+		if e.buf.Len() > 0 {
+			e.buf.WriteByte('\n')
+		}
+		fmt.Fprintf(&e.buf, "[%s]\n", name)
+		if err := e.entries(section, name); err != nil {
+			return nil, err
+		}
+	}
+	return e.buf.Bytes(), nil
+}
+
+func Unmarshal(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("ini.unmarshal", 1, args); err != nil {
+		return err
+	}
+	data, err := object.AsBytes(args[0])
+	if err != nil {
+		return err
+	}
+	result, decodeErr := unmarshal(data)
+	if decodeErr != nil {
+		return object.Errorf("value error: ini.unmarshal failed with: %s", decodeErr.Error())
+	}
+	return result
+}
+
+func Marshal(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("ini.marshal", 1, args); err != nil {
+		return err
+	}
+	data, err := marshal("ini.marshal", args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewString(string(data))
+}
+
+func encodeINI(ctx context.Context, obj object.Object) object.Object {
+	data, err := marshal("encode", obj)
+	if err != nil {
+		return err
+	}
+	return object.NewString(string(data))
+}
+
+func decodeINI(ctx context.Context, obj object.Object) object.Object {
+	data, err := object.AsBytes(obj)
+	if err != nil {
+		return err
+	}
+	result, decodeErr := unmarshal(data)
+	if decodeErr != nil {
+		return object.NewError(decodeErr)
+	}
+	return result
+}
+
+func init() {
+	builtins.RegisterCodec("ini", &builtins.Codec{Encode: encodeINI, Decode: decodeINI})
+}
+
+func Module() *object.Module {
+	return object.NewBuiltinsModule("ini", map[string]object.Object{
+		"marshal":   object.NewBuiltin("ini.marshal", Marshal),
+		"unmarshal": object.NewBuiltin("ini.unmarshal", Unmarshal),
+	})
+}
@@ -0,0 +1,143 @@
+package ini
+
+import (
+	"context"
+	"testing"
+
+	"github.com/itrn0/risor/builtins"
+	"github.com/itrn0/risor/object"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshal(t *testing.T) {
+	ctx := context.Background()
+	result := Unmarshal(ctx, object.NewString(`; global settings
+name = example
+debug: true
+
+[database]
+host = db.local   ; the primary
+url = http://db.local/#status
+password = "p;ss \"word\""
+literal = 'a\nb'
+empty =
+
+# sections with the same name are merged
+[server]
+port = 8080
+[database]
+port = 5432
+`))
+	require.Equal(t, object.NewMap(map[string]object.Object{
+		"name":  object.NewString("example"),
+		"debug": object.NewString("true"),
+		"database": object.NewMap(map[string]object.Object{
+			"host":     object.NewString("db.local"),
+			"url":      object.NewString("http://db.local/#status"),
+			"password": object.NewString(`p;ss "word"`),
+			"literal":  object.NewString(`a\nb`),
+			"empty":    object.NewString(""),
+			"port":     object.NewString("5432"),
+		}),
+		"server": object.NewMap(map[string]object.Object{
+			"port": object.NewString("8080"),
+		}),
+	}), result)
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		input  string
+		errMsg string
+	}{
+		{"[db", "ini: line 1: unterminated section header"},
+		{"\n[]", "ini: line 2: empty section name"},
+		{"[db] x", `ini: line 1: unexpected "x" after section header`},
+		{"key", "ini: line 1: expected a key and value separated by = or :"},
+		{"= value", "ini: line 1: missing key"},
+		{`a = "open`, "ini: line 1: unterminated quoted value"},
+		{`a = "x" y`, `ini: line 1: unexpected "y" after quoted value`},
+		{"db = 1\n[db]", `ini: line 2: section "db" has the same name as a key`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result := Unmarshal(ctx, object.NewString(tt.input))
+			require.Equal(t, object.Errorf("value error: ini.unmarshal failed with: %s", tt.errMsg), result)
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	ctx := context.Background()
+	value := object.NewMap(map[string]object.Object{
+		"name":    object.NewString("example"),
+		"retries": object.NewInt(3),
+		"server": object.NewMap(map[string]object.Object{
+			"port":  object.NewInt(8080),
+			"debug": object.False,
+		}),
+		"database": object.NewMap(map[string]object.Object{
+			"password": object.NewString(` p;ss "word"`),
+			"host":     object.NewString("db.local"),
+			"empty":    object.Nil,
+		}),
+	})
+	result := Marshal(ctx, value)
+	require.Equal(t, object.NewString(`name = example
+retries = 3
+
+[database]
+empty =
+host = db.local
+password = " p;ss \"word\""
+
+[server]
+debug = false
+port = 8080
+`), result)
+
+	decoded := Unmarshal(ctx, result).(*object.Map)
+	require.Equal(t, object.NewString(` p;ss "word"`), decoded.Get("database").(*object.Map).Get("password"))
+	require.Equal(t, object.NewString("3"), decoded.Get("retries"))
+}
+
+func TestMarshalErrors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		input    object.Object
+		expected object.Object
+	}{
+		{
+			object.NewString("a"),
+			object.TypeErrorf("type error: ini.marshal() expected a map (string given)"),
+		},
+		{
+			object.NewMap(map[string]object.Object{"s": object.NewMap(map[string]object.Object{
+				"nested": object.NewMap(map[string]object.Object{}),
+			})}),
+			object.Errorf(`value error: ini.marshal() section "s" contains a nested map`),
+		},
+		{
+			object.NewMap(map[string]object.Object{"a=b": object.NewInt(1)}),
+			object.Errorf(`value error: ini.marshal() invalid key "a=b"`),
+		},
+		{
+			object.NewMap(map[string]object.Object{"a": object.NewList(nil)}),
+			object.TypeErrorf("type error: ini.marshal() can't encode list values"),
+		},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, Marshal(ctx, tt.input))
+	}
+}
+
+func TestCodec(t *testing.T) {
+	ctx := context.Background()
+	value := object.NewMap(map[string]object.Object{
+		"s": object.NewMap(map[string]object.Object{"a": object.NewString("1")}),
+	})
+	encoded := builtins.Encode(ctx, value, object.NewString("ini"))
+	require.Equal(t, object.NewString("[s]\na = 1\n"), encoded)
+	require.Equal(t, value, builtins.Decode(ctx, encoded, object.NewString("ini")))
+}
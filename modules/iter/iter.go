@@ -0,0 +1,364 @@
+package iter
+
+import (
+	"context"
+
+	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/object"
+)
+
+// Returns the next item from the iterator. An iterator that stopped because of
+// an error, such as a generator that raised one, returns that error.
+func nextItem(ctx context.Context, iter object.Iterator) (object.Object, bool, error) {
+	item, ok := iter.Next(ctx)
+	if !ok {
+		if iter, ok := iter.(object.FallibleIterator); ok {
+			return nil, false, iter.Err()
+		}
+		return nil, false, nil
+	}
+	return item, true, nil
+}
+
+// Returns a non-negative count argument.
+func asCount(funcName string, obj object.Object) (int64, *object.Error) {
+	n, err := object.AsInt(obj)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, object.Errorf("value error: %s() count must be >= 0 (%d given)", funcName, n)
+	}
+	return n, nil
+}
+
+// Iter returns an iterator for the given object. This is also what's called
+// when the module itself is called, e.g. iter([1, 2]).
+func Iter(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("iter", 1, args); err != nil {
+		return err
+	}
+	iter, err := object.AsIterator(args[0])
+	if err != nil {
+		return err
+	}
+	return iter
+}
+
+func Map(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("iter.map", 2, args); err != nil {
+		return err
+	}
+	iter, err := object.AsIterator(args[0])
+	if err != nil {
+		return err
+	}
+	fn := args[1]
+	if err := object.RequireCallable("iter.map", fn); err != nil {
+		return err
+	}
+	return newIterator("map", func(ctx context.Context) (object.Object, bool, error) {
+		item, ok, err := nextItem(ctx, iter)
+		if !ok {
+			return nil, false, err
+		}
+		result, err := object.Call(ctx, fn, item)
+		if err != nil {
+			return nil, false, err
+		}
+		return result, true, nil
+	})
+}
+
+func Filter(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("iter.filter", 2, args); err != nil {
+		return err
+	}
+	iter, err := object.AsIterator(args[0])
+	if err != nil {
+		return err
+	}
+	fn := args[1]
+	if err := object.RequireCallable("iter.filter", fn); err != nil {
+		return err
+	}
+	return newIterator("filter", func(ctx context.Context) (object.Object, bool, error) {
+		for {
+			item, ok, err := nextItem(ctx, iter)
+			if !ok {
+				return nil, false, err
+			}
+			keep, err := object.Call(ctx, fn, item)
+			if err != nil {
+				return nil, false, err
+			}
+			if keep.IsTruthy() {
+				return item, true, nil
+			}
+		}
+	})
+}
+
+func Zip(ctx context.Context, args ...object.Object) object.Object {
+	if len(args) < 1 {
+		return object.ArgsErrorf("args error: iter.zip() takes at least 1 argument (0 given)")
+	}
+	iters := make([]object.Iterator, 0, len(args))
+	for _, obj := range args {
+		iter, err := object.AsIterator(obj)
+		if err != nil {
+			return err
+		}
+		iters = append(iters, iter)
+	}
+	// The zipped iterator stops when the shortest input does
+	return newIterator("zip", func(ctx context.Context) (object.Object, bool, error) {
+		items := make([]object.Object, 0, len(iters))
+		for _, iter := range iters {
+			item, ok, err := nextItem(ctx, iter)
+			if !ok {
+				return nil, false, err
+			}
+			items = append(items, item)
+		}
+		return object.NewList(items), true, nil
+	})
+}
+
+func Enumerate(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("iter.enumerate", 1, 2, args); err != nil {
+		return err
+	}
+	iter, err := object.AsIterator(args[0])
+	if err != nil {
+		return err
+	}
+	var index int64
+	if len(args) == 2 {
+		if index, err = object.AsInt(args[1]); err != nil {
+			return err
+		}
+	}
+	return newIterator("enumerate", func(ctx context.Context) (object.Object, bool, error) {
+		item, ok, err := nextItem(ctx, iter)
+		if !ok {
+			return nil, false, err
+		}
+		pair := object.NewList([]object.Object{object.NewInt(index), item})
+		index++
+		return pair, true, nil
+	})
+}
+
+func Chain(ctx context.Context, args ...object.Object) object.Object {
+	iters := make([]object.Iterator, 0, len(args))
+	for _, obj := range args {
+		iter, err := object.AsIterator(obj)
+		if err != nil {
+			return err
+		}
+		iters = append(iters, iter)
+	}
+	return newIterator("chain", func(ctx context.Context) (object.Object, bool, error) {
+		for len(iters) > 0 {
+			item, ok, err := nextItem(ctx, iters[0])
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				return item, true, nil
+			}
+			iters = iters[1:]
+		}
+		return nil, false, nil
+	})
+}
+
+func Take(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("iter.take", 2, args); err != nil {
+		return err
+	}
+	iter, err := object.AsIterator(args[0])
+	if err != nil {
+		return err
+	}
+	remaining, err := asCount("iter.take", args[1])
+	if err != nil {
+		return err
+	}
+	return newIterator("take", func(ctx context.Context) (object.Object, bool, error) {
+		// Stop without reading past the last item taken
+		if remaining == 0 {
+			return nil, false, nil
+		}
+		remaining--
+		return nextItem(ctx, iter)
+	})
+}
+
+func Skip(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("iter.skip", 2, args); err != nil {
+		return err
+	}
+	iter, err := object.AsIterator(args[0])
+	if err != nil {
+		return err
+	}
+	skip, err := asCount("iter.skip", args[1])
+	if err != nil {
+		return err
+	}
+	return newIterator("skip", func(ctx context.Context) (object.Object, bool, error) {
+		for ; skip > 0; skip-- {
+			if _, ok, err := nextItem(ctx, iter); !ok {
+				return nil, false, err
+			}
+		}
+		return nextItem(ctx, iter)
+	})
+}
+
+func GroupBy(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("iter.group_by", 2, args); err != nil {
+		return err
+	}
+	iter, err := object.AsIterator(args[0])
+	if err != nil {
+		return err
+	}
+	fn := args[1]
+	if err := object.RequireCallable("iter.group_by", fn); err != nil {
+		return err
+	}
+	// The first item of the next group and its key, which are read while
+	// looking for the end of the current group
+	var pending, pendingKey object.Object
+	started := false
+	return newIterator("group_by", func(ctx context.Context) (object.Object, bool, error) {
+		if !started {
+			started = true
+			item, ok, err := nextItem(ctx, iter)
+			if !ok {
+				return nil, false, err
+			}
+			key, err := object.Call(ctx, fn, item)
+			if err != nil {
+				return nil, false, err
+			}
+			pending, pendingKey = item, key
+		}
+		if pending == nil {
+			return nil, false, nil
+		}
+		key := pendingKey
+		group := []object.Object{pending}
+		pending, pendingKey = nil, nil
+		for {
+			item, ok, err := nextItem(ctx, iter)
+			if err != nil {
+				return nil, false, err
+			}
+			if !ok {
+				break
+			}
+			itemKey, err := object.Call(ctx, fn, item)
+			if err != nil {
+				return nil, false, err
+			}
+			if itemKey.Equals(key) != object.True {
+				pending, pendingKey = item, itemKey
+				break
+			}
+			group = append(group, item)
+		}
+		return object.NewList([]object.Object{key, object.NewList(group)}), true, nil
+	})
+}
+
+func Window(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("iter.window", 2, args); err != nil {
+		return err
+	}
+	iter, err := object.AsIterator(args[0])
+	if err != nil {
+		return err
+	}
+	size, err := object.AsInt(args[1])
+	if err != nil {
+		return err
+	}
+	if size < 1 {
+		return object.Errorf("value error: iter.window() size must be > 0 (%d given)", size)
+	}
+	window := make([]object.Object, 0, size)
+	return newIterator("window", func(ctx context.Context) (object.Object, bool, error) {
+		// Drop the oldest item of the previous window, then fill the window
+		if int64(len(window)) == size {
+			window = append(window[:0], window[1:]...)
+		}
+		for int64(len(window)) < size {
+			item, ok, err := nextItem(ctx, iter)
+			if !ok {
+				return nil, false, err
+			}
+			window = append(window, item)
+		}
+		items := make([]object.Object, len(window))
+		copy(items, window)
+		return object.NewList(items), true, nil
+	})
+}
+
+func Reduce(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("iter.reduce", 2, 3, args); err != nil {
+		return err
+	}
+	iter, err := object.AsIterator(args[0])
+	if err != nil {
+		return err
+	}
+	fn := args[1]
+	if err := object.RequireCallable("iter.reduce", fn); err != nil {
+		return err
+	}
+	var result object.Object
+	if len(args) == 3 {
+		result = args[2]
+	} else {
+		item, ok, err := nextItem(ctx, iter)
+		if err != nil {
+			return object.NewError(err)
+		}
+		if !ok {
+			return object.Errorf("value error: iter.reduce() of an empty iterable with no initial value")
+		}
+		result = item
+	}
+	for {
+		item, ok, err := nextItem(ctx, iter)
+		if err != nil {
+			return object.NewError(err)
+		}
+		if !ok {
+			return result
+		}
+		if result, err = object.Call(ctx, fn, result, item); err != nil {
+			return object.NewError(err)
+		}
+	}
+}
+
+func Module() *object.Module {
+	return object.NewBuiltinsModule("iter", map[string]object.Object{
+		"chain":     object.NewBuiltin("iter.chain", Chain),
+		"enumerate": object.NewBuiltin("iter.enumerate", Enumerate),
+		"filter":    object.NewBuiltin("iter.filter", Filter),
+		"group_by":  object.NewBuiltin("iter.group_by", GroupBy),
+		"map":       object.NewBuiltin("iter.map", Map),
+		"reduce":    object.NewBuiltin("iter.reduce", Reduce),
+		"skip":      object.NewBuiltin("iter.skip", Skip),
+		"take":      object.NewBuiltin("iter.take", Take),
+		"window":    object.NewBuiltin("iter.window", Window),
+		"zip":       object.NewBuiltin("iter.zip", Zip),
+	}, Iter)
+}
@@ -0,0 +1,149 @@
+package iter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/itrn0/risor/builtins"
+	"github.com/itrn0/risor/compiler"
+	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/parser"
+	"github.com/itrn0/risor/vm"
+	"github.com/stretchr/testify/require"
+)
+
+func ints(values ...int64) *object.List {
+	items := make([]object.Object, 0, len(values))
+	for _, v := range values {
+		items = append(items, object.NewInt(v))
+	}
+	return object.NewList(items)
+}
+
+// Runs Risor source with this module available as "iter".
+func run(t *testing.T, source string) (object.Object, error) {
+	t.Helper()
+	ctx := context.Background()
+	globals := map[string]any{"iter": Module()}
+	for name, value := range builtins.Builtins() {
+		if name != "iter" {
+			globals[name] = value
+		}
+	}
+	names := make([]string, 0, len(globals))
+	for name := range globals {
+		names = append(names, name)
+	}
+	ast, err := parser.Parse(ctx, source)
+	require.Nil(t, err)
+	code, err := compiler.Compile(ast, compiler.WithGlobalNames(names))
+	require.Nil(t, err)
+	machine := vm.New(code, vm.WithGlobals(globals))
+	if err := machine.Run(ctx); err != nil {
+		return nil, err
+	}
+	result, exists := machine.TOS()
+	require.True(t, exists)
+	return result, nil
+}
+
+func TestIterators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected object.Object
+	}{
+		{`list(iter.map([1, 2, 3], func(x) { x * 2 }))`, ints(2, 4, 6)},
+		{`list(iter.filter(range(6), func(x) { x % 2 == 1 }))`, ints(1, 3, 5)},
+		{`list(iter.map(iter.filter([1, 2, 3, 4], func(x) { x > 2 }), string))`,
+			object.NewStringList([]string{"3", "4"})},
+		{`list(iter.zip([1, 2, 3], ["a", "b"]))`, object.NewList([]object.Object{
+			object.NewList([]object.Object{object.NewInt(1), object.NewString("a")}),
+			object.NewList([]object.Object{object.NewInt(2), object.NewString("b")}),
+		})},
+		{`list(iter.enumerate(["a", "b"], 1))`, object.NewList([]object.Object{
+			object.NewList([]object.Object{object.NewInt(1), object.NewString("a")}),
+			object.NewList([]object.Object{object.NewInt(2), object.NewString("b")}),
+		})},
+		{`list(iter.chain([1], {2}, [], [3]))`, ints(1, 2, 3)},
+		{`list(iter.take(iter.skip(range(10), 2), 3))`, ints(2, 3, 4)},
+		{`list(iter.take([1, 2], 5))`, ints(1, 2)},
+		{`list(iter.skip([1, 2], 5))`, object.NewList(nil)},
+		{`list(iter.group_by([1, 1, 2, 3, 3, 1], func(x) { x }))`, object.NewList([]object.Object{
+			object.NewList([]object.Object{object.NewInt(1), ints(1, 1)}),
+			object.NewList([]object.Object{object.NewInt(2), ints(2)}),
+			object.NewList([]object.Object{object.NewInt(3), ints(3, 3)}),
+			object.NewList([]object.Object{object.NewInt(1), ints(1)}),
+		})},
+		{`list(iter.group_by([], func(x) { x }))`, object.NewList(nil)},
+		{`list(iter.window([1, 2, 3, 4], 3))`, object.NewList([]object.Object{ints(1, 2, 3), ints(2, 3, 4)})},
+		{`list(iter.window([1, 2], 3))`, object.NewList(nil)},
+		{`iter.reduce([1, 2, 3, 4], func(a, b) { a + b })`, object.NewInt(10)},
+		{`iter.reduce([], func(a, b) { a + b }, 5)`, object.NewInt(5)},
+		{`iter.reduce({"a": 1, "b": 2}, func(a, b) { a + b }, "")`, object.NewString("ab")},
+		{`total := 0; for x := range iter.map(range(4), func(x) { x * x }) { total += x }; total`, object.NewInt(14)},
+		{`r := []; for i, x := range iter.skip(["a", "b", "c"], 1) { r.append(sprintf("%d:%s", i, x)) }; r`,
+			object.NewList([]object.Object{object.NewString("0:b"), object.NewString("1:c")})},
+		{`list(iter([1, 2]))`, ints(1, 2)},
+		{`func gen() { for i := range 100 { yield i } }; list(iter.take(iter.map(gen(), func(x) { x + 1 }), 2))`,
+			ints(1, 2)},
+		{`it := iter.map([1], func(x) { x }); [it.next(), it.next()]`, object.NewList([]object.Object{
+			object.NewInt(1), object.Nil,
+		})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := run(t, tt.input)
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestIteratorsAreLazy(t *testing.T) {
+	result, err := run(t, `
+	calls := 0
+	func double(x) { calls++; return x * 2 }
+	it := iter.map(range(1000000), double)
+	first := list(iter.take(it, 3))
+	[first, calls]
+	`)
+	require.Nil(t, err)
+	require.Equal(t, object.NewList([]object.Object{ints(0, 2, 4), object.NewInt(3)}), result)
+}
+
+func TestIteratorErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		errMsg string
+	}{
+		{`iter.map(1.5, string)`, "type error: expected an iterable object (float given)"},
+		{`iter.map([1], 2)`, "type error: iter.map() expected a function (int given)"},
+		{`iter.take([1], -1)`, "value error: iter.take() count must be >= 0 (-1 given)"},
+		{`iter.window([1], 0)`, "value error: iter.window() size must be > 0 (0 given)"},
+		{`iter.zip()`, "args error: iter.zip() takes at least 1 argument (0 given)"},
+		{`iter.reduce([], func(a, b) { a })`, "value error: iter.reduce() of an empty iterable with no initial value"},
+		{`list(iter.map([1, 0], func(x) { x ? x : error("zero") }))`, "zero"},
+		{`for x := range iter.filter([1], func(x) { error("bad") }) {}`, "bad"},
+		{`func gen() { yield 1; error("gen failed") }; list(iter.map(gen(), string))`, "gen failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := run(t, tt.input)
+			require.NotNil(t, err)
+			require.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestIteratorFromGo(t *testing.T) {
+	ctx := context.Background()
+	double := object.NewBuiltin("double", func(ctx context.Context, args ...object.Object) object.Object {
+		return object.NewInt(args[0].(*object.Int).Value() * 2)
+	})
+	it, ok := Map(ctx, ints(1, 2), double).(*Iterator)
+	require.True(t, ok)
+	require.Equal(t, "iter.iterator(map)", it.Inspect())
+	require.Equal(t, []any{int64(2), int64(4)}, it.Interface())
+	require.False(t, it.IsTruthy())
+	require.Nil(t, it.Err())
+}
@@ -0,0 +1,132 @@
+package iter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/itrn0/risor/errz"
+	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/op"
+)
+
+const ITERATOR object.Type = "iter.iterator"
+
+// Produces the next item of an iterator, returning false once there are no
+// more items or an error occurs.
+type nextFunc func(ctx context.Context) (object.Object, bool, error)
+
+// Iterator is the lazy iterator returned by functions in this module. Each
+// item is computed only when it's requested, so no intermediate lists are
+// built when iterators are combined.
+type Iterator struct {
+	name    string
+	next    nextFunc
+	index   int64
+	current object.Object
+	done    bool
+	err     error
+}
+
+func (it *Iterator) Type() object.Type {
+	return ITERATOR
+}
+
+func (it *Iterator) Inspect() string {
+	return fmt.Sprintf("%s(%s)", ITERATOR, it.name)
+}
+
+func (it *Iterator) String() string {
+	return it.Inspect()
+}
+
+func (it *Iterator) Interface() interface{} {
+	ctx := context.Background()
+	var items []any
+	for {
+		item, ok := it.Next(ctx)
+		if !ok {
+			break
+		}
+		items = append(items, item.Interface())
+	}
+	return items
+}
+
+func (it *Iterator) IsTruthy() bool {
+	return !it.done
+}
+
+func (it *Iterator) Cost() int {
+	return 8
+}
+
+func (it *Iterator) MarshalJSON() ([]byte, error) {
+	return nil, errz.TypeErrorf("type error: unable to marshal %s", ITERATOR)
+}
+
+func (it *Iterator) RunOperation(opType op.BinaryOpType, right object.Object) object.Object {
+	return object.TypeErrorf("type error: unsupported operation for %s: %v", ITERATOR, opType)
+}
+
+func (it *Iterator) Equals(other object.Object) object.Object {
+	return object.NewBool(it == other)
+}
+
+func (it *Iterator) SetAttr(name string, value object.Object) error {
+	return object.TypeErrorf("type error: %s object has no attribute %q", ITERATOR, name)
+}
+
+func (it *Iterator) GetAttr(name string) (object.Object, bool) {
+	switch name {
+	case "next":
+		return object.NewBuiltin("iter.iterator.next", func(ctx context.Context, args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return object.NewArgsError("iter.iterator.next", 0, len(args))
+			}
+			item, ok := it.Next(ctx)
+			if !ok {
+				if it.err != nil {
+					return object.NewError(it.err)
+				}
+				return object.Nil
+			}
+			return item
+		}), true
+	}
+	return nil, false
+}
+
+func (it *Iterator) Next(ctx context.Context) (object.Object, bool) {
+	if it.done {
+		return nil, false
+	}
+	item, ok, err := it.next(ctx)
+	if err != nil || !ok {
+		it.done = true
+		it.current = nil
+		it.err = err
+		return nil, false
+	}
+	it.index++
+	it.current = item
+	return item, true
+}
+
+// Entry returns the position of the current item as the entry key, with the
+// item as the entry value and primary object, so that range loops bind the
+// items in the same way as they do for lists.
+func (it *Iterator) Entry() (object.IteratorEntry, bool) {
+	if it.current == nil {
+		return nil, false
+	}
+	return object.NewEntry(object.NewInt(it.index), it.current).WithValueAsPrimary(), true
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+func newIterator(name string, next nextFunc) *Iterator {
+	return &Iterator{name: name, next: next, index: -1}
+}
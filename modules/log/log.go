@@ -0,0 +1,118 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/object"
+)
+
+// The logger used by the module-level functions, which has no attributes
+var root = &Logger{}
+
+// Converts a level name such as "info" or "warn+2", or a level number, to a
+// slog level.
+func toLevel(funcName string, obj object.Object) (slog.Level, *object.Error) {
+	switch obj := obj.(type) {
+	case *object.Int:
+		return slog.Level(obj.Value()), nil
+	case *object.String:
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(obj.Value())); err != nil {
+			return 0, object.Errorf("value error: %s() invalid level %q", funcName, obj.Value())
+		}
+		return level, nil
+	default:
+		return 0, object.TypeErrorf("type error: %s() expected a string or int level (%s given)", funcName, obj.Type())
+	}
+}
+
+// Converts a map to attributes, sorted by key.
+func toAttrs(funcName string, obj object.Object) ([]slog.Attr, *object.Error) {
+	m, ok := obj.(*object.Map)
+	if !ok {
+		return nil, object.TypeErrorf("type error: %s() expected a map (%s given)", funcName, obj.Type())
+	}
+	keys := m.SortedKeys()
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, key := range keys {
+		attrs = append(attrs, slog.Attr{Key: key, Value: toValue(m.Get(key))})
+	}
+	return attrs, nil
+}
+
+// Converts a Risor value to a slog value. Maps become groups so that handlers
+// can render nested attributes in their own way.
+func toValue(obj object.Object) slog.Value {
+	switch obj := obj.(type) {
+	case *object.String:
+		return slog.StringValue(obj.Value())
+	case *object.Int:
+		return slog.Int64Value(obj.Value())
+	case *object.Float:
+		return slog.Float64Value(obj.Value())
+	case *object.Bool:
+		return slog.BoolValue(obj.Value())
+	case *object.Time:
+		return slog.TimeValue(obj.Value())
+	case *object.Error:
+		return slog.StringValue(obj.Message().Value())
+	case *object.Map:
+		attrs, _ := toAttrs("", obj)
+		return slog.GroupValue(attrs...)
+	default:
+		return slog.AnyValue(obj.Interface())
+	}
+}
+
+func enabled(ctx context.Context, funcName string, args ...object.Object) object.Object {
+	if err := arg.Require(funcName, 1, args); err != nil {
+		return err
+	}
+	level, err := toLevel(funcName, args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewBool(object.GetLogger(ctx).Enabled(ctx, level))
+}
+
+func Debug(ctx context.Context, args ...object.Object) object.Object {
+	return root.write(ctx, "log.debug", slog.LevelDebug, args...)
+}
+
+func Info(ctx context.Context, args ...object.Object) object.Object {
+	return root.write(ctx, "log.info", slog.LevelInfo, args...)
+}
+
+func Warn(ctx context.Context, args ...object.Object) object.Object {
+	return root.write(ctx, "log.warn", slog.LevelWarn, args...)
+}
+
+func Error(ctx context.Context, args ...object.Object) object.Object {
+	return root.write(ctx, "log.error", slog.LevelError, args...)
+}
+
+func Log(ctx context.Context, args ...object.Object) object.Object {
+	return root.log(ctx, "log.log", args...)
+}
+
+func With(ctx context.Context, args ...object.Object) object.Object {
+	return root.with("log.with", args...)
+}
+
+func Enabled(ctx context.Context, args ...object.Object) object.Object {
+	return enabled(ctx, "log.enabled", args...)
+}
+
+func Module() *object.Module {
+	return object.NewBuiltinsModule("log", map[string]object.Object{
+		"debug":   object.NewBuiltin("log.debug", Debug),
+		"enabled": object.NewBuiltin("log.enabled", Enabled),
+		"error":   object.NewBuiltin("log.error", Error),
+		"info":    object.NewBuiltin("log.info", Info),
+		"log":     object.NewBuiltin("log.log", Log),
+		"warn":    object.NewBuiltin("log.warn", Warn),
+		"with":    object.NewBuiltin("log.with", With),
+	})
+}
@@ -0,0 +1,140 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/itrn0/risor/compiler"
+	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/parser"
+	"github.com/itrn0/risor/vm"
+	"github.com/stretchr/testify/require"
+)
+
+// Runs Risor source with this module available as "log" and returns the
+// JSON records written to the VM's logger, without their timestamps.
+func run(t *testing.T, source string) ([]map[string]any, error) {
+	t.Helper()
+	ctx := context.Background()
+	var out bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	globals := map[string]any{"log": Module(), "error": object.NewBuiltin("error",
+		func(ctx context.Context, args ...object.Object) object.Object {
+			return object.Errorf("%s", args[0].(*object.String).Value()).WithRaised(false)
+		})}
+	ast, err := parser.Parse(ctx, source)
+	require.Nil(t, err)
+	code, err := compiler.Compile(ast, compiler.WithGlobalNames([]string{"log", "error"}))
+	require.Nil(t, err)
+	machine := vm.New(code, vm.WithGlobals(globals), vm.WithLogger(logger))
+	if err := machine.Run(ctx); err != nil {
+		return nil, err
+	}
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		require.Nil(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func TestLog(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []map[string]any
+	}{
+		{`log.info("started")`, []map[string]any{{"level": "INFO", "msg": "started"}}},
+		{`log.debug("a"); log.warn("b"); log.error("c")`, []map[string]any{
+			{"level": "DEBUG", "msg": "a"},
+			{"level": "WARN", "msg": "b"},
+			{"level": "ERROR", "msg": "c"},
+		}},
+		{`log.info("request", {"status": 200, "path": "/", "ok": true, "ms": 1.5, "tags": ["a"]})`,
+			[]map[string]any{{
+				"level": "INFO", "msg": "request",
+				"ok": true, "ms": 1.5, "path": "/", "status": float64(200), "tags": []any{"a"},
+			}}},
+		{`log.info("nested", {"user": {"id": 7}})`, []map[string]any{
+			{"level": "INFO", "msg": "nested", "user": map[string]any{"id": float64(7)}},
+		}},
+		{`l := log.with({"service": "api"}).with({"version": 2}); l.info("up", {"port": 80})`,
+			[]map[string]any{{
+				"level": "INFO", "msg": "up", "service": "api", "version": float64(2), "port": float64(80),
+			}}},
+		{`log.log("warn+2", "custom"); log.log(-4, "debug")`, []map[string]any{
+			{"level": "WARN+2", "msg": "custom"},
+			{"level": "DEBUG", "msg": "debug"},
+		}},
+		{`log.with({"a": 1}).log("error", "failed", {"err": error("boom")})`, []map[string]any{
+			{"level": "ERROR", "msg": "failed", "a": float64(1), "err": "boom"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			records, err := run(t, tt.input)
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, records)
+		})
+	}
+}
+
+func TestLogErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		errMsg string
+	}{
+		{`log.info()`, "args error: log.info() takes at least 1 argument (0 given)"},
+		{`log.info(1)`, "type error: expected a string (int given)"},
+		{`log.info("x", [1])`, "type error: log.info() expected a map (list given)"},
+		{`log.with(1)`, "type error: log.with() expected a map (int given)"},
+		{`log.log("loud", "x")`, `value error: log.log() invalid level "loud"`},
+		{`log.with({}).log(1.5, "x")`, "type error: log.logger.log() expected a string or int level (float given)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := run(t, tt.input)
+			require.NotNil(t, err)
+			require.Equal(t, tt.errMsg, err.Error())
+		})
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	ctx := object.WithLogger(context.Background(), logger)
+	require.Equal(t, object.False, Enabled(ctx, object.NewString("info")))
+	require.Equal(t, object.True, Enabled(ctx, object.NewString("error")))
+
+	// Records below the level of the logger are dropped
+	require.Equal(t, object.Nil, Info(ctx, object.NewString("dropped")))
+	require.Equal(t, object.Nil, Warn(ctx, object.NewString("kept"), object.NewMap(map[string]object.Object{
+		"n": object.NewInt(1),
+	})))
+	require.NotContains(t, out.String(), "dropped")
+	require.Contains(t, out.String(), "level=WARN msg=kept n=1")
+}
+
+func TestLogger(t *testing.T) {
+	l := NewLogger(slog.String("a", "b")).With(slog.Int("n", 1))
+	require.Equal(t, LOGGER, l.Type())
+	require.Equal(t, "log.logger(a=b n=1)", l.Inspect())
+	require.Equal(t, "log.logger()", NewLogger().Inspect())
+	require.Len(t, l.Attrs(), 2)
+}
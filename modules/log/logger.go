@@ -0,0 +1,178 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/errz"
+	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/op"
+)
+
+const LOGGER object.Type = "log.logger"
+
+// Logger writes log records to the logger of the evaluation context, adding
+// its bound attributes to each record. The logger is looked up each time a
+// record is written, so a Logger may be shared between evaluations that log
+// to different places.
+type Logger struct {
+	attrs []slog.Attr
+}
+
+func (l *Logger) Type() object.Type {
+	return LOGGER
+}
+
+func (l *Logger) Inspect() string {
+	if len(l.attrs) == 0 {
+		return fmt.Sprintf("%s()", LOGGER)
+	}
+	parts := make([]string, 0, len(l.attrs))
+	for _, attr := range l.attrs {
+		parts = append(parts, attr.String())
+	}
+	return fmt.Sprintf("%s(%s)", LOGGER, strings.Join(parts, " "))
+}
+
+func (l *Logger) String() string {
+	return l.Inspect()
+}
+
+// Attrs returns the attributes added to each record written by this logger.
+func (l *Logger) Attrs() []slog.Attr {
+	return l.attrs
+}
+
+// With returns a logger that adds the given attributes to each record, after
+// the attributes already bound to this logger.
+func (l *Logger) With(attrs ...slog.Attr) *Logger {
+	combined := make([]slog.Attr, 0, len(l.attrs)+len(attrs))
+	combined = append(combined, l.attrs...)
+	combined = append(combined, attrs...)
+	return &Logger{attrs: combined}
+}
+
+// Log writes a record with the bound attributes followed by the given ones.
+func (l *Logger) Log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	logger := object.GetLogger(ctx)
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	if len(l.attrs) > 0 {
+		attrs = append(append([]slog.Attr{}, l.attrs...), attrs...)
+	}
+	logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+func (l *Logger) Interface() interface{} {
+	return l.attrs
+}
+
+func (l *Logger) IsTruthy() bool {
+	return true
+}
+
+func (l *Logger) Cost() int {
+	return 8
+}
+
+func (l *Logger) MarshalJSON() ([]byte, error) {
+	return nil, errz.TypeErrorf("type error: unable to marshal %s", LOGGER)
+}
+
+func (l *Logger) RunOperation(opType op.BinaryOpType, right object.Object) object.Object {
+	return object.TypeErrorf("type error: unsupported operation for %s: %v", LOGGER, opType)
+}
+
+func (l *Logger) Equals(other object.Object) object.Object {
+	return object.NewBool(l == other)
+}
+
+func (l *Logger) SetAttr(name string, value object.Object) error {
+	return object.TypeErrorf("type error: %s object has no attribute %q", LOGGER, name)
+}
+
+func (l *Logger) GetAttr(name string) (object.Object, bool) {
+	switch name {
+	case "debug":
+		return l.levelFunc("log.logger.debug", slog.LevelDebug), true
+	case "info":
+		return l.levelFunc("log.logger.info", slog.LevelInfo), true
+	case "warn":
+		return l.levelFunc("log.logger.warn", slog.LevelWarn), true
+	case "error":
+		return l.levelFunc("log.logger.error", slog.LevelError), true
+	case "log":
+		return object.NewBuiltin("log.logger.log",
+			func(ctx context.Context, args ...object.Object) object.Object {
+				return l.log(ctx, "log.logger.log", args...)
+			}), true
+	case "with":
+		return object.NewBuiltin("log.logger.with",
+			func(ctx context.Context, args ...object.Object) object.Object {
+				return l.with("log.logger.with", args...)
+			}), true
+	case "enabled":
+		return object.NewBuiltin("log.logger.enabled",
+			func(ctx context.Context, args ...object.Object) object.Object {
+				return enabled(ctx, "log.logger.enabled", args...)
+			}), true
+	}
+	return nil, false
+}
+
+func (l *Logger) levelFunc(name string, level slog.Level) *object.Builtin {
+	return object.NewBuiltin(name, func(ctx context.Context, args ...object.Object) object.Object {
+		return l.write(ctx, name, level, args...)
+	})
+}
+
+// Writes a record from the arguments of one of the logging functions, which
+// are a message and an optional map of attributes.
+func (l *Logger) write(ctx context.Context, name string, level slog.Level, args ...object.Object) object.Object {
+	if err := arg.RequireRange(name, 1, 2, args); err != nil {
+		return err
+	}
+	msg, err := object.AsString(args[0])
+	if err != nil {
+		return err
+	}
+	var attrs []slog.Attr
+	if len(args) == 2 {
+		if attrs, err = toAttrs(name, args[1]); err != nil {
+			return err
+		}
+	}
+	l.Log(ctx, level, msg, attrs...)
+	return object.Nil
+}
+
+func (l *Logger) log(ctx context.Context, name string, args ...object.Object) object.Object {
+	if err := arg.RequireRange(name, 2, 3, args); err != nil {
+		return err
+	}
+	level, err := toLevel(name, args[0])
+	if err != nil {
+		return err
+	}
+	return l.write(ctx, name, level, args[1:]...)
+}
+
+func (l *Logger) with(name string, args ...object.Object) object.Object {
+	if err := arg.Require(name, 1, args); err != nil {
+		return err
+	}
+	attrs, err := toAttrs(name, args[0])
+	if err != nil {
+		return err
+	}
+	return l.With(attrs...)
+}
+
+// NewLogger returns a Logger that adds the given attributes to each record.
+func NewLogger(attrs ...slog.Attr) *Logger {
+	return &Logger{attrs: attrs}
+}
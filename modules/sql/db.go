@@ -73,6 +73,12 @@ func (db *DB) GetAttr(name string) (object.Object, bool) {
 		return object.NewBuiltin("sql.query", db.Query), true
 	case "exec":
 		return object.NewBuiltin("sql.exec", db.Exec), true
+	case "rows":
+		return object.NewBuiltin("sql.rows", db.Rows), true
+	case "prepare":
+		return object.NewBuiltin("sql.prepare", db.Prepare), true
+	case "begin":
+		return object.NewBuiltin("sql.begin", db.Begin), true
 	case "close":
 		return object.NewBuiltin("sql.close", func(ctx context.Context, args ...object.Object) object.Object {
 			if err := arg.Require("sql.close", 0, args); err != nil {
@@ -88,84 +94,32 @@ func (db *DB) GetAttr(name string) (object.Object, bool) {
 }
 
 func (db *DB) Exec(ctx context.Context, args ...object.Object) object.Object {
-	numArgs := len(args)
-	if numArgs < 1 {
-		return object.TypeErrorf("type error: sql.exec() requires at least one argument")
-	}
-
-	query, errObj := object.AsString(args[0])
-	if errObj != nil {
-		return errObj
-	}
-
-	// Build list of query args as their Go types
-	var queryArgs []interface{}
-	for _, queryArg := range args[1:] {
-		queryArgs = append(queryArgs, queryArg.Interface())
-	}
-	_, err := db.conn.Exec(query, queryArgs...)
-	if err != nil {
-		return object.NewError(err)
-	}
-
-	return nil
+	return exec(ctx, db.conn, "sql.exec", args)
 }
 
 func (db *DB) Query(ctx context.Context, args ...object.Object) object.Object {
-	numArgs := len(args)
-	if numArgs < 1 {
-		return object.TypeErrorf("type error: sql.query() requires at least one argument")
-	}
+	return query(ctx, db.conn, "sql.query", args)
+}
 
-	query, errObj := object.AsString(args[0])
-	if errObj != nil {
-		return errObj
-	}
+// Rows runs a query and returns an iterator over its rows, rather than
+// reading the whole result into memory.
+func (db *DB) Rows(ctx context.Context, args ...object.Object) object.Object {
+	return queryRows(ctx, db.conn, "sql.rows", args)
+}
 
-	// Build list of query args as their Go types
-	var queryArgs []interface{}
-	for _, queryArg := range args[1:] {
-		queryArgs = append(queryArgs, queryArg.Interface())
-	}
+func (db *DB) Prepare(ctx context.Context, args ...object.Object) object.Object {
+	return prepare(ctx, db.conn, "sql.prepare", args)
+}
 
-	// Start the query
-	rows, err := db.conn.Query(query, queryArgs...)
-	if err != nil || rows.Err() != nil {
-		return object.Errorf("failed to query db: %w", err)
+func (db *DB) Begin(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("sql.begin", 0, args); err != nil {
+		return err
 	}
-	defer rows.Close()
-
-	columns, err := rows.Columns()
+	tx, err := db.conn.BeginTx(ctx, nil)
 	if err != nil {
-		return object.Errorf("failed to get columns: %w", err)
-	}
-
-	rowList := object.NewList(make([]object.Object, 0))
-	for rows.Next() {
-		rowValues := make([]interface{}, len(columns))
-		for i := range rowValues {
-			var s interface{}
-			rowValues[i] = &s
-		}
-		if err := rows.Scan(rowValues...); err != nil {
-			return object.NewError(err)
-		}
-
-		row := object.NewMap(make(map[string]object.Object))
-		for i := range rowValues {
-			val := *(rowValues[i].(*interface{}))
-			switch val := val.(type) {
-			case []byte:
-				row.Set(columns[i], object.NewString(string(val)))
-			default:
-				row.Set(columns[i], object.FromGoType(val))
-			}
-		}
-
-		rowList.Append(row)
+		return object.Errorf("failed to begin transaction: %w", err)
 	}
-
-	return rowList
+	return NewTx(tx)
 }
 
 func (db *DB) Close() error {
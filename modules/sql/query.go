@@ -0,0 +1,169 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/limits"
+	"github.com/itrn0/risor/object"
+)
+
+// querier is implemented by connections and transactions.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// Splits the arguments of a query, exec or rows call into the SQL string and
+// the query args. See queryArgs.
+func parseQuery(name string, args []object.Object) (string, []any, *object.Error) {
+	if len(args) < 1 {
+		return "", nil, object.TypeErrorf("type error: %s() requires at least one argument", name)
+	}
+	query, err := object.AsString(args[0])
+	if err != nil {
+		return "", nil, err
+	}
+	return query, queryArgs(args[1:]), nil
+}
+
+// Converts query args to their Go types. A single map is treated as a set of
+// named parameters, which are referenced in SQL as :name, @name or $name
+// depending on the driver.
+func queryArgs(args []object.Object) []any {
+	if len(args) == 1 {
+		if params, ok := args[0].(*object.Map); ok {
+			keys := params.SortedKeys()
+			named := make([]any, 0, len(keys))
+			for _, key := range keys {
+				named = append(named, sql.Named(key, params.Get(key).Interface()))
+			}
+			return named
+		}
+	}
+	result := make([]any, 0, len(args))
+	for _, arg := range args {
+		result = append(result, arg.Interface())
+	}
+	return result
+}
+
+func exec(ctx context.Context, q querier, name string, args []object.Object) object.Object {
+	query, queryArgs, errObj := parseQuery(name, args)
+	if errObj != nil {
+		return errObj
+	}
+	if _, err := q.ExecContext(ctx, query, queryArgs...); err != nil {
+		return object.NewError(err)
+	}
+	return object.Nil
+}
+
+func query(ctx context.Context, q querier, name string, args []object.Object) object.Object {
+	query, queryArgs, errObj := parseQuery(name, args)
+	if errObj != nil {
+		return errObj
+	}
+	rows, err := q.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return object.Errorf("failed to query db: %w", err)
+	}
+	return readRows(ctx, rows)
+}
+
+func queryRows(ctx context.Context, q querier, name string, args []object.Object) object.Object {
+	query, queryArgs, errObj := parseQuery(name, args)
+	if errObj != nil {
+		return errObj
+	}
+	rows, err := q.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return object.Errorf("failed to query db: %w", err)
+	}
+	return NewRows(ctx, rows)
+}
+
+func prepare(ctx context.Context, q querier, name string, args []object.Object) object.Object {
+	if err := arg.Require(name, 1, args); err != nil {
+		return err
+	}
+	query, errObj := object.AsString(args[0])
+	if errObj != nil {
+		return errObj
+	}
+	stmt, err := q.PrepareContext(ctx, query)
+	if err != nil {
+		return object.Errorf("failed to prepare statement: %w", err)
+	}
+	return NewStmt(stmt, query)
+}
+
+// Returns the maximum number of bytes of row data that may be buffered, or
+// zero if there is no limit.
+func bufferLimit(ctx context.Context) int64 {
+	if lim, ok := limits.GetLimits(ctx); ok && lim.MaxBufferSize() > 0 {
+		return lim.MaxBufferSize()
+	}
+	return 0
+}
+
+// Reads all rows into a list of maps. The rows are closed. The total size of
+// the rows is limited by the buffer size limit, if any.
+func readRows(ctx context.Context, rows *sql.Rows) object.Object {
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return object.Errorf("failed to get columns: %w", err)
+	}
+	limit := bufferLimit(ctx)
+	var size int64
+	rowList := object.NewList(make([]object.Object, 0))
+	for rows.Next() {
+		row, rowSize, err := scanRow(rows, columns)
+		if err != nil {
+			return object.NewError(err)
+		}
+		size += rowSize
+		if limit > 0 && size > limit {
+			return object.NewError(limits.NewLimitsError(
+				"limit error: query result exceeded limit of %d bytes (use rows() to stream large results)", limit))
+		}
+		rowList.Append(row)
+	}
+	if err := rows.Err(); err != nil {
+		return object.NewError(err)
+	}
+	return rowList
+}
+
+// Scans the current row into a map keyed by column name. Also returns the
+// approximate size of the row's data in bytes.
+func scanRow(rows *sql.Rows, columns []string) (*object.Map, int64, error) {
+	rowValues := make([]interface{}, len(columns))
+	for i := range rowValues {
+		var s interface{}
+		rowValues[i] = &s
+	}
+	if err := rows.Scan(rowValues...); err != nil {
+		return nil, 0, err
+	}
+	var size int64
+	row := object.NewMap(make(map[string]object.Object, len(columns)))
+	for i := range rowValues {
+		val := *(rowValues[i].(*interface{}))
+		switch val := val.(type) {
+		case []byte:
+			size += int64(len(val))
+			row.Set(columns[i], object.NewString(string(val)))
+		case string:
+			size += int64(len(val))
+			row.Set(columns[i], object.NewString(val))
+		default:
+			size += 8
+			row.Set(columns[i], object.FromGoType(val))
+		}
+	}
+	return row, size, nil
+}
@@ -0,0 +1,196 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/errz"
+	"github.com/itrn0/risor/limits"
+	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/op"
+)
+
+const ROWS object.Type = "sql.rows"
+
+// Rows iterates over the rows of a query result, reading one row at a time
+// from the database. Each row is a map keyed by column name. If reading a row
+// fails, or a row is larger than the buffer size limit, iteration stops with
+// the error, which range loops raise and err() returns.
+type Rows struct {
+	rows    *sql.Rows
+	columns []string
+	limit   int64
+	pos     int64
+	current *object.Map
+	done    bool
+	err     error
+}
+
+func (r *Rows) Type() object.Type {
+	return ROWS
+}
+
+func (r *Rows) Inspect() string {
+	return fmt.Sprintf("sql.rows(%d)", r.pos+1)
+}
+
+func (r *Rows) Interface() interface{} {
+	ctx := context.Background()
+	var rows []any
+	for {
+		row, ok := r.Next(ctx)
+		if !ok {
+			break
+		}
+		rows = append(rows, row.Interface())
+	}
+	return rows
+}
+
+func (r *Rows) IsTruthy() bool {
+	return !r.done
+}
+
+func (r *Rows) Cost() int {
+	return 8
+}
+
+func (r *Rows) MarshalJSON() ([]byte, error) {
+	return nil, errz.TypeErrorf("type error: unable to marshal %s", ROWS)
+}
+
+func (r *Rows) RunOperation(opType op.BinaryOpType, right object.Object) object.Object {
+	return object.TypeErrorf("type error: unsupported operation for %s: %v", ROWS, opType)
+}
+
+func (r *Rows) Equals(other object.Object) object.Object {
+	return object.NewBool(r == other)
+}
+
+func (r *Rows) SetAttr(name string, value object.Object) error {
+	return object.TypeErrorf("type error: %s object has no attribute %q", ROWS, name)
+}
+
+func (r *Rows) GetAttr(name string) (object.Object, bool) {
+	switch name {
+	case "next":
+		return object.NewBuiltin("sql.rows.next", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.Require("sql.rows.next", 0, args); err != nil {
+				return err
+			}
+			row, ok := r.Next(ctx)
+			if !ok {
+				if r.err != nil {
+					return object.NewError(r.err)
+				}
+				return object.Nil
+			}
+			return row
+		}), true
+	case "columns":
+		return object.NewBuiltin("sql.rows.columns", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.Require("sql.rows.columns", 0, args); err != nil {
+				return err
+			}
+			return object.NewStringList(r.columns)
+		}), true
+	case "err":
+		return object.NewBuiltin("sql.rows.err", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.Require("sql.rows.err", 0, args); err != nil {
+				return err
+			}
+			if r.err != nil {
+				return object.NewError(r.err).WithRaised(false)
+			}
+			return object.Nil
+		}), true
+	case "close":
+		return object.NewBuiltin("sql.rows.close", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.Require("sql.rows.close", 0, args); err != nil {
+				return err
+			}
+			if err := r.Close(); err != nil {
+				return object.NewError(err)
+			}
+			return object.Nil
+		}), true
+	}
+	return nil, false
+}
+
+// Iter returns the rows themselves, so that they may be used in a range loop.
+func (r *Rows) Iter() object.Iterator {
+	return r
+}
+
+func (r *Rows) Next(ctx context.Context) (object.Object, bool) {
+	if r.done {
+		return nil, false
+	}
+	if !r.rows.Next() {
+		r.finish(r.rows.Err())
+		return nil, false
+	}
+	row, size, err := scanRow(r.rows, r.columns)
+	if err == nil && r.limit > 0 && size > r.limit {
+		err = limits.NewLimitsError("limit error: row size exceeded limit of %d bytes (got %d)", r.limit, size)
+	}
+	if err != nil {
+		r.finish(err)
+		return nil, false
+	}
+	r.pos++
+	r.current = row
+	return row, true
+}
+
+func (r *Rows) Entry() (object.IteratorEntry, bool) {
+	if r.current == nil {
+		return nil, false
+	}
+	return object.NewEntry(object.NewInt(r.pos), r.current).WithValueAsPrimary(), true
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (r *Rows) Err() error {
+	return r.err
+}
+
+// Stops the iteration, recording the error that caused it to stop, if any.
+func (r *Rows) finish(err error) {
+	r.done = true
+	r.err = err
+	r.current = nil
+	if closeErr := r.rows.Close(); r.err == nil {
+		r.err = closeErr
+	}
+}
+
+// Close stops the iteration and releases the underlying result set. It's
+// safe to call more than once.
+func (r *Rows) Close() error {
+	if r.done {
+		return nil
+	}
+	r.done = true
+	r.current = nil
+	return r.rows.Close()
+}
+
+// NewRows returns an iterator over the given rows. Rows larger than the
+// buffer size limit of the context, if any, stop the iteration with an error.
+func NewRows(ctx context.Context, rows *sql.Rows) object.Object {
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return object.Errorf("failed to get columns: %w", err)
+	}
+	return &Rows{
+		rows:    rows,
+		columns: columns,
+		limit:   bufferLimit(ctx),
+		pos:     -1,
+	}
+}
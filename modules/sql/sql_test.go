@@ -0,0 +1,169 @@
+package sql
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/itrn0/risor"
+	"github.com/itrn0/risor/limits"
+	"github.com/itrn0/risor/object"
+	"github.com/stretchr/testify/require"
+)
+
+const setup = `
+db := sql.connect("sqlite:" + path)
+db.exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+db.exec("INSERT INTO users (name) VALUES (?), (?), (?)", "Alice", "Bob", "Carol")
+`
+
+func eval(t *testing.T, source string, options ...risor.Option) (object.Object, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	options = append(options, risor.WithGlobal("sql", Module()), risor.WithGlobal("path", path))
+	return risor.Eval(context.Background(), setup+source, options...)
+}
+
+func TestQuery(t *testing.T) {
+	result, err := eval(t, `db.query("SELECT name FROM users WHERE id > ? ORDER BY id", 1)`)
+	require.Nil(t, err)
+	require.Equal(t, []any{
+		map[string]any{"name": "Bob"},
+		map[string]any{"name": "Carol"},
+	}, result.Interface())
+}
+
+func TestNamedParameters(t *testing.T) {
+	result, err := eval(t, `
+	db.exec("UPDATE users SET name = :name WHERE id = :id", {id: 2, name: "Bobby"})
+	db.query("SELECT name FROM users WHERE id = :id", {id: 2})[0].name
+	`)
+	require.Nil(t, err)
+	require.Equal(t, object.NewString("Bobby"), result)
+}
+
+func TestRows(t *testing.T) {
+	result, err := eval(t, `
+	names := []
+	for i, row := range db.rows("SELECT id, name FROM users ORDER BY id") {
+		names.append(sprintf("%d:%s", i, row.name))
+	}
+	names
+	`)
+	require.Nil(t, err)
+	require.Equal(t, []any{"0:Alice", "1:Bob", "2:Carol"}, result.Interface())
+
+	result, err = eval(t, `
+	names := []
+	for row := range db.rows("SELECT name FROM users ORDER BY id") {
+		names.append(row.name)
+	}
+	names
+	`)
+	require.Nil(t, err)
+	require.Equal(t, []any{"Alice", "Bob", "Carol"}, result.Interface())
+
+	result, err = eval(t, `
+	rows := db.rows("SELECT name FROM users WHERE id > 1 ORDER BY id")
+	[rows.columns(), rows.next().name, rows.next().name, rows.next(), rows.err()]
+	`)
+	require.Nil(t, err)
+	require.Equal(t, []any{[]any{"name"}, "Bob", "Carol", nil, nil}, result.Interface())
+}
+
+func TestRowsBufferLimit(t *testing.T) {
+	lim := limits.New(limits.WithMaxBufferSize(10))
+
+	_, err := eval(t, `db.query("SELECT name FROM users")`, risor.WithLimits(lim))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "limit error: query result exceeded limit of 10 bytes")
+
+	// Streaming keeps only one row in memory at a time
+	result, err := eval(t, `
+	count := 0
+	for row := range db.rows("SELECT name FROM users") { count++ }
+	count
+	`, risor.WithLimits(lim))
+	require.Nil(t, err)
+	require.Equal(t, object.NewInt(3), result)
+
+	_, err = eval(t, `
+	db.exec("INSERT INTO users (name) VALUES (?)", "A very long name indeed")
+	count := 0
+	for row := range db.rows("SELECT name FROM users ORDER BY id") { count++ }
+	count
+	`, risor.WithLimits(lim))
+	require.NotNil(t, err)
+	require.Equal(t, "limit error: row size exceeded limit of 10 bytes (got 23)", err.Error())
+
+	// The error remains available from err() after the loop
+	result, err = eval(t, `
+	db.exec("INSERT INTO users (name) VALUES (?)", "A very long name indeed")
+	rows := db.rows("SELECT name FROM users ORDER BY id")
+	count := 0
+	try(func() { for row := range rows { count++ } })
+	[count, rows.err()]
+	`, risor.WithLimits(lim))
+	require.Nil(t, err)
+	list := result.(*object.List).Value()
+	require.Equal(t, object.NewInt(3), list[0])
+	require.Equal(t, "limit error: row size exceeded limit of 10 bytes (got 23)",
+		list[1].(*object.Error).Message().Value())
+}
+
+func TestTransactions(t *testing.T) {
+	result, err := eval(t, `
+	tx := db.begin()
+	tx.exec("DELETE FROM users WHERE id = ?", 1)
+	tx.rollback()
+	tx = db.begin()
+	tx.exec("DELETE FROM users WHERE id = ?", 2)
+	tx.commit()
+	tx.rollback()
+	len(db.query("SELECT * FROM users"))
+	`)
+	require.Nil(t, err)
+	require.Equal(t, object.NewInt(2), result)
+}
+
+func TestTransactionDeferredRollback(t *testing.T) {
+	result, err := eval(t, `
+	func remove(id) {
+		tx := db.begin()
+		defer tx.rollback()
+		tx.exec("DELETE FROM users WHERE id = ?", id)
+		if id == 2 {
+			error("cannot remove user 2")
+		}
+		tx.commit()
+	}
+	remove(1)
+	try(func() { remove(2) })
+	db.query("SELECT id FROM users ORDER BY id")
+	`)
+	require.Nil(t, err)
+	require.Equal(t, []any{
+		map[string]any{"id": int64(2)},
+		map[string]any{"id": int64(3)},
+	}, result.Interface())
+}
+
+func TestPrepare(t *testing.T) {
+	result, err := eval(t, `
+	insert := db.prepare("INSERT INTO users (name) VALUES (?)")
+	for _, name := range ["Dave", "Erin"] {
+		insert.exec(name)
+	}
+	insert.close()
+	find := db.prepare("SELECT id FROM users WHERE name = :name")
+	[find.query({name: "Dave"})[0].id, find.query({name: "Erin"})[0].id]
+	`)
+	require.Nil(t, err)
+	require.Equal(t, []any{int64(4), int64(5)}, result.Interface())
+}
+
+func TestQueryArgsRequired(t *testing.T) {
+	_, err := eval(t, `db.query()`)
+	require.NotNil(t, err)
+	require.Equal(t, "type error: sql.query() requires at least one argument", err.Error())
+}
@@ -0,0 +1,102 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/errz"
+	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/op"
+)
+
+const STMT object.Type = "sql.stmt"
+
+// Stmt is a prepared statement that may be executed many times with
+// different arguments.
+type Stmt struct {
+	stmt  *sql.Stmt
+	query string
+}
+
+func (s *Stmt) Type() object.Type {
+	return STMT
+}
+
+func (s *Stmt) Inspect() string {
+	return "sql.stmt(" + s.query + ")"
+}
+
+func (s *Stmt) Interface() interface{} {
+	return s.stmt
+}
+
+func (s *Stmt) IsTruthy() bool {
+	return s.stmt != nil
+}
+
+func (s *Stmt) Cost() int {
+	return 8
+}
+
+func (s *Stmt) MarshalJSON() ([]byte, error) {
+	return nil, errz.TypeErrorf("type error: unable to marshal %s", STMT)
+}
+
+func (s *Stmt) RunOperation(opType op.BinaryOpType, right object.Object) object.Object {
+	return object.TypeErrorf("type error: unsupported operation for %s: %v", STMT, opType)
+}
+
+func (s *Stmt) Equals(other object.Object) object.Object {
+	if other.Type() != STMT {
+		return object.False
+	}
+	return object.NewBool(s.stmt == other.(*Stmt).stmt)
+}
+
+func (s *Stmt) SetAttr(name string, value object.Object) error {
+	return object.TypeErrorf("type error: %s object has no attribute %q", STMT, name)
+}
+
+func (s *Stmt) GetAttr(name string) (object.Object, bool) {
+	switch name {
+	case "query":
+		return object.NewBuiltin("sql.stmt.query", func(ctx context.Context, args ...object.Object) object.Object {
+			rows, err := s.stmt.QueryContext(ctx, queryArgs(args)...)
+			if err != nil {
+				return object.Errorf("failed to query db: %w", err)
+			}
+			return readRows(ctx, rows)
+		}), true
+	case "exec":
+		return object.NewBuiltin("sql.stmt.exec", func(ctx context.Context, args ...object.Object) object.Object {
+			if _, err := s.stmt.ExecContext(ctx, queryArgs(args)...); err != nil {
+				return object.NewError(err)
+			}
+			return object.Nil
+		}), true
+	case "rows":
+		return object.NewBuiltin("sql.stmt.rows", func(ctx context.Context, args ...object.Object) object.Object {
+			rows, err := s.stmt.QueryContext(ctx, queryArgs(args)...)
+			if err != nil {
+				return object.Errorf("failed to query db: %w", err)
+			}
+			return NewRows(ctx, rows)
+		}), true
+	case "close":
+		return object.NewBuiltin("sql.stmt.close", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.Require("sql.stmt.close", 0, args); err != nil {
+				return err
+			}
+			if err := s.stmt.Close(); err != nil {
+				return object.NewError(err)
+			}
+			return object.Nil
+		}), true
+	}
+	return nil, false
+}
+
+func NewStmt(stmt *sql.Stmt, query string) *Stmt {
+	return &Stmt{stmt: stmt, query: query}
+}
@@ -0,0 +1,105 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/errz"
+	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/op"
+)
+
+const TX object.Type = "sql.tx"
+
+// Tx is a database transaction. Once it has been committed or rolled back,
+// further rollbacks are ignored so that a rollback may always be deferred.
+type Tx struct {
+	tx *sql.Tx
+}
+
+func (tx *Tx) Type() object.Type {
+	return TX
+}
+
+func (tx *Tx) Inspect() string {
+	return "sql.tx"
+}
+
+func (tx *Tx) Interface() interface{} {
+	return tx.tx
+}
+
+func (tx *Tx) IsTruthy() bool {
+	return tx.tx != nil
+}
+
+func (tx *Tx) Cost() int {
+	return 8
+}
+
+func (tx *Tx) MarshalJSON() ([]byte, error) {
+	return nil, errz.TypeErrorf("type error: unable to marshal %s", TX)
+}
+
+func (tx *Tx) RunOperation(opType op.BinaryOpType, right object.Object) object.Object {
+	return object.TypeErrorf("type error: unsupported operation for %s: %v", TX, opType)
+}
+
+func (tx *Tx) Equals(other object.Object) object.Object {
+	if other.Type() != TX {
+		return object.False
+	}
+	return object.NewBool(tx.tx == other.(*Tx).tx)
+}
+
+func (tx *Tx) SetAttr(name string, value object.Object) error {
+	return object.TypeErrorf("type error: %s object has no attribute %q", TX, name)
+}
+
+func (tx *Tx) GetAttr(name string) (object.Object, bool) {
+	switch name {
+	case "query":
+		return object.NewBuiltin("sql.tx.query", func(ctx context.Context, args ...object.Object) object.Object {
+			return query(ctx, tx.tx, "sql.tx.query", args)
+		}), true
+	case "exec":
+		return object.NewBuiltin("sql.tx.exec", func(ctx context.Context, args ...object.Object) object.Object {
+			return exec(ctx, tx.tx, "sql.tx.exec", args)
+		}), true
+	case "rows":
+		return object.NewBuiltin("sql.tx.rows", func(ctx context.Context, args ...object.Object) object.Object {
+			return queryRows(ctx, tx.tx, "sql.tx.rows", args)
+		}), true
+	case "prepare":
+		return object.NewBuiltin("sql.tx.prepare", func(ctx context.Context, args ...object.Object) object.Object {
+			return prepare(ctx, tx.tx, "sql.tx.prepare", args)
+		}), true
+	case "commit":
+		return object.NewBuiltin("sql.tx.commit", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.Require("sql.tx.commit", 0, args); err != nil {
+				return err
+			}
+			if err := tx.tx.Commit(); err != nil {
+				return object.NewError(err)
+			}
+			return object.Nil
+		}), true
+	case "rollback":
+		return object.NewBuiltin("sql.tx.rollback", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.Require("sql.tx.rollback", 0, args); err != nil {
+				return err
+			}
+			if err := tx.tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+				return object.NewError(err)
+			}
+			return object.Nil
+		}), true
+	}
+	return nil, false
+}
+
+func NewTx(tx *sql.Tx) *Tx {
+	return &Tx{tx: tx}
+}
@@ -0,0 +1,119 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/op"
+)
+
+const COUNTER object.Type = "sync.counter"
+
+// Counter is an integer that may be updated atomically by many goroutines.
+type Counter struct {
+	// Held by pointer for the same reason as the state of a WaitGroup
+	value *atomic.Int64
+}
+
+func (c *Counter) Type() object.Type {
+	return COUNTER
+}
+
+func (c *Counter) Inspect() string {
+	return fmt.Sprintf("%s(%d)", COUNTER, c.value.Load())
+}
+
+func (c *Counter) Interface() interface{} {
+	return c.value.Load()
+}
+
+func (c *Counter) IsTruthy() bool {
+	return c.value.Load() != 0
+}
+
+func (c *Counter) Cost() int {
+	return 8
+}
+
+func (c *Counter) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d", c.value.Load())), nil
+}
+
+func (c *Counter) RunOperation(opType op.BinaryOpType, right object.Object) object.Object {
+	return object.TypeErrorf("type error: unsupported operation for %s: %v", COUNTER, opType)
+}
+
+func (c *Counter) Equals(other object.Object) object.Object {
+	return object.NewBool(c == other)
+}
+
+func (c *Counter) SetAttr(name string, value object.Object) error {
+	return object.TypeErrorf("type error: %s object has no attribute %q", COUNTER, name)
+}
+
+func (c *Counter) GetAttr(name string) (object.Object, bool) {
+	switch name {
+	case "add":
+		return object.NewBuiltin("sync.counter.add", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.RequireRange("sync.counter.add", 0, 1, args); err != nil {
+				return err
+			}
+			delta := int64(1)
+			if len(args) == 1 {
+				var err *object.Error
+				if delta, err = object.AsInt(args[0]); err != nil {
+					return err
+				}
+			}
+			return object.NewInt(c.value.Add(delta))
+		}), true
+	case "get":
+		return object.NewBuiltin("sync.counter.get", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.Require("sync.counter.get", 0, args); err != nil {
+				return err
+			}
+			return object.NewInt(c.value.Load())
+		}), true
+	case "set":
+		return object.NewBuiltin("sync.counter.set", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.Require("sync.counter.set", 1, args); err != nil {
+				return err
+			}
+			value, err := object.AsInt(args[0])
+			if err != nil {
+				return err
+			}
+			return object.NewInt(c.value.Swap(value))
+		}), true
+	case "compare_and_swap":
+		return object.NewBuiltin("sync.counter.compare_and_swap", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.Require("sync.counter.compare_and_swap", 2, args); err != nil {
+				return err
+			}
+			old, err := object.AsInt(args[0])
+			if err != nil {
+				return err
+			}
+			value, err := object.AsInt(args[1])
+			if err != nil {
+				return err
+			}
+			return object.NewBool(c.value.CompareAndSwap(old, value))
+		}), true
+	}
+	return nil, false
+}
+
+// Value returns the current value of the counter.
+func (c *Counter) Value() int64 {
+	return c.value.Load()
+}
+
+func NewCounter(value int64) *Counter {
+	c := &Counter{value: &atomic.Int64{}}
+	c.value.Store(value)
+	return c
+}
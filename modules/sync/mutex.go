@@ -0,0 +1,115 @@
+package sync
+
+import (
+	"context"
+	"errors"
+
+	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/errz"
+	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/op"
+)
+
+const MUTEX object.Type = "sync.mutex"
+
+// Mutex is a mutual exclusion lock. Unlike a Go mutex, waiting for the lock
+// stops if the context is cancelled, so a deadlocked script still honors its
+// timeout.
+type Mutex struct {
+	sem *Semaphore
+}
+
+func (m *Mutex) Type() object.Type {
+	return MUTEX
+}
+
+func (m *Mutex) Inspect() string {
+	return string(MUTEX) + "()"
+}
+
+func (m *Mutex) Interface() interface{} {
+	return m
+}
+
+func (m *Mutex) IsTruthy() bool {
+	return true
+}
+
+func (m *Mutex) Cost() int {
+	return 8
+}
+
+func (m *Mutex) MarshalJSON() ([]byte, error) {
+	return nil, errz.TypeErrorf("type error: unable to marshal %s", MUTEX)
+}
+
+func (m *Mutex) RunOperation(opType op.BinaryOpType, right object.Object) object.Object {
+	return object.TypeErrorf("type error: unsupported operation for %s: %v", MUTEX, opType)
+}
+
+func (m *Mutex) Equals(other object.Object) object.Object {
+	return object.NewBool(m == other)
+}
+
+func (m *Mutex) SetAttr(name string, value object.Object) error {
+	return object.TypeErrorf("type error: %s object has no attribute %q", MUTEX, name)
+}
+
+func (m *Mutex) GetAttr(name string) (object.Object, bool) {
+	switch name {
+	case "lock":
+		return object.NewBuiltin("sync.mutex.lock", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.Require("sync.mutex.lock", 0, args); err != nil {
+				return err
+			}
+			if err := m.Lock(ctx); err != nil {
+				return object.NewError(err)
+			}
+			return object.Nil
+		}), true
+	case "try_lock":
+		return object.NewBuiltin("sync.mutex.try_lock", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.Require("sync.mutex.try_lock", 0, args); err != nil {
+				return err
+			}
+			return object.NewBool(m.TryLock())
+		}), true
+	case "unlock":
+		return object.NewBuiltin("sync.mutex.unlock", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.Require("sync.mutex.unlock", 0, args); err != nil {
+				return err
+			}
+			if err := m.Unlock(); err != nil {
+				return object.NewError(err)
+			}
+			return object.Nil
+		}), true
+	case "locked":
+		return object.NewBool(len(m.sem.slots) > 0), true
+	}
+	return nil, false
+}
+
+// Lock waits until the mutex is unlocked and locks it. An error is returned
+// if the context is cancelled first.
+func (m *Mutex) Lock(ctx context.Context) error {
+	return m.sem.Acquire(ctx)
+}
+
+// TryLock locks the mutex if it's unlocked, without waiting.
+func (m *Mutex) TryLock() bool {
+	return m.sem.TryAcquire()
+}
+
+// Unlock unlocks the mutex. As with a Go mutex, it may be unlocked by a
+// different goroutine than the one that locked it.
+func (m *Mutex) Unlock() error {
+	if err := m.sem.Release(); err != nil {
+		return errors.New("value error: unlock of unlocked mutex")
+	}
+	return nil
+}
+
+func NewMutex() *Mutex {
+	return &Mutex{sem: NewSemaphore(1)}
+}
@@ -0,0 +1,107 @@
+package sync
+
+import (
+	"context"
+	"sync"
+
+	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/errz"
+	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/op"
+)
+
+const ONCE object.Type = "sync.once"
+
+// Once calls a function only the first time it's asked to. Callers that ask
+// while the first call is running wait for it to finish.
+type Once struct {
+	// Held by pointer for the same reason as the state of a WaitGroup
+	state *onceState
+}
+
+type onceState struct {
+	once   sync.Once
+	mutex  sync.Mutex
+	done   bool
+	result object.Object
+}
+
+func (o *Once) Type() object.Type {
+	return ONCE
+}
+
+func (o *Once) Inspect() string {
+	return string(ONCE) + "()"
+}
+
+func (o *Once) Interface() interface{} {
+	return o
+}
+
+func (o *Once) IsTruthy() bool {
+	return true
+}
+
+func (o *Once) Cost() int {
+	return 8
+}
+
+func (o *Once) MarshalJSON() ([]byte, error) {
+	return nil, errz.TypeErrorf("type error: unable to marshal %s", ONCE)
+}
+
+func (o *Once) RunOperation(opType op.BinaryOpType, right object.Object) object.Object {
+	return object.TypeErrorf("type error: unsupported operation for %s: %v", ONCE, opType)
+}
+
+func (o *Once) Equals(other object.Object) object.Object {
+	return object.NewBool(o == other)
+}
+
+func (o *Once) SetAttr(name string, value object.Object) error {
+	return object.TypeErrorf("type error: %s object has no attribute %q", ONCE, name)
+}
+
+func (o *Once) GetAttr(name string) (object.Object, bool) {
+	switch name {
+	case "do":
+		return object.NewBuiltin("sync.once.do", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.RequireRange("sync.once.do", 1, 64, args); err != nil {
+				return err
+			}
+			if err := object.RequireCallable("sync.once.do", args[0]); err != nil {
+				return err
+			}
+			return o.Do(ctx, args[0], args[1:]...)
+		}), true
+	case "done":
+		o.state.mutex.Lock()
+		defer o.state.mutex.Unlock()
+		return object.NewBool(o.state.done), true
+	}
+	return nil, false
+}
+
+// Do calls the function with the given arguments if this is the first call to
+// Do. Every call returns the result of that first call, including the error
+// it raised, if any.
+func (o *Once) Do(ctx context.Context, fn object.Object, args ...object.Object) object.Object {
+	state := o.state
+	state.once.Do(func() {
+		result, err := object.Call(ctx, fn, args...)
+		if err != nil {
+			result = object.NewError(err)
+		}
+		state.mutex.Lock()
+		defer state.mutex.Unlock()
+		state.result = result
+		state.done = true
+	})
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	return state.result
+}
+
+func NewOnce() *Once {
+	return &Once{state: &onceState{}}
+}
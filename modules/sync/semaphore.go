@@ -0,0 +1,128 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/errz"
+	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/op"
+)
+
+const SEMAPHORE object.Type = "sync.semaphore"
+
+// Semaphore limits the number of holders of a resource. Acquiring waits until
+// a slot is free or the context is cancelled.
+type Semaphore struct {
+	slots chan struct{}
+}
+
+func (s *Semaphore) Type() object.Type {
+	return SEMAPHORE
+}
+
+func (s *Semaphore) Inspect() string {
+	return fmt.Sprintf("%s(%d)", SEMAPHORE, cap(s.slots))
+}
+
+func (s *Semaphore) Interface() interface{} {
+	return s
+}
+
+func (s *Semaphore) IsTruthy() bool {
+	return true
+}
+
+func (s *Semaphore) Cost() int {
+	return 8
+}
+
+func (s *Semaphore) MarshalJSON() ([]byte, error) {
+	return nil, errz.TypeErrorf("type error: unable to marshal %s", SEMAPHORE)
+}
+
+func (s *Semaphore) RunOperation(opType op.BinaryOpType, right object.Object) object.Object {
+	return object.TypeErrorf("type error: unsupported operation for %s: %v", SEMAPHORE, opType)
+}
+
+func (s *Semaphore) Equals(other object.Object) object.Object {
+	return object.NewBool(s == other)
+}
+
+func (s *Semaphore) SetAttr(name string, value object.Object) error {
+	return object.TypeErrorf("type error: %s object has no attribute %q", SEMAPHORE, name)
+}
+
+func (s *Semaphore) GetAttr(name string) (object.Object, bool) {
+	switch name {
+	case "acquire":
+		return object.NewBuiltin("sync.semaphore.acquire", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.Require("sync.semaphore.acquire", 0, args); err != nil {
+				return err
+			}
+			if err := s.Acquire(ctx); err != nil {
+				return object.NewError(err)
+			}
+			return object.Nil
+		}), true
+	case "try_acquire":
+		return object.NewBuiltin("sync.semaphore.try_acquire", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.Require("sync.semaphore.try_acquire", 0, args); err != nil {
+				return err
+			}
+			return object.NewBool(s.TryAcquire())
+		}), true
+	case "release":
+		return object.NewBuiltin("sync.semaphore.release", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.Require("sync.semaphore.release", 0, args); err != nil {
+				return err
+			}
+			if err := s.Release(); err != nil {
+				return object.NewError(err)
+			}
+			return object.Nil
+		}), true
+	case "size":
+		return object.NewInt(int64(cap(s.slots))), true
+	case "held":
+		return object.NewInt(int64(len(s.slots))), true
+	}
+	return nil, false
+}
+
+// Acquire waits until a slot is free and takes it. An error is returned if the
+// context is cancelled first.
+func (s *Semaphore) Acquire(ctx context.Context) error {
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryAcquire takes a slot if one is free, without waiting.
+func (s *Semaphore) TryAcquire() bool {
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release frees a slot taken by Acquire or TryAcquire.
+func (s *Semaphore) Release() error {
+	select {
+	case <-s.slots:
+		return nil
+	default:
+		return errors.New("value error: semaphore released more times than it was acquired")
+	}
+}
+
+func NewSemaphore(size int) *Semaphore {
+	return &Semaphore{slots: make(chan struct{}, size)}
+}
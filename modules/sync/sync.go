@@ -0,0 +1,67 @@
+package sync
+
+import (
+	"context"
+
+	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/object"
+)
+
+func MutexFunc(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("sync.mutex", 0, args); err != nil {
+		return err
+	}
+	return NewMutex()
+}
+
+func WaitGroupFunc(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("sync.wait_group", 0, args); err != nil {
+		return err
+	}
+	return NewWaitGroup()
+}
+
+func SemaphoreFunc(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("sync.semaphore", 1, args); err != nil {
+		return err
+	}
+	size, err := object.AsInt(args[0])
+	if err != nil {
+		return err
+	}
+	if size < 1 {
+		return object.Errorf("value error: sync.semaphore() size must be > 0 (%d given)", size)
+	}
+	return NewSemaphore(int(size))
+}
+
+func OnceFunc(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("sync.once", 0, args); err != nil {
+		return err
+	}
+	return NewOnce()
+}
+
+func CounterFunc(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("sync.counter", 0, 1, args); err != nil {
+		return err
+	}
+	var value int64
+	if len(args) == 1 {
+		var err *object.Error
+		if value, err = object.AsInt(args[0]); err != nil {
+			return err
+		}
+	}
+	return NewCounter(value)
+}
+
+func Module() *object.Module {
+	return object.NewBuiltinsModule("sync", map[string]object.Object{
+		"counter":    object.NewBuiltin("sync.counter", CounterFunc),
+		"mutex":      object.NewBuiltin("sync.mutex", MutexFunc),
+		"once":       object.NewBuiltin("sync.once", OnceFunc),
+		"semaphore":  object.NewBuiltin("sync.semaphore", SemaphoreFunc),
+		"wait_group": object.NewBuiltin("sync.wait_group", WaitGroupFunc),
+	})
+}
@@ -0,0 +1,159 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/itrn0/risor/builtins"
+	"github.com/itrn0/risor/compiler"
+	modTime "github.com/itrn0/risor/modules/time"
+	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/parser"
+	"github.com/itrn0/risor/vm"
+	"github.com/stretchr/testify/require"
+)
+
+// Runs Risor source with this module available as "sync", along with the time
+// module, and concurrency allowed.
+func run(ctx context.Context, t *testing.T, source string) (object.Object, error) {
+	t.Helper()
+	globals := map[string]any{"sync": Module(), "time": modTime.Module()}
+	for name, value := range builtins.Builtins() {
+		globals[name] = value
+	}
+	names := make([]string, 0, len(globals))
+	for name := range globals {
+		names = append(names, name)
+	}
+	ast, err := parser.Parse(ctx, source)
+	require.Nil(t, err)
+	code, err := compiler.Compile(ast, compiler.WithGlobalNames(names))
+	require.Nil(t, err)
+	machine := vm.New(code, vm.WithGlobals(globals), vm.WithConcurrency())
+	if err := machine.Run(ctx); err != nil {
+		return nil, err
+	}
+	result, exists := machine.TOS()
+	require.True(t, exists)
+	return result, nil
+}
+
+func TestSync(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected object.Object
+	}{
+		{`
+		mu := sync.mutex()
+		wg := sync.wait_group()
+		total := 0
+		func add(n) {
+			defer wg.done()
+			mu.lock()
+			defer mu.unlock()
+			total += n
+		}
+		for i := range 50 {
+			wg.add()
+			go add(i)
+		}
+		wg.wait()
+		[total, mu.locked, wg.count]
+		`, object.NewList([]object.Object{object.NewInt(1225), object.False, object.NewInt(0)})},
+		{`
+		c := sync.counter()
+		wg := sync.wait_group()
+		wg.add(20)
+		for i := range 20 {
+			go func() { c.add(2); wg.done() }()
+		}
+		wg.wait()
+		c.get()
+		`, object.NewInt(40)},
+		{`
+		sem := sync.semaphore(2)
+		sem.acquire()
+		[sem.try_acquire(), sem.try_acquire(), sem.held, sem.size]
+		`, object.NewList([]object.Object{object.True, object.False, object.NewInt(2), object.NewInt(2)})},
+		{`
+		sem := sync.semaphore(3)
+		active := sync.counter()
+		peak := sync.counter()
+		wg := sync.wait_group()
+		func work() {
+			defer wg.done()
+			sem.acquire()
+			defer sem.release()
+			n := active.add(1)
+			for {
+				p := peak.get()
+				if n <= p || peak.compare_and_swap(p, n) { break }
+			}
+			time.sleep(0.005)
+			active.add(-1)
+		}
+		wg.add(12)
+		for i := range 12 { go work() }
+		wg.wait()
+		peak.get() <= 3
+		`, object.True},
+		{`
+		once := sync.once()
+		calls := 0
+		func init(x) { calls++; return x * 2 }
+		[once.do(init, 1), once.do(init, 5), calls, once.done]
+		`, object.NewList([]object.Object{object.NewInt(2), object.NewInt(2), object.NewInt(1), object.True})},
+		{`
+		mu := sync.mutex()
+		[mu.try_lock(), mu.try_lock(), mu.unlock(), mu.try_lock()]
+		`, object.NewList([]object.Object{object.True, object.False, object.Nil, object.True})},
+		{`c := sync.counter(5); [c.set(7), c.compare_and_swap(6, 1), c.compare_and_swap(7, 1), c.get()]`,
+			object.NewList([]object.Object{object.NewInt(5), object.False, object.True, object.NewInt(1)})},
+		{`sync.wait_group().wait()`, object.Nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := run(context.Background(), t, tt.input)
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestSyncErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		errMsg string
+	}{
+		{`sync.mutex().unlock()`, "value error: unlock of unlocked mutex"},
+		{`sync.semaphore(1).release()`, "value error: semaphore released more times than it was acquired"},
+		{`sync.semaphore(0)`, "value error: sync.semaphore() size must be > 0 (0 given)"},
+		{`sync.wait_group().done()`, "value error: negative wait group counter"},
+		{`sync.once().do(1)`, "type error: sync.once.do() expected a function (int given)"},
+		{`o := sync.once(); try { o.do(func() { error("failed") }) } catch {}; o.do(func() { 1 })`, "failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := run(context.Background(), t, tt.input)
+			require.NotNil(t, err)
+			require.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestWaitHonorsContext(t *testing.T) {
+	tests := []string{
+		`wg := sync.wait_group(); wg.add(); wg.wait()`,
+		`mu := sync.mutex(); mu.lock(); mu.lock()`,
+		`sem := sync.semaphore(1); sem.acquire(); sem.acquire()`,
+	}
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			_, err := run(ctx, t, input)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/errz"
+	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/op"
+)
+
+const WAIT_GROUP object.Type = "sync.wait_group"
+
+// WaitGroup waits for a collection of tasks to finish. Waiting stops if the
+// context is cancelled.
+type WaitGroup struct {
+	// The state is held by pointer, since the VM copies objects of types it
+	// doesn't know when estimating their size
+	state *waitGroupState
+}
+
+type waitGroupState struct {
+	mutex sync.Mutex
+	count int64
+	// Closed when the count returns to zero. It's nil while the count is zero.
+	done chan struct{}
+}
+
+func (wg *WaitGroup) Type() object.Type {
+	return WAIT_GROUP
+}
+
+func (wg *WaitGroup) Inspect() string {
+	return fmt.Sprintf("%s(%d)", WAIT_GROUP, wg.Count())
+}
+
+func (wg *WaitGroup) Interface() interface{} {
+	return wg
+}
+
+func (wg *WaitGroup) IsTruthy() bool {
+	return true
+}
+
+func (wg *WaitGroup) Cost() int {
+	return 8
+}
+
+func (wg *WaitGroup) MarshalJSON() ([]byte, error) {
+	return nil, errz.TypeErrorf("type error: unable to marshal %s", WAIT_GROUP)
+}
+
+func (wg *WaitGroup) RunOperation(opType op.BinaryOpType, right object.Object) object.Object {
+	return object.TypeErrorf("type error: unsupported operation for %s: %v", WAIT_GROUP, opType)
+}
+
+func (wg *WaitGroup) Equals(other object.Object) object.Object {
+	return object.NewBool(wg == other)
+}
+
+func (wg *WaitGroup) SetAttr(name string, value object.Object) error {
+	return object.TypeErrorf("type error: %s object has no attribute %q", WAIT_GROUP, name)
+}
+
+func (wg *WaitGroup) GetAttr(name string) (object.Object, bool) {
+	switch name {
+	case "add":
+		return object.NewBuiltin("sync.wait_group.add", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.RequireRange("sync.wait_group.add", 0, 1, args); err != nil {
+				return err
+			}
+			delta := int64(1)
+			if len(args) == 1 {
+				var err *object.Error
+				if delta, err = object.AsInt(args[0]); err != nil {
+					return err
+				}
+			}
+			if err := wg.Add(delta); err != nil {
+				return object.NewError(err)
+			}
+			return object.Nil
+		}), true
+	case "done":
+		return object.NewBuiltin("sync.wait_group.done", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.Require("sync.wait_group.done", 0, args); err != nil {
+				return err
+			}
+			if err := wg.Add(-1); err != nil {
+				return object.NewError(err)
+			}
+			return object.Nil
+		}), true
+	case "wait":
+		return object.NewBuiltin("sync.wait_group.wait", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.Require("sync.wait_group.wait", 0, args); err != nil {
+				return err
+			}
+			if err := wg.Wait(ctx); err != nil {
+				return object.NewError(err)
+			}
+			return object.Nil
+		}), true
+	case "count":
+		return object.NewInt(wg.Count()), true
+	}
+	return nil, false
+}
+
+// Add adds delta, which may be negative, to the count of unfinished tasks.
+func (wg *WaitGroup) Add(delta int64) error {
+	wg.state.mutex.Lock()
+	defer wg.state.mutex.Unlock()
+	count := wg.state.count + delta
+	if count < 0 {
+		return errors.New("value error: negative wait group counter")
+	}
+	wg.state.count = count
+	if count > 0 && wg.state.done == nil {
+		wg.state.done = make(chan struct{})
+	} else if count == 0 && wg.state.done != nil {
+		close(wg.state.done)
+		wg.state.done = nil
+	}
+	return nil
+}
+
+// Count returns the number of unfinished tasks.
+func (wg *WaitGroup) Count() int64 {
+	wg.state.mutex.Lock()
+	defer wg.state.mutex.Unlock()
+	return wg.state.count
+}
+
+// Wait waits until the count of unfinished tasks is zero. An error is
+// returned if the context is cancelled first.
+func (wg *WaitGroup) Wait(ctx context.Context) error {
+	wg.state.mutex.Lock()
+	done := wg.state.done
+	wg.state.mutex.Unlock()
+	if done == nil {
+		return nil
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func NewWaitGroup() *WaitGroup {
+	return &WaitGroup{state: &waitGroupState{}}
+}
@@ -0,0 +1,214 @@
+package testing
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// WriteText writes a human readable report of the results, grouped by file.
+// Tests that failed, errored or were skipped are listed along with their
+// messages and output. With verbose, passing tests and their output are
+// listed too.
+func WriteText(w io.Writer, results []*Result, verbose bool) error {
+	for _, group := range groupByFile(results) {
+		var failed bool
+		var duration time.Duration
+		for _, r := range group.results {
+			writeTextResult(w, r, 0, verbose)
+			failed = failed || r.Failed()
+			duration += r.Duration
+		}
+		status := "ok  "
+		if failed {
+			status = "FAIL"
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%.3fs\n", status, group.file, duration.Seconds()); err != nil {
+			return err
+		}
+	}
+	s := Summarize(results)
+	_, err := fmt.Fprintf(w, "\n%d passed, %d failed, %d errored, %d skipped\n",
+		s.Passed, s.Failed, s.Errored, s.Skipped)
+	return err
+}
+
+func writeTextResult(w io.Writer, r *Result, depth int, verbose bool) {
+	if r.Status == Passed && !verbose {
+		return
+	}
+	indent := strings.Repeat("    ", depth)
+	fmt.Fprintf(w, "%s--- %s: %s (%.2fs)\n", indent, strings.ToUpper(string(r.Status)), r.Name, r.Duration.Seconds())
+	if r.Location != "" {
+		fmt.Fprintf(w, "%s    %s: %s\n", indent, r.Location, r.Message)
+	} else if r.Message != "" {
+		fmt.Fprintf(w, "%s    %s\n", indent, r.Message)
+	}
+	for _, line := range r.Output {
+		fmt.Fprintf(w, "%s    %s\n", indent, line)
+	}
+	for _, sub := range r.Subtests {
+		writeTextResult(w, sub, depth+1, verbose)
+	}
+}
+
+// WriteTAP writes the results in Test Anything Protocol (TAP) version 13
+// format. Subtests are reported as separate test points following their
+// parents.
+func WriteTAP(w io.Writer, results []*Result) error {
+	var all []*Result
+	for _, r := range results {
+		all = append(all, r.Flatten()...)
+	}
+	fmt.Fprintf(w, "TAP version 13\n1..%d\n", len(all))
+	for i, r := range all {
+		description := fmt.Sprintf("%s: %s", r.File, r.Name)
+		switch r.Status {
+		case Passed:
+			fmt.Fprintf(w, "ok %d - %s\n", i+1, description)
+		case Skipped:
+			fmt.Fprintf(w, "ok %d - %s # SKIP %s\n", i+1, description, r.Message)
+		default:
+			fmt.Fprintf(w, "not ok %d - %s\n", i+1, description)
+			fmt.Fprintf(w, "  ---\n")
+			fmt.Fprintf(w, "  status: %s\n", r.Status)
+			fmt.Fprintf(w, "  message: %q\n", r.Message)
+			if r.Location != "" {
+				fmt.Fprintf(w, "  at: %q\n", r.Location)
+			}
+			fmt.Fprintf(w, "  ...\n")
+		}
+		for _, line := range r.Output {
+			fmt.Fprintf(w, "# %s\n", line)
+		}
+	}
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the results as JUnit XML, with a test suite for each
+// file. Subtests are reported as separate test cases.
+func WriteJUnit(w io.Writer, results []*Result) error {
+	report := junitTestSuites{}
+	var total time.Duration
+	for _, group := range groupByFile(results) {
+		suite := junitTestSuite{Name: group.file}
+		var duration time.Duration
+		for _, r := range group.results {
+			duration += r.Duration
+			for _, r := range r.Flatten() {
+				suite.Cases = append(suite.Cases, junitCase(r))
+				suite.Tests++
+				switch r.Status {
+				case Failed:
+					suite.Failures++
+				case Errored:
+					suite.Errors++
+				case Skipped:
+					suite.Skipped++
+				}
+			}
+		}
+		suite.Time = seconds(duration)
+		total += duration
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+		report.Suites = append(report.Suites, suite)
+	}
+	report.Time = seconds(total)
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitCase(r *Result) junitTestCase {
+	c := junitTestCase{
+		Name:      r.Name,
+		Classname: r.File,
+		Time:      seconds(r.Duration),
+		SystemOut: strings.Join(r.Output, "\n"),
+	}
+	message := &junitMessage{Message: r.Message, Text: r.Message}
+	if r.Location != "" {
+		message.Text = r.Location + ": " + r.Message
+	}
+	switch r.Status {
+	case Failed:
+		c.Failure = message
+	case Errored:
+		c.Error = message
+	case Skipped:
+		c.Skipped = &junitMessage{Message: r.Message}
+	}
+	return c
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+type fileResults struct {
+	file    string
+	results []*Result
+}
+
+// Groups results by file, preserving the order in which files first appear.
+func groupByFile(results []*Result) []*fileResults {
+	var groups []*fileResults
+	index := map[string]*fileResults{}
+	for _, r := range results {
+		group, ok := index[r.File]
+		if !ok {
+			group = &fileResults{file: r.File}
+			index[r.File] = group
+			groups = append(groups, group)
+		}
+		group.results = append(group.results, r)
+	}
+	return groups
+}
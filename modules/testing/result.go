@@ -0,0 +1,107 @@
+package testing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/vm"
+)
+
+// Status is the outcome of a test.
+type Status string
+
+const (
+	Passed  Status = "pass"
+	Failed  Status = "fail"
+	Skipped Status = "skip"
+	Errored Status = "error"
+)
+
+// Fixtures maps fixture names to the functions that supply them.
+type Fixtures map[string]*object.Function
+
+// Result is the outcome of a test and its subtests.
+type Result struct {
+	// Name of the test. Subtest names are prefixed with the names of their
+	// parents, separated by a slash.
+	Name string
+
+	// File the test is defined in.
+	File string
+
+	Status Status
+
+	// Message explains why the test failed, errored or was skipped.
+	Message string
+
+	// Location in the source where the test stopped, if known.
+	Location string
+
+	// Output holds the messages logged by the test.
+	Output []string
+
+	Duration time.Duration
+
+	Subtests []*Result
+}
+
+// Failed returns true if the test failed or errored.
+func (r *Result) Failed() bool {
+	return r.Status == Failed || r.Status == Errored
+}
+
+// Flatten returns the result followed by the results of all its subtests,
+// depth first.
+func (r *Result) Flatten() []*Result {
+	results := []*Result{r}
+	for _, sub := range r.Subtests {
+		results = append(results, sub.Flatten()...)
+	}
+	return results
+}
+
+// Summary counts test results by status.
+type Summary struct {
+	Total   int
+	Passed  int
+	Failed  int
+	Skipped int
+	Errored int
+}
+
+// Summarize counts the given results by status. Subtests aren't counted.
+func Summarize(results []*Result) Summary {
+	var s Summary
+	for _, r := range results {
+		s.Total++
+		switch r.Status {
+		case Passed:
+			s.Passed++
+		case Failed:
+			s.Failed++
+		case Skipped:
+			s.Skipped++
+		case Errored:
+			s.Errored++
+		}
+	}
+	return s
+}
+
+// Returns the source location of an error raised during evaluation, if any.
+func errorLocation(err error) string {
+	var runtimeErr *vm.RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		return ""
+	}
+	loc := runtimeErr.Location()
+	if loc.Line == 0 {
+		return ""
+	}
+	if loc.File == "" {
+		return fmt.Sprintf("%d:%d", loc.Line, loc.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", loc.File, loc.Line, loc.Column)
+}
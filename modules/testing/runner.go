@@ -0,0 +1,132 @@
+package testing
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itrn0/risor/compiler"
+	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/vm"
+)
+
+const (
+	testPrefix    = "test_"
+	fixturePrefix = "fixture_"
+)
+
+// File is a compiled test file.
+type File struct {
+	Path string
+	Code *compiler.Code
+}
+
+// Tests returns the names of the tests in the file, in the order they're
+// declared. Tests are global functions whose names begin with "test_".
+func (f *File) Tests() []string {
+	var names []string
+	for _, name := range f.Code.GlobalNames() {
+		if strings.HasPrefix(name, testPrefix) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Runner runs the tests in compiled Risor files. Each test runs in a new VM
+// which first evaluates the top level of its file, so tests can't affect each
+// other through global state.
+type Runner struct {
+	// VMOpts are used to create the VM for each test.
+	VMOpts []vm.Option
+
+	// Parallel is the maximum number of tests to run at once. Values less than
+	// one are treated as one.
+	Parallel int
+
+	// Filter selects the tests to run by name. All tests run if it's nil.
+	Filter *regexp.Regexp
+}
+
+type testCase struct {
+	file *File
+	name string
+}
+
+// Run runs the tests in the given files and returns their results, in the
+// order the tests appear in the files.
+func (r *Runner) Run(ctx context.Context, files []*File) []*Result {
+	var cases []testCase
+	for _, file := range files {
+		for _, name := range file.Tests() {
+			if r.Filter == nil || r.Filter.MatchString(name) {
+				cases = append(cases, testCase{file: file, name: name})
+			}
+		}
+	}
+	parallel := r.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	results := make([]*Result, len(cases))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				results[index] = r.runTest(ctx, cases[index].file, cases[index].name)
+			}
+		}()
+	}
+	for i := range cases {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+func (r *Runner) runTest(ctx context.Context, file *File, name string) *Result {
+	t := NewT(name, file.Path)
+	// The duration includes running the file, so that every result has one,
+	// including those of tests that fail before their function is called
+	start := time.Now()
+	defer func() {
+		t.result.Duration = time.Since(start)
+	}()
+	// Runtime errors are needed to report where a test failed
+	opts := append([]vm.Option{vm.WithRuntimeErrors()}, r.VMOpts...)
+	machine := vm.New(file.Code, opts...)
+	if err := machine.Run(ctx); err != nil {
+		t.record(fmt.Errorf("setup: %w", err))
+		return t.result
+	}
+	obj, err := machine.Get(name)
+	if err != nil {
+		t.record(err)
+		return t.result
+	}
+	fn, ok := obj.(*object.Function)
+	if !ok {
+		t.record(fmt.Errorf("test error: %s is not a function (got %s)", name, obj.Type()))
+		return t.result
+	}
+	fixtures := Fixtures{}
+	for _, global := range machine.GlobalNames() {
+		if !strings.HasPrefix(global, fixturePrefix) {
+			continue
+		}
+		if obj, err := machine.Get(global); err == nil {
+			if fixture, ok := obj.(*object.Function); ok {
+				fixtures[strings.TrimPrefix(global, fixturePrefix)] = fixture
+			}
+		}
+	}
+	t.Run(ctx, machine.Call, fn, fixtures)
+	return t.result
+}
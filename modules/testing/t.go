@@ -0,0 +1,349 @@
+package testing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/errz"
+	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/op"
+)
+
+const T_TYPE object.Type = "testing.t"
+
+// Stops a test function once it has failed or been skipped. These errors are
+// fatal so that they can't be caught by a try statement within the test.
+type stopError struct {
+	status  Status
+	message string
+}
+
+func (e *stopError) Error() string {
+	return e.message
+}
+
+func (e *stopError) IsFatal() bool {
+	return true
+}
+
+// T is passed to Risor test functions as their first argument. It's used to
+// make assertions, log messages, run subtests and register cleanup functions.
+type T struct {
+	mu       sync.Mutex
+	result   *Result
+	cleanups []object.Object
+}
+
+func (t *T) Type() object.Type {
+	return T_TYPE
+}
+
+func (t *T) Inspect() string {
+	return fmt.Sprintf("testing.t(%s)", t.result.Name)
+}
+
+func (t *T) Interface() interface{} {
+	return t.result
+}
+
+func (t *T) IsTruthy() bool {
+	return true
+}
+
+func (t *T) Cost() int {
+	return 8
+}
+
+func (t *T) MarshalJSON() ([]byte, error) {
+	return nil, errz.TypeErrorf("type error: unable to marshal %s", T_TYPE)
+}
+
+func (t *T) RunOperation(opType op.BinaryOpType, right object.Object) object.Object {
+	return object.TypeErrorf("type error: unsupported operation for %s: %v", T_TYPE, opType)
+}
+
+func (t *T) Equals(other object.Object) object.Object {
+	return object.NewBool(t == other)
+}
+
+func (t *T) SetAttr(name string, value object.Object) error {
+	return object.TypeErrorf("type error: %s object has no attribute %q", T_TYPE, name)
+}
+
+func (t *T) GetAttr(name string) (object.Object, bool) {
+	switch name {
+	case "name":
+		return object.NewBuiltin("testing.t.name", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.Require("testing.t.name", 0, args); err != nil {
+				return err
+			}
+			return object.NewString(t.result.Name)
+		}), true
+	case "log":
+		return object.NewBuiltin("testing.t.log", func(ctx context.Context, args ...object.Object) object.Object {
+			t.log(args)
+			return object.Nil
+		}), true
+	case "fail":
+		return object.NewBuiltin("testing.t.fail", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.RequireRange("testing.t.fail", 0, 1, args); err != nil {
+				return err
+			}
+			return t.stop(Failed, message(args, "test failed"))
+		}), true
+	case "skip":
+		return object.NewBuiltin("testing.t.skip", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.RequireRange("testing.t.skip", 0, 1, args); err != nil {
+				return err
+			}
+			return t.stop(Skipped, message(args, "test skipped"))
+		}), true
+	case "assert":
+		return object.NewBuiltin("testing.t.assert", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.RequireRange("testing.t.assert", 1, 2, args); err != nil {
+				return err
+			}
+			if !args[0].IsTruthy() {
+				return t.stop(Failed, message(args[1:], "assertion failed"))
+			}
+			return object.Nil
+		}), true
+	case "assert_equal":
+		return object.NewBuiltin("testing.t.assert_equal", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.RequireRange("testing.t.assert_equal", 2, 3, args); err != nil {
+				return err
+			}
+			actual, expected := args[0], args[1]
+			if !actual.Equals(expected).IsTruthy() {
+				return t.stop(Failed, message(args[2:],
+					fmt.Sprintf("expected %s, got %s", expected.Inspect(), actual.Inspect())))
+			}
+			return object.Nil
+		}), true
+	case "assert_not_equal":
+		return object.NewBuiltin("testing.t.assert_not_equal", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.RequireRange("testing.t.assert_not_equal", 2, 3, args); err != nil {
+				return err
+			}
+			actual, unexpected := args[0], args[1]
+			if actual.Equals(unexpected).IsTruthy() {
+				return t.stop(Failed, message(args[2:],
+					fmt.Sprintf("expected a value other than %s", unexpected.Inspect())))
+			}
+			return object.Nil
+		}), true
+	case "assert_error":
+		return object.NewBuiltin("testing.t.assert_error", t.assertError), true
+	case "run":
+		return object.NewBuiltin("testing.t.run", t.run), true
+	case "cleanup":
+		return object.NewBuiltin("testing.t.cleanup", func(ctx context.Context, args ...object.Object) object.Object {
+			if err := arg.Require("testing.t.cleanup", 1, args); err != nil {
+				return err
+			}
+			if _, ok := args[0].(*object.Function); !ok {
+				if _, ok := args[0].(object.Callable); !ok {
+					return object.TypeErrorf("type error: testing.t.cleanup() expected a function (%s given)", args[0].Type())
+				}
+			}
+			t.mu.Lock()
+			t.cleanups = append(t.cleanups, args[0])
+			t.mu.Unlock()
+			return object.Nil
+		}), true
+	}
+	return nil, false
+}
+
+// Calls a function that is expected to raise an error. The test fails if it
+// doesn't, or if the error message doesn't contain the given substring.
+func (t *T) assertError(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.RequireRange("testing.t.assert_error", 1, 2, args); err != nil {
+		return err
+	}
+	fn, ok := args[0].(*object.Function)
+	if !ok {
+		return object.TypeErrorf("type error: testing.t.assert_error() expected a function (%s given)", args[0].Type())
+	}
+	var substr string
+	if len(args) == 2 {
+		s, err := object.AsString(args[1])
+		if err != nil {
+			return err
+		}
+		substr = s
+	}
+	callFunc, found := object.GetCallFunc(ctx)
+	if !found {
+		return object.EvalErrorf("eval error: context did not contain a call function")
+	}
+	_, err := callFunc(ctx, fn, nil)
+	if err == nil {
+		return t.stop(Failed, "expected an error")
+	}
+	var stop *stopError
+	if errors.As(err, &stop) {
+		return object.NewError(stop)
+	}
+	if !strings.Contains(err.Error(), substr) {
+		return t.stop(Failed, fmt.Sprintf("expected an error containing %q, got %q", substr, err.Error()))
+	}
+	return object.NewError(err).WithRaised(false)
+}
+
+// Runs a subtest. The subtest fails its parent if it fails, but doesn't stop
+// it. Returns true if the subtest passed or was skipped.
+func (t *T) run(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("testing.t.run", 2, args); err != nil {
+		return err
+	}
+	name, err := object.AsString(args[0])
+	if err != nil {
+		return err
+	}
+	fn, ok := args[1].(*object.Function)
+	if !ok {
+		return object.TypeErrorf("type error: testing.t.run() expected a function (%s given)", args[1].Type())
+	}
+	callFunc, found := object.GetCallFunc(ctx)
+	if !found {
+		return object.EvalErrorf("eval error: context did not contain a call function")
+	}
+	sub := NewT(t.result.Name+"/"+name, t.result.File)
+	sub.Run(ctx, callFunc, fn, nil)
+	t.mu.Lock()
+	t.result.Subtests = append(t.result.Subtests, sub.result)
+	if sub.result.Status == Failed || sub.result.Status == Errored {
+		if t.result.Status == Passed {
+			t.result.Status = Failed
+			t.result.Message = "subtest failed"
+		}
+	}
+	t.mu.Unlock()
+	return object.NewBool(sub.result.Status != Failed && sub.result.Status != Errored)
+}
+
+func (t *T) log(args []object.Object) {
+	values := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case *object.String:
+			values = append(values, arg.Value())
+		default:
+			values = append(values, arg.Inspect())
+		}
+	}
+	t.mu.Lock()
+	t.result.Output = append(t.result.Output, strings.Join(values, " "))
+	t.mu.Unlock()
+}
+
+func (t *T) stop(status Status, message string) object.Object {
+	return object.NewError(&stopError{status: status, message: message})
+}
+
+// Returns the message given as the optional argument to an assertion, or the
+// default message if there isn't one.
+func message(args []object.Object, defaultMessage string) string {
+	if len(args) == 0 {
+		return defaultMessage
+	}
+	if s, ok := args[0].(*object.String); ok {
+		return s.Value()
+	}
+	return args[0].Inspect()
+}
+
+// Run runs fn as the body of the test, recording its outcome in the test's
+// result. The test is passed as the first argument to fn, unless fn takes no
+// parameters. Each further parameter of fn is supplied by the fixture of the
+// same name, which is called with the test if it takes a parameter. Cleanup
+// functions registered by the test and its fixtures are called in reverse
+// order once fn returns.
+func (t *T) Run(ctx context.Context, call object.CallFunc, fn *object.Function, fixtures Fixtures) {
+	start := time.Now()
+	defer func() {
+		t.result.Duration = time.Since(start)
+	}()
+	err := t.call(ctx, call, fn, fixtures)
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		if cleanupErr := callObject(ctx, call, t.cleanups[i], nil); cleanupErr != nil && err == nil {
+			err = fmt.Errorf("cleanup: %w", cleanupErr)
+		}
+	}
+	t.record(err)
+}
+
+func (t *T) call(ctx context.Context, call object.CallFunc, fn *object.Function, fixtures Fixtures) error {
+	params := fn.Parameters()
+	if len(params) == 0 {
+		_, err := call(ctx, fn, nil)
+		return err
+	}
+	args := []object.Object{t}
+	for _, name := range params[1:] {
+		fixture, ok := fixtures[name]
+		if !ok {
+			return fmt.Errorf("test error: fixture %q not found", name)
+		}
+		var fixtureArgs []object.Object
+		if len(fixture.Parameters()) > 0 {
+			fixtureArgs = []object.Object{t}
+		}
+		value, err := call(ctx, fixture, fixtureArgs)
+		if err != nil {
+			return fmt.Errorf("fixture %s: %w", name, err)
+		}
+		args = append(args, value)
+	}
+	_, err := call(ctx, fn, args)
+	return err
+}
+
+func callObject(ctx context.Context, call object.CallFunc, fn object.Object, args []object.Object) error {
+	switch fn := fn.(type) {
+	case *object.Function:
+		_, err := call(ctx, fn, args)
+		return err
+	case object.Callable:
+		if result, ok := fn.Call(ctx, args...).(*object.Error); ok && result.IsRaised() {
+			return result.Value()
+		}
+	}
+	return nil
+}
+
+// Records the outcome of the test given the error that stopped it, if any.
+func (t *T) record(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err == nil {
+		return
+	}
+	var stop *stopError
+	if errors.As(err, &stop) {
+		t.result.Status = stop.status
+		t.result.Message = stop.message
+	} else {
+		t.result.Status = Errored
+		t.result.Message = err.Error()
+	}
+	if t.result.Status != Skipped {
+		t.result.Location = errorLocation(err)
+	}
+}
+
+// Result returns the result of the test.
+func (t *T) Result() *Result {
+	return t.result
+}
+
+// NewT returns a new test with the given name, defined in the given file.
+func NewT(name, file string) *T {
+	return &T{result: &Result{Name: name, File: file, Status: Passed}}
+}
@@ -0,0 +1,255 @@
+package testing
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/itrn0/risor/builtins"
+	"github.com/itrn0/risor/compiler"
+	"github.com/itrn0/risor/parser"
+	"github.com/itrn0/risor/vm"
+	"github.com/stretchr/testify/require"
+)
+
+const source = `
+counter := 0
+
+func fixture_numbers(t) {
+	t.cleanup(func() { t.log("cleaned up") })
+	return [1, 2, 3]
+}
+
+func test_pass(t) {
+	counter++
+	t.assert_equal(counter, 1)
+	t.assert_not_equal(counter, 2)
+	t.assert(counter > 0)
+}
+
+func test_isolated(t) {
+	counter++
+	t.assert_equal(counter, 1, "globals are shared between tests")
+}
+
+func test_fixture(t, numbers) {
+	t.log("got", numbers)
+	t.assert_equal(len(numbers), 3)
+}
+
+func test_fail(t) {
+	t.assert_equal(1 + 1, 3)
+}
+
+func test_uncatchable(t) {
+	try { t.fail("stop") } catch e { }
+	t.fail("unreachable")
+}
+
+func test_subtests(t) {
+	t.assert(t.run("one", func(t) { t.assert(true) }))
+	t.assert(!t.run("two", func(t) { t.fail("nope") }))
+	t.run("three", func(t) { t.skip("later") })
+}
+
+func test_error(t) {
+	{}.missing
+}
+
+func test_assert_error(t) {
+	err := t.assert_error(func() { error("kaboom") }, "boom")
+	t.assert_equal(err.message(), "kaboom")
+}
+
+func test_skip(t) {
+	t.skip("not ready")
+}
+
+func test_missing_fixture(t, nope) {}
+`
+
+func compile(t *testing.T, path, source string) *File {
+	t.Helper()
+	ast, err := parser.Parse(context.Background(), source, parser.WithFile(path))
+	require.Nil(t, err)
+	code, err := compiler.Compile(ast, compiler.WithGlobalNames(builtinNames()))
+	require.Nil(t, err)
+	return &File{Path: path, Code: code}
+}
+
+func builtinNames() []string {
+	var names []string
+	for name := range builtins.Builtins() {
+		names = append(names, name)
+	}
+	return names
+}
+
+func newRunner() *Runner {
+	globals := map[string]any{}
+	for name, value := range builtins.Builtins() {
+		globals[name] = value
+	}
+	return &Runner{VMOpts: []vm.Option{vm.WithGlobals(globals)}}
+}
+
+func resultsByName(results []*Result) map[string]*Result {
+	byName := map[string]*Result{}
+	for _, r := range results {
+		for _, r := range r.Flatten() {
+			byName[r.Name] = r
+		}
+	}
+	return byName
+}
+
+func TestRunner(t *testing.T) {
+	file := compile(t, "example_test.risor", source)
+	require.Equal(t, []string{
+		"test_pass", "test_isolated", "test_fixture", "test_fail", "test_uncatchable",
+		"test_subtests", "test_error", "test_assert_error", "test_skip", "test_missing_fixture",
+	}, file.Tests())
+
+	runner := newRunner()
+	runner.Parallel = 4
+	results := runner.Run(context.Background(), []*File{file})
+	require.Len(t, results, 10)
+	require.Equal(t, "test_pass", results[0].Name)
+	require.Equal(t, "test_missing_fixture", results[9].Name)
+	for _, r := range results {
+		require.Positive(t, r.Duration, r.Name)
+	}
+
+	byName := resultsByName(results)
+	tests := []struct {
+		name     string
+		status   Status
+		message  string
+		location string
+	}{
+		{"test_pass", Passed, "", ""},
+		{"test_isolated", Passed, "", ""},
+		{"test_fixture", Passed, "", ""},
+		{"test_fail", Failed, "expected 3, got 2", "example_test.risor:27:3"},
+		{"test_uncatchable", Failed, "stop", "example_test.risor:31:9"},
+		{"test_subtests", Failed, "subtest failed", ""},
+		{"test_subtests/one", Passed, "", ""},
+		{"test_subtests/two", Failed, "nope", ""},
+		{"test_subtests/three", Skipped, "later", ""},
+		{"test_error", Errored, `type error: attribute "missing" not found on map object`, "example_test.risor:42:4"},
+		{"test_assert_error", Passed, "", ""},
+		{"test_skip", Skipped, "not ready", ""},
+		{"test_missing_fixture", Errored, `test error: fixture "nope" not found`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, ok := byName[tt.name]
+			require.True(t, ok)
+			require.Equal(t, tt.status, r.Status)
+			require.Equal(t, tt.message, r.Message)
+			require.Equal(t, tt.location, r.Location)
+			require.Equal(t, "example_test.risor", r.File)
+		})
+	}
+	require.Equal(t, []string{"got [1, 2, 3]", "cleaned up"}, byName["test_fixture"].Output)
+	require.Equal(t, Summary{Total: 10, Passed: 4, Failed: 3, Skipped: 1, Errored: 2}, Summarize(results))
+}
+
+func TestRunnerFilter(t *testing.T) {
+	file := compile(t, "example_test.risor", source)
+	runner := newRunner()
+	runner.Filter = regexp.MustCompile("^test_(pass|skip)$")
+	results := runner.Run(context.Background(), []*File{file})
+	require.Len(t, results, 2)
+	require.Equal(t, "test_pass", results[0].Name)
+	require.Equal(t, "test_skip", results[1].Name)
+}
+
+func TestRunnerSetupError(t *testing.T) {
+	file := compile(t, "setup_test.risor", "x := {}.missing\nfunc test_a(t) {}")
+	results := newRunner().Run(context.Background(), []*File{file})
+	require.Len(t, results, 1)
+	require.Equal(t, Errored, results[0].Status)
+	require.Equal(t, `setup: type error: attribute "missing" not found on map object`, results[0].Message)
+	require.Positive(t, results[0].Duration)
+}
+
+var reportResults = []*Result{
+	{Name: "test_a", File: "a_test.risor", Status: Passed, Output: []string{"hello"}},
+	{Name: "test_b", File: "a_test.risor", Status: Failed, Message: "expected 1, got 2", Location: "a_test.risor:5:3",
+		Subtests: []*Result{{Name: "test_b/x", File: "a_test.risor", Status: Skipped, Message: "later"}}},
+	{Name: "test_c", File: "b_test.risor", Status: Errored, Message: "kaboom"},
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	require.Nil(t, WriteText(&buf, reportResults, false))
+	require.Equal(t, `--- FAIL: test_b (0.00s)
+    a_test.risor:5:3: expected 1, got 2
+    --- SKIP: test_b/x (0.00s)
+        later
+FAIL	a_test.risor	0.000s
+--- ERROR: test_c (0.00s)
+    kaboom
+FAIL	b_test.risor	0.000s
+
+1 passed, 1 failed, 1 errored, 0 skipped
+`, buf.String())
+
+	buf.Reset()
+	require.Nil(t, WriteText(&buf, reportResults[:1], true))
+	require.Equal(t, `--- PASS: test_a (0.00s)
+    hello
+ok  	a_test.risor	0.000s
+
+1 passed, 0 failed, 0 errored, 0 skipped
+`, buf.String())
+}
+
+func TestWriteTAP(t *testing.T) {
+	var buf bytes.Buffer
+	require.Nil(t, WriteTAP(&buf, reportResults))
+	require.Equal(t, `TAP version 13
+1..4
+ok 1 - a_test.risor: test_a
+# hello
+not ok 2 - a_test.risor: test_b
+  ---
+  status: fail
+  message: "expected 1, got 2"
+  at: "a_test.risor:5:3"
+  ...
+ok 3 - a_test.risor: test_b/x # SKIP later
+not ok 4 - b_test.risor: test_c
+  ---
+  status: error
+  message: "kaboom"
+  ...
+`, buf.String())
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	require.Nil(t, WriteJUnit(&buf, reportResults))
+	require.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="4" failures="1" errors="1" skipped="1" time="0.000">
+  <testsuite name="a_test.risor" tests="3" failures="1" errors="0" skipped="1" time="0.000">
+    <testcase name="test_a" classname="a_test.risor" time="0.000">
+      <system-out>hello</system-out>
+    </testcase>
+    <testcase name="test_b" classname="a_test.risor" time="0.000">
+      <failure message="expected 1, got 2">a_test.risor:5:3: expected 1, got 2</failure>
+    </testcase>
+    <testcase name="test_b/x" classname="a_test.risor" time="0.000">
+      <skipped message="later"></skipped>
+    </testcase>
+  </testsuite>
+  <testsuite name="b_test.risor" tests="1" failures="0" errors="1" skipped="0" time="0.000">
+    <testcase name="test_c" classname="b_test.risor" time="0.000">
+      <error message="kaboom">kaboom</error>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, buf.String())
+}
@@ -0,0 +1,144 @@
+package toml
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/builtins"
+	"github.com/itrn0/risor/object"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Converts a decoded TOML value to a Risor object. Local dates and times,
+// which have no time zone, are represented as strings in TOML syntax.
+func fromTOML(value any) object.Object {
+	switch value := value.(type) {
+	case toml.LocalDate:
+		return object.NewString(value.String())
+	case toml.LocalTime:
+		return object.NewString(value.String())
+	case toml.LocalDateTime:
+		return object.NewString(value.String())
+	case []any:
+		items := make([]object.Object, 0, len(value))
+		for _, item := range value {
+			items = append(items, fromTOML(item))
+		}
+		return object.NewList(items)
+	case map[string]any:
+		items := make(map[string]object.Object, len(value))
+		for k, v := range value {
+			items[k] = fromTOML(v)
+		}
+		return object.NewMap(items)
+	default:
+		return object.FromGoType(value)
+	}
+}
+
+func unmarshal(data []byte) (object.Object, error) {
+	var doc map[string]any
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return fromTOML(doc), nil
+}
+
+// Returns the path of a nil value within the object, if there is one. TOML
+// has no null, and nil values would otherwise be dropped without an error.
+func findNil(obj object.Object, path string) (string, bool) {
+	switch obj := obj.(type) {
+	case *object.NilType:
+		return path, true
+	case *object.Map:
+		for _, key := range obj.SortedKeys() {
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			if nilPath, found := findNil(obj.Get(key), keyPath); found {
+				return nilPath, true
+			}
+		}
+	case *object.List:
+		for i, item := range obj.Value() {
+			if nilPath, found := findNil(item, fmt.Sprintf("%s[%d]", path, i)); found {
+				return nilPath, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Encodes a map as a TOML document, with the keys of each table sorted.
+func marshal(funcName string, obj object.Object) ([]byte, *object.Error) {
+	if _, ok := obj.(*object.Map); !ok {
+		return nil, object.TypeErrorf("type error: %s() expected a map (%s given)", funcName, obj.Type())
+	}
+	if path, found := findNil(obj, ""); found {
+		return nil, object.Errorf("value error: %s() can't encode nil value at %q: toml has no null", funcName, path)
+	}
+	data, err := toml.Marshal(obj.Interface())
+	if err != nil {
+		return nil, object.Errorf("value error: %s() failed: %s", funcName, err)
+	}
+	return data, nil
+}
+
+func Unmarshal(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("toml.unmarshal", 1, args); err != nil {
+		return err
+	}
+	data, err := object.AsBytes(args[0])
+	if err != nil {
+		return err
+	}
+	result, decodeErr := unmarshal(data)
+	if decodeErr != nil {
+		return object.Errorf("value error: toml.unmarshal failed with: %s", decodeErr.Error())
+	}
+	return result
+}
+
+func Marshal(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("toml.marshal", 1, args); err != nil {
+		return err
+	}
+	data, err := marshal("toml.marshal", args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewString(string(data))
+}
+
+func encodeTOML(ctx context.Context, obj object.Object) object.Object {
+	data, err := marshal("encode", obj)
+	if err != nil {
+		return err
+	}
+	return object.NewString(string(data))
+}
+
+func decodeTOML(ctx context.Context, obj object.Object) object.Object {
+	data, err := object.AsBytes(obj)
+	if err != nil {
+		return err
+	}
+	result, decodeErr := unmarshal(data)
+	if decodeErr != nil {
+		return object.NewError(decodeErr)
+	}
+	return result
+}
+
+func init() {
+	builtins.RegisterCodec("toml", &builtins.Codec{Encode: encodeTOML, Decode: decodeTOML})
+}
+
+func Module() *object.Module {
+	return object.NewBuiltinsModule("toml", map[string]object.Object{
+		"marshal":   object.NewBuiltin("toml.marshal", Marshal),
+		"unmarshal": object.NewBuiltin("toml.unmarshal", Unmarshal),
+	})
+}
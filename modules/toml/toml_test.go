@@ -0,0 +1,106 @@
+package toml
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/itrn0/risor/builtins"
+	"github.com/itrn0/risor/object"
+	"github.com/stretchr/testify/require"
+)
+
+const document = `title = "example"
+created = 2024-03-01T12:00:00Z
+released = 2024-03-01
+
+[server]
+host = "localhost"
+ports = [8080, 8081]
+ratio = 0.5
+enabled = true
+
+[[users]]
+name = "a"
+
+[[users]]
+name = "b"
+`
+
+func TestUnmarshal(t *testing.T) {
+	ctx := context.Background()
+	result := Unmarshal(ctx, object.NewString(document))
+	require.Equal(t, object.NewMap(map[string]object.Object{
+		"title":    object.NewString("example"),
+		"created":  object.NewTime(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)),
+		"released": object.NewString("2024-03-01"),
+		"server": object.NewMap(map[string]object.Object{
+			"host":    object.NewString("localhost"),
+			"ports":   object.NewList([]object.Object{object.NewInt(8080), object.NewInt(8081)}),
+			"ratio":   object.NewFloat(0.5),
+			"enabled": object.True,
+		}),
+		"users": object.NewList([]object.Object{
+			object.NewMap(map[string]object.Object{"name": object.NewString("a")}),
+			object.NewMap(map[string]object.Object{"name": object.NewString("b")}),
+		}),
+	}), result)
+
+	result = Unmarshal(ctx, object.NewString("a = "))
+	errObj, ok := result.(*object.Error)
+	require.True(t, ok)
+	require.Contains(t, errObj.Message().Value(), "value error: toml.unmarshal failed with:")
+}
+
+func TestMarshal(t *testing.T) {
+	ctx := context.Background()
+	value := object.NewMap(map[string]object.Object{
+		"name":  object.NewString("api"),
+		"count": object.NewInt(3),
+		"db": object.NewMap(map[string]object.Object{
+			"user": object.NewString("admin"),
+			"host": object.NewString("db.local"),
+		}),
+		"tags": object.NewStringList([]string{"x", "y"}),
+	})
+	result := Marshal(ctx, value)
+	require.Equal(t, object.NewString(`count = 3
+name = 'api'
+tags = ['x', 'y']
+
+[db]
+host = 'db.local'
+user = 'admin'
+`), result)
+
+	// Marshaling and unmarshaling returns the original value
+	require.Equal(t, value, Unmarshal(ctx, result))
+
+	require.Equal(t, object.TypeErrorf("type error: toml.marshal() expected a map (list given)"),
+		Marshal(ctx, object.NewList(nil)))
+}
+
+func TestCodec(t *testing.T) {
+	ctx := context.Background()
+	value := object.NewMap(map[string]object.Object{"a": object.NewInt(1)})
+	encoded := builtins.Encode(ctx, value, object.NewString("toml"))
+	require.Equal(t, object.NewString("a = 1\n"), encoded)
+	require.Equal(t, value, builtins.Decode(ctx, encoded, object.NewString("toml")))
+}
+
+func TestMarshalNil(t *testing.T) {
+	ctx := context.Background()
+	value := object.NewMap(map[string]object.Object{"a": object.Nil})
+	require.Equal(t, object.Errorf(`value error: toml.marshal() can't encode nil value at "a": toml has no null`),
+		Marshal(ctx, value))
+	require.Equal(t, object.Errorf(`value error: encode() can't encode nil value at "a": toml has no null`),
+		builtins.Encode(ctx, value, object.NewString("toml")))
+
+	nested := object.NewMap(map[string]object.Object{
+		"db": object.NewMap(map[string]object.Object{
+			"hosts": object.NewList([]object.Object{object.NewString("a"), object.Nil}),
+		}),
+	})
+	require.Equal(t, object.Errorf(`value error: toml.marshal() can't encode nil value at "db.hosts[1]": toml has no null`),
+		Marshal(ctx, nested))
+}
@@ -0,0 +1,329 @@
+package xml
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/itrn0/risor/arg"
+	"github.com/itrn0/risor/builtins"
+	"github.com/itrn0/risor/object"
+)
+
+// Elements are represented as maps, with attributes stored under keys with an
+// "@" prefix and text content stored under the textKey. Elements with neither
+// attributes nor children are represented by their text alone.
+const (
+	attrPrefix = "@"
+	textKey    = "#text"
+)
+
+// An element of the document being decoded
+type node struct {
+	name     string
+	attrs    []xml.Attr
+	names    []string
+	children map[string][]object.Object
+	text     strings.Builder
+}
+
+func (n *node) addChild(name string, value object.Object) {
+	if n.children == nil {
+		n.children = map[string][]object.Object{}
+	}
+	if _, ok := n.children[name]; !ok {
+		n.names = append(n.names, name)
+	}
+	n.children[name] = append(n.children[name], value)
+}
+
+func (n *node) object() object.Object {
+	text := strings.TrimSpace(n.text.String())
+	if len(n.attrs) == 0 && len(n.children) == 0 {
+		if text == "" {
+			return object.Nil
+		}
+		return object.NewString(text)
+	}
+	items := make(map[string]object.Object, len(n.attrs)+len(n.children)+1)
+	for _, attr := range n.attrs {
+		items[attrPrefix+qualifiedName(attr.Name)] = object.NewString(attr.Value)
+	}
+	for _, name := range n.names {
+		// Repeated elements are collected into a list
+		if values := n.children[name]; len(values) == 1 {
+			items[name] = values[0]
+		} else {
+			items[name] = object.NewList(values)
+		}
+	}
+	if text != "" {
+		items[textKey] = object.NewString(text)
+	}
+	return object.NewMap(items)
+}
+
+// Returns the name as written in the document, including any namespace prefix.
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// Decodes an XML document to a map with a single key, the name of the root
+// element. Comments, processing instructions and directives are ignored.
+func unmarshal(data []byte) (object.Object, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var stack []*node
+	var root object.Object
+	for {
+		// RawToken keeps namespace prefixes as written, so the element names
+		// are matched here rather than by the decoder
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			if root != nil {
+				return nil, errors.New("xml: document has multiple root elements")
+			}
+			stack = append(stack, &node{name: qualifiedName(token.Name), attrs: token.Attr})
+		case xml.EndElement:
+			name := qualifiedName(token.Name)
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("xml: unexpected end element </%s>", name)
+			}
+			current := stack[len(stack)-1]
+			if current.name != name {
+				return nil, fmt.Errorf("xml: element <%s> closed by </%s>", current.name, name)
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				root = object.NewMap(map[string]object.Object{name: current.object()})
+			} else {
+				stack[len(stack)-1].addChild(name, current.object())
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(token)
+			} else if len(bytes.TrimSpace(token)) > 0 {
+				return nil, errors.New("xml: text outside of the root element")
+			}
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("xml: element <%s> is not closed", stack[len(stack)-1].name)
+	}
+	if root == nil {
+		return nil, errors.New("xml: document has no root element")
+	}
+	return root, nil
+}
+
+func isValidName(name string) bool {
+	for i, r := range name {
+		if unicode.IsLetter(r) || r == '_' {
+			continue
+		}
+		if i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.' || r == ':') {
+			continue
+		}
+		return false
+	}
+	return name != ""
+}
+
+// Returns the text for a scalar value.
+func scalarText(obj object.Object) (string, bool) {
+	switch obj := obj.(type) {
+	case *object.String:
+		return obj.Value(), true
+	case *object.Int, *object.Float, *object.Bool:
+		return obj.Inspect(), true
+	case *object.Time:
+		return obj.Value().Format(time.RFC3339), true
+	case *object.NilType:
+		return "", true
+	default:
+		return "", false
+	}
+}
+
+type encoder struct {
+	funcName string
+	enc      *xml.Encoder
+}
+
+func (e *encoder) element(name string, value object.Object) *object.Error {
+	if !isValidName(name) {
+		return object.Errorf("value error: %s() invalid element name %q", e.funcName, name)
+	}
+	switch value := value.(type) {
+	case *object.List:
+		for _, item := range value.Value() {
+			if _, ok := item.(*object.List); ok {
+				return object.Errorf("value error: %s() element %q contains a nested list", e.funcName, name)
+			}
+			if err := e.element(name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *object.Map:
+		return e.mapElement(name, value)
+	}
+	text, ok := scalarText(value)
+	if !ok {
+		return object.TypeErrorf("type error: %s() can't encode %s values", e.funcName, value.Type())
+	}
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	return e.tokens(start, xml.CharData(text), start.End())
+}
+
+func (e *encoder) mapElement(name string, m *object.Map) *object.Error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	var children []string
+	for _, key := range m.SortedKeys() {
+		if key == textKey {
+			continue
+		}
+		if !strings.HasPrefix(key, attrPrefix) {
+			children = append(children, key)
+			continue
+		}
+		attrName := strings.TrimPrefix(key, attrPrefix)
+		if !isValidName(attrName) {
+			return object.Errorf("value error: %s() invalid attribute name %q", e.funcName, attrName)
+		}
+		value, ok := scalarText(m.Get(key))
+		if !ok {
+			return object.TypeErrorf("type error: %s() attribute %q must be a scalar value (%s given)",
+				e.funcName, attrName, m.Get(key).Type())
+		}
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: attrName}, Value: value})
+	}
+	if err := e.tokens(start); err != nil {
+		return err
+	}
+	if textValue := m.Get(textKey); textValue != object.Nil {
+		text, ok := scalarText(textValue)
+		if !ok {
+			return object.TypeErrorf("type error: %s() %q must be a scalar value (%s given)",
+				e.funcName, textKey, textValue.Type())
+		}
+		if err := e.tokens(xml.CharData(text)); err != nil {
+			return err
+		}
+	}
+	for _, child := range children {
+		if err := e.element(child, m.Get(child)); err != nil {
+			return err
+		}
+	}
+	return e.tokens(start.End())
+}
+
+func (e *encoder) tokens(tokens ...xml.Token) *object.Error {
+	for _, token := range tokens {
+		if err := e.enc.EncodeToken(token); err != nil {
+			return object.NewError(err)
+		}
+	}
+	return nil
+}
+
+// Encodes a map with a single key, the name of the root element, as an
+// indented XML document. Attributes and child elements are written in key
+// order.
+func marshal(funcName string, obj object.Object) ([]byte, *object.Error) {
+	m, ok := obj.(*object.Map)
+	if !ok {
+		return nil, object.TypeErrorf("type error: %s() expected a map (%s given)", funcName, obj.Type())
+	}
+	if m.Size() != 1 {
+		return nil, object.Errorf("value error: %s() expected a map with a single root element (%d keys given)", funcName, m.Size())
+	}
+	name := m.SortedKeys()[0]
+	if _, ok := m.Get(name).(*object.List); ok {
+		return nil, object.Errorf("value error: %s() root element %q must not be a list", funcName, name)
+	}
+	var buf bytes.Buffer
+	e := &encoder{funcName: funcName, enc: xml.NewEncoder(&buf)}
+	e.enc.Indent("", "  ")
+	if err := e.element(name, m.Get(name)); err != nil {
+		return nil, err
+	}
+	if err := e.enc.Flush(); err != nil {
+		return nil, object.NewError(err)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func Unmarshal(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("xml.unmarshal", 1, args); err != nil {
+		return err
+	}
+	data, err := object.AsBytes(args[0])
+	if err != nil {
+		return err
+	}
+	result, decodeErr := unmarshal(data)
+	if decodeErr != nil {
+		return object.Errorf("value error: xml.unmarshal failed with: %s", decodeErr.Error())
+	}
+	return result
+}
+
+func Marshal(ctx context.Context, args ...object.Object) object.Object {
+	if err := arg.Require("xml.marshal", 1, args); err != nil {
+		return err
+	}
+	data, err := marshal("xml.marshal", args[0])
+	if err != nil {
+		return err
+	}
+	return object.NewString(string(data))
+}
+
+func encodeXML(ctx context.Context, obj object.Object) object.Object {
+	data, err := marshal("encode", obj)
+	if err != nil {
+		return err
+	}
+	return object.NewString(string(data))
+}
+
+func decodeXML(ctx context.Context, obj object.Object) object.Object {
+	data, err := object.AsBytes(obj)
+	if err != nil {
+		return err
+	}
+	result, decodeErr := unmarshal(data)
+	if decodeErr != nil {
+		return object.NewError(decodeErr)
+	}
+	return result
+}
+
+func init() {
+	builtins.RegisterCodec("xml", &builtins.Codec{Encode: encodeXML, Decode: decodeXML})
+}
+
+func Module() *object.Module {
+	return object.NewBuiltinsModule("xml", map[string]object.Object{
+		"marshal":   object.NewBuiltin("xml.marshal", Marshal),
+		"unmarshal": object.NewBuiltin("xml.unmarshal", Unmarshal),
+	})
+}
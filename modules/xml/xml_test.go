@@ -0,0 +1,132 @@
+package xml
+
+import (
+	"context"
+	"testing"
+
+	"github.com/itrn0/risor/builtins"
+	"github.com/itrn0/risor/object"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshal(t *testing.T) {
+	ctx := context.Background()
+	result := Unmarshal(ctx, object.NewString(`<?xml version="1.0" encoding="UTF-8"?>
+<!-- service configuration -->
+<service name="api" xmlns:x="urn:example">
+  <host>a.local</host>
+  <host>b.local</host>
+  <port>8080</port>
+  <x:debug/>
+  <note lang="en">Uses <![CDATA[<tls>]]> &amp; retries</note>
+</service>`))
+	require.Equal(t, object.NewMap(map[string]object.Object{
+		"service": object.NewMap(map[string]object.Object{
+			"@name":    object.NewString("api"),
+			"@xmlns:x": object.NewString("urn:example"),
+			"host":     object.NewStringList([]string{"a.local", "b.local"}),
+			"port":     object.NewString("8080"),
+			"x:debug":  object.Nil,
+			"note": object.NewMap(map[string]object.Object{
+				"@lang": object.NewString("en"),
+				"#text": object.NewString("Uses <tls> & retries"),
+			}),
+		}),
+	}), result)
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		input  string
+		errMsg string
+	}{
+		{"<a><b></a>", "xml: element <b> closed by </a>"},
+		{"<a>", "xml: element <a> is not closed"},
+		{"", "xml: document has no root element"},
+		{"<a/><b/>", "xml: document has multiple root elements"},
+		{"text<a/>", "xml: text outside of the root element"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result := Unmarshal(ctx, object.NewString(tt.input))
+			require.Equal(t, object.Errorf("value error: xml.unmarshal failed with: %s", tt.errMsg), result)
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	ctx := context.Background()
+	value := object.NewMap(map[string]object.Object{
+		"service": object.NewMap(map[string]object.Object{
+			"@name":   object.NewString("api"),
+			"port":    object.NewInt(8080),
+			"host":    object.NewStringList([]string{"a.local", "b.local"}),
+			"enabled": object.True,
+			"empty":   object.Nil,
+			"note": object.NewMap(map[string]object.Object{
+				"@lang": object.NewString("en"),
+				"#text": object.NewString("a < b"),
+			}),
+		}),
+	})
+	result := Marshal(ctx, value)
+	require.Equal(t, object.NewString(`<service name="api">
+  <empty></empty>
+  <enabled>true</enabled>
+  <host>a.local</host>
+  <host>b.local</host>
+  <note lang="en">a &lt; b</note>
+  <port>8080</port>
+</service>
+`), result)
+
+	// Values are decoded as strings, so only they survive the round trip as is
+	decoded := Unmarshal(ctx, result).(*object.Map)
+	service := decoded.Get("service").(*object.Map)
+	require.Equal(t, object.NewString("8080"), service.Get("port"))
+	require.Equal(t, object.NewStringList([]string{"a.local", "b.local"}), service.Get("host"))
+	require.Equal(t, value.Get("service").(*object.Map).Get("note"), service.Get("note"))
+}
+
+func TestMarshalErrors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		input    object.Object
+		expected object.Object
+	}{
+		{
+			object.NewList(nil),
+			object.TypeErrorf("type error: xml.marshal() expected a map (list given)"),
+		},
+		{
+			object.NewMap(map[string]object.Object{"a": object.Nil, "b": object.Nil}),
+			object.Errorf("value error: xml.marshal() expected a map with a single root element (2 keys given)"),
+		},
+		{
+			object.NewMap(map[string]object.Object{"a": object.NewStringList([]string{"x"})}),
+			object.Errorf(`value error: xml.marshal() root element "a" must not be a list`),
+		},
+		{
+			object.NewMap(map[string]object.Object{"a b": object.Nil}),
+			object.Errorf(`value error: xml.marshal() invalid element name "a b"`),
+		},
+		{
+			object.NewMap(map[string]object.Object{"a": object.NewMap(map[string]object.Object{
+				"@id": object.NewList(nil),
+			})}),
+			object.TypeErrorf(`type error: xml.marshal() attribute "id" must be a scalar value (list given)`),
+		},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, Marshal(ctx, tt.input))
+	}
+}
+
+func TestCodec(t *testing.T) {
+	ctx := context.Background()
+	value := object.NewMap(map[string]object.Object{"a": object.NewString("1")})
+	encoded := builtins.Encode(ctx, value, object.NewString("xml"))
+	require.Equal(t, object.NewString("<a>1</a>\n"), encoded)
+	require.Equal(t, value, builtins.Decode(ctx, encoded, object.NewString("xml")))
+}
@@ -55,6 +55,15 @@ func (b *Builtin) Call(ctx context.Context, args ...Object) Object {
 	return b.fn(ctx, args...)
 }
 
+// Wrap returns a copy of the builtin whose function is the result of passing
+// the original function to the given wrapper. The copy keeps the name and
+// module of the original.
+func (b *Builtin) Wrap(wrapper func(BuiltinFunction) BuiltinFunction) *Builtin {
+	wrapped := *b
+	wrapped.fn = wrapper(b.fn)
+	return &wrapped
+}
+
 func (b *Builtin) Inspect() string {
 	if b.module == nil {
 		return fmt.Sprintf("builtin(%s)", b.name)
@@ -187,6 +187,27 @@ func (b *ByteSlice) GetAttr(name string) (Object, bool) {
 	return nil, false
 }
 
+// AttrNames returns the names of the attributes of the byte slice.
+func (b *ByteSlice) AttrNames() []string {
+	return []string{
+		"clone",
+		"contains",
+		"contains_any",
+		"contains_rune",
+		"count",
+		"equals",
+		"has_prefix",
+		"has_suffix",
+		"index",
+		"index_any",
+		"index_byte",
+		"index_rune",
+		"repeat",
+		"replace",
+		"replace_all",
+	}
+}
+
 func (b *ByteSlice) Interface() interface{} {
 	return b.value
 }
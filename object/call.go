@@ -0,0 +1,40 @@
+package object
+
+import (
+	"context"
+	"errors"
+)
+
+// RequireCallable returns a type error if the object isn't a function or
+// other callable object. The name of the calling function is included in the
+// error message.
+func RequireCallable(funcName string, obj Object) *Error {
+	switch obj.(type) {
+	case *Function, Callable:
+		return nil
+	default:
+		return TypeErrorf("type error: %s() expected a function (%s given)", funcName, obj.Type())
+	}
+}
+
+// Call calls a function or other callable object with the given arguments.
+// Risor functions are called using the CallFunc from the context. An error
+// raised by the call is returned as a Go error.
+func Call(ctx context.Context, fn Object, args ...Object) (Object, error) {
+	switch fn := fn.(type) {
+	case *Function:
+		callFunc, found := GetCallFunc(ctx)
+		if !found {
+			return nil, errors.New("eval error: context did not contain a call function")
+		}
+		return callFunc(ctx, fn, args)
+	case Callable:
+		result := fn.Call(ctx, args...)
+		if err, ok := result.(*Error); ok && err.IsRaised() {
+			return nil, err.Value()
+		}
+		return result, nil
+	default:
+		return nil, TypeErrorf("type error: object is not callable (got %s)", fn.Type())
+	}
+}
@@ -0,0 +1,44 @@
+package object
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireCallable(t *testing.T) {
+	builtin := NewBuiltin("double", func(ctx context.Context, args ...Object) Object {
+		return args[0]
+	})
+	require.Nil(t, RequireCallable("iter.map", builtin))
+
+	err := RequireCallable("iter.map", NewInt(1))
+	require.NotNil(t, err)
+	require.Equal(t, "type error: iter.map() expected a function (int given)", err.Error())
+}
+
+func TestCall(t *testing.T) {
+	ctx := context.Background()
+	builtin := NewBuiltin("double", func(ctx context.Context, args ...Object) Object {
+		return NewInt(args[0].(*Int).Value() * 2)
+	})
+	result, err := Call(ctx, builtin, NewInt(21))
+	require.Nil(t, err)
+	require.Equal(t, NewInt(42), result)
+
+	raise := NewBuiltin("raise", func(ctx context.Context, args ...Object) Object {
+		return Errorf("oops")
+	})
+	_, err = Call(ctx, raise)
+	require.NotNil(t, err)
+	require.Equal(t, "oops", err.Error())
+
+	_, err = Call(ctx, NewInt(1))
+	require.NotNil(t, err)
+	require.Equal(t, "type error: object is not callable (got int)", err.Error())
+
+	_, err = Call(ctx, &Function{})
+	require.NotNil(t, err)
+	require.Equal(t, "eval error: context did not contain a call function", err.Error())
+}
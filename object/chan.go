@@ -3,6 +3,7 @@ package object
 import (
 	"context"
 	"fmt"
+	"reflect"
 
 	"github.com/itrn0/risor/errz"
 	"github.com/itrn0/risor/op"
@@ -175,3 +176,60 @@ func NewChan(size int) *Chan {
 		value:    make(chan Object, size),
 	}
 }
+
+// SelectCase is one channel operation of a select statement. A case with a
+// nil channel is never chosen.
+type SelectCase struct {
+	Chan   *Chan
+	IsSend bool
+	Value  Object
+}
+
+// Select waits until one of the channel operations can proceed and performs
+// it. It returns the index of the chosen case, along with the received value
+// and whether the channel was open if the case is a receive. If hasDefault is
+// true and no operation can proceed immediately, -1 is returned instead of
+// waiting. An error is returned if the context is cancelled while waiting.
+func Select(ctx context.Context, cases []SelectCase, hasDefault bool) (index int, value Object, ok bool, err error) {
+	// Translate a "send on closed channel" panic to an error
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("exec error: %v", r)
+		}
+	}()
+	// The first case is the cancellation of the context
+	selectCases := make([]reflect.SelectCase, 0, len(cases)+2)
+	selectCases = append(selectCases, reflect.SelectCase{
+		Dir:  reflect.SelectRecv,
+		Chan: reflect.ValueOf(ctx.Done()),
+	})
+	for _, c := range cases {
+		var selectCase reflect.SelectCase
+		if c.IsSend {
+			selectCase.Dir = reflect.SelectSend
+			selectCase.Send = reflect.ValueOf(&c.Value).Elem()
+		} else {
+			selectCase.Dir = reflect.SelectRecv
+		}
+		if c.Chan != nil {
+			selectCase.Chan = reflect.ValueOf(c.Chan.value)
+		}
+		selectCases = append(selectCases, selectCase)
+	}
+	if hasDefault {
+		selectCases = append(selectCases, reflect.SelectCase{Dir: reflect.SelectDefault})
+	}
+	chosen, received, receivedOK := reflect.Select(selectCases)
+	switch {
+	case chosen == 0:
+		return 0, nil, false, ctx.Err()
+	case chosen > len(cases):
+		return -1, Nil, false, nil
+	case cases[chosen-1].IsSend:
+		return chosen - 1, Nil, true, nil
+	case !receivedOK:
+		return chosen - 1, Nil, false, nil
+	default:
+		return chosen - 1, received.Interface().(Object), true, nil
+	}
+}
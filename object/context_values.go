@@ -2,6 +2,7 @@ package object
 
 import (
 	"context"
+	"log/slog"
 )
 
 type contextKey string
@@ -74,3 +75,22 @@ func GetCloneCallFunc(ctx context.Context) (CallFunc, bool) {
 	}
 	return nil, false
 }
+
+////////////////////////////////////////////////////////////////////////////////
+
+const loggerKey = contextKey("risor:logger")
+
+// WithLogger returns a context with a logger associated, which is used by the
+// log module and by the VM to report events.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// GetLogger returns the logger from the context, or the default slog logger
+// if the context has none.
+func GetLogger(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok && logger != nil {
+		return logger
+	}
+	return slog.Default()
+}
@@ -2,6 +2,7 @@ package object
 
 import (
 	"context"
+	"log/slog"
 	"testing"
 
 	"github.com/itrn0/risor/compiler"
@@ -67,3 +68,11 @@ func TestContextCloneCallFunc(t *testing.T) {
 	require.Nil(t, err)
 	require.IsType(t, NewInt(42), result)
 }
+
+func TestContextLogger(t *testing.T) {
+	require.Equal(t, slog.Default(), GetLogger(context.Background()))
+
+	logger := slog.New(slog.NewJSONHandler(nil, nil))
+	ctx := WithLogger(context.Background(), logger)
+	require.Equal(t, logger, GetLogger(ctx))
+}
@@ -87,6 +87,14 @@ func (e *Error) GetAttr(name string) (Object, bool) {
 	}
 }
 
+// AttrNames returns the names of the attributes of the error.
+func (e *Error) AttrNames() []string {
+	return []string{
+		"error",
+		"message",
+	}
+}
+
 func (e *Error) Message() *String {
 	return NewString(e.err.Error())
 }
@@ -109,7 +109,7 @@ func (iter *FileIter) Entry() (IteratorEntry, bool) {
 	if iter.current == nil {
 		return nil, false
 	}
-	return NewEntry(NewInt(iter.pos), iter.current), true
+	return NewEntry(NewInt(iter.pos), iter.current).WithKeyAsPrimary(), true
 }
 
 func (iter *FileIter) MarshalJSON() ([]byte, error) {
@@ -0,0 +1,172 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/itrn0/risor/errz"
+	"github.com/itrn0/risor/op"
+)
+
+// ResumeFunc runs a suspended generator until it yields its next value, which
+// is returned along with true. Once the generator function returns, it
+// returns false.
+type ResumeFunc func(ctx context.Context) (Object, bool, error)
+
+// Generator is the iterator returned by calling a generator function, which
+// is a function that contains a yield statement. The function body runs only
+// as values are requested: each call to Next resumes it until it yields the
+// next value or returns.
+type Generator struct {
+	*base
+	fn      *Function
+	resume  ResumeFunc
+	mutex   sync.Mutex
+	running bool
+	done    bool
+	index   int64
+	current Object
+	err     error
+}
+
+func (g *Generator) Type() Type {
+	return GENERATOR
+}
+
+func (g *Generator) Inspect() string {
+	return fmt.Sprintf("generator(%s)", g.fn.Name())
+}
+
+func (g *Generator) String() string {
+	return g.Inspect()
+}
+
+// Function returns the generator function that was called to create this
+// generator.
+func (g *Generator) Function() *Function {
+	return g.fn
+}
+
+func (g *Generator) Interface() interface{} {
+	ctx := context.Background()
+	var entries []any
+	for {
+		entry, ok := g.Next(ctx)
+		if !ok {
+			break
+		}
+		entries = append(entries, entry.Interface())
+	}
+	return entries
+}
+
+func (g *Generator) Equals(other Object) Object {
+	return NewBool(g == other)
+}
+
+func (g *Generator) GetAttr(name string) (Object, bool) {
+	switch name {
+	case "next":
+		return NewBuiltin("generator.next", func(ctx context.Context, args ...Object) Object {
+			if len(args) != 0 {
+				return NewArgsError("generator.next", 0, len(args))
+			}
+			value, ok := g.Next(ctx)
+			if !ok {
+				if err := g.Err(); err != nil {
+					return NewError(err)
+				}
+				return Nil
+			}
+			return value
+		}), true
+	case "entry":
+		return NewBuiltin("generator.entry", func(ctx context.Context, args ...Object) Object {
+			if len(args) != 0 {
+				return NewArgsError("generator.entry", 0, len(args))
+			}
+			entry, ok := g.Entry()
+			if !ok {
+				return Nil
+			}
+			return entry
+		}), true
+	}
+	return nil, false
+}
+
+func (g *Generator) IsTruthy() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return !g.done
+}
+
+func (g *Generator) RunOperation(opType op.BinaryOpType, right Object) Object {
+	return TypeErrorf("type error: unsupported operation for %s: %v", GENERATOR, opType)
+}
+
+// Next resumes the generator function and returns the next value it yields.
+// False is returned once the function has returned or raised an error, which
+// is then available from Err.
+func (g *Generator) Next(ctx context.Context) (Object, bool) {
+	g.mutex.Lock()
+	if g.done {
+		g.mutex.Unlock()
+		return nil, false
+	}
+	if g.running {
+		// The generator function is iterating over its own generator
+		g.err = errors.New("value error: generator is already running")
+		g.mutex.Unlock()
+		return nil, false
+	}
+	g.running = true
+	g.mutex.Unlock()
+
+	value, ok, err := g.resume(ctx)
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.running = false
+	if err != nil || !ok {
+		g.done = true
+		g.current = nil
+		g.err = err
+		return nil, false
+	}
+	g.index++
+	g.current = value
+	return value, true
+}
+
+// Entry returns the position of the value most recently yielded as the entry
+// key, with the value itself as the entry value and primary object. This means
+// "for x := range gen" iterates over the values, while "for i, x := range gen"
+// also binds their positions, as with lists.
+func (g *Generator) Entry() (IteratorEntry, bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.current == nil {
+		return nil, false
+	}
+	return NewEntry(NewInt(g.index), g.current).WithValueAsPrimary(), true
+}
+
+// Err returns the error raised by the generator function, if any.
+func (g *Generator) Err() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.err
+}
+
+func (g *Generator) MarshalJSON() ([]byte, error) {
+	return nil, errz.TypeErrorf("type error: unable to marshal %s", GENERATOR)
+}
+
+// NewGenerator returns a generator for a call to the given function. The
+// resume function runs the call until it yields each value.
+func NewGenerator(fn *Function, resume ResumeFunc) *Generator {
+	return &Generator{fn: fn, resume: resume, index: -1}
+}
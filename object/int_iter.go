@@ -112,7 +112,7 @@ func (iter *IntIter) Entry() (IteratorEntry, bool) {
 	if iter.current == nil {
 		return nil, false
 	}
-	return NewEntry(NewInt(iter.pos), iter.current), true
+	return NewEntry(NewInt(iter.pos), iter.current).WithKeyAsPrimary(), true
 }
 
 func (iter *IntIter) MarshalJSON() ([]byte, error) {
@@ -218,6 +218,26 @@ func (ls *List) GetAttr(name string) (Object, bool) {
 	return nil, false
 }
 
+// AttrNames returns the names of the attributes of the list.
+func (ls *List) AttrNames() []string {
+	return []string{
+		"append",
+		"clear",
+		"copy",
+		"count",
+		"each",
+		"extend",
+		"filter",
+		"index",
+		"insert",
+		"map",
+		"pop",
+		"remove",
+		"reverse",
+		"sort",
+	}
+}
+
 func (ls *List) Map(ctx context.Context, fn Object) Object {
 	callFunc, found := GetCallFunc(ctx)
 	if !found {
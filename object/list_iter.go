@@ -106,7 +106,7 @@ func (iter *ListIter) Entry() (IteratorEntry, bool) {
 	if iter.current == nil {
 		return nil, false
 	}
-	return NewEntry(NewInt(iter.pos), iter.current), true
+	return NewEntry(NewInt(iter.pos), iter.current).WithKeyAsPrimary(), true
 }
 
 func (iter *ListIter) MarshalJSON() ([]byte, error) {
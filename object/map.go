@@ -184,6 +184,23 @@ func (m *Map) GetAttr(name string) (Object, bool) {
 	return o, ok
 }
 
+// AttrNames returns the names of the methods of the map, followed by its keys
+// in sorted order.
+func (m *Map) AttrNames() []string {
+	names := []string{
+		"clear",
+		"copy",
+		"get",
+		"items",
+		"keys",
+		"pop",
+		"setdefault",
+		"update",
+		"values",
+	}
+	return append(names, m.SortedKeys()...)
+}
+
 func (m *Map) ListItems() *List {
 	items := make([]Object, 0, len(m.items))
 	for _, k := range m.SortedKeys() {
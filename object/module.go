@@ -3,6 +3,7 @@ package object
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/itrn0/risor/compiler"
 	"github.com/itrn0/risor/errz"
@@ -41,6 +42,19 @@ func (m *Module) GetAttr(name string) (Object, bool) {
 	return nil, false
 }
 
+// AttrNames returns the names of the attributes of the module in sorted order.
+func (m *Module) AttrNames() []string {
+	names := make([]string, 0, len(m.builtins)+len(m.globalsIndex))
+	for name := range m.builtins {
+		names = append(names, name)
+	}
+	for name := range m.globalsIndex {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (m *Module) SetAttr(name string, value Object) error {
 	return errz.TypeErrorf("type error: cannot modify module attributes")
 }
@@ -72,6 +86,32 @@ func (m *Module) Override(name string, value Object) error {
 	return TypeErrorf("type error: module has no attribute %q", name)
 }
 
+// Clone returns a shallow copy of the module. Attributes may be overridden on
+// the copy without affecting the original.
+func (m *Module) Clone() *Module {
+	clone := *m
+	clone.builtins = make(map[string]Object, len(m.builtins))
+	for k, v := range m.builtins {
+		clone.builtins[k] = v
+	}
+	clone.globals = make([]Object, len(m.globals))
+	copy(clone.globals, m.globals)
+	clone.globalsIndex = make(map[string]int, len(m.globalsIndex))
+	for k, v := range m.globalsIndex {
+		clone.globalsIndex[k] = v
+	}
+	return &clone
+}
+
+// WrapCall replaces the function invoked when the module is called with the
+// result of passing it to the given wrapper. This has no effect on modules
+// that aren't callable.
+func (m *Module) WrapCall(wrapper func(BuiltinFunction) BuiltinFunction) {
+	if m.callable != nil {
+		m.callable = wrapper(m.callable)
+	}
+}
+
 func (m *Module) Interface() interface{} {
 	return nil
 }
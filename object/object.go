@@ -51,6 +51,7 @@ const (
 	FLOAT         Type = "float"
 	FLOAT_SLICE   Type = "float_slice"
 	FUNCTION      Type = "function"
+	GENERATOR     Type = "generator"
 	GO_FIELD      Type = "go_field"
 	GO_METHOD     Type = "go_method"
 	GO_TYPE       Type = "go_type"
@@ -71,6 +72,7 @@ const (
 	SLICE_ITER    Type = "slice_iter"
 	STRING        Type = "string"
 	STRING_ITER   Type = "string_iter"
+	STRUCT_TYPE   Type = "struct_type"
 	THREAD        Type = "thread"
 	TIME          Type = "time"
 )
@@ -118,7 +120,9 @@ type Slice struct {
 	Stop  Object
 }
 
-// IteratorEntry is a single item returned by an iterator.
+// IteratorEntry is a single item returned by an iterator. A range loop with
+// one variable binds it to the primary object of the entry, while a loop with
+// two variables binds them to the key and the value.
 type IteratorEntry interface {
 	Object
 	Key() Object
@@ -140,6 +144,27 @@ type Iterator interface {
 	Entry() (IteratorEntry, bool)
 }
 
+// FallibleIterator is an Iterator that may fail while producing items, such
+// as a generator whose function raises an error. Once Next returns false,
+// Err returns the error that stopped the iteration, if there was one.
+type FallibleIterator interface {
+	Iterator
+
+	// Err returns the error that stopped the iteration, if any.
+	Err() error
+}
+
+// IteratorErr returns the error that stopped an iteration, if the iterator
+// is a FallibleIterator that failed. Otherwise it returns nil.
+func IteratorErr(iter Iterator) *Error {
+	if iter, ok := iter.(FallibleIterator); ok {
+		if err := iter.Err(); err != nil {
+			return NewError(err)
+		}
+	}
+	return nil
+}
+
 // Iterable is an interface that exposes an iterator for an Object.
 type Iterable interface {
 	Iter() Iterator
@@ -167,6 +192,13 @@ type Container interface {
 	Len() *Int
 }
 
+// AttrLister is implemented by objects that can list the names of the
+// attributes available through GetAttr.
+type AttrLister interface {
+	// AttrNames returns the names of the attributes of the object.
+	AttrNames() []string
+}
+
 // Callable is an interface that exposes a Call method.
 type Callable interface {
 	// Call invokes the callable with the given arguments and returns the result.
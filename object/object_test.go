@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/itrn0/risor/compiler"
 	"github.com/itrn0/risor/errz"
 	"github.com/stretchr/testify/require"
 )
@@ -154,3 +155,65 @@ func TestPrintableValue(t *testing.T) {
 		})
 	}
 }
+
+func TestAttrNames(t *testing.T) {
+	point := NewStructType("Point", []string{"x", "y"}, []Object{Nil, Nil})
+	require.Nil(t, point.SetAttr("norm", NewFunction(compiler.NewFunction(compiler.FunctionOpts{
+		Name:       "Point.norm",
+		Parameters: []string{"p"},
+	}))))
+	objects := []Object{
+		NewString("abc"),
+		NewList(nil),
+		NewMap(map[string]Object{"a": NewInt(1)}),
+		NewSet(nil),
+		NewByteSlice([]byte("abc")),
+		NewTime(time.Now()),
+		NewError(errors.New("oops")),
+		NewBuiltinsModule("m", map[string]Object{"f": NewBuiltin("f", nil)}),
+		point,
+		point.Call(context.Background(), NewInt(1), NewInt(2)),
+	}
+	for _, obj := range objects {
+		lister, ok := obj.(AttrLister)
+		require.True(t, ok, obj.Type())
+		names := lister.AttrNames()
+		require.NotEmpty(t, names)
+		seen := map[string]bool{}
+		for _, name := range names {
+			require.False(t, seen[name], "duplicate attribute %s on %s", name, obj.Type())
+			seen[name] = true
+			_, found := obj.GetAttr(name)
+			require.True(t, found, "attribute %s on %s", name, obj.Type())
+		}
+	}
+	require.Equal(t, []string{"x", "y", "norm"}, objects[len(objects)-1].(AttrLister).AttrNames())
+}
+
+func TestWrapBuiltinAndModule(t *testing.T) {
+	ctx := context.Background()
+	double := func(fn BuiltinFunction) BuiltinFunction {
+		return func(ctx context.Context, args ...Object) Object {
+			return NewInt(fn(ctx, args...).(*Int).Value() * 2)
+		}
+	}
+	one := NewBuiltin("one", func(ctx context.Context, args ...Object) Object {
+		return NewInt(1)
+	})
+	m := NewBuiltinsModule("m", map[string]Object{"one": one}, one.Value())
+
+	clone := m.Clone()
+	attr, ok := clone.GetAttr("one")
+	require.True(t, ok)
+	wrapped := attr.(*Builtin).Wrap(double)
+	require.Nil(t, clone.Override("one", wrapped))
+	clone.WrapCall(double)
+
+	require.Equal(t, "builtin(m.one)", wrapped.Inspect())
+	require.Equal(t, NewInt(2), wrapped.Call(ctx))
+	require.Equal(t, NewInt(2), clone.Call(ctx))
+	require.Equal(t, NewInt(1), one.Call(ctx))
+	require.Equal(t, NewInt(1), m.Call(ctx))
+	attr, _ = m.GetAttr("one")
+	require.Equal(t, one, attr)
+}
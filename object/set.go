@@ -105,6 +105,17 @@ func (s *Set) GetAttr(name string) (Object, bool) {
 	return nil, false
 }
 
+// AttrNames returns the names of the attributes of the set.
+func (s *Set) AttrNames() []string {
+	return []string{
+		"add",
+		"clear",
+		"intersection",
+		"remove",
+		"union",
+	}
+}
+
 func (s *Set) Interface() interface{} {
 	items := make([]interface{}, 0, len(s.items))
 	for _, item := range s.SortedItems() {
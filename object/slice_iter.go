@@ -108,7 +108,7 @@ func (iter *SliceIter) Entry() (IteratorEntry, bool) {
 	if iter.current == nil {
 		return nil, false
 	}
-	return NewEntry(NewInt(int64(iter.pos)), iter.current), true
+	return NewEntry(NewInt(int64(iter.pos)), iter.current).WithKeyAsPrimary(), true
 }
 
 func (iter *SliceIter) RunOperation(opType op.BinaryOpType, right Object) Object {
@@ -209,6 +209,28 @@ func (s *String) GetAttr(name string) (Object, bool) {
 	return nil, false
 }
 
+// AttrNames returns the names of the attributes of the string.
+func (s *String) AttrNames() []string {
+	return []string{
+		"contains",
+		"count",
+		"fields",
+		"has_prefix",
+		"has_suffix",
+		"index",
+		"join",
+		"last_index",
+		"replace_all",
+		"split",
+		"to_lower",
+		"to_upper",
+		"trim",
+		"trim_prefix",
+		"trim_space",
+		"trim_suffix",
+	}
+}
+
 func (s *String) Interface() interface{} {
 	return s.value
 }
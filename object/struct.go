@@ -0,0 +1,279 @@
+package object
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/itrn0/risor/errz"
+	"github.com/itrn0/risor/op"
+)
+
+var _ Callable = (*StructType)(nil) // Ensure that *StructType implements Callable
+
+// StructType is a user-defined type declared with a struct statement. Calling
+// it creates a new instance of the type. Methods are attached to the type by
+// setting attributes on it to functions that take the instance as their first
+// parameter.
+type StructType struct {
+	*base
+	name     string
+	fields   []string
+	defaults []Object
+	index    map[string]int
+	methods  map[string]*Function
+}
+
+func (t *StructType) Type() Type {
+	return STRUCT_TYPE
+}
+
+func (t *StructType) Name() string {
+	return t.name
+}
+
+// Fields returns the names of the fields of the type, in declaration order.
+func (t *StructType) Fields() []string {
+	return t.fields
+}
+
+// Method returns the method with the given name, if one is defined.
+func (t *StructType) Method(name string) (*Function, bool) {
+	method, ok := t.methods[name]
+	return method, ok
+}
+
+func (t *StructType) Inspect() string {
+	fields := make([]string, 0, len(t.fields))
+	for i, name := range t.fields {
+		if def := t.defaults[i]; def != Nil {
+			name += " = " + def.Inspect()
+		}
+		fields = append(fields, name)
+	}
+	if len(fields) == 0 {
+		return fmt.Sprintf("struct %s {}", t.name)
+	}
+	return fmt.Sprintf("struct %s { %s }", t.name, strings.Join(fields, ", "))
+}
+
+func (t *StructType) String() string {
+	return t.Inspect()
+}
+
+func (t *StructType) Interface() interface{} {
+	return nil
+}
+
+func (t *StructType) GetAttr(name string) (Object, bool) {
+	switch name {
+	case "__name__":
+		return NewString(t.name), true
+	}
+	if method, ok := t.methods[name]; ok {
+		return method, true
+	}
+	return nil, false
+}
+
+// AttrNames returns the names of the methods of the struct type in sorted
+// order.
+func (t *StructType) AttrNames() []string {
+	names := make([]string, 0, len(t.methods))
+	for name := range t.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (t *StructType) SetAttr(name string, value Object) error {
+	method, ok := value.(*Function)
+	if !ok {
+		return errz.TypeErrorf("type error: struct type %s attributes must be methods (got %s)",
+			t.name, value.Type())
+	}
+	if _, ok := t.index[name]; ok {
+		return errz.TypeErrorf("type error: struct %s has a field named %q", t.name, name)
+	}
+	t.methods[name] = method
+	return nil
+}
+
+// Call creates a new instance of the struct type. Arguments are assigned to
+// fields in declaration order and any remaining fields take their default
+// values.
+func (t *StructType) Call(ctx context.Context, args ...Object) Object {
+	if len(args) > len(t.fields) {
+		return NewError(errz.ArgsErrorf("args error: struct %s has %d fields (%d given)",
+			t.name, len(t.fields), len(args)))
+	}
+	values := make([]Object, len(t.fields))
+	copy(values, args)
+	copy(values[len(args):], t.defaults[len(args):])
+	return &Struct{typ: t, values: values}
+}
+
+func (t *StructType) Equals(other Object) Object {
+	if t == other {
+		return True
+	}
+	return False
+}
+
+func (t *StructType) RunOperation(opType op.BinaryOpType, right Object) Object {
+	return TypeErrorf("type error: unsupported operation for struct type: %v", opType)
+}
+
+func (t *StructType) MarshalJSON() ([]byte, error) {
+	return nil, errz.TypeErrorf("type error: unable to marshal struct type")
+}
+
+// NewStructType returns a new struct type with the given name and fields.
+// The defaults slice holds the default value of each field.
+func NewStructType(name string, fields []string, defaults []Object) *StructType {
+	index := make(map[string]int, len(fields))
+	for i, field := range fields {
+		index[field] = i
+	}
+	return &StructType{
+		name:     name,
+		fields:   fields,
+		defaults: defaults,
+		index:    index,
+		methods:  map[string]*Function{},
+	}
+}
+
+// Struct is an instance of a user-defined struct type. Its Type is the name
+// the struct type was declared with.
+type Struct struct {
+	*base
+	typ    *StructType
+	values []Object
+}
+
+func (s *Struct) Type() Type {
+	return Type(s.typ.name)
+}
+
+// StructType returns the type this struct is an instance of.
+func (s *Struct) StructType() *StructType {
+	return s.typ
+}
+
+func (s *Struct) Inspect() string {
+	var out bytes.Buffer
+	out.WriteString(s.typ.name + "{")
+	for i, name := range s.typ.fields {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(name + ": " + s.values[i].Inspect())
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+func (s *Struct) String() string {
+	return s.Inspect()
+}
+
+func (s *Struct) Interface() interface{} {
+	result := make(map[string]any, len(s.values))
+	for i, name := range s.typ.fields {
+		result[name] = s.values[i].Interface()
+	}
+	return result
+}
+
+func (s *Struct) GetAttr(name string) (Object, bool) {
+	if i, ok := s.typ.index[name]; ok {
+		return s.values[i], true
+	}
+	if method, ok := s.typ.methods[name]; ok {
+		methodName := s.typ.name + "." + name
+		return &Builtin{
+			name: methodName,
+			fn: func(ctx context.Context, args ...Object) Object {
+				// Check the argument count here so that the receiver is not
+				// included in the counts reported to the caller
+				takesMax := len(method.Parameters()) - 1
+				takesMin := method.RequiredArgsCount() - 1
+				if len(args) > takesMax || len(args) < takesMin {
+					if takesMin == takesMax {
+						return NewArgsError(methodName, takesMax, len(args))
+					}
+					return NewArgsRangeError(methodName, takesMin, takesMax, len(args))
+				}
+				return method.Call(ctx, append([]Object{s}, args...)...)
+			},
+		}, true
+	}
+	return nil, false
+}
+
+// AttrNames returns the names of the fields of the struct in the order they
+// were declared, followed by the names of its methods.
+func (s *Struct) AttrNames() []string {
+	names := append([]string(nil), s.typ.fields...)
+	return append(names, s.typ.AttrNames()...)
+}
+
+func (s *Struct) SetAttr(name string, value Object) error {
+	i, ok := s.typ.index[name]
+	if !ok {
+		return errz.TypeErrorf("type error: struct %s has no field %q", s.typ.name, name)
+	}
+	s.values[i] = value
+	return nil
+}
+
+func (s *Struct) Equals(other Object) Object {
+	otherStruct, ok := other.(*Struct)
+	if !ok || otherStruct.typ != s.typ {
+		return False
+	}
+	for i, value := range s.values {
+		if !value.Equals(otherStruct.values[i]).IsTruthy() {
+			return False
+		}
+	}
+	return True
+}
+
+func (s *Struct) RunOperation(opType op.BinaryOpType, right Object) Object {
+	return TypeErrorf("type error: unsupported operation for %s: %v", s.typ.name, opType)
+}
+
+func (s *Struct) Cost() int {
+	return len(s.values) * 8
+}
+
+// MarshalJSON encodes the struct as a JSON object with its fields in
+// declaration order.
+func (s *Struct) MarshalJSON() ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteString("{")
+	for i, name := range s.typ.fields {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(s.values[i])
+		if err != nil {
+			return nil, err
+		}
+		out.Write(key)
+		out.WriteString(":")
+		out.Write(value)
+	}
+	out.WriteString("}")
+	return out.Bytes(), nil
+}
@@ -0,0 +1,62 @@
+package object
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStructType(t *testing.T) {
+	typ := NewStructType("Point", []string{"x", "y"}, []Object{Nil, NewInt(0)})
+	require.Equal(t, STRUCT_TYPE, typ.Type())
+	require.Equal(t, "struct Point { x, y = 0 }", typ.Inspect())
+
+	s, ok := typ.Call(context.Background(), NewInt(1)).(*Struct)
+	require.True(t, ok)
+	require.Equal(t, Type("Point"), s.Type())
+	require.Equal(t, "Point{x: 1, y: 0}", s.Inspect())
+
+	err, ok := typ.Call(context.Background(), NewInt(1), NewInt(2), NewInt(3)).(*Error)
+	require.True(t, ok)
+	require.Equal(t, "args error: struct Point has 2 fields (3 given)", err.Message().Value())
+}
+
+func TestStructAttrs(t *testing.T) {
+	typ := NewStructType("Point", []string{"x", "y"}, []Object{Nil, Nil})
+	s := typ.Call(context.Background(), NewInt(1), NewInt(2)).(*Struct)
+
+	value, ok := s.GetAttr("x")
+	require.True(t, ok)
+	require.Equal(t, NewInt(1), value)
+
+	require.Nil(t, s.SetAttr("y", NewString("a")))
+	value, ok = s.GetAttr("y")
+	require.True(t, ok)
+	require.Equal(t, NewString("a"), value)
+
+	_, ok = s.GetAttr("z")
+	require.False(t, ok)
+	require.Error(t, s.SetAttr("z", NewInt(3)))
+	require.Error(t, typ.SetAttr("method", NewInt(3)))
+}
+
+func TestStructEquals(t *testing.T) {
+	ctx := context.Background()
+	point := NewStructType("Point", []string{"x", "y"}, []Object{Nil, Nil})
+	other := NewStructType("Other", []string{"x", "y"}, []Object{Nil, Nil})
+	a := point.Call(ctx, NewInt(1), NewInt(2))
+	require.Equal(t, True, a.Equals(point.Call(ctx, NewInt(1), NewInt(2))))
+	require.Equal(t, False, a.Equals(point.Call(ctx, NewInt(1), NewInt(3))))
+	require.Equal(t, False, a.Equals(other.Call(ctx, NewInt(1), NewInt(2))))
+}
+
+func TestStructMarshalJSON(t *testing.T) {
+	typ := NewStructType("Point", []string{"y", "x"}, []Object{Nil, Nil})
+	s := typ.Call(context.Background(), NewInt(1), NewList([]Object{NewString("a")}))
+	data, err := json.Marshal(s)
+	require.Nil(t, err)
+	require.Equal(t, `{"y":1,"x":["a"]}`, string(data))
+	require.Equal(t, map[string]any{"y": int64(1), "x": []any{"a"}}, s.Interface())
+}
@@ -44,6 +44,17 @@ func (t *Time) GetAttr(name string) (Object, bool) {
 	}
 }
 
+// AttrNames returns the names of the attributes of the time.
+func (t *Time) AttrNames() []string {
+	return []string{
+		"after",
+		"before",
+		"format",
+		"unix",
+		"utc",
+	}
+}
+
 func (t *Time) Interface() interface{} {
 	return t.value
 }
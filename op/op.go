@@ -14,6 +14,7 @@ const (
 	ReturnValue Code = 4
 	Defer       Code = 5
 	Go          Code = 6
+	Yield       Code = 7
 
 	// Jump
 	JumpBackward          Code = 10
@@ -45,6 +46,7 @@ const (
 	BuildMap    Code = 51
 	BuildSet    Code = 52
 	BuildString Code = 53
+	BuildStruct Code = 54
 
 	// Containers
 	BinarySubscr Code = 60
@@ -76,6 +78,7 @@ const (
 	// Channels
 	Receive Code = 110
 	Send    Code = 111
+	Select  Code = 112
 
 	// Closures
 	LoadClosure Code = 120
@@ -83,6 +86,14 @@ const (
 
 	// Partials
 	Partial Code = 130
+
+	// Exceptions
+	PushExcept Code = 140
+	PopExcept  Code = 141
+	EndFinally Code = 142
+
+	// Pattern matching
+	MatchType Code = 150
 )
 
 // BinaryOpType describes a type of binary operation, as in an operation that
@@ -196,11 +207,13 @@ func init() {
 		{BuildMap, "BUILD_MAP", 1},
 		{BuildSet, "BUILD_SET", 1},
 		{BuildString, "BUILD_STRING", 1},
+		{BuildStruct, "BUILD_STRUCT", 1},
 		{Call, "CALL", 1},
 		{CompareOp, "COMPARE_OP", 1},
 		{ContainsOp, "CONTAINS_OP", 1},
 		{Copy, "COPY", 1},
 		{Defer, "DEFER", 0},
+		{EndFinally, "END_FINALLY", 0},
 		{False, "FALSE", 0},
 		{ForIter, "FOR_ITER", 2},
 		{FromImport, "FROM_IMPORT", 2},
@@ -218,15 +231,19 @@ func init() {
 		{LoadFree, "LOAD_FREE", 1},
 		{LoadGlobal, "LOAD_GLOBAL", 1},
 		{MakeCell, "MAKE_CELL", 2},
+		{MatchType, "MATCH_TYPE", 0},
 		{Nil, "NIL", 0},
 		{Nop, "NOP", 0},
 		{Partial, "PARTIAL", 1},
+		{PopExcept, "POP_EXCEPT", 0},
 		{PopJumpForwardIfFalse, "POP_JUMP_FORWARD_IF_FALSE", 1},
 		{PopJumpForwardIfTrue, "POP_JUMP_FORWARD_IF_TRUE", 1},
 		{PopTop, "POP_TOP", 0},
+		{PushExcept, "PUSH_EXCEPT", 1},
 		{Range, "RANGE", 0},
 		{Receive, "RECEIVE", 0},
 		{ReturnValue, "RETURN_VALUE", 0},
+		{Select, "SELECT", 2},
 		{Send, "SEND", 0},
 		{Slice, "SLICE", 0},
 		{StoreAttr, "STORE_ATTR", 1},
@@ -239,6 +256,7 @@ func init() {
 		{UnaryNegative, "UNARY_NEGATIVE", 0},
 		{UnaryNot, "UNARY_NOT", 0},
 		{Unpack, "UNPACK", 1},
+		{Yield, "YIELD", 0},
 	}
 	for _, o := range ops {
 		infos[o.op] = Info{
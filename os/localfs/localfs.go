@@ -11,7 +11,10 @@ import (
 	ros "github.com/itrn0/risor/os"
 )
 
-var _ ros.FS = (*Filesystem)(nil)
+var (
+	_ ros.FS      = (*Filesystem)(nil)
+	_ ros.LstatFS = (*Filesystem)(nil)
+)
 
 type Filesystem struct {
 	ctx  context.Context
@@ -180,6 +183,18 @@ func (fs *Filesystem) Stat(name string) (ros.FileInfo, error) {
 	return info, nil
 }
 
+func (fs *Filesystem) Lstat(name string) (ros.FileInfo, error) {
+	resolvedPath, err := fs.resolvePath(name, "lstat")
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(resolvedPath)
+	if err != nil {
+		return nil, ros.MassagePathError(fs.base, err)
+	}
+	return info, nil
+}
+
 func (fs *Filesystem) Symlink(oldname, newname string) error {
 	resolvedOld, err := fs.resolvePath(oldname, "symlink")
 	if err != nil {
@@ -63,6 +63,22 @@ type FS interface {
 	WalkDir(root string, fn WalkDirFunc) error
 }
 
+// LstatFS is implemented by filesystems that support symbolic links.
+type LstatFS interface {
+	// Lstat describes the named file. If the file is a symbolic link, the
+	// returned FileInfo describes the link rather than its target.
+	Lstat(name string) (FileInfo, error)
+}
+
+// Lstat describes the named file without following a symbolic link. On
+// filesystems that don't implement LstatFS it is equivalent to Stat.
+func Lstat(fsys FS, name string) (FileInfo, error) {
+	if lfs, ok := fsys.(LstatFS); ok {
+		return lfs.Lstat(name)
+	}
+	return fsys.Stat(name)
+}
+
 type OS interface {
 	FS
 	Args() []string
@@ -6,7 +6,10 @@ import (
 	"path/filepath"
 )
 
-var _ OS = (*SimpleOS)(nil)
+var (
+	_ OS      = (*SimpleOS)(nil)
+	_ LstatFS = (*SimpleOS)(nil)
+)
 
 type SimpleOS struct {
 	ctx  context.Context
@@ -107,6 +110,10 @@ func (osObj *SimpleOS) Stat(name string) (os.FileInfo, error) {
 	return os.Stat(name)
 }
 
+func (osObj *SimpleOS) Lstat(name string) (os.FileInfo, error) {
+	return os.Lstat(name)
+}
+
 func (osObj *SimpleOS) Symlink(oldname, newname string) error {
 	return os.Symlink(oldname, newname)
 }
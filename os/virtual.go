@@ -11,7 +11,10 @@ import (
 	"strings"
 )
 
-var _ OS = (*VirtualOS)(nil)
+var (
+	_ OS      = (*VirtualOS)(nil)
+	_ LstatFS = (*VirtualOS)(nil)
+)
 
 type ExitHandler func(int)
 
@@ -362,6 +365,14 @@ func (osObj *VirtualOS) Stat(name string) (os.FileInfo, error) {
 	return mount.Source.Stat(resolvedPath)
 }
 
+func (osObj *VirtualOS) Lstat(name string) (os.FileInfo, error) {
+	mount, resolvedPath, found := osObj.findMount(name)
+	if !found {
+		return nil, fmt.Errorf("no such file or directory: %s", name)
+	}
+	return Lstat(mount.Source, resolvedPath)
+}
+
 func (osObj *VirtualOS) Symlink(oldname, newname string) error {
 	mountOld, resolvedPathOld, found := osObj.findMount(oldname)
 	if !found {
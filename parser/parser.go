@@ -34,13 +34,7 @@ var statementTerminators = map[token.Type]bool{
 // Parse the provided input as Risor source code and return the AST. This is
 // shorthand way to create a Lexer and Parser and then call Parse on that.
 func Parse(ctx context.Context, input string, options ...Option) (*ast.Program, error) {
-	l := lexer.New(input)
-	p := New(l, options...)
-	if p.filename != "" {
-		// If an option specified a filename, pass that through to the lexer.
-		l.SetFilename(p.filename)
-	}
-	return p.Parse(ctx)
+	return New(lexer.New(input), options...).Parse(ctx)
 }
 
 // Option is a configuration function for a Lexer.
@@ -106,6 +100,11 @@ func New(l *lexer.Lexer, options ...Option) *Parser {
 	for _, opt := range options {
 		opt(p)
 	}
+	if p.filename != "" {
+		// If an option specified a filename, pass that through to the lexer
+		// before any tokens are read.
+		l.SetFilename(p.filename)
+	}
 
 	// Prime the token pump
 	p.nextToken() // makes curToken=<empty>, peekToken=token[0]
@@ -123,7 +122,7 @@ func New(l *lexer.Lexer, options ...Option) *Parser {
 	p.registerPrefix(token.FSTRING, p.parseString)
 	p.registerPrefix(token.FUNC, p.parseFunc)
 	p.registerPrefix(token.GO, p.parseGo)
-	p.registerPrefix(token.IDENT, p.parseIdent)
+	p.registerPrefix(token.IDENT, p.parseIdentOrKeyword)
 	p.registerPrefix(token.IF, p.parseIf)
 	p.registerPrefix(token.ILLEGAL, p.illegalToken)
 	p.registerPrefix(token.IMPORT, p.parseImport)
@@ -137,8 +136,10 @@ func New(l *lexer.Lexer, options ...Option) *Parser {
 	p.registerPrefix(token.PIPE, p.parsePrefixExpr)
 	p.registerPrefix(token.RANGE, p.parseRange)
 	p.registerPrefix(token.STRING, p.parseString)
+	p.registerPrefix(token.STRUCT, p.parseStruct)
 	p.registerPrefix(token.SWITCH, p.parseSwitch)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
+	p.registerPrefix(token.TRY, p.parseTry)
 	p.registerPrefix(token.SEND, p.parseReceive)
 
 	// Register infix functions
@@ -229,7 +230,11 @@ func (p *Parser) Parse(ctx context.Context) (*ast.Program, error) {
 			return nil, err
 		}
 	}
-	return ast.NewProgram(statements), p.err
+	var comments []*ast.Comment
+	for _, tok := range p.l.Comments() {
+		comments = append(comments, ast.NewComment(tok))
+	}
+	return ast.NewProgramWithComments(statements, comments), p.err
 }
 
 // registerPrefix registers a function for handling a prefix-based statement.
@@ -309,6 +314,8 @@ func (p *Parser) parseStatement() ast.Node {
 		stmt = p.parseConst()
 	case token.RETURN:
 		stmt = p.parseReturn()
+	case token.YIELD:
+		stmt = p.parseYield()
 	case token.BREAK:
 		stmt = p.parseBreak()
 	case token.CONTINUE:
@@ -316,7 +323,7 @@ func (p *Parser) parseStatement() ast.Node {
 	case token.NEWLINE:
 		stmt = nil
 	case token.IDENT:
-		if p.peekTokenIs(token.DECLARE) || p.peekTokenIs(token.COMMA) {
+		if p.peekTokenIs(token.DECLARE) || p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.COLON) {
 			stmt = p.parseDeclaration()
 		} else {
 			stmt = p.parseExpressionStatement()
@@ -344,6 +351,10 @@ func (p *Parser) parseVar() ast.Node {
 		}
 		idents = append(idents, ast.NewIdent(p.curToken))
 	}
+	typ, ok := p.parseVarType(idents)
+	if !ok {
+		return nil
+	}
 	if !p.expectPeek("var statement", token.ASSIGN) {
 		return nil
 	}
@@ -355,7 +366,26 @@ func (p *Parser) parseVar() ast.Node {
 	if len(idents) > 1 {
 		return ast.NewMultiVar(tok, idents, value, false)
 	}
-	return ast.NewVar(tok, idents[0], value)
+	stmt := ast.NewVar(tok, idents[0], value)
+	stmt.SetType(typ)
+	return stmt
+}
+
+// Parses the optional ": type" annotation following the name in a variable or
+// constant declaration. Only a single name may be annotated. The boolean
+// result is false if the annotation is invalid.
+func (p *Parser) parseVarType(idents []*ast.Ident) (*ast.TypeExpr, bool) {
+	if !p.peekTokenIs(token.COLON) {
+		return nil, true
+	}
+	p.nextToken() // move to the ":"
+	if len(idents) > 1 {
+		p.setTokenError(p.curToken, "type annotations are not supported when declaring multiple variables")
+		return nil, false
+	}
+	p.nextToken() // move to the type
+	typ := p.parseTypeExpr()
+	return typ, typ != nil
 }
 
 func (p *Parser) parseDeclaration() ast.Node {
@@ -368,6 +398,10 @@ func (p *Parser) parseDeclaration() ast.Node {
 		}
 		idents = append(idents, ast.NewIdent(p.curToken))
 	}
+	typ, ok := p.parseVarType(idents)
+	if !ok {
+		return nil
+	}
 	var isWalrus bool
 	switch p.peekToken.Type {
 	case token.ASSIGN:
@@ -378,6 +412,11 @@ func (p *Parser) parseDeclaration() ast.Node {
 		p.expectPeek("declaration statement", token.ASSIGN)
 		return nil
 	}
+	// An annotated name must be declared, e.g. "x: int := 1"
+	if typ != nil && !isWalrus {
+		p.expectPeek("declaration statement", token.DECLARE)
+		return nil
+	}
 	p.nextToken() // move to the assignment operator
 	p.nextToken() // move to the value
 	value := p.parseAssignmentValue()
@@ -387,7 +426,9 @@ func (p *Parser) parseDeclaration() ast.Node {
 	if len(idents) > 1 {
 		return ast.NewMultiVar(tok, idents, value, isWalrus)
 	}
-	return ast.NewDeclaration(tok, idents[0], value)
+	stmt := ast.NewDeclaration(tok, idents[0], value)
+	stmt.SetType(typ)
+	return stmt
 }
 
 func (p *Parser) parseConst() *ast.Const {
@@ -396,6 +437,10 @@ func (p *Parser) parseConst() *ast.Const {
 		return nil
 	}
 	ident := ast.NewIdent(p.curToken)
+	typ, ok := p.parseVarType([]*ast.Ident{ident})
+	if !ok {
+		return nil
+	}
 	if !p.expectPeek("const statement", token.ASSIGN) {
 		return nil
 	}
@@ -404,7 +449,9 @@ func (p *Parser) parseConst() *ast.Const {
 	if value == nil {
 		return nil
 	}
-	return ast.NewConst(tok, ident, value)
+	stmt := ast.NewConst(tok, ident, value)
+	stmt.SetType(typ)
+	return stmt
 }
 
 // Parses the right hand side of an assignment statement.
@@ -440,6 +487,22 @@ func (p *Parser) parseReturn() *ast.Return {
 	return ast.NewReturn(returnToken, value)
 }
 
+func (p *Parser) parseYield() *ast.Yield {
+	yieldToken := p.curToken
+	if p.peekTokenIs(token.SEMICOLON) ||
+		p.peekTokenIs(token.NEWLINE) ||
+		p.peekTokenIs(token.RBRACE) ||
+		p.peekTokenIs(token.EOF) {
+		return ast.NewYield(yieldToken, nil)
+	}
+	p.nextToken()
+	value := p.parseExpression(LOWEST)
+	if value == nil {
+		return nil
+	}
+	return ast.NewYield(yieldToken, value)
+}
+
 func (p *Parser) parseBreak() *ast.Control {
 	return ast.NewControl(p.curToken, nil)
 }
@@ -624,69 +687,484 @@ func (p *Parser) parseSwitch() ast.Node {
 		if !p.expectPeek("switch statement", token.COLON) {
 			return nil
 		}
-		// Now we are at the block of code to be executed for this case
-		p.nextToken()
-		p.eatNewlines()
-		// An empty case statement is valid
-		if p.curTokenIs(token.CASE) || p.curTokenIs(token.DEFAULT) || p.curTokenIs(token.RBRACE) {
-			if isDefaultCase {
-				defaultCaseCount++
-				if defaultCaseCount > 1 {
-					p.setTokenError(caseToken, "switch statement has multiple default blocks")
-					return nil
-				}
-				cases = append(cases, ast.NewDefaultCase(caseToken, nil))
-			} else {
-				cases = append(cases, ast.NewCase(caseToken, caseExprs, nil))
+		block, ok := p.parseCaseBlock()
+		if !ok {
+			return nil
+		}
+		if isDefaultCase {
+			defaultCaseCount++
+			if defaultCaseCount > 1 {
+				p.setTokenError(caseToken, "switch statement has multiple default blocks")
+				return nil
 			}
-			continue
+			cases = append(cases, ast.NewDefaultCase(caseToken, block))
+		} else {
+			cases = append(cases, ast.NewCase(caseToken, caseExprs, block))
 		}
-		blockFirstToken := p.curToken
-		var blockStatements []ast.Node
-		for {
-			// Skip over newlines and semicolons
-			for p.curTokenIs(token.NEWLINE) || p.curTokenIs(token.SEMICOLON) {
-				if err := p.nextToken(); err != nil {
+	}
+	return ast.NewSwitch(switchToken, switchValue, cases)
+}
+
+// Parses the statements in one case of a switch or match expression, which
+// end at the next case or the closing brace. The current token is the colon
+// that follows the case. Returns a nil block if the case is empty.
+func (p *Parser) parseCaseBlock() (*ast.Block, bool) {
+	p.nextToken()
+	p.eatNewlines()
+	// An empty case statement is valid
+	if p.curTokenIs(token.CASE) || p.curTokenIs(token.DEFAULT) || p.curTokenIs(token.RBRACE) {
+		return nil, true
+	}
+	blockFirstToken := p.curToken
+	var blockStatements []ast.Node
+	for {
+		// Skip over newlines and semicolons
+		for p.curTokenIs(token.NEWLINE) || p.curTokenIs(token.SEMICOLON) {
+			if err := p.nextToken(); err != nil {
+				return nil, false
+			}
+		}
+		// Any of these tokens indicate the end of the current case
+		if p.curTokenIs(token.CASE) ||
+			p.curTokenIs(token.DEFAULT) ||
+			p.curTokenIs(token.RBRACE) ||
+			p.curTokenIs(token.EOF) {
+			break
+		}
+		// Parse one statement
+		if s := p.parseStatement(); s != nil {
+			blockStatements = append(blockStatements, s)
+		}
+		if !p.curTokenIs(token.SEMICOLON) &&
+			!statementTerminators[p.peekToken.Type] &&
+			!p.peekTokenIs(token.CASE) &&
+			!p.peekTokenIs(token.DEFAULT) &&
+			!p.peekTokenIs(token.RBRACE) {
+			p.peekError("case statement", token.SEMICOLON, p.peekToken)
+			return nil, false
+		}
+		// Move to the token just beyond the statement
+		if err := p.nextToken(); err != nil {
+			return nil, false
+		}
+	}
+	return ast.NewBlock(blockFirstToken, blockStatements), true
+}
+
+// Tokens that may follow "match" to begin the value of a match expression.
+// Since "match" isn't a reserved keyword, it's otherwise an identifier, as in
+// regexp.match.
+var matchValueTokens = map[token.Type]bool{
+	token.IDENT:    true,
+	token.INT:      true,
+	token.FLOAT:    true,
+	token.STRING:   true,
+	token.BACKTICK: true,
+	token.TRUE:     true,
+	token.FALSE:    true,
+	token.NIL:      true,
+}
+
+// Tokens that may follow "match" to begin either the value of a match
+// expression, such as a list or map literal, or an index or call expression
+// on an identifier named "match".
+var ambiguousMatchValueTokens = map[token.Type]bool{
+	token.LBRACKET: true,
+	token.LBRACE:   true,
+	token.LPAREN:   true,
+}
+
+// Parses an identifier, or a match or select expression. Like "match",
+// "select" isn't a reserved keyword and begins a select expression only when
+// followed by a brace.
+func (p *Parser) parseIdentOrKeyword() ast.Node {
+	if p.curToken.Literal == "match" {
+		if matchValueTokens[p.peekToken.Type] ||
+			(ambiguousMatchValueTokens[p.peekToken.Type] && p.hasMatchValue()) {
+			return p.parseMatch()
+		}
+	}
+	if p.curToken.Literal == "select" && p.peekTokenIs(token.LBRACE) {
+		return p.parseSelect()
+	}
+	return p.parseIdent()
+}
+
+// Returns true if the tokens after the current "match" token form an
+// expression followed by a brace, which begins the cases of a match
+// expression. The parser and lexer are restored to their current state
+// afterwards.
+func (p *Parser) hasMatchValue() bool {
+	lexerState := *p.l
+	prevToken, curToken, peekToken := p.prevToken, p.curToken, p.peekToken
+	err, tern := p.err, p.tern
+	defer func() {
+		*p.l = lexerState
+		p.prevToken, p.curToken, p.peekToken = prevToken, curToken, peekToken
+		p.err, p.tern = err, tern
+	}()
+	p.nextToken()
+	value := p.parseExpression(LOWEST)
+	return value != nil && p.err == nil && p.peekTokenIs(token.LBRACE)
+}
+
+func (p *Parser) parseMatch() ast.Node {
+	matchToken := p.curToken
+	p.nextToken()
+	matchValue := p.parseExpression(LOWEST)
+	if matchValue == nil {
+		return nil
+	}
+	if !p.expectPeek("match expression", token.LBRACE) {
+		return nil
+	}
+	p.nextToken()
+	p.eatNewlines()
+	var cases []*ast.MatchCase
+	var defaultCaseCount int
+	// Each time through this loop we process one case
+	for !p.curTokenIs(token.RBRACE) {
+		if p.curTokenIs(token.EOF) {
+			p.setTokenError(p.prevToken, "unterminated match expression")
+			return nil
+		}
+		caseToken := p.curToken
+		var pattern ast.Pattern
+		var guard ast.Expression
+		if p.curTokenIs(token.DEFAULT) {
+			defaultCaseCount++
+			if defaultCaseCount > 1 {
+				p.setTokenError(caseToken, "match expression has multiple default blocks")
+				return nil
+			}
+		} else if p.curTokenIs(token.CASE) {
+			p.nextToken() // move to the token following "case"
+			if pattern = p.parsePattern(); pattern == nil {
+				return nil
+			}
+			if p.peekTokenIs(token.IF) {
+				p.nextToken() // move to the "if"
+				p.nextToken() // move to the guard expression
+				if guard = p.parseExpression(LOWEST); guard == nil {
 					return nil
 				}
 			}
-			// Any of these tokens indicate the end of the current case
-			if p.curTokenIs(token.CASE) ||
-				p.curTokenIs(token.DEFAULT) ||
-				p.curTokenIs(token.RBRACE) ||
-				p.curTokenIs(token.EOF) {
-				break
+		} else {
+			p.setTokenError(p.curToken, "expected 'case' or 'default' (got %s)", p.curToken.Literal)
+			return nil
+		}
+		if !p.expectPeek("match expression", token.COLON) {
+			return nil
+		}
+		block, ok := p.parseCaseBlock()
+		if !ok {
+			return nil
+		}
+		if pattern == nil {
+			cases = append(cases, ast.NewDefaultMatchCase(caseToken, block))
+		} else {
+			cases = append(cases, ast.NewMatchCase(caseToken, pattern, guard, block))
+		}
+	}
+	return ast.NewMatch(matchToken, matchValue, cases)
+}
+
+func (p *Parser) parseSelect() ast.Node {
+	selectToken := p.curToken
+	p.nextToken() // move to the "{"
+	p.nextToken()
+	p.eatNewlines()
+	var cases []*ast.SelectCase
+	var defaultCaseCount int
+	// Each time through this loop we process one case
+	for !p.curTokenIs(token.RBRACE) {
+		if p.curTokenIs(token.EOF) {
+			p.setTokenError(p.prevToken, "unterminated select statement")
+			return nil
+		}
+		caseToken := p.curToken
+		var comm ast.Node
+		var names []*ast.Ident
+		if p.curTokenIs(token.DEFAULT) {
+			defaultCaseCount++
+			if defaultCaseCount > 1 {
+				p.setTokenError(caseToken, "select statement has multiple default blocks")
+				return nil
 			}
-			// Parse one statement
-			if s := p.parseStatement(); s != nil {
-				blockStatements = append(blockStatements, s)
+		} else if p.curTokenIs(token.CASE) {
+			p.nextToken() // move to the token following "case"
+			if comm, names = p.parseSelectComm(); comm == nil {
+				return nil
 			}
-			if !p.curTokenIs(token.SEMICOLON) &&
-				!statementTerminators[p.peekToken.Type] &&
-				!p.peekTokenIs(token.CASE) &&
-				!p.peekTokenIs(token.DEFAULT) &&
-				!p.peekTokenIs(token.RBRACE) {
-				p.peekError("case statement", token.SEMICOLON, p.peekToken)
+		} else {
+			p.setTokenError(p.curToken, "expected 'case' or 'default' (got %s)", p.curToken.Literal)
+			return nil
+		}
+		if !p.expectPeek("select statement", token.COLON) {
+			return nil
+		}
+		block, ok := p.parseCaseBlock()
+		if !ok {
+			return nil
+		}
+		if comm == nil {
+			cases = append(cases, ast.NewDefaultSelectCase(caseToken, block))
+		} else {
+			cases = append(cases, ast.NewSelectCase(caseToken, comm, names, block))
+		}
+	}
+	return ast.NewSelect(selectToken, cases)
+}
+
+// Parses the channel operation of a case in a select statement. This is a
+// send, a receive, or a receive whose result is assigned to one or two new
+// variables, as in "case value, ok := <-ch". The names of any variables are
+// returned along with the operation.
+func (p *Parser) parseSelectComm() (ast.Node, []*ast.Ident) {
+	var names []*ast.Ident
+	if p.curTokenIs(token.IDENT) && (p.peekTokenIs(token.DECLARE) || p.peekTokenIs(token.COMMA)) {
+		names = append(names, ast.NewIdent(p.curToken))
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken() // move to the comma
+			if !p.expectPeek("select statement", token.IDENT) {
+				return nil, nil
+			}
+			names = append(names, ast.NewIdent(p.curToken))
+		}
+		if !p.expectPeek("select statement", token.DECLARE) {
+			return nil, nil
+		}
+		p.nextToken() // move to the receive expression
+	}
+	commToken := p.curToken
+	comm := p.parseNode(LOWEST)
+	if comm == nil || p.err != nil {
+		return nil, nil
+	}
+	switch comm.(type) {
+	case *ast.Receive:
+		return comm, names
+	case *ast.Send:
+		if len(names) == 0 {
+			return comm, nil
+		}
+	}
+	p.setTokenError(commToken, "select case must be a channel send or receive")
+	return nil, nil
+}
+
+// Parses a pattern in a case of a match expression, including alternatives
+// separated by "|". The current token is the first token of the pattern, and
+// the last token of the pattern is current on return.
+func (p *Parser) parsePattern() ast.Pattern {
+	first := p.parsePrimaryPattern()
+	if first == nil || !p.peekTokenIs(token.PIPE) {
+		return first
+	}
+	alternatives := []ast.Pattern{first}
+	for p.peekTokenIs(token.PIPE) {
+		p.nextToken() // move to the "|"
+		p.nextToken() // move to the next alternative
+		alt := p.parsePrimaryPattern()
+		if alt == nil {
+			return nil
+		}
+		alternatives = append(alternatives, alt)
+	}
+	return ast.NewOrPattern(first.Token(), alternatives)
+}
+
+func (p *Parser) parsePrimaryPattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.IDENT:
+		if p.peekTokenIs(token.PERIOD) || p.peekTokenIs(token.LPAREN) {
+			return p.parseTypePattern()
+		}
+		if p.curToken.Literal == "_" {
+			return ast.NewWildcardPattern(p.curToken)
+		}
+		return ast.NewBindPattern(ast.NewIdent(p.curToken))
+	case token.INT, token.FLOAT, token.STRING, token.BACKTICK, token.TRUE, token.FALSE, token.NIL:
+		value := p.parseExpressionPrefix()
+		if value == nil {
+			return nil
+		}
+		return ast.NewLiteralPattern(value)
+	case token.MINUS:
+		minusToken := p.curToken
+		if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.FLOAT) {
+			p.setTokenError(p.peekToken, "invalid pattern (expected a number after %q)", "-")
+			return nil
+		}
+		p.nextToken()
+		value := p.parseExpressionPrefix()
+		if value == nil {
+			return nil
+		}
+		return ast.NewLiteralPattern(ast.NewPrefix(minusToken, value))
+	case token.LBRACKET:
+		return p.parseListPattern()
+	case token.LBRACE:
+		return p.parseMapPattern()
+	default:
+		p.setTokenError(p.curToken, "invalid pattern (unexpected %q)", p.curToken.Literal)
+		return nil
+	}
+}
+
+// Parses the literal at the current token using its prefix parse function.
+func (p *Parser) parseExpressionPrefix() ast.Expression {
+	node := p.prefixParseFns[p.curToken.Type]()
+	if node == nil || p.err != nil {
+		return nil
+	}
+	expr, ok := node.(ast.Expression)
+	if !ok {
+		p.setTokenError(p.curToken, "invalid pattern (unexpected %q)", p.curToken.Literal)
+		return nil
+	}
+	return expr
+}
+
+// Parses a type pattern such as int(x), string() or sql.rows(r).
+func (p *Parser) parseTypePattern() ast.Pattern {
+	nameToken := p.curToken
+	name := p.curToken.Literal
+	for p.peekTokenIs(token.PERIOD) {
+		p.nextToken()
+		if !p.expectPeek("type pattern", token.IDENT) {
+			return nil
+		}
+		name += "." + p.curToken.Literal
+	}
+	if !p.expectPeek("type pattern", token.LPAREN) {
+		return nil
+	}
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return ast.NewTypePattern(nameToken, name, nil)
+	}
+	p.nextToken()
+	inner := p.parsePattern()
+	if inner == nil {
+		return nil
+	}
+	if !p.expectPeek("type pattern", token.RPAREN) {
+		return nil
+	}
+	return ast.NewTypePattern(nameToken, name, inner)
+}
+
+// Moves past any newlines that follow the current token.
+func (p *Parser) skipPeekNewlines() bool {
+	for p.peekTokenIs(token.NEWLINE) {
+		if err := p.nextToken(); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Parser) parseListPattern() ast.Pattern {
+	listToken := p.curToken
+	var elements []ast.Pattern
+	var hasRest bool
+	for {
+		if !p.skipPeekNewlines() {
+			return nil
+		}
+		if p.peekTokenIs(token.RBRACKET) {
+			p.nextToken()
+			break
+		}
+		p.nextToken() // move to the element
+		if p.curTokenIs(token.ELLIPSIS) {
+			if hasRest {
+				p.setTokenError(p.curToken, "list pattern has more than one rest element")
 				return nil
 			}
-			// Move to the token just beyond the statement
-			if err := p.nextToken(); err != nil {
+			hasRest = true
+			restToken := p.curToken
+			var ident *ast.Ident
+			if p.peekTokenIs(token.IDENT) {
+				p.nextToken()
+				if p.curToken.Literal != "_" {
+					ident = ast.NewIdent(p.curToken)
+				}
+			}
+			elements = append(elements, ast.NewRestPattern(restToken, ident))
+		} else {
+			element := p.parsePattern()
+			if element == nil {
 				return nil
 			}
+			elements = append(elements, element)
 		}
-		block := ast.NewBlock(blockFirstToken, blockStatements)
-		if isDefaultCase {
-			defaultCaseCount++
-			if defaultCaseCount > 1 {
-				p.setTokenError(caseToken, "switch statement has multiple default blocks")
+		if !p.skipPeekNewlines() {
+			return nil
+		}
+		if p.peekTokenIs(token.RBRACKET) {
+			p.nextToken()
+			break
+		}
+		if !p.expectPeek("list pattern", token.COMMA) {
+			return nil
+		}
+	}
+	return ast.NewListPattern(listToken, elements)
+}
+
+func (p *Parser) parseMapPattern() ast.Pattern {
+	mapToken := p.curToken
+	var keys []ast.Expression
+	var values []ast.Pattern
+	for {
+		if !p.skipPeekNewlines() {
+			return nil
+		}
+		if p.peekTokenIs(token.RBRACE) {
+			p.nextToken()
+			break
+		}
+		p.nextToken() // move to the key
+		var key ast.Expression
+		switch p.curToken.Type {
+		case token.STRING, token.BACKTICK:
+			key = ast.NewString(p.curToken)
+		case token.IDENT:
+			key = ast.NewIdent(p.curToken)
+		default:
+			p.setTokenError(p.curToken, "invalid map pattern key (unexpected %q)", p.curToken.Literal)
+			return nil
+		}
+		var value ast.Pattern
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken() // move to the ":"
+			p.nextToken() // move to the value
+			if value = p.parsePattern(); value == nil {
 				return nil
 			}
-			cases = append(cases, ast.NewDefaultCase(caseToken, block))
+		} else if ident, ok := key.(*ast.Ident); ok {
+			// {name} is shorthand for {name: name}
+			value = ast.NewBindPattern(ident)
 		} else {
-			cases = append(cases, ast.NewCase(caseToken, caseExprs, block))
+			p.peekError("map pattern", token.COLON, p.peekToken)
+			return nil
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+		if !p.skipPeekNewlines() {
+			return nil
+		}
+		if p.peekTokenIs(token.RBRACE) {
+			p.nextToken()
+			break
+		}
+		if !p.expectPeek("map pattern", token.COMMA) {
+			return nil
 		}
 	}
-	return ast.NewSwitch(switchToken, switchValue, cases)
+	return ast.NewMapPattern(mapToken, keys, values)
 }
 
 func (p *Parser) parseImport() ast.Node {
@@ -1062,42 +1540,104 @@ func (p *Parser) parseFunc() ast.Node {
 	if !p.expectPeek("function", token.LPAREN) { // Move to the "("
 		return nil
 	}
-	defaults, params := p.parseFuncParams()
+	defaults, params, types := p.parseFuncParams()
+	if defaults == nil {
+		return nil
+	}
+	// A name following the parameters of an unnamed function means that the
+	// "parameters" were actually a method receiver, e.g. func (p Point) dist()
+	if ident == nil && p.peekTokenIs(token.IDENT) {
+		if len(types) > 0 {
+			p.setTokenError(funcToken, "invalid method receiver (expected a name and a struct type)")
+			return nil
+		}
+		return p.parseMethod(funcToken, params, defaults)
+	}
+	result, ok := p.parseResultType()
+	if !ok {
+		return nil
+	}
 	if !p.expectPeek("function", token.LBRACE) { // move to the "{"
 		return nil
 	}
-	return ast.NewFunc(funcToken, ident, params, defaults, p.parseBlock())
+	fn := ast.NewFunc(funcToken, ident, params, defaults, p.parseBlock())
+	fn.SetTypes(types, result)
+	return fn
 }
 
-func (p *Parser) parseFuncParams() (map[string]ast.Expression, []*ast.Ident) {
+func (p *Parser) parseMethod(funcToken token.Token, receiver []*ast.Ident, receiverDefaults map[string]ast.Expression) ast.Node {
+	if len(receiver) != 2 || len(receiverDefaults) > 0 {
+		p.setTokenError(funcToken, "invalid method receiver (expected a name and a struct type)")
+		return nil
+	}
+	p.nextToken() // move to the method name
+	ident := ast.NewIdent(p.curToken)
+	if !p.expectPeek("method", token.LPAREN) { // move to the "("
+		return nil
+	}
+	defaults, params, types := p.parseFuncParams()
+	if defaults == nil {
+		return nil
+	}
+	result, ok := p.parseResultType()
+	if !ok {
+		return nil
+	}
+	if !p.expectPeek("method", token.LBRACE) { // move to the "{"
+		return nil
+	}
+	body := p.parseBlock()
+	if body == nil {
+		return nil
+	}
+	method := ast.NewMethod(funcToken, receiver[0], receiver[1], ident, params, defaults, body)
+	method.SetTypes(types, result)
+	return method
+}
+
+func (p *Parser) parseFuncParams() (map[string]ast.Expression, []*ast.Ident, map[string]*ast.TypeExpr) {
 	// If the next parameter is ")", then there are no parameters
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return map[string]ast.Expression{}, nil
+		return map[string]ast.Expression{}, nil, nil
 	}
 	defaults := map[string]ast.Expression{}
 	params := make([]*ast.Ident, 0)
+	var types map[string]*ast.TypeExpr
 	p.nextToken()
 	for !p.curTokenIs(token.RPAREN) { // Keep going until we find a ")"
 		if p.curTokenIs(token.EOF) {
 			p.setTokenError(p.prevToken, "unterminated function parameters")
-			return nil, nil
+			return nil, nil, nil
 		}
 		if !p.curTokenIs(token.IDENT) {
 			p.setTokenError(p.curToken, "expected an identifier (got %s)", p.curToken.Literal)
-			return nil, nil
+			return nil, nil, nil
 		}
 		ident := ast.NewIdent(p.curToken)
 		params = append(params, ident)
 		if err := p.nextToken(); err != nil {
-			return nil, nil
+			return nil, nil, nil
+		}
+		// If there is ":type" after the name then the parameter is annotated
+		if p.curTokenIs(token.COLON) {
+			p.nextToken()
+			typ := p.parseTypeExpr()
+			if typ == nil {
+				return nil, nil, nil
+			}
+			if types == nil {
+				types = map[string]*ast.TypeExpr{}
+			}
+			types[ident.String()] = typ
+			p.nextToken()
 		}
 		// If there is "=expr" after the name then expr is a default value
 		if p.curTokenIs(token.ASSIGN) {
 			p.nextToken()
 			expr := p.parseExpression(LOWEST)
 			if expr == nil {
-				return nil, nil
+				return nil, nil, nil
 			}
 			defaults[ident.String()] = expr
 			p.nextToken()
@@ -1106,7 +1646,68 @@ func (p *Parser) parseFuncParams() (map[string]ast.Expression, []*ast.Ident) {
 			p.nextToken()
 		}
 	}
-	return defaults, params
+	return defaults, params, types
+}
+
+// Parses the optional "-> type" annotation following function parameters.
+// The boolean result is false if the annotation is invalid.
+func (p *Parser) parseResultType() (*ast.TypeExpr, bool) {
+	if !p.peekTokenIs(token.ARROW) {
+		return nil, true
+	}
+	p.nextToken() // move to the "->"
+	p.nextToken() // move to the type
+	typ := p.parseTypeExpr()
+	return typ, typ != nil
+}
+
+// Parses a type annotation such as "int", "list[string]" or "string | nil".
+// The current token is the first token of the annotation.
+func (p *Parser) parseTypeExpr() *ast.TypeExpr {
+	tok := p.curToken
+	typ := p.parseNamedTypeExpr()
+	if typ == nil || !p.peekTokenIs(token.PIPE) {
+		return typ
+	}
+	alternatives := []*ast.TypeExpr{typ}
+	for p.peekTokenIs(token.PIPE) {
+		p.nextToken() // move to the "|"
+		p.nextToken() // move to the next type
+		alt := p.parseNamedTypeExpr()
+		if alt == nil {
+			return nil
+		}
+		alternatives = append(alternatives, alt)
+	}
+	return ast.NewUnionTypeExpr(tok, alternatives)
+}
+
+func (p *Parser) parseNamedTypeExpr() *ast.TypeExpr {
+	tok := p.curToken
+	if !p.curTokenIs(token.IDENT) && !p.curTokenIs(token.NIL) {
+		p.setTokenError(tok, "expected a type (got %s)", tok.Literal)
+		return nil
+	}
+	var params []*ast.TypeExpr
+	if p.peekTokenIs(token.LBRACKET) {
+		p.nextToken() // move to the "["
+		for {
+			p.nextToken() // move to the type parameter
+			param := p.parseTypeExpr()
+			if param == nil {
+				return nil
+			}
+			params = append(params, param)
+			if !p.peekTokenIs(token.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+		if !p.expectPeek("type", token.RBRACKET) {
+			return nil
+		}
+	}
+	return ast.NewTypeExpr(tok, tok.Literal, params)
 }
 
 func (p *Parser) parseGo() ast.Node {
@@ -1159,6 +1760,106 @@ func (p *Parser) parseDefer() ast.Node {
 	}
 }
 
+func (p *Parser) parseTry() ast.Node {
+	tryToken := p.curToken
+	// The try builtin predates try statements, so treat "try" as a regular
+	// identifier when it isn't followed by a block, e.g. try(func() { ... })
+	if !p.peekTokenIs(token.LBRACE) {
+		return ast.NewIdent(tryToken)
+	}
+	p.nextToken() // move to the "{"
+	body := p.parseBlock()
+	if body == nil {
+		return nil
+	}
+	var catchIdent *ast.Ident
+	var catchBlock, finallyBlock *ast.Block
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken() // move to the "catch"
+		if p.peekTokenIs(token.IDENT) {
+			p.nextToken() // move to the error variable name
+			catchIdent = ast.NewIdent(p.curToken)
+		}
+		if !p.expectPeek("a catch block", token.LBRACE) {
+			return nil
+		}
+		catchBlock = p.parseBlock()
+		if catchBlock == nil {
+			return nil
+		}
+	}
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken() // move to the "finally"
+		if !p.expectPeek("a finally block", token.LBRACE) {
+			return nil
+		}
+		finallyBlock = p.parseBlock()
+		if finallyBlock == nil {
+			return nil
+		}
+	}
+	if catchBlock == nil && finallyBlock == nil {
+		p.setTokenError(tryToken, "try statement requires a catch or finally block")
+		return nil
+	}
+	return ast.NewTry(tryToken, body, catchIdent, catchBlock, finallyBlock)
+}
+
+func (p *Parser) parseStruct() ast.Node {
+	structToken := p.curToken
+	if !p.expectPeek("struct", token.IDENT) { // move to the struct name
+		return nil
+	}
+	name := ast.NewIdent(p.curToken)
+	if !p.expectPeek("struct", token.LBRACE) { // move to the "{"
+		return nil
+	}
+	var fields []*ast.Ident
+	defaults := map[string]ast.Expression{}
+	seen := map[string]bool{}
+	// Fields are separated by commas and/or newlines
+	for {
+		for p.peekTokenIs(token.NEWLINE) || p.peekTokenIs(token.COMMA) {
+			if err := p.nextToken(); err != nil {
+				return nil
+			}
+		}
+		if p.peekTokenIs(token.RBRACE) {
+			p.nextToken()
+			break
+		}
+		if p.peekTokenIs(token.EOF) {
+			p.setTokenError(p.curToken, "unterminated struct")
+			return nil
+		}
+		if !p.expectPeek("struct field", token.IDENT) { // move to the field name
+			return nil
+		}
+		field := ast.NewIdent(p.curToken)
+		if seen[field.Literal()] {
+			p.setTokenError(p.curToken, "duplicate struct field: %s", field.Literal())
+			return nil
+		}
+		seen[field.Literal()] = true
+		fields = append(fields, field)
+		// If there is "=expr" after the name then expr is a default value
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken() // move to the "="
+			p.nextToken() // move to the expression
+			expr := p.parseExpression(LOWEST)
+			if expr == nil {
+				return nil
+			}
+			defaults[field.Literal()] = expr
+		}
+		if !p.peekTokenIs(token.COMMA) && !p.peekTokenIs(token.NEWLINE) && !p.peekTokenIs(token.RBRACE) {
+			p.peekError("struct", token.COMMA, p.peekToken)
+			return nil
+		}
+	}
+	return ast.NewStruct(structToken, name, fields, defaults)
+}
+
 func (p *Parser) parseString() ast.Node {
 	strToken := p.curToken
 	if strToken.Type == token.BACKTICK || strToken.Type == token.STRING {
@@ -201,6 +201,25 @@ func TestReturn(t *testing.T) {
 	}
 }
 
+func TestYield(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"yield 1", "yield 1"},
+		{"yield x + 1;", "yield (x + 1)"},
+		{"yield", "yield"},
+	}
+	for _, tt := range tests {
+		program, err := Parse(context.Background(), tt.input)
+		require.Nil(t, err)
+		require.Len(t, program.Statements(), 1)
+		stmt, ok := program.First().(*ast.Yield)
+		require.True(t, ok)
+		require.Equal(t, tt.expected, stmt.String())
+	}
+}
+
 func TestIdent(t *testing.T) {
 	program, err := Parse(context.Background(), "foobar;")
 	require.Nil(t, err)
@@ -595,6 +614,178 @@ func TestSwitch(t *testing.T) {
 	require.Len(t, choice2.Expressions(), 0)
 }
 
+func TestMatch(t *testing.T) {
+	input := `match val {
+	case [first, ...rest] if first > 1:
+		rest
+	case {"kind": "pod", name: n, id}:
+	case int(x) | float(x):
+	case sql.rows():
+	case -1 | "a" | nil | _:
+	default:
+		0
+}`
+	program, err := Parse(context.Background(), input)
+	require.Nil(t, err)
+	require.Len(t, program.Statements(), 1)
+	matchExpr, ok := program.First().(*ast.Match)
+	require.True(t, ok)
+	require.Equal(t, "val", matchExpr.Value().String())
+	cases := matchExpr.Cases()
+	require.Len(t, cases, 6)
+	require.Equal(t, "[first, ...rest]", cases[0].Pattern().String())
+	require.Equal(t, "(first > 1)", cases[0].Guard().String())
+	require.Len(t, cases[0].Block().Statements(), 1)
+	require.Equal(t, `{"kind": "pod", name: n, id: id}`, cases[1].Pattern().String())
+	require.Nil(t, cases[1].Block())
+	require.Equal(t, "int(x) | float(x)", cases[2].Pattern().String())
+	typePattern, ok := cases[3].Pattern().(*ast.TypePattern)
+	require.True(t, ok)
+	require.Equal(t, "sql.rows", typePattern.Name())
+	require.Nil(t, typePattern.Pattern())
+	or, ok := cases[4].Pattern().(*ast.OrPattern)
+	require.True(t, ok)
+	require.Len(t, or.Alternatives(), 4)
+	require.IsType(t, &ast.LiteralPattern{}, or.Alternatives()[0])
+	require.IsType(t, &ast.WildcardPattern{}, or.Alternatives()[3])
+	require.True(t, cases[5].IsDefault())
+}
+
+func TestMatchLiteralValues(t *testing.T) {
+	tests := []struct {
+		input string
+		value string
+	}{
+		{"match [1, [2, 3]] { case [a, [b, c]]: a + b + c }", "[1, [2, 3]]"},
+		{`match {"a": 1} { case {"a": a}: a }`, `{"a":1}`},
+		{"match (a + b) { case 3: true }", "(a + b)"},
+		{"match [1][0] { case 1: true }", "([1][0])"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program, err := Parse(context.Background(), tt.input)
+			require.Nil(t, err)
+			matchExpr, ok := program.First().(*ast.Match)
+			require.True(t, ok)
+			require.Equal(t, tt.value, matchExpr.Value().String())
+			require.Len(t, matchExpr.Cases(), 1)
+		})
+	}
+}
+
+func TestMatchIdentifier(t *testing.T) {
+	// "match" is only a keyword when followed by the value to match
+	inputs := []string{
+		`match := 1; match + 1`,
+		`regexp.match("a", "b")`,
+		`match("a")`,
+		`x := {match: 1}`,
+		`match := [1]; match[0]`,
+		`match := func(x) { x }; y := match(1)`,
+		`match := true; if match { print(1) }`,
+		`match := [[1]]; match[0][0]`,
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			_, err := Parse(context.Background(), input)
+			require.Nil(t, err)
+		})
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"match x { case [a, ...b, ...c]: 1 }", "parse error: list pattern has more than one rest element"},
+		{"match x { case a + 1: 1 }", "parse error: unexpected + while parsing match expression (expected :)"},
+		{"match x { case (a): 1 }", `parse error: invalid pattern (unexpected "(")`},
+		{"match x { case {1: a}: 1 }", `parse error: invalid map pattern key (unexpected "1")`},
+		{"match x { default: 1\ndefault: 2 }", "parse error: match expression has multiple default blocks"},
+		{"match x { case 1: 2", "parse error: unterminated match expression"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse(context.Background(), tt.input)
+			require.NotNil(t, err)
+			require.Equal(t, tt.err, err.Error())
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	input := `select {
+	case v := <-a:
+		v
+	case v, ok := <-b:
+	case <-c:
+	case d <- 1:
+		print("sent")
+	default:
+}`
+	program, err := Parse(context.Background(), input)
+	require.Nil(t, err)
+	require.Len(t, program.Statements(), 1)
+	selectExpr, ok := program.First().(*ast.Select)
+	require.True(t, ok)
+	cases := selectExpr.Cases()
+	require.Len(t, cases, 5)
+	require.Equal(t, "<- a", cases[0].Comm().String())
+	require.Len(t, cases[0].Names(), 1)
+	require.Equal(t, "v", cases[0].Names()[0].String())
+	require.Len(t, cases[0].Block().Statements(), 1)
+	require.Len(t, cases[1].Names(), 2)
+	require.Equal(t, "ok", cases[1].Names()[1].String())
+	require.Nil(t, cases[1].Block())
+	_, ok = cases[2].Comm().(*ast.Receive)
+	require.True(t, ok)
+	require.Len(t, cases[2].Names(), 0)
+	send, ok := cases[3].Comm().(*ast.Send)
+	require.True(t, ok)
+	require.Equal(t, "d <- 1", send.String())
+	require.True(t, cases[4].IsDefault())
+	require.Nil(t, cases[4].Block())
+}
+
+func TestSelectIdentifier(t *testing.T) {
+	// "select" is only a keyword when followed by a brace
+	inputs := []string{
+		`select := 1; select + 1`,
+		`db.select("a")`,
+		`select("a")`,
+		`x := {select: 1}`,
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			_, err := Parse(context.Background(), input)
+			require.Nil(t, err)
+		})
+	}
+}
+
+func TestSelectErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"select { case x: 1 }", "parse error: select case must be a channel send or receive"},
+		{"select { case x := y: 1 }", "parse error: select case must be a channel send or receive"},
+		{"select { case x := c <- 1: 1 }", "parse error: select case must be a channel send or receive"},
+		{"select { case x, 1 := <-c: 1 }", "parse error: unexpected 1 while parsing select statement (expected identifier)"},
+		{"select { default: 1\ndefault: 2 }", "parse error: select statement has multiple default blocks"},
+		{"select { case <-c: 2", "parse error: unterminated select statement"},
+		{"select { x }", "parse error: expected 'case' or 'default' (got x)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse(context.Background(), tt.input)
+			require.NotNil(t, err)
+			require.Equal(t, tt.err, err.Error())
+		})
+	}
+}
+
 func TestMultiDefault(t *testing.T) {
 	input := `
 switch val {
@@ -1091,6 +1282,172 @@ func TestInvalidDeferStatements(t *testing.T) {
 	}
 }
 
+func TestTryStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { x() } catch { 1 }", "try { x() } catch { 1 }"},
+		{"try { x() } catch err { err }", "try { x() } catch err { err }"},
+		{"try { x() } finally { y() }", "try { x() } finally { y() }"},
+		{"try {\n\tx()\n} catch err {\n\terr\n} finally {\n\ty()\n}",
+			"try { x() } catch err { err } finally { y() }"},
+	}
+	for _, tt := range tests {
+		result, err := Parse(context.Background(), tt.input)
+		require.Nil(t, err)
+		require.Len(t, result.Statements(), 1)
+		stmt, ok := result.First().(*ast.Try)
+		require.True(t, ok)
+		require.Equal(t, tt.expected, stmt.String())
+	}
+}
+
+func TestTryBuiltinStillParses(t *testing.T) {
+	result, err := Parse(context.Background(), "try(func() { x() }, 1)")
+	require.Nil(t, err)
+	call, ok := result.First().(*ast.Call)
+	require.True(t, ok)
+	require.Equal(t, "try", call.Function().String())
+}
+
+func TestInvalidTryStatements(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"try { x() }", "parse error: try statement requires a catch or finally block"},
+		{"try { x() } catch err", "parse error: unexpected end of file while parsing a catch block (expected {)"},
+		{"try { x() } finally", "parse error: unexpected end of file while parsing a finally block (expected {)"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(context.Background(), tt.input)
+		require.NotNil(t, err)
+		require.Equal(t, tt.err, err.Error())
+	}
+}
+
+func TestStructStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"struct Point { x, y }", "struct Point { x, y }"},
+		{"struct Point { x = 1, y = \"a\" }", "struct Point { x = 1, y = \"a\" }"},
+		{"struct Point {\n\tx\n\ty = 0,\n}", "struct Point { x, y = 0 }"},
+		{"struct Empty {}", "struct Empty {}"},
+	}
+	for _, tt := range tests {
+		result, err := Parse(context.Background(), tt.input)
+		require.Nil(t, err)
+		require.Len(t, result.Statements(), 1)
+		stmt, ok := result.First().(*ast.Struct)
+		require.True(t, ok)
+		require.Equal(t, tt.expected, stmt.String())
+	}
+}
+
+func TestMethod(t *testing.T) {
+	result, err := Parse(context.Background(), "func (p Point) dist(other, scale=1) { p.x }")
+	require.Nil(t, err)
+	fn, ok := result.First().(*ast.Func)
+	require.True(t, ok)
+	require.True(t, fn.IsMethod())
+	require.Equal(t, "p", fn.Receiver().Literal())
+	require.Equal(t, "Point", fn.ReceiverType().Literal())
+	require.Equal(t, "dist", fn.Name().Literal())
+	require.Equal(t, []string{"other", "scale"}, fn.ParameterNames())
+	require.Equal(t, "func (p Point) dist(other, scale) { p.x }", fn.String())
+}
+
+func TestInvalidStructStatements(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"struct { x }", "parse error: unexpected { while parsing struct (expected identifier)"},
+		{"struct Point { x y }", "parse error: unexpected y while parsing struct (expected ,)"},
+		{"struct Point { x, x }", "parse error: duplicate struct field: x"},
+		{"struct Point { x,", "parse error: unterminated struct"},
+		{"struct Point { x", "parse error: unexpected end of file while parsing struct (expected ,)"},
+		{"func (p) dist() {}", "parse error: invalid method receiver (expected a name and a struct type)"},
+		{"func (p Point) dist {}", "parse error: unexpected { while parsing method (expected ()"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(context.Background(), tt.input)
+		require.NotNil(t, err)
+		require.Equal(t, tt.err, err.Error())
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"func add(a: int, b: int) -> int { a + b }", "func add(a: int, b: int) -> int { (a + b) }"},
+		{"func f(a, b: string = \"x\") { a }", "func f(a, b: string) { a }"},
+		{"func f() -> list[int] { [] }", "func f() -> list[int] { [] }"},
+		{"func f(m: map[string | nil]) -> int | nil { nil }", "func f(m: map[string | nil]) -> int | nil { nil }"},
+		{"func (p Point) dist(q: Point) -> float { 0.0 }", "func (p Point) dist(q: Point) -> float { 0.0 }"},
+		{"var x: int = 1", "var x: int = 1"},
+		{"x: float := 1.5", "x: float := 1.5"},
+		{"const name: string = \"a\"", "const name: string = \"a\""},
+		{"f := func(x: int) -> bool { x > 0 }", "f := func(x: int) -> bool { (x > 0) }"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := Parse(context.Background(), tt.input)
+			require.Nil(t, err)
+			require.Len(t, result.Statements(), 1)
+			require.Equal(t, tt.expected, result.First().String())
+		})
+	}
+}
+
+func TestTypeAnnotationNodes(t *testing.T) {
+	result, err := Parse(context.Background(), "func f(a: list[int], b) -> string | nil { }")
+	require.Nil(t, err)
+	fn, ok := result.First().(*ast.Func)
+	require.True(t, ok)
+	a := fn.ParameterType("a")
+	require.NotNil(t, a)
+	require.Equal(t, "list", a.Name())
+	require.Len(t, a.Params(), 1)
+	require.Equal(t, "int", a.Params()[0].Name())
+	require.Equal(t, 10, a.Token().StartPosition.Column)
+	require.Nil(t, fn.ParameterType("b"))
+	ret := fn.ResultType()
+	require.True(t, ret.IsUnion())
+	require.Len(t, ret.Alternatives(), 2)
+	require.Equal(t, "nil", ret.Alternatives()[1].Name())
+
+	result, err = Parse(context.Background(), "var x = 1")
+	require.Nil(t, err)
+	require.Nil(t, result.First().(*ast.Var).Type())
+}
+
+func TestInvalidTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"func f(a: ) {}", "parse error: expected a type (got ))"},
+		{"func f(a: list[int) {}", "parse error: unexpected ) while parsing type (expected ])"},
+		{"func f() -> {}", "parse error: expected a type (got {)"},
+		{"var a, b: int = [1, 2]", "parse error: type annotations are not supported when declaring multiple variables"},
+		{"x: int = 1", "parse error: unexpected = while parsing declaration statement (expected :=)"},
+		{"func (p: Point) dist() {}", "parse error: invalid method receiver (expected a name and a struct type)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse(context.Background(), tt.input)
+			require.NotNil(t, err)
+			require.Equal(t, tt.err, err.Error())
+		})
+	}
+}
+
 func TestFromImport(t *testing.T) {
 	tests := []struct {
 		input    string
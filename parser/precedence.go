@@ -56,3 +56,12 @@ var precedences = map[token.Type]int{
 	token.RANGE:           PREFIX,
 	token.SEND:            CALL,
 }
+
+// Precedence returns the precedence of the given operator token type, or
+// LOWEST if the token type is not an operator.
+func Precedence(t token.Type) int {
+	if p, ok := precedences[t]; ok {
+		return p
+	}
+	return LOWEST
+}
@@ -0,0 +1,505 @@
+// Package policy provides capability policies that control which privileged
+// functions a Risor evaluation may call, and with what arguments.
+//
+// Functions are named by their module path and attribute, e.g. "os.read_file"
+// or "http.get". Calling a module directly, e.g. exec("ls"), uses the name of
+// the module. Builtins that aren't part of a module, such as fetch and open,
+// are named as they are. A name ending in ".*" matches a module, everything
+// within it, and any call to the module itself. The name "*" matches all
+// functions.
+//
+// Some functions access the same files or environment as functions of the os
+// module: cat reads files like os.read_file and archive.extract writes them
+// like os.write_file. These are listed in DefaultAliases, and calls to them
+// are also checked against the rules for the corresponding os functions. A
+// rule such as Deny("os.read_file") therefore applies to cat as well.
+//
+// Rules are checked when a named function is called, not when a resource is
+// accessed. Methods of the objects a function returns aren't checked, so a
+// file returned by os.open can be read even if os.read_file is denied. To
+// keep a script from reading files, deny os.open (which also covers open)
+// along with os.read_file.
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/itrn0/risor/errz"
+	"github.com/itrn0/risor/object"
+	ros "github.com/itrn0/risor/os"
+)
+
+// DefaultPrivileged lists the functions that are considered privileged by
+// default, because they access the filesystem, the network, the environment
+// or other processes.
+var DefaultPrivileged = []string{
+	"archive.*",
+	"aws.*",
+	"cat",
+	"cd",
+	"cp",
+	"dns.*",
+	"exec.*",
+	"fetch",
+	"getenv",
+	"gha.*",
+	"http.*",
+	"k8s.*",
+	"ls",
+	"net.*",
+	"nslookup",
+	"open",
+	"os.*",
+	"pgx.*",
+	"setenv",
+	"sql.*",
+	"unsetenv",
+	"vault.*",
+}
+
+// Alias relates a function to a function of the os module that accesses the
+// same resources, so that rules for the os function apply to it too.
+type Alias struct {
+	// Name is the name of the os function, e.g. "os.read_file".
+	Name string
+
+	// Args returns the arguments to check against the rules for the os
+	// function, given the arguments of the call. A call that accesses several
+	// resources, such as cat with several files, is checked once for each.
+	Args func(args []object.Object) [][]object.Object
+}
+
+// DefaultAliases lists the functions that access the same resources as
+// functions of the os module.
+var DefaultAliases = map[string][]Alias{
+	"archive.create":  {{Name: "os.read_file", Args: archiveSources}, {Name: "os.write_file", Args: argAt(0)}},
+	"archive.extract": {{Name: "os.read_file", Args: argAt(0)}, {Name: "os.write_file", Args: argAt(1)}},
+	"archive.list":    {{Name: "os.read_file", Args: argAt(0)}},
+	"cat":             {{Name: "os.read_file", Args: eachArg}},
+	"cd":              {{Name: "os.chdir", Args: argAt(0)}},
+	"cp":              {{Name: "os.read_file", Args: argAt(0)}, {Name: "os.write_file", Args: argAt(1)}},
+	"getenv":          {{Name: "os.getenv", Args: argAt(0)}},
+	"ls":              {{Name: "os.read_dir", Args: dirArg}},
+	"open":            {{Name: "os.open", Args: argAt(0)}},
+	"setenv":          {{Name: "os.setenv", Args: argAt(0)}},
+	"unsetenv":        {{Name: "os.unsetenv", Args: argAt(0)}},
+}
+
+// Returns the argument at index i, along with any that follow it.
+func argAt(i int) func(args []object.Object) [][]object.Object {
+	return func(args []object.Object) [][]object.Object {
+		if i >= len(args) {
+			return [][]object.Object{nil}
+		}
+		return [][]object.Object{args[i:]}
+	}
+}
+
+func eachArg(args []object.Object) [][]object.Object {
+	if len(args) == 0 {
+		return [][]object.Object{nil}
+	}
+	result := make([][]object.Object, len(args))
+	for i, arg := range args {
+		result[i] = []object.Object{arg}
+	}
+	return result
+}
+
+// ls lists the working directory when called without arguments
+func dirArg(args []object.Object) [][]object.Object {
+	if len(args) == 0 {
+		return [][]object.Object{{object.NewString(".")}}
+	}
+	return [][]object.Object{args}
+}
+
+// Returns the paths read by archive.create, which are given as a list that is
+// relative to the optional "dir" option.
+func archiveSources(args []object.Object) [][]object.Object {
+	if len(args) < 2 {
+		return [][]object.Object{nil}
+	}
+	paths, ok := args[1].(*object.List)
+	if !ok {
+		return [][]object.Object{{args[1]}}
+	}
+	var dir string
+	if len(args) > 2 {
+		if opts, ok := args[2].(*object.Map); ok {
+			if s, ok := opts.Get("dir").(*object.String); ok {
+				dir = s.Value()
+			}
+		}
+	}
+	var result [][]object.Object
+	for _, path := range paths.Value() {
+		s, ok := path.(*object.String)
+		if !ok {
+			result = append(result, []object.Object{path})
+			continue
+		}
+		result = append(result, []object.Object{object.NewString(filepath.Join(dir, s.Value()))})
+	}
+	return result
+}
+
+// Check decides whether a call to a function is allowed, given the arguments
+// of the call. It returns nil to allow the call or an error explaining why
+// the call is not allowed.
+type Check func(ctx context.Context, args []object.Object) error
+
+// Event describes a call to a privileged function, which is reported to the
+// audit callback of the policy whether or not the call was allowed.
+type Event struct {
+	// Name is the name of the function that was called, e.g. "os.read_file".
+	Name string
+
+	// Args are the arguments passed to the function.
+	Args []object.Object
+
+	// Err is the reason the call was denied, or nil if it was allowed.
+	Err *errz.PermissionError
+}
+
+// Allowed returns true if the call was allowed.
+func (e Event) Allowed() bool {
+	return e.Err == nil
+}
+
+// AuditFunc is called for every call to a privileged function.
+type AuditFunc func(ctx context.Context, event Event)
+
+type rule struct {
+	allow bool
+	check Check
+}
+
+// Policy determines which privileged functions may be called. Calls to
+// functions that match a rule are governed by that rule, with exact names
+// taking precedence over the most specific matching wildcard. Calls to
+// privileged functions that don't match a rule are allowed, unless the policy
+// denies them by default. Calls to other functions are always allowed.
+type Policy struct {
+	rules         map[string]rule
+	privileged    map[string]bool
+	aliases       map[string][]Alias
+	denyByDefault bool
+	audit         AuditFunc
+}
+
+// Option describes a function used to configure a Policy.
+type Option func(*Policy)
+
+// New returns a Policy configured with the given options. Functions in
+// DefaultPrivileged are considered privileged, and the functions in
+// DefaultAliases are checked against the rules for their os functions.
+func New(opts ...Option) *Policy {
+	p := &Policy{
+		rules:      map[string]rule{},
+		privileged: map[string]bool{},
+		aliases:    map[string][]Alias{},
+	}
+	for _, name := range DefaultPrivileged {
+		p.privileged[name] = true
+	}
+	for name, aliases := range DefaultAliases {
+		p.aliases[name] = aliases
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Allow allows calls to the named functions.
+func Allow(names ...string) Option {
+	return func(p *Policy) {
+		for _, name := range names {
+			p.rules[name] = rule{allow: true}
+		}
+	}
+}
+
+// Deny denies all calls to the named functions.
+func Deny(names ...string) Option {
+	return func(p *Policy) {
+		for _, name := range names {
+			p.rules[name] = rule{allow: false}
+		}
+	}
+}
+
+// AllowIf allows calls to the named function when the given check passes.
+func AllowIf(name string, check Check) Option {
+	return func(p *Policy) {
+		p.rules[name] = rule{allow: true, check: check}
+	}
+}
+
+// AllowHosts allows calls to the named function when its first argument is a
+// URL or address whose host is in the given list. A host beginning with "*."
+// matches any subdomain of the rest of the host, e.g. "*.example.com" matches
+// "api.example.com" but not "example.com".
+func AllowHosts(name string, hosts ...string) Option {
+	return AllowIf(name, HostCheck(hosts...))
+}
+
+// AllowPaths allows calls to the named function when its first argument is a
+// path within one of the given directories. Relative paths are resolved
+// against the working directory of the evaluation. Symbolic links are not
+// resolved.
+func AllowPaths(name string, dirs ...string) Option {
+	return AllowIf(name, PathCheck(dirs...))
+}
+
+// DenyByDefault denies calls to privileged functions that don't match a rule.
+func DenyByDefault() Option {
+	return func(p *Policy) {
+		p.denyByDefault = true
+	}
+}
+
+// WithPrivileged marks additional functions as privileged, for example the
+// functions of a custom module.
+func WithPrivileged(names ...string) Option {
+	return func(p *Policy) {
+		for _, name := range names {
+			p.privileged[name] = true
+		}
+	}
+}
+
+// WithAliases relates a function to the os functions that access the same
+// resources, replacing any default aliases for it.
+func WithAliases(name string, aliases ...Alias) Option {
+	return func(p *Policy) {
+		p.aliases[name] = aliases
+	}
+}
+
+// WithAudit sets a callback that is called for every call to a privileged
+// function.
+func WithAudit(fn AuditFunc) Option {
+	return func(p *Policy) {
+		p.audit = fn
+	}
+}
+
+// Governs returns true if calls to the named function are subject to the
+// policy, because the function is privileged or matches a rule.
+func (p *Policy) Governs(name string) bool {
+	if _, ok := p.lookup(name); ok {
+		return true
+	}
+	for _, pattern := range patterns(name) {
+		if p.privileged[pattern] {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns a *errz.PermissionError if a call to the named function with
+// the given arguments is not allowed. Calls to functions that the policy
+// governs are reported to the audit callback.
+//
+// A call to a function with aliases must also be allowed by the rules
+// matching each of its os functions. When no rule matches the function
+// itself, those rules decide whether the call is allowed, so that, for
+// example, AllowPaths("os.read_file", dir) allows cat to read files in dir.
+func (p *Policy) Check(ctx context.Context, name string, args []object.Object) error {
+	if !p.Governs(name) {
+		return nil
+	}
+	var denied *errz.PermissionError
+	r, ok := p.lookup(name)
+	switch {
+	case !ok && p.denyByDefault && !p.matchesAlias(name):
+		denied = errz.PermissionErrorf(name, "not allowed by policy")
+	case ok && !r.allow:
+		denied = errz.PermissionErrorf(name, "denied by policy")
+	case ok && r.check != nil:
+		if err := r.check(ctx, args); err != nil {
+			denied = errz.NewPermissionError(name, err)
+		}
+	}
+	if denied == nil {
+		if err := p.checkAliases(ctx, name, args, ok); err != nil {
+			denied = errz.NewPermissionError(name, err)
+		}
+	}
+	if p.audit != nil {
+		p.audit(ctx, Event{Name: name, Args: args, Err: denied})
+	}
+	if denied != nil {
+		return denied
+	}
+	return nil
+}
+
+// Returns true if a rule matches one of the os functions aliased by the named
+// function.
+func (p *Policy) matchesAlias(name string) bool {
+	for _, alias := range p.aliases[name] {
+		if _, ok := p.lookup(alias.Name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks a call against the rules for the os functions aliased by the named
+// function. If neither the function nor an os function matches a rule, the
+// call is denied when the policy denies by default.
+func (p *Policy) checkAliases(ctx context.Context, name string, args []object.Object, matched bool) error {
+	for _, alias := range p.aliases[name] {
+		r, ok := p.lookup(alias.Name)
+		switch {
+		case !ok && !matched && p.denyByDefault:
+			return fmt.Errorf("%s: not allowed by policy", alias.Name)
+		case ok && !r.allow:
+			return fmt.Errorf("%s: denied by policy", alias.Name)
+		case ok && r.check != nil:
+			for _, aliasArgs := range alias.Args(args) {
+				if err := r.check(ctx, aliasArgs); err != nil {
+					return fmt.Errorf("%s: %w", alias.Name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Wrapper returns a function that wraps a builtin function so that calls to
+// it are checked against the policy using the given name.
+func (p *Policy) Wrapper(name string) func(object.BuiltinFunction) object.BuiltinFunction {
+	return func(fn object.BuiltinFunction) object.BuiltinFunction {
+		return func(ctx context.Context, args ...object.Object) object.Object {
+			if err := p.Check(ctx, name, args); err != nil {
+				return object.NewError(err)
+			}
+			return fn(ctx, args...)
+		}
+	}
+}
+
+// Returns the most specific rule matching the name.
+func (p *Policy) lookup(name string) (rule, bool) {
+	for _, pattern := range patterns(name) {
+		if r, ok := p.rules[pattern]; ok {
+			return r, true
+		}
+	}
+	return rule{}, false
+}
+
+// Returns the patterns that match the given name, from most to least
+// specific. For "os.read_file" these are "os.read_file", "os.read_file.*",
+// "os.*" and "*".
+func patterns(name string) []string {
+	result := []string{name}
+	for prefix := name; prefix != ""; {
+		result = append(result, prefix+".*")
+		dot := strings.LastIndex(prefix, ".")
+		if dot < 0 {
+			break
+		}
+		prefix = prefix[:dot]
+	}
+	return append(result, "*")
+}
+
+// HostCheck returns a Check that passes when the first argument is a URL or
+// address whose host is in the given list. See AllowHosts.
+func HostCheck(hosts ...string) Check {
+	return func(ctx context.Context, args []object.Object) error {
+		if len(args) == 0 {
+			return errors.New("missing url")
+		}
+		s, ok := args[0].(*object.String)
+		if !ok {
+			return fmt.Errorf("expected a url string (got %s)", args[0].Type())
+		}
+		host := hostOf(s.Value())
+		for _, allowed := range hosts {
+			if matchHost(allowed, host) {
+				return nil
+			}
+		}
+		return fmt.Errorf("host %q is not allowed", host)
+	}
+}
+
+func hostOf(address string) string {
+	if strings.Contains(address, "://") {
+		if u, err := url.Parse(address); err == nil {
+			return strings.ToLower(u.Hostname())
+		}
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return strings.ToLower(host)
+	}
+	return strings.ToLower(address)
+}
+
+func matchHost(pattern, host string) bool {
+	if host == "" {
+		return false
+	}
+	pattern = strings.ToLower(pattern)
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok && strings.HasPrefix(suffix, ".") {
+		return strings.HasSuffix(host, suffix)
+	}
+	return pattern == host
+}
+
+// PathCheck returns a Check that passes when the first argument is a path
+// within one of the given directories. See AllowPaths.
+func PathCheck(dirs ...string) Check {
+	cleaned := make([]string, len(dirs))
+	for i, dir := range dirs {
+		cleaned[i] = filepath.Clean(dir)
+	}
+	return func(ctx context.Context, args []object.Object) error {
+		if len(args) == 0 {
+			return errors.New("missing path")
+		}
+		s, ok := args[0].(*object.String)
+		if !ok {
+			return fmt.Errorf("expected a path string (got %s)", args[0].Type())
+		}
+		path := s.Value()
+		if !filepath.IsAbs(path) {
+			wd, err := ros.GetDefaultOS(ctx).Getwd()
+			if err != nil {
+				return err
+			}
+			path = filepath.Join(wd, path)
+		}
+		path = filepath.Clean(path)
+		for _, dir := range cleaned {
+			if isWithin(dir, path) {
+				return nil
+			}
+		}
+		return fmt.Errorf("path %q is not allowed", s.Value())
+	}
+}
+
+func isWithin(dir, path string) bool {
+	if path == dir {
+		return true
+	}
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+	return strings.HasPrefix(path, dir)
+}
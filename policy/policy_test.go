@@ -0,0 +1,211 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/itrn0/risor/errz"
+	"github.com/itrn0/risor/object"
+	ros "github.com/itrn0/risor/os"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatterns(t *testing.T) {
+	require.Equal(t, []string{"os.read_file", "os.read_file.*", "os.*", "*"}, patterns("os.read_file"))
+	require.Equal(t, []string{"exec", "exec.*", "*"}, patterns("exec"))
+	require.Equal(t, []string{"a.b.c", "a.b.c.*", "a.b.*", "a.*", "*"}, patterns("a.b.c"))
+}
+
+func TestGoverns(t *testing.T) {
+	p := New(Allow("strings.to_upper"), WithPrivileged("custom.*"))
+	require.True(t, p.Governs("os.read_file"))
+	require.True(t, p.Governs("exec"))
+	require.True(t, p.Governs("fetch"))
+	require.True(t, p.Governs("archive.extract"))
+	require.True(t, p.Governs("strings.to_upper"))
+	require.True(t, p.Governs("custom.run"))
+	require.False(t, p.Governs("strings.to_lower"))
+	require.False(t, p.Governs("len"))
+	require.False(t, p.Governs("ospath"))
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	p := New(
+		Deny("os.*"),
+		Allow("os.getwd"),
+		AllowIf("os.getenv", func(ctx context.Context, args []object.Object) error {
+			if len(args) == 1 && args[0].Equals(object.NewString("HOME")) == object.True {
+				return nil
+			}
+			return errors.New("only HOME may be read")
+		}),
+	)
+	require.Nil(t, p.Check(ctx, "os.getwd", nil))
+	require.Nil(t, p.Check(ctx, "os.getenv", []object.Object{object.NewString("HOME")}))
+	require.Nil(t, p.Check(ctx, "http.get", nil))
+	require.Nil(t, p.Check(ctx, "len", nil))
+
+	err := p.Check(ctx, "os.getenv", []object.Object{object.NewString("TOKEN")})
+	require.Equal(t, "permission error: os.getenv: only HOME may be read", err.Error())
+	var permErr *errz.PermissionError
+	require.True(t, errors.As(err, &permErr))
+	require.Equal(t, "os.getenv", permErr.Name)
+	require.True(t, permErr.IsFatal())
+
+	err = p.Check(ctx, "os.remove", nil)
+	require.Equal(t, "permission error: os.remove: denied by policy", err.Error())
+
+	strict := New(DenyByDefault(), Allow("http.*"))
+	require.Nil(t, strict.Check(ctx, "http.get", nil))
+	require.Nil(t, strict.Check(ctx, "len", nil))
+	err = strict.Check(ctx, "open", nil)
+	require.Equal(t, "permission error: open: not allowed by policy", err.Error())
+}
+
+func TestAliases(t *testing.T) {
+	ctx := context.Background()
+	str := func(values ...string) []object.Object {
+		result := make([]object.Object, len(values))
+		for i, v := range values {
+			result[i] = object.NewString(v)
+		}
+		return result
+	}
+
+	p := New(Deny("os.read_file"))
+	err := p.Check(ctx, "cat", str("/etc/passwd"))
+	require.Equal(t, "permission error: cat: os.read_file: denied by policy", err.Error())
+	require.Nil(t, p.Check(ctx, "cd", str("/tmp")))
+
+	strict := New(DenyByDefault(), AllowPaths("os.read_file", "/data"), AllowPaths("os.write_file", "/out"))
+	require.Nil(t, strict.Check(ctx, "cat", str("/data/a", "/data/b")))
+	err = strict.Check(ctx, "cat", str("/data/a", "/etc/passwd"))
+	require.Equal(t, `permission error: cat: os.read_file: path "/etc/passwd" is not allowed`, err.Error())
+	require.Nil(t, strict.Check(ctx, "cp", str("/data/a", "/out/a")))
+	err = strict.Check(ctx, "cp", str("/data/a", "/tmp/a"))
+	require.Equal(t, `permission error: cp: os.write_file: path "/tmp/a" is not allowed`, err.Error())
+	err = strict.Check(ctx, "open", str("/data/a"))
+	require.Equal(t, "permission error: open: not allowed by policy", err.Error())
+
+	// The paths read by archive.create are relative to its dir option
+	archiveArgs := []object.Object{
+		object.NewString("/out/x.tar"),
+		object.NewList(str("hostname")),
+		object.NewMap(map[string]object.Object{"dir": object.NewString("/etc")}),
+	}
+	err = strict.Check(ctx, "archive.create", archiveArgs)
+	require.Equal(t, `permission error: archive.create: os.read_file: path "/etc/hostname" is not allowed`, err.Error())
+	archiveArgs[2] = object.NewMap(map[string]object.Object{"dir": object.NewString("/data")})
+	require.Nil(t, strict.Check(ctx, "archive.create", archiveArgs))
+
+	// A rule for the function itself doesn't override a rule for its alias
+	both := New(Allow("cat"), Deny("os.*"))
+	err = both.Check(ctx, "cat", str("/data/a"))
+	require.Equal(t, "permission error: cat: os.read_file: denied by policy", err.Error())
+
+	custom := New(Deny("os.read_file"), WithAliases("cat"))
+	require.Nil(t, custom.Check(ctx, "cat", str("/etc/passwd")))
+}
+
+func TestAudit(t *testing.T) {
+	ctx := context.Background()
+	var events []Event
+	p := New(Deny("exec.command"), WithAudit(func(ctx context.Context, event Event) {
+		events = append(events, event)
+	}))
+	args := []object.Object{object.NewString("ls")}
+	require.NotNil(t, p.Check(ctx, "exec.command", args))
+	require.Nil(t, p.Check(ctx, "os.getwd", nil))
+	require.Nil(t, p.Check(ctx, "strings.split", nil))
+	require.Len(t, events, 2)
+	require.Equal(t, "exec.command", events[0].Name)
+	require.Equal(t, args, events[0].Args)
+	require.False(t, events[0].Allowed())
+	require.Equal(t, "os.getwd", events[1].Name)
+	require.True(t, events[1].Allowed())
+}
+
+func TestHostCheck(t *testing.T) {
+	ctx := context.Background()
+	check := HostCheck("example.com", "*.example.org", "127.0.0.1")
+	allowed := []string{
+		"https://example.com/path?q=1",
+		"http://EXAMPLE.com:8080",
+		"https://api.example.org",
+		"https://a.b.example.org/x",
+		"example.com:443",
+		"127.0.0.1:9000",
+		"example.com",
+	}
+	for _, address := range allowed {
+		require.Nil(t, check(ctx, []object.Object{object.NewString(address)}), address)
+	}
+	denied := map[string]string{
+		"https://example.org":          `host "example.org" is not allowed`,
+		"https://example.com.evil.net": `host "example.com.evil.net" is not allowed`,
+		"https://user@evil.net":        `host "evil.net" is not allowed`,
+		":8080":                        `host "" is not allowed`,
+	}
+	for address, expected := range denied {
+		err := check(ctx, []object.Object{object.NewString(address)})
+		require.NotNil(t, err, address)
+		require.Equal(t, expected, err.Error())
+	}
+	require.Equal(t, "missing url", check(ctx, nil).Error())
+	require.Equal(t, "expected a url string (got int)",
+		check(ctx, []object.Object{object.NewInt(1)}).Error())
+}
+
+func TestPathCheck(t *testing.T) {
+	ctx := ros.WithOS(context.Background(),
+		ros.NewVirtualOS(context.Background(), ros.WithCwd("/work")))
+	check := PathCheck("/data", "/work/out/")
+	allowed := []string{
+		"/data",
+		"/data/a.txt",
+		"/data/sub/../b.txt",
+		"out/result.json",
+		"./out",
+	}
+	for _, path := range allowed {
+		require.Nil(t, check(ctx, []object.Object{object.NewString(path)}), path)
+	}
+	denied := []string{
+		"/etc/passwd",
+		"/data/../etc/passwd",
+		"/database",
+		"result.json",
+		"out/../../data2",
+	}
+	for _, path := range denied {
+		err := check(ctx, []object.Object{object.NewString(path)})
+		require.NotNil(t, err, path)
+		require.Contains(t, err.Error(), "is not allowed")
+	}
+}
+
+func TestWrapper(t *testing.T) {
+	ctx := context.Background()
+	p := New(Deny("exec.command"))
+	var called bool
+	fn := p.Wrapper("exec.command")(func(ctx context.Context, args ...object.Object) object.Object {
+		called = true
+		return object.Nil
+	})
+	result := fn(ctx)
+	require.False(t, called)
+	errObj, ok := result.(*object.Error)
+	require.True(t, ok)
+	require.True(t, errObj.IsRaised())
+	var permErr *errz.PermissionError
+	require.True(t, errors.As(errObj.Value(), &permErr))
+
+	fn = p.Wrapper("exec.look_path")(func(ctx context.Context, args ...object.Object) object.Object {
+		called = true
+		return object.Nil
+	})
+	require.Equal(t, object.Nil, fn(ctx))
+	require.True(t, called)
+}
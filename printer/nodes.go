@@ -0,0 +1,850 @@
+package printer
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/itrn0/risor/ast"
+	"github.com/itrn0/risor/parser"
+	"github.com/itrn0/risor/token"
+)
+
+// Prints a statement or expression.
+func (p *printer) stmt(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.Var:
+		name, value := node.Value()
+		if typ := node.Type(); typ != nil {
+			name += ": " + typ.String()
+		}
+		if node.IsWalrus() {
+			p.write(name + " := ")
+		} else {
+			p.write("var " + name + " = ")
+		}
+		p.expr(value)
+	case *ast.MultiVar:
+		names, value := node.Value()
+		list := strings.Join(names, ", ")
+		switch {
+		case node.IsWalrus():
+			p.write(list + " := ")
+		case node.Token().Type == token.VAR:
+			p.write("var " + list + " = ")
+		default:
+			p.write(list + " = ")
+		}
+		p.expr(value)
+	case *ast.Const:
+		name, value := node.Value()
+		if typ := node.Type(); typ != nil {
+			name += ": " + typ.String()
+		}
+		p.write("const " + name + " = ")
+		p.expr(value)
+	case *ast.Return:
+		p.write("return")
+		if value := node.Value(); value != nil {
+			p.write(" ")
+			p.expr(value)
+		}
+	case *ast.Yield:
+		p.write("yield")
+		if value := node.Value(); value != nil {
+			p.write(" ")
+			p.expr(value)
+		}
+	case *ast.Control:
+		p.write(node.Literal())
+		if value := node.Value(); value != nil {
+			p.write(" ")
+			p.expr(value)
+		}
+	case *ast.Assign:
+		if index := node.Index(); index != nil {
+			p.expr(index)
+		} else {
+			p.write(node.Name())
+		}
+		p.write(" " + node.Operator() + " ")
+		p.expr(node.Value())
+	case *ast.SetAttr:
+		p.operand(node.Object(), parser.INDEX)
+		p.write("." + node.Name() + " = ")
+		p.expr(node.Value())
+	case *ast.Postfix:
+		p.write(node.Literal() + node.Operator())
+	case *ast.Import:
+		p.write("import ")
+		p.importName(node)
+	case *ast.FromImport:
+		p.fromImport(node)
+	case *ast.Go:
+		p.write("go ")
+		p.expr(node.Call())
+	case *ast.Defer:
+		p.write("defer ")
+		p.expr(node.Call())
+	case *ast.Send:
+		p.operand(node.Channel(), parser.CALL)
+		p.write(" <- ")
+		// The value is parsed with the precedence of a call, so a call must
+		// be enclosed in parentheses
+		if _, ok := node.Value().(*ast.Call); ok {
+			p.parens(node.Value())
+		} else {
+			p.right(node.Value(), parser.CALL)
+		}
+	case *ast.For:
+		p.forLoop(node)
+	case *ast.Try:
+		p.try(node)
+	case *ast.Struct:
+		p.structType(node)
+	case *ast.Block:
+		p.block(node)
+	default:
+		p.expr(node)
+	}
+}
+
+// Returns the precedence of an expression, which determines whether it must
+// be enclosed in parentheses when it is the operand of another expression.
+// Expressions that can't be extended by a following operator, such as calls
+// and literals, have the highest precedence.
+func precedence(node ast.Node) int {
+	switch node := node.(type) {
+	case *ast.Infix:
+		return parser.Precedence(node.Token().Type)
+	case *ast.Ternary:
+		return parser.TERNARY
+	case *ast.Pipe:
+		return parser.PIPE
+	case *ast.Prefix, *ast.In, *ast.Range:
+		return parser.PREFIX
+	case *ast.Receive:
+		return parser.LOWEST
+	}
+	return parser.HIGHEST
+}
+
+// Prints an operand that precedes an operator with the given precedence.
+func (p *printer) operand(node ast.Node, prec int) {
+	if precedence(node) < prec {
+		p.parens(node)
+		return
+	}
+	p.expr(node)
+}
+
+// Prints an operand that follows an operator with the given precedence. The
+// operand was parsed with that precedence, so it must be enclosed in
+// parentheses unless its own precedence is higher. Prefix expressions are the
+// exception, since they begin with their operator.
+func (p *printer) right(node ast.Node, prec int) {
+	switch node.(type) {
+	case *ast.Prefix, *ast.Range:
+		p.expr(node)
+	default:
+		p.operand(node, prec+1)
+	}
+}
+
+func (p *printer) parens(node ast.Node) {
+	p.write("(")
+	p.expr(node)
+	p.write(")")
+}
+
+// Returns true if the given operand begins on a later line than the operator
+// that precedes it in the source.
+func (p *printer) breaksBefore(node ast.Node) bool {
+	if !p.hasSource() {
+		return false
+	}
+	start := p.outerStart(node)
+	line := p.endLine(start.Char)
+	return line >= 0 && start.Line > line
+}
+
+// Starts a new, further indented line if the token following the given one
+// is on a later line in the source. This is used to preserve line breaks in
+// chains of method calls. Returns true if a line was started, in which case
+// the caller must restore the indentation.
+func (p *printer) breaksAfter(tok token.Token) bool {
+	if !p.hasSource() {
+		return false
+	}
+	i := p.tokenIndex(tok.StartPosition.Char) + 1
+	if i >= len(p.tokens) || p.tokens[i].StartPosition.Line <= tok.EndPosition.Line {
+		return false
+	}
+	p.indent++
+	p.newline()
+	return true
+}
+
+// Prints an expression.
+func (p *printer) expr(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.Ident:
+		p.write(node.Literal())
+	case *ast.Int, *ast.Float, *ast.Bool, *ast.Nil:
+		p.write(node.Literal())
+	case *ast.String:
+		p.str(node)
+	case *ast.Prefix:
+		p.write(node.Operator())
+		right := node.Right()
+		if inner, ok := right.(*ast.Prefix); ok {
+			// Avoid printing "--x", which is a decrement
+			if node.Operator() == "-" && inner.Operator() == "-" {
+				p.parens(right)
+			} else {
+				p.expr(right)
+			}
+		} else {
+			p.operand(right, parser.PREFIX+1)
+		}
+	case *ast.Infix:
+		prec := parser.Precedence(node.Token().Type)
+		p.operand(node.Left(), prec)
+		p.write(" " + node.Operator())
+		if p.breaksBefore(node.Right()) {
+			p.indent++
+			p.newline()
+			p.right(node.Right(), prec)
+			p.indent--
+		} else {
+			p.write(" ")
+			p.right(node.Right(), prec)
+		}
+	case *ast.Ternary:
+		p.operand(node.Condition(), parser.TERNARY)
+		p.write(" ? ")
+		p.right(node.IfTrue(), parser.TERNARY)
+		p.write(" : ")
+		p.right(node.IfFalse(), parser.TERNARY)
+	case *ast.In:
+		p.operand(node.Left(), parser.PREFIX)
+		p.write(" in ")
+		p.right(node.Right(), parser.PREFIX)
+	case *ast.Range:
+		p.write("range ")
+		p.right(node.Container(), parser.PREFIX)
+	case *ast.Receive:
+		p.write("<-")
+		p.expr(node.Channel())
+	case *ast.Pipe:
+		p.pipe(node)
+	case *ast.Call:
+		p.call(node)
+	case *ast.GetAttr:
+		p.operand(node.Object(), parser.INDEX)
+		p.write(".")
+		broken := p.breaksAfter(node.Token())
+		p.write(node.Name())
+		if broken {
+			p.indent--
+		}
+	case *ast.ObjectCall:
+		p.operand(node.Object(), parser.INDEX)
+		p.write(".")
+		broken := p.breaksAfter(node.Token())
+		if call, ok := node.Call().(*ast.Call); ok {
+			p.call(call)
+		} else {
+			p.expr(node.Call())
+		}
+		if broken {
+			p.indent--
+		}
+	case *ast.Index:
+		p.operand(node.Left(), parser.INDEX)
+		p.write("[")
+		p.expr(node.Index())
+		p.write("]")
+	case *ast.Slice:
+		p.operand(node.Left(), parser.INDEX)
+		p.write("[")
+		if from := node.FromIndex(); from != nil {
+			p.expr(from)
+		}
+		p.write(":")
+		if to := node.ToIndex(); to != nil {
+			p.expr(to)
+		}
+		p.write("]")
+	case *ast.List:
+		p.list(node)
+	case *ast.Map:
+		p.mapLiteral(node)
+	case *ast.Set:
+		p.set(node)
+	case *ast.Func:
+		p.fn(node)
+	case *ast.If:
+		p.ifExpr(node)
+	case *ast.Switch:
+		p.switchExpr(node)
+	case *ast.Match:
+		p.matchExpr(node)
+	case *ast.Select:
+		p.selectExpr(node)
+	case nil:
+	default:
+		p.write(node.String())
+	}
+}
+
+// Prints a string literal as it appears in the source, or quoted in the
+// style of its token type if the source isn't available.
+func (p *printer) str(node *ast.String) {
+	tok := node.Token()
+	start, end := tok.StartPosition.Char, tok.EndPosition.Char+1
+	if p.hasSource() && start < end && end <= len(p.src) {
+		p.write(string(p.src[start:end]))
+		return
+	}
+	switch tok.Type {
+	case token.BACKTICK:
+		p.write("`" + tok.Literal + "`")
+	case token.FSTRING:
+		p.write(quote(tok.Literal, '\''))
+	default:
+		p.write(quote(tok.Literal, '"'))
+	}
+}
+
+// Quotes a string using the given quote character and the escape sequences
+// that the lexer understands.
+func quote(s string, q rune) string {
+	var b strings.Builder
+	b.WriteRune(q)
+	for _, r := range s {
+		switch {
+		case r == q || r == '\\':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r == '"' || r == '\'':
+			b.WriteRune(r)
+		case strconv.IsPrint(r):
+			b.WriteRune(r)
+		default:
+			quoted := strconv.QuoteRune(r)
+			b.WriteString(quoted[1 : len(quoted)-1])
+		}
+	}
+	b.WriteRune(q)
+	return b.String()
+}
+
+func (p *printer) pipe(node *ast.Pipe) {
+	broken := false
+	for i, expr := range node.Expressions() {
+		if i == 0 {
+			p.operand(expr, parser.PIPE)
+			continue
+		}
+		p.write(" |")
+		if p.breaksBefore(expr) {
+			if !broken {
+				broken = true
+				p.indent++
+			}
+			p.newline()
+		} else {
+			p.write(" ")
+		}
+		p.right(expr, parser.PIPE)
+	}
+	if broken {
+		p.indent--
+	}
+}
+
+func (p *printer) call(node *ast.Call) {
+	// A call of an attribute is otherwise parsed as a method call
+	if _, ok := node.Function().(*ast.GetAttr); ok {
+		p.parens(node.Function())
+	} else {
+		p.operand(node.Function(), parser.CALL)
+	}
+	p.write("(")
+	args := node.Arguments()
+	p.items(node.Token(), len(args),
+		func(i int) token.Position { return p.start(args[i]) },
+		func(i int) { p.expr(args[i]) },
+		")", ",")
+}
+
+func (p *printer) list(node *ast.List) {
+	items := node.Items()
+	p.write("[")
+	p.items(node.Token(), len(items),
+		func(i int) token.Position { return p.start(items[i]) },
+		func(i int) { p.expr(items[i]) },
+		"]", ",")
+}
+
+func (p *printer) set(node *ast.Set) {
+	items := node.Items()
+	p.write("{")
+	p.items(node.Token(), len(items),
+		func(i int) token.Position { return p.start(items[i]) },
+		func(i int) { p.expr(items[i]) },
+		"}", ",")
+}
+
+func (p *printer) mapLiteral(node *ast.Map) {
+	// Map items are unordered, so print them in the order of the source, or
+	// ordered by key if the source isn't available
+	items := node.Items()
+	keys := make([]ast.Expression, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if p.hasSource() {
+			return p.start(keys[i]).Char < p.start(keys[j]).Char
+		}
+		return keys[i].String() < keys[j].String()
+	})
+	p.write("{")
+	p.items(node.Token(), len(keys),
+		func(i int) token.Position { return p.start(keys[i]) },
+		func(i int) {
+			p.expr(keys[i])
+			p.write(": ")
+			p.expr(items[keys[i]])
+		},
+		"}", ",")
+}
+
+// Prints a block. Empty blocks are printed as "{}" and a block that holds a
+// single statement on one line in the source is kept on one line. Otherwise
+// each statement is printed on its own line.
+func (p *printer) block(node *ast.Block) {
+	stmts := node.Statements()
+	open := node.Token()
+	close, ok := p.closing(open)
+	if !ok {
+		if len(stmts) == 0 {
+			p.write("{}")
+			return
+		}
+		p.write("{")
+		p.newline()
+		p.indent++
+		p.statements(stmts, 0)
+		p.indent--
+		p.write("}")
+		return
+	}
+	end := close.StartPosition.Char
+	hasComments := p.hasComments(open.StartPosition.Char, end)
+	if len(stmts) == 0 && !hasComments {
+		p.write("{}")
+		return
+	}
+	if len(stmts) == 1 && !hasComments && close.StartPosition.Line == open.StartPosition.Line {
+		p.write("{ ")
+		p.stmt(stmts[0])
+		p.write(" }")
+		return
+	}
+	p.write("{")
+	boundary := end
+	if len(stmts) > 0 {
+		boundary = p.outerStart(stmts[0]).Char
+	}
+	p.trailingComments(boundary, open.StartPosition.Line)
+	p.newline()
+	p.indent++
+	p.statements(stmts, end)
+	p.indent--
+	p.write("}")
+}
+
+func (p *printer) ifExpr(node *ast.If) {
+	p.write("if ")
+	p.expr(node.Condition())
+	p.write(" ")
+	p.block(node.Consequence())
+	alternative := node.Alternative()
+	if alternative == nil {
+		return
+	}
+	p.write(" else ")
+	if nested := elseIf(alternative); nested != nil {
+		p.ifExpr(nested)
+	} else {
+		p.block(alternative)
+	}
+}
+
+// Returns the if expression of an "else if", which is parsed as an else block
+// holding just the nested if expression.
+func elseIf(block *ast.Block) *ast.If {
+	stmts := block.Statements()
+	if len(stmts) != 1 {
+		return nil
+	}
+	nested, ok := stmts[0].(*ast.If)
+	if !ok || nested.Token() != block.Token() {
+		return nil
+	}
+	return nested
+}
+
+// One case of a switch or match expression.
+type caseClause struct {
+	token token.Token
+
+	// Prints the case up to and including its colon
+	header func()
+
+	block *ast.Block
+}
+
+func (p *printer) switchExpr(node *ast.Switch) {
+	var clauses []caseClause
+	for _, choice := range node.Choices() {
+		choice := choice
+		clauses = append(clauses, caseClause{
+			token: choice.Token(),
+			header: func() {
+				if choice.IsDefault() {
+					p.write("default:")
+					return
+				}
+				p.write("case ")
+				for j, expr := range choice.Expressions() {
+					if j > 0 {
+						p.write(", ")
+					}
+					p.expr(expr)
+				}
+				p.write(":")
+			},
+			block: choice.Block(),
+		})
+	}
+	p.cases(node.Token(), node.Value(), clauses)
+}
+
+func (p *printer) matchExpr(node *ast.Match) {
+	var clauses []caseClause
+	for _, matchCase := range node.Cases() {
+		matchCase := matchCase
+		clauses = append(clauses, caseClause{
+			token: matchCase.Token(),
+			header: func() {
+				if matchCase.IsDefault() {
+					p.write("default:")
+					return
+				}
+				p.write("case ")
+				p.pattern(matchCase.Pattern())
+				if guard := matchCase.Guard(); guard != nil {
+					p.write(" if ")
+					p.expr(guard)
+				}
+				p.write(":")
+			},
+			block: matchCase.Block(),
+		})
+	}
+	p.cases(node.Token(), node.Value(), clauses)
+}
+
+func (p *printer) selectExpr(node *ast.Select) {
+	var clauses []caseClause
+	for _, selectCase := range node.Cases() {
+		selectCase := selectCase
+		clauses = append(clauses, caseClause{
+			token: selectCase.Token(),
+			header: func() {
+				if selectCase.IsDefault() {
+					p.write("default:")
+					return
+				}
+				p.write("case ")
+				for i, name := range selectCase.Names() {
+					if i > 0 {
+						p.write(", ")
+					}
+					p.write(name.Literal())
+				}
+				if len(selectCase.Names()) > 0 {
+					p.write(" := ")
+				}
+				p.stmt(selectCase.Comm())
+				p.write(":")
+			},
+			block: selectCase.Block(),
+		})
+	}
+	p.cases(node.Token(), nil, clauses)
+}
+
+// Prints a switch, match or select expression, given its keyword and value.
+// A select expression has no value.
+func (p *printer) cases(keyword token.Token, value ast.Expression, clauses []caseClause) {
+	p.write(keyword.Literal)
+	if value != nil {
+		p.write(" ")
+		p.expr(value)
+	}
+	if len(clauses) == 0 {
+		p.write(" {}")
+		return
+	}
+	p.write(" {")
+	end := -1
+	if p.hasSource() {
+		if open, ok := p.lastBefore(clauses[0].token.StartPosition.Char); ok {
+			if close, ok := p.closing(open); ok {
+				end = close.StartPosition.Char
+			}
+		}
+		p.trailingComments(clauses[0].token.StartPosition.Char, keyword.StartPosition.Line)
+	}
+	p.newline()
+	line := -1
+	for i, clause := range clauses {
+		start := clause.token.StartPosition
+		if p.hasSource() {
+			line = p.comments(start.Char, line)
+			if line >= 0 && start.Line-line > 1 {
+				p.newline()
+			}
+		}
+		clause.header()
+		boundary := end
+		if i+1 < len(clauses) {
+			boundary = clauses[i+1].token.StartPosition.Char
+		}
+		var stmts []ast.Node
+		if clause.block != nil {
+			stmts = clause.block.Statements()
+		}
+		if p.hasSource() {
+			first := boundary
+			if len(stmts) > 0 {
+				first = p.outerStart(stmts[0]).Char
+			}
+			line = p.trailingComments(first, start.Line)
+		}
+		p.newline()
+		// Comments that follow the statements of a case are printed with the
+		// next case
+		p.indent++
+		if bodyLine := p.statementList(stmts, boundary); bodyLine >= 0 {
+			line = bodyLine
+		}
+		p.indent--
+	}
+	if p.hasSource() {
+		p.comments(end, line)
+	}
+	p.write("}")
+}
+
+// Prints a pattern in a case of a match expression.
+func (p *printer) pattern(node ast.Pattern) {
+	switch node := node.(type) {
+	case *ast.LiteralPattern:
+		p.expr(node.Value())
+	case *ast.TypePattern:
+		p.write(node.Name() + "(")
+		if inner := node.Pattern(); inner != nil {
+			p.pattern(inner)
+		}
+		p.write(")")
+	case *ast.ListPattern:
+		p.write("[")
+		for i, el := range node.Elements() {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.pattern(el)
+		}
+		p.write("]")
+	case *ast.MapPattern:
+		p.write("{")
+		values := node.Values()
+		for i, key := range node.Keys() {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.expr(key)
+			// The {name} shorthand shares its identifier with the pattern
+			if bind, ok := values[i].(*ast.BindPattern); ok && bind.Ident() == key {
+				continue
+			}
+			p.write(": ")
+			p.pattern(values[i])
+		}
+		p.write("}")
+	case *ast.OrPattern:
+		for i, alt := range node.Alternatives() {
+			if i > 0 {
+				p.write(" | ")
+			}
+			p.pattern(alt)
+		}
+	default:
+		p.write(node.String())
+	}
+}
+
+func (p *printer) forLoop(node *ast.For) {
+	p.write("for ")
+	init, condition, post := node.Init(), node.Condition(), node.Post()
+	switch {
+	case init == nil && condition == nil && post == nil:
+	case init == nil && post == nil:
+		p.stmt(condition)
+		p.write(" ")
+	default:
+		if init != nil {
+			p.stmt(init)
+		}
+		p.write(";")
+		if condition != nil {
+			p.write(" ")
+			p.expr(condition)
+		}
+		p.write(";")
+		if post != nil {
+			p.write(" ")
+			p.stmt(post)
+		}
+		p.write(" ")
+	}
+	p.block(node.Consequence())
+}
+
+func (p *printer) try(node *ast.Try) {
+	p.write("try ")
+	p.block(node.Body())
+	if catch := node.CatchBlock(); catch != nil {
+		p.write(" catch ")
+		if ident := node.CatchIdent(); ident != nil {
+			p.write(ident.Literal() + " ")
+		}
+		p.block(catch)
+	}
+	if finally := node.FinallyBlock(); finally != nil {
+		p.write(" finally ")
+		p.block(finally)
+	}
+}
+
+func (p *printer) fn(node *ast.Func) {
+	p.write("func")
+	if node.IsMethod() {
+		p.write(" (" + node.Receiver().Literal() + " " + node.ReceiverType().Literal() + ")")
+	}
+	if name := node.Name(); name != nil {
+		p.write(" " + name.Literal())
+	}
+	p.params(node)
+	if result := node.ResultType(); result != nil {
+		p.write(" -> " + result.String())
+	}
+	p.write(" ")
+	p.block(node.Body())
+}
+
+// Prints the parenthesized list of a function's parameters. Defaults of
+// annotated parameters are spaced out, e.g. "(a, b: int = 1, c=2)".
+func (p *printer) params(node *ast.Func) {
+	defaults := node.Defaults()
+	p.write("(")
+	for i, param := range node.Parameters() {
+		if i > 0 {
+			p.write(", ")
+		}
+		name := param.Literal()
+		p.write(name)
+		typ := node.ParameterType(name)
+		if typ != nil {
+			p.write(": " + typ.String())
+		}
+		if value, ok := defaults[name]; ok {
+			if typ != nil {
+				p.write(" = ")
+			} else {
+				p.write("=")
+			}
+			p.expr(value)
+		}
+	}
+	p.write(")")
+}
+
+func (p *printer) structType(node *ast.Struct) {
+	p.write("struct " + node.Name().Literal() + " ")
+	fields := node.Fields()
+	defaults := node.Defaults()
+	if len(fields) == 0 {
+		p.write("{}")
+		return
+	}
+	var open token.Token
+	if p.hasSource() {
+		open, _ = p.find(token.LBRACE, node.Name().Token().StartPosition.Char)
+	}
+	field := func(i int) {
+		name := fields[i].Literal()
+		p.write(name)
+		if value, ok := defaults[name]; ok {
+			p.write(" = ")
+			p.expr(value)
+		}
+	}
+	start := func(i int) token.Position { return fields[i].Token().StartPosition }
+	if p.hasSource() && start(0).Line > open.StartPosition.Line {
+		p.write("{")
+		p.items(open, len(fields), start, field, "}", "")
+		return
+	}
+	p.write("{ ")
+	p.items(open, len(fields), start, field, " }", "")
+}
+
+func (p *printer) importName(node *ast.Import) {
+	p.write(node.Name().Literal())
+	if alias := node.Alias(); alias != nil {
+		p.write(" as " + alias.Literal())
+	}
+}
+
+func (p *printer) fromImport(node *ast.FromImport) {
+	parents := make([]string, 0, len(node.Parents()))
+	for _, parent := range node.Parents() {
+		parents = append(parents, parent.Literal())
+	}
+	p.write("from " + strings.Join(parents, ".") + " import ")
+	imports := node.Imports()
+	start := func(i int) token.Position { return imports[i].Name().Token().StartPosition }
+	item := func(i int) { p.importName(imports[i]) }
+	if !node.IsGrouped() {
+		for i := range imports {
+			if i > 0 {
+				p.write(", ")
+			}
+			item(i)
+		}
+		return
+	}
+	p.write("(")
+	var open token.Token
+	if p.hasSource() && len(imports) > 0 {
+		open, _ = p.lastBefore(start(0).Char)
+	}
+	p.items(open, len(imports), start, item, ")", ",")
+}
@@ -0,0 +1,388 @@
+// Package printer formats Risor programs as canonical Risor source code.
+//
+// Programs are printed with four space indentation, one statement per line,
+// and with the spacing and parentheses of expressions normalized. When the
+// source code a program was parsed from is available, its comments and blank
+// lines are preserved, as are the line breaks of lists, maps, calls and other
+// bracketed expressions that span multiple lines.
+package printer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/itrn0/risor/ast"
+	"github.com/itrn0/risor/lexer"
+	"github.com/itrn0/risor/parser"
+	"github.com/itrn0/risor/token"
+)
+
+const indentation = "    "
+
+// Format parses the given source code and returns it formatted in canonical
+// style. An error is returned if the source code can't be parsed.
+func Format(ctx context.Context, source string, opts ...parser.Option) (string, error) {
+	program, err := parser.Parse(ctx, source, opts...)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := Fprint(&buf, program, source); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Fprint writes the program to w in canonical style. The source is the code
+// the program was parsed from, which is used to preserve comments and layout.
+// If the source is empty, the program is printed without them.
+func Fprint(w io.Writer, program *ast.Program, source string) error {
+	p := newPrinter(program, source)
+	p.statements(program.Statements(), len(p.src))
+	p.comments(len(p.src), -1)
+	_, err := w.Write(p.bytes())
+	return err
+}
+
+type printer struct {
+	out bytes.Buffer
+
+	// The current indentation level
+	indent int
+
+	// True if nothing has been written to the current line yet
+	lineStart bool
+
+	// The source code, which is nil if it isn't available
+	src []rune
+
+	// The tokens of the source code, excluding newlines and semicolons
+	tokens []token.Token
+
+	// The comments of the program and the index of the next one to print
+	notes []*ast.Comment
+	next  int
+}
+
+func newPrinter(program *ast.Program, source string) *printer {
+	p := &printer{lineStart: true}
+	if source == "" {
+		return p
+	}
+	p.src = []rune(source)
+	p.notes = program.Comments()
+	l := lexer.New(source)
+	for {
+		tok, err := l.Next()
+		if err != nil || tok.Type == token.EOF {
+			break
+		}
+		if tok.Type != token.NEWLINE && tok.Type != token.SEMICOLON {
+			p.tokens = append(p.tokens, tok)
+		}
+	}
+	return p
+}
+
+func (p *printer) bytes() []byte {
+	out := bytes.TrimRight(p.out.Bytes(), "\n")
+	if len(out) == 0 {
+		return nil
+	}
+	return append(out, '\n')
+}
+
+func (p *printer) hasSource() bool {
+	return p.src != nil
+}
+
+// Writes text to the current line, indenting it if it's the first text on
+// the line.
+func (p *printer) write(s string) {
+	if s == "" {
+		return
+	}
+	if p.lineStart {
+		p.out.WriteString(strings.Repeat(indentation, p.indent))
+		p.lineStart = false
+	}
+	p.out.WriteString(s)
+}
+
+func (p *printer) newline() {
+	p.out.WriteByte('\n')
+	p.lineStart = true
+}
+
+// Returns the index of the first source token at or after the given offset.
+func (p *printer) tokenIndex(char int) int {
+	return sort.Search(len(p.tokens), func(i int) bool {
+		return p.tokens[i].StartPosition.Char >= char
+	})
+}
+
+// Returns the last source token that begins before the given offset.
+func (p *printer) lastBefore(char int) (token.Token, bool) {
+	i := p.tokenIndex(char) - 1
+	if i < 0 {
+		return token.Token{}, false
+	}
+	return p.tokens[i], true
+}
+
+// Returns the line on which the source code before the given offset ends,
+// or -1 if it isn't known.
+func (p *printer) endLine(char int) int {
+	tok, ok := p.lastBefore(char)
+	if !ok {
+		return -1
+	}
+	return tok.EndPosition.Line
+}
+
+// Returns the bracket that closes the given opening bracket in the source.
+func (p *printer) closing(open token.Token) (token.Token, bool) {
+	i := p.tokenIndex(open.StartPosition.Char)
+	if i >= len(p.tokens) || p.tokens[i].StartPosition.Char != open.StartPosition.Char {
+		return token.Token{}, false
+	}
+	depth := 0
+	for ; i < len(p.tokens); i++ {
+		switch p.tokens[i].Type {
+		case token.LPAREN, token.LBRACKET, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACKET, token.RBRACE:
+			depth--
+			if depth == 0 {
+				return p.tokens[i], true
+			}
+		}
+	}
+	return token.Token{}, false
+}
+
+// Returns the first source token of the given type at or after the offset.
+func (p *printer) find(typ token.Type, char int) (token.Token, bool) {
+	for i := p.tokenIndex(char); i < len(p.tokens); i++ {
+		if p.tokens[i].Type == typ {
+			return p.tokens[i], true
+		}
+	}
+	return token.Token{}, false
+}
+
+// Returns true if there are unprinted comments between the two offsets.
+func (p *printer) hasComments(from, to int) bool {
+	for _, c := range p.notes[p.next:] {
+		char := c.Token().StartPosition.Char
+		if char >= from && char < to {
+			return true
+		}
+	}
+	return false
+}
+
+// Prints the comments that appear before the given offset, each on its own
+// line. The line argument is the source line of the last item printed, or -1
+// if there isn't one, and is used to preserve blank lines between items. The
+// source line of the last comment printed is returned.
+func (p *printer) comments(char, line int) int {
+	for p.next < len(p.notes) {
+		c := p.notes[p.next]
+		start := c.Token().StartPosition
+		if start.Char >= char {
+			break
+		}
+		if !p.lineStart {
+			p.newline()
+		}
+		if line >= 0 && start.Line-line > 1 {
+			p.newline()
+		}
+		p.write(c.Text())
+		p.newline()
+		line = c.Token().EndPosition.Line
+		p.next++
+	}
+	return line
+}
+
+// Prints the comments that appear before the given offset and no later than
+// the given line at the end of the current line. These are comments that
+// follow an item on the same line, along with any comments within the item
+// that weren't printed with it. The source line of the last comment printed
+// is returned.
+func (p *printer) trailingComments(char, line int) int {
+	var afterLineComment bool
+	for p.next < len(p.notes) {
+		c := p.notes[p.next]
+		start := c.Token().StartPosition
+		if start.Char >= char || start.Line > line {
+			break
+		}
+		if afterLineComment {
+			p.newline()
+		} else if !p.lineStart {
+			p.write(" ")
+		}
+		p.write(c.Text())
+		afterLineComment = !c.IsBlock()
+		if end := c.Token().EndPosition.Line; end > line {
+			line = end
+		}
+		p.next++
+	}
+	return line
+}
+
+// Returns the position where a node begins in the source. The tokens of some
+// nodes are operators, rather than the leftmost token of the node.
+func (p *printer) start(node ast.Node) token.Position {
+	switch node := node.(type) {
+	case *ast.Infix:
+		return p.start(node.Left())
+	case *ast.In:
+		return p.start(node.Left())
+	case *ast.Ternary:
+		return p.start(node.Condition())
+	case *ast.Call:
+		return p.start(node.Function())
+	case *ast.GetAttr:
+		return p.start(node.Object())
+	case *ast.ObjectCall:
+		return p.start(node.Object())
+	case *ast.Index:
+		return p.start(node.Left())
+	case *ast.Slice:
+		return p.start(node.Left())
+	case *ast.Pipe:
+		return p.start(node.Expressions()[0])
+	case *ast.Send:
+		return p.start(node.Channel())
+	case *ast.SetAttr:
+		return p.start(node.Object())
+	case *ast.Assign:
+		if node.Index() != nil {
+			return p.start(node.Index())
+		}
+		if tok, ok := p.lastBefore(node.Token().StartPosition.Char); ok {
+			return tok.StartPosition
+		}
+	}
+	return node.Token().StartPosition
+}
+
+// Returns the position where a node begins in the source, including any
+// parentheses that enclose its leftmost expression.
+func (p *printer) outerStart(node ast.Node) token.Position {
+	pos := p.start(node)
+	for i := p.tokenIndex(pos.Char) - 1; i >= 0; i-- {
+		if p.tokens[i].Type != token.LPAREN {
+			break
+		}
+		pos = p.tokens[i].StartPosition
+	}
+	return pos
+}
+
+// Prints a list of statements, each on its own line along with the comments
+// that precede and follow it. The end is the offset in the source where the
+// list ends, e.g. the position of the closing brace of a block.
+func (p *printer) statements(nodes []ast.Node, end int) {
+	line := p.statementList(nodes, end)
+	if p.hasSource() {
+		p.comments(end, line)
+	}
+}
+
+// Prints a list of statements like statements does, but without the comments
+// that follow the last statement on later lines. Returns the source line of
+// the last statement or comment printed.
+func (p *printer) statementList(nodes []ast.Node, end int) int {
+	line := -1
+	for i := 0; i < len(nodes); i++ {
+		node := nodes[i]
+		if i+1 < len(nodes) && isIncrement(node, nodes[i+1]) {
+			// "x++" is parsed as the identifier followed by a postfix
+			// statement, which already holds the identifier
+			i++
+			node = nodes[i]
+		}
+		start := p.outerStart(node)
+		if p.hasSource() {
+			line = p.comments(start.Char, line)
+			if line >= 0 && start.Line-line > 1 {
+				p.newline()
+			}
+		}
+		p.stmt(node)
+		if p.hasSource() {
+			boundary := end
+			if i+1 < len(nodes) {
+				boundary = p.outerStart(nodes[i+1]).Char
+			}
+			line = p.trailingComments(boundary, p.endLine(boundary))
+		}
+		p.newline()
+	}
+	return line
+}
+
+func isIncrement(node, next ast.Node) bool {
+	ident, ok := node.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	postfix, ok := next.(*ast.Postfix)
+	return ok && postfix.Token().StartPosition == ident.Token().StartPosition
+}
+
+// Prints the items of a bracketed list, after its opening bracket has been
+// printed. If the first item begins on a later line than the opening bracket
+// in the source, each item is printed on its own line followed by the given
+// separator. Otherwise the items are printed on one line, separated by commas.
+func (p *printer) items(open token.Token, count int, start func(i int) token.Position, item func(i int), closer, sep string) {
+	var close token.Token
+	multiline := false
+	if p.hasSource() && count > 0 {
+		var ok bool
+		close, ok = p.closing(open)
+		multiline = ok && start(0).Line > open.StartPosition.Line
+	}
+	if !multiline {
+		for i := 0; i < count; i++ {
+			if i > 0 {
+				p.write(", ")
+			}
+			item(i)
+		}
+		p.write(closer)
+		return
+	}
+	end := close.StartPosition.Char
+	p.trailingComments(start(0).Char, open.StartPosition.Line)
+	p.newline()
+	p.indent++
+	line := -1
+	for i := 0; i < count; i++ {
+		pos := start(i)
+		line = p.comments(pos.Char, line)
+		if line >= 0 && pos.Line-line > 1 {
+			p.newline()
+		}
+		item(i)
+		p.write(sep)
+		boundary := end
+		if i+1 < count {
+			boundary = start(i + 1).Char
+		}
+		line = p.trailingComments(boundary, p.endLine(boundary))
+		p.newline()
+	}
+	p.comments(end, line)
+	p.indent--
+	p.write(closer)
+}
@@ -0,0 +1,177 @@
+package printer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itrn0/risor/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"spacing",
+			"x:=1+2*3\nvar   y=x\nconst Z=[1,2 ,3]",
+			"x := 1 + 2 * 3\nvar y = x\nconst Z = [1, 2, 3]\n",
+		},
+		{
+			"parentheses",
+			"x := ((1 + 2)) * 3\ny := 1 + (2 * 3)\nz := -(-a)\nw := (a && b) ? (1 + 2) : c\n",
+			"x := (1 + 2) * 3\ny := 1 + 2 * 3\nz := -(-a)\nw := (a && b) ? 1 + 2 : c\n",
+		},
+		{
+			"left associative operators",
+			"x := a - (b - c)\ny := (a - b) - c\nz := (a.b)(c)\n",
+			"x := a - (b - c)\ny := a - b - c\nz := (a.b)(c)\n",
+		},
+		{
+			"comments",
+			"#!/usr/bin/env risor\n// leading\nx := 1   // trailing\n\n\n\n/* block */\ny := 2\n// end",
+			"#!/usr/bin/env risor\n// leading\nx := 1 // trailing\n\n/* block */\ny := 2\n// end\n",
+		},
+		{
+			"blocks",
+			"func add(a,b=1){ return a+b }\nif x{\nprint(x)\n}else if y{print(y)}else{\n}\n",
+			"func add(a, b=1) { return a + b }\nif x {\n    print(x)\n} else if y { print(y) } else {}\n",
+		},
+		{
+			"increment",
+			"for i:=0;i<3;i++ {\n  x++\n  y--\n}",
+			"for i := 0; i < 3; i++ {\n    x++\n    y--\n}\n",
+		},
+		{
+			"multi-line literals",
+			"x := {\n  \"b\": 1, // first\n  \"a\": [1,\n 2]}\nf(1,\n  2)\ng(\n1)",
+			"x := {\n    \"b\": 1, // first\n    \"a\": [1, 2],\n}\nf(1, 2)\ng(\n    1,\n)\n",
+		},
+		{
+			"strings",
+			"x := 'hi {name}'\ny := \"a\\tb\"\nz := `raw\n  text`",
+			"x := 'hi {name}'\ny := \"a\\tb\"\nz := `raw\n  text`\n",
+		},
+		{
+			"switch",
+			"switch x {\ncase 1,2:\nprint(1)\n  // two\ncase 3:\ndefault:\n  print(0)\n}",
+			"switch x {\ncase 1, 2:\n    print(1)\n// two\ncase 3:\ndefault:\n    print(0)\n}\n",
+		},
+		{
+			"match",
+			"match x {\ncase [a,...rest] if a>1:\nprint(rest)\n  // map\ncase {\"kind\":\"pod\",name}: name\ncase int()|string(_):\ncase -1|\"a\":\ndefault:\n  print(0)\n}",
+			"match x {\ncase [a, ...rest] if a > 1:\n    print(rest)\n// map\ncase {\"kind\": \"pod\", name}:\n    name\ncase int() | string(_):\ncase -1 | \"a\":\ndefault:\n    print(0)\n}\n",
+		},
+		{
+			"select",
+			"select {  // wait\ncase v,ok:=<-a: print(v)\ncase b<-(f(1)):\n  // sent\n  print(\"sent\")\ncase <-c:\ndefault:\n}",
+			"select { // wait\ncase v, ok := <-a:\n    print(v)\ncase b <- (f(1)):\n    // sent\n    print(\"sent\")\ncase <-c:\ndefault:\n}\n",
+		},
+		{
+			"generator",
+			"func count(n) {\nfor i := range n {\nyield i*2\n}\nyield\n}",
+			"func count(n) {\n    for i := range n {\n        yield i * 2\n    }\n    yield\n}\n",
+		},
+		{
+			"try",
+			"try {\nthrow(\"x\")\n} catch e {\nprint(e)\n} finally {\nprint(1)\n}",
+			"try {\n    throw(\"x\")\n} catch e {\n    print(e)\n} finally {\n    print(1)\n}\n",
+		},
+		{
+			"structs and methods",
+			"struct Point {x,y=0}\nstruct Config {\nname\nport=80\n}\nfunc (p Point) len() {\nreturn p.x\n}",
+			"struct Point { x, y = 0 }\nstruct Config {\n    name\n    port = 80\n}\nfunc (p Point) len() {\n    return p.x\n}\n",
+		},
+		{
+			"type annotations",
+			"func add(a:int,b:int=1,c=2)->int{ return a+b }\nvar x:list[int]=[]\ny : string|nil := nil\nconst Z:float=1.0",
+			"func add(a: int, b: int = 1, c=2) -> int { return a + b }\nvar x: list[int] = []\ny: string | nil := nil\nconst Z: float = 1.0\n",
+		},
+		{
+			"loops",
+			"for { break }\nfor x < 3 {\nx += 1\n}\nfor _, v := range  items {\n}",
+			"for { break }\nfor x < 3 {\n    x += 1\n}\nfor _, v := range items {}\n",
+		},
+		{
+			"imports",
+			"import  strings as s\nfrom a.b import (c as d,\n  e)\nfrom x import (\ny,\nz,\n)",
+			"import strings as s\nfrom a.b import (c as d, e)\nfrom x import (\n    y,\n    z,\n)\n",
+		},
+		{
+			"line breaks",
+			"x := a |\n  b | c\ny := a &&\n  b\nz := a.\n  b().\n  c()",
+			"x := a |\n    b | c\ny := a &&\n    b\nz := a.\n    b().\n    c()\n",
+		},
+		{
+			"channels",
+			"ch <- (f(1))\nv := <-ch\ngo func() { ch <- 1 }()\ndefer close(ch)",
+			"ch <- (f(1))\nv := <-ch\ngo func() { ch <- 1 }()\ndefer close(ch)\n",
+		},
+		{
+			"empty",
+			"\n\n",
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Format(context.Background(), tt.input)
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, result)
+			again, err := Format(context.Background(), result)
+			require.Nil(t, err)
+			require.Equal(t, result, again)
+		})
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	_, err := Format(context.Background(), "x := ")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "parse error")
+}
+
+func TestFprintWithoutSource(t *testing.T) {
+	ctx := context.Background()
+	program, err := parser.Parse(ctx, "// comment\nx := {\n  \"b\": 'a\\'b',\n  \"a\": \"\\n\",\n}\n\ny := `z`")
+	require.Nil(t, err)
+	var buf bytes.Buffer
+	require.Nil(t, Fprint(&buf, program, ""))
+	require.Equal(t, "x := {\"a\": \"\\n\", \"b\": 'a\\'b'}\ny := `z`\n", buf.String())
+}
+
+// Formatting the example scripts must produce programs that are equivalent to
+// the originals and are unchanged when formatted again.
+func TestFormatExamples(t *testing.T) {
+	ctx := context.Background()
+	paths, err := filepath.Glob("../examples/scripts/*.risor")
+	require.Nil(t, err)
+	require.NotEmpty(t, paths)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		require.Nil(t, err)
+		source := string(data)
+		result, err := Format(ctx, source)
+		require.Nil(t, err, path)
+
+		original, err := parser.Parse(ctx, source)
+		require.Nil(t, err)
+		formatted, err := parser.Parse(ctx, result)
+		require.Nil(t, err, path)
+		// Without the source, both are printed in the same canonical form
+		var want, got bytes.Buffer
+		require.Nil(t, Fprint(&want, original, ""))
+		require.Nil(t, Fprint(&got, formatted, ""))
+		require.Equal(t, want.String(), got.String(), path)
+		require.Equal(t, len(original.Comments()), len(formatted.Comments()), path)
+
+		again, err := Format(ctx, result)
+		require.Nil(t, err)
+		require.Equal(t, result, again, path)
+	}
+}
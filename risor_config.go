@@ -2,14 +2,18 @@ package risor
 
 import (
 	"fmt"
+	"log/slog"
 	"sort"
 	"strings"
 
 	"github.com/itrn0/risor/builtins"
 	"github.com/itrn0/risor/compiler"
 	"github.com/itrn0/risor/importer"
+	"github.com/itrn0/risor/limits"
+	modArchive "github.com/itrn0/risor/modules/archive"
 	modBase64 "github.com/itrn0/risor/modules/base64"
 	modBytes "github.com/itrn0/risor/modules/bytes"
+	modCrypto "github.com/itrn0/risor/modules/crypto"
 	modDns "github.com/itrn0/risor/modules/dns"
 	modErrors "github.com/itrn0/risor/modules/errors"
 	modExec "github.com/itrn0/risor/modules/exec"
@@ -26,7 +30,16 @@ import (
 	modTime "github.com/itrn0/risor/modules/time"
 	modYAML "github.com/itrn0/risor/modules/yaml"
 	"github.com/itrn0/risor/object"
+	"github.com/itrn0/risor/parser"
+	"github.com/itrn0/risor/policy"
 	"github.com/itrn0/risor/vm"
+
+	// These modules aren't default globals, since their names are common
+	// variable names, but their codecs are available to encode and decode
+	_ "github.com/itrn0/risor/modules/dotenv"
+	_ "github.com/itrn0/risor/modules/ini"
+	_ "github.com/itrn0/risor/modules/toml"
+	_ "github.com/itrn0/risor/modules/xml"
 )
 
 // Config assists in configuring Risor evaluations.
@@ -38,7 +51,19 @@ type Config struct {
 	localImportPath       string
 	withoutDefaultGlobals bool
 	withConcurrency       bool
+	runtimeErrors         bool
 	listenersAllowed      bool
+	policy                *policy.Policy
+	filename              string
+	maxMemoryUsage        *int64
+	maxInstructions       *int64
+	limits                limits.Limits
+	budget                *vm.Budget
+	cloneBudget           vm.CloneBudget
+	debugger              *vm.Debugger
+	profiler              *vm.Profiler
+	coverage              *vm.Coverage
+	logger                *slog.Logger
 	initialized           bool
 }
 
@@ -105,6 +130,7 @@ func (cfg *Config) init() error {
 	if err := cfg.applyOverrides(); err != nil {
 		return err
 	}
+	cfg.applyPolicy()
 	return nil
 }
 
@@ -127,8 +153,10 @@ func (cfg *Config) applyDefaultGlobals() {
 	}
 	// Add default modules as globals
 	modules := map[string]object.Object{
+		"archive":  modArchive.Module(),
 		"base64":   modBase64.Module(),
 		"bytes":    modBytes.Module(),
+		"crypto":   modCrypto.Module(),
 		"errors":   modErrors.Module(),
 		"exec":     modExec.Module(),
 		"filepath": modFilepath.Module(),
@@ -192,6 +220,45 @@ func (cfg *Config) applyOverrides() error {
 	return nil
 }
 
+// Wraps the builtins governed by the policy, including those within modules,
+// so that calls to them are checked against the policy. Modules are copied
+// before they are modified since they may be shared with other evaluations.
+func (cfg *Config) applyPolicy() {
+	if cfg.policy == nil {
+		return
+	}
+	for name, value := range cfg.globals {
+		switch value := value.(type) {
+		case *object.Builtin:
+			if cfg.policy.Governs(name) {
+				cfg.globals[name] = value.Wrap(cfg.policy.Wrapper(name))
+			}
+		case *object.Module:
+			cfg.globals[name] = applyModulePolicy(cfg.policy, value, name)
+		}
+	}
+}
+
+func applyModulePolicy(p *policy.Policy, m *object.Module, name string) *object.Module {
+	m = m.Clone()
+	if p.Governs(name) {
+		m.WrapCall(p.Wrapper(name))
+	}
+	for _, attr := range m.AttrNames() {
+		attrName := name + "." + attr
+		value, _ := m.GetAttr(attr)
+		switch value := value.(type) {
+		case *object.Builtin:
+			if p.Governs(attrName) {
+				m.Override(attr, value.Wrap(p.Wrapper(attrName)))
+			}
+		case *object.Module:
+			m.Override(attr, applyModulePolicy(p, value, attrName))
+		}
+	}
+	return m
+}
+
 // CompilerOpts returns compiler options derived from this configuration.
 func (cfg *Config) CompilerOpts() []compiler.Option {
 	cfg.init()
@@ -203,6 +270,15 @@ func (cfg *Config) CompilerOpts() []compiler.Option {
 	return opts
 }
 
+// ParserOpts returns parser options derived from this configuration.
+func (cfg *Config) ParserOpts() []parser.Option {
+	var opts []parser.Option
+	if cfg.filename != "" {
+		opts = append(opts, parser.WithFile(cfg.filename))
+	}
+	return opts
+}
+
 // VMOpts returns virtual machine options derived from this configuration.
 func (cfg *Config) VMOpts() []vm.Option {
 	cfg.init()
@@ -225,6 +301,34 @@ func (cfg *Config) VMOpts() []vm.Option {
 	if cfg.withConcurrency {
 		opts = append(opts, vm.WithConcurrency())
 	}
+	if cfg.runtimeErrors {
+		opts = append(opts, vm.WithRuntimeErrors())
+	}
+	if cfg.limits != nil {
+		opts = append(opts, vm.WithLimits(cfg.limits))
+	}
+	if cfg.maxMemoryUsage != nil {
+		opts = append(opts, vm.WithMaxMemoryUsage(*cfg.maxMemoryUsage))
+	}
+	if cfg.maxInstructions != nil {
+		opts = append(opts, vm.WithMaxInstructions(*cfg.maxInstructions))
+	}
+	if cfg.budget != nil {
+		opts = append(opts, vm.WithBudget(cfg.budget))
+	}
+	opts = append(opts, vm.WithCloneBudget(cfg.cloneBudget))
+	if cfg.debugger != nil {
+		opts = append(opts, vm.WithDebugger(cfg.debugger))
+	}
+	if cfg.profiler != nil {
+		opts = append(opts, vm.WithProfiler(cfg.profiler))
+	}
+	if cfg.coverage != nil {
+		opts = append(opts, vm.WithCoverage(cfg.coverage))
+	}
+	if cfg.logger != nil {
+		opts = append(opts, vm.WithLogger(cfg.logger))
+	}
 	return opts
 }
 
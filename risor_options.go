@@ -1,6 +1,13 @@
 package risor
 
-import "github.com/itrn0/risor/importer"
+import (
+	"log/slog"
+
+	"github.com/itrn0/risor/importer"
+	"github.com/itrn0/risor/limits"
+	"github.com/itrn0/risor/policy"
+	"github.com/itrn0/risor/vm"
+)
 
 // Option describes a function used to configure a Risor evaluation.
 type Option func(*Config)
@@ -76,9 +83,109 @@ func WithConcurrency() Option {
 	}
 }
 
+// WithRuntimeErrors causes evaluation errors to be wrapped in a
+// vm.RuntimeError, which reports the location where the error occurred.
+func WithRuntimeErrors() Option {
+	return func(cfg *Config) {
+		cfg.runtimeErrors = true
+	}
+}
+
 // WithListenersAllowed allows opening sockets for listening.
 func WithListenersAllowed() Option {
 	return func(cfg *Config) {
 		cfg.listenersAllowed = true
 	}
 }
+
+// WithPolicy applies a capability policy to Risor evaluations. Calls to the
+// privileged builtins and modules available as globals are checked against
+// the policy, and denied calls fail with an *errz.PermissionError.
+func WithPolicy(p *policy.Policy) Option {
+	return func(cfg *Config) {
+		cfg.policy = p
+	}
+}
+
+// WithFilename sets the name of the file the source code was read from. This is
+// included in the locations reported by parse and runtime errors.
+func WithFilename(name string) Option {
+	return func(cfg *Config) {
+		cfg.filename = name
+	}
+}
+
+// WithMaxMemoryUsage sets the maximum number of bytes that may be held on the
+// VM stack. Use limits.NoLimit to remove the limit.
+func WithMaxMemoryUsage(size int64) Option {
+	return func(cfg *Config) {
+		cfg.maxMemoryUsage = &size
+	}
+}
+
+// WithMaxInstructions sets the maximum number of instructions that may be
+// executed. Use limits.NoLimit to remove the limit.
+func WithMaxInstructions(count int64) Option {
+	return func(cfg *Config) {
+		cfg.maxInstructions = &count
+	}
+}
+
+// WithLimits applies the given Limits to Risor evaluations. If it implements
+// limits.VMLimits, the memory and instruction limits it sets are used unless
+// overridden by WithMaxMemoryUsage or WithMaxInstructions.
+func WithLimits(l limits.Limits) Option {
+	return func(cfg *Config) {
+		cfg.limits = l
+	}
+}
+
+// WithBudget supplies an instruction budget to draw from. Sharing one budget
+// across evaluations enforces a combined instruction limit.
+func WithBudget(budget *vm.Budget) Option {
+	return func(cfg *Config) {
+		cfg.budget = budget
+	}
+}
+
+// WithDebugger attaches a Debugger that may stop execution at breakpoints and
+// while stepping through code.
+func WithDebugger(debugger *vm.Debugger) Option {
+	return func(cfg *Config) {
+		cfg.debugger = debugger
+	}
+}
+
+// WithProfiler attaches a Profiler that records the instructions executed and
+// the wall time spent in each Risor function and source line.
+func WithProfiler(profiler *vm.Profiler) Option {
+	return func(cfg *Config) {
+		cfg.profiler = profiler
+	}
+}
+
+// WithCoverage attaches a Coverage that records the source lines executed
+// during the evaluation.
+func WithCoverage(coverage *vm.Coverage) Option {
+	return func(cfg *Config) {
+		cfg.coverage = coverage
+	}
+}
+
+// WithCloneBudget determines whether goroutines started with spawn and go
+// share the instruction budget of the evaluation or inherit a copy of it.
+func WithCloneBudget(mode vm.CloneBudget) Option {
+	return func(cfg *Config) {
+		cfg.cloneBudget = mode
+	}
+}
+
+// WithLogger sets the logger that the log module writes to. The VM also
+// reports events such as exceeding the memory limit to it. If no logger is
+// set, the default slog logger is used. The log module isn't a default
+// global, so add it with WithGlobal to use it in scripts.
+func WithLogger(logger *slog.Logger) Option {
+	return func(cfg *Config) {
+		cfg.logger = logger
+	}
+}
@@ -1,14 +1,24 @@
 package risor
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/itrn0/risor/compiler"
+	"github.com/itrn0/risor/errz"
+	"github.com/itrn0/risor/limits"
+	modIter "github.com/itrn0/risor/modules/iter"
+	modLog "github.com/itrn0/risor/modules/log"
 	"github.com/itrn0/risor/object"
 	ros "github.com/itrn0/risor/os"
 	"github.com/itrn0/risor/parser"
+	"github.com/itrn0/risor/policy"
+	"github.com/itrn0/risor/vm"
 	"github.com/stretchr/testify/require"
 )
 
@@ -78,6 +88,28 @@ func TestDefaultGlobals(t *testing.T) {
 	}
 }
 
+func TestCommonNamesAreFree(t *testing.T) {
+	// Modules with names that scripts commonly use for variables aren't
+	// default globals
+	for _, name := range []string{"dotenv", "ini", "log", "sync", "toml", "xml"} {
+		result, err := Eval(context.Background(), name+` := [1]; `+name)
+		require.Nil(t, err, name)
+		require.Equal(t, object.NewList([]object.Object{object.NewInt(1)}), result)
+	}
+	// The codecs of these modules are still available
+	result, err := Eval(context.Background(), `decode(encode({a: 1}, "toml"), "toml")`)
+	require.Nil(t, err)
+	require.Equal(t, map[string]any{"a": int64(1)}, result.Interface())
+}
+
+func TestIterModuleOverride(t *testing.T) {
+	// The iter builtin is a default global, which the module may replace
+	result, err := Eval(context.Background(), `[list(iter([1])), list(iter.take(range(5), 2))]`,
+		WithGlobalOverride("iter", modIter.Module()))
+	require.Nil(t, err)
+	require.Equal(t, []any{[]any{int64(1)}, []any{int64(0), int64(1)}}, result.Interface())
+}
+
 func TestWithDenyList(t *testing.T) {
 	type testCase struct {
 		input       string
@@ -129,13 +161,32 @@ exit(1)`,
 func TestWithoutDefaultGlobals(t *testing.T) {
 	_, err := Eval(context.Background(), "json.marshal(42)", WithoutDefaultGlobals())
 	require.NotNil(t, err)
-	require.Equal(t, errors.New("compile error: undefined variable \"json\" (line 1)"), err)
+	require.EqualError(t, err, "compile error: undefined variable \"json\" (line 1)")
 }
 
 func TestWithoutDefaultGlobal(t *testing.T) {
 	_, err := Eval(context.Background(), "json.marshal(42)", WithoutGlobal("json"))
 	require.NotNil(t, err)
-	require.Equal(t, errors.New("compile error: undefined variable \"json\" (line 1)"), err)
+	require.EqualError(t, err, "compile error: undefined variable \"json\" (line 1)")
+}
+
+func TestWithLogger(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	_, err := Eval(context.Background(), `
+	log.info("hello", {"n": 1})
+	spawn(func() { log.with({"thread": true}).warn("from thread") }).wait()
+	log.debug("dropped")
+	`, WithLogger(logger), WithConcurrency(), WithGlobal("log", modLog.Module()))
+	require.Nil(t, err)
+	require.Equal(t, "level=INFO msg=hello n=1\nlevel=WARN msg=\"from thread\" thread=true\n", out.String())
 }
 
 func TestWithVirtualOSStdinBuffer(t *testing.T) {
@@ -240,6 +291,36 @@ func TestEvalCode(t *testing.T) {
 	}
 }
 
+func TestEvalCodeBinary(t *testing.T) {
+	ctx := context.Background()
+
+	source := `
+	func add(a, b=10) { a + b }
+	[add(1), add(2, 3), strings.to_upper("ok")]
+	`
+
+	cfg := NewConfig()
+	ast, err := parser.Parse(ctx, source)
+	require.Nil(t, err)
+	code, err := compiler.Compile(ast, cfg.CompilerOpts()...)
+	require.Nil(t, err)
+
+	// Round trip the code through the binary format before evaluating it
+	data, err := compiler.MarshalCodeBinary(code)
+	require.Nil(t, err)
+	require.True(t, compiler.IsBinaryCode(data))
+	decoded, err := compiler.UnmarshalCodeBinary(data)
+	require.Nil(t, err)
+
+	result, err := EvalCode(ctx, decoded)
+	require.Nil(t, err)
+	require.Equal(t, object.NewList([]object.Object{
+		object.NewInt(11),
+		object.NewInt(5),
+		object.NewString("OK"),
+	}), result)
+}
+
 func TestCall(t *testing.T) {
 	ctx := context.Background()
 	source := `
@@ -364,3 +445,209 @@ func TestWithConcurrency(t *testing.T) {
 	require.NotNil(t, err)
 	require.Equal(t, "eval error: context did not contain a spawn function", err.Error())
 }
+
+func TestWithRuntimeErrors(t *testing.T) {
+	ctx := context.Background()
+	script := "x := 1\nerror(\"oops\")"
+
+	_, err := Eval(ctx, script)
+	require.NotNil(t, err)
+	var runtimeErr *vm.RuntimeError
+	require.False(t, errors.As(err, &runtimeErr))
+
+	_, err = Eval(ctx, script, WithRuntimeErrors(), WithFilename("main.risor"))
+	require.NotNil(t, err)
+	require.True(t, errors.As(err, &runtimeErr))
+	require.Equal(t, "main.risor", runtimeErr.Location().File)
+	require.Equal(t, 2, runtimeErr.Location().Line)
+}
+
+func TestWithMaxInstructions(t *testing.T) {
+	ctx := context.Background()
+	_, err := Eval(ctx, `for i := 0; i < 100; i++ {}`, WithMaxInstructions(50))
+	require.Error(t, err)
+	require.Equal(t, "eval error: max instructions limit of 50 exceeded", err.Error())
+
+	_, err = Eval(ctx, `for i := 0; i < 2000000; i++ {}`, WithMaxInstructions(limits.NoLimit))
+	require.Nil(t, err)
+}
+
+func TestWithMaxMemoryUsage(t *testing.T) {
+	ctx := context.Background()
+	_, err := Eval(ctx, `strings.repeat("a", 1000)`, WithMaxMemoryUsage(100))
+	require.Error(t, err)
+	require.Equal(t, "memory limit exceeded", err.Error())
+
+	result, err := Eval(ctx, `len(strings.repeat("a", 2000000))`, WithMaxMemoryUsage(limits.NoLimit))
+	require.Nil(t, err)
+	require.Equal(t, object.NewInt(2000000), result)
+}
+
+func TestWithPolicy(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.txt")
+	require.Nil(t, os.WriteFile(path, []byte("hello"), 0o600))
+
+	p := policy.New(
+		policy.Deny("exec.*"),
+		policy.AllowPaths("os.read_file", dir),
+		policy.AllowHosts("http.get", "example.com", "*.example.org"),
+	)
+
+	result, err := Eval(ctx, `string(os.read_file(path))`,
+		WithPolicy(p), WithGlobal("path", path))
+	require.Nil(t, err)
+	require.Equal(t, object.NewString("hello"), result)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`exec.command("ls")`, "permission error: exec.command: denied by policy"},
+		{`exec("ls")`, "permission error: exec: denied by policy"},
+		{`os.read_file("/etc/passwd")`, `permission error: os.read_file: path "/etc/passwd" is not allowed`},
+		{`os.read_file(dir + "/../x")`, `permission error: os.read_file: path "` + dir + `/../x" is not allowed`},
+		{`http.get("https://example.net/x")`, `permission error: http.get: host "example.net" is not allowed`},
+		{`http.get("https://example.org")`, `permission error: http.get: host "example.org" is not allowed`},
+		{`try { exec.command("ls") } catch e { "caught" }`, "permission error: exec.command: denied by policy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Eval(ctx, tt.input, WithPolicy(p), WithGlobal("dir", dir))
+			require.NotNil(t, err)
+			require.Equal(t, tt.expected, err.Error())
+			var permErr *errz.PermissionError
+			require.True(t, errors.As(err, &permErr))
+		})
+	}
+
+	// Calls that aren't privileged are unaffected
+	result, err = Eval(ctx, `strings.to_upper("ok")`, WithPolicy(p))
+	require.Nil(t, err)
+	require.Equal(t, object.NewString("OK"), result)
+}
+
+func TestWithPolicyDenyByDefault(t *testing.T) {
+	ctx := context.Background()
+	p := policy.New(policy.DenyByDefault(), policy.Allow("os.getwd"))
+
+	_, err := Eval(ctx, `os.getwd()`, WithPolicy(p))
+	require.Nil(t, err)
+	_, err = Eval(ctx, `getenv("HOME")`, WithPolicy(p))
+	require.NotNil(t, err)
+	require.Equal(t, "permission error: getenv: not allowed by policy", err.Error())
+	result, err := Eval(ctx, `len([1, 2])`, WithPolicy(p))
+	require.Nil(t, err)
+	require.Equal(t, object.NewInt(2), result)
+}
+
+func TestWithPolicyDeniesArchive(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	p := policy.New(policy.DenyByDefault())
+	opts := []Option{WithPolicy(p), WithGlobal("dir", dir)}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`archive.create(dir + "/x.tar", ["hostname"], {"dir": "/etc"})`, "permission error: archive.create: not allowed by policy"},
+		{`archive.extract(dir + "/x.tar", dir + "/out")`, "permission error: archive.extract: not allowed by policy"},
+		{`archive.list(dir + "/x.tar")`, "permission error: archive.list: not allowed by policy"},
+	}
+	for _, tt := range tests {
+		_, err := Eval(ctx, tt.input, opts...)
+		require.NotNil(t, err)
+		require.Equal(t, tt.expected, err.Error())
+	}
+	_, err := os.Stat(filepath.Join(dir, "x.tar"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestWithPolicyAliases(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "data.txt"), []byte("hello"), 0o600))
+	p := policy.New(policy.DenyByDefault(), policy.AllowPaths("os.read_file", dir))
+	opts := []Option{WithPolicy(p), WithGlobal("dir", dir)}
+
+	result, err := Eval(ctx, `cat(dir + "/data.txt")`, opts...)
+	require.Nil(t, err)
+	require.Equal(t, object.NewString("hello"), result)
+
+	_, err = Eval(ctx, `cat("/etc/passwd")`, opts...)
+	require.NotNil(t, err)
+	require.Equal(t, `permission error: cat: os.read_file: path "/etc/passwd" is not allowed`, err.Error())
+
+	_, err = Eval(ctx, `archive.list("/etc/passwd")`, opts...)
+	require.NotNil(t, err)
+	require.Equal(t, `permission error: archive.list: os.read_file: path "/etc/passwd" is not allowed`, err.Error())
+}
+
+func TestWithPolicyFileMethods(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.txt")
+	require.Nil(t, os.WriteFile(path, []byte("hello"), 0o600))
+	opts := []Option{WithGlobal("path", path)}
+
+	// Rules only apply to the functions they name, not to file methods
+	p := policy.New(policy.Deny("os.read_file"))
+	result, err := Eval(ctx, `string(os.open(path).read())`, append(opts, WithPolicy(p))...)
+	require.Nil(t, err)
+	require.Equal(t, object.NewString("hello"), result)
+
+	p = policy.New(policy.Deny("os.read_file"), policy.Deny("os.open"))
+	_, err = Eval(ctx, `os.open(path).read()`, append(opts, WithPolicy(p))...)
+	require.NotNil(t, err)
+	require.Equal(t, "permission error: os.open: denied by policy", err.Error())
+
+	_, err = Eval(ctx, `open(path).read()`, append(opts, WithPolicy(p))...)
+	require.NotNil(t, err)
+	require.Equal(t, "permission error: open: os.open: denied by policy", err.Error())
+}
+
+func TestWithPolicyAudit(t *testing.T) {
+	ctx := context.Background()
+	var events []policy.Event
+	p := policy.New(
+		policy.Deny("os.setenv"),
+		policy.WithPrivileged("custom.*"),
+		policy.WithAudit(func(ctx context.Context, event policy.Event) {
+			events = append(events, event)
+		}),
+	)
+	custom := object.NewBuiltinsModule("custom", map[string]object.Object{
+		"run": object.NewBuiltin("run", func(ctx context.Context, args ...object.Object) object.Object {
+			return object.NewString("ran")
+		}),
+	})
+	opts := []Option{WithPolicy(p), WithGlobal("custom", custom)}
+
+	_, err := Eval(ctx, `custom.run(1); len("abc"); os.setenv("X", "1")`, opts...)
+	require.NotNil(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, "custom.run", events[0].Name)
+	require.Equal(t, []object.Object{object.NewInt(1)}, events[0].Args)
+	require.True(t, events[0].Allowed())
+	require.Equal(t, "os.setenv", events[1].Name)
+	require.False(t, events[1].Allowed())
+	require.Equal(t, "denied by policy", events[1].Err.Err.Error())
+
+	// Modules shared between evaluations are only wrapped once per evaluation
+	events = nil
+	for i := 0; i < 2; i++ {
+		result, err := Eval(ctx, `custom.run()`, opts...)
+		require.Nil(t, err)
+		require.Equal(t, object.NewString("ran"), result)
+	}
+	require.Len(t, events, 2)
+
+	// The module supplied by the caller is left unmodified
+	events = nil
+	run, ok := custom.GetAttr("run")
+	require.True(t, ok)
+	run.(*object.Builtin).Call(ctx)
+	require.Empty(t, events)
+}
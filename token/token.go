@@ -1,6 +1,8 @@
 // Package token defines language keywords and tokens used when lexing source code.
 package token
 
+import "sort"
+
 // Type describes the type of a token as a string.
 type Type string
 
@@ -35,6 +37,7 @@ type Token struct {
 // Token types
 const (
 	AND             = "&&"
+	ARROW           = "->"
 	ASSIGN          = "="
 	ASTERISK        = "*"
 	ASTERISK_EQUALS = "*="
@@ -42,7 +45,9 @@ const (
 	FSTRING         = "'"
 	BANG            = "!"
 	CASE            = "case"
+	CATCH           = "CATCH"
 	COLON           = ":"
+	COMMENT         = "COMMENT"
 	COMMA           = ","
 	CONST           = "CONST"
 	DECLARE         = ":="
@@ -50,9 +55,11 @@ const (
 	DEFER           = "DEFER"
 	FUNC            = "FUNC"
 	ELSE            = "ELSE"
+	ELLIPSIS        = "..."
 	EOF             = "EOF"
 	EQ              = "=="
 	FALSE           = "FALSE"
+	FINALLY         = "FINALLY"
 	FLOAT           = "FLOAT"
 	FOR             = "FOR"
 	GT              = ">"
@@ -95,6 +102,7 @@ const (
 	STRUCT          = "STRUCT"
 	SWITCH          = "switch"
 	TRUE            = "TRUE"
+	TRY             = "TRY"
 	NEWLINE         = "EOL"
 	IMPORT          = "IMPORT"
 	BREAK           = "BREAK"
@@ -104,6 +112,7 @@ const (
 	RANGE           = "RANGE"
 	FROM            = "FROM"
 	AS              = "AS"
+	YIELD           = "YIELD"
 )
 
 // Reserved keywords
@@ -111,12 +120,14 @@ var keywords = map[string]Type{
 	"as":       AS,
 	"break":    BREAK,
 	"case":     CASE,
+	"catch":    CATCH,
 	"const":    CONST,
 	"continue": CONTINUE,
 	"default":  DEFAULT,
 	"defer":    DEFER,
 	"else":     ELSE,
 	"false":    FALSE,
+	"finally":  FINALLY,
 	"for":      FOR,
 	"from":     FROM,
 	"func":     FUNC,
@@ -130,7 +141,19 @@ var keywords = map[string]Type{
 	"struct":   STRUCT,
 	"switch":   SWITCH,
 	"true":     TRUE,
+	"try":      TRY,
 	"var":      VAR,
+	"yield":    YIELD,
+}
+
+// Keywords returns the reserved keywords in sorted order.
+func Keywords() []string {
+	names := make([]string, 0, len(keywords))
+	for name := range keywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // LookupIdentifier used to determinate whether identifier is keyword nor not
@@ -1,6 +1,7 @@
 package token
 
 import (
+	"sort"
 	"strings"
 	"testing"
 
@@ -37,3 +38,12 @@ func TestPosition(t *testing.T) {
 	require.Equal(t, 3, tok.StartPosition.LineNumber())
 	require.Equal(t, 1, tok.StartPosition.ColumnNumber())
 }
+
+func TestKeywords(t *testing.T) {
+	names := Keywords()
+	require.Len(t, names, len(keywords))
+	require.True(t, sort.StringsAreSorted(names))
+	for _, name := range names {
+		require.NotEqual(t, IDENT, LookupIdentifier(name))
+	}
+}
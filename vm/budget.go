@@ -0,0 +1,57 @@
+package vm
+
+import (
+	"sync/atomic"
+
+	"github.com/itrn0/risor/limits"
+)
+
+// Budget tracks the number of instructions executed against a limit. A single
+// Budget may be shared by several VMs, e.g. a VM and the clones it creates for
+// spawn and go, so that they all draw from the same allowance.
+type Budget struct {
+	limit int64
+	used  int64
+}
+
+// NewBudget returns a Budget that allows the given number of instructions.
+// Use limits.NoLimit for an unlimited budget.
+func NewBudget(limit int64) *Budget {
+	return &Budget{limit: limit}
+}
+
+// Limit returns the maximum number of instructions allowed by the budget.
+func (b *Budget) Limit() int64 {
+	return b.limit
+}
+
+// Used returns the number of instructions consumed so far.
+func (b *Budget) Used() int64 {
+	return atomic.LoadInt64(&b.used)
+}
+
+// Consume records the execution of one instruction. False is returned if
+// the budget is exhausted.
+func (b *Budget) Consume() bool {
+	used := atomic.AddInt64(&b.used, 1)
+	return b.limit <= limits.NoLimit || used < b.limit
+}
+
+// Fork returns a new Budget with the same limit, seeded with the number of
+// instructions consumed so far by this one.
+func (b *Budget) Fork() *Budget {
+	return &Budget{limit: b.limit, used: b.Used()}
+}
+
+// CloneBudget determines how the clones of a VM used by spawn and go account
+// for the instructions they execute.
+type CloneBudget int
+
+const (
+	// CloneBudgetInherit gives each clone its own budget, with the same limit
+	// as the parent and starting from the parent's current usage.
+	CloneBudgetInherit CloneBudget = iota
+
+	// CloneBudgetShared makes clones draw from the parent's budget.
+	CloneBudgetShared
+)
@@ -0,0 +1,208 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/itrn0/risor/compiler"
+)
+
+// Coverage records which source lines are executed by VMs. A Coverage may be
+// shared by several VMs, including the clones used by spawn and go, and by
+// VMs that compile the same files separately.
+//
+// Each source line that compiled to at least one instruction is a coverable
+// line. A line's count is the number of times execution entered a run of
+// instructions compiled from it.
+type Coverage struct {
+	mu    sync.Mutex
+	lines map[coverageKey]*coverageLine
+	codes map[*compiler.Code][]*coverageLine
+}
+
+type coverageKey struct {
+	file string
+	line int
+}
+
+type coverageLine struct {
+	count       int64
+	startColumn int
+	endColumn   int
+}
+
+// CoverageLine is the coverage of one source line.
+type CoverageLine struct {
+	File string
+	Line int
+
+	// StartColumn and EndColumn span the columns of the code on the line
+	// that compiled to instructions.
+	StartColumn int
+	EndColumn   int
+
+	// Count is the number of times the line was executed.
+	Count int64
+}
+
+// NewCoverage returns an empty Coverage.
+func NewCoverage() *Coverage {
+	return &Coverage{
+		lines: map[coverageKey]*coverageLine{},
+		codes: map[*compiler.Code][]*coverageLine{},
+	}
+}
+
+// Add registers the lines of the given code and all the functions within
+// it, so that they're reported even if they never run. Code is registered
+// automatically when it first runs, so this is only needed for code that
+// might not.
+func (c *Coverage) Add(code *compiler.Code) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.add(code.Root())
+}
+
+func (c *Coverage) add(root *compiler.Code) {
+	if _, ok := c.codes[root]; ok {
+		return
+	}
+	for _, code := range root.Flatten() {
+		counters := make([]*coverageLine, code.InstructionCount())
+		var prev compiler.SourceLocation
+		for offset := range counters {
+			loc, ok := code.LocationAt(offset)
+			if !ok {
+				prev = loc
+				continue
+			}
+			key := coverageKey{file: loc.File, line: loc.Line}
+			line, ok := c.lines[key]
+			if !ok {
+				line = &coverageLine{startColumn: loc.Column, endColumn: loc.Column + 1}
+				c.lines[key] = line
+			}
+			if loc.Column < line.startColumn {
+				line.startColumn = loc.Column
+			}
+			if loc.Column+1 > line.endColumn {
+				line.endColumn = loc.Column + 1
+			}
+			// Count entries into each run of instructions from one line
+			if offset == 0 || loc.Line != prev.Line || loc.File != prev.File {
+				counters[offset] = line
+			}
+			prev = loc
+		}
+		c.codes[code] = counters
+	}
+}
+
+// Returns the line counters for each instruction offset in the code. Only the
+// first offset in each run of instructions from one line has a counter.
+func (c *Coverage) counters(code *compiler.Code) []*coverageLine {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if counters, ok := c.codes[code]; ok {
+		return counters
+	}
+	c.add(code.Root())
+	return c.codes[code]
+}
+
+// Called before each instruction is executed.
+func (c *Coverage) record(vm *VirtualMachine) {
+	code := vm.activeCode.Code
+	if code != vm.coverageCode {
+		vm.coverageCode = code
+		vm.coverageCounters = c.counters(code)
+	}
+	if vm.ip < len(vm.coverageCounters) {
+		if line := vm.coverageCounters[vm.ip]; line != nil {
+			atomic.AddInt64(&line.count, 1)
+		}
+	}
+}
+
+// Lines returns the coverage of each coverable line, sorted by file and then
+// by line number.
+func (c *Coverage) Lines() []CoverageLine {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lines := make([]CoverageLine, 0, len(c.lines))
+	for key, line := range c.lines {
+		lines = append(lines, CoverageLine{
+			File:        key.file,
+			Line:        key.line,
+			StartColumn: line.startColumn,
+			EndColumn:   line.endColumn,
+			Count:       atomic.LoadInt64(&line.count),
+		})
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].File != lines[j].File {
+			return lines[i].File < lines[j].File
+		}
+		return lines[i].Line < lines[j].Line
+	})
+	return lines
+}
+
+// CoveragePercent returns the percentage of the lines that were executed.
+// Zero is returned if there are no lines.
+func CoveragePercent(lines []CoverageLine) float64 {
+	if len(lines) == 0 {
+		return 0
+	}
+	var covered int
+	for _, line := range lines {
+		if line.Count > 0 {
+			covered++
+		}
+	}
+	return 100 * float64(covered) / float64(len(lines))
+}
+
+// WriteCoverProfile writes the lines in the coverage profile format used by
+// "go test -coverprofile", with one block for each line.
+func WriteCoverProfile(w io.Writer, lines []CoverageLine) error {
+	if _, err := io.WriteString(w, "mode: count\n"); err != nil {
+		return err
+	}
+	for _, line := range lines {
+		_, err := fmt.Fprintf(w, "%s:%d.%d,%d.%d 1 %d\n",
+			line.File, line.Line, line.StartColumn, line.Line, line.EndColumn, line.Count)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteLCOV writes the lines in LCOV tracefile format, with a record for
+// each file.
+func WriteLCOV(w io.Writer, lines []CoverageLine) error {
+	for i := 0; i < len(lines); {
+		file := lines[i].File
+		if _, err := fmt.Fprintf(w, "TN:\nSF:%s\n", file); err != nil {
+			return err
+		}
+		var found, hit int
+		for ; i < len(lines) && lines[i].File == file; i++ {
+			if _, err := fmt.Fprintf(w, "DA:%d,%d\n", lines[i].Line, lines[i].Count); err != nil {
+				return err
+			}
+			found++
+			if lines[i].Count > 0 {
+				hit++
+			}
+		}
+		if _, err := fmt.Fprintf(w, "LF:%d\nLH:%d\nend_of_record\n", found, hit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package vm
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/itrn0/risor/compiler"
+	"github.com/itrn0/risor/object"
+)
+
+// StopReason describes why a Debugger stopped execution.
+type StopReason string
+
+const (
+	StopEntry      StopReason = "entry"
+	StopBreakpoint StopReason = "breakpoint"
+	StopStep       StopReason = "step"
+	StopPause      StopReason = "pause"
+)
+
+// DebugAction tells a Debugger how to proceed after execution stops.
+type DebugAction int
+
+const (
+	// DebugContinue runs until a breakpoint is reached or a pause is requested.
+	DebugContinue DebugAction = iota
+
+	// DebugStepIn stops at the next line, including lines in called functions.
+	DebugStepIn
+
+	// DebugStepOver stops at the next line in the current function or in one
+	// of its callers.
+	DebugStepOver
+
+	// DebugStepOut stops at the next line after the current function returns.
+	DebugStepOut
+)
+
+// Variable is a named value that was visible when execution stopped.
+type Variable struct {
+	Name  string
+	Value object.Object
+}
+
+// DebugFrame describes an active call when execution stopped, along with the
+// variables visible within it.
+type DebugFrame struct {
+	StackFrame
+
+	// Locals holds the local variables of the call that have been assigned.
+	// This is empty for frames running top-level code, since the variables
+	// there are globals.
+	Locals []Variable
+
+	// Free holds the variables the function captured from enclosing scopes.
+	Free []Variable
+}
+
+// Stop describes where and why execution stopped. Variables refer to the live
+// objects, which must not be modified while execution continues.
+type Stop struct {
+	Reason   StopReason
+	Location compiler.SourceLocation
+
+	// Frames is the call stack, from the outermost frame to the frame where
+	// execution stopped.
+	Frames []DebugFrame
+
+	// Globals holds the global variables defined by the script. Globals
+	// supplied by the host, such as builtins and modules, are omitted.
+	Globals []Variable
+}
+
+// StopHandler is called each time a Debugger stops execution. It runs on the
+// goroutine executing the code, which remains stopped until it returns the
+// action to take next. Cancel the evaluation context to stop the VM instead.
+type StopHandler func(ctx context.Context, stop *Stop) DebugAction
+
+// Debugger stops the execution of a VM at breakpoints and while stepping
+// through code. Execution stops only at the first instruction of a line. A
+// Debugger may be attached to one VM at a time; the clones used by spawn and
+// go run without it.
+type Debugger struct {
+	handler     StopHandler
+	mu          sync.Mutex
+	breakpoints map[string]map[int]bool
+	stopOnEntry bool
+	started     bool
+	action      DebugAction
+	actionDepth int
+	pause       int32
+}
+
+// NewDebugger returns a Debugger that calls the given handler each time
+// execution stops.
+func NewDebugger(handler StopHandler) *Debugger {
+	return &Debugger{
+		handler:     handler,
+		breakpoints: map[string]map[int]bool{},
+	}
+}
+
+// SetStopOnEntry determines whether execution stops at the first line of
+// code that is run.
+func (d *Debugger) SetStopOnEntry(stopOnEntry bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopOnEntry = stopOnEntry
+}
+
+// SetBreakpoints replaces the breakpoints in the given file with breakpoints
+// on the given lines. Files are matched against the filename recorded in
+// source locations and line numbers begin at 1.
+func (d *Debugger) SetBreakpoints(file string, lines []int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(lines) == 0 {
+		delete(d.breakpoints, file)
+		return
+	}
+	set := make(map[int]bool, len(lines))
+	for _, line := range lines {
+		set[line] = true
+	}
+	d.breakpoints[file] = set
+}
+
+// Pause requests that execution stop at the next line. It may be called from
+// any goroutine.
+func (d *Debugger) Pause() {
+	atomic.StoreInt32(&d.pause, 1)
+}
+
+// Called before each instruction is executed. If execution should stop here,
+// the handler is called and its action is recorded.
+func (d *Debugger) check(ctx context.Context, vm *VirtualMachine) {
+	loc, ok := vm.activeCode.LocationAt(vm.ip)
+	if !ok {
+		return
+	}
+	// Consider stopping only when a line is entered. Jumping backwards within
+	// a line, as a loop on one line does, enters it again.
+	f := vm.activeFrame
+	if loc.Line == f.debugLine && vm.ip > f.debugIP {
+		f.debugIP = vm.ip
+		return
+	}
+	f.debugLine, f.debugIP = loc.Line, vm.ip
+
+	reason, stop := d.shouldStop(vm.fp, loc)
+	if !stop {
+		return
+	}
+	action := d.handler(ctx, vm.debugStop(reason, loc))
+	d.mu.Lock()
+	d.action = action
+	d.actionDepth = vm.fp
+	d.mu.Unlock()
+}
+
+func (d *Debugger) shouldStop(depth int, loc compiler.SourceLocation) (StopReason, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if atomic.CompareAndSwapInt32(&d.pause, 1, 0) {
+		return StopPause, true
+	}
+	if !d.started {
+		d.started = true
+		if d.stopOnEntry {
+			return StopEntry, true
+		}
+	}
+	if d.breakpoints[loc.File][loc.Line] {
+		return StopBreakpoint, true
+	}
+	switch d.action {
+	case DebugStepIn:
+		return StopStep, true
+	case DebugStepOver:
+		return StopStep, depth <= d.actionDepth
+	case DebugStepOut:
+		return StopStep, depth < d.actionDepth
+	}
+	return "", false
+}
+
+// Captures the state of the VM where execution stopped.
+func (vm *VirtualMachine) debugStop(reason StopReason, loc compiler.SourceLocation) *Stop {
+	trace := vm.stackTraceAt(vm.ip)
+	frames := make([]DebugFrame, len(trace))
+	for fp, stackFrame := range trace {
+		f := &vm.frames[fp]
+		frame := DebugFrame{StackFrame: stackFrame}
+		if f.code != nil && !f.code.IsRoot() {
+			for i, value := range f.locals {
+				// Named functions hold a reference to themselves to support
+				// recursion, which is not of interest here
+				if value == nil || (f.fn != nil && value == f.fn) {
+					continue
+				}
+				frame.Locals = append(frame.Locals, Variable{
+					Name:  f.code.Local(i).Name(),
+					Value: value,
+				})
+			}
+		}
+		if f.fn != nil {
+			for i, cell := range f.fn.FreeVars() {
+				if i >= f.code.FreeCount() {
+					break
+				}
+				frame.Free = append(frame.Free, Variable{
+					Name:  f.code.Free(i).Name(),
+					Value: cell.Value(),
+				})
+			}
+		}
+		frames[fp] = frame
+	}
+	var globals []Variable
+	for i, name := range vm.activeCode.GlobalNames() {
+		if i >= len(vm.activeCode.Globals) {
+			break
+		}
+		value := vm.activeCode.Globals[i]
+		if value == nil {
+			continue
+		}
+		if _, found := vm.inputGlobals[name]; found {
+			continue
+		}
+		globals = append(globals, Variable{Name: name, Value: value})
+	}
+	return &Stop{
+		Reason:   reason,
+		Location: loc,
+		Frames:   frames,
+		Globals:  globals,
+	}
+}
@@ -0,0 +1,183 @@
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/itrn0/risor/compiler"
+	"github.com/itrn0/risor/errz"
+	"github.com/itrn0/risor/object"
+)
+
+// StackFrame describes one active call in the Risor call stack.
+type StackFrame struct {
+	// Function is the name of the function, or the code name for frames that
+	// are not function calls, e.g. "__main__".
+	Function string
+
+	// Location is the position in the source that was executing in this frame.
+	Location compiler.SourceLocation
+}
+
+func (f StackFrame) String() string {
+	return fmt.Sprintf("%s (%s)", f.Function, f.Location)
+}
+
+// RuntimeError wraps an error that occurred during evaluation with the source
+// location where it occurred and the Risor call stack at that time. The error
+// message is that of the wrapped error. Errors are only wrapped by VMs created
+// with WithRuntimeErrors.
+type RuntimeError struct {
+	err   error
+	stack []StackFrame
+}
+
+func (e *RuntimeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.err
+}
+
+// IsFatal reports whether the wrapped error is fatal. This preserves the
+// semantics of the errz error types, so errors that do not implement
+// errz.Error are considered non-fatal.
+func (e *RuntimeError) IsFatal() bool {
+	var err errz.Error
+	if errors.As(e.err, &err) {
+		return err.IsFatal()
+	}
+	return false
+}
+
+// Location returns the source location where the error occurred.
+func (e *RuntimeError) Location() compiler.SourceLocation {
+	if len(e.stack) == 0 {
+		return compiler.SourceLocation{}
+	}
+	return e.stack[len(e.stack)-1].Location
+}
+
+// Stack returns the Risor call stack at the time of the error, ordered from
+// the outermost frame to the frame where the error occurred.
+func (e *RuntimeError) Stack() []StackFrame {
+	return e.stack
+}
+
+func (e *RuntimeError) FriendlyErrorMessage() string {
+	var msg bytes.Buffer
+	msg.WriteString(e.Error())
+	msg.WriteString("\n\n")
+	loc := e.Location()
+	friendlyLoc := fmt.Sprintf("line %d, column %d", loc.Line, loc.Column)
+	if loc.File != "" {
+		msg.WriteString(fmt.Sprintf("location: %s:%d:%d (%s)\n",
+			loc.File, loc.Line, loc.Column, friendlyLoc))
+	} else {
+		msg.WriteString(fmt.Sprintf("location: %s\n", friendlyLoc))
+	}
+	msg.WriteString("\nstack trace (most recent call last):")
+	for _, frame := range e.stack {
+		msg.WriteString(fmt.Sprintf("\n  %s", frame))
+	}
+	return msg.String()
+}
+
+// Transfers control to the innermost exception handler in the active frame
+// that accepts the error. The stack is unwound to where it was when the try
+// statement began and the error is pushed for the catch or finally block to
+// consume. False is returned if no handler accepts the error. Fatal errors
+// and errors caused by halting the VM are never handled.
+func (vm *VirtualMachine) handleError(err error) bool {
+	if atomic.LoadInt32(&vm.halt) == 1 {
+		return false
+	}
+	var errzErr errz.Error
+	if errors.As(err, &errzErr) && errzErr.IsFatal() {
+		return false
+	}
+	frame := vm.activeFrame
+	for len(frame.handlers) > 0 {
+		handler := frame.handlers[len(frame.handlers)-1]
+		entry := vm.activeCode.ExceptionHandler(handler.index)
+		var target int
+		if entry.Catch >= 0 && !handler.catching {
+			// The handler remains active while the catch block runs
+			frame.handlers[len(frame.handlers)-1].catching = true
+			target = entry.Catch
+		} else {
+			frame.handlers = frame.handlers[:len(frame.handlers)-1]
+			if entry.Finally < 0 {
+				continue
+			}
+			target = entry.Finally
+		}
+		// Capture where the error occurred in case it is raised again
+		vm.recordErrorStack(err)
+		for vm.sp > handler.sp {
+			vm.pop()
+		}
+		vm.push(object.NewError(err).WithRaised(false))
+		vm.ip = target
+		return true
+	}
+	return false
+}
+
+// Records the call stack for an error returned from eval. Since errors pass
+// through nested evals as they propagate, only the first (innermost) record
+// for a given error is kept.
+func (vm *VirtualMachine) recordErrorStack(err error) {
+	if vm.errStack != nil && errors.Is(err, vm.errStack.err) {
+		return
+	}
+	vm.errStack = &RuntimeError{err: err, stack: vm.stackTrace()}
+}
+
+// Wraps the error in a RuntimeError using the call stack recorded when the
+// error occurred, falling back to the current call stack. The error is
+// returned as is unless the VM was created with WithRuntimeErrors.
+func (vm *VirtualMachine) runtimeError(err error) error {
+	if !vm.runtimeErrors {
+		return err
+	}
+	var runtimeErr *RuntimeError
+	if errors.As(err, &runtimeErr) {
+		return err
+	}
+	if vm.errStack != nil && errors.Is(err, vm.errStack.err) {
+		return &RuntimeError{err: err, stack: vm.errStack.stack}
+	}
+	return &RuntimeError{err: err, stack: vm.stackTrace()}
+}
+
+// Returns the call stack of the active frames, from the outermost frame to
+// the active frame.
+func (vm *VirtualMachine) stackTrace() []StackFrame {
+	// The instruction pointer has already advanced past the opcode of the
+	// current instruction, so step back to land within it.
+	return vm.stackTraceAt(vm.ip - 1)
+}
+
+// Returns the call stack of the active frames, where ip is an offset within
+// the instruction executing in the active frame.
+func (vm *VirtualMachine) stackTraceAt(ip int) []StackFrame {
+	stack := make([]StackFrame, vm.fp+1)
+	for fp := vm.fp; fp >= 0; fp-- {
+		f := &vm.frames[fp]
+		stackFrame := StackFrame{Function: f.Name()}
+		if f.code != nil {
+			if loc, ok := f.code.LocationAt(ip); ok {
+				stackFrame.Location = loc
+			}
+		}
+		stack[fp] = stackFrame
+		// The caller addresses saved in each frame are past the call
+		// instruction, so step back to land within it
+		ip = f.callerIP - 1
+	}
+	return stack
+}
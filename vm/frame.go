@@ -8,6 +8,7 @@ const DefaultFrameLocals = 8
 
 type frame struct {
 	returnAddr     int
+	callerIP       int
 	returnSp       int
 	localsCount    uint16
 	fn             *object.Function
@@ -17,6 +18,25 @@ type frame struct {
 	extendedLocals []object.Object
 	capturedLocals []object.Object
 	defers         []*object.Partial
+	handlers       []exceptionHandler
+	generator      *generator
+	debugLine      int
+	debugIP        int
+	profileParent  *profileNode
+	profileNode    *profileNode
+}
+
+// An exception handler that was activated in a frame by a PushExcept
+// instruction and has not yet been popped.
+type exceptionHandler struct {
+	// Index of the handler in the exception handler table of the code
+	index int
+
+	// The stack pointer when the handler was activated
+	sp int
+
+	// Set once an error was routed to the catch block of the handler
+	catching bool
 }
 
 func (f *frame) ActivateCode(code *code) {
@@ -26,6 +46,12 @@ func (f *frame) ActivateCode(code *code) {
 	f.localsCount = uint16(code.LocalsCount())
 	f.capturedLocals = nil
 	f.defers = nil
+	f.handlers = f.handlers[:0]
+	f.generator = nil
+	f.debugLine = 0
+	f.debugIP = 0
+	f.profileParent = nil
+	f.profileNode = nil
 	for i := 0; i < DefaultFrameLocals; i++ {
 		f.storage[i] = nil
 	}
@@ -54,6 +80,40 @@ func (f *frame) ActivateFunction(fn *object.Function, code *code, returnAddr, re
 	} //lint:ignore S1001 - this loop is faster than using copy
 }
 
+// ActivateGenerator resumes a suspended generator in this frame. The frame
+// uses the generator's locals directly, so closures created in earlier steps
+// of the generator continue to share them.
+func (f *frame) ActivateGenerator(g *generator, code *code, returnSp int) {
+	f.ActivateCode(code)
+	f.fn = g.fn
+	f.generator = g
+	f.returnAddr = StopSignal
+	f.returnSp = returnSp
+	f.extendedLocals = nil
+	f.locals = g.locals
+	f.capturedLocals = g.locals
+	f.defers = g.defers
+	for _, handler := range g.handlers {
+		handler.sp += returnSp
+		f.handlers = append(f.handlers, handler)
+	}
+}
+
+// Name returns the name of the function running in this frame. Frames that
+// are not function calls are named after their code, e.g. "__main__".
+func (f *frame) Name() string {
+	if f.fn != nil {
+		if name := f.fn.Name(); name != "" {
+			return name
+		}
+		return "<anonymous>"
+	}
+	if f.code != nil {
+		return f.code.CodeName()
+	}
+	return ""
+}
+
 func (f *frame) Locals() []object.Object {
 	return f.locals
 }
@@ -0,0 +1,153 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/itrn0/risor/object"
+)
+
+// The suspended state of a call to a generator function. While the generator
+// isn't running, its frame is saved here instead of on the frame stack. Each
+// time the generator is resumed, the frame is restored on top of the frame
+// stack of the VM that resumes it, so no goroutine is needed per generator.
+type generator struct {
+	// The VM that created the generator, used when the generator is resumed
+	// from Go code rather than from a running VM
+	vm *VirtualMachine
+
+	fn     *object.Function
+	locals []object.Object
+
+	// The instruction to resume at and the contents of the frame's portion of
+	// the data stack, e.g. the iterators of active loops
+	ip    int
+	stack []object.Object
+
+	// Active exception handlers, with stack pointers relative to the base of
+	// the frame's portion of the data stack
+	handlers []exceptionHandler
+
+	defers []*object.Partial
+
+	// Set by a yield instruction to indicate the generator was suspended,
+	// rather than having returned
+	suspended bool
+}
+
+type vmContextKey struct{}
+
+// Creates a generator for a call to the given generator function. None of the
+// function body runs until the generator is first resumed.
+func (vm *VirtualMachine) newGenerator(fn *object.Function, args []object.Object) *object.Generator {
+	g := &generator{
+		vm:     vm,
+		fn:     fn,
+		locals: make([]object.Object, fn.Code().LocalsCount()),
+	}
+	initLocals(g.locals, fn, args)
+	return object.NewGenerator(fn, g.resume)
+}
+
+// Runs the generator until it yields a value or returns. It runs in the VM
+// found in the context, which is the VM iterating over the generator.
+func (g *generator) resume(ctx context.Context) (result object.Object, ok bool, err error) {
+	if vm, found := ctx.Value(vmContextKey{}).(*VirtualMachine); found {
+		return vm.resumeGenerator(ctx, g)
+	}
+	// The generator is being iterated from Go code, so resume it in the VM
+	// that created it, as with a call to vm.Call
+	vm := g.vm
+	if err := vm.start(ctx); err != nil {
+		return nil, false, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+		vm.stop()
+	}()
+	result, ok, err = vm.resumeGenerator(vm.initContext(ctx), g)
+	if err != nil {
+		return nil, false, vm.runtimeError(err)
+	}
+	return result, ok, nil
+}
+
+func (vm *VirtualMachine) resumeGenerator(
+	ctx context.Context,
+	g *generator,
+) (object.Object, bool, error) {
+	baseFP := vm.fp
+	baseIP := vm.ip
+	baseSP := vm.sp
+
+	// Restore the previous frame when done
+	defer vm.resumeFrame(baseFP, baseIP, baseSP)
+
+	// Restore the generator frame and its portion of the data stack
+	genFrame := vm.activateGenerator(vm.fp+1, g)
+	for _, obj := range g.stack {
+		vm.push(obj)
+	}
+	g.stack = nil
+	g.suspended = false
+
+	err := vm.eval(ctx)
+	if err == nil {
+		if g.suspended {
+			return vm.pop(), true, nil
+		}
+		// The generator function returned. The return value is discarded.
+		vm.pop()
+	}
+
+	// The generator is finished, so its deferred function calls run now
+	defers := genFrame.defers
+	g.finish()
+	for _, partial := range defers {
+		if deferErr := vm.callObject(ctx, partial.Function(), partial.Args()); deferErr != nil {
+			err = deferErr
+		} else {
+			vm.pop()
+		}
+	}
+	return nil, false, err
+}
+
+// Saves the state of the generator running in the given frame, so that it
+// can be resumed later.
+func (g *generator) suspend(vm *VirtualMachine, f *frame) {
+	g.ip = vm.ip
+	g.stack = make([]object.Object, vm.sp-f.returnSp)
+	copy(g.stack, vm.stack[f.returnSp+1:vm.sp+1])
+	g.handlers = g.handlers[:0]
+	for _, handler := range f.handlers {
+		handler.sp -= f.returnSp
+		g.handlers = append(g.handlers, handler)
+	}
+	g.defers = f.defers
+	g.suspended = true
+}
+
+// Releases the state of a generator that has finished.
+func (g *generator) finish() {
+	g.locals = nil
+	g.stack = nil
+	g.handlers = nil
+	g.defers = nil
+}
+
+// Activate a frame to resume the given generator.
+func (vm *VirtualMachine) activateGenerator(fp int, g *generator) *frame {
+	code := vm.loadCode(g.fn.Code())
+	callerIP := vm.ip
+	returnSp := vm.sp
+	vm.fp = fp
+	vm.ip = g.ip
+	vm.activeFrame = &vm.frames[fp]
+	vm.activeFrame.ActivateGenerator(g, code, returnSp)
+	vm.activeFrame.callerIP = callerIP
+	vm.activeCode = code
+	return vm.activeFrame
+}
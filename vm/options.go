@@ -1,7 +1,10 @@
 package vm
 
 import (
+	"log/slog"
+
 	"github.com/itrn0/risor/importer"
+	"github.com/itrn0/risor/limits"
 )
 
 // Option is a configuration function for a Virtual Machine.
@@ -14,6 +17,15 @@ func WithInstructionOffset(offset int) Option {
 	}
 }
 
+// WithRuntimeErrors causes errors returned by Run and Call to be wrapped in a
+// RuntimeError, which reports where the error occurred. Callers then need
+// errors.Is or errors.As to inspect the original error.
+func WithRuntimeErrors() Option {
+	return func(vm *VirtualMachine) {
+		vm.runtimeErrors = true
+	}
+}
+
 // WithImporter is used to supply an Importer to the Virtual Machine.
 func WithImporter(importer importer.Importer) Option {
 	return func(vm *VirtualMachine) {
@@ -36,3 +48,87 @@ func WithConcurrency() Option {
 		vm.concAllowed = true
 	}
 }
+
+// WithMaxMemoryUsage sets the maximum number of bytes that may be held on the
+// stack. Use limits.NoLimit to remove the limit.
+func WithMaxMemoryUsage(size int64) Option {
+	return func(vm *VirtualMachine) {
+		vm.maxMemory = size
+	}
+}
+
+// WithMaxInstructions sets the maximum number of instructions that may be
+// executed. Use limits.NoLimit to remove the limit. This is ignored if a
+// budget is supplied via WithBudget.
+func WithMaxInstructions(count int64) Option {
+	return func(vm *VirtualMachine) {
+		vm.maxInstructions = count
+	}
+}
+
+// WithBudget supplies the instruction budget the VM draws from. The same
+// Budget may be given to multiple VMs to enforce a combined limit.
+func WithBudget(budget *Budget) Option {
+	return func(vm *VirtualMachine) {
+		vm.budget = budget
+	}
+}
+
+// WithCloneBudget determines whether the clones used by spawn and go share
+// this VM's instruction budget or inherit a copy of it.
+func WithCloneBudget(mode CloneBudget) Option {
+	return func(vm *VirtualMachine) {
+		vm.cloneBudget = mode
+	}
+}
+
+// WithLimits makes the given Limits available to builtins through the
+// evaluation context. If it implements limits.VMLimits, the memory and
+// instruction limits it sets replace the VM defaults.
+func WithLimits(l limits.Limits) Option {
+	return func(vm *VirtualMachine) {
+		vm.limits = l
+		vmLimits, ok := l.(limits.VMLimits)
+		if !ok {
+			return
+		}
+		if size, ok := vmLimits.MaxMemoryUsage(); ok {
+			vm.maxMemory = size
+		}
+		if count, ok := vmLimits.MaxInstructions(); ok {
+			vm.maxInstructions = count
+		}
+	}
+}
+
+// WithDebugger attaches a Debugger that may stop execution at breakpoints and
+// while stepping through code.
+func WithDebugger(debugger *Debugger) Option {
+	return func(vm *VirtualMachine) {
+		vm.debugger = debugger
+	}
+}
+
+// WithProfiler attaches a Profiler that records the work done by the VM. The
+// Profiler is shared with the clones used by spawn and go.
+func WithProfiler(profiler *Profiler) Option {
+	return func(vm *VirtualMachine) {
+		vm.profiler = profiler
+	}
+}
+
+// WithCoverage attaches a Coverage that records the source lines executed by
+// the VM. The Coverage is shared with the clones used by spawn and go.
+func WithCoverage(coverage *Coverage) Option {
+	return func(vm *VirtualMachine) {
+		vm.coverage = coverage
+	}
+}
+
+// WithLogger sets the logger used for the VM's own events and made available
+// to builtins, such as the log module, through the evaluation context.
+func WithLogger(logger *slog.Logger) Option {
+	return func(vm *VirtualMachine) {
+		vm.logger = logger
+	}
+}
@@ -0,0 +1,167 @@
+package vm
+
+import (
+	"compress/gzip"
+	"io"
+	"time"
+)
+
+// WriteProfile writes the recorded samples to w in the gzip-compressed
+// protocol buffer format read by pprof, e.g. "go tool pprof". Each sample has
+// two values: the number of instructions executed and the sampled wall time
+// in nanoseconds. Source lines within Risor functions serve as locations.
+func (p *Profiler) WriteProfile(w io.Writer) error {
+	samples := p.Samples()
+	duration := p.Duration()
+
+	b := &protobuf{}
+	stringIndex := map[string]int{"": 0}
+	stringTable := []string{""}
+	str := func(s string) uint64 {
+		if i, ok := stringIndex[s]; ok {
+			return uint64(i)
+		}
+		stringIndex[s] = len(stringTable)
+		stringTable = append(stringTable, s)
+		return uint64(len(stringTable) - 1)
+	}
+
+	// Profile.sample_type
+	for _, st := range [][2]string{{"instructions", "count"}, {"wall", "nanoseconds"}} {
+		typ, unit := str(st[0]), str(st[1])
+		b.message(1, func(b *protobuf) {
+			b.uint64(1, typ)
+			b.uint64(2, unit)
+		})
+	}
+
+	// Locations are lines within functions, which are identified by their
+	// name and file
+	type functionKey struct{ name, file string }
+	type locationKey struct {
+		function uint64
+		line     int
+	}
+	functions := map[functionKey]uint64{}
+	var functionOrder []functionKey
+	locations := map[locationKey]uint64{}
+	var locationOrder []locationKey
+	for _, sample := range samples {
+		ids := make([]uint64, 0, len(sample.Stack))
+		// Stacks in pprof begin with the innermost frame
+		for i := len(sample.Stack) - 1; i >= 0; i-- {
+			frame := sample.Stack[i]
+			fk := functionKey{frame.Function, frame.Location.File}
+			fid, ok := functions[fk]
+			if !ok {
+				fid = uint64(len(functionOrder) + 1)
+				functions[fk] = fid
+				functionOrder = append(functionOrder, fk)
+			}
+			lk := locationKey{fid, frame.Location.Line}
+			lid, ok := locations[lk]
+			if !ok {
+				lid = uint64(len(locationOrder) + 1)
+				locations[lk] = lid
+				locationOrder = append(locationOrder, lk)
+			}
+			ids = append(ids, lid)
+		}
+		// Profile.sample
+		values := []uint64{uint64(sample.Instructions), uint64(sample.Duration.Nanoseconds())}
+		b.message(2, func(b *protobuf) {
+			b.packed(1, ids)
+			b.packed(2, values)
+		})
+	}
+	// Profile.location
+	for i, lk := range locationOrder {
+		b.message(4, func(b *protobuf) {
+			b.uint64(1, uint64(i+1))
+			b.message(4, func(b *protobuf) {
+				b.uint64(1, lk.function)
+				b.uint64(2, uint64(lk.line))
+			})
+		})
+	}
+	// Profile.function
+	for i, fk := range functionOrder {
+		name, file := str(fk.name), str(fk.file)
+		b.message(5, func(b *protobuf) {
+			b.uint64(1, uint64(i+1))
+			b.uint64(2, name)
+			b.uint64(3, name)
+			b.uint64(4, file)
+		})
+	}
+	periodType, periodUnit := str("wall"), str("nanoseconds")
+	// Profile.string_table
+	for _, s := range stringTable {
+		b.string(6, s)
+	}
+	// Profile.time_nanos, duration_nanos, period_type and period
+	b.uint64(9, uint64(time.Now().Add(-duration).UnixNano()))
+	b.uint64(10, uint64(duration.Nanoseconds()))
+	b.message(11, func(b *protobuf) {
+		b.uint64(1, periodType)
+		b.uint64(2, periodUnit)
+	})
+	b.uint64(12, uint64(p.interval.Nanoseconds()))
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b.data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// A minimal protocol buffer encoder, covering what the pprof format needs
+type protobuf struct {
+	data []byte
+}
+
+func (b *protobuf) varint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+func (b *protobuf) key(tag int, wireType int) {
+	b.varint(uint64(tag)<<3 | uint64(wireType))
+}
+
+func (b *protobuf) uint64(tag int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.key(tag, 0)
+	b.varint(x)
+}
+
+func (b *protobuf) bytes(tag int, data []byte) {
+	b.key(tag, 2)
+	b.varint(uint64(len(data)))
+	b.data = append(b.data, data...)
+}
+
+// Unlike other fields, strings are written even if empty, since the string
+// table must begin with the empty string.
+func (b *protobuf) string(tag int, s string) {
+	b.bytes(tag, []byte(s))
+}
+
+func (b *protobuf) packed(tag int, values []uint64) {
+	inner := &protobuf{}
+	for _, x := range values {
+		inner.varint(x)
+	}
+	b.bytes(tag, inner.data)
+}
+
+func (b *protobuf) message(tag int, fn func(b *protobuf)) {
+	inner := &protobuf{}
+	fn(inner)
+	b.bytes(tag, inner.data)
+}
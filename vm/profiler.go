@@ -0,0 +1,241 @@
+package vm
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/itrn0/risor/compiler"
+)
+
+// DefaultProfileInterval is the interval at which a Profiler samples wall time
+// unless configured otherwise.
+const DefaultProfileInterval = 10 * time.Millisecond
+
+// ProfileSample aggregates the work done at one point in the code, when it was
+// reached through one particular sequence of calls.
+type ProfileSample struct {
+	// Stack is the call stack, from the outermost frame to the frame where the
+	// work was done. The location of each frame is the line that was running.
+	Stack []StackFrame
+
+	// Instructions is the number of instructions executed.
+	Instructions int64
+
+	// Duration is the wall time attributed by sampling.
+	Duration time.Duration
+}
+
+// Profiler attributes the work done by a VM to Risor functions and source
+// lines. Every instruction executed is counted, while wall time is sampled
+// at a fixed interval. A Profiler may be shared by several VMs, including
+// the clones used by spawn and go.
+type Profiler struct {
+	interval time.Duration
+	mu       sync.Mutex
+	root     *profileNode
+	active   int
+	started  time.Time
+	elapsed  time.Duration
+	sampled  time.Time
+	ticker   *time.Ticker
+	done     chan struct{}
+	tick     int32
+}
+
+// Identifies a node in the call tree: a line within a function
+type profileKey struct {
+	function string
+	file     string
+	line     int
+}
+
+type profileNode struct {
+	key          profileKey
+	parent       *profileNode
+	children     map[profileKey]*profileNode
+	instructions int64
+	duration     time.Duration
+}
+
+func (n *profileNode) child(key profileKey) *profileNode {
+	if c, ok := n.children[key]; ok {
+		return c
+	}
+	if n.children == nil {
+		n.children = map[profileKey]*profileNode{}
+	}
+	c := &profileNode{key: key, parent: n}
+	n.children[key] = c
+	return c
+}
+
+// NewProfiler returns a Profiler that samples wall time at the given interval.
+// DefaultProfileInterval is used if the interval is not positive.
+func NewProfiler(interval time.Duration) *Profiler {
+	if interval <= 0 {
+		interval = DefaultProfileInterval
+	}
+	return &Profiler{interval: interval, root: &profileNode{}}
+}
+
+// Interval returns the wall time sampling interval.
+func (p *Profiler) Interval() time.Duration {
+	return p.interval
+}
+
+// Duration returns the total time that VMs using the Profiler were running.
+func (p *Profiler) Duration() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.active > 0 {
+		return p.elapsed + time.Since(p.started)
+	}
+	return p.elapsed
+}
+
+// Called when a VM using the Profiler starts running. The sampling clock
+// runs while at least one VM is running.
+func (p *Profiler) start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.active++
+	if p.active > 1 {
+		return
+	}
+	p.started = time.Now()
+	p.sampled = p.started
+	p.ticker = time.NewTicker(p.interval)
+	p.done = make(chan struct{})
+	go func(ticker *time.Ticker, done chan struct{}) {
+		for {
+			select {
+			case <-ticker.C:
+				atomic.StoreInt32(&p.tick, 1)
+			case <-done:
+				return
+			}
+		}
+	}(p.ticker, p.done)
+}
+
+// Called when a VM using the Profiler stops running. The time since the last
+// sample is attributed to the last instruction the VM ran.
+func (p *Profiler) stop(vm *VirtualMachine) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if vm.profileLast != nil {
+		now := time.Now()
+		vm.profileLast.duration += now.Sub(p.sampled)
+		p.sampled = now
+	}
+	p.active--
+	if p.active > 0 {
+		return
+	}
+	p.ticker.Stop()
+	close(p.done)
+	p.elapsed += time.Since(p.started)
+}
+
+// Called before each instruction is executed. Counts the instruction and, if
+// the sampling clock has ticked, attributes the time since the last sample to
+// the instruction that was running when it did.
+func (p *Profiler) record(vm *VirtualMachine) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if atomic.CompareAndSwapInt32(&p.tick, 1, 0) {
+		now := time.Now()
+		last := vm.profileLast
+		if last == nil {
+			last = p.node(vm)
+		}
+		last.duration += now.Sub(p.sampled)
+		p.sampled = now
+	}
+	node := p.node(vm)
+	node.instructions++
+	vm.profileLast = node
+}
+
+// Returns the node in the call tree for the instruction about to run.
+func (p *Profiler) node(vm *VirtualMachine) *profileNode {
+	return p.frameNode(vm, vm.fp, vm.ip)
+}
+
+// Returns the node for the given frame when running the instruction at ip.
+// Callers don't move while a frame is active, so the node of the caller is
+// saved in the frame rather than found for every instruction.
+func (p *Profiler) frameNode(vm *VirtualMachine, fp, ip int) *profileNode {
+	f := &vm.frames[fp]
+	parent := f.profileParent
+	if parent == nil {
+		if fp == 0 {
+			parent = p.root
+		} else {
+			// The caller addresses saved in each frame are past the call
+			// instruction, so step back to land within it
+			parent = p.frameNode(vm, fp-1, f.callerIP-1)
+		}
+		f.profileParent = parent
+	}
+	var location compiler.SourceLocation
+	if f.code != nil {
+		location, _ = f.code.LocationAt(ip)
+	}
+	// Most instructions run on the same line as the one before them
+	if node := f.profileNode; node != nil && node.key.line == location.Line &&
+		node.key.file == location.File {
+		return node
+	}
+	node := parent.child(profileKey{
+		function: f.Name(),
+		file:     location.File,
+		line:     location.Line,
+	})
+	f.profileNode = node
+	return node
+}
+
+// Samples returns the work recorded so far, ordered by call stack.
+func (p *Profiler) Samples() []ProfileSample {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var samples []ProfileSample
+	var visit func(n *profileNode, stack []StackFrame)
+	visit = func(n *profileNode, stack []StackFrame) {
+		if n != p.root {
+			stack = append(stack, StackFrame{
+				Function: n.key.function,
+				Location: compiler.SourceLocation{File: n.key.file, Line: n.key.line},
+			})
+			if n.instructions > 0 || n.duration > 0 {
+				samples = append(samples, ProfileSample{
+					Stack:        append([]StackFrame(nil), stack...),
+					Instructions: n.instructions,
+					Duration:     n.duration,
+				})
+			}
+		}
+		keys := make([]profileKey, 0, len(n.children))
+		for key := range n.children {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			a, b := keys[i], keys[j]
+			if a.function != b.function {
+				return a.function < b.function
+			}
+			if a.file != b.file {
+				return a.file < b.file
+			}
+			return a.line < b.line
+		})
+		for _, key := range keys {
+			visit(n.children[key], stack)
+		}
+	}
+	visit(p.root, nil)
+	return samples
+}
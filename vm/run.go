@@ -34,7 +34,9 @@ func Run(ctx context.Context, main *compiler.Code, options ...Option) (object.Ob
 }
 
 type runOpts struct {
-	Globals map[string]interface{}
+	Globals         map[string]interface{}
+	Options         []Option
+	CompilerOptions []compiler.Option
 }
 
 // Run the given source code in a new VM. Used for testing.
@@ -59,16 +61,21 @@ func newVM(ctx context.Context, source string, opts ...runOpts) (*VirtualMachine
 		return nil, err
 	}
 	globals := basicBuiltins()
+	var vmOpts []Option
+	var compilerOpts []compiler.Option
 	if len(opts) > 0 {
 		for k, v := range opts[0].Globals {
 			globals[k] = v
 		}
+		vmOpts = opts[0].Options
+		compilerOpts = opts[0].CompilerOptions
 	}
 	var globalNames []string
 	for k := range globals {
 		globalNames = append(globalNames, k)
 	}
-	main, err := compiler.Compile(ast, compiler.WithGlobalNames(globalNames))
+	compilerOpts = append(compilerOpts, compiler.WithGlobalNames(globalNames))
+	main, err := compiler.Compile(ast, compilerOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -77,7 +84,8 @@ func newVM(ctx context.Context, source string, opts ...runOpts) (*VirtualMachine
 		Extensions:  []string{".risor", ".rsr"},
 		GlobalNames: globalNames,
 	})
-	return New(main, WithImporter(im), WithGlobals(globals), WithConcurrency()), nil
+	vmOpts = append([]Option{WithImporter(im), WithGlobals(globals), WithConcurrency()}, vmOpts...)
+	return New(main, vmOpts...), nil
 }
 
 // Builtins to be used in VM tests.
@@ -2,6 +2,7 @@ package vm
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/itrn0/risor/compiler"
@@ -45,4 +46,10 @@ func TestRunError(t *testing.T) {
 	errValue, ok := err.(*errz.TypeError)
 	require.True(t, ok)
 	require.Equal(t, "type error: attribute \"bar\" not found on int object", errValue.Error())
+
+	_, err = Run(ctx, code, WithRuntimeErrors())
+	require.NotNil(t, err)
+	var runtimeErr *RuntimeError
+	require.True(t, errors.As(err, &runtimeErr))
+	require.Equal(t, compiler.SourceLocation{Line: 1, Column: 15}, runtimeErr.Location())
 }
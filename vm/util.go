@@ -32,6 +32,10 @@ const (
 	ObjectMapIterSize    = int(unsafe.Sizeof(object.MapIter{}))
 	ObjectSetIterSize    = int(unsafe.Sizeof(object.SetIter{}))
 	ObjectSliceIterSize  = int(unsafe.Sizeof(object.SliceIter{}))
+	ObjectGeneratorSize  = int(unsafe.Sizeof(object.Generator{}))
+	ObjectReferenceSize  = int(unsafe.Sizeof(object.Object(nil)))
+	ObjectStructSize     = int(unsafe.Sizeof(object.Struct{}))
+	ObjectStructTypeSize = int(unsafe.Sizeof(object.StructType{}))
 
 	StringSize = int(unsafe.Sizeof(""))
 	ArraySize  = int(unsafe.Sizeof([]any{}))
@@ -39,6 +43,27 @@ const (
 	PtrSize    = int(unsafe.Sizeof(unsafe.Pointer(nil)))
 )
 
+// Assembles the initial local variables for a call to the given function,
+// returning the number of locals set. The local variable order is:
+// 1. Function parameters, using defaults for any arguments not given
+// 2. Function name (if the function is named)
+func initLocals(locals []object.Object, fn *object.Function, args []object.Object) int {
+	paramsCount := len(fn.Parameters())
+	count := copy(locals, args)
+	if count < paramsCount {
+		defaults := fn.Defaults()
+		for i := count; i < len(defaults); i++ {
+			locals[i] = defaults[i]
+		}
+		count = paramsCount
+	}
+	if fn.Code().IsNamed() {
+		locals[paramsCount] = fn
+		count++
+	}
+	return count
+}
+
 func checkCallArgs(fn *object.Function, argc int) error {
 	// Number of parameters in the function signature
 	paramsCount := len(fn.Parameters())
@@ -104,6 +129,23 @@ func varSize(value any) (int, error) {
 			size += subSize
 		}
 		return ObjectMapSize + size, nil
+	case *object.Struct:
+		var size int
+		for _, name := range val.StructType().Fields() {
+			v, _ := val.GetAttr(name)
+			subSize, err := varSize(v)
+			if err != nil {
+				return 0, err
+			}
+			size += subSize
+		}
+		return ObjectStructSize + size, nil
+	case *object.StructType:
+		size := len(val.Name())
+		for _, name := range val.Fields() {
+			size += StringSize + len(name)
+		}
+		return ObjectStructTypeSize + size, nil
 	case *object.Partial:
 		size, err := varSize(val.Args())
 		if err != nil {
@@ -116,6 +158,12 @@ func varSize(value any) (int, error) {
 			size += ObjectStringSize + len(val.Value().Error())
 		}
 		return ObjectErrorSize + size, nil
+	case *object.Error:
+		var size int
+		if val.Value() != nil {
+			size += ObjectStringSize + len(val.Value().Error())
+		}
+		return ObjectErrorSize + size, nil
 	case *object.Builtin:
 		return ObjectBuiltinSize + len(val.Name()), nil
 	case *object.Module:
@@ -165,6 +213,13 @@ func varSize(value any) (int, error) {
 		return ObjectSetIterSize, nil
 	case *object.SliceIter:
 		return ObjectSliceIterSize, nil
+	case *object.Generator:
+		return ObjectGeneratorSize, nil
+	case object.Object:
+		// Objects defined by modules, such as iterators, sync primitives and
+		// test handles, keep their state private, so only the reference to
+		// them is counted
+		return ObjectReferenceSize + PtrSize, nil
 	default:
 		// Для остальных типов fallback на рефлексию
 		slog.Info(
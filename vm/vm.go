@@ -14,6 +14,7 @@ import (
 	"github.com/itrn0/risor/compiler"
 	"github.com/itrn0/risor/errz"
 	"github.com/itrn0/risor/importer"
+	"github.com/itrn0/risor/limits"
 	"github.com/itrn0/risor/object"
 	"github.com/itrn0/risor/op"
 )
@@ -24,8 +25,8 @@ const (
 	MaxStackDepth   = 256 // 1024
 	StopSignal      = -1
 	MB              = 1024 * 1024
-	MaxMemoryUsage  = 1 * MB    // Maximum memory volume that can be used
-	MaxInstructions = 1_000_000 // The maximum number of executable instructions
+	MaxMemoryUsage  = 1 * MB    // Default maximum memory volume that can be used
+	MaxInstructions = 1_000_000 // Default maximum number of executable instructions
 )
 
 /* ------------------------- */
@@ -48,9 +49,9 @@ func (vm *VirtualMachine) push(obj object.Object) {
 		atomic.StoreInt32(&vm.halt, 1)
 		return
 	}
-	if vm.memoryUsage+size > MaxMemoryUsage {
+	if vm.maxMemory > limits.NoLimit && int64(vm.memoryUsage+size) > vm.maxMemory {
 		vm.limitErr = fmt.Errorf("memory limit exceeded")
-		slog.Warn(fmt.Sprintf(">>> memory limit exceeded (usage: %d, limit: %d)", vm.memoryUsage+size, MaxMemoryUsage))
+		vm.log().Warn(fmt.Sprintf(">>> memory limit exceeded (usage: %d, limit: %d)", vm.memoryUsage+size, vm.maxMemory))
 		atomic.StoreInt32(&vm.halt, 1)
 		return
 	}
@@ -109,30 +110,48 @@ type VirtualMachine struct {
 	stack        [MaxStackDepth]object.Object
 	frames       [MaxFrameDepth]frame
 	//
-	stackElSize    [MaxStackDepth]int
-	memoryUsage    int
-	instructions   int64
-	limitErr       error
-	maxMemoryUsage int
+	stackElSize     [MaxStackDepth]int
+	memoryUsage     int
+	maxMemory       int64
+	maxInstructions int64
+	budget          *Budget
+	cloneBudget     CloneBudget
+	limits          limits.Limits
+	limitErr        error
+	maxMemoryUsage  int
+	errStack        *RuntimeError
+	runtimeErrors   bool
+	debugger        *Debugger
+	profiler        *Profiler
+	profileLast     *profileNode
+	coverage        *Coverage
+	logger          *slog.Logger
+	// Line counters for the code the coverage was last recorded in
+	coverageCode     *compiler.Code
+	coverageCounters []*coverageLine
 }
 
 // New creates a new Virtual Machine.
 func New(main *compiler.Code, options ...Option) *VirtualMachine {
 	vm := &VirtualMachine{
-		sp:           -1,
-		ip:           0,
-		instructions: 0,
-		fp:           0,
-		halt:         0,
-		main:         main,
-		modules:      map[string]*object.Module{},
-		inputGlobals: map[string]any{},
-		globals:      map[string]object.Object{},
-		loadedCode:   map[*compiler.Code]*code{},
+		sp:              -1,
+		ip:              0,
+		fp:              0,
+		halt:            0,
+		main:            main,
+		modules:         map[string]*object.Module{},
+		inputGlobals:    map[string]any{},
+		globals:         map[string]object.Object{},
+		loadedCode:      map[*compiler.Code]*code{},
+		maxMemory:       MaxMemoryUsage,
+		maxInstructions: MaxInstructions,
 	}
 	for _, opt := range options {
 		opt(vm)
 	}
+	if vm.budget == nil {
+		vm.budget = NewBudget(vm.maxInstructions)
+	}
 	// Convert globals to Risor objects
 	var err error
 	vm.globals, err = object.AsObjects(vm.inputGlobals)
@@ -158,6 +177,8 @@ func (vm *VirtualMachine) start(ctx context.Context) error {
 	vm.running = true
 	// Halt execution when the context is cancelled
 	vm.halt = 0
+	vm.limitErr = nil
+	vm.errStack = nil
 	if doneChan := ctx.Done(); doneChan != nil {
 		go func() {
 			<-doneChan
@@ -205,8 +226,16 @@ func (vm *VirtualMachine) Run(ctx context.Context) (err error) {
 	// Activate the entrypoint code in frame zero
 	vm.activateCode(0, vm.ip, main)
 
+	if vm.profiler != nil {
+		vm.profiler.start()
+		defer vm.profiler.stop(vm)
+	}
+
 	// Run the entrypoint until completion
-	return vm.eval(vm.initContext(ctx))
+	if err := vm.eval(vm.initContext(ctx)); err != nil {
+		return vm.runtimeError(err)
+	}
+	return nil
 }
 
 // Get a global variable by name as a Risor Object.
@@ -245,12 +274,42 @@ func (vm *VirtualMachine) GlobalNames() []string {
 //   - vm.activeFrame - the active call frame to use
 //
 // Assuming this function returns without error, the result of the evaluation
-// will be on the top of the stack.
+// will be on the top of the stack. Errors raised within try statements are
+// handled here. If an error is returned, the call stack at the point of
+// failure is recorded so that Run and Call can report it.
 func (vm *VirtualMachine) eval(ctx context.Context) error {
+	for {
+		err := vm.dispatch(ctx)
+		if err == nil {
+			return nil
+		}
+		// Resume at an exception handler in the active frame if there is one.
+		// Otherwise the error propagates to the caller.
+		if vm.handleError(err) {
+			continue
+		}
+		vm.recordErrorStack(err)
+		return err
+	}
+}
+
+// Execute instructions until the end of the active code is reached, a return
+// to a StopSignal address occurs, or an error is encountered.
+func (vm *VirtualMachine) dispatch(ctx context.Context) error {
 	// Run to the end of the active code
 	// vm.instructions = 0
 	for vm.ip < len(vm.activeCode.Instructions) {
 
+		if vm.debugger != nil {
+			vm.debugger.check(ctx, vm)
+		}
+		if vm.profiler != nil {
+			vm.profiler.record(vm)
+		}
+		if vm.coverage != nil {
+			vm.coverage.record(vm)
+		}
+
 		if atomic.LoadInt32(&vm.halt) == 1 {
 			if vm.limitErr != nil {
 				return vm.limitErr
@@ -391,6 +450,18 @@ func (vm *VirtualMachine) eval(ctx context.Context) error {
 			obj := vm.pop()
 			partial := object.NewPartial(obj, args)
 			vm.push(partial)
+		case op.Yield:
+			// Suspend the generator and return to the eval call that resumed
+			// it, with the yielded value on the top of the stack
+			activeFrame := vm.activeFrame
+			if activeFrame.generator == nil {
+				return errz.EvalErrorf("eval error: yield outside of a generator")
+			}
+			value := vm.pop()
+			activeFrame.generator.suspend(vm, activeFrame)
+			vm.push(value)
+			vm.resumeFrame(vm.fp-1, StopSignal, activeFrame.returnSp)
+			return nil
 		case op.ReturnValue:
 			activeFrame := vm.activeFrame
 			returnAddr := activeFrame.returnAddr
@@ -445,6 +516,16 @@ func (vm *VirtualMachine) eval(ctx context.Context) error {
 				items[i] = vm.pop()
 			}
 			vm.push(object.NewSet(items))
+		case op.BuildStruct:
+			count := vm.fetch()
+			fields := make([]string, count)
+			defaults := make([]object.Object, count)
+			for i := int(count) - 1; i >= 0; i-- {
+				defaults[i] = vm.pop()
+				fields[i] = vm.pop().(*object.String).Value()
+			}
+			name := vm.pop().(*object.String).Value()
+			vm.push(object.NewStructType(name, fields, defaults))
 		case op.BinarySubscr:
 			idx := vm.pop()
 			lhs := vm.pop()
@@ -551,6 +632,14 @@ func (vm *VirtualMachine) eval(ctx context.Context) error {
 					containerObj.Type())
 			}
 			vm.push(container.Len())
+		case op.MatchType:
+			nameObj := vm.pop()
+			name, ok := nameObj.(*object.String)
+			if !ok {
+				return errz.TypeErrorf("type error: type name is not a string (got %s)", nameObj.Type())
+			}
+			obj := vm.pop()
+			vm.push(object.NewBool(string(obj.Type()) == name.Value()))
 		case op.Copy:
 			offset := vm.fetch()
 			vm.push(vm.stack[vm.sp-int(offset)])
@@ -643,12 +732,18 @@ func (vm *VirtualMachine) eval(ctx context.Context) error {
 			nameCount := vm.fetch()
 			iter := vm.pop().(object.Iterator)
 			if _, ok := iter.Next(ctx); !ok {
+				// Iterators such as generators may stop because of an error
+				if iter, ok := iter.(object.FallibleIterator); ok {
+					if err := iter.Err(); err != nil {
+						return err
+					}
+				}
 				vm.ip = base + int(jumpAmount)
 			} else {
 				obj, _ := iter.Entry()
 				vm.push(iter)
 				if nameCount == 1 {
-					vm.push(obj.Key())
+					vm.push(obj.Primary())
 				} else if nameCount == 2 {
 					vm.push(obj.Value())
 					vm.push(obj.Key())
@@ -693,19 +788,64 @@ func (vm *VirtualMachine) eval(ctx context.Context) error {
 				return err
 			}
 			vm.push(value)
+		case op.Select:
+			count := int(vm.fetch())
+			hasDefault := vm.fetch() == 1
+			// Each case is a channel, then a value if it's a send, then a flag
+			// indicating whether it's a send
+			cases := make([]object.SelectCase, count)
+			for i := count - 1; i >= 0; i-- {
+				if vm.pop() == object.True {
+					cases[i].IsSend = true
+					cases[i].Value = vm.pop()
+				}
+				switch channel := vm.pop().(type) {
+				case *object.Chan:
+					cases[i].Chan = channel
+				case *object.NilType:
+					// A nil channel disables the case
+				default:
+					return errz.TypeErrorf("type error: object is not a channel (got %s)", channel.Type())
+				}
+			}
+			index, value, ok, err := object.Select(ctx, cases, hasDefault)
+			if err != nil {
+				return err
+			}
+			vm.push(value)
+			vm.push(object.NewBool(ok))
+			vm.push(object.NewInt(int64(index)))
+		case op.PushExcept:
+			index := int(vm.fetch())
+			vm.activeFrame.handlers = append(vm.activeFrame.handlers,
+				exceptionHandler{index: index, sp: vm.sp})
+		case op.PopExcept:
+			handlers := vm.activeFrame.handlers
+			if len(handlers) == 0 {
+				return errz.EvalErrorf("eval error: no active exception handler")
+			}
+			vm.activeFrame.handlers = handlers[:len(handlers)-1]
+		case op.EndFinally:
+			// Re-raise the error that was pending when the finally block began
+			if err, ok := vm.pop().(*object.Error); ok {
+				return err.Value()
+			}
 		case op.Halt:
 			return nil
 		default:
 			return errz.EvalErrorf("eval error: unknown opcode: %d", opcode)
 		}
 
-		atomic.AddInt64(&vm.instructions, 1)
-		if vm.instructions >= MaxInstructions {
+		if !vm.budget.Consume() {
 			return errz.EvalErrorf("eval error: max instructions limit of %d exceeded",
-				MaxInstructions)
+				vm.budget.Limit())
 		}
 	}
 
+	// A limit may have been hit by the final instruction
+	if vm.limitErr != nil {
+		return vm.limitErr
+	}
 	// slog.Info("vm.eval: done", "max_mem", vm.maxMemoryUsage)
 	return nil
 }
@@ -727,6 +867,14 @@ func (vm *VirtualMachine) SetIP(value int) error {
 	return nil
 }
 
+// Returns the logger for the VM's own events.
+func (vm *VirtualMachine) log() *slog.Logger {
+	if vm.logger != nil {
+		return vm.logger
+	}
+	return slog.Default()
+}
+
 // TOS returns the top-of-stack object if there is one, without modifying the
 // stack. The returned bool value indicates whether there was a valid TOS. This
 // only works on a stopped VM. If the VM is running, (nil, false) is returned.
@@ -757,7 +905,11 @@ func (vm *VirtualMachine) Call(
 		}
 		vm.stop()
 	}()
-	return vm.callFunction(vm.initContext(ctx), fn, args)
+	result, err = vm.callFunction(vm.initContext(ctx), fn, args)
+	if err != nil {
+		return nil, vm.runtimeError(err)
+	}
+	return result, nil
 }
 
 // Calls a compiled function with the given arguments. This is used internally
@@ -768,9 +920,7 @@ func (vm *VirtualMachine) callFunction(
 	args []object.Object,
 ) (result object.Object, resultErr error) {
 	// Check that the argument count is appropriate
-	paramsCount := len(fn.Parameters())
 	argc := len(args)
-
 	if argc > MaxArgs {
 		return nil, errz.EvalErrorf("eval error: max args limit of %d exceeded (got %d)",
 			MaxArgs, argc)
@@ -779,6 +929,12 @@ func (vm *VirtualMachine) callFunction(
 		return nil, err
 	}
 
+	// Calling a generator function creates a generator, which runs the
+	// function body as values are requested from it
+	if fn.Code().IsGenerator() {
+		return vm.newGenerator(fn, args), nil
+	}
+
 	baseFP := vm.fp
 	baseIP := vm.ip
 	baseSP := vm.sp
@@ -786,25 +942,11 @@ func (vm *VirtualMachine) callFunction(
 	// Restore the previous frame when done
 	defer vm.resumeFrame(baseFP, baseIP, baseSP)
 
-	// Assemble frame local variables in vm.tmp. The local variable order is:
-	// 1. Function parameters
-	// 2. Function name (if the function is named)
-	copy(vm.tmp[:argc], args)
-	if argc < paramsCount {
-		defaults := fn.Defaults()
-		for i := argc; i < len(defaults); i++ {
-			vm.tmp[i] = defaults[i]
-		}
-		argc = paramsCount
-	}
-	code := fn.Code()
-	if code.IsNamed() {
-		vm.tmp[paramsCount] = fn
-		argc++
-	}
+	// Assemble frame local variables in vm.tmp
+	localsCount := initLocals(vm.tmp[:], fn, args)
 
 	// Activate a frame for the function call
-	vm.activateFunction(vm.fp+1, 0, fn, vm.tmp[:argc])
+	vm.activateFunction(vm.fp+1, 0, fn, vm.tmp[:localsCount])
 
 	// Setting StopSignal as the return address will cause the eval function to
 	// stop execution when it reaches the end of the active code.
@@ -880,11 +1022,11 @@ func (vm *VirtualMachine) resumeFrame(fp, ip, sp int) *frame {
 	if vm.sp > sp {
 		frameResult = vm.pop()
 	}
-	// Remove any items left on the stack by the previous frame
-	for i := vm.sp; i > sp; i-- {
-		vm.stack[i] = nil
+	// Remove any items left on the stack by the previous frame, such as the
+	// iterators of loops it returned from, releasing their memory usage
+	for vm.sp > sp {
+		vm.pop()
 	}
-	vm.sp = sp
 	// Push the frame result back onto the stack
 	if frameResult != nil {
 		vm.push(frameResult)
@@ -900,10 +1042,12 @@ func (vm *VirtualMachine) resumeFrame(fp, ip, sp int) *frame {
 // Activate a frame with the given code. This is typically used to begin
 // running the entrypoint for a module or script.
 func (vm *VirtualMachine) activateCode(fp, ip int, code *code) *frame {
+	callerIP := vm.ip
 	vm.fp = fp
 	vm.ip = ip
 	vm.activeFrame = &vm.frames[fp]
 	vm.activeFrame.ActivateCode(code)
+	vm.activeFrame.callerIP = callerIP
 	vm.activeCode = code
 	return vm.activeFrame
 }
@@ -917,6 +1061,7 @@ func (vm *VirtualMachine) activateFunction(fp, ip int, fn *object.Function, loca
 	vm.ip = ip
 	vm.activeFrame = &vm.frames[fp]
 	vm.activeFrame.ActivateFunction(fn, code, returnAddr, returnSp, locals)
+	vm.activeFrame.callerIP = returnAddr
 	vm.activeCode = code
 	return vm.activeFrame
 }
@@ -1022,19 +1167,34 @@ func (vm *VirtualMachine) Clone() (*VirtualMachine, error) {
 		loadedCode[cc] = c
 	}
 
+	// Clones either draw from the same instruction budget as this VM or
+	// continue from a snapshot of it, depending on how the VM was configured
+	budget := vm.budget
+	if vm.cloneBudget == CloneBudgetInherit {
+		budget = budget.Fork()
+	}
+
 	clone := &VirtualMachine{
-		sp:           -1,
-		ip:           0,
-		fp:           0,
-		instructions: vm.instructions,
-		running:      false,
-		importer:     vm.importer,
-		main:         vm.main,
-		inputGlobals: vm.inputGlobals,
-		globals:      vm.globals,
-		modules:      modules,
-		loadedCode:   loadedCode,
-		concAllowed:  vm.concAllowed,
+		sp:              -1,
+		ip:              0,
+		fp:              0,
+		running:         false,
+		importer:        vm.importer,
+		main:            vm.main,
+		inputGlobals:    vm.inputGlobals,
+		globals:         vm.globals,
+		modules:         modules,
+		loadedCode:      loadedCode,
+		concAllowed:     vm.concAllowed,
+		maxMemory:       vm.maxMemory,
+		maxInstructions: vm.maxInstructions,
+		budget:          budget,
+		cloneBudget:     vm.cloneBudget,
+		limits:          vm.limits,
+		runtimeErrors:   vm.runtimeErrors,
+		profiler:        vm.profiler,
+		coverage:        vm.coverage,
+		logger:          vm.logger,
 	}
 	clone.activateCode(clone.fp, clone.ip, clone.loadCode(clone.main))
 	return clone, nil
@@ -1070,6 +1230,13 @@ func (vm *VirtualMachine) cloneCallSync(
 
 func (vm *VirtualMachine) initContext(ctx context.Context) context.Context {
 	ctx = object.WithCallFunc(ctx, vm.callFunction)
+	ctx = context.WithValue(ctx, vmContextKey{}, vm)
+	if vm.limits != nil {
+		ctx = limits.WithLimits(ctx, vm.limits)
+	}
+	if vm.logger != nil {
+		ctx = object.WithLogger(ctx, vm.logger)
+	}
 	if vm.concAllowed {
 		ctx = object.WithSpawnFunc(ctx, vm.cloneCallAsync)
 		ctx = object.WithCloneCallFunc(ctx, vm.cloneCallSync)
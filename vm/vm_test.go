@@ -1,13 +1,19 @@
 package vm
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"testing"
 	"time"
 
 	"github.com/itrn0/risor/compiler"
 	"github.com/itrn0/risor/errz"
+	"github.com/itrn0/risor/limits"
+	modStrings "github.com/itrn0/risor/modules/strings"
 	"github.com/itrn0/risor/object"
 	"github.com/itrn0/risor/parser"
 	"github.com/stretchr/testify/require"
@@ -407,6 +413,251 @@ func TestSwitch5(t *testing.T) {
 	require.Equal(t, object.NewInt(2), result)
 }
 
+func TestMatch(t *testing.T) {
+	describe := `
+	func describe(v) {
+		return match v {
+		case 0: "zero"
+		case int(n) if n > 10: "big"
+		case int(n): 'int {n}'
+		case "a" | "b": "letter"
+		case []: "empty"
+		case [x]: 'one {x}'
+		case [first, ...rest]: 'first {first} rest {rest}'
+		case {"kind": "pod", "name": name}: 'pod {name}'
+		case {kind}: 'kind {kind}'
+		default: "other"
+		}
+	}
+	`
+	tests := []testCase{
+		{describe + `describe(0)`, object.NewString("zero")},
+		{describe + `describe(11)`, object.NewString("big")},
+		{describe + `describe(3)`, object.NewString("int 3")},
+		{describe + `describe("b")`, object.NewString("letter")},
+		{describe + `describe("c")`, object.NewString("other")},
+		{describe + `describe([])`, object.NewString("empty")},
+		{describe + `describe([7])`, object.NewString("one 7")},
+		{describe + `describe([1, 2, 3])`, object.NewString("first 1 rest [2, 3]")},
+		{describe + `describe({"kind": "pod", "name": "web"})`, object.NewString("pod web")},
+		{describe + `describe({"kind": "svc", "name": "web"})`, object.NewString("kind svc")},
+		{describe + `describe({"name": "web"})`, object.NewString("other")},
+		{describe + `describe(1.5)`, object.NewString("other")},
+		{`x := [1, 2, 3, 4]; match x { case [a, ...m, z]: [a, m, z]; default: nil }`,
+			object.NewList([]object.Object{object.NewInt(1),
+				object.NewList([]object.Object{object.NewInt(2), object.NewInt(3)}), object.NewInt(4)})},
+		{`x := [1, 2]; match x { case [a, ...m, z]: m; default: nil }`, object.NewList([]object.Object{})},
+		{`x := [1, [2, 3]]; match x { case [_, [_, c]]: c; default: nil }`, object.NewInt(3)},
+		{`match [1, [2, 3]] { case [a, [b, c]]: a + b + c; default: nil }`, object.NewInt(6)},
+		{`match {"a": 1} { case {"a": a}: a; default: nil }`, object.NewInt(1)},
+		{`a := 1; b := 2; match (a + b) { case 3: "three"; default: nil }`, object.NewString("three")},
+		{`x := [1, 2]; match x { case [a]: a; case [a, ...]: a * 10; default: nil }`, object.NewInt(10)},
+		{`x := -2; match x { case -2: "neg"; default: "other" }`, object.NewString("neg")},
+		{`x := 5; match x { case 1: "one" }`, object.Nil},
+		{`x := 5; match x { case n: n * 2 }`, object.NewInt(10)},
+		{`x := 5; match x { case float(): "float"; case int(): "int"; default: nil }`, object.NewString("int")},
+		{`x := 5; y := match x { case 5: }; y`, object.Nil},
+		{`n := 1; x := 5; match x { case n: n }; n`, object.NewInt(1)},
+	}
+	runTests(t, tests)
+}
+
+func TestMatchClosure(t *testing.T) {
+	result, err := run(context.Background(), `
+	func f(v) {
+		fn := match v {
+		case {"n": int(n)}: func() { n * 2 }
+		default: func() { 0 }
+		}
+		return fn()
+	}
+	[f({"n": 4}), f({"n": "4"}), f(nil)]
+	`)
+	require.Nil(t, err)
+	require.Equal(t, object.NewList([]object.Object{
+		object.NewInt(8), object.NewInt(0), object.NewInt(0),
+	}), result)
+}
+
+func TestGenerators(t *testing.T) {
+	count := `
+	func count(n, step=1) {
+		i := 0
+		for i < n {
+			yield i
+			i += step
+		}
+	}
+	`
+	tests := []testCase{
+		{count + `list(count(4))`, object.NewList([]object.Object{
+			object.NewInt(0), object.NewInt(1), object.NewInt(2), object.NewInt(3),
+		})},
+		{count + `list(count(5, 2))`, object.NewList([]object.Object{
+			object.NewInt(0), object.NewInt(2), object.NewInt(4),
+		})},
+		{count + `total := 0; for x := range count(5) { total += x }; total`, object.NewInt(10)},
+		{`func letters() { yield "a"; yield "b" }; r := []; for x := range letters() { r.append(x) }; r`,
+			object.NewList([]object.Object{object.NewString("a"), object.NewString("b")})},
+		// Two variables bind the position and the value, as with lists
+		{`func letters() { yield "a"; yield "b" }
+		r := []
+		for i, x := range letters() { r.append([i, x]) }
+		for i, x := range ["a", "b"] { r.append([i, x]) }
+		r`, object.NewList([]object.Object{
+			object.NewList([]object.Object{object.NewInt(0), object.NewString("a")}),
+			object.NewList([]object.Object{object.NewInt(1), object.NewString("b")}),
+			object.NewList([]object.Object{object.NewInt(0), object.NewString("a")}),
+			object.NewList([]object.Object{object.NewInt(1), object.NewString("b")}),
+		})},
+		{count + `any(count(3))`, object.True},
+		{count + `all(count(3))`, object.False},
+		{count + `sorted(count(3), func(a, b) { a > b })`, object.NewList([]object.Object{
+			object.NewInt(2), object.NewInt(1), object.NewInt(0),
+		})},
+		{count + `g := count(2); [g.next(), g.next(), g.next()]`, object.NewList([]object.Object{
+			object.NewInt(0), object.NewInt(1), object.Nil,
+		})},
+		{count + `g := count(3); list(g); list(g)`, object.NewList(nil)},
+		{count + `type(count(1))`, object.NewString("generator")},
+		{count + `g := count(2); bool(g)`, object.True},
+		{`func early() { yield 1; if true { return 2 }; yield 3 }; list(early())`, object.NewList([]object.Object{
+			object.NewInt(1),
+		})},
+		{`func one() { yield }; list(one())`, object.NewList([]object.Object{object.Nil})},
+		{`
+		func nested() {
+			for _, row := range [[1, 2], [3]] {
+				for _, x := range row { yield x * 10 }
+			}
+		}
+		list(nested())
+		`, object.NewList([]object.Object{object.NewInt(10), object.NewInt(20), object.NewInt(30)})},
+		{`
+		func evens(g) {
+			for x := range g {
+				if x % 2 == 0 { yield x }
+			}
+		}
+		func nums() { for i := range 7 { yield i } }
+		list(evens(nums()))
+		`, object.NewList([]object.Object{
+			object.NewInt(0), object.NewInt(2), object.NewInt(4), object.NewInt(6),
+		})},
+	}
+	runTests(t, tests)
+}
+
+func TestGeneratorState(t *testing.T) {
+	result, err := run(context.Background(), `
+	log := []
+	func steps() {
+		defer log.append("deferred")
+		log.append("start")
+		n := 0
+		get := func() { n * 100 }
+		for i := range 3 {
+			try {
+				yield i
+				error("fail")
+			} catch e {
+				log.append('caught {i}')
+			}
+			n++
+		}
+		yield get()
+		n = 2
+		yield get()
+	}
+	g := steps()
+	log.append("created")
+	values := list(g)
+	[log, values]
+	`)
+	require.Nil(t, err)
+	require.Equal(t, `[["created", "start", "caught 0", "caught 1", "caught 2", "deferred"], [0, 1, 2, 300, 200]]`,
+		result.Inspect())
+}
+
+func TestGeneratorErrors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		input  string
+		errMsg string
+	}{
+		{`func g() { yield 1; error("boom") }; for x := range g() {}`, "boom"},
+		{`func g() { yield 1; error("boom") }; list(g())`, "boom"},
+		{`func g() { yield 1; error("boom") }; sorted(g())`, "boom"},
+		{`func g(n) { yield n }; g()`, "args error: function \"g\" takes 1 argument (0 given)"},
+		{`gen := nil; func g() { for x := range gen { yield x } }; gen = g(); list(gen)`,
+			"value error: generator is already running"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := run(ctx, tt.input)
+			require.NotNil(t, err)
+			require.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+	result, err := run(ctx, `
+	func g() { yield 1; error("boom") }
+	x := ""
+	try { list(g()) } catch e { x = 'caught {e.message()}' }
+	x
+	`)
+	require.Nil(t, err)
+	require.Equal(t, object.NewString("caught boom"), result)
+}
+
+func TestGeneratorFromGo(t *testing.T) {
+	ctx := context.Background()
+	vm, err := newVM(ctx, `func count(n) { for i := range n { yield i } }; count(3)`)
+	require.Nil(t, err)
+	require.Nil(t, vm.Run(ctx))
+	result, exists := vm.TOS()
+	require.True(t, exists)
+	gen, ok := result.(*object.Generator)
+	require.True(t, ok)
+	require.Equal(t, []any{int64(0), int64(1), int64(2)}, gen.Interface())
+	_, ok = gen.Next(ctx)
+	require.False(t, ok)
+	require.Nil(t, gen.Err())
+}
+
+func TestGeneratorMemoryUsage(t *testing.T) {
+	// Resuming a generator inside a loop must not accumulate memory usage,
+	// so a long generator can be consumed under the default limits
+	ctx := context.Background()
+	vm, err := newVM(ctx, `
+	func gen(n) { for i := range n { yield i } }
+	c := 0
+	for x := range gen(40000) { c++ }
+	c`)
+	require.Nil(t, err)
+	require.Equal(t, int64(MaxMemoryUsage), vm.maxMemory)
+	require.Nil(t, vm.Run(ctx))
+	result, exists := vm.TOS()
+	require.True(t, exists)
+	require.Equal(t, object.NewInt(40000), result)
+}
+
+func TestIteratorSize(t *testing.T) {
+	ctx := context.Background()
+	vm, err := newVM(ctx, `func count(n) { for i := range n { yield i } }; count(3)`)
+	require.Nil(t, err)
+	require.Nil(t, vm.Run(ctx))
+	result, exists := vm.TOS()
+	require.True(t, exists)
+	size, err := varSize(result)
+	require.Nil(t, err)
+	require.Equal(t, ObjectGeneratorSize, size)
+
+	// Objects without a case of their own are counted by reference
+	size, err = varSize(&object.FileIter{})
+	require.Nil(t, err)
+	require.Equal(t, ObjectReferenceSize+PtrSize, size)
+}
+
 func TestStr(t *testing.T) {
 	result, err := run(context.Background(), `
 	s := "hello"
@@ -1463,7 +1714,7 @@ func TestProxy(t *testing.T) {
 func TestHalt(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
 	defer cancel()
-	_, err := run(ctx, `for {}`)
+	_, err := run(ctx, `for {}`, runOpts{Options: []Option{WithMaxInstructions(limits.NoLimit)}})
 	require.NotNil(t, err)
 	require.Equal(t, context.DeadlineExceeded, err)
 }
@@ -1472,7 +1723,8 @@ func TestCallHalt(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
 	defer cancel()
 
-	vm, err := newVM(context.Background(), "func block() { for {} }")
+	vm, err := newVM(context.Background(), "func block() { for {} }",
+		runOpts{Options: []Option{WithMaxInstructions(limits.NoLimit)}})
 	require.NoError(t, err)
 	require.NoError(t, vm.Run(context.Background()))
 
@@ -2037,6 +2289,13 @@ func TestChannels(t *testing.T) {
 			object.NewString("a"),
 			object.NewString("b"),
 		})},
+		{`c := chan(2); c <- "a"; c <- "b"; close(c);
+		  results := []
+		  for value := range c { results.append(value) }
+		  results`, object.NewList([]object.Object{
+			object.NewString("a"),
+			object.NewString("b"),
+		})},
 	}
 	runTests(t, tests)
 }
@@ -2059,6 +2318,64 @@ func TestChannelErrors(t *testing.T) {
 	}
 }
 
+func TestSelect(t *testing.T) {
+	tests := []testCase{
+		{`a := chan(1); b := chan(1); b <- 2
+		  select {
+		  case v := <-a: ["a", v]
+		  case v, ok := <-b: ["b", v, ok]
+		  }`, object.NewList([]object.Object{
+			object.NewString("b"), object.NewInt(2), object.True,
+		})},
+		{`c := chan(); select { case v := <-c: v; default: "empty" }`, object.NewString("empty")},
+		{`c := chan(1); select { case c <- 5: "sent"; default: "full" }; <-c`, object.NewInt(5)},
+		{`c := chan(1); c <- 1; select { case c <- 5: "sent"; default: "full" }`, object.NewString("full")},
+		{`c := chan(); close(c); select { case v, ok := <-c: [v, ok] }`, object.NewList([]object.Object{
+			object.Nil, object.False,
+		})},
+		{`a := nil; b := chan(1); b <- 1; select { case <-a: "a"; case <-b: "b" }`, object.NewString("b")},
+		{`c := chan(); select { case <-c: 1; default: }`, object.Nil},
+		{`c := chan(); go func() { c <- 42 }(); select { case v := <-c: v * 2 }`, object.NewInt(84)},
+		{`v := "outer"; c := chan(1); c <- 1; select { case v := <-c: v }; v`, object.NewString("outer")},
+		{`results := []
+		  a := chan(3); a <- 1; a <- 2; a <- 3; close(a)
+		  for {
+		      done := select {
+		      case v, ok := <-a:
+		          if ok { results.append(v) }
+		          !ok
+		      }
+		      if done { break }
+		  }
+		  results`, object.NewList([]object.Object{
+			object.NewInt(1), object.NewInt(2), object.NewInt(3),
+		})},
+	}
+	runTests(t, tests)
+}
+
+func TestSelectErrors(t *testing.T) {
+	tests := []struct {
+		input     string
+		expectErr string
+	}{
+		{`c := chan(1); close(c); select { case c <- 1: 1 }`, "exec error: send on closed channel"},
+		{`x := 1; select { case <-x: 1; default: 2 }`, "type error: object is not a channel (got int)"},
+	}
+	for _, tt := range tests {
+		_, err := run(context.Background(), tt.input)
+		require.NotNil(t, err)
+		require.Equal(t, tt.expectErr, err.Error())
+	}
+}
+
+func TestSelectHonorsContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := run(ctx, `c := chan(); select { case <-c: 1 }`)
+	require.Equal(t, context.DeadlineExceeded, err)
+}
+
 func TestGoStatement(t *testing.T) {
 	tests := []testCase{
 		{`go func() { 1 }()`, object.Nil},
@@ -2264,3 +2581,655 @@ func runTests(t *testing.T, tests []testCase) {
 		})
 	}
 }
+
+func TestMaxInstructions(t *testing.T) {
+	ctx := context.Background()
+	ast, err := parser.Parse(ctx, `for i := 0; i < 100; i++ {}`)
+	require.Nil(t, err)
+	main, err := compiler.Compile(ast)
+	require.Nil(t, err)
+
+	err = New(main, WithMaxInstructions(50)).Run(ctx)
+	require.Error(t, err)
+	require.Equal(t, "eval error: max instructions limit of 50 exceeded", err.Error())
+
+	require.Nil(t, New(main, WithMaxInstructions(limits.NoLimit)).Run(ctx))
+}
+
+func TestMaxMemoryUsage(t *testing.T) {
+	ctx := context.Background()
+	ast, err := parser.Parse(ctx, `strings.repeat("a", 1000)`)
+	require.Nil(t, err)
+	globals := map[string]any{"strings": modStrings.Module()}
+	main, err := compiler.Compile(ast, compiler.WithGlobalNames([]string{"strings"}))
+	require.Nil(t, err)
+
+	err = New(main, WithGlobals(globals), WithMaxMemoryUsage(500)).Run(ctx)
+	require.Error(t, err)
+	require.Equal(t, "memory limit exceeded", err.Error())
+
+	err = New(main, WithGlobals(globals), WithMaxMemoryUsage(limits.NoLimit)).Run(ctx)
+	require.Nil(t, err)
+}
+
+func TestWithLimits(t *testing.T) {
+	ctx := context.Background()
+	ast, err := parser.Parse(ctx, `for i := 0; i < 100; i++ {}`)
+	require.Nil(t, err)
+	main, err := compiler.Compile(ast)
+	require.Nil(t, err)
+
+	err = New(main, WithLimits(limits.New(limits.WithMaxInstructions(20)))).Run(ctx)
+	require.Error(t, err)
+	require.Equal(t, "eval error: max instructions limit of 20 exceeded", err.Error())
+}
+
+func TestWithLimitsKeepsDefaults(t *testing.T) {
+	ctx := context.Background()
+	ast, err := parser.Parse(ctx, `for i := 0; i < 1000000; i++ {}`)
+	require.Nil(t, err)
+	main, err := compiler.Compile(ast)
+	require.Nil(t, err)
+
+	// Limits that only restrict buffer sizes leave the VM defaults in place
+	vm := New(main, WithLimits(limits.New(limits.WithMaxBufferSize(10))))
+	require.Equal(t, int64(MaxInstructions), vm.maxInstructions)
+	require.Equal(t, int64(MaxMemoryUsage), vm.maxMemory)
+	err = vm.Run(ctx)
+	require.Error(t, err)
+	require.Equal(t, fmt.Sprintf("eval error: max instructions limit of %d exceeded", MaxInstructions), err.Error())
+
+	err = New(main, WithLimits(limits.New(limits.WithMaxInstructions(limits.NoLimit)))).Run(ctx)
+	require.Nil(t, err)
+}
+
+func TestCloneBudget(t *testing.T) {
+	ctx := context.Background()
+	ast, err := parser.Parse(ctx, `
+	func work() { for i := 0; i < 10; i++ {} }
+	work()
+	`)
+	require.Nil(t, err)
+	main, err := compiler.Compile(ast)
+	require.Nil(t, err)
+
+	budget := NewBudget(limits.NoLimit)
+	vm := New(main, WithBudget(budget), WithCloneBudget(CloneBudgetShared))
+	require.Nil(t, vm.Run(ctx))
+	used := budget.Used()
+	require.Greater(t, used, int64(0))
+
+	// A shared budget is drawn down by work done in clones
+	work, err := vm.Get("work")
+	require.Nil(t, err)
+	clone, err := vm.Clone()
+	require.Nil(t, err)
+	_, err = clone.Call(ctx, work.(*object.Function), nil)
+	require.Nil(t, err)
+	require.Greater(t, budget.Used(), used)
+
+	// An inherited budget is not
+	budget = NewBudget(limits.NoLimit)
+	vm = New(main, WithBudget(budget))
+	require.Nil(t, vm.Run(ctx))
+	used = budget.Used()
+	clone, err = vm.Clone()
+	require.Nil(t, err)
+	_, err = clone.Call(ctx, work.(*object.Function), nil)
+	require.Nil(t, err)
+	require.Equal(t, used, budget.Used())
+	require.Greater(t, clone.budget.Used(), used)
+}
+
+func TestRuntimeErrorStack(t *testing.T) {
+	ctx := context.Background()
+	ast, err := parser.Parse(ctx, `
+func inner(x) {
+	return x.missing
+}
+func outer() {
+	return inner(1)
+}
+outer()
+`, parser.WithFile("main.risor"))
+	require.Nil(t, err)
+	main, err := compiler.Compile(ast)
+	require.Nil(t, err)
+
+	err = New(main, WithRuntimeErrors()).Run(ctx)
+	require.Error(t, err)
+	require.Equal(t, "type error: attribute \"missing\" not found on int object", err.Error())
+
+	var runtimeErr *RuntimeError
+	require.True(t, errors.As(err, &runtimeErr))
+	require.False(t, runtimeErr.IsFatal())
+	require.Equal(t, compiler.SourceLocation{File: "main.risor", Line: 3, Column: 10},
+		runtimeErr.Location())
+
+	var names []string
+	var lines []int
+	for _, frame := range runtimeErr.Stack() {
+		names = append(names, frame.Function)
+		lines = append(lines, frame.Location.Line)
+	}
+	require.Equal(t, []string{"__main__", "outer", "inner"}, names)
+	require.Equal(t, []int{8, 6, 3}, lines)
+
+	require.Contains(t, runtimeErr.FriendlyErrorMessage(),
+		"location: main.risor:3:10 (line 3, column 10)")
+}
+
+func TestTryStatement(t *testing.T) {
+	tests := []testCase{
+		{`x := 0; try { x = 1 } catch { x = 2 }; x`, object.NewInt(1)},
+		{`x := 0; try { error("oops"); x = 1 } catch { x = 2 }; x`, object.NewInt(2)},
+		{`x := ""; try { error("oops") } catch err { x = err.message() }; x`, object.NewString("oops")},
+		{`x := ""; try { 1 + "a" } catch err { x = err.message() }; x`, object.NewString(
+			"type error: unsupported operation for int: + on type string")},
+		{`x := []; try { x.append(1) } finally { x.append(2) }; x`, object.NewList(
+			[]object.Object{object.NewInt(1), object.NewInt(2)})},
+		{`x := []; try { error("oops") } catch { x.append(1) } finally { x.append(2) }; x`, object.NewList(
+			[]object.Object{object.NewInt(1), object.NewInt(2)})},
+		{`x := []; try { x.append(1) } catch { x.append("c") } finally { x.append("f") }; x`, object.NewList(
+			[]object.Object{object.NewInt(1), object.NewString("f")})},
+		{`n := 0; f := 0
+		for i := 0; i < 1000; i++ { try { n++ } catch { n = -1 } finally { f++ } }
+		[n, f]`, object.NewList([]object.Object{object.NewInt(1000), object.NewInt(1000)})},
+		{`x := [1, 2, 3]; n := 0; try { y := [4, 5, x[10]] } catch { n = len(x) }; n`, object.NewInt(3)},
+		{`x := ""
+		try {
+			try { error("inner") } catch err { error(err.message() + " again") }
+		} catch err { x = err.message() }
+		x`, object.NewString("inner again")},
+		{`x := []
+		try {
+			try { error("inner") } finally { x.append("finally") }
+		} catch err { x.append(err.message()) }
+		x`, object.NewList([]object.Object{object.NewString("finally"), object.NewString("inner")})},
+		{`x := []
+		func f() {
+			defer func() { x.append("deferred") }()
+			error("oops")
+		}
+		try { f() } catch err { x.append("caught " + err.message()) }
+		x`, object.NewList([]object.Object{object.NewString("deferred"), object.NewString("caught oops")})},
+		{`func f() {
+			try { return "try" } finally { return "finally" }
+		}
+		f()`, object.NewString("finally")},
+		{`x := []
+		func f() {
+			try {
+				try { return 1 } finally { x.append("inner") }
+			} finally { x.append("outer") }
+		}
+		[f(), x]`, object.NewList([]object.Object{
+			object.NewInt(1),
+			object.NewList([]object.Object{object.NewString("inner"), object.NewString("outer")}),
+		})},
+		{`x := []
+		for i := 0; i < 5; i++ {
+			try {
+				if i == 1 { continue }
+				if i == 3 { break }
+				x.append(i)
+			} finally { x.append("f") }
+		}
+		x`, object.NewList([]object.Object{
+			object.NewInt(0), object.NewString("f"),
+			object.NewString("f"),
+			object.NewInt(2), object.NewString("f"),
+			object.NewString("f"),
+		})},
+		{`x := 0; for _, v := range [1, 2, 3] { try { error("oops") } catch { x += v } }; x`, object.NewInt(6)},
+	}
+	runTests(t, tests)
+}
+
+func TestTryFinallyReraises(t *testing.T) {
+	code := `
+	x := []
+	func f() {
+		try { error("oops") } finally { x.append("finally") }
+	}
+	f()
+	`
+	_, err := run(context.Background(), code)
+	require.Error(t, err)
+	require.Equal(t, "oops", err.Error())
+}
+
+func TestTryErrorInCatchRunsFinally(t *testing.T) {
+	code := `
+	x := []
+	try {
+		try { error("one") } catch { error("two") } finally { x.append("finally") }
+	} catch err { x.append(err.message()) }
+	x
+	`
+	result, err := run(context.Background(), code)
+	require.Nil(t, err)
+	require.Equal(t, object.NewList([]object.Object{
+		object.NewString("finally"),
+		object.NewString("two"),
+	}), result)
+}
+
+func TestTryDoesNotCatchFatalErrors(t *testing.T) {
+	code := `
+	x := 0
+	try { error(errors.eval_error("fatal")) } catch { x = 1 } finally { x = 2 }
+	`
+	_, err := run(context.Background(), code)
+	require.Error(t, err)
+	require.Equal(t, errz.EvalErrorf("fatal"), err)
+}
+
+func TestTryDoesNotCatchLimits(t *testing.T) {
+	ctx := context.Background()
+	_, err := run(ctx, `try { for { } } catch { "caught" }`, runOpts{
+		Options: []Option{WithMaxInstructions(100)},
+	})
+	require.Error(t, err)
+	require.Equal(t, "eval error: max instructions limit of 100 exceeded", err.Error())
+}
+
+func TestTryReraisedErrorLocation(t *testing.T) {
+	ctx := context.Background()
+	ast, err := parser.Parse(ctx, `
+try {
+	error("oops")
+} finally {
+	print("done")
+}
+`, parser.WithFile("main.risor"))
+	require.Nil(t, err)
+	globals := basicBuiltins()
+	var globalNames []string
+	for name := range globals {
+		globalNames = append(globalNames, name)
+	}
+	main, err := compiler.Compile(ast, compiler.WithGlobalNames(globalNames))
+	require.Nil(t, err)
+
+	err = New(main, WithGlobals(globals), WithRuntimeErrors()).Run(ctx)
+	require.Error(t, err)
+	var runtimeErr *RuntimeError
+	require.True(t, errors.As(err, &runtimeErr))
+	require.Equal(t, compiler.SourceLocation{File: "main.risor", Line: 3, Column: 7},
+		runtimeErr.Location())
+}
+
+func TestStruct(t *testing.T) {
+	tests := []testCase{
+		{`struct Point { x, y }; p := Point(1, 2); p.x + p.y`, object.NewInt(3)},
+		{`struct Point { x, y = 5 }; Point(1).y`, object.NewInt(5)},
+		{`struct Point { x, y }; Point().x`, object.Nil},
+		{`struct Point { x, y }; p := Point(1, 2); p.y = 10; p.y`, object.NewInt(10)},
+		{`struct Point { x, y }; type(Point(1, 2))`, object.NewString("Point")},
+		{`struct Point { x, y }; type(Point)`, object.NewString("struct_type")},
+		{`struct Point { x, y }; string(Point(1, "a"))`, object.NewString(`Point{x: 1, y: "a"}`)},
+		{`struct Point { x, y = 0 }; string(Point)`, object.NewString("struct Point { x, y = 0 }")},
+		{`struct Point { x, y }; Point(1, 2) == Point(1, 2)`, object.True},
+		{`struct Point { x, y }; Point(1, 2) == Point(2, 1)`, object.False},
+		{`struct A { x }; struct B { x }; A(1) == B(1)`, object.False},
+		{`struct Point { x, y }; json.marshal(Point(1, [2]))`, object.NewString(`{"x":1,"y":[2]}`)},
+		{`struct Point { y, x }; encode(Point(1, 2), "json")`, object.NewString(`{"x":2,"y":1}`)},
+		{`func f() { struct Local { v = "ok" }; return Local().v }; f()`, object.NewString("ok")},
+	}
+	runTests(t, tests)
+}
+
+func TestStructMethods(t *testing.T) {
+	tests := []testCase{
+		{`struct Point { x, y }
+		func (p Point) sum() { return p.x + p.y }
+		Point(1, 2).sum()`, object.NewInt(3)},
+		{`struct Point { x, y }
+		func (p Point) scale(n, m=1) { return Point(p.x * n * m, p.y * n * m) }
+		Point(1, 2).scale(2, 3).y`, object.NewInt(12)},
+		{`struct Counter { n = 0 }
+		func (c Counter) incr() { c.n = c.n + 1; return c }
+		c := Counter()
+		c.incr().incr()
+		c.n`, object.NewInt(2)},
+		{`struct Point { x }
+		func (p Point) getter() { return func() { return p.x } }
+		Point(7).getter()()`, object.NewInt(7)},
+		{`struct Point { x }
+		func (p Point) double() { return p.x * 2 }
+		Point.double(Point(4))`, object.NewInt(8)},
+	}
+	runTests(t, tests)
+}
+
+func TestStructErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`struct Point { x, y }; Point(1, 2, 3)`, "args error: struct Point has 2 fields (3 given)"},
+		{`struct Point { x, y }; Point().z = 1`, `type error: struct Point has no field "z"`},
+		{`struct Point { x }; func (p Point) x() {}`, `type error: struct Point has a field named "x"`},
+		{`struct Point { x }; func (p Point) f(a) {}; Point().f()`, "args error: Point.f() takes exactly 1 arguments (0 given)"},
+		{`struct Point { x }; Point() + 1`, "type error: unsupported operation for Point: +"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := run(context.Background(), tt.input)
+			require.Error(t, err)
+			require.Equal(t, tt.expected, err.Error())
+		})
+	}
+}
+
+func TestDebuggerBreakpoints(t *testing.T) {
+	var lines []int
+	debugger := NewDebugger(func(ctx context.Context, stop *Stop) DebugAction {
+		require.Equal(t, StopBreakpoint, stop.Reason)
+		lines = append(lines, stop.Location.Line)
+		return DebugContinue
+	})
+	debugger.SetBreakpoints("", []int{3, 6})
+	result, err := run(context.Background(), `
+x := 1
+func f(a) {
+	return a * 2
+}
+for i := 0; i < 2; i++ { x = f(x) }
+x`, runOpts{Options: []Option{WithDebugger(debugger)}})
+	require.Nil(t, err)
+	require.Equal(t, object.NewInt(4), result)
+	// The loop body is entered once per iteration, but the function
+	// declaration is only evaluated once
+	require.Equal(t, []int{3, 6, 6, 6}, lines)
+}
+
+func TestDebuggerStepping(t *testing.T) {
+	source := `
+func f(a) {
+	b := a + 1
+	return b
+}
+x := f(1)
+y := x + 1
+y`
+	tests := []struct {
+		action DebugAction
+		lines  []int
+	}{
+		{DebugStepIn, []int{2, 6, 3, 4, 7, 8}},
+		{DebugStepOver, []int{2, 6, 7, 8}},
+	}
+	for _, tt := range tests {
+		var lines []int
+		debugger := NewDebugger(func(ctx context.Context, stop *Stop) DebugAction {
+			lines = append(lines, stop.Location.Line)
+			return tt.action
+		})
+		debugger.SetStopOnEntry(true)
+		result, err := run(context.Background(), source, runOpts{
+			Options: []Option{WithDebugger(debugger)},
+		})
+		require.Nil(t, err)
+		require.Equal(t, object.NewInt(3), result)
+		require.Equal(t, tt.lines, lines)
+	}
+}
+
+func TestDebuggerStepOut(t *testing.T) {
+	var stops []*Stop
+	debugger := NewDebugger(func(ctx context.Context, stop *Stop) DebugAction {
+		stops = append(stops, stop)
+		if stop.Reason == StopBreakpoint {
+			return DebugStepOut
+		}
+		return DebugContinue
+	})
+	debugger.SetBreakpoints("", []int{3})
+	_, err := run(context.Background(), `
+func f(a) {
+	return a + 1
+}
+x := f(1)
+y := x + 1`, runOpts{Options: []Option{WithDebugger(debugger)}})
+	require.Nil(t, err)
+	require.Len(t, stops, 2)
+	require.Equal(t, StopStep, stops[1].Reason)
+	require.Equal(t, 6, stops[1].Location.Line)
+	require.Len(t, stops[1].Frames, 1)
+}
+
+func TestDebuggerVariables(t *testing.T) {
+	var stop *Stop
+	debugger := NewDebugger(func(ctx context.Context, s *Stop) DebugAction {
+		stop = s
+		return DebugContinue
+	})
+	debugger.SetBreakpoints("", []int{6})
+	_, err := run(context.Background(), `
+count := 10
+func outer(a) {
+	b := "local"
+	return func() {
+		return a + count
+	}
+}
+outer(5)()`, runOpts{Options: []Option{WithDebugger(debugger)}})
+	require.Nil(t, err)
+	require.NotNil(t, stop)
+	require.Len(t, stop.Frames, 2)
+	require.Equal(t, "__main__", stop.Frames[0].Function)
+	require.Empty(t, stop.Frames[0].Locals)
+	inner := stop.Frames[1]
+	require.Equal(t, "<anonymous>", inner.Function)
+	require.Equal(t, []Variable{{Name: "a", Value: object.NewInt(5)}}, inner.Free)
+	require.Contains(t, stop.Globals, Variable{Name: "count", Value: object.NewInt(10)})
+	for _, global := range stop.Globals {
+		require.NotEqual(t, "print", global.Name)
+	}
+}
+
+func TestDebuggerPause(t *testing.T) {
+	var reasons []StopReason
+	debugger := NewDebugger(func(ctx context.Context, stop *Stop) DebugAction {
+		reasons = append(reasons, stop.Reason)
+		return DebugContinue
+	})
+	debugger.Pause()
+	_, err := run(context.Background(), "x := 1\ny := 2", runOpts{
+		Options: []Option{WithDebugger(debugger)},
+	})
+	require.Nil(t, err)
+	require.Equal(t, []StopReason{StopPause}, reasons)
+}
+
+func TestOptimizedCode(t *testing.T) {
+	sources := []string{
+		`60 * 60 * 24`,
+		`"a" + "b" + "c"`,
+		`-(2 ** 10) + 0.5`,
+		`x := 1; if false { x = 2 }; x`,
+		`if true { "yes" } else { "no" }`,
+		`!true || !nil`,
+		`total := 0
+		for i := 0; i < 10; i++ {
+			if i % 2 == 0 { continue }
+			if i > 7 { break }
+			total += i * (2 + 3)
+		}
+		total`,
+		`func f(x) {
+			try {
+				if x > 1 { error("big") }
+				return x + 1 * 2
+			} catch e {
+				return "caught " + string(e)
+			}
+			return "unreachable"
+		}
+		[f(0), f(5)]`,
+		`switch 1 + 1 { case 2: "two"; default: "other" }`,
+	}
+	ctx := context.Background()
+	for _, src := range sources {
+		t.Run(src, func(t *testing.T) {
+			expected, err := run(ctx, src)
+			require.Nil(t, err)
+			for _, level := range []int{compiler.OptimizeBasic, compiler.OptimizeFull} {
+				result, err := run(ctx, src, runOpts{
+					CompilerOptions: []compiler.Option{compiler.WithOptimizationLevel(level)},
+				})
+				require.Nil(t, err)
+				require.Equal(t, expected, result)
+			}
+		})
+	}
+}
+
+func TestOptimizedCodeErrors(t *testing.T) {
+	// Operations that fail at runtime are not folded
+	_, err := run(context.Background(), "x := 1\n1 / 0", runOpts{
+		CompilerOptions: []compiler.Option{compiler.WithOptimizationLevel(compiler.OptimizeFull)},
+	})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "integer divide by zero")
+}
+
+func TestProfiler(t *testing.T) {
+	profiler := NewProfiler(time.Millisecond)
+	_, err := run(context.Background(), `
+func add(a, b) {
+	return a + b
+}
+total := 0
+for i := 0; i < 10; i++ {
+	total = add(total, i)
+}
+total`, runOpts{Options: []Option{WithProfiler(profiler)}})
+	require.Nil(t, err)
+
+	var total, inAdd int64
+	for _, sample := range profiler.Samples() {
+		require.Equal(t, "__main__", sample.Stack[0].Function)
+		total += sample.Instructions
+		leaf := sample.Stack[len(sample.Stack)-1]
+		if leaf.Function == "add" {
+			require.Len(t, sample.Stack, 2)
+			require.Equal(t, 7, sample.Stack[0].Location.Line)
+			if leaf.Location.Line == 3 {
+				inAdd += sample.Instructions
+			}
+		}
+	}
+	// LOAD_FAST, LOAD_FAST, BINARY_OP and RETURN_VALUE for each call
+	require.Equal(t, int64(40), inAdd)
+	require.Greater(t, total, inAdd)
+	require.Greater(t, profiler.Duration(), time.Duration(0))
+}
+
+func TestProfilerWallTime(t *testing.T) {
+	profiler := NewProfiler(time.Millisecond)
+	_, err := run(context.Background(), `
+func wait() {
+	sleep(0.02)
+}
+wait()`, runOpts{
+		Globals: map[string]any{
+			"sleep": object.NewBuiltin("sleep", func(ctx context.Context, args ...object.Object) object.Object {
+				time.Sleep(time.Duration(args[0].(*object.Float).Value() * float64(time.Second)))
+				return object.Nil
+			}),
+		},
+		Options: []Option{WithProfiler(profiler)},
+	})
+	require.Nil(t, err)
+
+	var inWait time.Duration
+	for _, sample := range profiler.Samples() {
+		leaf := sample.Stack[len(sample.Stack)-1]
+		if leaf.Function == "wait" && leaf.Location.Line == 3 {
+			inWait += sample.Duration
+		}
+	}
+	require.GreaterOrEqual(t, inWait, 15*time.Millisecond)
+}
+
+func TestProfilerWriteProfile(t *testing.T) {
+	profiler := NewProfiler(0)
+	require.Equal(t, DefaultProfileInterval, profiler.Interval())
+	_, err := run(context.Background(), `
+func square(x) { return x * x }
+square(3)`, runOpts{Options: []Option{WithProfiler(profiler)}})
+	require.Nil(t, err)
+
+	var buf bytes.Buffer
+	require.Nil(t, profiler.WriteProfile(&buf))
+	zr, err := gzip.NewReader(&buf)
+	require.Nil(t, err)
+	data, err := io.ReadAll(zr)
+	require.Nil(t, err)
+	for _, s := range []string{"instructions", "wall", "nanoseconds", "square", "__main__"} {
+		require.Contains(t, string(data), s)
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	coverage := NewCoverage()
+	_, err := run(context.Background(), `
+func sign(n) {
+	if n < 0 {
+		return -1
+	}
+	return 1
+}
+func unused() {
+	return 0
+}
+for i := 0; i < 3; i++ {
+	sign(i)
+}`, runOpts{Options: []Option{WithCoverage(coverage)}})
+	require.Nil(t, err)
+
+	counts := map[int]int64{}
+	for _, line := range coverage.Lines() {
+		counts[line.Line] = line.Count
+	}
+	require.Equal(t, int64(3), counts[3])
+	require.Equal(t, int64(0), counts[4])
+	require.Equal(t, int64(3), counts[6])
+	require.Equal(t, int64(0), counts[9])
+	require.Greater(t, counts[12], int64(0))
+
+	lines := []CoverageLine{
+		{File: "a.risor", Line: 1, StartColumn: 1, EndColumn: 7, Count: 2},
+		{File: "a.risor", Line: 2, StartColumn: 3, EndColumn: 4, Count: 0},
+		{File: "b.risor", Line: 5, StartColumn: 1, EndColumn: 2, Count: 1},
+	}
+	require.InDelta(t, 66.7, CoveragePercent(lines), 0.1)
+
+	var buf bytes.Buffer
+	require.Nil(t, WriteCoverProfile(&buf, lines))
+	require.Equal(t, `mode: count
+a.risor:1.1,1.7 1 2
+a.risor:2.3,2.4 1 0
+b.risor:5.1,5.2 1 1
+`, buf.String())
+
+	buf.Reset()
+	require.Nil(t, WriteLCOV(&buf, lines))
+	require.Equal(t, `TN:
+SF:a.risor
+DA:1,2
+DA:2,0
+LF:2
+LH:1
+end_of_record
+TN:
+SF:b.risor
+DA:5,1
+LF:1
+LH:1
+end_of_record
+`, buf.String())
+}